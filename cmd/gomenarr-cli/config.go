@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/config"
+)
+
+// deprecatedVariable is a deprecated variable name found by config migrate-env
+type deprecatedVariable struct {
+	Name        string `json:"name"`
+	Replacement string `json:"replacement"`
+	Ignored     bool   `json:"ignored"`
+}
+
+// envMigration is the --json output of config migrate-env
+type envMigration struct {
+	Settings     map[string]string    `json:"settings"`
+	Deprecations []deprecatedVariable `json:"deprecations"`
+}
+
+// configCommand runs the configuration subcommands, locally without the server
+func configCommand(out *output, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing config subcommand (migrate-env)")
+	}
+
+	switch args[0] {
+	case "migrate-env":
+		return configMigrateEnv(out, args[1:])
+	default:
+		return fmt.Errorf("unknown config subcommand: %s", args[0])
+	}
+}
+
+// configMigrateEnv prints the settings of a .env file and of the environment as a
+// config.yaml, with the deprecated variable names replaced by the current ones
+func configMigrateEnv(out *output, args []string) error {
+	flags := flag.NewFlagSet("config migrate-env", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "the .env file to migrate, skipped when missing")
+	environ := flags.Bool("environ", true, "include the environment variables, they win over the .env file like in gomenarr")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	vars := make(map[string]string)
+	if *envFile != "" {
+		fileVars, err := config.ReadEnvFile(*envFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		for name, value := range fileVars {
+			vars[name] = value
+		}
+	}
+	if *environ {
+		for name, value := range config.Environ() {
+			vars[name] = value
+		}
+	}
+
+	settings, deprecations := config.MigrateEnv(vars)
+	result := envMigration{Settings: settings, Deprecations: []deprecatedVariable{}}
+	for _, deprecation := range deprecations {
+		result.Deprecations = append(result.Deprecations, deprecatedVariable{
+			Name:        deprecation.Name,
+			Replacement: deprecation.Replacement,
+			Ignored:     deprecation.Ignored,
+		})
+	}
+
+	return out.print(result, func(w io.Writer) error {
+		fmt.Fprintln(w, "# gomenarr config.yaml, read from the working directory or CONFIG_FILE")
+		for _, deprecation := range result.Deprecations {
+			if deprecation.Ignored {
+				fmt.Fprintf(w, "# %s dropped, %s is set too\n", deprecation.Name, deprecation.Replacement)
+			} else {
+				fmt.Fprintf(w, "# %s renamed to %s\n", deprecation.Name, deprecation.Replacement)
+			}
+		}
+
+		names := make([]string, 0, len(settings))
+		for name := range settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			// Go quoted strings are valid YAML double-quoted scalars
+			fmt.Fprintf(w, "%s: %s\n", strings.ToLower(name), strconv.Quote(settings[name]))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: gomenarr-cli [flags] <command> [args]
+
+Commands:
+  version                      Print the CLI and server build information
+  task list                    List the scheduled tasks
+  task run <name> [--no-deps]  Run a task now, after its dependencies unless --no-deps
+  task cancel <name>           Cancel the current run of a task
+  nzb audit [flags] [id...]    Show how stored release titles are normalized and parsed
+                               (--media <id>, --limit <n>, --mismatches)
+  quarantine list              List the downloads rejected on import
+  quarantine import <id>       Import a quarantined download anyway
+  quarantine purge <id>|--all  Delete quarantined downloads
+  search <query>               Search media, releases and history (e.g. search the bear s03)
+  config migrate-env [flags]   Print the .env file and environment settings as a config.yaml,
+                               renaming deprecated variables (--env-file <path>, --environ=false)
+
+Every command prints JSON instead of text with --json.
+
+Flags:
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("gomenarr-cli", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	serverURL := flags.String("url", envOrDefault("GOMENARR_URL", "http://localhost:8080"), "gomenarr server URL (env GOMENARR_URL)")
+	apiKey := flags.String("api-key", os.Getenv("GOMENARR_API_KEY"), "API key of the server (env GOMENARR_API_KEY)")
+	jsonOutput := flags.Bool("json", false, "print machine-readable JSON output")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	client := newAPIClient(*serverURL, *apiKey)
+	out := &output{json: *jsonOutput, w: os.Stdout}
+
+	switch command := flags.Arg(0); command {
+	case "version":
+		return versionCommand(client, out)
+	case "task":
+		return taskCommand(client, out, flags.Args()[1:])
+	case "nzb":
+		return nzbCommand(client, out, flags.Args()[1:])
+	case "quarantine":
+		return quarantineCommand(client, out, flags.Args()[1:])
+	case "search":
+		return searchCommand(client, out, flags.Args()[1:])
+	case "config":
+		return configCommand(out, flags.Args()[1:])
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+// envOrDefault returns the environment variable value or a default
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
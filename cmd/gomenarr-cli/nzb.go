@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// releaseFields mirrors the parsed release fields returned by /api/nzbs/audit
+type releaseFields struct {
+	Quality    string `json:"quality"`
+	Resolution int    `json:"resolution,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Codec      string `json:"codec,omitempty"`
+	Group      string `json:"group,omitempty"`
+	Year       int    `json:"year,omitempty"`
+	Season     *int   `json:"season,omitempty"`
+	Episode    *int   `json:"episode,omitempty"`
+	SeasonPack bool   `json:"season_pack"`
+}
+
+// titleAudit mirrors a release title audit returned by /api/nzbs/audit
+type titleAudit struct {
+	NZBID      uint64        `json:"nzb_id"`
+	MediaID    uint64        `json:"media_id"`
+	Title      string        `json:"title"`
+	Normalized string        `json:"normalized"`
+	MatchKey   string        `json:"match_key"`
+	Stored     releaseFields `json:"stored"`
+	Detected   releaseFields `json:"detected"`
+	Mismatches []string      `json:"mismatches,omitempty"`
+}
+
+// nzbCommand runs the release subcommands
+func nzbCommand(client *apiClient, out *output, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing nzb subcommand (audit)")
+	}
+
+	switch args[0] {
+	case "audit":
+		return nzbAudit(client, out, args[1:])
+	default:
+		return fmt.Errorf("unknown nzb subcommand: %s", args[0])
+	}
+}
+
+// nzbAudit prints how the current parsing rules read stored release titles
+func nzbAudit(client *apiClient, out *output, args []string) error {
+	flags := flag.NewFlagSet("nzb audit", flag.ContinueOnError)
+	mediaID := flags.Uint64("media", 0, "audit the releases of a media item")
+	limit := flags.Int("limit", 0, "number of recent releases to audit (server default: 50)")
+	mismatches := flags.Bool("mismatches", false, "only show releases parsed differently than when they were saved")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for _, id := range flags.Args() {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return fmt.Errorf("invalid NZB ID: %s", id)
+		}
+	}
+	if flags.NArg() > 0 {
+		query.Set("ids", strings.Join(flags.Args(), ","))
+	}
+	if *mediaID != 0 {
+		query.Set("media_id", strconv.FormatUint(*mediaID, 10))
+	}
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+	if *mismatches {
+		query.Set("mismatches", "true")
+	}
+
+	path := "/api/nzbs/audit"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var audits []titleAudit
+	if err := client.get(path, &audits); err != nil {
+		return err
+	}
+
+	return out.print(audits, func(dst io.Writer) error {
+		w := tabwriter.NewWriter(dst, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTITLE\tNORMALIZED\tQUALITY\tEPISODE\tGROUP\tMISMATCHES")
+		for _, a := range audits {
+			mismatch := strings.Join(a.Mismatches, "; ")
+			if mismatch == "" {
+				mismatch = "-"
+			}
+			group := a.Detected.Group
+			if group == "" {
+				group = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", a.NZBID, a.Title, a.Normalized, a.Detected.Quality, formatEpisode(a.Detected), group, mismatch)
+		}
+		return w.Flush()
+	})
+}
+
+// formatEpisode prints the detected season/episode of a release, e.g. S01E05 or S01 for a pack
+func formatEpisode(fields releaseFields) string {
+	switch {
+	case fields.Season != nil && fields.Episode != nil:
+		return fmt.Sprintf("S%02dE%02d", *fields.Season, *fields.Episode)
+	case fields.Season != nil:
+		return fmt.Sprintf("S%02d", *fields.Season)
+	default:
+		return "-"
+	}
+}
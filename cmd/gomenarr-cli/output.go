@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// output prints command results, as indented JSON for scripting when --json is set
+type output struct {
+	json bool
+	w    io.Writer
+}
+
+// print writes value as JSON, or calls text to write the human readable form
+func (o *output) print(value interface{}, text func(w io.Writer) error) error {
+	if !o.json {
+		return text(o.w)
+	}
+
+	encoder := json.NewEncoder(o.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// quarantineItem mirrors a quarantined download returned by /api/quarantine
+type quarantineItem struct {
+	ID      uint64
+	MediaID uint64
+	NZBID   uint64
+	Title   string
+	Release string
+	Reason  string
+	Detail  string
+	Path    string
+	Size    int64
+	At      time.Time
+}
+
+// quarantineResult is the --json output of quarantine import and purge
+type quarantineResult struct {
+	IDs    []uint64 `json:"ids"`
+	Action string   `json:"action"`
+}
+
+// quarantineCommand lists, imports or purges the downloads rejected on import
+func quarantineCommand(client *apiClient, out *output, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing quarantine subcommand (list, import or purge)")
+	}
+
+	switch args[0] {
+	case "list":
+		return quarantineList(client, out)
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("missing quarantine ID")
+		}
+		return quarantineImport(client, out, args[1])
+	case "purge":
+		if len(args) < 2 {
+			return fmt.Errorf("missing quarantine ID or --all")
+		}
+		return quarantinePurge(client, out, args[1])
+	default:
+		return fmt.Errorf("unknown quarantine subcommand: %s", args[0])
+	}
+}
+
+// quarantineList prints the quarantined downloads
+func quarantineList(client *apiClient, out *output) error {
+	var items []quarantineItem
+	if err := client.get("/api/quarantine", &items); err != nil {
+		return err
+	}
+
+	return out.print(items, func(dst io.Writer) error {
+		w := tabwriter.NewWriter(dst, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tAT\tMEDIA\tRELEASE\tREASON\tSIZE\tPATH")
+		for _, item := range items {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%.1f GB\t%s\n", item.ID, item.At.Format("2006-01-02 15:04:05"),
+				item.Title, item.Release, item.Reason, float64(item.Size)/(1<<30), item.Path)
+		}
+		return w.Flush()
+	})
+}
+
+// quarantineImport queues the import of a quarantined download despite its rejection
+func quarantineImport(client *apiClient, out *output, value string) error {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quarantine ID: %s", value)
+	}
+	if err := client.post(fmt.Sprintf("/api/quarantine/%d/import", id), nil); err != nil {
+		return err
+	}
+
+	result := quarantineResult{IDs: []uint64{id}, Action: "import queued"}
+	return out.print(result, func(w io.Writer) error {
+		fmt.Fprintf(w, "Import of quarantined download %d queued\n", id)
+		return nil
+	})
+}
+
+// quarantinePurge deletes one quarantined download, or all of them with --all
+func quarantinePurge(client *apiClient, out *output, value string) error {
+	var ids []uint64
+	if value == "--all" {
+		var items []quarantineItem
+		if err := client.get("/api/quarantine", &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+	} else {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid quarantine ID: %s", value)
+		}
+		ids = append(ids, id)
+	}
+
+	result := quarantineResult{IDs: []uint64{}, Action: "purged"}
+	for _, id := range ids {
+		if err := client.delete(fmt.Sprintf("/api/quarantine/%d", id)); err != nil {
+			return err
+		}
+		result.IDs = append(result.IDs, id)
+	}
+
+	return out.print(result, func(w io.Writer) error {
+		fmt.Fprintf(w, "%d quarantined download(s) purged\n", len(result.IDs))
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// searchHit mirrors a record returned by /api/search
+type searchHit struct {
+	Kind    string    `json:"kind"`
+	ID      uint64    `json:"id,omitempty"`
+	MediaID uint64    `json:"media_id,omitempty"`
+	Title   string    `json:"title"`
+	Status  string    `json:"status,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// searchCommand searches the media, releases and history records of the server
+func searchCommand(client *apiClient, out *output, args []string) error {
+	query := strings.TrimSpace(strings.Join(args, " "))
+	if query == "" {
+		return fmt.Errorf("missing search query")
+	}
+
+	var hits []searchHit
+	if err := client.get("/api/search?q="+url.QueryEscape(query), &hits); err != nil {
+		return err
+	}
+
+	return out.print(hits, func(dst io.Writer) error {
+		w := tabwriter.NewWriter(dst, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tID\tAT\tTITLE\tSTATUS\tDETAIL")
+		for _, hit := range hits {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", hit.Kind, hit.ID, hit.At.Format("2006-01-02 15:04:05"),
+				hit.Title, hit.Status, hit.Detail)
+		}
+		return w.Flush()
+	})
+}
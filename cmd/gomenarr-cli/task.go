@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// taskInfo mirrors the task description returned by /api/tasks
+type taskInfo struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	DependsOn []string   `json:"depends_on"`
+	Enabled   bool       `json:"enabled"`
+	Running   bool       `json:"running"`
+	Stalled   bool       `json:"stalled"`
+	LastRun   *time.Time `json:"last_run"`
+	NextRun   *time.Time `json:"next_run"`
+}
+
+// taskCommand lists the scheduled tasks or runs one on demand
+func taskCommand(client *apiClient, out *output, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing task subcommand (list, run or cancel)")
+	}
+
+	switch args[0] {
+	case "list":
+		return taskList(client, out)
+	case "run":
+		if len(args) < 2 {
+			return fmt.Errorf("missing task name")
+		}
+		withDependencies := true
+		for _, arg := range args[2:] {
+			if arg != "--no-deps" {
+				return fmt.Errorf("unknown flag: %s", arg)
+			}
+			withDependencies = false
+		}
+		return taskRun(client, out, args[1], withDependencies)
+	case "cancel":
+		if len(args) < 2 {
+			return fmt.Errorf("missing task name")
+		}
+		return taskCancel(client, out, args[1])
+	default:
+		return fmt.Errorf("unknown task subcommand: %s", args[0])
+	}
+}
+
+// taskList prints the scheduled tasks
+func taskList(client *apiClient, out *output) error {
+	var tasks []taskInfo
+	if err := client.get("/api/tasks", &tasks); err != nil {
+		return err
+	}
+
+	return out.print(tasks, func(dst io.Writer) error {
+		w := tabwriter.NewWriter(dst, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCHEDULE\tDEPENDS ON\tENABLED\tRUNNING\tLAST RUN\tNEXT RUN")
+		for _, t := range tasks {
+			lastRun := "-"
+			if t.LastRun != nil {
+				lastRun = t.LastRun.Format("2006-01-02 15:04:05")
+			}
+			nextRun := "-"
+			if t.NextRun != nil {
+				nextRun = t.NextRun.Format("2006-01-02 15:04:05")
+			}
+			dependsOn := strings.Join(t.DependsOn, ",")
+			if dependsOn == "" {
+				dependsOn = "-"
+			}
+			running := fmt.Sprint(t.Running)
+			if t.Stalled {
+				running = "stalled"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\t%s\n", t.Name, t.Schedule, dependsOn, t.Enabled, running, lastRun, nextRun)
+		}
+		return w.Flush()
+	})
+}
+
+// taskRunResult is the --json output of task run
+type taskRunResult struct {
+	Task         string `json:"task"`
+	Started      bool   `json:"started"`
+	Dependencies bool   `json:"dependencies"`
+}
+
+// taskRun starts a task on the server
+func taskRun(client *apiClient, out *output, name string, withDependencies bool) error {
+	path := "/api/tasks/" + url.PathEscape(name) + "/run"
+	if !withDependencies {
+		path += "?deps=false"
+	}
+
+	if err := client.post(path, nil); err != nil {
+		return err
+	}
+
+	result := taskRunResult{Task: name, Started: true, Dependencies: withDependencies}
+	return out.print(result, func(w io.Writer) error {
+		if withDependencies {
+			fmt.Fprintf(w, "Task %s started (after its dependencies)\n", name)
+		} else {
+			fmt.Fprintf(w, "Task %s started\n", name)
+		}
+		return nil
+	})
+}
+
+// taskCancelResult is the --json output of task cancel
+type taskCancelResult struct {
+	Task     string `json:"task"`
+	Canceled bool   `json:"canceled"`
+}
+
+// taskCancel cancels the current run of a task on the server
+func taskCancel(client *apiClient, out *output, name string) error {
+	if err := client.post("/api/tasks/"+url.PathEscape(name)+"/cancel", nil); err != nil {
+		return err
+	}
+
+	result := taskCancelResult{Task: name, Canceled: true}
+	return out.print(result, func(w io.Writer) error {
+		fmt.Fprintf(w, "Task %s canceled\n", name)
+		return nil
+	})
+}
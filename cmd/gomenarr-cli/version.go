@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/amaumene/gomenarr/internal/version"
+)
+
+// versionResult is the --json output of version
+type versionResult struct {
+	CLI         version.BuildInfo  `json:"cli"`
+	Server      *version.BuildInfo `json:"server"`
+	ServerError string             `json:"server_error,omitempty"`
+}
+
+// versionCommand prints the CLI build and, if reachable, the server build
+func versionCommand(client *apiClient, out *output) error {
+	result := versionResult{CLI: version.Info()}
+
+	var server version.BuildInfo
+	if err := client.get("/api/system/version", &server); err != nil {
+		result.ServerError = err.Error()
+	} else {
+		result.Server = &server
+	}
+
+	return out.print(result, func(w io.Writer) error {
+		fmt.Fprintln(w, "gomenarr-cli "+result.CLI.String())
+		if result.Server == nil {
+			fmt.Fprintf(w, "gomenarr server unreachable: %s\n", result.ServerError)
+			return nil
+		}
+		fmt.Fprintln(w, "gomenarr "+result.Server.String())
+		return nil
+	})
+}
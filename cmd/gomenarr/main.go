@@ -2,61 +2,164 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/api"
 	"github.com/amaumene/gomenarr/internal/config"
 	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/metrics"
 	"github.com/amaumene/gomenarr/internal/models"
 	"github.com/amaumene/gomenarr/internal/scheduler"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/amaumene/gomenarr/internal/services/mediaserver"
 	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/amaumene/gomenarr/internal/services/notify"
 	"github.com/amaumene/gomenarr/internal/services/torbox"
 	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/storage"
+	"github.com/amaumene/gomenarr/internal/tracing"
 	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/amaumene/gomenarr/internal/version"
+	"github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout bounds the wait for the HTTP server and the background deletions on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func main() {
-	if err := run(); err != nil {
+	if len(os.Args) > 1 && (os.Args[1] == "version" || os.Args[1] == "--version") {
+		fmt.Println("gomenarr " + version.Info().String())
+		return
+	}
+
+	dryRun := flag.Bool("dry-run", false, "run the pipeline without starting downloads or deleting media (same as DRY_RUN=true)")
+	logFile := flag.String("log-file", "", "write logs to this rotating file instead of stdout (same as LOG_OUTPUT=file and LOG_FILE)")
+	flag.Parse()
+
+	if err := run(*dryRun, *logFile); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
+func run(dryRun bool, logFile string) error {
 	// 1. Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	cfg.DryRun = cfg.DryRun || dryRun
+	if logFile != "" {
+		cfg.LogFile = logFile
+		if cfg.LogOutput == "stdout" {
+			cfg.LogOutput = "file"
+		}
+	}
 
 	// 2. Setup logger
 	logger := utils.NewLogger(cfg.LogLevel)
-	logger.Info("Starting Gomenarr")
-	logger.WithField("config_dir", filepath.Dir(cfg.DatabaseFile)).Info("Configuration loaded")
+	if cfg.LogOutput != "stdout" {
+		file, err := utils.OpenLogFile(utils.LogFileOptions{
+			Path:       cfg.LogFile,
+			MaxSize:    int64(cfg.LogMaxSizeMB) << 20,
+			MaxAge:     time.Duration(cfg.LogMaxAgeDays) * 24 * time.Hour,
+			MaxBackups: cfg.LogMaxBackups,
+			Compress:   cfg.LogCompress,
+		})
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if cfg.LogOutput == "both" {
+			logger.SetOutput(io.MultiWriter(os.Stdout, file))
+		} else {
+			logger.SetOutput(file)
+		}
+	}
+	buildInfo := version.Info()
+	logger.WithFields(logrus.Fields{
+		"version":    buildInfo.Version,
+		"commit":     buildInfo.Commit,
+		"build_date": buildInfo.BuildDate,
+		"go_version": buildInfo.GoVersion,
+	}).Info("Starting Gomenarr")
+	metrics.RecordBuildInfo()
+	logger.WithField("config_dir", cfg.ConfigDir).Info("Configuration loaded")
+	for _, deprecation := range cfg.Deprecations {
+		fields := logrus.Fields{"variable": deprecation.Name, "replacement": deprecation.Replacement}
+		if deprecation.Ignored {
+			logger.WithFields(fields).Warn("Deprecated variable ignored, its replacement is set too")
+			continue
+		}
+		logger.WithFields(fields).Warn("Deprecated variable, rename it (gomenarr-cli config migrate-env)")
+	}
+	if cfg.DryRun {
+		logger.Warn("Dry run enabled: releases will not be downloaded and media will not be deleted")
+	}
+	if cfg.Tracing.Endpoint != "" {
+		tracer := tracing.Init(tracing.Options{
+			Endpoint:    cfg.Tracing.Endpoint,
+			ServiceName: cfg.Tracing.ServiceName,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		}, logger)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			tracer.Shutdown(ctx)
+		}()
+	}
 
 	// 3. Initialize database
-	db, err := models.NewDatabase(cfg.DatabaseFile)
+	db, err := models.NewDatabase(cfg.DatabaseFile, logger)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer db.Close()
+	metrics.OnCollect(db.CollectMetrics)
 	logger.Info("Database initialized")
 
 	// 4. Load blacklist
-	blacklist, err := utils.LoadBlacklist(cfg.BlacklistFile)
+	store, err := storage.New(storage.Options{
+		Backend:   cfg.Storage.Backend,
+		Dir:       cfg.ConfigDir,
+		URL:       cfg.Storage.URL,
+		Prefix:    cfg.Storage.Prefix,
+		Bucket:    cfg.Storage.Bucket,
+		Region:    cfg.Storage.Region,
+		AccessKey: cfg.Storage.AccessKey,
+		SecretKey: cfg.Storage.SecretKey,
+		Username:  cfg.Storage.Username,
+		Password:  cfg.Storage.Password,
+		Token:     cfg.Storage.Token,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	logger.WithField("backend", cfg.Storage.Backend).Info("Storage initialized")
+
+	blacklist, err := utils.LoadBlacklistFrom(store, utils.BlacklistKey, logger)
 	if err != nil {
 		logger.WithError(err).Warn("Failed to load blacklist, continuing without it")
 		blacklist = &utils.Blacklist{}
 	} else {
 		logger.Info("Blacklist loaded")
 	}
+	blacklist.Subscribe(cfg.BlacklistURLs)
+
+	qualityProfiles, err := utils.NewQualityProfiles(cfg.QualityProfiles, cfg.Scoring)
+	if err != nil {
+		return fmt.Errorf("failed to load quality profiles: %w", err)
+	}
 
 	// 5. Initialize services
-	traktClient, err := trakt.NewClient(cfg, logger)
+	traktClient, err := trakt.NewClient(cfg, store, logger)
 	if err != nil {
 		return fmt.Errorf("failed to initialize Trakt client: %w", err)
 	}
@@ -72,7 +175,15 @@ func run() error {
 		}
 	}
 
-	newznabClient, err := newznab.NewClient(cfg, logger)
+	notifier, err := notify.NewDispatcher(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize notifications: %w", err)
+	}
+	if names := notifier.Notifiers(); len(names) > 0 {
+		logger.WithField("notifiers", names).Info("Notifications initialized")
+	}
+
+	newznabClient, err := newznab.NewClient(cfg, notifier, logger)
 	if err != nil {
 		return fmt.Errorf("failed to initialize Newznab client: %w", err)
 	}
@@ -84,23 +195,108 @@ func run() error {
 	}
 	logger.Info("TorBox client initialized")
 
+	hookRunner := hooks.NewRunner(cfg, db, logger)
+	if len(cfg.Hooks) > 0 {
+		logger.WithField("hooks", len(cfg.Hooks)).Info("Script hooks initialized")
+	}
+
+	mediaServer, err := mediaserver.NewClient(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize media server client: %w", err)
+	}
+	if mediaServer != nil {
+		logger.WithField("server", mediaServer.Type()).Info("Media server client initialized")
+	}
+
 	// 6. Initialize controllers
-	cleanupCtrl := controllers.NewCleanupController(db, torboxClient, traktClient, cfg.TraktSyncDays, logger)
-	syncCtrl := controllers.NewSyncController(db, traktClient, cleanupCtrl, logger)
-	strategyCtrl := controllers.NewStrategyController(db, traktClient, logger)
-	searchCtrl := controllers.NewSearchController(db, newznabClient, traktClient, blacklist, logger)
-	downloadCtrl := controllers.NewDownloadController(db, torboxClient, newznabClient, logger)
+	cleanupCtrl := controllers.NewCleanupController(db, torboxClient, traktClient, cfg.TraktSyncDays, cfg.LibraryDirs, cfg.CleanupRemoveArtifacts, controllers.WatchlistRemoval(cfg.TraktWatchlistRemoval), cfg.TraktSyncCollection, cfg.CleanupDeleteWorkers, cfg.CleanupDeleteRetries, notifier, hookRunner, cfg.DryRun, logger)
+	var traktLists []controllers.TraktList
+	for _, list := range cfg.TraktLists {
+		traktLists = append(traktLists, controllers.TraktList{
+			Name:     list.Name,
+			Path:     list.Path,
+			Types:    list.Types,
+			Strategy: models.EpisodeStrategy(list.Strategy),
+		})
+	}
+	syncCtrl := controllers.NewSyncController(db, traktClient, cleanupCtrl, cfg.RedownloadWatched, traktLists, cfg.TraktMetadataLanguage, cfg.TraktCollectionSources, notifier, logger)
+	strategyCtrl := controllers.NewStrategyController(db, traktClient, cfg.RedownloadWatched, time.Duration(cfg.AirOffsetMinutes)*time.Minute, cfg.TraktCollectionSources, logger)
+	searchCtrl := controllers.NewSearchController(db, newznabClient, traktClient, blacklist, qualityProfiles, cfg.BackfillConcurrency, cfg.SearchWidenAfter, logger)
+	downloadParams := controllers.DownloadParamTemplates{
+		Default: cfg.TorBoxDownloadParams,
+		Movie:   cfg.TorBoxDownloadParamsMovie,
+		TV:      cfg.TorBoxDownloadParamsTV,
+	}
+	renamer, err := controllers.NewRenamer(controllers.RenameOptions{
+		Mode:            cfg.RenameMode,
+		DownloadDir:     cfg.DownloadDir,
+		MoviesDir:       cfg.RenameMoviesDir,
+		ShowsDir:        cfg.RenameShowsDir,
+		MovieTemplate:   cfg.RenameMovieTemplate,
+		EpisodeTemplate: cfg.RenameEpisodeTemplate,
+		QuarantineDir:   cfg.QuarantineDir,
+	}, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize renamer: %w", err)
+	}
+	var collection *trakt.Client
+	if cfg.TraktSyncCollection {
+		collection = traktClient
+	}
+	importCtrl := controllers.NewImportController(db, renamer, cfg.LibraryDirs, mediaServer, cfg.ImportConcurrency, time.Duration(cfg.MediaServerRefreshInterval)*time.Second, hookRunner, collection, traktClient, logger)
+	var quotas []controllers.StorageQuota
+	for _, quota := range cfg.StorageQuotas {
+		quotas = append(quotas, controllers.StorageQuota{
+			Name:    quota.Name,
+			Library: quota.Library,
+			Limit:   int64(quota.MaxGB * (1 << 30)),
+			Policy:  controllers.QuotaPolicy(quota.Policy),
+		})
+	}
+	retryPolicy := controllers.RetryPolicy{MaxRetries: cfg.DownloadMaxRetries, Backoff: cfg.DownloadRetryBackoff}
+	diskGuard := controllers.DiskGuard{Path: cfg.DownloadDiskPath, MinFree: int64(cfg.DownloadMinFreeGB) << 30}
+	downloadCtrl := controllers.NewDownloadController(db, torboxClient, newznabClient, downloadParams, importCtrl, cleanupCtrl, quotas, diskGuard, time.Duration(cfg.SearchCandidateTTLHours)*time.Hour, retryPolicy, notifier, hookRunner, cfg.DryRun, logger)
+	importCtrl.VerifyWith(downloadCtrl)
+	libraryCtrl := controllers.NewLibraryController(db, cfg.LibraryDirs, logger)
+	mediaCtrl := controllers.NewMediaController(db, traktClient, cleanupCtrl, cfg.RedownloadWatched, notifier, logger)
 	logger.Info("Controllers initialized")
 
 	// 7. Initialize scheduler
-	sched := scheduler.NewScheduler(syncCtrl, strategyCtrl, searchCtrl, downloadCtrl, cleanupCtrl, db, cfg.DownloadTimeoutMinutes, logger)
+	taskOptions := scheduler.TaskOptions{
+		Disabled:         cfg.TasksDisabled,
+		Schedules:        cfg.TaskSchedules,
+		Startup:          cfg.StartupTasks,
+		RecoveryMaxAge:   time.Duration(cfg.RecoveryMaxAgeHours) * time.Hour,
+		GapFillShows:     cfg.GapFillShows,
+		RSS:              cfg.SearchRSSEnabled,
+		MediaTimeout:     time.Duration(cfg.MediaTimeoutSeconds) * time.Second,
+		StallAfter:       time.Duration(cfg.TaskStallMinutes) * time.Minute,
+		MaintenanceTasks: cfg.MaintenanceTasks,
+		IdleInterval:     time.Duration(cfg.IdleIntervalHours) * time.Hour,
+		IdleTasks:        cfg.IdleTasks,
+	}
+	for _, window := range cfg.MaintenanceWindows {
+		taskOptions.Maintenance = append(taskOptions.Maintenance, scheduler.MaintenanceWindow{
+			Start:    window.Start,
+			Duration: window.Duration,
+		})
+	}
+	grabLimits := scheduler.GrabLimits{
+		ColdStart:   cfg.ColdStartGrabs,
+		MaxPerCycle: cfg.MaxGrabsPerCycle,
+	}
+	searchBackoff := scheduler.SearchBackoff{
+		Base: time.Duration(cfg.SearchBackoffMinutes) * time.Minute,
+		Max:  time.Duration(cfg.SearchBackoffMaxMinutes) * time.Minute,
+	}
+	sched := scheduler.NewScheduler(syncCtrl, strategyCtrl, searchCtrl, downloadCtrl, cleanupCtrl, libraryCtrl, traktClient, db, blacklist, cfg.DownloadTimeoutMinutes, cfg.UpgradeEnabled, cfg.SeasonPackUpgrade, taskOptions, grabLimits, searchBackoff, notifier, hookRunner, logger)
 	if err := sched.Start(); err != nil {
 		return fmt.Errorf("failed to start scheduler: %w", err)
 	}
 	defer sched.Stop()
 
 	// 8. Initialize HTTP server
-	server := api.NewServer(cfg, db, downloadCtrl, logger)
+	server := api.NewServer(cfg, db, downloadCtrl, mediaCtrl, sched, sched, sched, sched, sched, importCtrl, traktClient, blacklist, notifier, hookRunner, logger)
 
 	// Start server in goroutine
 	ctx, cancel := context.WithCancel(context.Background())
@@ -125,9 +321,17 @@ func run() error {
 	case sig := <-sigChan:
 		logger.WithField("signal", sig).Info("Received shutdown signal")
 		cancel()
-		if err := server.Shutdown(context.Background()); err != nil {
-			logger.WithError(err).Error("Error during server shutdown")
-		}
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.WithError(err).Error("Error during server shutdown")
+	}
+
+	// Removed media have no records left, their TorBox jobs would be orphaned
+	if err := cleanupCtrl.WaitDeletions(shutdownCtx); err != nil {
+		logger.WithError(err).Warn("Stopped before the TorBox job deletions finished, their jobs may be left on TorBox")
 	}
 
 	logger.Info("Gomenarr stopped")
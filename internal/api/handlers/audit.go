@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultAuditTitles is the number of recent releases audited without ?ids= or ?media_id=
+const defaultAuditTitles = 50
+
+// AuditHandler runs stored release titles through the current parsing rules
+type AuditHandler struct {
+	db     *models.Database
+	logger *logrus.Logger
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(db *models.Database, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Titles handles GET /api/nzbs/audit
+// The releases are picked with ?ids=1,2,3, ?media_id= or the most recent ?limit= (default 50).
+// ?mismatches=true only returns the releases whose stored fields are parsed differently now.
+func (h *AuditHandler) Titles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var nzbs []*models.NZB
+	switch {
+	case query.Get("ids") != "":
+		for _, value := range strings.Split(query.Get("ids"), ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid NZB ID: "+value, http.StatusBadRequest)
+				return
+			}
+			nzb, err := h.db.GetNZBByID(id)
+			if err != nil {
+				http.Error(w, "NZB not found: "+value, http.StatusNotFound)
+				return
+			}
+			nzbs = append(nzbs, nzb)
+		}
+
+	case query.Get("media_id") != "":
+		mediaID, err := strconv.ParseUint(query.Get("media_id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid media ID", http.StatusBadRequest)
+			return
+		}
+		nzbs, err = h.db.GetNZBsByMediaID(mediaID)
+		if err != nil {
+			h.logger.WithError(err).WithField("media_id", mediaID).Error("Failed to get NZBs")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		limit := defaultAuditTitles
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		var err error
+		nzbs, err = h.db.GetRecentNZBs(limit)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get recent NZBs")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	onlyMismatches := query.Get("mismatches") == "true"
+	audits := make([]controllers.TitleAudit, 0, len(nzbs))
+	for _, nzb := range nzbs {
+		audit := controllers.AuditTitle(nzb)
+		if onlyMismatches && len(audit.Mismatches) == 0 {
+			continue
+		}
+		audits = append(audits, audit)
+	}
+
+	writeJSON(w, http.StatusOK, audits)
+}
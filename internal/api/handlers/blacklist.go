@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// maxImportSize bounds the size of an imported blacklist
+const maxImportSize = 10 << 20
+
+// BlacklistHandler manages the release blacklist
+type BlacklistHandler struct {
+	blacklist *utils.Blacklist
+	logger    *logrus.Logger
+}
+
+// NewBlacklistHandler creates a new blacklist handler
+func NewBlacklistHandler(blacklist *utils.Blacklist, logger *logrus.Logger) *BlacklistHandler {
+	return &BlacklistHandler{
+		blacklist: blacklist,
+		logger:    logger,
+	}
+}
+
+// List handles GET /api/blacklist
+func (h *BlacklistHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]utils.BlacklistEntry{
+		"local":  h.blacklist.Entries(),
+		"remote": h.blacklist.RemoteEntries(),
+	})
+}
+
+// Add handles POST /api/blacklist with a {"term": "...", "regex": false, "scope": "", "required": false} payload
+// The scope is empty (global), "movies", "shows" or the IMDB ID of a show. Required terms
+// need a show scope, the releases of the show not matching them are blacklisted.
+func (h *BlacklistHandler) Add(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Term     string `json:"term"`
+		Regex    bool   `json:"regex"`
+		Scope    string `json:"scope"`
+		Required bool   `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Term) == "" {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	newEntry := utils.NewBlacklistEntry
+	if req.Required {
+		newEntry = utils.NewRequiredEntry
+	}
+	entry, err := newEntry(req.Term, req.Regex, req.Scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.blacklist.AddEntries(entry)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to save blacklist")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if added == 0 {
+		http.Error(w, "Term already blacklisted", http.StatusConflict)
+		return
+	}
+
+	h.logger.WithField("term", entry.String()).Info("Added blacklist term")
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// Delete handles DELETE /api/blacklist/{term} with the term in the line format,
+// e.g. "[movies] /cam(rip)?/" or "[tt0903747] +AMZN" URL-encoded
+func (h *BlacklistHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	term := r.PathValue("term")
+
+	removed, err := h.blacklist.Remove(term)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to save blacklist")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.Error(w, "Term not found", http.StatusNotFound)
+		return
+	}
+
+	h.logger.WithField("term", term).Info("Removed blacklist term")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reload handles POST /api/blacklist/reload, reading the local terms from the storage backend again
+func (h *BlacklistHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := h.blacklist.Reload(r.Context()); err != nil {
+		h.logger.WithError(err).Error("Failed to reload blacklist")
+		http.Error(w, "Failed to reload blacklist: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	total := len(h.blacklist.Entries())
+	h.logger.WithField("total", total).Info("Reloaded blacklist")
+	writeJSON(w, http.StatusOK, map[string]int{"total": total})
+}
+
+// Export handles GET /api/blacklist/export, one term per line
+func (h *BlacklistHandler) Export(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="blacklist.txt"`)
+	if err := h.blacklist.Export(w); err != nil {
+		h.logger.WithError(err).Warn("Failed to export blacklist")
+	}
+}
+
+// Import handles POST /api/blacklist/import with a list in the line format
+// Terms are merged with the local ones, ?replace=true replaces them instead.
+func (h *BlacklistHandler) Import(w http.ResponseWriter, r *http.Request) {
+	entries, err := utils.ParseBlacklist(http.MaxBytesReader(w, r.Body, maxImportSize), h.logger)
+	if err != nil {
+		http.Error(w, "Invalid blacklist: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var added int
+	if r.URL.Query().Get("replace") == "true" {
+		added, err = h.blacklist.Replace(entries)
+	} else {
+		added, err = h.blacklist.AddEntries(entries...)
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to save blacklist")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"received": len(entries),
+		"added":    added,
+	}).Info("Imported blacklist")
+
+	writeJSON(w, http.StatusOK, map[string]int{
+		"received": len(entries),
+		"added":    added,
+		"total":    len(h.blacklist.Entries()),
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// eventHeartbeat is how often an idle stream sends a comment, keeping proxies from closing it
+const eventHeartbeat = 30 * time.Second
+
+// EventSource delivers live activity events
+type EventSource interface {
+	Subscribe() (<-chan notify.Event, func())
+}
+
+// EventsHandler streams live activity as Server-Sent Events
+type EventsHandler struct {
+	source    EventSource
+	done      chan struct{}
+	closeOnce sync.Once
+	logger    *logrus.Logger
+}
+
+// NewEventsHandler creates a new events handler
+func NewEventsHandler(source EventSource, logger *logrus.Logger) *EventsHandler {
+	return &EventsHandler{
+		source: source,
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+}
+
+// Close ends the open streams, so they don't hold up a server shutdown
+func (h *EventsHandler) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
+
+// Stream handles GET /api/events/stream
+// Each event is sent with its type as the SSE event name and its JSON payload as data.
+// ?types=download.completed,task.completed limits the stream to these event types.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var types map[notify.EventType]bool
+	if param := r.URL.Query().Get("types"); param != "" {
+		types = make(map[notify.EventType]bool)
+		for _, eventType := range strings.Split(param, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				types[notify.EventType(eventType)] = true
+			}
+		}
+	}
+
+	// The stream outlives the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Error("Event stream not supported by the response writer")
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := h.source.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	h.logger.WithField("remote_addr", r.RemoteAddr).Debug("Event stream opened")
+	defer h.logger.WithField("remote_addr", r.RemoteAddr).Debug("Event stream closed")
+
+	// Send the headers right away so clients know the stream is open
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.done:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if types != nil && !types[event.Type] {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.WithError(err).WithField("event", event.Type).Warn("Failed to encode stream event")
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
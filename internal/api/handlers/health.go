@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/amaumene/gomenarr/internal/services/httpclient"
 	"github.com/sirupsen/logrus"
 )
 
@@ -31,3 +32,26 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// DetailedHealthResponse represents the detailed health check response
+// Status is "degraded" while an external API host has its circuit breaker open.
+type DetailedHealthResponse struct {
+	Status   string                   `json:"status"`
+	Breakers []httpclient.BreakerInfo `json:"breakers"`
+}
+
+// Detailed handles the detailed health check endpoint
+func (h *HealthHandler) Detailed(w http.ResponseWriter, r *http.Request) {
+	response := DetailedHealthResponse{
+		Status:   "healthy",
+		Breakers: httpclient.Breakers(),
+	}
+	for _, breaker := range response.Breakers {
+		if breaker.State != httpclient.BreakerClosed {
+			response.Status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultHookRuns is the number of hook runs listed without ?limit=
+const defaultHookRuns = 50
+
+// HookHandler exposes the configured script hooks and their run history
+type HookHandler struct {
+	db     *models.Database
+	runner *hooks.Runner
+	logger *logrus.Logger
+}
+
+// NewHookHandler creates a new hook handler
+func NewHookHandler(db *models.Database, runner *hooks.Runner, logger *logrus.Logger) *HookHandler {
+	return &HookHandler{
+		db:     db,
+		runner: runner,
+		logger: logger,
+	}
+}
+
+// hookResponse describes a configured hook
+type hookResponse struct {
+	Name    string `json:"name"`
+	Stage   string `json:"stage"`
+	Command string `json:"command"`
+	Timeout int    `json:"timeout_seconds"`
+	Policy  string `json:"policy"`
+}
+
+// List handles GET /api/hooks
+func (h *HookHandler) List(w http.ResponseWriter, r *http.Request) {
+	response := []hookResponse{}
+	for _, hook := range h.runner.Hooks() {
+		policy := "ignore"
+		if hook.Abort {
+			policy = "abort"
+		}
+		response = append(response, hookResponse{
+			Name:    hook.Name,
+			Stage:   string(hook.Stage),
+			Command: hook.Command,
+			Timeout: int(hook.Timeout.Seconds()),
+			Policy:  policy,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Runs handles GET /api/hooks/runs, newest first, with an optional ?limit=
+func (h *HookHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	limit := defaultHookRuns
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.db.GetHookRuns(limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get hook runs")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, runs)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// LibraryHandler exposes the files found by the library scan
+type LibraryHandler struct {
+	db     *models.Database
+	logger *logrus.Logger
+}
+
+// NewLibraryHandler creates a new library handler
+func NewLibraryHandler(db *models.Database, logger *logrus.Logger) *LibraryHandler {
+	return &LibraryHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List handles GET /api/library, optionally filtered with ?imdb_id=
+// Scans run daily, or on demand with POST /api/tasks/library_scan/run.
+func (h *LibraryHandler) List(w http.ResponseWriter, r *http.Request) {
+	files, err := h.db.GetLibraryFiles(r.URL.Query().Get("imdb_id"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get library files")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, files)
+}
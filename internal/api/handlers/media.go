@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// MediaSearcher runs immediate and interactive searches for a media item
+type MediaSearcher interface {
+	ProcessMedia(ctx context.Context, media *models.Media) error
+	NextSearch(media *models.Media) time.Time
+	Candidates(ctx context.Context, media *models.Media) ([]*models.NZB, error)
+	DownloadRelease(nzb *models.NZB) error
+}
+
+// MediaHandler handles manual media management
+type MediaHandler struct {
+	db        *models.Database
+	mediaCtrl *controllers.MediaController
+	searcher  MediaSearcher
+	tasks     TaskRunner
+	grabRamp  GrabRamp
+	logger    *logrus.Logger
+}
+
+// NewMediaHandler creates a new media handler
+func NewMediaHandler(db *models.Database, mediaCtrl *controllers.MediaController, searcher MediaSearcher, tasks TaskRunner, grabRamp GrabRamp, logger *logrus.Logger) *MediaHandler {
+	return &MediaHandler{
+		db:        db,
+		mediaCtrl: mediaCtrl,
+		searcher:  searcher,
+		tasks:     tasks,
+		grabRamp:  grabRamp,
+		logger:    logger,
+	}
+}
+
+// List handles GET /api/media
+func (h *MediaHandler) List(w http.ResponseWriter, r *http.Request) {
+	medias, err := h.db.GetAllMedias()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get medias")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, medias)
+}
+
+// Get handles GET /api/media/{id}
+func (h *MediaHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := h.db.GetMediaByID(id)
+	if err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, media)
+}
+
+// Add handles POST /api/media
+func (h *MediaHandler) Add(w http.ResponseWriter, r *http.Request) {
+	var req controllers.AddMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	media, err := h.mediaCtrl.AddMedia(r.Context(), req)
+	switch {
+	case errors.Is(err, controllers.ErrMediaExists):
+		writeJSON(w, http.StatusConflict, media)
+		return
+	case errors.Is(err, controllers.ErrInvalidMedia):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, controllers.ErrMediaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to add media")
+		http.Error(w, "Failed to add media", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, media)
+}
+
+// Import handles POST /api/media/import, adding movies and shows by IMDB or TMDB ID
+// The batch runs in the background: the response holds its ID, polled on GET /api/imports/{id}
+// for the outcome of every ID. A search cycle is started for the added media, ramping up the
+// grabs when the batch is large.
+func (h *MediaHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var req controllers.BatchAddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.mediaCtrl.StartMediaBatch(req, h.searchAdded)
+	switch {
+	case errors.Is(err, controllers.ErrInvalidMedia):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to import media")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// ImportStatus handles GET /api/imports/{id}, the progress and report of a batch
+func (h *MediaHandler) ImportStatus(w http.ResponseWriter, r *http.Request) {
+	job, err := h.mediaCtrl.MediaBatch(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// searchAdded starts a search cycle for the media added by a batch, reporting whether the grabs are ramped up
+func (h *MediaHandler) searchAdded(added int) bool {
+	rampUp, err := h.grabRamp.RampUpBacklog(added)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to ramp up grabs")
+	}
+
+	if err := h.tasks.RunTask(scheduler.TaskSearch, false); err != nil && !errors.Is(err, scheduler.ErrTaskRunning) {
+		h.logger.WithError(err).Warn("Failed to start search, the added media wait for the next cycle")
+	}
+	return rampUp
+}
+
+// Delete handles DELETE /api/media/{id}
+func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.mediaCtrl.RemoveMedia(id); err != nil {
+		if errors.Is(err, controllers.ErrMediaNotFound) {
+			http.Error(w, "Media not found", http.StatusNotFound)
+			return
+		}
+		h.logger.WithError(err).WithField("media_id", id).Error("Failed to remove media")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Search handles POST /api/media/{id}/search
+// The search runs in the background, the response is sent once it is queued.
+func (h *MediaHandler) Search(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := h.mediaCtrl.PrepareSearch(id)
+	switch {
+	case errors.Is(err, controllers.ErrMediaNotFound):
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	case errors.Is(err, controllers.ErrMediaBusy):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("media_id", id).Error("Failed to prepare search")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := h.searcher.ProcessMedia(context.Background(), media); err != nil {
+			h.logger.WithError(err).WithField("media_id", media.ID).Warn("Forced search skipped")
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, media)
+}
+
+// Searches handles GET /api/media/{id}/searches
+func (h *MediaHandler) Searches(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := h.db.GetMediaByID(id)
+	if err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	history, err := h.mediaCtrl.SearchHistory(media)
+	if err != nil {
+		h.logger.WithError(err).WithField("media_id", id).Error("Failed to get search history")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if next := h.searcher.NextSearch(media); next.After(time.Now()) {
+		history.NextSearchAt = &next
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// Rejections handles GET /api/media/{id}/rejections
+// Lists the releases the last searches dropped and why, e.g. to tune the quality profiles.
+func (h *MediaHandler) Rejections(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := h.db.GetMediaByID(id)
+	if err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	report, err := h.mediaCtrl.Rejections(media)
+	if err != nil {
+		h.logger.WithError(err).WithField("media_id", id).Error("Failed to get rejections")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Candidates handles GET /api/media/{id}/candidates
+// The indexers are searched live, the releases are returned best first without downloading.
+func (h *MediaHandler) Candidates(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	media, err := h.db.GetMediaByID(id)
+	if err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	nzbs, err := h.searcher.Candidates(r.Context(), media)
+	switch {
+	case errors.Is(err, controllers.ErrMediaBusy):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("media_id", id).Error("Interactive search failed")
+		http.Error(w, "Search failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if nzbs == nil {
+		nzbs = []*models.NZB{}
+	}
+
+	writeJSON(w, http.StatusOK, nzbs)
+}
+
+// DownloadRelease handles POST /api/nzbs/{id}/download
+// The download starts before the response is sent, its progress follows the usual webhooks.
+func (h *MediaHandler) DownloadRelease(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid NZB ID", http.StatusBadRequest)
+		return
+	}
+
+	nzb, err := h.db.GetNZBByID(id)
+	if err != nil {
+		http.Error(w, "NZB not found", http.StatusNotFound)
+		return
+	}
+
+	err = h.searcher.DownloadRelease(nzb)
+	switch {
+	case errors.Is(err, controllers.ErrMediaNotFound):
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	case errors.Is(err, controllers.ErrMediaBusy), errors.Is(err, controllers.ErrReleaseUnavailable), errors.Is(err, controllers.ErrQuotaExceeded),
+		errors.Is(err, controllers.ErrAlreadyCovered):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("nzb_id", id).Error("Manual download failed")
+		http.Error(w, "Download failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, nzb)
+}
+
+// ShowStats handles GET /api/shows/{id}/stats
+func (h *MediaHandler) ShowStats(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.mediaCtrl.ShowStats(r.Context(), id)
+	switch {
+	case errors.Is(err, controllers.ErrMediaNotFound):
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	case errors.Is(err, controllers.ErrInvalidMedia):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("media_id", id).Error("Failed to compute show stats")
+		http.Error(w, "Failed to compute show stats", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// ShowGaps handles GET /api/shows/{id}/gaps
+func (h *MediaHandler) ShowGaps(w http.ResponseWriter, r *http.Request) {
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	gaps, err := h.mediaCtrl.ShowGaps(r.Context(), id)
+	switch {
+	case errors.Is(err, controllers.ErrMediaNotFound):
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	case errors.Is(err, controllers.ErrInvalidMedia):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("media_id", id).Error("Failed to compute show gaps")
+		http.Error(w, "Failed to compute show gaps", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, gaps)
+}
+
+// GapReport handles GET /api/shows/gaps, the shows with missing episodes
+func (h *MediaHandler) GapReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.mediaCtrl.GapReport(r.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to compute gap report")
+		http.Error(w, "Failed to compute gap report", http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// mediaID parses the {id} path value, writing a 400 response if it is invalid
+func mediaID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeJSON writes a JSON response with a status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// Orchestrator pauses and resumes the automatic tasks
+type Orchestrator interface {
+	Orchestrator() scheduler.OrchestratorInfo
+	Pause(reason string) error
+	Resume() error
+}
+
+// OrchestratorHandler exposes the pause controls of the scheduler
+type OrchestratorHandler struct {
+	orchestrator Orchestrator
+	logger       *logrus.Logger
+}
+
+// NewOrchestratorHandler creates a new orchestrator handler
+func NewOrchestratorHandler(orchestrator Orchestrator, logger *logrus.Logger) *OrchestratorHandler {
+	return &OrchestratorHandler{
+		orchestrator: orchestrator,
+		logger:       logger,
+	}
+}
+
+// pauseRequest is the optional body of POST /api/orchestrator/pause
+type pauseRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Get handles GET /api/orchestrator
+func (h *OrchestratorHandler) Get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.orchestrator.Orchestrator())
+}
+
+// Pause handles POST /api/orchestrator/pause, with an optional {"reason": "..."} body
+func (h *OrchestratorHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	var req pauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.orchestrator.Pause(req.Reason)
+	switch {
+	case errors.Is(err, scheduler.ErrAlreadyPaused):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to pause scheduler")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.orchestrator.Orchestrator())
+}
+
+// Resume handles POST /api/orchestrator/resume
+func (h *OrchestratorHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	err := h.orchestrator.Resume()
+	switch {
+	case errors.Is(err, scheduler.ErrNotPaused):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to resume scheduler")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.orchestrator.Orchestrator())
+}
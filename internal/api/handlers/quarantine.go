@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Quarantine manages the downloads rejected on import
+type Quarantine interface {
+	Quarantined() ([]*models.QuarantineItem, error)
+	ForceImport(id uint64) error
+	PurgeQuarantined(id uint64) error
+}
+
+// QuarantineHandler lists, force imports and purges quarantined downloads
+type QuarantineHandler struct {
+	quarantine Quarantine
+	logger     *logrus.Logger
+}
+
+// NewQuarantineHandler creates a new quarantine handler
+func NewQuarantineHandler(quarantine Quarantine, logger *logrus.Logger) *QuarantineHandler {
+	return &QuarantineHandler{
+		quarantine: quarantine,
+		logger:     logger,
+	}
+}
+
+// List handles GET /api/quarantine, newest first
+func (h *QuarantineHandler) List(w http.ResponseWriter, r *http.Request) {
+	items, err := h.quarantine.Quarantined()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get quarantined downloads")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Import handles POST /api/quarantine/{id}/import, the import runs in the background
+func (h *QuarantineHandler) Import(w http.ResponseWriter, r *http.Request) {
+	id, ok := quarantineID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.quarantine.ForceImport(id)
+	switch {
+	case errors.Is(err, controllers.ErrQuarantineNotFound), errors.Is(err, controllers.ErrMediaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "import queued"})
+}
+
+// Purge handles DELETE /api/quarantine/{id}
+func (h *QuarantineHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	id, ok := quarantineID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.quarantine.PurgeQuarantined(id)
+	switch {
+	case errors.Is(err, controllers.ErrQuarantineNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("id", id).Error("Failed to purge quarantined download")
+		http.Error(w, "Failed to purge quarantined download", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// quarantineID reads the quarantined download ID from the path
+func quarantineID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid quarantine ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultQuotaActions is the number of storage quota actions listed without ?limit=
+const defaultQuotaActions = 50
+
+// QuotaHandler exposes the library storage quotas and the grabs they held back
+type QuotaHandler struct {
+	db           *models.Database
+	downloadCtrl *controllers.DownloadController
+	logger       *logrus.Logger
+}
+
+// NewQuotaHandler creates a new storage quota handler
+func NewQuotaHandler(db *models.Database, downloadCtrl *controllers.DownloadController, logger *logrus.Logger) *QuotaHandler {
+	return &QuotaHandler{
+		db:           db,
+		downloadCtrl: downloadCtrl,
+		logger:       logger,
+	}
+}
+
+// List handles GET /api/quotas
+func (h *QuotaHandler) List(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.downloadCtrl.QuotaUsage()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get storage quota usage")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// Actions handles GET /api/quotas/actions, newest first, with an optional ?limit=
+func (h *QuotaHandler) Actions(w http.ResponseWriter, r *http.Request) {
+	limit := defaultQuotaActions
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	actions, err := h.db.GetQuotaActions(limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get storage quota actions")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if actions == nil {
+		actions = []*models.QuotaAction{}
+	}
+
+	writeJSON(w, http.StatusOK, actions)
+}
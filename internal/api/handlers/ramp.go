@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// GrabRamp reports and lifts the cold-start grab limit
+type GrabRamp interface {
+	GrabRamp() (scheduler.GrabRampInfo, error)
+	ConfirmGrabRamp() error
+	RampUpBacklog(count int) (bool, error)
+}
+
+// RampHandler exposes the cold-start protection
+type RampHandler struct {
+	ramp   GrabRamp
+	logger *logrus.Logger
+}
+
+// NewRampHandler creates a new ramp handler
+func NewRampHandler(ramp GrabRamp, logger *logrus.Logger) *RampHandler {
+	return &RampHandler{
+		ramp:   ramp,
+		logger: logger,
+	}
+}
+
+// Get handles GET /api/grabs/ramp
+func (h *RampHandler) Get(w http.ResponseWriter, r *http.Request) {
+	info, err := h.ramp.GrabRamp()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cold-start state")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// Confirm handles POST /api/grabs/ramp/confirm, lifting the ramp-up limit
+func (h *RampHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	err := h.ramp.ConfirmGrabRamp()
+	switch {
+	case errors.Is(err, scheduler.ErrRampInactive):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to lift cold-start limit")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	info, err := h.ramp.GrabRamp()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cold-start state")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
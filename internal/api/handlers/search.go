@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/sirupsen/logrus"
+)
+
+// SearchHandler searches the media, releases and history records by text
+type SearchHandler struct {
+	index  *controllers.HistoryIndex
+	logger *logrus.Logger
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(index *controllers.HistoryIndex, logger *logrus.Logger) *SearchHandler {
+	return &SearchHandler{
+		index:  index,
+		logger: logger,
+	}
+}
+
+// Search handles GET /api/search?q=the bear s03, with an optional ?limit= (default 50)
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	hits, err := h.index.Search(query, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search history")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hits)
+}
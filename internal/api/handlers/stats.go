@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultAvailabilityDays is the period of the availability stats without ?days=
+const defaultAvailabilityDays = 90
+
+// StatsHandler exposes statistics over the imported releases
+type StatsHandler struct {
+	db     *models.Database
+	logger *logrus.Logger
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(db *models.Database, logger *logrus.Logger) *StatsHandler {
+	return &StatsHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Availability handles GET /api/stats/availability
+// Reports how long after their air or release date releases were available and imported,
+// by indexer, resolution and source. ?days= sets the period (0 for all), ?media_type=
+// keeps movies or tv, ?upgrades=true includes quality upgrades.
+func (h *StatsHandler) Availability(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	days := defaultAvailabilityDays
+	if value := query.Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+	mediaType := models.MediaType(query.Get("media_type"))
+	if mediaType != "" && mediaType != models.MediaTypeMovie && mediaType != models.MediaTypeTV {
+		http.Error(w, "Invalid media_type", http.StatusBadRequest)
+		return
+	}
+	upgrades := query.Get("upgrades") == "true"
+
+	var since time.Time
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	records, err := h.db.GetAvailabilityRecords(since)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get availability records")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var kept []*models.AvailabilityRecord
+	for _, record := range records {
+		if (mediaType != "" && record.MediaType != mediaType) || (record.Upgrade && !upgrades) {
+			continue
+		}
+		kept = append(kept, record)
+	}
+
+	writeJSON(w, http.StatusOK, controllers.SummarizeAvailability(kept))
+}
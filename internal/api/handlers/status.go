@@ -3,25 +3,48 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/scheduler"
+	"github.com/amaumene/gomenarr/internal/services/httpclient"
+	"github.com/amaumene/gomenarr/internal/version"
 	"github.com/sirupsen/logrus"
 )
 
+// statusCycles are the task cycles shown by the status page
+var statusCycles = []string{"sync", "search", "cleanup"}
+
+// CycleSource reports the outcome of the scheduler task cycles
+type CycleSource interface {
+	Cycles() map[string]scheduler.CycleStatus
+}
+
 // StatusHandler handles status requests
+// The status is public: it holds counts and times only, no titles or host names.
 type StatusHandler struct {
 	db     *models.Database
+	cycles CycleSource
 	logger *logrus.Logger
 }
 
 // NewStatusHandler creates a new status handler
-func NewStatusHandler(db *models.Database, logger *logrus.Logger) *StatusHandler {
+func NewStatusHandler(db *models.Database, cycles CycleSource, logger *logrus.Logger) *StatusHandler {
 	return &StatusHandler{
 		db:     db,
+		cycles: cycles,
 		logger: logger,
 	}
 }
 
+// StatusBreaker is an external service whose circuit breaker isn't closed
+type StatusBreaker struct {
+	Service string     `json:"service"`
+	State   string     `json:"state"`
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
 // StatusResponse represents the status response
 type StatusResponse struct {
 	TotalMedias     int            `json:"total_medias"`
@@ -32,6 +55,11 @@ type StatusResponse struct {
 	Failed          int            `json:"failed"`
 	MediasByType    map[string]int `json:"medias_by_type"`
 	MediasBySource  map[string]int `json:"medias_by_source"`
+
+	Version      string                           `json:"version"`
+	QueueDepth   int                              `json:"queue_depth"` // Media pending, searching or downloading
+	Cycles       map[string]scheduler.CycleStatus `json:"cycles"`      // Last sync, search and cleanup runs
+	OpenBreakers []StatusBreaker                  `json:"open_breakers"`
 }
 
 // ServeHTTP handles the status endpoint
@@ -52,6 +80,9 @@ func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		TotalMedias:    len(medias),
 		MediasByType:   make(map[string]int),
 		MediasBySource: make(map[string]int),
+		Version:        version.Info().Version,
+		Cycles:         make(map[string]scheduler.CycleStatus),
+		OpenBreakers:   []StatusBreaker{},
 	}
 
 	for _, media := range medias {
@@ -76,6 +107,31 @@ func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		response.MediasBySource[string(media.Source)]++
 	}
 
+	response.QueueDepth = response.Pending + response.Searching + response.Downloading
+
+	cycles := h.cycles.Cycles()
+	for _, name := range statusCycles {
+		if status, ok := cycles[name]; ok {
+			response.Cycles[name] = status
+		}
+	}
+
+	for _, breaker := range httpclient.Breakers() {
+		if breaker.State != httpclient.BreakerClosed {
+			response.OpenBreakers = append(response.OpenBreakers, StatusBreaker{
+				Service: breaker.Service,
+				State:   breaker.State,
+				RetryAt: breaker.RetryAt,
+			})
+		}
+	}
+
+	// Browsers get the status page, API clients the JSON
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		h.renderPage(w, response)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
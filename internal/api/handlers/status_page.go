@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/scheduler"
+)
+
+// statusPage is the human readable version of GET /status
+var statusPage = template.Must(template.New("status").Funcs(template.FuncMap{
+	"since": func(t *time.Time) string {
+		if t == nil {
+			return "not since startup"
+		}
+		return time.Since(*t).Round(time.Minute).String() + " ago"
+	},
+	"cycle": func(cycles map[string]scheduler.CycleStatus, name string) *scheduler.CycleStatus {
+		if status, ok := cycles[name]; ok {
+			return &status
+		}
+		return nil
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="60">
+<title>gomenarr status</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; }
+td { padding: 0.2em 1em 0.2em 0; }
+.ok { color: green; } .warn { color: darkorange; } .bad { color: firebrick; }
+</style>
+</head>
+<body>
+<h1>gomenarr</h1>
+{{if .OpenBreakers}}<p class="bad">Some services are unreachable, downloads may be delayed.</p>{{else}}<p class="ok">All services reachable.</p>{{end}}
+<h2>Tasks</h2>
+<table>
+{{range $name := .CycleNames}}{{with cycle $.Cycles $name}}<tr><td>{{$name}}</td><td class="{{if .Failures}}warn{{else}}ok{{end}}">last success {{since .LastSuccess}}</td><td>{{if .Failures}}{{.Failures}} failures in the last run{{end}}</td></tr>
+{{else}}<tr><td>{{$name}}</td><td>not run since startup</td><td></td></tr>
+{{end}}{{end}}</table>
+<h2>Queue</h2>
+<p>{{.QueueDepth}} waiting ({{.Pending}} pending, {{.Searching}} searching, {{.Downloading}} downloading), {{.Failed}} failed</p>
+{{if .OpenBreakers}}<h2>Unreachable services</h2>
+<table>
+{{range .OpenBreakers}}<tr><td>{{.Service}}</td><td class="bad">{{.State}}</td><td>{{if .RetryAt}}retry at {{.RetryAt.Format "15:04"}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}<p><small>Version {{.Version}}</small></p>
+</body>
+</html>
+`))
+
+// statusPageData is the status with the cycles in display order
+type statusPageData struct {
+	StatusResponse
+	CycleNames []string
+}
+
+// renderPage writes the status as an HTML page
+func (h *StatusHandler) renderPage(w http.ResponseWriter, response StatusResponse) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusPage.Execute(w, statusPageData{StatusResponse: response, CycleNames: statusCycles}); err != nil {
+		h.logger.WithError(err).Error("Failed to render status page")
+	}
+}
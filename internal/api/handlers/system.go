@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/services/torbox"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/amaumene/gomenarr/internal/version"
+	"github.com/sirupsen/logrus"
+)
+
+// SystemHandler handles system status requests
+type SystemHandler struct {
+	traktClient  *trakt.Client
+	downloadCtrl *controllers.DownloadController
+	downloadDir  string // Where completed downloads appear, its free space is reported
+	dryRun       bool
+	logger       *logrus.Logger
+}
+
+// NewSystemHandler creates a new system handler
+func NewSystemHandler(traktClient *trakt.Client, downloadCtrl *controllers.DownloadController, downloadDir string, dryRun bool, logger *logrus.Logger) *SystemHandler {
+	return &SystemHandler{
+		traktClient:  traktClient,
+		downloadCtrl: downloadCtrl,
+		downloadDir:  downloadDir,
+		dryRun:       dryRun,
+		logger:       logger,
+	}
+}
+
+// SystemStatusResponse represents the system status response
+type SystemStatusResponse struct {
+	Trakt     trakt.Availability     `json:"trakt"`
+	DryRun    bool                   `json:"dry_run"`              // Downloads and deletions are only logged
+	DiskSpace *controllers.DiskSpace `json:"disk_space,omitempty"` // Download volume, when DOWNLOAD_MIN_FREE_GB is set
+}
+
+// Status handles the system status endpoint
+func (h *SystemHandler) Status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := SystemStatusResponse{
+		Trakt:     h.traktClient.Availability(),
+		DryRun:    h.dryRun,
+		DiskSpace: h.downloadCtrl.DiskSpace(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DownloaderStatus is the health of a download client
+type DownloaderStatus struct {
+	Name      string        `json:"name"`
+	Available bool          `json:"available"`
+	Error     string        `json:"error,omitempty"`
+	Stats     *torbox.Stats `json:"stats,omitempty"`
+	Disk      *DiskStatus   `json:"disk,omitempty"` // Volume of DOWNLOAD_DIR, when set
+}
+
+// DiskStatus is the space of the volume holding a directory
+type DiskStatus struct {
+	Path  string `json:"path"`
+	Free  uint64 `json:"free_bytes"`
+	Total uint64 `json:"total_bytes"`
+	Error string `json:"error,omitempty"`
+}
+
+// Downloaders handles GET /api/system/downloaders
+func (h *SystemHandler) Downloaders(w http.ResponseWriter, r *http.Request) {
+	status := DownloaderStatus{Name: "torbox", Available: true}
+
+	stats, err := h.downloadCtrl.DownloaderStats()
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to get TorBox queue statistics")
+		status.Available = false
+		status.Error = err.Error()
+	} else {
+		status.Stats = stats
+	}
+
+	if h.downloadDir != "" {
+		disk := &DiskStatus{Path: h.downloadDir}
+		if disk.Free, disk.Total, err = utils.DiskUsage(h.downloadDir); err != nil {
+			disk.Error = err.Error()
+		}
+		status.Disk = disk
+	}
+
+	writeJSON(w, http.StatusOK, []DownloaderStatus{status})
+}
+
+// Version handles the build version endpoint
+func (h *SystemHandler) Version(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Info())
+}
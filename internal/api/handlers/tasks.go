@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// TaskRunner lists the scheduled tasks and runs them on demand
+type TaskRunner interface {
+	Tasks() []scheduler.TaskInfo
+	Schedule() []scheduler.TaskInfo
+	RunTask(name string, withDependencies bool) error
+	CancelTask(name string) error
+	Status() scheduler.SchedulerInfo
+}
+
+// TaskHandler exposes the scheduled tasks
+type TaskHandler struct {
+	runner TaskRunner
+	logger *logrus.Logger
+}
+
+// NewTaskHandler creates a new task handler
+func NewTaskHandler(runner TaskRunner, logger *logrus.Logger) *TaskHandler {
+	return &TaskHandler{
+		runner: runner,
+		logger: logger,
+	}
+}
+
+// List handles GET /api/tasks
+func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.runner.Tasks())
+}
+
+// Scheduler handles GET /api/scheduler, with the maintenance windows
+func (h *TaskHandler) Scheduler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.runner.Status())
+}
+
+// Schedule handles GET /api/schedule, the next run of each scheduled task, soonest first
+func (h *TaskHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	schedule := h.runner.Schedule()
+	if schedule == nil {
+		schedule = []scheduler.TaskInfo{}
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// Run handles POST /api/tasks/{name}/run
+// The task runs in the background after its dependencies, unless ?deps=false.
+func (h *TaskHandler) Run(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	withDependencies := r.URL.Query().Get("deps") != "false"
+
+	err := h.runner.RunTask(name, withDependencies)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrTaskDisabled), errors.Is(err, scheduler.ErrTaskRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("task", name).Error("Failed to run task")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"task":         name,
+		"dependencies": withDependencies,
+	})
+}
+
+// Cancel handles POST /api/tasks/{name}/cancel
+// The task stops before its next item, the media being searched is left pending.
+func (h *TaskHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	err := h.runner.CancelTask(name)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrTaskIdle):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.logger.WithError(err).WithField("task", name).Error("Failed to cancel task")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"task":     name,
+		"canceled": true,
+	})
+}
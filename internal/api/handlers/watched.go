@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// WatchedHandler exposes the ledger of watched and cleaned up items
+type WatchedHandler struct {
+	db     *models.Database
+	logger *logrus.Logger
+}
+
+// NewWatchedHandler creates a new watched ledger handler
+func NewWatchedHandler(db *models.Database, logger *logrus.Logger) *WatchedHandler {
+	return &WatchedHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List handles GET /api/watched, optionally filtered with ?imdb_id=
+func (h *WatchedHandler) List(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.db.GetWatchedEntries(r.URL.Query().Get("imdb_id"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get watched entries")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// Delete handles DELETE /api/watched/{imdb_id}
+// Clearing the entries allows the movie or the show episodes to be downloaded again.
+func (h *WatchedHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	imdbID := r.PathValue("imdb_id")
+
+	removed, err := h.db.DeleteWatchedEntries(imdbID)
+	if err != nil {
+		h.logger.WithError(err).WithField("imdb_id", imdbID).Error("Failed to delete watched entries")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if removed == 0 {
+		http.Error(w, "No watched entries", http.StatusNotFound)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"imdb_id": imdbID,
+		"removed": removed,
+	}).Info("Cleared watched ledger entries, item can be downloaded again")
+
+	writeJSON(w, http.StatusOK, map[string]int{"removed": removed})
+}
@@ -1,101 +1,227 @@
 package handlers
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/controllers"
-	"github.com/amaumene/gomenarr/internal/services/torbox"
 	"github.com/sirupsen/logrus"
 )
 
-// WebhookHandler handles TorBox webhook callbacks
+// maxWebhookBody limits the size of an incoming webhook payload
+const maxWebhookBody = 1 << 20
+
+// signatureHeaders carry the HMAC-SHA256 signature of a webhook payload, hex encoded with an
+// optional sha256= prefix
+var signatureHeaders = []string{"X-Webhook-Signature", "X-Signature", "X-Hub-Signature-256"}
+
+// WebhookEvent is a download notification decoded from a provider payload
+type WebhookEvent struct {
+	Name   string // Download name, matched against the release title
+	Hash   string // Download hash, used when the name is unknown
+	Status string // "completed", "failed" or "unknown"
+
+	// Event type and time, which tell a redelivered event from a new one
+	Type string
+	At   time.Time
+}
+
+// WebhookParser decodes the webhook payloads of one download provider
+type WebhookParser interface {
+	// Provider returns the name used in the /api/webhooks/{provider} route
+	Provider() string
+	// Detect reports whether a payload was sent by this provider
+	Detect(r *http.Request, body []byte) bool
+	// Parse decodes a payload into an event
+	Parse(body []byte) (*WebhookEvent, error)
+}
+
+// WebhookHandler handles download provider webhook callbacks
 type WebhookHandler struct {
-	downloadCtrl *controllers.DownloadController
-	logger       *logrus.Logger
+	downloadCtrl  *controllers.DownloadController
+	parsers       []WebhookParser
+	signingSecret string // Key of the HMAC signature required on payloads, empty to accept unsigned payloads
+	logger        *logrus.Logger
 }
 
-// NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(downloadCtrl *controllers.DownloadController, logger *logrus.Logger) *WebhookHandler {
+// NewWebhookHandler creates a new webhook handler with the built-in provider parsers
+func NewWebhookHandler(downloadCtrl *controllers.DownloadController, signingSecret string, logger *logrus.Logger) *WebhookHandler {
 	return &WebhookHandler{
-		downloadCtrl: downloadCtrl,
-		logger:       logger,
+		downloadCtrl:  downloadCtrl,
+		parsers:       []WebhookParser{&TorBoxWebhookParser{}},
+		signingSecret: signingSecret,
+		logger:        logger,
+	}
+}
+
+// Register adds a provider parser, replacing any parser for the same provider
+func (h *WebhookHandler) Register(parser WebhookParser) {
+	for i, existing := range h.parsers {
+		if existing.Provider() == parser.Provider() {
+			h.parsers[i] = parser
+			return
+		}
+	}
+	h.parsers = append(h.parsers, parser)
+}
+
+// parser returns the parser for a provider, nil if none is registered
+func (h *WebhookHandler) parser(provider string) WebhookParser {
+	for _, parser := range h.parsers {
+		if parser.Provider() == provider {
+			return parser
+		}
+	}
+	return nil
+}
+
+// detect returns the first parser recognizing a payload, nil if none does
+func (h *WebhookHandler) detect(r *http.Request, body []byte) WebhookParser {
+	for _, parser := range h.parsers {
+		if parser.Detect(r, body) {
+			return parser
+		}
+	}
+	return nil
+}
+
+// Provider handles POST /api/webhooks/{provider}
+func (h *WebhookHandler) Provider(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	parser := h.parser(provider)
+	if parser == nil {
+		http.Error(w, fmt.Sprintf("Unknown webhook provider %q", provider), http.StatusNotFound)
+		return
+	}
+
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+	h.handle(w, parser, body)
+}
+
+// Detect handles POST /api/webhooks, detecting the provider from the payload
+func (h *WebhookHandler) Detect(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
 	}
+
+	parser := h.detect(r, body)
+	if parser == nil {
+		h.logger.Warn("Received webhook from unrecognized provider")
+		http.Error(w, "Unrecognized webhook provider", http.StatusBadRequest)
+		return
+	}
+	h.handle(w, parser, body)
 }
 
-// ServeHTTP handles the webhook endpoint
+// ServeHTTP handles the legacy /api/webhook/torbox endpoint
 func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var payload torbox.WebhookPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		h.logger.WithError(err).Error("Failed to decode webhook payload")
+	body, ok := h.readBody(w, r)
+	if !ok {
+		return
+	}
+	h.handle(w, h.parser(torboxProvider), body)
+}
+
+// handle parses a payload and applies the event to the matching release
+func (h *WebhookHandler) handle(w http.ResponseWriter, parser WebhookParser, body []byte) {
+	event, err := parser.Parse(body)
+	if err != nil {
+		h.logger.WithError(err).WithField("provider", parser.Provider()).Error("Failed to decode webhook payload")
 		http.Error(w, "Invalid payload", http.StatusBadRequest)
 		return
 	}
 
-	status := payload.GetStatus()
+	fields := logrus.Fields{
+		"provider": parser.Provider(),
+		"status":   event.Status,
+	}
+	delivery := controllers.WebhookDelivery{Type: parser.Provider() + ":" + event.Type, At: event.At}
 
-	// Extract download name from the notification message
-	downloadName, err := payload.ExtractDownloadName()
-	if err != nil {
-		// Fallback: Try to extract hash from the message
-		h.logger.WithFields(logrus.Fields{
-			"title":   payload.Data.Title,
-			"message": payload.Data.Message,
-		}).Debug("Could not extract download name, trying hash fallback")
-
-		hash, hashErr := payload.ExtractHash()
-		if hashErr != nil {
-			// Neither download name nor hash could be extracted
-			h.logger.WithFields(logrus.Fields{
-				"type":      payload.Type,
-				"timestamp": payload.Timestamp,
-				"title":     payload.Data.Title,
-				"message":   payload.Data.Message,
-			}).Warn("Received TorBox webhook without extractable download name or hash")
-
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	switch {
+	case event.Name != "":
+		// Handle webhook by download name (primary method)
+		fields["download_name"] = event.Name
+		h.logger.WithFields(fields).Info("Received webhook (matched by name)")
+
+		// The HandleWebhookByName method will delete from TorBox and switch to next candidate on failure
+		if err := h.downloadCtrl.HandleWebhookByName(event.Name, event.Status, delivery); err != nil {
+			h.logger.WithError(err).Error("Failed to handle webhook by name")
+			http.Error(w, "Failed to process webhook", http.StatusInternalServerError)
 			return
 		}
 
-		// Handle webhook by hash
-		h.logger.WithFields(logrus.Fields{
-			"hash":   hash,
-			"status": status,
-			"title":  payload.Data.Title,
-		}).Info("Received TorBox webhook (matched by hash)")
+	case event.Hash != "":
+		fields["hash"] = event.Hash
+		h.logger.WithFields(fields).Info("Received webhook (matched by hash)")
 
-		if err := h.downloadCtrl.HandleWebhookByHash(hash, status); err != nil {
+		if err := h.downloadCtrl.HandleWebhookByHash(event.Hash, event.Status, delivery); err != nil {
 			h.logger.WithError(err).Error("Failed to handle webhook by hash")
 			http.Error(w, "Failed to process webhook", http.StatusInternalServerError)
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-		return
+	default:
+		// Nothing to match the event against, acknowledge it so the provider doesn't retry
+		h.logger.WithFields(fields).Warn("Received webhook without extractable download name or hash")
 	}
 
-	// Handle webhook by download name (primary method)
-	h.logger.WithFields(logrus.Fields{
-		"download_name": downloadName,
-		"status":        status,
-		"title":         payload.Data.Title,
-	}).Info("Received TorBox webhook (matched by name)")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
 
-	// Handle all webhook statuses (completed, failed, etc.) through the unified handler
-	// The HandleWebhookByName method will delete from TorBox and switch to next candidate on failure
-	if err := h.downloadCtrl.HandleWebhookByName(downloadName, status); err != nil {
-		h.logger.WithError(err).Error("Failed to handle webhook by name")
-		http.Error(w, "Failed to process webhook", http.StatusInternalServerError)
-		return
+// readBody reads a webhook payload and checks its signature, rejecting the request when
+// it can't be read or isn't signed with the signing secret
+func (h *WebhookHandler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := readWebhookBody(r)
+	if err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return nil, false
+	}
+	if h.signingSecret != "" && !validSignature(r, body, h.signingSecret) {
+		h.logger.WithFields(logrus.Fields{
+			"path":        r.URL.Path,
+			"remote_addr": r.RemoteAddr,
+		}).Warn("Rejected webhook with a missing or invalid signature")
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return nil, false
 	}
+	return body, true
+}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+// validSignature checks the HMAC-SHA256 signature of a payload
+func validSignature(r *http.Request, body []byte, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, header := range signatureHeaders {
+		value := strings.TrimSpace(r.Header.Get(header))
+		if value == "" {
+			continue
+		}
+		signature, err := hex.DecodeString(strings.TrimPrefix(value, "sha256="))
+		return err == nil && hmac.Equal(signature, expected)
+	}
+	return false
+}
+
+// readWebhookBody reads a webhook payload, bounded by maxWebhookBody
+func readWebhookBody(r *http.Request) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
 }
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// emptyParser decodes every payload into an event matching no download
+type emptyParser struct {
+	provider string
+}
+
+func (p *emptyParser) Provider() string                         { return p.provider }
+func (p *emptyParser) Detect(r *http.Request, body []byte) bool { return true }
+func (p *emptyParser) Parse(body []byte) (*WebhookEvent, error) { return &WebhookEvent{}, nil }
+
+func TestWebhookSignature(t *testing.T) {
+	const secret = "signing-secret"
+	body := `{"event":"download_completed"}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	handler := NewWebhookHandler(nil, secret, logrus.New())
+	handler.Register(&emptyParser{provider: torboxProvider})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/webhooks/{provider}", handler.Provider)
+	mux.HandleFunc("/api/webhook/torbox", handler.ServeHTTP)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		body   string
+		want   int
+	}{
+		{"valid", "X-Webhook-Signature", signature, body, http.StatusOK},
+		{"prefixed", "X-Hub-Signature-256", "sha256=" + signature, body, http.StatusOK},
+		{"wrong signature", "X-Signature", strings.Repeat("00", sha256.Size), body, http.StatusUnauthorized},
+		{"missing header", "", "", body, http.StatusUnauthorized},
+		{"tampered body", "X-Webhook-Signature", signature, `{"event":"download_failed"}`, http.StatusUnauthorized},
+	}
+
+	for _, path := range []string{"/api/webhooks/torbox", "/api/webhook/torbox"} {
+		for _, tt := range tests {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s: expected %d, got %d", path, tt.name, tt.want, rec.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/services/torbox"
+)
+
+const torboxProvider = "torbox"
+
+// TorBoxWebhookParser decodes TorBox notification webhooks
+type TorBoxWebhookParser struct{}
+
+// Provider returns the TorBox provider name
+func (p *TorBoxWebhookParser) Provider() string {
+	return torboxProvider
+}
+
+// Detect recognizes TorBox notifications by their type and title/message data
+func (p *TorBoxWebhookParser) Detect(r *http.Request, body []byte) bool {
+	var payload torbox.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return false
+	}
+	return payload.Type != "" && payload.Data.Title != "" && payload.Data.Message != ""
+}
+
+// Parse decodes a TorBox notification, matching by download name then by hash
+func (p *TorBoxWebhookParser) Parse(body []byte) (*WebhookEvent, error) {
+	var payload torbox.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	event := &WebhookEvent{Status: payload.GetStatus(), Type: payload.Type, At: payload.Timestamp}
+	if name, err := payload.ExtractDownloadName(); err == nil {
+		event.Name = name
+	} else if hash, err := payload.ExtractHash(); err == nil {
+		event.Hash = hash
+	}
+	return event, nil
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuthOptions configures the authentication of the HTTP API
+type AuthOptions struct {
+	APIKey         string       // Key of the API requests, empty disables authentication
+	WebhookSecret  string       // Shared secret of the download provider webhooks, empty to accept the API key
+	TrustedProxies []*net.IPNet // Reverse proxies authenticating requests themselves, their requests skip the API key
+	PublicPaths    []string     // Paths served without authentication, a trailing slash matches the subtree
+	WebhookPaths   []string     // Paths authenticated with the webhook secret, a trailing slash matches the subtree
+}
+
+// Auth middleware rejects the requests without a valid API key, sent in the X-Api-Key header,
+// as a bearer token or in the apikey query parameter
+func Auth(next http.Handler, options AuthOptions, logger *logrus.Logger) http.Handler {
+	if options.APIKey == "" && options.WebhookSecret == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case matchPath(r.URL.Path, options.PublicPaths):
+		case matchPath(r.URL.Path, options.WebhookPaths):
+			if !validWebhook(r, options) {
+				unauthorized(w, r, "Invalid webhook secret", logger)
+				return
+			}
+		case options.APIKey == "" || trusted(r, options.TrustedProxies):
+		case !validKey(requestKey(r), options.APIKey):
+			unauthorized(w, r, "Invalid or missing API key", logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestKey returns the API key of a request, empty without one
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get("apikey")
+}
+
+// validWebhook checks the shared secret of a webhook, sent in the X-Webhook-Secret header or
+// the secret query parameter. Without a secret, webhooks are authenticated like the API.
+func validWebhook(r *http.Request, options AuthOptions) bool {
+	if options.WebhookSecret == "" {
+		return options.APIKey == "" || trusted(r, options.TrustedProxies) || validKey(requestKey(r), options.APIKey)
+	}
+	secret := r.Header.Get("X-Webhook-Secret")
+	if secret == "" {
+		secret = r.URL.Query().Get("secret")
+	}
+	return validKey(secret, options.WebhookSecret)
+}
+
+// validKey compares a key in constant time
+func validKey(key, expected string) bool {
+	return key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(expected)) == 1
+}
+
+// trusted checks if a request comes from a trusted proxy
+func trusted(r *http.Request, proxies []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath checks if a path is one of the paths, or under one ending with a slash
+func matchPath(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// unauthorized rejects a request
+func unauthorized(w http.ResponseWriter, r *http.Request, message string, logger *logrus.Logger) {
+	logger.WithFields(logrus.Fields{
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"remote_addr": r.RemoteAddr,
+	}).Warn("Rejected unauthenticated request")
+	http.Error(w, message, http.StatusUnauthorized)
+}
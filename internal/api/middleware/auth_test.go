@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAuth(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	handler := Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), AuthOptions{
+		APIKey:         "key",
+		WebhookSecret:  "secret",
+		TrustedProxies: []*net.IPNet{proxies},
+		PublicPaths:    []string{"/health"},
+		WebhookPaths:   []string{"/api/webhooks/"},
+	}, logrus.New())
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		value  string
+		remote string
+		want   int
+	}{
+		{"public", "/health", "", "", "192.168.1.2:1234", http.StatusOK},
+		{"missing key", "/api/media", "", "", "192.168.1.2:1234", http.StatusUnauthorized},
+		{"wrong key", "/api/media", "X-Api-Key", "nope", "192.168.1.2:1234", http.StatusUnauthorized},
+		{"header key", "/api/media", "X-Api-Key", "key", "192.168.1.2:1234", http.StatusOK},
+		{"bearer key", "/api/media", "Authorization", "Bearer key", "192.168.1.2:1234", http.StatusOK},
+		{"query key", "/api/media?apikey=key", "", "", "192.168.1.2:1234", http.StatusOK},
+		{"trusted proxy", "/api/media", "", "", "10.1.2.3:1234", http.StatusOK},
+		{"webhook secret", "/api/webhooks/torbox?secret=secret", "", "", "192.168.1.2:1234", http.StatusOK},
+		{"webhook with API key", "/api/webhooks/torbox", "X-Api-Key", "key", "192.168.1.2:1234", http.StatusUnauthorized},
+		{"webhook from trusted proxy", "/api/webhooks/torbox", "", "", "10.1.2.3:1234", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.RemoteAddr = tt.remote
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
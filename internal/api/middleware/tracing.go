@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/amaumene/gomenarr/internal/tracing"
+)
+
+// Tracing middleware records a server span per request, joining the caller's trace
+// when a traceparent header is sent
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.StartKind(ctx, r.Method+" "+r.URL.Path, tracing.KindServer)
+		defer span.End()
+
+		wrapped := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.route", r.Pattern)
+		span.SetAttribute("http.status_code", wrapped.statusCode)
+	})
+}
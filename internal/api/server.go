@@ -10,7 +10,12 @@ import (
 	"github.com/amaumene/gomenarr/internal/api/middleware"
 	"github.com/amaumene/gomenarr/internal/config"
 	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/metrics"
 	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -19,14 +24,37 @@ type Server struct {
 	server       *http.Server
 	db           *models.Database
 	downloadCtrl *controllers.DownloadController
+	mediaCtrl    *controllers.MediaController
+	searcher     handlers.MediaSearcher
+	tasks        handlers.TaskRunner
+	grabRamp     handlers.GrabRamp
+	orchestrator handlers.Orchestrator
+	cycles       handlers.CycleSource
+	quarantine   handlers.Quarantine
+	traktClient  *trakt.Client
+	blacklist    *utils.Blacklist
+	hooks        *hooks.Runner
+	notifier     *notify.Dispatcher
+	events       *handlers.EventsHandler
 	logger       *logrus.Logger
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, db *models.Database, downloadCtrl *controllers.DownloadController, logger *logrus.Logger) *Server {
+func NewServer(cfg *config.Config, db *models.Database, downloadCtrl *controllers.DownloadController, mediaCtrl *controllers.MediaController, searcher handlers.MediaSearcher, tasks handlers.TaskRunner, grabRamp handlers.GrabRamp, orchestrator handlers.Orchestrator, cycles handlers.CycleSource, quarantine handlers.Quarantine, traktClient *trakt.Client, blacklist *utils.Blacklist, notifier *notify.Dispatcher, hookRunner *hooks.Runner, logger *logrus.Logger) *Server {
 	s := &Server{
 		db:           db,
 		downloadCtrl: downloadCtrl,
+		mediaCtrl:    mediaCtrl,
+		searcher:     searcher,
+		tasks:        tasks,
+		grabRamp:     grabRamp,
+		orchestrator: orchestrator,
+		cycles:       cycles,
+		quarantine:   quarantine,
+		traktClient:  traktClient,
+		blacklist:    blacklist,
+		hooks:        hookRunner,
+		notifier:     notifier,
 		logger:       logger,
 	}
 
@@ -35,27 +63,138 @@ func NewServer(cfg *config.Config, db *models.Database, downloadCtrl *controller
 
 	s.server = &http.Server{
 		Addr:         ":" + cfg.ServerPort,
-		Handler:      middleware.Logging(mux, logger),
+		Handler:      middleware.Logging(middleware.Tracing(middleware.Auth(mux, authOptions(cfg), logger)), logger),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	s.server.RegisterOnShutdown(s.events.Close)
 
 	return s
 }
 
+// authOptions returns the authentication of the routes: the health check and the status page
+// are public, the download provider webhooks check their shared secret
+func authOptions(cfg *config.Config) middleware.AuthOptions {
+	return middleware.AuthOptions{
+		APIKey:         cfg.ServerAPIKey,
+		WebhookSecret:  cfg.ServerWebhookSecret,
+		TrustedProxies: cfg.ServerTrustedProxies,
+		PublicPaths:    []string{"/health", "/status"},
+		WebhookPaths:   []string{"/api/webhooks", "/api/webhooks/", "/api/webhook/"},
+	}
+}
+
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes(mux *http.ServeMux, cfg *config.Config) {
 	// Health check
 	healthHandler := handlers.NewHealthHandler(s.logger)
 	mux.HandleFunc("/health", healthHandler.ServeHTTP)
+	mux.HandleFunc("GET /api/health/detailed", healthHandler.Detailed)
 
-	// Status endpoint
-	statusHandler := handlers.NewStatusHandler(s.db, s.logger)
+	// Public status page
+	statusHandler := handlers.NewStatusHandler(s.db, s.cycles, s.logger)
 	mux.HandleFunc("/status", statusHandler.ServeHTTP)
 
-	// TorBox webhook
-	webhookHandler := handlers.NewWebhookHandler(s.downloadCtrl, s.logger)
+	// System status (external service availability)
+	systemHandler := handlers.NewSystemHandler(s.traktClient, s.downloadCtrl, cfg.DownloadDir, cfg.DryRun, s.logger)
+	mux.HandleFunc("/api/system/status", systemHandler.Status)
+	mux.HandleFunc("/api/system/version", systemHandler.Version)
+	mux.HandleFunc("GET /api/system/downloaders", systemHandler.Downloaders)
+
+	// Manual media management
+	mediaHandler := handlers.NewMediaHandler(s.db, s.mediaCtrl, s.searcher, s.tasks, s.grabRamp, s.logger)
+	mux.HandleFunc("GET /api/media", mediaHandler.List)
+	mux.HandleFunc("POST /api/media", mediaHandler.Add)
+	mux.HandleFunc("POST /api/media/import", mediaHandler.Import)
+	mux.HandleFunc("GET /api/imports/{id}", mediaHandler.ImportStatus)
+	mux.HandleFunc("GET /api/media/{id}", mediaHandler.Get)
+	mux.HandleFunc("DELETE /api/media/{id}", mediaHandler.Delete)
+	mux.HandleFunc("POST /api/media/{id}/search", mediaHandler.Search)
+	mux.HandleFunc("GET /api/media/{id}/searches", mediaHandler.Searches)
+	mux.HandleFunc("GET /api/media/{id}/rejections", mediaHandler.Rejections)
+	mux.HandleFunc("GET /api/media/{id}/candidates", mediaHandler.Candidates)
+	mux.HandleFunc("POST /api/nzbs/{id}/download", mediaHandler.DownloadRelease)
+	mux.HandleFunc("GET /api/shows/{id}/stats", mediaHandler.ShowStats)
+	mux.HandleFunc("GET /api/shows/{id}/gaps", mediaHandler.ShowGaps)
+	mux.HandleFunc("GET /api/shows/gaps", mediaHandler.GapReport)
+
+	// Watched ledger (re-download guard)
+	watchedHandler := handlers.NewWatchedHandler(s.db, s.logger)
+	mux.HandleFunc("GET /api/watched", watchedHandler.List)
+	mux.HandleFunc("DELETE /api/watched/{imdb_id}", watchedHandler.Delete)
+
+	// Library files found on disk
+	libraryHandler := handlers.NewLibraryHandler(s.db, s.logger)
+	mux.HandleFunc("GET /api/library", libraryHandler.List)
+
+	// Scheduled tasks
+	taskHandler := handlers.NewTaskHandler(s.tasks, s.logger)
+	mux.HandleFunc("GET /api/tasks", taskHandler.List)
+	mux.HandleFunc("GET /api/scheduler", taskHandler.Scheduler)
+	mux.HandleFunc("GET /api/schedule", taskHandler.Schedule)
+	mux.HandleFunc("POST /api/tasks/{name}/run", taskHandler.Run)
+	mux.HandleFunc("POST /api/tasks/{name}/cancel", taskHandler.Cancel)
+
+	// Pause/resume of the automatic tasks
+	orchestratorHandler := handlers.NewOrchestratorHandler(s.orchestrator, s.logger)
+	mux.HandleFunc("GET /api/orchestrator", orchestratorHandler.Get)
+	mux.HandleFunc("POST /api/orchestrator/pause", orchestratorHandler.Pause)
+	mux.HandleFunc("POST /api/orchestrator/resume", orchestratorHandler.Resume)
+
+	// Cold-start grab limit
+	rampHandler := handlers.NewRampHandler(s.grabRamp, s.logger)
+	mux.HandleFunc("GET /api/grabs/ramp", rampHandler.Get)
+	mux.HandleFunc("POST /api/grabs/ramp/confirm", rampHandler.Confirm)
+
+	// Library storage quotas
+	quotaHandler := handlers.NewQuotaHandler(s.db, s.downloadCtrl, s.logger)
+	mux.HandleFunc("GET /api/quotas", quotaHandler.List)
+	mux.HandleFunc("GET /api/quotas/actions", quotaHandler.Actions)
+
+	// Downloads rejected on import
+	quarantineHandler := handlers.NewQuarantineHandler(s.quarantine, s.logger)
+	mux.HandleFunc("GET /api/quarantine", quarantineHandler.List)
+	mux.HandleFunc("POST /api/quarantine/{id}/import", quarantineHandler.Import)
+	mux.HandleFunc("DELETE /api/quarantine/{id}", quarantineHandler.Purge)
+
+	// Release blacklist
+	blacklistHandler := handlers.NewBlacklistHandler(s.blacklist, s.logger)
+	mux.HandleFunc("GET /api/blacklist", blacklistHandler.List)
+	mux.HandleFunc("POST /api/blacklist", blacklistHandler.Add)
+	mux.HandleFunc("DELETE /api/blacklist/{term}", blacklistHandler.Delete)
+	mux.HandleFunc("GET /api/blacklist/export", blacklistHandler.Export)
+	mux.HandleFunc("POST /api/blacklist/import", blacklistHandler.Import)
+	mux.HandleFunc("POST /api/blacklist/reload", blacklistHandler.Reload)
+
+	// Release title parsing audit
+	auditHandler := handlers.NewAuditHandler(s.db, s.logger)
+	mux.HandleFunc("GET /api/nzbs/audit", auditHandler.Titles)
+
+	// Time from air or release date to availability
+	statsHandler := handlers.NewStatsHandler(s.db, s.logger)
+	mux.HandleFunc("GET /api/stats/availability", statsHandler.Availability)
+
+	// Full-text search over the media, releases and history
+	searchHandler := handlers.NewSearchHandler(controllers.NewHistoryIndex(s.db, s.logger), s.logger)
+	mux.HandleFunc("GET /api/search", searchHandler.Search)
+
+	// Script hooks
+	hookHandler := handlers.NewHookHandler(s.db, s.hooks, s.logger)
+	mux.HandleFunc("GET /api/hooks", hookHandler.List)
+	mux.HandleFunc("GET /api/hooks/runs", hookHandler.Runs)
+
+	// Live activity stream
+	s.events = handlers.NewEventsHandler(s.notifier, s.logger)
+	mux.HandleFunc("GET /api/events/stream", s.events.Stream)
+
+	// Prometheus metrics
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Download provider webhooks
+	webhookHandler := handlers.NewWebhookHandler(s.downloadCtrl, cfg.ServerWebhookSigningSecret, s.logger)
+	mux.HandleFunc("POST /api/webhooks", webhookHandler.Detect)
+	mux.HandleFunc("POST /api/webhooks/{provider}", webhookHandler.Provider)
 	mux.HandleFunc("/api/webhook/torbox", webhookHandler.ServeHTTP)
 }
 
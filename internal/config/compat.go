@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// legacyPrefix marks the deprecated names of the settings, e.g. GOMENARR_TRAKT_CLIENT_ID
+const legacyPrefix = "GOMENARR_"
+
+// legacySpellings are the misspellings of the deprecated names, e.g. GOMENARR_NEWSNAB_URL
+var legacySpellings = strings.NewReplacer("NEWSNAB", "NEWZNAB")
+
+// settingPrefixes are the prefixes of the variables read by Load
+var settingPrefixes = []string{
+	"AIR_OFFSET_", "BACKFILL_", "BLACKLIST_", "CIRCUIT_BREAKER_", "CLEANUP_", "COLD_START_",
+	"CONFIG_DIR", "DISCORD_", "DOWNLOAD_", "DRY_RUN", "GAP_FILL_", "HOOK_", "HTTP_MAX_", "IDLE_",
+	"IMPORT_", "LIBRARY_DIRS", "LOG_", "MAINTENANCE_", "MAX_GRABS_", "MEDIA_", "NETWORK_",
+	"NEWZNAB_", "OTEL_", "PUSHOVER_", "QUALITY_PROFILE_", "QUARANTINE_DIR", "RECOVERY_",
+	"REDOWNLOAD_", "RENAME_", "SCORING_", "SEARCH_", "SEASON_PACK_", "SERVER_", "STARTUP_",
+	"STORAGE_", "TASK", "TELEGRAM_", "TORBOX_", "TRACING_", "TRAKT_", "UPGRADE_", "WEBHOOK_",
+}
+
+// Deprecation is a deprecated variable name found in the environment or the config files
+type Deprecation struct {
+	Name        string
+	Replacement string
+	Ignored     bool // The replacement is set too and wins
+}
+
+// IsSetting checks if a variable name is one of the settings read by Load
+func IsSetting(name string) bool {
+	for _, prefix := range settingPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replacement returns the current name of a deprecated variable name
+func Replacement(name string) (string, bool) {
+	current, ok := strings.CutPrefix(name, legacyPrefix)
+	if !ok {
+		return "", false
+	}
+	current = legacySpellings.Replace(current)
+	if !IsSetting(current) {
+		return "", false
+	}
+	return current, true
+}
+
+// MigrateEnv maps variables to the settings they hold under their current names
+// Deprecated names are renamed unless the current name is set too, variables that
+// aren't settings are left out. Deprecations are sorted by name.
+func MigrateEnv(vars map[string]string) (map[string]string, []Deprecation) {
+	settings := make(map[string]string)
+	var deprecations []Deprecation
+	for name, value := range vars {
+		if IsSetting(name) {
+			settings[name] = value
+			continue
+		}
+		current, ok := Replacement(name)
+		if !ok {
+			continue
+		}
+		_, ignored := vars[current]
+		deprecations = append(deprecations, Deprecation{Name: name, Replacement: current, Ignored: ignored})
+	}
+
+	sort.Slice(deprecations, func(i, j int) bool {
+		return deprecations[i].Name < deprecations[j].Name
+	})
+	for _, deprecation := range deprecations {
+		if !deprecation.Ignored {
+			settings[deprecation.Replacement] = vars[deprecation.Name]
+		}
+	}
+	return settings, deprecations
+}
+
+// ReadEnvFile reads the variables of a .env file
+func ReadEnvFile(path string) (map[string]string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("env")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	vars := make(map[string]string)
+	for _, key := range v.AllKeys() {
+		vars[strings.ToUpper(key)] = v.GetString(key)
+	}
+	return vars, nil
+}
+
+// Environ returns the environment variables by name
+func Environ() map[string]string {
+	vars := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+// applyDeprecations sets the settings given under a deprecated name in the environment,
+// .env or config.yaml under their current name
+func applyDeprecations() []Deprecation {
+	vars := make(map[string]string)
+	for _, key := range viper.AllKeys() {
+		if viper.InConfig(key) {
+			vars[strings.ToUpper(key)] = viper.GetString(key)
+		}
+	}
+	for name, value := range Environ() {
+		vars[name] = value
+	}
+
+	settings, deprecations := MigrateEnv(vars)
+	for _, deprecation := range deprecations {
+		if !deprecation.Ignored {
+			viper.Set(deprecation.Replacement, settings[deprecation.Replacement])
+		}
+	}
+	return deprecations
+}
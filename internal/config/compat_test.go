@@ -0,0 +1,51 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestMigrateEnv(t *testing.T) {
+	settings, deprecations := MigrateEnv(map[string]string{
+		"GOMENARR_NEWSNAB_URL":     "https://indexer.example",
+		"GOMENARR_NEWZNAB_1_KEY":   "key",
+		"GOMENARR_TRAKT_CLIENT_ID": "old",
+		"TRAKT_CLIENT_ID":          "new",
+		"GOMENARR_URL":             "http://localhost:8080",
+		"PATH":                     "/usr/bin",
+	})
+
+	want := map[string]string{
+		"NEWZNAB_URL":     "https://indexer.example",
+		"NEWZNAB_1_KEY":   "key",
+		"TRAKT_CLIENT_ID": "new",
+	}
+	if len(settings) != len(want) {
+		t.Errorf("Expected %d settings, got %v", len(want), settings)
+	}
+	for name, value := range want {
+		if settings[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, settings[name])
+		}
+	}
+
+	if len(deprecations) != 3 {
+		t.Fatalf("Expected 3 deprecations, got %v", deprecations)
+	}
+	if d := deprecations[2]; d.Name != "GOMENARR_TRAKT_CLIENT_ID" || d.Replacement != "TRAKT_CLIENT_ID" || !d.Ignored {
+		t.Errorf("Expected the deprecated client ID to be ignored, got %+v", d)
+	}
+}
+
+func TestSettingPrefixesCoverLoad(t *testing.T) {
+	source, err := os.ReadFile("config.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range regexp.MustCompile(`viper\.\w+\("([A-Z0-9_]+)"`).FindAllSubmatch(source, -1) {
+		if name := string(match[1]); name != "CONFIG_FILE" && !IsSetting(name) {
+			t.Errorf("%s is read by Load but not a setting, add its prefix to settingPrefixes", name)
+		}
+	}
+}
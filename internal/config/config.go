@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -15,44 +19,550 @@ type Config struct {
 	TraktClientSecret string
 	TraktSyncDays     int // Days to look back for watched media (default: 3)
 
-	// Newznab
-	NewznabURL string
-	NewznabKey string
+	// Hours before its expiry the Trakt token is refreshed, measured on the Trakt clock (default: 24)
+	TraktTokenRefreshHours int
+
+	// When watchlist movies are removed from the Trakt watchlist: downloaded (kept until watched),
+	// watched (once their files are cleaned up) or empty for never. "off" is read as empty and
+	// TRAKT_REMOVE_WATCHED_FROM_WATCHLIST=true as watched.
+	TraktWatchlistRemoval string
+
+	// Add imported releases to the Trakt collection, remove them once cleanup deletes them
+	TraktSyncCollection bool
+
+	// Sources (watchlist, favorites, list) or custom list names whose movies and episodes are
+	// not downloaded when in the Trakt collection, empty to ignore the collection
+	TraktCollectionSources []string
+
+	// Language of the titles used by title searches and matches (default: en, empty for the synced titles)
+	TraktMetadataLanguage string
+
+	// Custom Trakt lists synced besides the watchlist and favorites (TRAKT_LIST_*, TRAKT_LIST_<n>_*)
+	TraktLists []TraktListConfig
+
+	// Newznab indexers (NEWZNAB_* is the first one, NEWZNAB_<n>_* add more)
+	Indexers []IndexerConfig
 
 	// TorBox
-	TorBoxAPIKey string
+	TorBoxAPIKey        string
+	TorBoxAPITimeout    int  // Seconds before an API call times out (default: 30)
+	TorBoxUploadTimeout int  // Seconds before an NZB/torrent upload times out (default: 300)
+	TorBoxUploadRetries int  // Upload retries on network/server errors, with exponential backoff (default: 3)
+	TorBoxUploadGzip    bool // Gzip large uploads, disabled automatically if TorBox rejects them
+
+	// Extra TorBox download parameters, e.g. "post_processing=-1,as_queued=false"
+	// Values support tokens: {title}, {imdb_id}, {quality}, {media_type}, {source}, {year}, {season}, {episode}
+	TorBoxDownloadParams      string
+	TorBoxDownloadParamsMovie string // Overrides TorBoxDownloadParams for movies
+	TorBoxDownloadParamsTV    string // Overrides TorBoxDownloadParams for TV shows
+
+	// Outbound requests to Trakt, the indexers and TorBox
+	RateLimits     RateLimitConfig
+	Retry          RetryConfig
+	CircuitBreaker CircuitBreakerConfig
+	Proxies        ProxyConfig
+	Network        NetworkConfig
+
+	// Quality profiles (QUALITY_PROFILE_MOVIE_*, QUALITY_PROFILE_TV_* and named QUALITY_PROFILE_<n>_*)
+	QualityProfiles []QualityProfileConfig
+	Scoring         ScoringConfig // Release score weights, overridable per quality profile
+	BlacklistURLs   []string      // Remote blacklists merged with the local file, refreshed daily
 
 	// Download
-	DownloadTimeoutMinutes int // Minutes before a download is considered stuck (default: 30)
+	DownloadTimeoutMinutes int  // Minutes before a download is considered stuck (default: 30)
+	RedownloadWatched      bool // Download items again after they were watched and cleaned up (e.g. Trakt progress reset)
+	UpgradeEnabled         bool // Search completed movies daily for releases scoring higher under their quality profile
+	SeasonPackUpgrade      bool // Replace the episodes of seasons that finished airing with a season pack ("packupgrade" note per show)
+	BackfillConcurrency    int  // Parallel season searches of shows using the backfill strategy (default: 2)
+	ColdStartGrabs         int  // Grabs of the first search cycle of a fresh install, doubled every cycle (default: 10, 0 disables)
+	MaxGrabsPerCycle       int  // Grabs per scheduled search cycle, 0 for unlimited (default)
+	AirOffsetMinutes       int  // Minutes after an episode aired before it is searched (default: 0)
+
+	// Download retries with the next candidate
+	DownloadMaxRetries   int             // Failures of a release before its media item is given up (default: 5)
+	DownloadRetryBackoff []time.Duration // Wait before the nth retry of a media item, the last one repeats (default: 0s,5m,15m,1h)
+
+	// Free space kept on the download volume, grabs are held back below it
+	DownloadMinFreeGB int    // Gigabytes left free once a release is downloaded, 0 disables the check (default)
+	DownloadDiskPath  string // Directory on the checked volume (default: DOWNLOAD_DIR)
+
+	// Backoff of scheduled searches for media without results, doubled after each empty search
+	SearchBackoffMinutes    int  // Wait after the first empty search (default: 60, 0 searches every cycle)
+	SearchBackoffMaxMinutes int  // Longest wait between two searches (default: 1440)
+	SearchCandidateTTLHours int  // Age after which stored candidates are dropped on retry (default: 168, 0 keeps them)
+	SearchRSSEnabled        bool // Grab pending media from the latest releases of the indexers (rss_sync task)
+	SearchWidenAfter        int  // Empty searches after which show searches are widened (default: 0, never widened)
+
+	// Scheduler
+	TasksDisabled []string          // Scheduled tasks turned off (e.g. upgrade, cleanup_watched)
+	TaskSchedules map[string]string // Cron schedule overrides by task name
+	StartupTasks  []string          // Tasks run at startup, in order (default: recover_downloads, blacklist_refresh, sync, search)
+
+	RecoveryMaxAgeHours int // Age of the TorBox downloads checked by the startup recovery scan (default: 72, 0 for all)
+	GapFillShows        int // Backfill shows searched for missing episodes per gap_fill run, 0 disables it (default)
+	MediaTimeoutSeconds int // Deadline of the search of a single media item (default: 300, 0 for none)
+	TaskStallMinutes    int // Run time after which a task is reported as stalled (default: 60, 0 disables the watchdog)
+
+	// Recurring windows during which MaintenanceTasks are skipped (e.g. indexer API counter resets)
+	MaintenanceWindows []MaintenanceWindowConfig
+	MaintenanceTasks   []string // Tasks skipped during maintenance windows (default: search, rss_sync, upgrade, season_pack_upgrade, gap_fill)
+
+	// Idle mode, while no media item waits for a search or a download
+	IdleIntervalHours int      // Least time between two runs of IdleTasks (default: 12, 0 disables idle mode)
+	IdleTasks         []string // Tasks stretched while idle (default: sync, search, reconcile_downloads, stuck_check)
 
 	// Server
-	ServerPort string
+	ServerPort                 string
+	ServerAPIKey               string       // Key required by the HTTP API, empty leaves it open
+	ServerWebhookSecret        string       // Shared secret of the download provider webhooks, empty to use the API key
+	ServerWebhookSigningSecret string       // Key of the HMAC-SHA256 signature required on webhook payloads, empty to accept unsigned payloads
+	ServerTrustedProxies       []*net.IPNet // Reverse proxies authenticating requests themselves, skipping the API key
+
+	// Library
+	LibraryDirs            []string // Media library roots, files are only deleted inside them
+	CleanupRemoveArtifacts bool     // Also delete nfo/subtitle/artwork files next to deleted media
+	CleanupDeleteWorkers   int      // TorBox jobs deleted at the same time by cleanups (default: 4)
+	CleanupDeleteRetries   int      // Retries of a failed TorBox job deletion (default: 3)
+
+	// Storage budgets of libraries (STORAGE_QUOTA_<n>_*)
+	StorageQuotas []StorageQuotaConfig
+
+	// Renaming of completed downloads into the library (RENAME_MODE empty leaves them in place)
+	RenameMode            string // move, hardlink or copy
+	DownloadDir           string // Where completed downloads appear (e.g. a TorBox WebDAV mount)
+	RenameMoviesDir       string // Library root of renamed movies, added to LibraryDirs
+	RenameShowsDir        string // Library root of renamed episodes, added to LibraryDirs
+	RenameMovieTemplate   string // e.g. "{Title} ({Year})/{Title} ({Year}) - {Quality}"
+	RenameEpisodeTemplate string // e.g. "{Title} ({Year})/Season {Season:02}/{Title} - S{Season:02}E{Episode:02} - {Quality}"
+	QuarantineDir         string // Where downloads rejected on import are moved (default: $CONFIG_DIR/quarantine)
+
+	// Media server refresh after imports (Plex, Jellyfin or Emby)
+	MediaServerType            string
+	MediaServerURL             string
+	MediaServerToken           string
+	MediaServerRefreshInterval int // Minimum seconds between two library refreshes (default: 60)
+	ImportConcurrency          int // Completed downloads imported at the same time (default: 2)
+
+	// Notifications (WEBHOOK_* is the first webhook, WEBHOOK_<n>_* add more)
+	Webhooks []WebhookConfig
+
+	// Script hooks run at pipeline stages (HOOK_<n>_*)
+	Hooks []HookConfig
+
+	// Chat notifications, each with an optional list of event types (default: all)
+	DiscordWebhookURL string
+	DiscordEvents     []string
+	TelegramBotToken  string
+	TelegramChatID    string
+	TelegramEvents    []string
+	PushoverToken     string
+	PushoverUser      string
+	PushoverPriority  int // -2 (silent) to 1 (high), default 0
+	PushoverEvents    []string
 
 	// Paths
-	TokenFile     string // $CONFIG_DIR/token.json
-	BlacklistFile string // $CONFIG_DIR/blacklist.txt
-	DatabaseFile  string // $CONFIG_DIR/gomenarr.db
+	ConfigDir    string // Holds token.json and blacklist.txt with the file storage backend
+	DatabaseFile string // $CONFIG_DIR/gomenarr.db
+
+	// Where the Trakt token and the blacklist are kept (STORAGE_BACKEND, default: files in ConfigDir)
+	Storage StorageConfig
 
 	// Logging
-	LogLevel string
+	LogLevel      string
+	LogOutput     string // stdout, file or both (default: stdout)
+	LogFile       string // File of the file output (default: $CONFIG_DIR/gomenarr.log)
+	LogMaxSizeMB  int    // Size at which the log file is rotated, 0 never rotates (default: 100)
+	LogMaxAgeDays int    // Rotated files older than this are deleted, 0 keeps them (default: 0)
+	LogMaxBackups int    // Rotated files kept, 0 keeps them all (default: 5)
+	LogCompress   bool   // Gzip rotated files (default: false)
+
+	// Span export to an OTLP/HTTP collector (OTEL_EXPORTER_OTLP_ENDPOINT empty disables tracing)
+	Tracing TracingConfig
+
+	// Run the pipeline without starting downloads or deleting media, logging what would happen
+	DryRun bool
+
+	// Deprecated variable names found while loading, logged at startup
+	Deprecations []Deprecation
+}
+
+// TracingConfig holds where and how many traces are exported
+type TracingConfig struct {
+	Endpoint    string  // OTLP/HTTP base URL (e.g. http://tempo:4318)
+	ServiceName string  // default: gomenarr
+	SampleRatio float64 // Share of the traces exported (default: 1)
+}
+
+// RateLimitConfig holds the requests per second sent to each external API host, 0 for unlimited
+type RateLimitConfig struct {
+	Trakt   float64
+	Newznab float64 // Per indexer host
+	TorBox  float64
+}
+
+// ProxyConfig holds the proxy of the requests to each external API
+// Proxies are http://, https:// or socks5:// URLs, with credentials as user:password@.
+// ProxyDirect ignores the HTTP_PROXY/HTTPS_PROXY environment, empty follows it.
+type ProxyConfig struct {
+	Trakt   string
+	Newznab string // Every indexer
+	TorBox  string
+}
+
+// ProxyDirect connects without a proxy, ignoring the proxy environment variables
+const ProxyDirect = "direct"
+
+// Validate checks the proxies are usable
+func (p ProxyConfig) Validate() error {
+	for name, value := range map[string]string{"TRAKT_PROXY": p.Trakt, "NEWZNAB_PROXY": p.Newznab, "TORBOX_PROXY": p.TorBox} {
+		if value == "" || value == ProxyDirect {
+			continue
+		}
+		proxyURL, err := url.Parse(value)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("%s must be a proxy URL (e.g. socks5://host:1080) or %q", name, ProxyDirect)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("%s scheme must be http, https or socks5", name)
+		}
+	}
+	return nil
+}
+
+// NetworkConfig holds how external API hosts are resolved and dialed
+type NetworkConfig struct {
+	IPFamily  string // "ipv4" or "ipv6" to only connect over one, empty for both
+	DNSServer string // host:port of the DNS server resolving host names, empty for the system resolver
+}
+
+// IP families
+const (
+	IPFamilyV4 = "ipv4"
+	IPFamilyV6 = "ipv6"
+)
+
+// RetryConfig holds the retries of rate limited (429) and unavailable (502, 503, 504) responses
+type RetryConfig struct {
+	MaxRetries int // Retries with exponential backoff, Retry-After is honored when provided
+	MaxWait    int // Seconds, responses asking for a longer wait are not retried
+}
+
+// CircuitBreakerConfig holds when requests to a failing external API host stop being sent
+type CircuitBreakerConfig struct {
+	Failures int // Consecutive failures (network errors, timeouts, 5xx) opening the breaker, 0 disables it
+	Cooldown int // Seconds requests fail fast before the host is probed again
+}
+
+// StorageConfig holds the backend keeping the Trakt token and the blacklist
+type StorageConfig struct {
+	Backend string // file, s3, etcd or consul
+	URL     string // Endpoint of the remote backend
+	Prefix  string // Prepended to the keys of remote backends (default: "gomenarr/")
+
+	// S3-compatible bucket
+	Bucket    string
+	Region    string // default: us-east-1
+	AccessKey string
+	SecretKey string
+
+	// etcd authentication
+	Username string
+	Password string
+
+	// Consul ACL token
+	Token string
+}
+
+// TraktListConfig holds the configuration of a custom Trakt list
+type TraktListConfig struct {
+	Name     string
+	Path     string   // API path, e.g. "users/justin/lists/imdb-top-250" or "lists/2142753"
+	Types    []string // "movies" and/or "shows" (default: both)
+	Strategy string   // Episodes downloaded for shows: "next" (default) or "season"
+}
+
+// IndexerConfig holds the configuration of a single Newznab or Torznab indexer
+type IndexerConfig struct {
+	Name       string
+	Type       string // "newznab" (usenet, default) or "torznab" (torrents, e.g. Jackett/Prowlarr)
+	URL        string
+	APIKey     string
+	Categories []string // Newznab category IDs (e.g. 2000, 5000), empty for all
+	Priority   int      // Lower is preferred when the same release is found on several indexers
+
+	// Categories of the text searches for anime shows, which use absolute episode numbers
+	AnimeCategories []string
+
+	// Torrent health (only applies to results carrying Torznab seeders/freeleech attributes)
+	MinSeeders      int  // Results with fewer seeders are dropped (default: 1)
+	MinLeechers     int  // Results with fewer leechers are dropped (default: 0)
+	FreeleechOnly   bool // Drop results that are not freeleech
+	SeedersWeight   int  // Score added at 100+ seeders, scaled linearly below
+	FreeleechWeight int  // Score added to freeleech results
+
+	// Post age filters, 0 for none
+	RetentionDays int // Usenet provider retention, older posts are dropped
+	MinAgeMinutes int // Newer posts are dropped, they may not be propagated yet
+	MaxAgeDays    int // Older posts are dropped
+
+	// Results requested per search type, 0 for the indexer default (capped to the indexer maximum)
+	MovieLimit   int
+	EpisodeLimit int
+	SeasonLimit  int
+	Sort         string // "date" or "size" (newest/largest first) where supported, empty for the indexer order
+
+	// Certificate pins of the indexer host: "sha256/<base64>" public key (SPKI) pins or hex
+	// SHA-256 certificate fingerprints. Requests fail when the certificate matches none.
+	TLSPins []string
+
+	// Self-hosted indexers (e.g. NZBHydra behind an internal CA): skip the certificate
+	// verification, pins still apply, or trust the CAs of a PEM bundle besides the system ones
+	TLSSkipVerify bool
+	TLSCAFile     string
+}
+
+// QualityProfileConfig holds the configuration of a quality profile
+// Resolutions are given as tags such as 2160p, 1080p or 720p.
+type QualityProfileConfig struct {
+	Name      string   // "movie" and "tv" are the media type defaults
+	Preferred string   // Resolution ranked above every other one
+	Min       string   // Lowest accepted resolution
+	Max       string   // Highest accepted resolution
+	Sources   []string // Preferred sources, best first (e.g. remux, bluray, web-dl)
+	Codecs    []string // Preferred codecs, best first (e.g. x265, x264)
+	Shows     []string // IMDB IDs using this profile
+	Scoring   ScoringConfig
+
+	PreferredGroups []string // Preferred release groups, best first (e.g. FLUX, NTb)
+	AvoidedGroups   []string // Release groups ranked below every other one (e.g. YIFY)
+	Sizes           []string // Size limits in GB per resolution, e.g. 1080p:2-40~8 (~ the preferred size)
+
+	Languages         []string // Preferred audio languages, best first (e.g. fr, en)
+	RequiredLanguages []string // Audio languages of which releases must carry one
+	RejectedLanguages []string // Audio languages of rejected dubs (e.g. de)
+	Subtitles         []string // Preferred subtitle languages, best first (e.g. fr for VOSTFR)
+}
+
+// ScoringConfig holds the weights of the release quality score
+// The resolution, source, release group, codec, size, language and subtitle ranks of a release
+// are multiplied by their weight and summed. The defaults keep each part from outweighing the
+// one above it, the language ranks weigh a resolution step.
+type ScoringConfig struct {
+	Resolution int
+	Source     int
+	Group      int
+	Codec      int
+	Size       int
+	Language   int
+	Subtitle   int
+}
+
+// Validate checks the weights are usable
+func (s ScoringConfig) Validate() error {
+	if s.Resolution < 0 || s.Source < 0 || s.Group < 0 || s.Codec < 0 || s.Size < 0 || s.Language < 0 || s.Subtitle < 0 {
+		return fmt.Errorf("scoring weights must not be negative")
+	}
+	sum := s.Resolution + s.Source + s.Group + s.Codec + s.Size + s.Language + s.Subtitle
+	if sum == 0 {
+		return fmt.Errorf("at least one scoring weight must be positive")
+	}
+	if sum > maxScoringWeight {
+		return fmt.Errorf("scoring weights must not sum above %d", maxScoringWeight)
+	}
+	return nil
+}
+
+// MaintenanceWindowConfig holds a recurring maintenance window
+type MaintenanceWindowConfig struct {
+	Start    string        // Cron spec of the window start, e.g. "CRON_TZ=UTC 45 23 * * *"
+	Duration time.Duration // e.g. 30m
+}
+
+// WebhookConfig holds the configuration of a generic outbound webhook
+type WebhookConfig struct {
+	Name    string
+	URL     string
+	Method  string            // HTTP method (default: POST)
+	Headers map[string]string // Extra request headers
+	Body    string            // Go template rendered with the event, JSON-encoded event if empty
+	Events  []string          // Event types to send, empty for all
+}
+
+// StorageQuotaConfig holds the storage budget of a library
+type StorageQuotaConfig struct {
+	Name    string
+	Library string  // Custom Trakt list name, source (watchlist, favorites, manual) or media type (movie, tv)
+	MaxGB   float64 // Size of the downloading and completed releases of the library
+	Policy  string  // Grab exceeding the budget: "skip" (default), "smaller" release or "evict" the oldest watched media
 }
 
-// Load loads configuration from environment variables and .env file
+// Storage quota policies
+const (
+	QuotaPolicySkip    = "skip"
+	QuotaPolicySmaller = "smaller"
+	QuotaPolicyEvict   = "evict"
+)
+
+// HookConfig holds the configuration of a script hook
+type HookConfig struct {
+	Name    string
+	Stage   string        // pre-grab, post-import, pre-delete or cycle-complete
+	Command string        // Run with sh -c, the context is passed as GOMENARR_* environment variables
+	Timeout time.Duration // Default: 30s
+	Policy  string        // "ignore" (default) or "abort": a failing pre-grab/pre-delete hook cancels the grab/deletion
+}
+
+// Script hook stages and failure policies
+const (
+	HookStagePreGrab       = "pre-grab"
+	HookStagePostImport    = "post-import"
+	HookStagePreDelete     = "pre-delete"
+	HookStageCycleComplete = "cycle-complete"
+
+	HookPolicyIgnore = "ignore"
+	HookPolicyAbort  = "abort"
+)
+
+// Default quality profile names
+const (
+	QualityProfileMovie = "movie"
+	QualityProfileTV    = "tv"
+)
+
+// maxScoringWeight bounds the sum of the scoring weights
+const maxScoringWeight = 1000000
+
+// maxQualityProfiles is the highest QUALITY_PROFILE_<n>_* index scanned for named profiles
+const maxQualityProfiles = 20
+
+// maxWebhooks is the highest WEBHOOK_<n>_* index scanned for additional webhooks
+const maxWebhooks = 10
+
+// maxHooks is the highest HOOK_<n>_* index scanned for script hooks
+const maxHooks = 10
+
+// maxStorageQuotas is the highest STORAGE_QUOTA_<n>_* index scanned for storage budgets
+const maxStorageQuotas = 10
+
+// Episode strategies of custom Trakt lists
+const (
+	ListStrategyNext     = "next"     // Next unwatched episode, like the watchlist
+	ListStrategySeason   = "season"   // Season pack or next episodes, like favorites
+	ListStrategyBackfill = "backfill" // Every unwatched season and episode
+)
+
+// Rename modes of completed downloads
+const (
+	RenameModeMove     = "move"
+	RenameModeHardlink = "hardlink"
+	RenameModeCopy     = "copy"
+)
+
+// maxTraktLists is the highest TRAKT_LIST_<n>_* index scanned for additional lists
+const maxTraktLists = 20
+
+// Indexer types
+const (
+	IndexerTypeNewznab = "newznab"
+	IndexerTypeTorznab = "torznab"
+)
+
+// Indexer result orders
+const (
+	IndexerSortDate = "date"
+	IndexerSortSize = "size"
+)
+
+// defaultMinSeeders drops dead torrents unless an indexer overrides it
+const defaultMinSeeders = 1
+
+// defaultAnimeCategory is the Newznab TV/Anime category
+const defaultAnimeCategory = "5070"
+
+// maxIndexers is the highest NEWZNAB_<n>_* index scanned for additional indexers
+const maxIndexers = 20
+
+// defaultConfigFile is the YAML config file read when CONFIG_FILE is not set
+const defaultConfigFile = "config.yaml"
+
+// Load loads configuration from environment variables, .env file and config.yaml
+// Environment variables win over .env entries, which win over config.yaml settings.
 func Load() (*Config, error) {
-	// Setup viper FIRST to load .env file
-	viper.SetConfigName(".env")
-	viper.SetConfigType("env")
-	viper.AddConfigPath(".")
 	viper.AutomaticEnv()
 
+	// Load config.yaml first (flat keys, e.g. "newznab_url: ..."), a missing default file is ignored
+	configFile := viper.GetString("CONFIG_FILE")
+	if configFile == "" {
+		configFile = defaultConfigFile
+	}
+	if _, err := os.Stat(configFile); err == nil || viper.GetString("CONFIG_FILE") != "" {
+		viper.SetConfigFile(configFile)
+		viper.SetConfigType("yaml")
+		if err := viper.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
 	// Load .env file if it exists (ignore if not found)
-	_ = viper.ReadInConfig()
+	viper.SetConfigFile(".env")
+	viper.SetConfigType("env")
+	_ = viper.MergeInConfig()
+
+	// Settings under a deprecated name (GOMENARR_*) are moved to the current one
+	deprecations := applyDeprecations()
 
 	// Set defaults
 	viper.SetDefault("TRAKT_SYNC_DAYS", 3)
+	viper.SetDefault("TRAKT_TOKEN_REFRESH_HOURS", 24)
+	viper.SetDefault("TRAKT_METADATA_LANGUAGE", "en")
 	viper.SetDefault("DOWNLOAD_TIMEOUT_MINUTES", 30)
+	viper.SetDefault("DOWNLOAD_MAX_RETRIES", 5)
+	viper.SetDefault("DOWNLOAD_RETRY_BACKOFF", "0s,5m,15m,1h")
+	viper.SetDefault("BACKFILL_CONCURRENCY", 2)
+	viper.SetDefault("COLD_START_GRABS", 10)
+	viper.SetDefault("SEARCH_BACKOFF_MINUTES", 60)
+	viper.SetDefault("SEARCH_BACKOFF_MAX_MINUTES", 1440)
+	viper.SetDefault("SEARCH_CANDIDATE_TTL_HOURS", 168)
+	viper.SetDefault("IDLE_INTERVAL_HOURS", 12)
+	viper.SetDefault("RECOVERY_MAX_AGE_HOURS", 72)
+	viper.SetDefault("MEDIA_TIMEOUT_SECONDS", 300)
+	viper.SetDefault("TASK_STALL_MINUTES", 60)
+	viper.SetDefault("MAINTENANCE_TASKS", "search,rss_sync,upgrade,season_pack_upgrade,gap_fill")
 	viper.SetDefault("SERVER_PORT", "8080")
 	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_OUTPUT", "stdout")
+	viper.SetDefault("LOG_MAX_SIZE_MB", 100)
+	viper.SetDefault("LOG_MAX_BACKUPS", 5)
+	viper.SetDefault("OTEL_SERVICE_NAME", "gomenarr")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("MEDIA_SERVER_TYPE", "plex")
+	viper.SetDefault("MEDIA_SERVER_REFRESH_INTERVAL", 60)
+	viper.SetDefault("IMPORT_CONCURRENCY", 2)
+	viper.SetDefault("CLEANUP_DELETE_WORKERS", 4)
+	viper.SetDefault("CLEANUP_DELETE_RETRIES", 3)
+	viper.SetDefault("RENAME_MOVIE_TEMPLATE", "{Title} ({Year})/{Title} ({Year}) - {Quality}")
+	viper.SetDefault("RENAME_EPISODE_TEMPLATE", "{Title} ({Year})/Season {Season:02}/{Title} - S{Season:02}E{Episode:02} - {Quality}")
+	viper.SetDefault("SCORING_RESOLUTION_WEIGHT", 10000)
+	viper.SetDefault("SCORING_SOURCE_WEIGHT", 100)
+	viper.SetDefault("SCORING_GROUP_WEIGHT", 10)
+	viper.SetDefault("SCORING_CODEC_WEIGHT", 3)
+	viper.SetDefault("SCORING_SIZE_WEIGHT", 1)
+	viper.SetDefault("SCORING_LANGUAGE_WEIGHT", 100000)
+	viper.SetDefault("SCORING_SUBTITLE_WEIGHT", 1000)
+	viper.SetDefault("TORBOX_API_TIMEOUT", 30)
+	viper.SetDefault("STORAGE_BACKEND", "file")
+	viper.SetDefault("STORAGE_PREFIX", "gomenarr/")
+	viper.SetDefault("TORBOX_UPLOAD_TIMEOUT", 300)
+	viper.SetDefault("TORBOX_UPLOAD_RETRIES", 3)
+	viper.SetDefault("TRAKT_RATE_LIMIT", 2)
+	viper.SetDefault("NEWZNAB_RATE_LIMIT", 1)
+	viper.SetDefault("TORBOX_RATE_LIMIT", 2)
+	viper.SetDefault("HTTP_MAX_RETRIES", 3)
+	viper.SetDefault("HTTP_MAX_RETRY_WAIT", 60)
+	viper.SetDefault("CIRCUIT_BREAKER_FAILURES", 5)
+	viper.SetDefault("CIRCUIT_BREAKER_COOLDOWN", 60)
 
 	// NOW read CONFIG_DIR from viper (which has loaded .env file)
 	configDir := viper.GetString("CONFIG_DIR")
@@ -82,27 +592,207 @@ func Load() (*Config, error) {
 		TraktClientSecret: viper.GetString("TRAKT_CLIENT_SECRET"),
 		TraktSyncDays:     viper.GetInt("TRAKT_SYNC_DAYS"),
 
+		TraktTokenRefreshHours: viper.GetInt("TRAKT_TOKEN_REFRESH_HOURS"),
+
+		TraktWatchlistRemoval:  strings.ToLower(viper.GetString("TRAKT_WATCHLIST_REMOVAL")),
+		TraktSyncCollection:    viper.GetBool("TRAKT_SYNC_COLLECTION"),
+		TraktCollectionSources: splitList(viper.GetString("TRAKT_COLLECTION_SOURCES")),
+		TraktMetadataLanguage:  strings.ToLower(viper.GetString("TRAKT_METADATA_LANGUAGE")),
+		TraktLists:             loadTraktLists(),
+
 		// Newznab
-		NewznabURL: viper.GetString("NEWZNAB_URL"),
-		NewznabKey: viper.GetString("NEWZNAB_KEY"),
+		Indexers: loadIndexers(),
 
 		// TorBox
-		TorBoxAPIKey: viper.GetString("TORBOX_API_KEY"),
+		TorBoxAPIKey:              viper.GetString("TORBOX_API_KEY"),
+		TorBoxAPITimeout:          viper.GetInt("TORBOX_API_TIMEOUT"),
+		TorBoxUploadTimeout:       viper.GetInt("TORBOX_UPLOAD_TIMEOUT"),
+		TorBoxUploadRetries:       viper.GetInt("TORBOX_UPLOAD_RETRIES"),
+		TorBoxUploadGzip:          viper.GetBool("TORBOX_UPLOAD_GZIP"),
+		TorBoxDownloadParams:      viper.GetString("TORBOX_DOWNLOAD_PARAMS"),
+		TorBoxDownloadParamsMovie: viper.GetString("TORBOX_DOWNLOAD_PARAMS_MOVIE"),
+		TorBoxDownloadParamsTV:    viper.GetString("TORBOX_DOWNLOAD_PARAMS_TV"),
+
+		// Outbound requests
+		RateLimits: RateLimitConfig{
+			Trakt:   viper.GetFloat64("TRAKT_RATE_LIMIT"),
+			Newznab: viper.GetFloat64("NEWZNAB_RATE_LIMIT"),
+			TorBox:  viper.GetFloat64("TORBOX_RATE_LIMIT"),
+		},
+		Retry: RetryConfig{
+			MaxRetries: viper.GetInt("HTTP_MAX_RETRIES"),
+			MaxWait:    viper.GetInt("HTTP_MAX_RETRY_WAIT"),
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			Failures: viper.GetInt("CIRCUIT_BREAKER_FAILURES"),
+			Cooldown: viper.GetInt("CIRCUIT_BREAKER_COOLDOWN"),
+		},
+		Proxies: ProxyConfig{
+			Trakt:   viper.GetString("TRAKT_PROXY"),
+			Newznab: viper.GetString("NEWZNAB_PROXY"),
+			TorBox:  viper.GetString("TORBOX_PROXY"),
+		},
+		Network: NetworkConfig{
+			IPFamily:  strings.ToLower(viper.GetString("NETWORK_IP_FAMILY")),
+			DNSServer: viper.GetString("NETWORK_DNS_SERVER"),
+		},
+
+		// Quality
+		Scoring: ScoringConfig{
+			Resolution: viper.GetInt("SCORING_RESOLUTION_WEIGHT"),
+			Source:     viper.GetInt("SCORING_SOURCE_WEIGHT"),
+			Group:      viper.GetInt("SCORING_GROUP_WEIGHT"),
+			Codec:      viper.GetInt("SCORING_CODEC_WEIGHT"),
+			Size:       viper.GetInt("SCORING_SIZE_WEIGHT"),
+			Language:   viper.GetInt("SCORING_LANGUAGE_WEIGHT"),
+			Subtitle:   viper.GetInt("SCORING_SUBTITLE_WEIGHT"),
+		},
+
+		BlacklistURLs: splitList(viper.GetString("BLACKLIST_URLS")),
 
 		// Download
-		DownloadTimeoutMinutes: viper.GetInt("DOWNLOAD_TIMEOUT_MINUTES"),
+		DownloadTimeoutMinutes:  viper.GetInt("DOWNLOAD_TIMEOUT_MINUTES"),
+		DownloadMaxRetries:      viper.GetInt("DOWNLOAD_MAX_RETRIES"),
+		DownloadMinFreeGB:       viper.GetInt("DOWNLOAD_MIN_FREE_GB"),
+		DownloadDiskPath:        viper.GetString("DOWNLOAD_DISK_PATH"),
+		RedownloadWatched:       viper.GetBool("REDOWNLOAD_WATCHED"),
+		BackfillConcurrency:     viper.GetInt("BACKFILL_CONCURRENCY"),
+		ColdStartGrabs:          viper.GetInt("COLD_START_GRABS"),
+		MaxGrabsPerCycle:        viper.GetInt("MAX_GRABS_PER_CYCLE"),
+		AirOffsetMinutes:        viper.GetInt("AIR_OFFSET_MINUTES"),
+		SearchBackoffMinutes:    viper.GetInt("SEARCH_BACKOFF_MINUTES"),
+		SearchBackoffMaxMinutes: viper.GetInt("SEARCH_BACKOFF_MAX_MINUTES"),
+		SearchCandidateTTLHours: viper.GetInt("SEARCH_CANDIDATE_TTL_HOURS"),
+		SearchRSSEnabled:        viper.GetBool("SEARCH_RSS_ENABLED"),
+		SearchWidenAfter:        viper.GetInt("SEARCH_WIDEN_AFTER"),
+		UpgradeEnabled:          viper.GetBool("UPGRADE_ENABLED"),
+		SeasonPackUpgrade:       viper.GetBool("SEASON_PACK_UPGRADE"),
+
+		// Scheduler
+		TasksDisabled: splitList(viper.GetString("TASKS_DISABLED")),
+		TaskSchedules: loadTaskSchedules(),
+		StartupTasks:  splitList(viper.GetString("STARTUP_TASKS")),
+
+		RecoveryMaxAgeHours: viper.GetInt("RECOVERY_MAX_AGE_HOURS"),
+		GapFillShows:        viper.GetInt("GAP_FILL_SHOWS"),
+		MediaTimeoutSeconds: viper.GetInt("MEDIA_TIMEOUT_SECONDS"),
+		TaskStallMinutes:    viper.GetInt("TASK_STALL_MINUTES"),
+
+		MaintenanceTasks: splitList(viper.GetString("MAINTENANCE_TASKS")),
+
+		IdleIntervalHours: viper.GetInt("IDLE_INTERVAL_HOURS"),
+		IdleTasks:         splitList(viper.GetString("IDLE_TASKS")),
 
 		// Server
-		ServerPort: viper.GetString("SERVER_PORT"),
+		ServerPort:                 viper.GetString("SERVER_PORT"),
+		ServerAPIKey:               viper.GetString("SERVER_API_KEY"),
+		ServerWebhookSecret:        viper.GetString("SERVER_WEBHOOK_SECRET"),
+		ServerWebhookSigningSecret: viper.GetString("SERVER_WEBHOOK_SIGNING_SECRET"),
+
+		// Library
+		LibraryDirs:            splitList(viper.GetString("LIBRARY_DIRS")),
+		CleanupRemoveArtifacts: viper.GetBool("CLEANUP_REMOVE_ARTIFACTS"),
+		CleanupDeleteWorkers:   viper.GetInt("CLEANUP_DELETE_WORKERS"),
+		CleanupDeleteRetries:   viper.GetInt("CLEANUP_DELETE_RETRIES"),
+
+		// Renaming
+		RenameMode:            strings.ToLower(viper.GetString("RENAME_MODE")),
+		DownloadDir:           viper.GetString("DOWNLOAD_DIR"),
+		RenameMoviesDir:       viper.GetString("RENAME_MOVIES_DIR"),
+		RenameShowsDir:        viper.GetString("RENAME_SHOWS_DIR"),
+		RenameMovieTemplate:   viper.GetString("RENAME_MOVIE_TEMPLATE"),
+		RenameEpisodeTemplate: viper.GetString("RENAME_EPISODE_TEMPLATE"),
+		QuarantineDir:         viper.GetString("QUARANTINE_DIR"),
+
+		// Media server
+		MediaServerType:            viper.GetString("MEDIA_SERVER_TYPE"),
+		MediaServerURL:             viper.GetString("MEDIA_SERVER_URL"),
+		MediaServerToken:           viper.GetString("MEDIA_SERVER_TOKEN"),
+		MediaServerRefreshInterval: viper.GetInt("MEDIA_SERVER_REFRESH_INTERVAL"),
+		ImportConcurrency:          viper.GetInt("IMPORT_CONCURRENCY"),
+
+		// Notifications
+		DiscordWebhookURL: viper.GetString("DISCORD_WEBHOOK_URL"),
+		DiscordEvents:     splitList(viper.GetString("DISCORD_EVENTS")),
+		TelegramBotToken:  viper.GetString("TELEGRAM_BOT_TOKEN"),
+		TelegramChatID:    viper.GetString("TELEGRAM_CHAT_ID"),
+		TelegramEvents:    splitList(viper.GetString("TELEGRAM_EVENTS")),
+		PushoverToken:     viper.GetString("PUSHOVER_TOKEN"),
+		PushoverUser:      viper.GetString("PUSHOVER_USER"),
+		PushoverPriority:  viper.GetInt("PUSHOVER_PRIORITY"),
+		PushoverEvents:    splitList(viper.GetString("PUSHOVER_EVENTS")),
 
 		// Paths
-		TokenFile:     filepath.Join(configDir, "token.json"),
-		BlacklistFile: filepath.Join(configDir, "blacklist.txt"),
-		DatabaseFile:  filepath.Join(configDir, "gomenarr.db"),
+		ConfigDir:    configDir,
+		DatabaseFile: filepath.Join(configDir, "gomenarr.db"),
+
+		Storage: StorageConfig{
+			Backend:   strings.ToLower(viper.GetString("STORAGE_BACKEND")),
+			URL:       viper.GetString("STORAGE_URL"),
+			Prefix:    viper.GetString("STORAGE_PREFIX"),
+			Bucket:    viper.GetString("STORAGE_S3_BUCKET"),
+			Region:    viper.GetString("STORAGE_S3_REGION"),
+			AccessKey: viper.GetString("STORAGE_S3_ACCESS_KEY"),
+			SecretKey: viper.GetString("STORAGE_S3_SECRET_KEY"),
+			Username:  viper.GetString("STORAGE_ETCD_USERNAME"),
+			Password:  viper.GetString("STORAGE_ETCD_PASSWORD"),
+			Token:     viper.GetString("STORAGE_CONSUL_TOKEN"),
+		},
 
 		// Logging
-		LogLevel: viper.GetString("LOG_LEVEL"),
+		LogLevel:      viper.GetString("LOG_LEVEL"),
+		LogOutput:     strings.ToLower(viper.GetString("LOG_OUTPUT")),
+		LogFile:       viper.GetString("LOG_FILE"),
+		LogMaxSizeMB:  viper.GetInt("LOG_MAX_SIZE_MB"),
+		LogMaxAgeDays: viper.GetInt("LOG_MAX_AGE_DAYS"),
+		LogMaxBackups: viper.GetInt("LOG_MAX_BACKUPS"),
+		LogCompress:   viper.GetBool("LOG_COMPRESS"),
+
+		Tracing: TracingConfig{
+			Endpoint:    viper.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"),
+			ServiceName: viper.GetString("OTEL_SERVICE_NAME"),
+			SampleRatio: viper.GetFloat64("TRACING_SAMPLE_RATIO"),
+		},
+
+		DryRun: viper.GetBool("DRY_RUN"),
+
+		Deprecations: deprecations,
+	}
+
+	config.QualityProfiles = loadQualityProfiles(config.Scoring)
+	if config.QuarantineDir == "" {
+		config.QuarantineDir = filepath.Join(configDir, "quarantine")
+	}
+
+	windows, err := loadMaintenanceWindows()
+	if err != nil {
+		return nil, err
+	}
+	config.MaintenanceWindows = windows
+
+	backoff, err := loadRetryBackoff()
+	if err != nil {
+		return nil, err
+	}
+	config.DownloadRetryBackoff = backoff
+
+	webhooks, err := loadWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	config.Webhooks = webhooks
+
+	hooks, err := loadHooks()
+	if err != nil {
+		return nil, err
+	}
+	config.Hooks = hooks
+
+	quotas, err := loadStorageQuotas()
+	if err != nil {
+		return nil, err
 	}
+	config.StorageQuotas = quotas
 
 	// Validate required fields
 	if config.TraktClientID == "" {
@@ -111,15 +801,602 @@ func Load() (*Config, error) {
 	if config.TraktClientSecret == "" {
 		return nil, fmt.Errorf("TRAKT_CLIENT_SECRET is required")
 	}
-	if config.NewznabURL == "" {
+	for _, list := range config.TraktLists {
+		if list.Strategy != ListStrategyNext && list.Strategy != ListStrategySeason && list.Strategy != ListStrategyBackfill {
+			return nil, fmt.Errorf("invalid strategy %q for Trakt list %s (next, season or backfill)", list.Strategy, list.Name)
+		}
+		for _, listType := range list.Types {
+			if listType != "movies" && listType != "shows" {
+				return nil, fmt.Errorf("invalid type %q for Trakt list %s (movies or shows)", listType, list.Name)
+			}
+		}
+	}
+	if len(config.Indexers) == 0 {
 		return nil, fmt.Errorf("NEWZNAB_URL is required")
 	}
-	if config.NewznabKey == "" {
-		return nil, fmt.Errorf("NEWZNAB_KEY is required")
+	for _, indexer := range config.Indexers {
+		if indexer.APIKey == "" {
+			return nil, fmt.Errorf("API key is required for indexer %s", indexer.Name)
+		}
+		if indexer.Type != IndexerTypeNewznab && indexer.Type != IndexerTypeTorznab {
+			return nil, fmt.Errorf("invalid type %q for indexer %s (newznab or torznab)", indexer.Type, indexer.Name)
+		}
+		if indexer.Sort != "" && indexer.Sort != IndexerSortDate && indexer.Sort != IndexerSortSize {
+			return nil, fmt.Errorf("invalid sort %q for indexer %s (date or size)", indexer.Sort, indexer.Name)
+		}
+		if indexer.MovieLimit < 0 || indexer.EpisodeLimit < 0 || indexer.SeasonLimit < 0 {
+			return nil, fmt.Errorf("result limits of indexer %s must not be negative", indexer.Name)
+		}
+		if indexer.RetentionDays < 0 || indexer.MinAgeMinutes < 0 || indexer.MaxAgeDays < 0 {
+			return nil, fmt.Errorf("age limits of indexer %s must not be negative", indexer.Name)
+		}
+	}
+	if config.RateLimits.Trakt < 0 || config.RateLimits.Newznab < 0 || config.RateLimits.TorBox < 0 {
+		return nil, fmt.Errorf("rate limits must not be negative")
+	}
+	if config.Retry.MaxRetries < 0 || config.Retry.MaxWait < 0 {
+		return nil, fmt.Errorf("HTTP_MAX_RETRIES and HTTP_MAX_RETRY_WAIT must not be negative")
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+	if config.CircuitBreaker.Failures < 0 || config.CircuitBreaker.Cooldown < 0 {
+		return nil, fmt.Errorf("CIRCUIT_BREAKER_FAILURES and CIRCUIT_BREAKER_COOLDOWN must not be negative")
+	}
+	if err := config.Proxies.Validate(); err != nil {
+		return nil, err
+	}
+	if family := config.Network.IPFamily; family != "" && family != IPFamilyV4 && family != IPFamilyV6 {
+		return nil, fmt.Errorf("NETWORK_IP_FAMILY must be %s or %s", IPFamilyV4, IPFamilyV6)
+	}
+	for _, proxy := range splitList(viper.GetString("SERVER_TRUSTED_PROXIES")) {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("SERVER_TRUSTED_PROXIES: %w", err)
+		}
+		config.ServerTrustedProxies = append(config.ServerTrustedProxies, network)
+	}
+	if server := config.Network.DNSServer; server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			config.Network.DNSServer = net.JoinHostPort(server, "53")
+		}
+	}
+	if err := config.Scoring.Validate(); err != nil {
+		return nil, err
+	}
+	for _, profile := range config.QualityProfiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("name is required for named quality profiles")
+		}
+		if err := profile.Scoring.Validate(); err != nil {
+			return nil, fmt.Errorf("quality profile %s: %w", profile.Name, err)
+		}
+	}
+	if config.BackfillConcurrency < 1 {
+		return nil, fmt.Errorf("BACKFILL_CONCURRENCY must be at least 1")
+	}
+	if config.ColdStartGrabs < 0 || config.MaxGrabsPerCycle < 0 {
+		return nil, fmt.Errorf("COLD_START_GRABS and MAX_GRABS_PER_CYCLE must not be negative")
+	}
+	if config.AirOffsetMinutes < 0 {
+		return nil, fmt.Errorf("AIR_OFFSET_MINUTES must not be negative")
+	}
+	if config.DownloadMaxRetries < 0 {
+		return nil, fmt.Errorf("DOWNLOAD_MAX_RETRIES must not be negative")
+	}
+	if config.TraktWatchlistRemoval == "" && viper.GetBool("TRAKT_REMOVE_WATCHED_FROM_WATCHLIST") {
+		config.TraktWatchlistRemoval = "watched"
+	}
+	switch config.TraktWatchlistRemoval {
+	case "off":
+		config.TraktWatchlistRemoval = ""
+	case "", "downloaded", "watched":
+	default:
+		return nil, fmt.Errorf("invalid TRAKT_WATCHLIST_REMOVAL %q (off, downloaded or watched)", config.TraktWatchlistRemoval)
+	}
+
+	switch config.LogOutput {
+	case "stdout", "file", "both":
+	default:
+		return nil, fmt.Errorf("invalid LOG_OUTPUT %q (stdout, file or both)", config.LogOutput)
+	}
+	if config.LogFile == "" {
+		config.LogFile = filepath.Join(configDir, "gomenarr.log")
+	}
+	if config.LogMaxSizeMB < 0 || config.LogMaxAgeDays < 0 || config.LogMaxBackups < 0 {
+		return nil, fmt.Errorf("LOG_MAX_SIZE_MB, LOG_MAX_AGE_DAYS and LOG_MAX_BACKUPS must not be negative")
+	}
+	if config.DownloadMinFreeGB < 0 {
+		return nil, fmt.Errorf("DOWNLOAD_MIN_FREE_GB must not be negative")
+	}
+	if config.DownloadDiskPath == "" {
+		config.DownloadDiskPath = config.DownloadDir
+	}
+	if config.DownloadMinFreeGB > 0 && config.DownloadDiskPath == "" {
+		return nil, fmt.Errorf("DOWNLOAD_DISK_PATH or DOWNLOAD_DIR is required with DOWNLOAD_MIN_FREE_GB")
+	}
+	if config.SearchBackoffMinutes < 0 || config.SearchBackoffMaxMinutes < 0 {
+		return nil, fmt.Errorf("SEARCH_BACKOFF_MINUTES and SEARCH_BACKOFF_MAX_MINUTES must not be negative")
+	}
+	if config.IdleIntervalHours < 0 {
+		return nil, fmt.Errorf("IDLE_INTERVAL_HOURS must not be negative")
+	}
+	if config.SearchWidenAfter < 0 {
+		return nil, fmt.Errorf("SEARCH_WIDEN_AFTER must not be negative")
+	}
+	if config.SearchCandidateTTLHours < 0 {
+		return nil, fmt.Errorf("SEARCH_CANDIDATE_TTL_HOURS must not be negative")
+	}
+	if config.GapFillShows < 0 {
+		return nil, fmt.Errorf("GAP_FILL_SHOWS must not be negative")
+	}
+	if config.MediaTimeoutSeconds < 0 || config.TaskStallMinutes < 0 {
+		return nil, fmt.Errorf("MEDIA_TIMEOUT_SECONDS and TASK_STALL_MINUTES must not be negative")
 	}
 	if config.TorBoxAPIKey == "" {
 		return nil, fmt.Errorf("TORBOX_API_KEY is required")
 	}
+	if config.RenameMode != "" {
+		if config.RenameMode != RenameModeMove && config.RenameMode != RenameModeHardlink && config.RenameMode != RenameModeCopy {
+			return nil, fmt.Errorf("invalid RENAME_MODE %q (move, hardlink or copy)", config.RenameMode)
+		}
+		if config.DownloadDir == "" || config.RenameMoviesDir == "" || config.RenameShowsDir == "" {
+			return nil, fmt.Errorf("DOWNLOAD_DIR, RENAME_MOVIES_DIR and RENAME_SHOWS_DIR are required with RENAME_MODE")
+		}
+		// Renamed files are scanned and cleaned up like the rest of the library
+		config.LibraryDirs = appendMissing(config.LibraryDirs, config.RenameMoviesDir, config.RenameShowsDir)
+	}
 
 	return config, nil
 }
+
+// splitList splits a comma-separated configuration value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNetwork parses a network in CIDR notation, or a single address
+func parseNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address %q", value)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q", value)
+	}
+	return network, nil
+}
+
+// appendMissing appends the values not already in a list
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, item := range list {
+			if filepath.Clean(item) == filepath.Clean(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// splitPairs parses a "key=value,key2=value2" configuration value
+func splitPairs(value string) map[string]string {
+	pairs := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, found := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		pairs[key] = strings.TrimSpace(val)
+	}
+	return pairs
+}
+
+// loadQualityProfiles reads the media type defaults (QUALITY_PROFILE_MOVIE_*,
+// QUALITY_PROFILE_TV_*) and the named profiles (QUALITY_PROFILE_1_NAME, ...)
+// A profile is only returned when at least one of its settings is set.
+func loadQualityProfiles(scoring ScoringConfig) []QualityProfileConfig {
+	var profiles []QualityProfileConfig
+
+	prefixes := map[string]string{
+		"QUALITY_PROFILE_MOVIE_": QualityProfileMovie,
+		"QUALITY_PROFILE_TV_":    QualityProfileTV,
+	}
+	order := []string{"QUALITY_PROFILE_MOVIE_", "QUALITY_PROFILE_TV_"}
+	for i := 1; i <= maxQualityProfiles; i++ {
+		order = append(order, fmt.Sprintf("QUALITY_PROFILE_%d_", i))
+	}
+
+	for _, prefix := range order {
+		profile := QualityProfileConfig{
+			Name:      prefixes[prefix],
+			Preferred: viper.GetString(prefix + "PREFERRED"),
+			Min:       viper.GetString(prefix + "MIN"),
+			Max:       viper.GetString(prefix + "MAX"),
+			Sources:   splitList(strings.ToLower(viper.GetString(prefix + "SOURCES"))),
+			Codecs:    splitList(strings.ToLower(viper.GetString(prefix + "CODECS"))),
+			Shows:     splitList(viper.GetString(prefix + "SHOWS")),
+			Scoring:   scoring,
+
+			PreferredGroups: splitList(viper.GetString(prefix + "PREFERRED_GROUPS")),
+			AvoidedGroups:   splitList(viper.GetString(prefix + "AVOIDED_GROUPS")),
+			Sizes:           splitList(viper.GetString(prefix + "SIZES")),
+
+			Languages:         splitList(strings.ToLower(viper.GetString(prefix + "LANGUAGES"))),
+			RequiredLanguages: splitList(strings.ToLower(viper.GetString(prefix + "REQUIRED_LANGUAGES"))),
+			RejectedLanguages: splitList(strings.ToLower(viper.GetString(prefix + "REJECTED_LANGUAGES"))),
+			Subtitles:         splitList(strings.ToLower(viper.GetString(prefix + "SUBTITLES"))),
+		}
+		if profile.Name == "" {
+			profile.Name = strings.ToLower(viper.GetString(prefix + "NAME"))
+		}
+
+		// Weights left unset keep the global ones
+		overridden := false
+		for key, weight := range map[string]*int{
+			"RESOLUTION_WEIGHT": &profile.Scoring.Resolution,
+			"SOURCE_WEIGHT":     &profile.Scoring.Source,
+			"GROUP_WEIGHT":      &profile.Scoring.Group,
+			"CODEC_WEIGHT":      &profile.Scoring.Codec,
+			"SIZE_WEIGHT":       &profile.Scoring.Size,
+			"LANGUAGE_WEIGHT":   &profile.Scoring.Language,
+			"SUBTITLE_WEIGHT":   &profile.Scoring.Subtitle,
+		} {
+			if viper.IsSet(prefix + key) {
+				*weight = viper.GetInt(prefix + key)
+				overridden = true
+			}
+		}
+
+		if profile.Preferred == "" && profile.Min == "" && profile.Max == "" &&
+			len(profile.Sources) == 0 && len(profile.Codecs) == 0 &&
+			len(profile.PreferredGroups) == 0 && len(profile.AvoidedGroups) == 0 &&
+			len(profile.Sizes) == 0 && len(profile.Languages) == 0 && len(profile.RequiredLanguages) == 0 &&
+			len(profile.RejectedLanguages) == 0 && len(profile.Subtitles) == 0 && !overridden {
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
+
+// taskScheduleKeys are the dedicated schedule settings of the main tasks
+var taskScheduleKeys = map[string]string{
+	"SYNC_SCHEDULE":        "sync",
+	"SEARCH_SCHEDULE":      "search",
+	"CLEANUP_SCHEDULE":     "cleanup_watched",
+	"STUCK_CHECK_SCHEDULE": "stuck_check",
+}
+
+// loadTaskSchedules reads the cron schedule overrides of scheduled tasks
+// Format: "sync=0 */4 * * *;search=*/15 * * * *" (semicolons, as cron specs may hold commas)
+// The dedicated settings (SYNC_SCHEDULE, ...) override TASK_SCHEDULES.
+func loadTaskSchedules() map[string]string {
+	schedules := make(map[string]string)
+	for _, item := range strings.Split(viper.GetString("TASK_SCHEDULES"), ";") {
+		name, schedule, found := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		schedules[name] = strings.TrimSpace(schedule)
+	}
+	for key, name := range taskScheduleKeys {
+		if schedule := strings.TrimSpace(viper.GetString(key)); schedule != "" {
+			schedules[name] = schedule
+		}
+	}
+	return schedules
+}
+
+// loadRetryBackoff reads the waits before the download retries, e.g. "0s,5m,15m,1h"
+func loadRetryBackoff() ([]time.Duration, error) {
+	var backoff []time.Duration
+	for _, item := range splitList(viper.GetString("DOWNLOAD_RETRY_BACKOFF")) {
+		parsed, err := time.ParseDuration(item)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid duration %q in DOWNLOAD_RETRY_BACKOFF", item)
+		}
+		backoff = append(backoff, parsed)
+	}
+	return backoff, nil
+}
+
+// loadMaintenanceWindows reads the recurring maintenance windows
+// Format: "CRON_TZ=UTC 45 23 * * * for 30m;0 4 * * 0 for 1h" (semicolons, as cron specs may hold commas)
+func loadMaintenanceWindows() ([]MaintenanceWindowConfig, error) {
+	var windows []MaintenanceWindowConfig
+	for _, item := range strings.Split(viper.GetString("MAINTENANCE_WINDOWS"), ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		start, duration, found := strings.Cut(item, " for ")
+		if !found {
+			return nil, fmt.Errorf("invalid maintenance window %q (expected \"<cron> for <duration>\")", item)
+		}
+		parsed, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid duration in maintenance window %q", item)
+		}
+		windows = append(windows, MaintenanceWindowConfig{
+			Start:    strings.TrimSpace(start),
+			Duration: parsed,
+		})
+	}
+	return windows, nil
+}
+
+// loadTraktLists reads the primary custom list (TRAKT_LIST_PATH, TRAKT_LIST_STRATEGY, ...)
+// and the additional ones (TRAKT_LIST_1_PATH, ...)
+// Paths can be given as API paths or trakt.tv URLs.
+func loadTraktLists() []TraktListConfig {
+	var lists []TraktListConfig
+
+	prefixes := []string{"TRAKT_LIST_"}
+	for i := 1; i <= maxTraktLists; i++ {
+		prefixes = append(prefixes, fmt.Sprintf("TRAKT_LIST_%d_", i))
+	}
+
+	for _, prefix := range prefixes {
+		path := viper.GetString(prefix + "PATH")
+		if path == "" {
+			continue
+		}
+		if parsed, err := url.Parse(path); err == nil && parsed.Host != "" {
+			path = parsed.Path
+		}
+		path = strings.Trim(path, "/")
+
+		// Default name: list slug
+		name := viper.GetString(prefix + "NAME")
+		if name == "" {
+			name = path[strings.LastIndex(path, "/")+1:]
+		}
+
+		types := splitList(strings.ToLower(viper.GetString(prefix + "TYPES")))
+		if len(types) == 0 {
+			types = []string{"movies", "shows"}
+		}
+
+		strategy := strings.ToLower(viper.GetString(prefix + "STRATEGY"))
+		if strategy == "" {
+			strategy = ListStrategyNext
+		}
+
+		lists = append(lists, TraktListConfig{
+			Name:     name,
+			Path:     path,
+			Types:    types,
+			Strategy: strategy,
+		})
+	}
+
+	return lists
+}
+
+// loadWebhooks reads the primary webhook (WEBHOOK_URL, WEBHOOK_METHOD, ...) and the
+// additional ones (WEBHOOK_1_URL, WEBHOOK_1_METHOD, ...)
+// The body template can be given inline (WEBHOOK_BODY) or as a file (WEBHOOK_BODY_FILE).
+func loadWebhooks() ([]WebhookConfig, error) {
+	var webhooks []WebhookConfig
+
+	prefixes := []string{"WEBHOOK_"}
+	for i := 1; i <= maxWebhooks; i++ {
+		prefixes = append(prefixes, fmt.Sprintf("WEBHOOK_%d_", i))
+	}
+
+	for i, prefix := range prefixes {
+		webhookURL := viper.GetString(prefix + "URL")
+		if webhookURL == "" {
+			continue
+		}
+
+		name := viper.GetString(prefix + "NAME")
+		if name == "" {
+			name = fmt.Sprintf("webhook%d", i)
+		}
+
+		method := strings.ToUpper(viper.GetString(prefix + "METHOD"))
+		if method == "" {
+			method = "POST"
+		}
+
+		body := viper.GetString(prefix + "BODY")
+		if bodyFile := viper.GetString(prefix + "BODY_FILE"); bodyFile != "" {
+			data, err := os.ReadFile(bodyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %sBODY_FILE: %w", prefix, err)
+			}
+			body = string(data)
+		}
+
+		webhooks = append(webhooks, WebhookConfig{
+			Name:    name,
+			URL:     webhookURL,
+			Method:  method,
+			Headers: splitPairs(viper.GetString(prefix + "HEADERS")),
+			Body:    body,
+			Events:  splitList(viper.GetString(prefix + "EVENTS")),
+		})
+	}
+
+	return webhooks, nil
+}
+
+// loadHooks reads the script hooks (HOOK_1_STAGE, HOOK_1_COMMAND, ...)
+func loadHooks() ([]HookConfig, error) {
+	var hooks []HookConfig
+
+	for i := 1; i <= maxHooks; i++ {
+		prefix := fmt.Sprintf("HOOK_%d_", i)
+		command := viper.GetString(prefix + "COMMAND")
+		if command == "" {
+			continue
+		}
+
+		hook := HookConfig{
+			Name:    viper.GetString(prefix + "NAME"),
+			Stage:   strings.ToLower(viper.GetString(prefix + "STAGE")),
+			Command: command,
+			Timeout: 30 * time.Second,
+			Policy:  strings.ToLower(viper.GetString(prefix + "POLICY")),
+		}
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook%d", i)
+		}
+		if viper.IsSet(prefix + "TIMEOUT") {
+			hook.Timeout = time.Duration(viper.GetInt(prefix+"TIMEOUT")) * time.Second
+		}
+		if hook.Policy == "" {
+			hook.Policy = HookPolicyIgnore
+		}
+
+		switch hook.Stage {
+		case HookStagePreGrab, HookStagePostImport, HookStagePreDelete, HookStageCycleComplete:
+		default:
+			return nil, fmt.Errorf("%sSTAGE must be one of pre-grab, post-import, pre-delete, cycle-complete", prefix)
+		}
+		if hook.Policy != HookPolicyIgnore && hook.Policy != HookPolicyAbort {
+			return nil, fmt.Errorf("%sPOLICY must be ignore or abort", prefix)
+		}
+		if hook.Timeout <= 0 {
+			return nil, fmt.Errorf("%sTIMEOUT must be positive", prefix)
+		}
+
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, nil
+}
+
+// loadStorageQuotas reads the library storage budgets (STORAGE_QUOTA_1_LIBRARY, STORAGE_QUOTA_1_MAX_GB, ...)
+func loadStorageQuotas() ([]StorageQuotaConfig, error) {
+	var quotas []StorageQuotaConfig
+
+	for i := 1; i <= maxStorageQuotas; i++ {
+		prefix := fmt.Sprintf("STORAGE_QUOTA_%d_", i)
+		library := viper.GetString(prefix + "LIBRARY")
+		if library == "" {
+			continue
+		}
+
+		quota := StorageQuotaConfig{
+			Name:    viper.GetString(prefix + "NAME"),
+			Library: library,
+			MaxGB:   viper.GetFloat64(prefix + "MAX_GB"),
+			Policy:  strings.ToLower(viper.GetString(prefix + "POLICY")),
+		}
+		if quota.Name == "" {
+			quota.Name = library
+		}
+		if quota.Policy == "" {
+			quota.Policy = QuotaPolicySkip
+		}
+
+		if quota.MaxGB <= 0 {
+			return nil, fmt.Errorf("%sMAX_GB must be positive", prefix)
+		}
+		switch quota.Policy {
+		case QuotaPolicySkip, QuotaPolicySmaller, QuotaPolicyEvict:
+		default:
+			return nil, fmt.Errorf("%sPOLICY must be one of skip, smaller, evict", prefix)
+		}
+
+		quotas = append(quotas, quota)
+	}
+
+	return quotas, nil
+}
+
+// loadIndexers reads the primary indexer (NEWZNAB_URL, NEWZNAB_KEY, ...) and the
+// additional ones (NEWZNAB_1_URL, NEWZNAB_1_KEY, ...)
+func loadIndexers() []IndexerConfig {
+	var indexers []IndexerConfig
+
+	prefixes := []string{"NEWZNAB_"}
+	for i := 1; i <= maxIndexers; i++ {
+		prefixes = append(prefixes, fmt.Sprintf("NEWZNAB_%d_", i))
+	}
+
+	for i, prefix := range prefixes {
+		indexerURL := viper.GetString(prefix + "URL")
+		if indexerURL == "" {
+			continue
+		}
+
+		// Default name: indexer host
+		name := viper.GetString(prefix + "NAME")
+		if name == "" {
+			if parsed, err := url.Parse(indexerURL); err == nil && parsed.Hostname() != "" {
+				name = parsed.Hostname()
+			} else {
+				name = fmt.Sprintf("indexer%d", i)
+			}
+		}
+
+		minSeeders := defaultMinSeeders
+		if viper.IsSet(prefix + "MIN_SEEDERS") {
+			minSeeders = viper.GetInt(prefix + "MIN_SEEDERS")
+		}
+
+		indexerType := strings.ToLower(viper.GetString(prefix + "TYPE"))
+		if indexerType == "" {
+			indexerType = IndexerTypeNewznab
+		}
+
+		animeCategories := []string{defaultAnimeCategory}
+		if viper.IsSet(prefix + "ANIME_CATEGORIES") {
+			animeCategories = splitList(viper.GetString(prefix + "ANIME_CATEGORIES"))
+		}
+
+		indexers = append(indexers, IndexerConfig{
+			Name:            name,
+			Type:            indexerType,
+			URL:             indexerURL,
+			APIKey:          viper.GetString(prefix + "KEY"),
+			Categories:      splitList(viper.GetString(prefix + "CATEGORIES")),
+			AnimeCategories: animeCategories,
+			Priority:        viper.GetInt(prefix + "PRIORITY"),
+			MinSeeders:      minSeeders,
+			MinLeechers:     viper.GetInt(prefix + "MIN_LEECHERS"),
+			FreeleechOnly:   viper.GetBool(prefix + "FREELEECH_ONLY"),
+			SeedersWeight:   viper.GetInt(prefix + "SEEDERS_WEIGHT"),
+			FreeleechWeight: viper.GetInt(prefix + "FREELEECH_WEIGHT"),
+			RetentionDays:   viper.GetInt(prefix + "RETENTION_DAYS"),
+			MinAgeMinutes:   viper.GetInt(prefix + "MIN_AGE_MINUTES"),
+			MaxAgeDays:      viper.GetInt(prefix + "MAX_AGE_DAYS"),
+			MovieLimit:      viper.GetInt(prefix + "MOVIE_LIMIT"),
+			EpisodeLimit:    viper.GetInt(prefix + "EPISODE_LIMIT"),
+			SeasonLimit:     viper.GetInt(prefix + "SEASON_LIMIT"),
+			Sort:            strings.ToLower(viper.GetString(prefix + "SORT")),
+			TLSPins:         splitList(viper.GetString(prefix + "TLS_PINS")),
+			TLSSkipVerify:   viper.GetBool(prefix + "TLS_SKIP_VERIFY"),
+			TLSCAFile:       viper.GetString(prefix + "TLS_CA_FILE"),
+		})
+	}
+
+	return indexers
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/sirupsen/logrus"
+)
+
+// seasonLengthsTTL is how long the season lengths of an anime show are reused
+const seasonLengthsTTL = 6 * time.Hour
+
+// seasonLengths are the episode counts of the seasons of a show, fetched from Trakt
+type seasonLengths struct {
+	counts    map[int]int // Episode count by season number
+	fetchedAt time.Time
+}
+
+// absolute returns the absolute number of an episode, counted from the first episode of
+// season 1. Specials (season 0) have no absolute number.
+func (s seasonLengths) absolute(ep trakt.Episode) (int, bool) {
+	if ep.Season < 1 {
+		return 0, false
+	}
+	absolute := ep.Episode
+	for season := 1; season < ep.Season; season++ {
+		count, ok := s.counts[season]
+		if !ok {
+			return 0, false
+		}
+		absolute += count
+	}
+	return absolute, true
+}
+
+// searchEpisode searches for an episode of a show
+// Anime shows are searched by absolute episode number as well, their releases seldom
+// carry a season number. Results found by both searches are kept once.
+func (c *SearchController) searchEpisode(ctx context.Context, media *models.Media, ep trakt.Episode) ([]newznab.SearchResult, error) {
+	results, err := c.newznabClient.SearchEpisode(ctx, media.IMDBId, ep.Season, ep.Episode)
+	if !media.Overrides.Anime {
+		return results, err
+	}
+
+	absolute, ok := c.absoluteEpisode(ctx, media, ep)
+	if !ok {
+		return results, err
+	}
+
+	animeResults, animeErr := c.newznabClient.SearchAnimeEpisode(ctx, media.QueryTitle(), ep.Season, ep.Episode, absolute)
+	if animeErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		c.logger.WithError(animeErr).WithFields(logrus.Fields{
+			"title":    media.Title,
+			"absolute": absolute,
+		}).Warn("Anime episode search failed")
+		return results, nil
+	}
+
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.GUID] = true
+	}
+	for _, result := range animeResults {
+		if result.GUID == "" || !seen[result.GUID] {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// absoluteEpisode maps the Trakt season and episode of an anime episode to its absolute number
+func (c *SearchController) absoluteEpisode(ctx context.Context, media *models.Media, ep trakt.Episode) (int, bool) {
+	c.seasonsMu.Lock()
+	lengths, ok := c.seasons[media.IMDBId]
+	c.seasonsMu.Unlock()
+
+	if !ok || time.Since(lengths.fetchedAt) > seasonLengthsTTL {
+		seasons, err := c.traktClient.GetSeasons(ctx, media.IMDBId)
+		if err != nil {
+			c.logger.WithError(err).WithField("title", media.Title).Warn("Failed to get season lengths, skipping anime search")
+			return 0, false
+		}
+
+		lengths = seasonLengths{counts: make(map[int]int, len(seasons)), fetchedAt: time.Now()}
+		for _, season := range seasons {
+			lengths.counts[season.Number] = season.EpisodeCount
+		}
+
+		c.seasonsMu.Lock()
+		if c.seasons == nil {
+			c.seasons = make(map[string]seasonLengths)
+		}
+		c.seasons[media.IMDBId] = lengths
+		c.seasonsMu.Unlock()
+	}
+
+	absolute, ok := lengths.absolute(ep)
+	if !ok {
+		c.logger.WithFields(logrus.Fields{
+			"title":   media.Title,
+			"season":  ep.Season,
+			"episode": ep.Episode,
+		}).Debug("No absolute number for anime episode")
+	}
+	return absolute, ok
+}
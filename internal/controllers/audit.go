@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/amaumene/gomenarr/internal/utils"
+)
+
+// ReleaseFields are the fields parsed from a release title
+type ReleaseFields struct {
+	Quality    models.Quality `json:"quality"`
+	Resolution int            `json:"resolution,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	Codec      string         `json:"codec,omitempty"`
+	Group      string         `json:"group,omitempty"`
+	Year       int            `json:"year,omitempty"`
+	Season     *int           `json:"season,omitempty"`
+	Episode    *int           `json:"episode,omitempty"`
+	SeasonPack bool           `json:"season_pack"`
+}
+
+// TitleAudit compares what the current parsing rules detect in a stored release title
+// with what was saved when the release was found
+type TitleAudit struct {
+	NZBID      uint64        `json:"nzb_id"`
+	MediaID    uint64        `json:"media_id"`
+	Title      string        `json:"title"`
+	Normalized string        `json:"normalized"` // Key merging the same release found on several indexers
+	MatchKey   string        `json:"match_key"`  // Key matching TorBox download names to releases
+	Stored     ReleaseFields `json:"stored"`
+	Detected   ReleaseFields `json:"detected"`
+	Mismatches []string      `json:"mismatches,omitempty"` // Stored fields the current rules parse differently
+}
+
+// ParseReleaseTitle runs a release title through the parsing used when search results are saved
+func ParseReleaseTitle(title string) ReleaseFields {
+	season, episode, seasonPack := newznab.ParseSeasonEpisode(title)
+	return ReleaseFields{
+		Quality:    utils.DetermineQuality(title),
+		Resolution: utils.ReleaseResolution(title),
+		Source:     utils.ReleaseSource(title),
+		Codec:      utils.ReleaseCodec(title),
+		Group:      utils.ReleaseGroup(title),
+		Year:       utils.ExtractYear(title),
+		Season:     season,
+		Episode:    episode,
+		SeasonPack: seasonPack,
+	}
+}
+
+// AuditTitle parses a stored release title again and reports the fields that changed
+func AuditTitle(nzb *models.NZB) TitleAudit {
+	audit := TitleAudit{
+		NZBID:      nzb.ID,
+		MediaID:    nzb.MediaID,
+		Title:      nzb.Title,
+		Normalized: newznab.NormalizeReleaseTitle(nzb.Title),
+		MatchKey:   utils.NormalizeTitle(nzb.Title),
+		Stored: ReleaseFields{
+			Quality:    nzb.Quality,
+			Year:       nzb.Year,
+			Season:     nzb.Season,
+			Episode:    nzb.Episode,
+			SeasonPack: nzb.IsSeasonPack,
+		},
+		Detected: ParseReleaseTitle(nzb.Title),
+	}
+
+	stored, detected := audit.Stored, audit.Detected
+	if stored.Quality != detected.Quality {
+		audit.Mismatches = append(audit.Mismatches, fmt.Sprintf("quality: %s -> %s", stored.Quality, detected.Quality))
+	}
+	if stored.Year != detected.Year {
+		audit.Mismatches = append(audit.Mismatches, fmt.Sprintf("year: %d -> %d", stored.Year, detected.Year))
+	}
+	if !equalNumber(stored.Season, detected.Season) {
+		audit.Mismatches = append(audit.Mismatches, fmt.Sprintf("season: %s -> %s", formatNumber(stored.Season), formatNumber(detected.Season)))
+	}
+	if !equalNumber(stored.Episode, detected.Episode) {
+		audit.Mismatches = append(audit.Mismatches, fmt.Sprintf("episode: %s -> %s", formatNumber(stored.Episode), formatNumber(detected.Episode)))
+	}
+	if stored.SeasonPack != detected.SeasonPack {
+		audit.Mismatches = append(audit.Mismatches, fmt.Sprintf("season pack: %t -> %t", stored.SeasonPack, detected.SeasonPack))
+	}
+	return audit
+}
+
+// equalNumber compares optional season/episode numbers
+func equalNumber(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// formatNumber prints an optional season/episode number, "-" when unset
+func formatNumber(n *int) string {
+	if n == nil {
+		return "-"
+	}
+	return fmt.Sprint(*n)
+}
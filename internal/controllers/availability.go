@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// AvailabilityStats are the delays between the air or release date and the availability
+// and import of a set of releases
+type AvailabilityStats struct {
+	Count                int     `json:"count"`
+	Exclusive            int     `json:"exclusive"` // Releases no other indexer offered
+	AvailableMedianHours float64 `json:"available_median_hours"`
+	AvailableP90Hours    float64 `json:"available_p90_hours"`
+	ImportedMedianHours  float64 `json:"imported_median_hours"`
+	ImportedP90Hours     float64 `json:"imported_p90_hours"`
+}
+
+// AvailabilityReport breaks the availability of the imported releases down by indexer,
+// resolution and source
+type AvailabilityReport struct {
+	Overall      AvailabilityStats             `json:"overall"`
+	ByIndexer    map[string]*AvailabilityStats `json:"by_indexer"`
+	ByResolution map[string]*AvailabilityStats `json:"by_resolution"`
+	BySource     map[string]*AvailabilityStats `json:"by_source"`
+}
+
+// SummarizeAvailability computes the availability report of imported releases
+func SummarizeAvailability(records []*models.AvailabilityRecord) *AvailabilityReport {
+	groups := map[string]map[string][]*models.AvailabilityRecord{
+		"indexer":    {},
+		"resolution": {},
+		"source":     {},
+	}
+	for _, record := range records {
+		resolution, source := "unknown", "unknown"
+		if record.Resolution > 0 {
+			resolution = fmt.Sprintf("%dp", record.Resolution)
+		}
+		if record.Source != "" {
+			source = record.Source
+		}
+		groups["indexer"][record.Indexer] = append(groups["indexer"][record.Indexer], record)
+		groups["resolution"][resolution] = append(groups["resolution"][resolution], record)
+		groups["source"][source] = append(groups["source"][source], record)
+	}
+
+	summarize := func(group map[string][]*models.AvailabilityRecord) map[string]*AvailabilityStats {
+		stats := make(map[string]*AvailabilityStats, len(group))
+		for key, records := range group {
+			stats[key] = availabilityStats(records)
+		}
+		return stats
+	}
+
+	return &AvailabilityReport{
+		Overall:      *availabilityStats(records),
+		ByIndexer:    summarize(groups["indexer"]),
+		ByResolution: summarize(groups["resolution"]),
+		BySource:     summarize(groups["source"]),
+	}
+}
+
+// availabilityStats computes the delays of a set of releases
+func availabilityStats(records []*models.AvailabilityRecord) *AvailabilityStats {
+	stats := &AvailabilityStats{Count: len(records)}
+	if len(records) == 0 {
+		return stats
+	}
+
+	available := make([]time.Duration, 0, len(records))
+	imported := make([]time.Duration, 0, len(records))
+	for _, record := range records {
+		available = append(available, record.AvailableAfter())
+		imported = append(imported, record.ImportedAfter())
+		if len(record.Alternates) == 0 {
+			stats.Exclusive++
+		}
+	}
+	stats.AvailableMedianHours = percentileHours(available, 50)
+	stats.AvailableP90Hours = percentileHours(available, 90)
+	stats.ImportedMedianHours = percentileHours(imported, 50)
+	stats.ImportedP90Hours = percentileHours(imported, 90)
+	return stats
+}
+
+// percentileHours returns a percentile of durations in hours (nearest rank), rounded to a tenth
+func percentileHours(durations []time.Duration, percentile int) float64 {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	rank := int(math.Ceil(float64(percentile)/100*float64(len(durations)))) - 1
+	rank = max(rank, 0)
+	return math.Round(durations[rank].Hours()*10) / 10
+}
+
+// recordAvailability records how long after its air or release date an imported release
+// was available and imported. Releases without a known release date aren't recorded.
+func (c *ImportController) recordAvailability(media *models.Media, nzb *models.NZB) {
+	if c.metadata == nil {
+		return
+	}
+
+	fields := logrus.Fields{
+		"media_id": media.ID,
+		"release":  nzb.Title,
+	}
+	releasedAt, err := c.releaseDate(context.Background(), media, nzb)
+	if err != nil {
+		c.logger.WithError(err).WithFields(fields).Warn("Failed to get release date for availability stats")
+		return
+	}
+	if releasedAt == nil {
+		c.logger.WithFields(fields).Debug("No release date, availability not recorded")
+		return
+	}
+
+	record := &models.AvailabilityRecord{
+		MediaID:     media.ID,
+		NZBID:       nzb.ID,
+		MediaType:   media.MediaType,
+		Title:       nzb.Title,
+		Indexer:     nzb.Indexer,
+		Resolution:  utils.ReleaseResolution(nzb.Title),
+		Source:      utils.ReleaseSource(nzb.Title),
+		Upgrade:     nzb.IsUpgrade(),
+		ReleasedAt:  *releasedAt,
+		AvailableAt: nzb.CreatedAt,
+		ImportedAt:  time.Now(),
+	}
+	if nzb.PublishedAt != nil {
+		record.AvailableAt = *nzb.PublishedAt
+	}
+	if nzb.Season != nil {
+		record.Season = *nzb.Season
+	}
+	if nzb.Episode != nil {
+		record.Episode = *nzb.Episode
+	}
+	for _, alternate := range nzb.Alternates {
+		if alternate.Indexer != nzb.Indexer && !slices.Contains(record.Alternates, alternate.Indexer) {
+			record.Alternates = append(record.Alternates, alternate.Indexer)
+		}
+	}
+
+	if err := c.db.RecordAvailability(record); err != nil {
+		c.logger.WithError(err).WithFields(fields).Warn("Failed to record availability")
+		return
+	}
+
+	c.logger.WithFields(fields).WithFields(logrus.Fields{
+		"indexer":         record.Indexer,
+		"available_after": record.AvailableAfter().Round(time.Minute).String(),
+		"imported_after":  record.ImportedAfter().Round(time.Minute).String(),
+	}).Debug("Recorded release availability")
+}
+
+// releaseDate returns the home release date of a movie, or the air time of the last aired
+// episode a release covers. Returns nil when unknown.
+func (c *ImportController) releaseDate(ctx context.Context, media *models.Media, nzb *models.NZB) (*time.Time, error) {
+	if media.MediaType == models.MediaTypeMovie {
+		return c.metadata.GetMovieReleaseDate(ctx, media.IMDBId)
+	}
+
+	season := nzb.Season
+	if season == nil {
+		season = media.SeasonNumber
+	}
+	if season == nil {
+		return nil, nil
+	}
+	episodes := nzb.EpisodeNumbers()
+	if len(episodes) == 0 && !nzb.IsSeasonPack && media.EpisodeNumber != nil {
+		episodes = []int{*media.EpisodeNumber}
+	}
+
+	airTimes, err := c.metadata.GetEpisodeAirTimes(ctx, media.IMDBId, *season)
+	if err != nil {
+		return nil, err
+	}
+
+	// Season packs without an episode list cover the episodes aired so far
+	now := time.Now()
+	var latest *time.Time
+	for ep, airedAt := range airTimes {
+		if airedAt.After(now) || (len(episodes) > 0 && !slices.Contains(episodes, ep.Episode)) {
+			continue
+		}
+		if latest == nil || airedAt.After(*latest) {
+			latest = &airedAt
+		}
+	}
+	return latest, nil
+}
@@ -2,52 +2,117 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/amaumene/gomenarr/internal/services/notify"
 	"github.com/amaumene/gomenarr/internal/services/torbox"
 	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
 	"github.com/sirupsen/logrus"
 )
 
+// deleteRetryDelay is the wait before the first retry of a TorBox job deletion, growing linearly
+const deleteRetryDelay = 5 * time.Second
+
+// watchlistRemovalTimeout bounds the Trakt call removing a downloaded movie, made while
+// handling the download webhook
+const watchlistRemovalTimeout = 10 * time.Second
+
+// WatchlistRemoval is when watchlist movies are removed from the Trakt watchlist
+type WatchlistRemoval string
+
+const (
+	WatchlistRemovalOff        WatchlistRemoval = ""           // Leave the watchlist alone
+	WatchlistRemovalDownloaded WatchlistRemoval = "downloaded" // Once downloaded, the movie is kept until watched
+	WatchlistRemovalWatched    WatchlistRemoval = "watched"    // Once watched and cleaned up
+)
+
 // CleanupController handles cleanup of watched and removed content
 type CleanupController struct {
-	db           *models.Database
-	torboxClient *torbox.Client
-	traktClient  *trakt.Client
-	syncDays     int
-	logger       *logrus.Logger
+	db               *models.Database
+	torboxClient     *torbox.Client
+	traktClient      *trakt.Client
+	syncDays         int
+	libraryRoots     []string
+	removeArtifacts  bool
+	watchlistRemoval WatchlistRemoval // When movies are removed from the Trakt watchlist
+	syncCollection   bool             // Remove deleted releases from the Trakt collection
+	notifier         *notify.Dispatcher
+	hooks            *hooks.Runner
+	dryRun           bool // Log the media that would be deleted instead of deleting them
+	logger           *logrus.Logger
+
+	deleteSlots   chan struct{} // Bounds the TorBox job deletions running in the background
+	deleteRetries int
+	deletes       sync.WaitGroup
 }
 
 // NewCleanupController creates a new cleanup controller
-func NewCleanupController(db *models.Database, torboxClient *torbox.Client, traktClient *trakt.Client, syncDays int, logger *logrus.Logger) *CleanupController {
+func NewCleanupController(db *models.Database, torboxClient *torbox.Client, traktClient *trakt.Client, syncDays int, libraryRoots []string, removeArtifacts bool, watchlistRemoval WatchlistRemoval, syncCollection bool, deleteWorkers int, deleteRetries int, notifier *notify.Dispatcher, hookRunner *hooks.Runner, dryRun bool, logger *logrus.Logger) *CleanupController {
+	if deleteWorkers < 1 {
+		deleteWorkers = 1
+	}
 	return &CleanupController{
-		db:           db,
-		torboxClient: torboxClient,
-		traktClient:  traktClient,
-		syncDays:     syncDays,
-		logger:       logger,
+		db:               db,
+		torboxClient:     torboxClient,
+		traktClient:      traktClient,
+		syncDays:         syncDays,
+		libraryRoots:     libraryRoots,
+		removeArtifacts:  removeArtifacts,
+		watchlistRemoval: watchlistRemoval,
+		syncCollection:   syncCollection,
+		deleteSlots:      make(chan struct{}, deleteWorkers),
+		deleteRetries:    deleteRetries,
+		notifier:         notifier,
+		hooks:            hookRunner,
+		dryRun:           dryRun,
+		logger:           logger,
 	}
 }
 
 // CleanupRemovedFromTrakt removes media items that are no longer in Trakt lists
-// This is called immediately after sync
-func (c *CleanupController) CleanupRemovedFromTrakt(ctx context.Context) error {
+// This is called immediately after sync. Returns the number of media cleaned up.
+func (c *CleanupController) CleanupRemovedFromTrakt(ctx context.Context) (int, error) {
 	c.logger.Info("Starting cleanup of content removed from Trakt")
 
 	medias, err := c.db.GetMediasNotInTrakt()
 	if err != nil {
-		return fmt.Errorf("failed to get medias not in Trakt: %w", err)
+		return 0, fmt.Errorf("failed to get medias not in Trakt: %w", err)
 	}
 
+	cleanedCount := 0
+
 	c.logger.WithField("count", len(medias)).Info("Found medias removed from Trakt")
 
 	for _, media := range medias {
+		// Media added through the API are never in Trakt lists, downloaded movies we removed
+		// from the watchlist wait for their watch
+		if media.Source == models.SourceManual || media.RemovedFromWatchlist {
+			continue
+		}
+
+		// Episode-level media follow their parent show
+		if media.ParentID != 0 {
+			parent, err := c.db.GetMediaByID(media.ParentID)
+			if err == nil && parent.InTrakt {
+				continue
+			}
+		}
+
 		c.logger.WithFields(logrus.Fields{
 			"media_id": media.ID,
 			"title":    media.Title,
 		}).Info("Cleaning up removed media")
 
+		if c.skipDelete(media, "removed from Trakt") {
+			continue
+		}
+
 		// Get all NZBs for this media
 		nzbs, err := c.db.GetNZBsByMediaID(media.ID)
 		if err != nil {
@@ -55,15 +120,16 @@ func (c *CleanupController) CleanupRemovedFromTrakt(ctx context.Context) error {
 			continue
 		}
 
-		// Cancel/delete TorBox jobs
+		// Cancel/delete TorBox jobs in the background, the records go away below
 		for _, nzb := range nzbs {
 			if nzb.TorBoxJobID != "" {
-				if err := c.torboxClient.DeleteJob(nzb.TorBoxJobID); err != nil {
-					c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to delete TorBox job")
-				}
+				c.deleteJobInBackground(nzb)
 			}
 		}
 
+		// Delete library files
+		c.deleteFiles(media)
+
 		// Delete NZBs from database
 		if err := c.db.DeleteNZBsByMediaID(media.ID); err != nil {
 			c.logger.WithError(err).Error("Failed to delete NZBs")
@@ -72,23 +138,72 @@ func (c *CleanupController) CleanupRemovedFromTrakt(ctx context.Context) error {
 		// Delete media from database
 		if err := c.db.DeleteMedia(media.ID); err != nil {
 			c.logger.WithError(err).Error("Failed to delete media")
+			continue
 		}
+
+		cleanedCount++
+		c.notifier.Notify(mediaEvent(notify.EventMediaRemoved, media, fmt.Sprintf("Removed %s (no longer in Trakt)", describeMedia(media))))
 	}
 
-	c.logger.WithField("cleaned", len(medias)).Info("Cleanup of removed content completed")
-	return nil
+	c.logger.WithField("cleaned", cleanedCount).Info("Cleanup of removed content completed")
+	return cleanedCount, nil
+}
+
+// deleteJobInBackground queues the deletion of the TorBox job of a release
+// It returns immediately, the deletion waits for a free slot and is retried with a
+// growing delay when it fails.
+func (c *CleanupController) deleteJobInBackground(nzb *models.NZB) {
+	c.deletes.Add(1)
+	go func() {
+		defer c.deletes.Done()
+		c.deleteSlots <- struct{}{}
+		defer func() { <-c.deleteSlots }()
+
+		for attempt := 0; ; attempt++ {
+			err := deleteTorBoxJob(c.torboxClient, nzb)
+			if err == nil {
+				return
+			}
+			if attempt >= c.deleteRetries {
+				c.logger.WithError(err).WithFields(logrus.Fields{
+					"job_id":   nzb.TorBoxJobID,
+					"attempts": attempt + 1,
+				}).Warn("Failed to delete TorBox job")
+				return
+			}
+			c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Debug("TorBox job deletion failed, retrying")
+			time.Sleep(time.Duration(attempt+1) * deleteRetryDelay)
+		}
+	}()
+}
+
+// WaitDeletions waits for the TorBox job deletions running in the background, returning
+// the context error if it ends first
+func (c *CleanupController) WaitDeletions(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.deletes.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // CleanupWatched cleans up watched content (conditional cleanup)
-// This runs hourly
-func (c *CleanupController) CleanupWatched(ctx context.Context) error {
+// This runs hourly. Returns the number of watched items processed.
+func (c *CleanupController) CleanupWatched(ctx context.Context) (int, error) {
 	c.logger.Info("Starting cleanup of watched content")
 
 	// Get recently watched items from Trakt
 	watchedItems, err := c.traktClient.GetRecentlyWatched(ctx, c.syncDays)
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to get watched items, skipping cleanup")
-		return fmt.Errorf("failed to get watched items: %w", err)
+		return 0, fmt.Errorf("failed to get watched items: %w", err)
 	}
 
 	c.logger.WithField("count", len(watchedItems)).Debug("Retrieved watched items")
@@ -98,7 +213,7 @@ func (c *CleanupController) CleanupWatched(ctx context.Context) error {
 	for _, item := range watchedItems {
 		if item.MediaType == "movie" {
 			// Movies: delete immediately
-			if err := c.cleanupMovie(item); err != nil {
+			if err := c.cleanupMovie(ctx, item); err != nil {
 				c.logger.WithError(err).Error("Failed to cleanup movie")
 			} else {
 				cleanedCount++
@@ -114,11 +229,11 @@ func (c *CleanupController) CleanupWatched(ctx context.Context) error {
 	}
 
 	c.logger.WithField("cleaned", cleanedCount).Info("Cleanup of watched content completed")
-	return nil
+	return cleanedCount, nil
 }
 
 // cleanupMovie deletes a watched movie
-func (c *CleanupController) cleanupMovie(item trakt.WatchedItem) error {
+func (c *CleanupController) cleanupMovie(ctx context.Context, item trakt.WatchedItem) error {
 	// Find media
 	media, err := c.db.GetMediaByIMDBID(item.IMDBId, models.MediaTypeMovie, nil, nil)
 	if err != nil {
@@ -126,8 +241,8 @@ func (c *CleanupController) cleanupMovie(item trakt.WatchedItem) error {
 		return nil
 	}
 
-	// Only clean up if still in Trakt (InTrakt=true)
-	if !media.InTrakt {
+	// Only clean up if still in Trakt (InTrakt=true), or removed from the watchlist once downloaded
+	if !media.InTrakt && !media.RemovedFromWatchlist {
 		return nil
 	}
 
@@ -136,7 +251,67 @@ func (c *CleanupController) cleanupMovie(item trakt.WatchedItem) error {
 		"title":    media.Title,
 	}).Info("Cleaning up watched movie")
 
-	return c.deleteMedia(media)
+	if c.skipDelete(media, "watched") {
+		return nil
+	}
+	if err := c.deleteMedia(media); err != nil {
+		return err
+	}
+	c.recordWatched(media, 0, 0, item.WatchedAt)
+
+	// Files are gone and the history entry exists: drop it from the watchlist
+	// so the next sync doesn't pick it up again
+	if c.watchlistRemoval == WatchlistRemovalWatched && media.Source == models.SourceWatchlist && !media.RemovedFromWatchlist {
+		if err := c.traktClient.RemoveFromWatchlist(ctx, media.IMDBId); err != nil {
+			c.logger.WithError(err).WithField("imdb_id", media.IMDBId).Warn("Failed to remove movie from Trakt watchlist")
+			return nil
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"imdb_id": media.IMDBId,
+			"title":   media.Title,
+		}).Info("Removed watched movie from Trakt watchlist")
+	}
+
+	return nil
+}
+
+// removeDownloaded drops a downloaded watchlist movie from the Trakt watchlist when movies
+// are removed once downloaded. The media item is kept and cleaned up once watched.
+func (c *CleanupController) removeDownloaded(ctx context.Context, media *models.Media) {
+	if c.watchlistRemoval != WatchlistRemovalDownloaded || media.MediaType != models.MediaTypeMovie ||
+		media.Source != models.SourceWatchlist || media.RemovedFromWatchlist {
+		return
+	}
+	if c.dryRun {
+		c.logger.WithField("title", media.Title).Info("Dry run: would remove downloaded movie from Trakt watchlist")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, watchlistRemovalTimeout)
+	defer cancel()
+	if err := c.traktClient.RemoveFromWatchlist(ctx, media.IMDBId); err != nil {
+		c.logger.WithError(err).WithField("imdb_id", media.IMDBId).Warn("Failed to remove movie from Trakt watchlist")
+		return
+	}
+
+	// The caller may save its copy of the media afterwards: flag both
+	media.RemovedFromWatchlist = true
+	current, err := c.db.GetMediaByID(media.ID)
+	if err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to get media")
+		return
+	}
+	current.RemovedFromWatchlist = true
+	if err := c.db.UpdateMedia(current); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to update media")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"imdb_id": media.IMDBId,
+		"title":   media.Title,
+	}).Info("Removed downloaded movie from Trakt watchlist")
 }
 
 // cleanupEpisode handles cleanup of watched episodes
@@ -179,7 +354,14 @@ func (c *CleanupController) cleanupEpisode(ctx context.Context, item trakt.Watch
 						"season":   item.Season,
 						"episode":  item.Episode,
 					}).Info("Cleaning up watched episode")
-					return c.deleteMedia(media)
+					if c.skipDelete(media, "watched") {
+						return nil
+					}
+					if err := c.deleteMedia(media); err != nil {
+						return err
+					}
+					c.recordWatched(media, item.Season, item.Episode, item.WatchedAt)
+					return nil
 				}
 			}
 		}
@@ -190,6 +372,22 @@ func (c *CleanupController) cleanupEpisode(ctx context.Context, item trakt.Watch
 
 // handleSeasonPackWatched updates season pack watched status and deletes if last episode watched
 func (c *CleanupController) handleSeasonPackWatched(ctx context.Context, nzb *models.NZB, item trakt.WatchedItem) error {
+	// Only the downloaded pack of the watched episode's season is tracked
+	if nzb.Season == nil || *nzb.Season != item.Season || nzb.Status != models.NZBStatusCompleted {
+		return nil
+	}
+
+	// Packs saved without their episode list (Trakt unavailable at search time) would never be cleaned up
+	if len(nzb.Episodes) == 0 {
+		seasonInfo, err := c.traktClient.GetSeasonInfo(ctx, item.IMDBId, item.Season)
+		if err != nil {
+			return fmt.Errorf("failed to get season episodes: %w", err)
+		}
+		for _, ep := range seasonInfo.Episodes {
+			nzb.Episodes = append(nzb.Episodes, models.EpisodeInfo{EpisodeNumber: ep.Number})
+		}
+	}
+
 	// Update episode watched status
 	updated := false
 	for i := range nzb.Episodes {
@@ -225,15 +423,167 @@ func (c *CleanupController) handleSeasonPackWatched(ctx context.Context, nzb *mo
 			if err != nil {
 				return err
 			}
-			return c.deleteMedia(media)
+			if c.skipDelete(media, "season pack watched") {
+				return nil
+			}
+			if err := c.deleteMedia(media); err != nil {
+				return err
+			}
+
+			if nzb.Season != nil {
+				for _, ep := range nzb.Episodes {
+					watchedAt := item.WatchedAt
+					if ep.WatchedAt != nil {
+						watchedAt = *ep.WatchedAt
+					}
+					c.recordWatched(media, *nzb.Season, ep.EpisodeNumber, watchedAt)
+				}
+			}
+			return nil
 		}
 	}
 
 	return nil
 }
 
+// recordWatched adds a cleaned up movie (season and episode 0) or episode to the watched
+// ledger, so a Trakt progress reset doesn't download it again
+func (c *CleanupController) recordWatched(media *models.Media, season, episode int, watchedAt time.Time) {
+	entry := &models.WatchedEntry{
+		IMDBId:    media.IMDBId,
+		MediaType: media.MediaType,
+		Title:     media.Title,
+		Season:    season,
+		Episode:   episode,
+		WatchedAt: watchedAt,
+	}
+	if err := c.db.RecordWatched(entry); err != nil {
+		c.logger.WithError(err).WithField("key", models.WatchedKey(media.IMDBId, season, episode)).Warn("Failed to record watched item")
+	}
+}
+
+// ReplaceRelease removes a completed release superseded by an upgrade: its TorBox job
+// and library files are deleted and the NZB is kept as replaced. The caller saves the media.
+// Pre-delete hooks with the abort policy keep the release.
+func (c *CleanupController) ReplaceRelease(media *models.Media, old *models.NZB) error {
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"nzb_id":   old.ID,
+		"title":    old.Title,
+	}).Info("Replacing release with upgrade")
+
+	if err := runPreDelete(c.hooks, releaseEvent(notify.EventMediaRemoved, media, old, ""), media.Path); err != nil {
+		return err
+	}
+
+	if old.TorBoxJobID != "" {
+		if err := deleteTorBoxJob(c.torboxClient, old); err != nil {
+			c.logger.WithError(err).WithField("job_id", old.TorBoxJobID).Warn("Failed to delete replaced TorBox job")
+		}
+	}
+
+	c.deleteFiles(media)
+	media.Path = ""
+
+	old.Status = models.NZBStatusReplaced
+	return c.db.UpdateNZB(old)
+}
+
+// ReplaceEpisodeRelease removes a completed episode release superseded by a season pack:
+// its TorBox job and library file are deleted and the NZB is kept as replaced
+// Pre-delete hooks with the abort policy keep the release.
+func (c *CleanupController) ReplaceEpisodeRelease(media *models.Media, old *models.NZB) error {
+	var file *models.LibraryFile
+	if old.Season != nil && old.Episode != nil {
+		file, _ = c.db.GetLibraryFile(media.IMDBId, *old.Season, *old.Episode)
+	}
+	path := ""
+	if file != nil {
+		path = file.Path
+	}
+	if err := runPreDelete(c.hooks, releaseEvent(notify.EventMediaRemoved, media, old, ""), path); err != nil {
+		return err
+	}
+
+	if old.TorBoxJobID != "" {
+		if err := deleteTorBoxJob(c.torboxClient, old); err != nil {
+			c.logger.WithError(err).WithField("job_id", old.TorBoxJobID).Warn("Failed to delete replaced TorBox job")
+		}
+	}
+
+	if file != nil {
+		if err := utils.DeleteLibraryPath(file.Path, c.libraryRoots, c.removeArtifacts); err != nil {
+			c.logger.WithError(err).WithField("path", file.Path).Warn("Failed to delete replaced episode file")
+		}
+		if err := c.db.DeleteLibraryFile(file.Key); err != nil {
+			c.logger.WithError(err).WithField("key", file.Key).Warn("Failed to remove library file record")
+		}
+	}
+
+	old.Status = models.NZBStatusReplaced
+	return c.db.UpdateNZB(old)
+}
+
+// RemoveMedia deletes a media item with its TorBox jobs, library files and NZBs
+func (c *CleanupController) RemoveMedia(media *models.Media) error {
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+	}).Info("Removing media")
+
+	if c.skipDelete(media, "removed manually") {
+		return nil
+	}
+	return c.deleteMedia(media)
+}
+
+// skipDelete logs a media item that would be deleted in dry-run mode
+// Returns true when the deletion must be skipped.
+func (c *CleanupController) skipDelete(media *models.Media, reason string) bool {
+	if !c.dryRun {
+		return false
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"path":     media.Path,
+		"reason":   reason,
+	}).Info("Dry run: would delete media")
+	return true
+}
+
+// runPreDelete runs the pre-delete hooks before the files at path are deleted
+// Returns an error wrapping hooks.ErrAborted when a hook keeps them.
+func runPreDelete(runner *hooks.Runner, event notify.Event, path string) error {
+	env := hooks.EventEnv(event)
+	if path != "" {
+		env["GOMENARR_PATH"] = path
+	}
+	return runner.Run(context.Background(), hooks.StagePreDelete, env)
+}
+
 // deleteMedia deletes a media item and its associated data
 func (c *CleanupController) deleteMedia(media *models.Media) error {
+	// Pre-delete hooks with the abort policy keep the media
+	if err := runPreDelete(c.hooks, mediaEvent(notify.EventMediaRemoved, media, ""), media.Path); err != nil {
+		return err
+	}
+
+	// Delete episode-level media attached to this show first, a show keeps its
+	// files when a hook keeps one of its episodes
+	children, err := c.db.GetChildMedias(media.ID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := c.deleteMedia(child); errors.Is(err, hooks.ErrAborted) {
+			return fmt.Errorf("episode-level media %d kept: %w", child.ID, err)
+		} else if err != nil {
+			c.logger.WithError(err).WithField("media_id", child.ID).Warn("Failed to delete episode-level media")
+		}
+	}
+
 	// Get all NZBs
 	nzbs, err := c.db.GetNZBsByMediaID(media.ID)
 	if err != nil {
@@ -243,17 +593,46 @@ func (c *CleanupController) deleteMedia(media *models.Media) error {
 	// Delete TorBox jobs
 	for _, nzb := range nzbs {
 		if nzb.TorBoxJobID != "" {
-			if err := c.torboxClient.DeleteJob(nzb.TorBoxJobID); err != nil {
+			if err := deleteTorBoxJob(c.torboxClient, nzb); err != nil {
 				c.logger.WithError(err).Warn("Failed to delete TorBox job")
 			}
 		}
 	}
 
+	// Delete library files
+	c.deleteFiles(media)
+	c.uncollect(media, nzbs)
+
 	// Delete NZBs
 	if err := c.db.DeleteNZBsByMediaID(media.ID); err != nil {
 		return err
 	}
 
 	// Delete media
-	return c.db.DeleteMedia(media.ID)
+	if err := c.db.DeleteMedia(media.ID); err != nil {
+		return err
+	}
+
+	c.notifier.Notify(mediaEvent(notify.EventMediaRemoved, media, fmt.Sprintf("Removed %s", describeMedia(media))))
+	return nil
+}
+
+// deleteFiles removes the media files from the library and prunes empty season/show folders
+func (c *CleanupController) deleteFiles(media *models.Media) {
+	if media.Path == "" {
+		return
+	}
+
+	if err := utils.DeleteLibraryPath(media.Path, c.libraryRoots, c.removeArtifacts); err != nil {
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"path":     media.Path,
+		}).Warn("Failed to delete library files")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"path":     media.Path,
+	}).Info("Deleted library files")
 }
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// collectionResolutions maps release resolutions to the Trakt collection ones
+var collectionResolutions = map[int]string{
+	2160: "uhd_4k",
+	1080: "hd_1080p",
+	720:  "hd_720p",
+	576:  "sd_576p",
+	480:  "sd_480p",
+}
+
+// collectionMediaTypes maps release sources to the Trakt collection media types
+var collectionMediaTypes = map[string]string{
+	"remux":  "bluray",
+	"bluray": "bluray",
+	"webrip": "digital",
+	"web-dl": "digital",
+	"hdtv":   "digital",
+	"dvd":    "dvd",
+}
+
+// hdrPatterns detects the HDR format of a release, checked in order (DV releases often carry HDR10 too)
+var hdrPatterns = []struct {
+	hdr     string
+	pattern *regexp.Regexp
+}{
+	{"dolby_vision", regexp.MustCompile(`(?i)\b(dv|dovi|dolby[ ._-]?vision)\b`)},
+	{"hdr10_plus", regexp.MustCompile(`(?i)\bhdr10(\+|plus)`)},
+	{"hlg", regexp.MustCompile(`(?i)\bhlg\b`)},
+	{"hdr10", regexp.MustCompile(`(?i)\bhdr(10)?\b`)},
+}
+
+// CollectionSources are the sources (watchlist, favorites, list) or custom Trakt list names
+// whose items count as owned once in the Trakt collection, e.g. ripped from a disc
+type CollectionSources []string
+
+// Covers reports whether the items of a source or custom list count as owned once collected
+func (s CollectionSources) Covers(source models.Source, list string) bool {
+	for _, name := range s {
+		if name == string(source) || (list != "" && name == list) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectionItem describes a downloaded release for the Trakt collection
+// Season packs cover the episodes listed from Trakt, or the whole season without a list.
+func collectionItem(media *models.Media, nzb *models.NZB) trakt.CollectionItem {
+	item := trakt.CollectionItem{
+		IMDBId:      media.IMDBId,
+		Show:        media.MediaType == models.MediaTypeTV,
+		MediaType:   collectionMediaTypes[utils.ReleaseSource(nzb.Title)],
+		Resolution:  collectionResolutions[utils.ReleaseResolution(nzb.Title)],
+		CollectedAt: time.Now(),
+	}
+	if nzb.DownloadedAt != nil {
+		item.CollectedAt = *nzb.DownloadedAt
+	}
+	for _, p := range hdrPatterns {
+		if p.pattern.MatchString(nzb.Title) {
+			item.HDR = p.hdr
+			break
+		}
+	}
+	if !item.Show {
+		return item
+	}
+
+	item.Season = nzb.Season
+	if item.Season == nil {
+		item.Season = media.SeasonNumber
+	}
+	switch {
+	case nzb.IsSeasonPack:
+		for _, episode := range nzb.Episodes {
+			if !episode.Failed {
+				item.Episodes = append(item.Episodes, episode.EpisodeNumber)
+			}
+		}
+	case nzb.Episode != nil:
+		last := *nzb.Episode
+		if nzb.LastEpisode != nil {
+			last = *nzb.LastEpisode
+		}
+		for episode := *nzb.Episode; episode <= last; episode++ {
+			item.Episodes = append(item.Episodes, episode)
+		}
+	case media.EpisodeNumber != nil:
+		item.Episodes = []int{*media.EpisodeNumber}
+	}
+	return item
+}
+
+// syncCollection stores the movies and episodes of the Trakt collection
+// The previous collection is kept when Trakt can't be reached.
+func (c *SyncController) syncCollection(ctx context.Context) error {
+	var items []*models.CollectedItem
+	for _, mediaType := range []string{"movies", "shows"} {
+		collected, err := c.traktClient.GetCollection(ctx, mediaType)
+		if err != nil {
+			return err
+		}
+		for _, item := range collected {
+			mType := models.MediaTypeMovie
+			if mediaType == "shows" {
+				mType = models.MediaTypeTV
+			}
+			items = append(items, &models.CollectedItem{
+				IMDBId:      item.IMDBId,
+				MediaType:   mType,
+				Season:      item.Season,
+				Episode:     item.Episode,
+				CollectedAt: item.CollectedAt,
+			})
+		}
+	}
+
+	if err := c.db.ReplaceCollection(items); err != nil {
+		return fmt.Errorf("failed to save collection: %w", err)
+	}
+	c.logger.WithField("items", len(items)).Debug("Synced Trakt collection")
+	return nil
+}
+
+// collectedMovie checks if a movie synced from a source is owned through the Trakt collection
+func (c *SyncController) collectedMovie(imdbID string, mType models.MediaType, origin syncOrigin) bool {
+	if mType != models.MediaTypeMovie || !c.collectionSources.Covers(origin.source, origin.list) {
+		return false
+	}
+	collected, err := c.db.IsCollected(imdbID, 0, 0)
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to check Trakt collection")
+	}
+	return collected
+}
+
+// filterCollected drops the episodes of the Trakt collection when the show source counts them as owned
+func (c *StrategyController) filterCollected(media *models.Media, episodes []trakt.Episode) []trakt.Episode {
+	if !c.collectionSources.Covers(media.Source, media.List) {
+		return episodes
+	}
+
+	var remaining []trakt.Episode
+	skipped := 0
+	for _, ep := range episodes {
+		collected, err := c.db.IsCollected(media.IMDBId, ep.Season, ep.Episode)
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to check Trakt collection")
+		}
+		if collected {
+			skipped++
+			continue
+		}
+		remaining = append(remaining, ep)
+	}
+
+	if skipped > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+			"skipped":  skipped,
+		}).Debug("Skipping episodes already in the Trakt collection")
+	}
+
+	return remaining
+}
+
+// collect adds an imported release to the Trakt collection
+func (c *ImportController) collect(media *models.Media, nzb *models.NZB) {
+	if c.collection == nil {
+		return
+	}
+
+	item := collectionItem(media, nzb)
+	if err := c.collection.AddToCollection(context.Background(), []trakt.CollectionItem{item}); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to add release to Trakt collection")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id":   media.ID,
+		"title":      media.Title,
+		"resolution": item.Resolution,
+	}).Info("Added to Trakt collection")
+}
+
+// uncollect removes the completed releases of a deleted media item from the Trakt collection
+func (c *CleanupController) uncollect(media *models.Media, nzbs []*models.NZB) {
+	if !c.syncCollection {
+		return
+	}
+
+	var items []trakt.CollectionItem
+	for _, nzb := range nzbs {
+		if nzb.Status == models.NZBStatusCompleted {
+			items = append(items, collectionItem(media, nzb))
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	if err := c.traktClient.RemoveFromCollection(context.Background(), items); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to remove media from Trakt collection")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+	}).Info("Removed from Trakt collection")
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestDatabase opens an empty database closed at the end of the test
+func newTestDatabase(t *testing.T) *models.Database {
+	t.Helper()
+	db, err := models.NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
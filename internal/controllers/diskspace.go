@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrDiskSpaceLow is returned when a release would leave less than the minimum free space
+// on the download volume
+var ErrDiskSpaceLow = errors.New("disk space low")
+
+// DiskGuard is the free space kept on the volume downloads are written to
+type DiskGuard struct {
+	Path    string // Directory on the checked volume
+	MinFree int64  // Bytes left free once a release is downloaded, 0 disables the check
+}
+
+// DiskSpace is the state of the download volume as of the last check
+type DiskSpace struct {
+	Path      string     `json:"path"`
+	Free      int64      `json:"free_bytes"`
+	MinFree   int64      `json:"min_free_bytes"`
+	Low       bool       `json:"low"` // Grabs are held back until space is freed
+	LowSince  *time.Time `json:"low_since,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// DiskSpace returns the state of the download volume as of the last check, nil when the
+// check is disabled
+func (c *DownloadController) DiskSpace() *DiskSpace {
+	if c.diskGuard.MinFree <= 0 {
+		return nil
+	}
+
+	c.diskMu.Lock()
+	defer c.diskMu.Unlock()
+	space := c.disk
+	space.Path = c.diskGuard.Path
+	space.MinFree = c.diskGuard.MinFree
+	return &space
+}
+
+// DiskSpaceLow checks the download volume and reports whether it is under the minimum
+// free space, so tasks can skip searches whose grabs would be held back
+func (c *DownloadController) DiskSpaceLow() bool {
+	if c.diskGuard.MinFree <= 0 {
+		return false
+	}
+	free, err := c.freeSpace()
+	if err != nil {
+		c.logger.WithError(err).WithField("path", c.diskGuard.Path).Warn("Failed to check free disk space")
+		return false
+	}
+	return c.recordDiskSpace(free)
+}
+
+// checkDiskSpace holds a release back when it would leave less than the minimum free space
+// on the download volume. Returns an error wrapping ErrDiskSpaceLow when it is held back.
+func (c *DownloadController) checkDiskSpace(nzb *models.NZB) error {
+	if c.diskGuard.MinFree <= 0 {
+		return nil
+	}
+
+	// An unreadable volume doesn't block downloads, TorBox reports real failures
+	free, err := c.freeSpace()
+	if err != nil {
+		c.logger.WithError(err).WithField("path", c.diskGuard.Path).Warn("Failed to check free disk space")
+		return nil
+	}
+	if low := c.recordDiskSpace(free); !low && free-nzb.Size >= c.diskGuard.MinFree {
+		return nil
+	}
+
+	nzb.Status = models.NZBStatusCandidate
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		c.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Failed to update NZB status")
+	}
+	return fmt.Errorf("%w: %s free on %s, %s needs %s and %s are kept free", ErrDiskSpaceLow,
+		formatGB(free), c.diskGuard.Path, nzb.Title, formatGB(nzb.Size), formatGB(c.diskGuard.MinFree))
+}
+
+// freeSpace returns the free bytes of the download volume
+func (c *DownloadController) freeSpace() (int64, error) {
+	free, _, err := utils.DiskUsage(c.diskGuard.Path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(free), nil
+}
+
+// recordDiskSpace updates the volume state and reports whether it is under the minimum
+// free space. Low space is notified once, when grabs start being held back.
+func (c *DownloadController) recordDiskSpace(free int64) bool {
+	now := time.Now()
+	low := free < c.diskGuard.MinFree
+
+	c.diskMu.Lock()
+	wasLow := c.disk.Low
+	lowSince := c.disk.LowSince
+	c.disk.Free = free
+	c.disk.Low = low
+	c.disk.CheckedAt = &now
+	switch {
+	case low && !wasLow:
+		c.disk.LowSince = &now
+	case !low:
+		c.disk.LowSince = nil
+	}
+	c.diskMu.Unlock()
+
+	fields := logrus.Fields{
+		"path":     c.diskGuard.Path,
+		"free":     formatGB(free),
+		"min_free": formatGB(c.diskGuard.MinFree),
+	}
+	switch {
+	case low && !wasLow:
+		c.logger.WithFields(fields).Warn("Disk space low, holding back new downloads")
+		c.notifier.Notify(notify.Event{
+			Type:    notify.EventDiskSpaceLow,
+			Message: fmt.Sprintf("Disk space low on %s: %s free, new downloads are held back until %s are free", c.diskGuard.Path, formatGB(free), formatGB(c.diskGuard.MinFree)),
+		})
+	case !low && wasLow:
+		c.logger.WithFields(fields).WithField("held_for", now.Sub(*lowSince).Round(time.Second).String()).Info("Disk space freed, resuming downloads")
+	}
+	return low
+}
@@ -1,33 +1,71 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
 	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/amaumene/gomenarr/internal/services/notify"
 	"github.com/amaumene/gomenarr/internal/services/torbox"
+	"github.com/amaumene/gomenarr/internal/utils"
 	"github.com/sirupsen/logrus"
 )
 
-const maxRetries = 5
+// ErrAlreadyCovered is returned when the episodes of a release are downloading or downloaded
+// in another release, e.g. a multi-episode one
+var ErrAlreadyCovered = errors.New("episodes already covered by another release")
+
+// ErrCandidatesExpired is returned when every candidate left for a media item is older than
+// the candidate TTL, the media item goes back to the search queue instead of failing
+var ErrCandidatesExpired = errors.New("all candidates expired")
 
 // DownloadController manages download operations
 type DownloadController struct {
-	db            *models.Database
-	torboxClient  *torbox.Client
-	newznabClient *newznab.Client
-	logger        *logrus.Logger
+	db             *models.Database
+	torboxClient   *torbox.Client
+	jobLookup      torboxJobLookup // TorBox job reads of the reconciler
+	jobLocks       sync.Map        // NZB ID to *sync.Mutex serializing job status updates
+	newznabClient  *newznab.Client
+	paramTemplates DownloadParamTemplates
+	importer       *ImportController
+	cleanupCtrl    *CleanupController
+	quotas         []StorageQuota // Storage budgets checked before each grab
+	diskGuard      DiskGuard      // Free space kept on the download volume
+	diskMu         sync.Mutex
+	disk           DiskSpace     // Download volume as of the last check
+	candidateTTL   time.Duration // Age after which stored candidates are dropped, 0 keeps them
+	retry          RetryPolicy
+	notifier       *notify.Dispatcher
+	hooks          *hooks.Runner
+	dryRun         bool // Log the releases that would be downloaded instead of sending them to TorBox
+	logger         *logrus.Logger
 }
 
 // NewDownloadController creates a new download controller
-func NewDownloadController(db *models.Database, torboxClient *torbox.Client, newznabClient *newznab.Client, logger *logrus.Logger) *DownloadController {
+func NewDownloadController(db *models.Database, torboxClient *torbox.Client, newznabClient *newznab.Client, paramTemplates DownloadParamTemplates, importer *ImportController, cleanupCtrl *CleanupController, quotas []StorageQuota, diskGuard DiskGuard, candidateTTL time.Duration, retry RetryPolicy, notifier *notify.Dispatcher, hookRunner *hooks.Runner, dryRun bool, logger *logrus.Logger) *DownloadController {
 	return &DownloadController{
-		db:            db,
-		torboxClient:  torboxClient,
-		newznabClient: newznabClient,
-		logger:        logger,
+		db:             db,
+		torboxClient:   torboxClient,
+		jobLookup:      torboxClient,
+		newznabClient:  newznabClient,
+		paramTemplates: paramTemplates,
+		importer:       importer,
+		cleanupCtrl:    cleanupCtrl,
+		quotas:         quotas,
+		diskGuard:      diskGuard,
+		candidateTTL:   candidateTTL,
+		retry:          retry,
+		notifier:       notifier,
+		hooks:          hookRunner,
+		dryRun:         dryRun,
+		logger:         logger,
 	}
 }
 
@@ -39,52 +77,83 @@ func (c *DownloadController) DownloadNZB(nzb *models.NZB) error {
 		"link":   nzb.Link,
 	}).Info("Starting download")
 
-	// Download NZB file from indexer
-	nzbData, err := c.newznabClient.DownloadNZB(nzb.Link)
+	if c.dryRun {
+		return c.skipDownload(nzb)
+	}
+
+	media, _ := c.db.GetMediaByID(nzb.MediaID)
+
+	// Overlapping releases are not downloaded twice
+	if covering := c.coveringRelease(nzb); covering != nil {
+		return c.skipCovered(media, nzb, covering)
+	}
+
+	// Releases exceeding the storage quota of their library are skipped or swapped for a smaller one,
+	// watched media evicted for them are only deleted once the release is sent to TorBox
+	nzb, evictions, err := c.enforceQuotas(media, nzb)
 	if err != nil {
+		return err
+	}
+
+	// Releases that would fill the download volume wait for space to be freed
+	if err := c.checkDiskSpace(nzb); err != nil {
+		return err
+	}
+
+	// Pre-grab hooks with the abort policy veto the release
+	env := hooks.EventEnv(releaseEvent(notify.EventDownloadStarted, media, nzb, ""))
+	if err := c.hooks.Run(context.Background(), hooks.StagePreGrab, env); err != nil {
 		nzb.Status = models.NZBStatusFailed
-		nzb.FailureReason = fmt.Sprintf("failed to download NZB: %v", err)
-		c.db.UpdateNZB(nzb)
-		return fmt.Errorf("failed to download NZB from indexer: %w", err)
+		nzb.FailureReason = err.Error()
+		if updateErr := c.db.UpdateNZB(nzb); updateErr != nil {
+			c.logger.WithError(updateErr).WithField("nzb_id", nzb.ID).Warn("Failed to update vetoed NZB")
+		}
+		return fmt.Errorf("download vetoed: %w", err)
 	}
 
-	// Create TorBox job by uploading NZB file
-	filename := nzb.Title + ".nzb"
-	jobID, response, err := c.torboxClient.CreateDownloadJob(nzbData, filename, nzb.Title)
+	// Fetch the release from the indexer and hand it to TorBox
+	jobID, hash, cached, err := c.createJob(nzb)
 	if err != nil {
 		nzb.Status = models.NZBStatusFailed
-		nzb.FailureReason = fmt.Sprintf("failed to upload to TorBox: %v", err)
+		nzb.FailureReason = fmt.Sprintf("failed to create download job: %v", err)
 		c.db.UpdateNZB(nzb)
 		return fmt.Errorf("failed to create download job: %w", err)
 	}
+	c.evict(evictions)
 
 	// Update NZB with job ID and hash
 	nzb.TorBoxJobID = jobID
-	nzb.TorBoxHash = response.Data.Hash
+	nzb.TorBoxHash = hash
 	nzb.Status = models.NZBStatusDownloading
 	if err := c.db.UpdateNZB(nzb); err != nil {
 		c.logger.WithError(err).Error("Failed to update NZB status")
 	}
 
 	// Update media status
-	media, err := c.db.GetMediaByID(nzb.MediaID)
+	media, err = c.db.GetMediaByID(nzb.MediaID)
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to get media")
 		return err
 	}
 
-	media.Status = models.StatusDownloading
-	if err := c.db.UpdateMedia(media); err != nil {
-		c.logger.WithError(err).Error("Failed to update media status")
+	// Upgrades keep the media completed, the current release stays until the new one is done
+	if !nzb.IsUpgrade() {
+		media.Status = models.StatusDownloading
+		if err := c.db.UpdateMedia(media); err != nil {
+			c.logger.WithError(err).Error("Failed to update media status")
+		}
 	}
 
 	c.logger.WithFields(logrus.Fields{
-		"nzb_id": nzb.ID,
-		"job_id": jobID,
+		"nzb_id":   nzb.ID,
+		"job_id":   jobID,
+		"replaces": nzb.Replaces,
 	}).Info("Download job created")
 
+	c.notifier.Notify(releaseEvent(notify.EventDownloadStarted, media, nzb, fmt.Sprintf("Downloading %s", describeMedia(media))))
+
 	// Check if file is cached - if so, mark as completed immediately
-	if response != nil && response.Detail == "Found cached usenet download. Using cached download." {
+	if cached {
 		c.logger.WithFields(logrus.Fields{
 			"nzb_id": nzb.ID,
 			"job_id": jobID,
@@ -98,6 +167,273 @@ func (c *DownloadController) DownloadNZB(nzb *models.NZB) error {
 	return nil
 }
 
+// coveringRelease returns the downloading or completed release of the same media covering
+// every episode of an episode release, nil if there is none. Upgrades are never covered.
+func (c *DownloadController) coveringRelease(nzb *models.NZB) *models.NZB {
+	if nzb.IsSeasonPack || nzb.Season == nil || nzb.Episode == nil || nzb.IsUpgrade() {
+		return nil
+	}
+
+	for _, status := range []models.NZBStatus{models.NZBStatusDownloading, models.NZBStatusCompleted} {
+		others, err := c.db.GetNZBsByMediaIDAndStatus(nzb.MediaID, status)
+		if err != nil {
+			c.logger.WithError(err).WithField("media_id", nzb.MediaID).Warn("Failed to get releases")
+			return nil
+		}
+		for _, other := range others {
+			if other.ID == nzb.ID {
+				continue
+			}
+			covered := true
+			for _, episode := range nzb.EpisodeNumbers() {
+				covered = covered && other.Covers(*nzb.Season, episode)
+			}
+			if covered {
+				return other
+			}
+		}
+	}
+	return nil
+}
+
+// skipCovered puts a release covered by another one back with the candidates
+// A media still searching follows the covering release.
+func (c *DownloadController) skipCovered(media *models.Media, nzb *models.NZB, covering *models.NZB) error {
+	c.logger.WithFields(logrus.Fields{
+		"nzb_id":   nzb.ID,
+		"title":    nzb.Title,
+		"covering": covering.Title,
+	}).Info("Episodes already covered by another release, not downloading")
+
+	nzb.Status = models.NZBStatusCandidate
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		c.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Failed to update NZB status")
+	}
+
+	if media != nil && media.Status == models.StatusSearching {
+		media.Status = models.StatusCompleted
+		if covering.Status == models.NZBStatusDownloading {
+			media.Status = models.StatusDownloading
+		}
+		if err := c.db.UpdateMedia(media); err != nil {
+			c.logger.WithError(err).Error("Failed to update media status")
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAlreadyCovered, covering.Title)
+}
+
+// skipDownload logs a release that would be downloaded in dry-run mode
+// The release stays selected and the media pending, so the next search selects it again.
+func (c *DownloadController) skipDownload(nzb *models.NZB) error {
+	c.logger.WithFields(logrus.Fields{
+		"nzb_id":   nzb.ID,
+		"media_id": nzb.MediaID,
+		"title":    nzb.Title,
+		"quality":  nzb.Quality,
+		"size":     nzb.Size,
+		"score":    nzb.QualityScore,
+		"replaces": nzb.Replaces,
+	}).Info("Dry run: would download release")
+
+	media, err := c.db.GetMediaByID(nzb.MediaID)
+	if err != nil || media.Status != models.StatusSearching {
+		return nil
+	}
+	media.Status = models.StatusPending
+	if err := c.db.UpdateMedia(media); err != nil {
+		c.logger.WithError(err).Error("Failed to update media status")
+	}
+	return nil
+}
+
+// DownloadUpgrade downloads a better release of a completed media item
+// The current release is replaced once the upgrade completes.
+func (c *DownloadController) DownloadUpgrade(nzb *models.NZB, current *models.NZB) error {
+	nzb.Replaces = current.ID
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		return fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	return c.DownloadNZB(nzb)
+}
+
+// DownloadSeasonUpgrade downloads a season pack replacing the completed episodes of a season
+// The episodes are replaced once the pack completes.
+func (c *DownloadController) DownloadSeasonUpgrade(pack *models.NZB, episodes []*models.NZB) error {
+	pack.ReplacesEpisodes = pack.ReplacesEpisodes[:0]
+	for _, episode := range episodes {
+		pack.ReplacesEpisodes = append(pack.ReplacesEpisodes, episode.ID)
+	}
+	if err := c.db.UpdateNZB(pack); err != nil {
+		return fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	return c.DownloadNZB(pack)
+}
+
+// replaceUpgraded removes the releases an upgrade replaces, once the upgrade is downloaded
+func (c *DownloadController) replaceUpgraded(media *models.Media, nzb *models.NZB) {
+	if len(nzb.ReplacesEpisodes) > 0 {
+		c.replaceEpisodes(media, nzb)
+	}
+	if nzb.Replaces == 0 {
+		return
+	}
+
+	old, err := c.db.GetNZBByID(nzb.Replaces)
+	if err != nil {
+		c.logger.WithError(err).WithField("nzb_id", nzb.Replaces).Warn("Replaced NZB not found")
+		return
+	}
+
+	if err := c.cleanupCtrl.ReplaceRelease(media, old); err != nil {
+		c.logger.WithError(err).WithField("nzb_id", old.ID).Error("Failed to replace release")
+	}
+}
+
+// replaceEpisodes removes the episode releases a season pack replaces
+// Episodes the pack failed to deliver (infected files) keep their release.
+func (c *DownloadController) replaceEpisodes(media *models.Media, pack *models.NZB) {
+	failed := make(map[int]bool)
+	for _, ep := range pack.Episodes {
+		if ep.Failed {
+			failed[ep.EpisodeNumber] = true
+		}
+	}
+
+	var reclaimed int64
+	replaced := 0
+	for _, id := range pack.ReplacesEpisodes {
+		old, err := c.db.GetNZBByID(id)
+		if err != nil {
+			c.logger.WithError(err).WithField("nzb_id", id).Warn("Replaced NZB not found")
+			continue
+		}
+		if old.Status != models.NZBStatusCompleted || (old.Episode != nil && failed[*old.Episode]) {
+			continue
+		}
+
+		if err := c.cleanupCtrl.ReplaceEpisodeRelease(media, old); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", old.ID).Error("Failed to replace episode release")
+			continue
+		}
+		reclaimed += old.Size
+		replaced++
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id":        media.ID,
+		"title":           media.Title,
+		"pack":            pack.Title,
+		"episodes":        replaced,
+		"reclaimed_bytes": reclaimed,
+	}).Info("Replaced episodes with season pack")
+}
+
+// cachedUsenetDetail is the TorBox response detail for usenet downloads it already has
+const cachedUsenetDetail = "Found cached usenet download. Using cached download."
+
+// createJob fetches the release from the indexer and submits it to TorBox, using the
+// torrent API for Torznab releases and the usenet API otherwise
+// When the indexer fails to serve the file, the alternate indexers offering the same
+// release are tried in turn.
+// Returns the TorBox job ID, the release hash and whether TorBox already had it cached
+func (c *DownloadController) createJob(nzb *models.NZB) (string, string, bool, error) {
+	sources := append([]models.NZBAlternate{{
+		Indexer:  nzb.Indexer,
+		Link:     nzb.Link,
+		GUID:     nzb.GUID,
+		Protocol: nzb.Protocol,
+	}}, nzb.Alternates...)
+
+	var lastErr error
+	for i, source := range sources {
+		data, magnet, err := c.fetchRelease(source)
+		if err != nil {
+			// The same release may still be fetched from another indexer
+			c.logger.WithError(err).WithFields(logrus.Fields{
+				"nzb_id":  nzb.ID,
+				"indexer": source.Indexer,
+			}).Warn("Failed to fetch release from indexer")
+			lastErr = err
+			continue
+		}
+
+		if i > 0 {
+			c.logger.WithFields(logrus.Fields{
+				"nzb_id":  nzb.ID,
+				"indexer": source.Indexer,
+			}).Info("Fetched release from alternate indexer")
+			nzb.Indexer = source.Indexer
+			nzb.Link = source.Link
+			nzb.GUID = source.GUID
+			nzb.Protocol = source.Protocol
+		}
+		return c.uploadRelease(nzb, data, magnet)
+	}
+
+	return "", "", false, lastErr
+}
+
+// fetchRelease downloads the NZB or torrent file of a release from an indexer
+// magnet is set instead of the data for magnet-only torrents.
+func (c *DownloadController) fetchRelease(source models.NZBAlternate) ([]byte, string, error) {
+	if source.Protocol == models.ProtocolTorrent {
+		torrentData, magnet, err := c.newznabClient.DownloadTorrent(source.Link)
+		if err != nil {
+			return nil, "", fmt.Errorf("download torrent: %w", err)
+		}
+		return torrentData, magnet, nil
+	}
+
+	// Download NZB file from indexer
+	nzbData, err := c.newznabClient.DownloadNZB(source.Link)
+	if err != nil {
+		return nil, "", fmt.Errorf("download NZB: %w", err)
+	}
+	return nzbData, "", nil
+}
+
+// uploadRelease creates the TorBox job of a fetched release
+func (c *DownloadController) uploadRelease(nzb *models.NZB, data []byte, magnet string) (string, string, bool, error) {
+	if nzb.IsTorrent() {
+		jobID, response, err := c.torboxClient.CreateTorrentJob(data, magnet, nzb.Title, c.downloadParams(nzb))
+		if err != nil {
+			return "", "", false, fmt.Errorf("upload to TorBox: %w", err)
+		}
+
+		cached := strings.Contains(strings.ToLower(response.Detail), "cached")
+		return jobID, response.Data.Hash, cached, nil
+	}
+
+	// Create TorBox job by uploading NZB file
+	filename := nzb.Title + ".nzb"
+	jobID, response, err := c.torboxClient.CreateDownloadJob(data, filename, nzb.Title, c.downloadParams(nzb))
+	if err != nil {
+		return "", "", false, fmt.Errorf("upload to TorBox: %w", err)
+	}
+
+	return jobID, response.Data.Hash, response.Detail == cachedUsenetDetail, nil
+}
+
+// deleteTorBoxJob removes the TorBox job of an NZB through the API matching its protocol
+func deleteTorBoxJob(torboxClient *torbox.Client, nzb *models.NZB) error {
+	if nzb.IsTorrent() {
+		return torboxClient.DeleteTorrentJob(nzb.TorBoxJobID)
+	}
+	return torboxClient.DeleteJob(nzb.TorBoxJobID)
+}
+
+// downloadParams renders the extra download parameters for an NZB and its media
+func (c *DownloadController) downloadParams(nzb *models.NZB) map[string]string {
+	media, err := c.db.GetMediaByID(nzb.MediaID)
+	if err != nil {
+		c.logger.WithError(err).WithField("nzb_id", nzb.ID).Debug("Media not found for download parameters")
+		media = nil
+	}
+	return c.buildDownloadParams(nzb, media)
+}
+
 // HandleCachedDownload verifies a download is cached and marks it as completed
 func (c *DownloadController) HandleCachedDownload(nzb *models.NZB, jobID string) error {
 	// Convert jobID to int
@@ -107,15 +443,25 @@ func (c *DownloadController) HandleCachedDownload(nzb *models.NZB, jobID string)
 	}
 
 	// Verify the download is truly cached
-	download, err := c.torboxClient.FindDownloadByID(downloadID)
-	if err != nil {
-		return fmt.Errorf("failed to find download: %w", err)
+	cached := false
+	if nzb.IsTorrent() {
+		torrent, err := c.torboxClient.FindTorrentByID(downloadID)
+		if err != nil {
+			return fmt.Errorf("failed to find torrent: %w", err)
+		}
+		cached = torrent.Cached || torrent.DownloadFinished
+	} else {
+		download, err := c.torboxClient.FindDownloadByID(downloadID)
+		if err != nil {
+			return fmt.Errorf("failed to find download: %w", err)
+		}
+		cached = download.Cached
 	}
 
-	if !download.Cached {
+	if !cached {
 		c.logger.WithFields(logrus.Fields{
 			"job_id": jobID,
-			"cached": download.Cached,
+			"cached": cached,
 		}).Info("Download not truly cached yet, waiting for webhook")
 		return nil
 	}
@@ -140,6 +486,8 @@ func (c *DownloadController) HandleCachedDownload(nzb *models.NZB, jobID string)
 		return fmt.Errorf("failed to get media: %w", err)
 	}
 
+	c.replaceUpgraded(media, nzb)
+
 	media.Status = models.StatusCompleted
 	media.CompletedAt = &now
 	if err := c.db.UpdateMedia(media); err != nil {
@@ -151,27 +499,78 @@ func (c *DownloadController) HandleCachedDownload(nzb *models.NZB, jobID string)
 		"title":    media.Title,
 	}).Info("Cached download marked as completed")
 
+	c.importer.Import(media, nzb)
+	c.cleanupCtrl.removeDownloaded(context.Background(), media)
+
+	c.notifier.Notify(releaseEvent(notify.EventDownloadCompleted, media, nzb, fmt.Sprintf("Downloaded %s", describeMedia(media))))
+
 	return nil
 }
 
-// HandleWebhook handles webhook callbacks from TorBox
-func (c *DownloadController) HandleWebhook(jobID string, status string, errorMsg string) error {
+// HandleWebhook handles webhook callbacks from TorBox for a usenet or torrent job
+func (c *DownloadController) HandleWebhook(protocol models.Protocol, jobID string, status string, errorMsg string) error {
 	c.logger.WithFields(logrus.Fields{
-		"job_id": jobID,
-		"status": status,
+		"job_id":   jobID,
+		"protocol": protocol,
+		"status":   status,
 	}).Info("Processing webhook")
 
 	// Find NZB by job ID
-	nzb, err := c.db.GetNZBByTorBoxJobID(jobID)
+	nzb, err := c.db.GetNZBByTorBoxJobID(protocol, jobID)
 	if err != nil {
 		return fmt.Errorf("NZB not found for job ID %s: %w", jobID, err)
 	}
 
+	return c.applyJobStatus(nzb, status, errorMsg)
+}
+
+// applyJobStatus moves an NZB and its media to the outcome of their TorBox job
+// Shared by webhooks and the polling reconciler.
+func (c *DownloadController) applyJobStatus(nzb *models.NZB, status string, errorMsg string) error {
+	unlock := c.lockNZB(nzb.ID)
+	defer unlock()
+
+	// A webhook or the reconciler may have handled the job since nzb was read
+	current, err := c.db.GetNZBByID(nzb.ID)
+	if err != nil {
+		return fmt.Errorf("NZB not found: %w", err)
+	}
+	completed := status == "completed" || status == "success"
+	if current.Status != nzb.Status || (completed && current.Status != models.NZBStatusDownloading && current.Status != models.NZBStatusFailed) {
+		c.logger.WithFields(logrus.Fields{
+			"job_id": nzb.TorBoxJobID,
+			"nzb_id": nzb.ID,
+			"status": current.Status,
+		}).Info("Skipping job status already handled")
+		return nil
+	}
+	nzb = current
+
+	// Late failure webhooks must not undo a completed download (nor trigger retries)
+	if (status == "failed" || status == "error") && !models.CanTransitionNZB(nzb.Status, models.NZBStatusFailed) {
+		c.logger.WithFields(logrus.Fields{
+			"job_id": nzb.TorBoxJobID,
+			"nzb_id": nzb.ID,
+			"status": nzb.Status,
+		}).Warn("Ignoring failure webhook for NZB that can no longer fail")
+		return nil
+	}
+
 	media, err := c.db.GetMediaByID(nzb.MediaID)
 	if err != nil {
 		return fmt.Errorf("media not found: %w", err)
 	}
 
+	// Season packs: keep the healthy episodes when only some files are infected
+	if nzb.IsSeasonPack && status != "unknown" {
+		partial, err := c.recoverPartialSeasonPack(nzb, media)
+		if err != nil {
+			c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to inspect season pack files")
+		} else if partial {
+			status = "completed"
+		}
+	}
+
 	switch status {
 	case "completed", "success":
 		// Mark as completed
@@ -187,10 +586,16 @@ func (c *DownloadController) HandleWebhook(jobID string, status string, errorMsg
 			"title":    media.Title,
 		}).Info("Download completed successfully")
 
+		c.replaceUpgraded(media, nzb)
+		c.importer.Import(media, nzb)
+		c.cleanupCtrl.removeDownloaded(context.Background(), media)
+
+		c.notifier.Notify(releaseEvent(notify.EventDownloadCompleted, media, nzb, fmt.Sprintf("Downloaded %s", describeMedia(media))))
+
 	case "failed", "error":
 		// Delete from TorBox before trying next candidate
 		if nzb.TorBoxJobID != "" {
-			if err := c.torboxClient.DeleteJob(nzb.TorBoxJobID); err != nil {
+			if err := deleteTorBoxJob(c.torboxClient, nzb); err != nil {
 				c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to delete job from TorBox")
 			} else {
 				c.logger.WithField("job_id", nzb.TorBoxJobID).Info("Deleted failed download from TorBox")
@@ -208,15 +613,32 @@ func (c *DownloadController) HandleWebhook(jobID string, status string, errorMsg
 			"error":       errorMsg,
 		}).Warn("Download failed")
 
-		// Try next candidate
-		if nzb.RetryCount < maxRetries {
-			if err := c.RetryWithNextCandidate(nzb.MediaID); err != nil {
+		event := releaseEvent(notify.EventDownloadFailed, media, nzb, fmt.Sprintf("Download of %s failed", describeMedia(media)))
+		event.Error = errorMsg
+		c.notifier.Notify(event)
+
+		// Save the failure first so the retry can steer away from this release
+		if err := c.db.UpdateNZB(nzb); err != nil {
+			c.logger.WithError(err).Error("Failed to update NZB")
+		}
+
+		// Try next candidate, failed upgrades keep the current release until the next upgrade search
+		if nzb.IsUpgrade() {
+			c.logger.WithField("media_id", media.ID).Info("Upgrade download failed, keeping current release")
+		} else if nzb.RetryCount < c.retry.MaxRetries {
+			if c.deferRetry(nzb) {
+				// Picked up by RetryDue, the media keeps downloading meanwhile
+			} else if err := c.RetryWithNextCandidate(nzb.MediaID); errors.Is(err, ErrCandidatesExpired) {
+				media.Status = models.StatusPending
+			} else if err != nil {
 				c.logger.WithError(err).Error("Failed to retry with next candidate")
 				media.Status = models.StatusFailed
+				c.notifyMediaFailed(media, err.Error())
 			}
 		} else {
 			c.logger.WithField("media_id", media.ID).Error("Max retries reached")
 			media.Status = models.StatusFailed
+			c.notifyMediaFailed(media, "max retries reached")
 		}
 	}
 
@@ -232,12 +654,176 @@ func (c *DownloadController) HandleWebhook(jobID string, status string, errorMsg
 	return nil
 }
 
+// lockNZB serializes the job status updates of an NZB, returning the unlock function
+func (c *DownloadController) lockNZB(id uint64) func() {
+	value, _ := c.jobLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// recoverPartialSeasonPack inspects the files of a finished season pack download and, when
+// only some of them are infected, marks the affected episodes as failed and queues an
+// episode-level re-search for each of them. Returns true if the pack was partially recovered.
+func (c *DownloadController) recoverPartialSeasonPack(nzb *models.NZB, media *models.Media) (bool, error) {
+	// TorBox only reports infected files for usenet downloads
+	if nzb.IsTorrent() {
+		return false, nil
+	}
+
+	downloadID, err := strconv.Atoi(nzb.TorBoxJobID)
+	if err != nil {
+		return false, fmt.Errorf("invalid job ID: %w", err)
+	}
+
+	download, err := c.jobLookup.FindDownloadByID(downloadID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find download: %w", err)
+	}
+
+	// Failed downloads list files that were never fetched, they are retried instead
+	if !download.Cached && !(download.DownloadFinished && download.DownloadPresent) {
+		return false, nil
+	}
+
+	infected := download.InfectedFiles()
+	if len(infected) == 0 || len(download.HealthyFiles()) == 0 {
+		// Either fully healthy or fully broken: regular handling applies
+		return false, nil
+	}
+
+	for _, file := range infected {
+		season, episode, ok := utils.ParseFileEpisode(file.Name)
+		if !ok {
+			c.logger.WithField("file", file.Name).Warn("Could not determine episode of infected file")
+			continue
+		}
+		if nzb.Season != nil && *nzb.Season != season {
+			continue
+		}
+
+		for i := range nzb.Episodes {
+			if nzb.Episodes[i].EpisodeNumber == episode {
+				nzb.Episodes[i].Failed = true
+			}
+		}
+
+		if err := c.queueEpisodeResearch(media, season, episode); err != nil {
+			c.logger.WithError(err).WithFields(logrus.Fields{
+				"season":  season,
+				"episode": episode,
+			}).Error("Failed to queue episode re-search")
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"nzb_id":   nzb.ID,
+		"title":    nzb.Title,
+		"infected": len(infected),
+		"files":    len(download.Files),
+	}).Warn("Season pack partially infected, keeping healthy episodes")
+
+	return true, nil
+}
+
+// queueEpisodeResearch creates a pending episode-level media so the next search
+// cycle looks for a replacement of a single damaged episode
+func (c *DownloadController) queueEpisodeResearch(parent *models.Media, season, episode int) error {
+	children, err := c.db.GetChildMedias(parent.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if child.SeasonNumber != nil && *child.SeasonNumber == season &&
+			child.EpisodeNumber != nil && *child.EpisodeNumber == episode {
+			// Already queued
+			return nil
+		}
+	}
+
+	child := &models.Media{
+		IMDBId:          parent.IMDBId,
+		MediaType:       parent.MediaType,
+		Title:           parent.Title,
+		Year:            parent.Year,
+		SearchTitle:     parent.SearchTitle,
+		SeasonNumber:    &season,
+		EpisodeNumber:   &episode,
+		ParentID:        parent.ID,
+		Source:          parent.Source,
+		Overrides:       models.MediaOverrides{Quality: parent.Overrides.Quality, Language: parent.Overrides.Language, Anime: parent.Overrides.Anime},
+		Status:          models.StatusPending,
+		InTrakt:         parent.InTrakt,
+		LastSeenInTrakt: parent.LastSeenInTrakt,
+	}
+
+	if err := c.db.CreateMedia(child); err != nil {
+		return err
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": child.ID,
+		"title":    child.Title,
+		"season":   season,
+		"episode":  episode,
+	}).Info("Queued re-search for damaged episode")
+
+	return nil
+}
+
+// DownloadRelease downloads a release picked by hand, overriding the automatic selection
+// Picking a release for a completed movie replaces the current release once it completes,
+// like an upgrade. Blacklisted releases can be picked too.
+func (c *DownloadController) DownloadRelease(nzb *models.NZB) error {
+	if nzb.Status != models.NZBStatusSelected && !models.CanTransitionNZB(nzb.Status, models.NZBStatusSelected) {
+		return fmt.Errorf("%w: status is %s", ErrReleaseUnavailable, nzb.Status)
+	}
+
+	media, err := c.db.GetMediaByID(nzb.MediaID)
+	if err != nil {
+		return ErrMediaNotFound
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"nzb_id":   nzb.ID,
+		"title":    nzb.Title,
+	}).Info("Release picked manually")
+
+	nzb.Status = models.NZBStatusSelected
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		return fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	if media.Status == models.StatusCompleted && media.MediaType == models.MediaTypeMovie {
+		completed, err := c.db.GetNZBsByMediaIDAndStatus(media.ID, models.NZBStatusCompleted)
+		if err != nil {
+			return fmt.Errorf("failed to get completed NZBs: %w", err)
+		}
+		var current *models.NZB
+		for _, done := range completed {
+			if current == nil || (done.DownloadedAt != nil && current.DownloadedAt != nil && done.DownloadedAt.After(*current.DownloadedAt)) {
+				current = done
+			}
+		}
+		if current != nil {
+			return c.DownloadUpgrade(nzb, current)
+		}
+	}
+
+	return c.DownloadNZB(nzb)
+}
+
 // RetryWithNextCandidate finds and downloads the next best candidate
 func (c *DownloadController) RetryWithNextCandidate(mediaID uint64) error {
 	c.logger.WithField("media_id", mediaID).Info("Retrying with next candidate")
 
-	// Get next best candidate
-	nzb, err := c.db.GetBestCandidateNZB(mediaID)
+	nzb, err := c.nextCandidate(mediaID)
+	if errors.Is(err, ErrCandidatesExpired) {
+		c.searchAgain(mediaID)
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("no more candidates available: %w", err)
 	}
@@ -251,12 +837,99 @@ func (c *DownloadController) RetryWithNextCandidate(mediaID uint64) error {
 	return c.DownloadNZB(nzb)
 }
 
+// nextCandidate picks the best remaining candidate for a retry, preferring a release group
+// and indexer that haven't failed yet for this media
+func (c *DownloadController) nextCandidate(mediaID uint64) (*models.NZB, error) {
+	candidates, err := c.db.GetNZBsByMediaIDAndStatus(mediaID, models.NZBStatusCandidate)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidates left for media %d", mediaID)
+	}
+	if candidates = c.dropExpired(mediaID, candidates); len(candidates) == 0 {
+		return nil, ErrCandidatesExpired
+	}
+
+	failed, err := c.db.GetNZBsByMediaIDAndStatus(mediaID, models.NZBStatusFailed)
+	if err != nil {
+		c.logger.WithError(err).WithField("media_id", mediaID).Warn("Failed to get failed NZBs, using quality order")
+		failed = nil
+	}
+
+	ordered := utils.OrderForRetry(utils.RankByQuality(candidates), failed)
+	next := ordered[0]
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": mediaID,
+		"title":    next.Title,
+		"group":    utils.ReleaseGroup(next.Title),
+		"indexer":  next.Indexer,
+		"failed":   len(failed),
+	}).Debug("Picked next candidate")
+
+	return next, nil
+}
+
+// dropExpired deletes the candidates no search found again within the candidate TTL, their
+// links are likely dead by now. Returns the remaining candidates.
+func (c *DownloadController) dropExpired(mediaID uint64, candidates []*models.NZB) []*models.NZB {
+	if c.candidateTTL <= 0 {
+		return candidates
+	}
+
+	cutoff := time.Now().Add(-c.candidateTTL)
+	fresh := candidates[:0]
+	expired := 0
+	for _, candidate := range candidates {
+		// Found again by a search refreshes UpdatedAt (CreateNZB merges the record)
+		if candidate.UpdatedAt.IsZero() || candidate.UpdatedAt.After(cutoff) {
+			fresh = append(fresh, candidate)
+			continue
+		}
+		if err := c.db.DeleteNZB(candidate.ID); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", candidate.ID).Warn("Failed to delete expired candidate")
+			continue
+		}
+		expired++
+	}
+
+	if expired > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"media_id": mediaID,
+			"expired":  expired,
+			"left":     len(fresh),
+			"ttl":      c.candidateTTL.String(),
+		}).Info("Dropped expired candidates")
+	}
+	return fresh
+}
+
+// searchAgain puts a media item whose candidates all expired back in the search queue
+func (c *DownloadController) searchAgain(mediaID uint64) {
+	media, err := c.db.GetMediaByID(mediaID)
+	if err != nil {
+		c.logger.WithError(err).WithField("media_id", mediaID).Warn("Failed to get media to search again")
+		return
+	}
+
+	media.Status = models.StatusPending
+	if err := c.db.UpdateMedia(media); err != nil {
+		c.logger.WithError(err).WithField("media_id", mediaID).Error("Failed to update media status")
+		return
+	}
+	c.logger.WithFields(logrus.Fields{
+		"media_id": mediaID,
+		"title":    media.Title,
+	}).Info("All candidates expired, searching again")
+}
+
 // RestartDownload restarts a failed download with the same NZB
-func (c *DownloadController) RestartDownload(jobID string) error {
+func (c *DownloadController) RestartDownload(protocol models.Protocol, jobID string) error {
 	c.logger.WithField("job_id", jobID).Info("Restarting failed download")
 
 	// Find NZB by job ID
-	nzb, err := c.db.GetNZBByTorBoxJobID(jobID)
+	nzb, err := c.db.GetNZBByTorBoxJobID(protocol, jobID)
 	if err != nil {
 		return fmt.Errorf("NZB not found for job ID %s: %w", jobID, err)
 	}
@@ -268,7 +941,7 @@ func (c *DownloadController) RestartDownload(jobID string) error {
 	}).Info("Found NZB to restart")
 
 	// Check if we've exceeded max retries
-	if nzb.RetryCount >= maxRetries {
+	if nzb.RetryCount >= c.retry.MaxRetries {
 		c.logger.WithFields(logrus.Fields{
 			"nzb_id":      nzb.ID,
 			"retry_count": nzb.RetryCount,
@@ -281,21 +954,11 @@ func (c *DownloadController) RestartDownload(jobID string) error {
 	// Increment retry count
 	nzb.RetryCount++
 
-	// Download NZB file from indexer
-	nzbData, err := c.newznabClient.DownloadNZB(nzb.Link)
+	// Create new TorBox job from the same release
+	newJobID, _, _, err := c.createJob(nzb)
 	if err != nil {
 		nzb.Status = models.NZBStatusFailed
-		nzb.FailureReason = fmt.Sprintf("restart failed - download NZB: %v", err)
-		c.db.UpdateNZB(nzb)
-		return fmt.Errorf("failed to download NZB for restart: %w", err)
-	}
-
-	// Create new TorBox job by uploading NZB file
-	filename := nzb.Title + ".nzb"
-	newJobID, _, err := c.torboxClient.CreateDownloadJob(nzbData, filename, nzb.Title)
-	if err != nil {
-		nzb.Status = models.NZBStatusFailed
-		nzb.FailureReason = fmt.Sprintf("restart failed - upload to TorBox: %v", err)
+		nzb.FailureReason = fmt.Sprintf("restart failed - %v", err)
 		c.db.UpdateNZB(nzb)
 		return fmt.Errorf("failed to restart download: %w", err)
 	}
@@ -321,7 +984,7 @@ func (c *DownloadController) RestartDownload(jobID string) error {
 }
 
 // HandleWebhookByName handles webhook callbacks from TorBox by download name
-func (c *DownloadController) HandleWebhookByName(downloadName string, status string) error {
+func (c *DownloadController) HandleWebhookByName(downloadName string, status string, delivery WebhookDelivery) error {
 	c.logger.WithFields(logrus.Fields{
 		"download_name": downloadName,
 		"status":        status,
@@ -333,12 +996,12 @@ func (c *DownloadController) HandleWebhookByName(downloadName string, status str
 		return fmt.Errorf("NZB not found for download name %s: %w", downloadName, err)
 	}
 
-	// Use the existing webhook handler with the job_id
-	return c.HandleWebhook(nzb.TorBoxJobID, status, "")
+	// Use the existing webhook handler with the job_id, once per event
+	return c.handleWebhookOnce(delivery, nzb, status)
 }
 
 // HandleWebhookByHash handles webhook callbacks from TorBox by hash
-func (c *DownloadController) HandleWebhookByHash(hash string, status string) error {
+func (c *DownloadController) HandleWebhookByHash(hash string, status string, delivery WebhookDelivery) error {
 	c.logger.WithFields(logrus.Fields{
 		"hash":   hash,
 		"status": status,
@@ -350,8 +1013,8 @@ func (c *DownloadController) HandleWebhookByHash(hash string, status string) err
 		return fmt.Errorf("NZB not found for hash %s: %w", hash, err)
 	}
 
-	// Use the existing webhook handler with the job_id
-	return c.HandleWebhook(nzb.TorBoxJobID, status, "")
+	// Use the existing webhook handler with the job_id, once per event
+	return c.handleWebhookOnce(delivery, nzb, status)
 }
 
 // RestartDownloadByName restarts a failed download by download name
@@ -371,7 +1034,7 @@ func (c *DownloadController) RestartDownloadByName(downloadName string) error {
 	}).Info("Found NZB to restart")
 
 	// Check if we've exceeded max retries
-	if nzb.RetryCount >= maxRetries {
+	if nzb.RetryCount >= c.retry.MaxRetries {
 		c.logger.WithFields(logrus.Fields{
 			"nzb_id":      nzb.ID,
 			"retry_count": nzb.RetryCount,
@@ -384,21 +1047,11 @@ func (c *DownloadController) RestartDownloadByName(downloadName string) error {
 	// Increment retry count
 	nzb.RetryCount++
 
-	// Download NZB file from indexer
-	nzbData, err := c.newznabClient.DownloadNZB(nzb.Link)
+	// Create new TorBox job from the same release
+	newJobID, _, _, err := c.createJob(nzb)
 	if err != nil {
 		nzb.Status = models.NZBStatusFailed
-		nzb.FailureReason = fmt.Sprintf("restart failed - download NZB: %v", err)
-		c.db.UpdateNZB(nzb)
-		return fmt.Errorf("failed to download NZB for restart: %w", err)
-	}
-
-	// Create new TorBox job by uploading NZB file
-	filename := nzb.Title + ".nzb"
-	newJobID, _, err := c.torboxClient.CreateDownloadJob(nzbData, filename, nzb.Title)
-	if err != nil {
-		nzb.Status = models.NZBStatusFailed
-		nzb.FailureReason = fmt.Sprintf("restart failed - upload to TorBox: %v", err)
+		nzb.FailureReason = fmt.Sprintf("restart failed - %v", err)
 		c.db.UpdateNZB(nzb)
 		return fmt.Errorf("failed to restart download: %w", err)
 	}
@@ -423,16 +1076,17 @@ func (c *DownloadController) RestartDownloadByName(downloadName string) error {
 }
 
 // CheckStuckDownloads checks for downloads that have been stuck for too long and retries them
-func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) error {
+// Returns the number of stuck downloads found
+func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) (int, error) {
 	// Get all downloading NZBs
 	nzbs, err := c.db.GetNZBsByStatus(models.NZBStatusDownloading)
 	if err != nil {
-		return fmt.Errorf("failed to get downloading NZBs: %w", err)
+		return 0, fmt.Errorf("failed to get downloading NZBs: %w", err)
 	}
 
 	if len(nzbs) == 0 {
 		c.logger.Debug("No downloading NZBs to check")
-		return nil
+		return 0, nil
 	}
 
 	now := time.Now()
@@ -453,7 +1107,7 @@ func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) error {
 
 			// Delete from TorBox
 			if nzb.TorBoxJobID != "" {
-				if err := c.torboxClient.DeleteJob(nzb.TorBoxJobID); err != nil {
+				if err := deleteTorBoxJob(c.torboxClient, nzb); err != nil {
 					c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to delete stuck job from TorBox")
 				} else {
 					c.logger.WithField("job_id", nzb.TorBoxJobID).Info("Deleted stuck download from TorBox")
@@ -471,8 +1125,13 @@ func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) error {
 			}
 
 			// Retry with next candidate
-			if nzb.RetryCount < maxRetries {
-				if err := c.RetryWithNextCandidate(nzb.MediaID); err != nil {
+			if nzb.IsUpgrade() {
+				c.logger.WithField("media_id", nzb.MediaID).Info("Upgrade download stuck, keeping current release")
+			} else if nzb.RetryCount < c.retry.MaxRetries {
+				if c.deferRetry(nzb) {
+					continue
+				}
+				if err := c.RetryWithNextCandidate(nzb.MediaID); err != nil && !errors.Is(err, ErrCandidatesExpired) {
 					c.logger.WithError(err).Error("Failed to retry with next candidate")
 
 					// Update media status to failed if no more candidates
@@ -480,6 +1139,7 @@ func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) error {
 					if err == nil {
 						media.Status = models.StatusFailed
 						c.db.UpdateMedia(media)
+						c.notifyMediaFailed(media, "no candidates left")
 					}
 				}
 			} else {
@@ -493,6 +1153,7 @@ func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) error {
 				if err == nil {
 					media.Status = models.StatusFailed
 					c.db.UpdateMedia(media)
+					c.notifyMediaFailed(media, "max retries reached")
 				}
 			}
 		}
@@ -502,5 +1163,17 @@ func (c *DownloadController) CheckStuckDownloads(timeout time.Duration) error {
 		c.logger.WithField("count", stuckCount).Info("Processed stuck downloads")
 	}
 
-	return nil
+	return stuckCount, nil
+}
+
+// DownloaderStats returns the queue of the TorBox account: speed, remaining size and paused downloads
+func (c *DownloadController) DownloaderStats() (*torbox.Stats, error) {
+	return c.torboxClient.Stats()
+}
+
+// notifyMediaFailed announces a media item given up on after its last download attempt
+func (c *DownloadController) notifyMediaFailed(media *models.Media, reason string) {
+	event := mediaEvent(notify.EventMediaFailed, media, fmt.Sprintf("Gave up on %s", describeMedia(media)))
+	event.Error = reason
+	c.notifier.Notify(event)
 }
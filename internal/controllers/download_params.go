@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// DownloadParamTemplates holds the configured extra download parameter templates
+type DownloadParamTemplates struct {
+	Default string
+	Movie   string
+	TV      string
+}
+
+// forMediaType returns the template that applies to a media type
+func (t DownloadParamTemplates) forMediaType(mediaType models.MediaType) string {
+	switch {
+	case mediaType == models.MediaTypeMovie && t.Movie != "":
+		return t.Movie
+	case mediaType == models.MediaTypeTV && t.TV != "":
+		return t.TV
+	default:
+		return t.Default
+	}
+}
+
+// buildDownloadParams renders the extra download parameters for an NZB
+// Template format: "key=value,key2=value2" with {token} substitution in values
+func (c *DownloadController) buildDownloadParams(nzb *models.NZB, media *models.Media) map[string]string {
+	var mediaType models.MediaType
+	if media != nil {
+		mediaType = media.MediaType
+	}
+
+	template := c.paramTemplates.forMediaType(mediaType)
+	if template == "" {
+		return nil
+	}
+
+	replacer := strings.NewReplacer(paramTokens(nzb, media)...)
+
+	params := make(map[string]string)
+	for _, pair := range strings.Split(template, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		params[key] = replacer.Replace(strings.TrimSpace(value))
+	}
+
+	return params
+}
+
+// paramTokens returns the token/value pairs available in download parameter templates
+// There is no Trakt ID or tag token, media only carry their IMDB ID.
+func paramTokens(nzb *models.NZB, media *models.Media) []string {
+	season := ""
+	if nzb.Season != nil {
+		season = strconv.Itoa(*nzb.Season)
+	}
+	episode := ""
+	if nzb.Episode != nil {
+		episode = strconv.Itoa(*nzb.Episode)
+	}
+
+	tokens := []string{
+		"{title}", nzb.Title,
+		"{quality}", string(nzb.Quality),
+		"{season}", season,
+		"{episode}", episode,
+	}
+
+	if media != nil {
+		tokens = append(tokens,
+			"{imdb_id}", media.IMDBId,
+			"{media_type}", string(media.MediaType),
+			"{source}", string(media.Source),
+			"{year}", strconv.Itoa(media.Year),
+		)
+	} else {
+		tokens = append(tokens, "{imdb_id}", "", "{media_type}", "", "{source}", "", "{year}", "")
+	}
+
+	return tokens
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/torbox"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDropExpired(t *testing.T) {
+	db := newTestDatabase(t)
+	ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, nil, nil, DiskGuard{}, 24*time.Hour, RetryPolicy{}, nil, nil, false, logrus.New())
+
+	old := time.Now().Add(-48 * time.Hour)
+	refreshed := &models.NZB{MediaID: 1, GUID: "refreshed", Title: "Refreshed", Status: models.NZBStatusCandidate}
+	stale := &models.NZB{MediaID: 1, GUID: "stale", Title: "Stale", Status: models.NZBStatusCandidate}
+	for _, nzb := range []*models.NZB{refreshed, stale} {
+		if err := db.CreateNZB(nzb); err != nil {
+			t.Fatalf("Failed to create NZB: %v", err)
+		}
+		nzb.CreatedAt = old
+	}
+	// Only the refreshed candidate was found again by a recent search
+	stale.UpdatedAt = old
+
+	fresh := ctrl.dropExpired(1, []*models.NZB{refreshed, stale})
+	if len(fresh) != 1 || fresh[0].ID != refreshed.ID {
+		t.Fatalf("Expected only the refreshed candidate to be kept, got %d", len(fresh))
+	}
+	if _, err := db.GetNZBByID(stale.ID); err == nil {
+		t.Error("Expected the stale candidate to be deleted")
+	}
+	if _, err := db.GetNZBByID(refreshed.ID); err != nil {
+		t.Errorf("Expected the refreshed candidate to be kept, got %v", err)
+	}
+}
+
+func TestRecoverPartialSeasonPack(t *testing.T) {
+	files := []torbox.UsenetDownloadFile{
+		{Name: "Show.S01E01.mkv"},
+		{Name: "Show.S01E02.mkv", Infected: true},
+	}
+	tests := []struct {
+		name     string
+		download torbox.UsenetDownload
+		want     bool
+	}{
+		{"finished", torbox.UsenetDownload{ID: 1, Files: files, DownloadFinished: true, DownloadPresent: true}, true},
+		{"failed", torbox.UsenetDownload{ID: 1, Files: files, DownloadState: "failed"}, false},
+		{"fully infected", torbox.UsenetDownload{ID: 1, Files: files[1:], DownloadFinished: true, DownloadPresent: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDatabase(t)
+			media := &models.Media{IMDBId: "tt0000001", MediaType: models.MediaTypeTV, Title: "Show", Status: models.StatusDownloading}
+			if err := db.CreateMedia(media); err != nil {
+				t.Fatalf("Failed to create media: %v", err)
+			}
+			season := 1
+			nzb := &models.NZB{MediaID: media.ID, Title: "Show.S01", IsSeasonPack: true, Season: &season, TorBoxJobID: "1",
+				Episodes: []models.EpisodeInfo{{EpisodeNumber: 1}, {EpisodeNumber: 2}}}
+
+			ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, nil, nil, DiskGuard{}, 0, RetryPolicy{}, nil, nil, false, logrus.New())
+			download := tt.download
+			ctrl.jobLookup = &fakeJobLookup{byID: map[int]*torbox.UsenetDownload{1: &download}}
+
+			partial, err := ctrl.recoverPartialSeasonPack(nzb, media)
+			if err != nil {
+				t.Fatalf("recoverPartialSeasonPack failed: %v", err)
+			}
+			if partial != tt.want {
+				t.Fatalf("Expected partial recovery %v, got %v", tt.want, partial)
+			}
+
+			children, err := db.GetChildMedias(media.ID)
+			if err != nil {
+				t.Fatalf("Failed to get child media: %v", err)
+			}
+			if tt.want && (len(children) != 1 || !nzb.Episodes[1].Failed || nzb.Episodes[0].Failed) {
+				t.Errorf("Expected episode 2 to be re-searched, got %d children and episodes %+v", len(children), nzb.Episodes)
+			}
+			if !tt.want && len(children) != 0 {
+				t.Errorf("Expected no re-search, got %d children", len(children))
+			}
+		})
+	}
+}
+
+// createSeasonUpgrade stores a TV show with two completed episodes and a downloading
+// season pack replacing them
+func createSeasonUpgrade(t *testing.T, db *models.Database, jobID string) (*models.NZB, []*models.NZB) {
+	media := &models.Media{IMDBId: "tt0000002", MediaType: models.MediaTypeTV, Title: "Show", Status: models.StatusCompleted}
+	if err := db.CreateMedia(media); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+	season := 1
+	var episodes []*models.NZB
+	for i := 1; i <= 2; i++ {
+		episode := i
+		nzb := &models.NZB{MediaID: media.ID, GUID: fmt.Sprintf("episode-%d", i), Title: fmt.Sprintf("Show.S01E0%d", i),
+			Status: models.NZBStatusCompleted, Season: &season, Episode: &episode}
+		if err := db.CreateNZB(nzb); err != nil {
+			t.Fatalf("Failed to create NZB: %v", err)
+		}
+		episodes = append(episodes, nzb)
+	}
+
+	pack := &models.NZB{MediaID: media.ID, GUID: "pack", Title: "Show.S01", Status: models.NZBStatusDownloading,
+		IsSeasonPack: true, Season: &season, TorBoxJobID: jobID,
+		Episodes:         []models.EpisodeInfo{{EpisodeNumber: 1}, {EpisodeNumber: 2}},
+		ReplacesEpisodes: []uint64{episodes[0].ID, episodes[1].ID}}
+	if err := db.CreateNZB(pack); err != nil {
+		t.Fatalf("Failed to create NZB: %v", err)
+	}
+	return pack, episodes
+}
+
+func TestDownloadSeasonUpgrade(t *testing.T) {
+	db := newTestDatabase(t)
+	pack, episodes := createSeasonUpgrade(t, db, "")
+	pack.ReplacesEpisodes = []uint64{99}
+
+	ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, nil, nil, DiskGuard{}, 0, RetryPolicy{}, nil, nil, true, logrus.New())
+	if err := ctrl.DownloadSeasonUpgrade(pack, episodes); err != nil {
+		t.Fatalf("DownloadSeasonUpgrade failed: %v", err)
+	}
+
+	stored, err := db.GetNZBByID(pack.ID)
+	if err != nil {
+		t.Fatalf("Failed to get NZB: %v", err)
+	}
+	if len(stored.ReplacesEpisodes) != 2 || stored.ReplacesEpisodes[0] != episodes[0].ID || stored.ReplacesEpisodes[1] != episodes[1].ID {
+		t.Errorf("Expected the pack to replace episodes %d and %d, got %v", episodes[0].ID, episodes[1].ID, stored.ReplacesEpisodes)
+	}
+}
+
+func TestSeasonPackReplacesEpisodes(t *testing.T) {
+	files := []torbox.UsenetDownloadFile{
+		{Name: "Show.S01E01.mkv"},
+		{Name: "Show.S01E02.mkv", Infected: true},
+	}
+	tests := []struct {
+		name       string
+		jobID      string
+		status     string
+		wantPack   models.NZBStatus
+		wantStatus []models.NZBStatus
+	}{
+		// Episode 2 is infected in the pack and keeps its release
+		{"partially completed", "1", "completed", models.NZBStatusCompleted, []models.NZBStatus{models.NZBStatusReplaced, models.NZBStatusCompleted}},
+		// Failed upgrades keep the current releases
+		{"failed", "", "failed", models.NZBStatusFailed, []models.NZBStatus{models.NZBStatusCompleted, models.NZBStatusCompleted}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDatabase(t)
+			pack, episodes := createSeasonUpgrade(t, db, tt.jobID)
+
+			cleanupCtrl := NewCleanupController(db, nil, nil, 0, nil, false, "", false, 1, 0, nil, nil, false, logrus.New())
+			ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, cleanupCtrl, nil, DiskGuard{}, 0, RetryPolicy{MaxRetries: 3}, nil, nil, false, logrus.New())
+			ctrl.jobLookup = &fakeJobLookup{byID: map[int]*torbox.UsenetDownload{
+				1: {ID: 1, Files: files, DownloadFinished: true, DownloadPresent: true},
+			}}
+
+			if err := ctrl.applyJobStatus(pack, tt.status, "x"); err != nil {
+				t.Fatalf("applyJobStatus failed: %v", err)
+			}
+
+			stored, err := db.GetNZBByID(pack.ID)
+			if err != nil {
+				t.Fatalf("Failed to get NZB: %v", err)
+			}
+			if stored.Status != tt.wantPack {
+				t.Errorf("Expected pack status %s, got %s", tt.wantPack, stored.Status)
+			}
+			for i, episode := range episodes {
+				stored, err := db.GetNZBByID(episode.ID)
+				if err != nil {
+					t.Fatalf("Failed to get NZB: %v", err)
+				}
+				if stored.Status != tt.wantStatus[i] {
+					t.Errorf("Expected episode %d status %s, got %s", i+1, tt.wantStatus[i], stored.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+)
+
+// mediaEvent builds a notification event describing a media item
+func mediaEvent(eventType notify.EventType, media *models.Media, message string) notify.Event {
+	event := notify.Event{
+		Type:    eventType,
+		Message: message,
+	}
+	if media == nil {
+		return event
+	}
+
+	event.MediaID = media.ID
+	event.IMDBId = media.IMDBId
+	event.Title = media.Title
+	event.Year = media.Year
+	event.MediaType = string(media.MediaType)
+	event.Source = string(media.Source)
+	event.Season = media.SeasonNumber
+	event.Episode = media.EpisodeNumber
+	return event
+}
+
+// releaseEvent builds a notification event describing a release of a media item
+func releaseEvent(eventType notify.EventType, media *models.Media, nzb *models.NZB, message string) notify.Event {
+	event := mediaEvent(eventType, media, message)
+
+	event.Release = nzb.Title
+	event.Quality = string(nzb.Quality)
+	event.Size = nzb.Size
+	event.Indexer = nzb.Indexer
+	event.Protocol = string(nzb.Protocol)
+	event.JobID = nzb.TorBoxJobID
+	if nzb.Season != nil {
+		event.Season = nzb.Season
+	}
+	if nzb.Episode != nil {
+		event.Episode = nzb.Episode
+	}
+	return event
+}
+
+// describeMedia returns a short human readable label for a media item
+func describeMedia(media *models.Media) string {
+	if media == nil {
+		return "unknown media"
+	}
+	if media.SeasonNumber != nil && media.EpisodeNumber != nil {
+		return fmt.Sprintf("%s S%02dE%02d", media.Title, *media.SeasonNumber, *media.EpisodeNumber)
+	}
+	if media.Year != 0 {
+		return fmt.Sprintf("%s (%d)", media.Title, media.Year)
+	}
+	return media.Title
+}
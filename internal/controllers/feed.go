@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// LatestReleases returns the newest releases of the indexers, the feed watched by RSS sync
+func (c *SearchController) LatestReleases(ctx context.Context) ([]newznab.SearchResult, error) {
+	return c.newznabClient.LatestReleases(ctx)
+}
+
+// FeedMatches returns the releases of a feed that name a media item
+// Releases carrying an IMDB ID match on it, the others on the title: the release name must
+// start with the media title in the metadata language, followed by the year for movies and a season for shows.
+func (c *SearchController) FeedMatches(media *models.Media, feed []newznab.SearchResult) []newznab.SearchResult {
+	title := newznab.NormalizeReleaseTitle(strings.ReplaceAll(media.QueryTitle(), "'", ""))
+	if title == "" {
+		return nil
+	}
+
+	var matches []newznab.SearchResult
+	for _, result := range feed {
+		if result.IMDBID != "" {
+			if result.IMDBID == media.IMDBId {
+				matches = append(matches, result)
+			}
+			continue
+		}
+		if !strings.HasPrefix(newznab.NormalizeReleaseTitle(result.Title)+" ", title+" ") {
+			continue
+		}
+		if media.MediaType == models.MediaTypeMovie {
+			if result.Season != nil {
+				continue
+			}
+			if year := utils.ExtractYear(result.Title); year == 0 || (media.Year != 0 && year != media.Year) {
+				continue
+			}
+		} else if result.Season == nil {
+			continue
+		}
+		matches = append(matches, result)
+	}
+	return matches
+}
+
+// SearchFeed saves the releases of a feed wanted by a strategy as candidates and selects
+// the releases to download. No search attempt is recorded, so the backoff of scheduled
+// searches is left as is.
+func (c *SearchController) SearchFeed(ctx context.Context, media *models.Media, strategy *DownloadStrategy, feed []newznab.SearchResult) ([]*models.NZB, error) {
+	var wanted []newznab.SearchResult
+	for _, result := range feed {
+		if strategyWants(strategy, result) {
+			wanted = append(wanted, result)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"strategy": strategy.Type,
+		"releases": len(wanted),
+	}).Info("Wanted releases found in feed")
+
+	nzbs, _ := c.saveCandidates(ctx, media, wanted, true, false)
+	return nzbs, nil
+}
+
+// strategyWants checks if a release covers what a strategy searches for
+func strategyWants(strategy *DownloadStrategy, result newznab.SearchResult) bool {
+	if strategy.Type == StrategySingleMovie {
+		return result.Season == nil
+	}
+	if result.Season == nil {
+		return false
+	}
+	season := *result.Season
+
+	if result.IsSeasonPack {
+		if strategy.Type == StrategySingleEpisode {
+			return false
+		}
+		if strategy.SeasonNumber != nil && *strategy.SeasonNumber == season {
+			return true
+		}
+		if slices.Contains(strategy.Seasons, season) {
+			return true
+		}
+		return slices.ContainsFunc(strategy.Episodes, func(ep trakt.Episode) bool {
+			return ep.Season == season
+		})
+	}
+
+	if result.Episode == nil {
+		return false
+	}
+	first, last := *result.Episode, *result.Episode
+	if result.LastEpisode != nil {
+		last = *result.LastEpisode
+	}
+	return slices.ContainsFunc(strategy.Episodes, func(ep trakt.Episode) bool {
+		return ep.Season == season && ep.Episode >= first && ep.Episode <= last
+	})
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// SearchHistory summarizes the searches of a media item
+type SearchHistory struct {
+	MediaID        uint64                  `json:"media_id"`
+	Title          string                  `json:"title"`
+	Summary        string                  `json:"summary"` // e.g. "searched 12 times, 0 results since 2026-03-01 18:30"
+	SearchCount    int                     `json:"search_count"`
+	EmptySearches  int                     `json:"empty_searches"`
+	LastSearchedAt *time.Time              `json:"last_searched_at,omitempty"`
+	NoResultsSince *time.Time              `json:"no_results_since,omitempty"`
+	NextSearchAt   *time.Time              `json:"next_search_at,omitempty"` // Set while scheduled searches back off
+	Attempts       []*models.SearchAttempt `json:"attempts"`                 // Newest first
+}
+
+// SearchHistory returns the recorded searches of a media item
+func (c *MediaController) SearchHistory(media *models.Media) (*SearchHistory, error) {
+	attempts, err := c.db.GetSearchAttempts(media.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search attempts: %w", err)
+	}
+	if attempts == nil {
+		attempts = []*models.SearchAttempt{}
+	}
+
+	return &SearchHistory{
+		MediaID:        media.ID,
+		Title:          media.Title,
+		Summary:        searchSummary(media),
+		SearchCount:    media.SearchCount,
+		EmptySearches:  media.EmptySearches,
+		LastSearchedAt: media.LastSearchedAt,
+		NoResultsSince: media.NoResultsSince,
+		Attempts:       attempts,
+	}, nil
+}
+
+// searchSummary describes the search counters of a media item in one line
+func searchSummary(media *models.Media) string {
+	if media.SearchCount == 0 {
+		return "never searched"
+	}
+
+	times := "times"
+	if media.SearchCount == 1 {
+		times = "time"
+	}
+	summary := fmt.Sprintf("searched %d %s", media.SearchCount, times)
+	if media.NoResultsSince != nil {
+		summary += ", 0 results since " + media.NoResultsSince.Format("2006-01-02 15:04")
+	}
+	return summary
+}
+
+// RejectionReport lists the releases the last searches of a media item dropped
+type RejectionReport struct {
+	MediaID    uint64              `json:"media_id"`
+	Title      string              `json:"title"`
+	Reasons    map[string]int      `json:"reasons"`    // Rejections by reason
+	Rejections []*models.Rejection `json:"rejections"` // Newest first
+}
+
+// Rejections returns the releases dropped by the last searches of a media item
+func (c *MediaController) Rejections(media *models.Media) (*RejectionReport, error) {
+	rejections, err := c.db.GetRejections(media.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejections: %w", err)
+	}
+	if rejections == nil {
+		rejections = []*models.Rejection{}
+	}
+
+	reasons := make(map[string]int)
+	for _, rejection := range rejections {
+		reasons[rejection.Reason]++
+	}
+
+	return &RejectionReport{
+		MediaID:    media.ID,
+		Title:      media.Title,
+		Reasons:    reasons,
+		Rejections: rejections,
+	}, nil
+}
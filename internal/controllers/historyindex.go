@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// historyIndexTTL is how long the history index is reused before being rebuilt
+const historyIndexTTL = 30 * time.Second
+
+// defaultHistoryHits bounds the hits returned when no limit is given
+const defaultHistoryHits = 50
+
+// Kinds of the records found by a history search
+const (
+	HistoryMedia      = "media"      // Movie or show
+	HistoryRelease    = "release"    // Release found, downloaded or failed
+	HistoryRejection  = "rejection"  // Release dropped by a search
+	HistoryQuota      = "quota"      // Storage quota action
+	HistoryQuarantine = "quarantine" // Download rejected on import
+	HistoryWatched    = "watched"    // Movie or episode watched and cleaned up
+)
+
+// HistoryHit is a record matching a history search
+type HistoryHit struct {
+	Kind    string    `json:"kind"`
+	ID      uint64    `json:"id,omitempty"`
+	MediaID uint64    `json:"media_id,omitempty"`
+	Title   string    `json:"title"`
+	Status  string    `json:"status,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// historyDoc is an indexed record with its words
+type historyDoc struct {
+	hit   HistoryHit
+	words []string
+}
+
+// HistoryIndex is an in-memory full-text index over the media, releases and history records
+// It is rebuilt from the database when a search finds it older than historyIndexTTL.
+// Every word of a query must start a word of a record, e.g. "bear s03" finds
+// The.Bear.S03E01.1080p.WEB.
+type HistoryIndex struct {
+	db     *models.Database
+	logger *logrus.Logger
+
+	mu       sync.Mutex
+	docs     []historyDoc
+	words    []string         // Sorted distinct words
+	postings map[string][]int // Word -> indexes in docs
+	builtAt  time.Time
+}
+
+// NewHistoryIndex creates a new history index
+func NewHistoryIndex(db *models.Database, logger *logrus.Logger) *HistoryIndex {
+	return &HistoryIndex{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Search returns the records matching every word of a query, best matches first then newest
+func (x *HistoryIndex) Search(query string, limit int) ([]HistoryHit, error) {
+	terms := historyWords(query)
+	if len(terms) == 0 {
+		return []HistoryHit{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultHistoryHits
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if time.Since(x.builtAt) > historyIndexTTL {
+		if err := x.build(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Documents matching every term, scored by the terms matching a whole word
+	var scores map[int]int
+	for _, term := range terms {
+		matched := make(map[int]int)
+		start := sort.SearchStrings(x.words, term)
+		for i := start; i < len(x.words) && strings.HasPrefix(x.words[i], term); i++ {
+			exact := 0
+			if x.words[i] == term {
+				exact = 1
+			}
+			for _, doc := range x.postings[x.words[i]] {
+				if previous, ok := scores[doc]; scores == nil || ok {
+					matched[doc] = max(matched[doc], previous+1+exact)
+				}
+			}
+		}
+		scores = matched
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return x.docs[docs[i]].hit.At.After(x.docs[docs[j]].hit.At)
+	})
+
+	hits := make([]HistoryHit, 0, min(len(docs), limit))
+	for _, doc := range docs[:min(len(docs), limit)] {
+		hits = append(hits, x.docs[doc].hit)
+	}
+	return hits, nil
+}
+
+// build reads the records from the database and indexes their words
+// Must be called with the lock held.
+func (x *HistoryIndex) build() error {
+	medias, err := x.db.GetAllMedias()
+	if err != nil {
+		return fmt.Errorf("failed to get medias: %w", err)
+	}
+	nzbs, err := x.db.GetRecentNZBs(0)
+	if err != nil {
+		return fmt.Errorf("failed to get releases: %w", err)
+	}
+	rejections, err := x.db.GetAllRejections()
+	if err != nil {
+		return fmt.Errorf("failed to get rejections: %w", err)
+	}
+	actions, err := x.db.GetQuotaActions(0)
+	if err != nil {
+		return fmt.Errorf("failed to get quota actions: %w", err)
+	}
+	quarantined, err := x.db.GetQuarantineItems()
+	if err != nil {
+		return fmt.Errorf("failed to get quarantined downloads: %w", err)
+	}
+	watched, err := x.db.GetWatchedEntries("")
+	if err != nil {
+		return fmt.Errorf("failed to get watched entries: %w", err)
+	}
+
+	var docs []historyDoc
+	add := func(hit HistoryHit, text ...string) {
+		docs = append(docs, historyDoc{hit: hit, words: historyWords(strings.Join(text, " "))})
+	}
+
+	titles := make(map[uint64]string, len(medias))
+	for _, media := range medias {
+		titles[media.ID] = describeMedia(media)
+		add(HistoryHit{
+			Kind:   HistoryMedia,
+			ID:     media.ID,
+			Title:  describeMedia(media),
+			Status: string(media.Status),
+			Detail: media.IMDBId,
+			At:     media.UpdatedAt,
+		}, describeMedia(media), media.IMDBId, string(media.MediaType), string(media.Status))
+	}
+	for _, nzb := range nzbs {
+		at := nzb.UpdatedAt
+		if nzb.DownloadedAt != nil {
+			at = *nzb.DownloadedAt
+		}
+		add(HistoryHit{
+			Kind:    HistoryRelease,
+			ID:      nzb.ID,
+			MediaID: nzb.MediaID,
+			Title:   nzb.Title,
+			Status:  string(nzb.Status),
+			Detail:  nzb.FailureReason,
+			At:      at,
+		}, nzb.Title, titles[nzb.MediaID], episodeWords(nzb), string(nzb.Status), nzb.Indexer, nzb.FailureReason)
+	}
+	for _, rejection := range rejections {
+		add(HistoryHit{
+			Kind:    HistoryRejection,
+			ID:      rejection.ID,
+			MediaID: rejection.MediaID,
+			Title:   rejection.Title,
+			Status:  rejection.Reason,
+			Detail:  rejection.Detail,
+			At:      rejection.At,
+		}, rejection.Title, titles[rejection.MediaID], rejection.Indexer, rejection.Reason, rejection.Detail)
+	}
+	for _, action := range actions {
+		add(HistoryHit{
+			Kind:    HistoryQuota,
+			ID:      action.ID,
+			MediaID: action.MediaID,
+			Title:   action.Release,
+			Status:  action.Action,
+			Detail:  action.Detail,
+			At:      action.At,
+		}, action.Title, action.Release, action.Quota, action.Action, action.Detail)
+	}
+	for _, item := range quarantined {
+		add(HistoryHit{
+			Kind:    HistoryQuarantine,
+			ID:      item.ID,
+			MediaID: item.MediaID,
+			Title:   item.Release,
+			Status:  item.Reason,
+			Detail:  item.Detail,
+			At:      item.At,
+		}, item.Title, item.Release, item.Reason, item.Detail)
+	}
+	for _, entry := range watched {
+		title := entry.Title
+		if entry.Season != 0 || entry.Episode != 0 {
+			title = fmt.Sprintf("%s S%02dE%02d", entry.Title, entry.Season, entry.Episode)
+		}
+		add(HistoryHit{
+			Kind:   HistoryWatched,
+			Title:  title,
+			Detail: entry.IMDBId,
+			At:     entry.CleanedAt,
+		}, title, entry.IMDBId)
+	}
+
+	postings := make(map[string][]int)
+	for i, doc := range docs {
+		for _, word := range doc.words {
+			if list := postings[word]; len(list) == 0 || list[len(list)-1] != i {
+				postings[word] = append(list, i)
+			}
+		}
+	}
+	words := make([]string, 0, len(postings))
+	for word := range postings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	x.docs, x.words, x.postings, x.builtAt = docs, words, postings, time.Now()
+	x.logger.WithFields(logrus.Fields{
+		"records": len(docs),
+		"words":   len(words),
+	}).Debug("Built history index")
+	return nil
+}
+
+// episodeWords renders the season and episodes of a release as words, e.g. "s03e01 s03"
+func episodeWords(nzb *models.NZB) string {
+	if nzb.Season == nil {
+		return ""
+	}
+	words := []string{fmt.Sprintf("s%02d", *nzb.Season)}
+	for _, episode := range nzb.EpisodeNumbers() {
+		words = append(words, fmt.Sprintf("s%02de%02d", *nzb.Season, episode))
+	}
+	return strings.Join(words, " ")
+}
+
+// historyWords lowercases a text and splits it into words of letters and digits
+func historyWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/amaumene/gomenarr/internal/services/mediaserver"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// ImportController runs the post-download steps of completed downloads
+// Releases are renamed into the library when a rename mode is configured. Imports are
+// limited to a fixed number running at the same time and media server refreshes are
+// coalesced and spaced out, so a burst of completions doesn't hammer the disk or the
+// media server.
+type ImportController struct {
+	db              *models.Database
+	renamer         *Renamer
+	libraryRoots    []string
+	mediaServer     *mediaserver.Client
+	slots           chan struct{}
+	refreshInterval time.Duration
+	hooks           *hooks.Runner
+	collection      *trakt.Client   // Trakt collection updated after imports, nil to leave it alone
+	metadata        *trakt.Client   // Air and release dates of the availability stats, nil to skip them
+	verifier        releaseVerifier // Set with VerifyWith, nil to skip the checks
+	logger          *logrus.Logger
+
+	mu             sync.Mutex
+	lastRefresh    time.Time
+	refreshPending bool
+}
+
+// ErrQuarantineNotFound is returned when a quarantined download doesn't exist
+var ErrQuarantineNotFound = errors.New("quarantined download not found")
+
+// releaseVerifier provides the checksums of the files of a release and handles
+// releases rejected on import
+type releaseVerifier interface {
+	fileChecksums(nzb *models.NZB) map[string]string
+	rejectRelease(media *models.Media, nzb *models.NZB, err error, deleteJob bool)
+}
+
+// NewImportController creates a new import controller
+// mediaServer may be nil when no media server is configured
+func NewImportController(db *models.Database, renamer *Renamer, libraryRoots []string, mediaServer *mediaserver.Client, concurrency int, refreshInterval time.Duration, hookRunner *hooks.Runner, collection *trakt.Client, metadata *trakt.Client, logger *logrus.Logger) *ImportController {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &ImportController{
+		db:              db,
+		renamer:         renamer,
+		libraryRoots:    libraryRoots,
+		mediaServer:     mediaServer,
+		slots:           make(chan struct{}, concurrency),
+		refreshInterval: refreshInterval,
+		hooks:           hookRunner,
+		collection:      collection,
+		metadata:        metadata,
+		logger:          logger,
+	}
+}
+
+// VerifyWith checks the copies of the imports against the TorBox checksums known to the
+// download controller, which also handles the releases rejected on import
+// Called once the download controller exists, since it is created with the import controller.
+func (c *ImportController) VerifyWith(downloads *DownloadController) {
+	if c == nil || downloads == nil {
+		return
+	}
+	c.verifier = downloads
+}
+
+// Import queues the post-download steps of a completed download
+// It returns immediately, the import waits for a free slot in the background.
+func (c *ImportController) Import(media *models.Media, nzb *models.NZB) {
+	if c == nil {
+		return
+	}
+
+	go func() {
+		c.slots <- struct{}{}
+		defer func() { <-c.slots }()
+
+		c.importRelease(media, nzb)
+	}()
+}
+
+// importRelease runs the import steps of a release while holding an import slot
+func (c *ImportController) importRelease(media *models.Media, nzb *models.NZB) {
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"release":  nzb.Title,
+	}).Debug("Importing completed download")
+
+	if c.renamer.Enabled() {
+		err := c.rename(media, nzb)
+		if reason := quarantineReason(err); reason != "" && c.verifier != nil {
+			// A download that couldn't be quarantined stays recoverable from TorBox
+			quarantined := c.quarantine(media, nzb, reason, err) == nil
+			c.verifier.rejectRelease(media, nzb, err, quarantined)
+			return
+		}
+		if err != nil {
+			c.logger.WithError(err).WithFields(logrus.Fields{
+				"media_id": media.ID,
+				"release":  nzb.Title,
+			}).Warn("Failed to rename download into library")
+		}
+	}
+
+	c.collect(media, nzb)
+	c.recordAvailability(media, nzb)
+
+	// The import is done, an abort policy has nothing left to cancel
+	env := hooks.EventEnv(releaseEvent(notify.EventDownloadCompleted, media, nzb, ""))
+	if current, err := c.db.GetMediaByID(media.ID); err == nil && current.Path != "" {
+		env["GOMENARR_PATH"] = current.Path
+	}
+	c.hooks.Run(context.Background(), hooks.StagePostImport, env)
+
+	c.requestRefresh()
+}
+
+// rename places a release into the library and records where its files went
+// Movies and episode-level media get their path updated, the file they replace
+// (e.g. after a quality upgrade) is deleted. Episodes of a show are recorded as
+// library files so the strategies and show statistics see them on disk.
+func (c *ImportController) rename(media *models.Media, nzb *models.NZB) error {
+	var checksums map[string]string
+	if c.renamer.Copies() && c.verifier != nil {
+		checksums = c.verifier.fileChecksums(nzb)
+	}
+
+	files, err := c.renamer.Rename(media, nzb, checksums)
+	return c.record(media, files, err)
+}
+
+// record saves where the files of a release went, renameErr is returned once the
+// files placed before it are recorded
+func (c *ImportController) record(media *models.Media, files []RenamedFile, renameErr error) error {
+	for _, file := range files {
+		libraryFile := &models.LibraryFile{
+			IMDBId:    media.IMDBId,
+			MediaID:   media.ID,
+			MediaType: media.MediaType,
+			Season:    file.Season,
+			Episode:   file.Episode,
+			Path:      file.Path,
+			Size:      file.Size,
+			ScannedAt: time.Now(),
+		}
+		if saveErr := c.db.SaveLibraryFile(libraryFile); saveErr != nil {
+			c.logger.WithError(saveErr).WithField("path", file.Path).Warn("Failed to record library file")
+		}
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	if media.MediaType == models.MediaTypeTV && media.ParentID == 0 {
+		return nil
+	}
+
+	// Reload the media, its status may have changed since the import was queued
+	current, err := c.db.GetMediaByID(media.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get media: %w", err)
+	}
+
+	previous := current.Path
+	current.Path = files[0].Path
+	if err := c.db.UpdateMedia(current); err != nil {
+		return fmt.Errorf("failed to update media path: %w", err)
+	}
+
+	if previous != "" && previous != current.Path {
+		if err := runPreDelete(c.hooks, mediaEvent(notify.EventMediaRemoved, current, ""), previous); err != nil {
+			c.logger.WithError(err).WithField("path", previous).Info("Keeping replaced library file")
+		} else if err := utils.DeleteLibraryPath(previous, c.libraryRoots, true); err != nil {
+			c.logger.WithError(err).WithField("path", previous).Warn("Failed to delete replaced library file")
+		}
+	}
+	return nil
+}
+
+// quarantineReason classifies the import rejections, empty for other errors
+func quarantineReason(err error) string {
+	switch {
+	case errors.Is(err, ErrChecksumMismatch):
+		return models.QuarantineCorrupt
+	case errors.Is(err, ErrSampleOnly):
+		return models.QuarantineSampleOnly
+	case errors.Is(err, ErrWrongContent):
+		return models.QuarantineWrongContent
+	default:
+		return ""
+	}
+}
+
+// quarantine moves a rejected download out of the download directory and records why
+func (c *ImportController) quarantine(media *models.Media, nzb *models.NZB, reason string, err error) error {
+	path, size, moveErr := c.renamer.Quarantine(nzb)
+	if moveErr != nil {
+		c.logger.WithError(moveErr).WithField("release", nzb.Title).Warn("Failed to quarantine rejected download")
+		return moveErr
+	}
+
+	item := &models.QuarantineItem{
+		MediaID: media.ID,
+		NZBID:   nzb.ID,
+		Title:   describeMedia(media),
+		Release: nzb.Title,
+		Reason:  reason,
+		Detail:  err.Error(),
+		Path:    path,
+		Size:    size,
+		At:      time.Now(),
+	}
+	if err := c.db.RecordQuarantineItem(item); err != nil {
+		c.logger.WithError(err).WithField("path", path).Warn("Failed to record quarantined download")
+		return err
+	}
+	return nil
+}
+
+// Quarantined returns the downloads rejected on import, newest first
+func (c *ImportController) Quarantined() ([]*models.QuarantineItem, error) {
+	items, err := c.db.GetQuarantineItems()
+	if items == nil {
+		items = []*models.QuarantineItem{}
+	}
+	return items, err
+}
+
+// ForceImport queues the import of a quarantined download despite its rejection
+// It returns once the download is checked, the import waits for a free slot in the
+// background. The media is marked completed unless another release is downloading for it.
+func (c *ImportController) ForceImport(id uint64) error {
+	if !c.renamer.Enabled() {
+		return fmt.Errorf("renaming is disabled (RENAME_MODE), nothing to import into")
+	}
+	item, err := c.db.GetQuarantineItem(id)
+	if err != nil {
+		return ErrQuarantineNotFound
+	}
+	media, err := c.db.GetMediaByID(item.MediaID)
+	if err != nil {
+		return fmt.Errorf("%w: %d", ErrMediaNotFound, item.MediaID)
+	}
+	nzb, err := c.db.GetNZBByID(item.NZBID)
+	if err != nil {
+		return fmt.Errorf("failed to get NZB: %w", err)
+	}
+
+	go func() {
+		c.slots <- struct{}{}
+		defer func() { <-c.slots }()
+
+		c.forceImport(item, media, nzb)
+	}()
+	return nil
+}
+
+// forceImport imports a quarantined download while holding an import slot
+func (c *ImportController) forceImport(item *models.QuarantineItem, media *models.Media, nzb *models.NZB) {
+	files, err := c.renamer.ForceRename(item.Path, media, nzb)
+	if err := c.record(media, files, err); err != nil {
+		c.logger.WithError(err).WithField("release", item.Release).Warn("Failed to force import quarantined download")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"release":  item.Release,
+		"reason":   item.Reason,
+	}).Info("Force imported quarantined download")
+
+	if current, err := c.db.GetMediaByID(media.ID); err == nil && (current.Status == models.StatusPending || current.Status == models.StatusFailed) {
+		now := time.Now()
+		current.Status = models.StatusCompleted
+		current.CompletedAt = &now
+		if err := c.db.UpdateMedia(current); err != nil {
+			c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to update media status")
+		}
+	}
+	if current, err := c.db.GetNZBByID(nzb.ID); err == nil && current.Status == models.NZBStatusRejected {
+		current.Status = models.NZBStatusCompleted
+		if err := c.db.UpdateNZB(current); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Failed to update NZB status")
+		}
+	}
+
+	// Files the import left behind (move mode) go with the record
+	if err := c.renamer.Purge(item.Path); err != nil && !os.IsNotExist(err) {
+		c.logger.WithError(err).WithField("path", item.Path).Warn("Failed to delete quarantined download")
+	}
+	if err := c.db.DeleteQuarantineItem(item.ID); err != nil {
+		c.logger.WithError(err).WithField("id", item.ID).Warn("Failed to delete quarantine record")
+	}
+
+	c.collect(media, nzb)
+
+	env := hooks.EventEnv(releaseEvent(notify.EventDownloadCompleted, media, nzb, ""))
+	if current, err := c.db.GetMediaByID(media.ID); err == nil && current.Path != "" {
+		env["GOMENARR_PATH"] = current.Path
+	}
+	c.hooks.Run(context.Background(), hooks.StagePostImport, env)
+	c.requestRefresh()
+}
+
+// PurgeQuarantined deletes a quarantined download and its record
+func (c *ImportController) PurgeQuarantined(id uint64) error {
+	item, err := c.db.GetQuarantineItem(id)
+	if err != nil {
+		return ErrQuarantineNotFound
+	}
+	if err := c.renamer.Purge(item.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", item.Path, err)
+	}
+	if err := c.db.DeleteQuarantineItem(item.ID); err != nil {
+		return fmt.Errorf("failed to delete quarantine record: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"release": item.Release,
+		"path":    item.Path,
+	}).Info("Purged quarantined download")
+	return nil
+}
+
+// requestRefresh triggers a media server refresh, or schedules one when the last
+// refresh is too recent. Requests arriving while one is scheduled are merged into it.
+func (c *ImportController) requestRefresh() {
+	if c.mediaServer == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refreshPending {
+		return
+	}
+
+	wait := c.refreshInterval - time.Since(c.lastRefresh)
+	if wait <= 0 {
+		c.lastRefresh = time.Now()
+		go c.refresh()
+		return
+	}
+
+	c.refreshPending = true
+	c.logger.WithField("delay", wait.Round(time.Second)).Debug("Media server refresh scheduled")
+
+	time.AfterFunc(wait, func() {
+		c.mu.Lock()
+		c.refreshPending = false
+		c.lastRefresh = time.Now()
+		c.mu.Unlock()
+
+		c.refresh()
+	})
+}
+
+// refresh asks the media server to scan its libraries
+func (c *ImportController) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := c.mediaServer.RefreshLibrary(ctx); err != nil {
+		c.logger.WithError(err).Warn("Failed to refresh media server library")
+	}
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// LibraryController imports an existing media library
+// Movies and episodes found on disk are matched to media items so they are not downloaded again.
+type LibraryController struct {
+	db     *models.Database
+	roots  []string
+	logger *logrus.Logger
+}
+
+// NewLibraryController creates a new library controller
+func NewLibraryController(db *models.Database, roots []string, logger *logrus.Logger) *LibraryController {
+	return &LibraryController{
+		db:     db,
+		roots:  roots,
+		logger: logger,
+	}
+}
+
+// LibraryScanStats counts what a library scan found
+type LibraryScanStats struct {
+	Files     int // Video files found
+	Movies    int // Files matched to a movie
+	Episodes  int // Files matched to a show episode
+	Unmatched int // Files without a matching media item
+	Removed   int // Previously found files gone from disk
+}
+
+// Enabled checks if library directories are configured
+func (c *LibraryController) Enabled() bool {
+	return len(c.roots) > 0
+}
+
+// Scan walks the library directories and records the files matching media items
+// Matched movies are marked completed with their path, episodes are recorded so the
+// strategies skip them. Files no longer on disk are forgotten, unless part of the library
+// couldn't be read (e.g. an unmounted share): its files would look gone and be downloaded again.
+func (c *LibraryController) Scan(ctx context.Context) (*LibraryScanStats, error) {
+	stats := &LibraryScanStats{}
+	scanStart := time.Now()
+
+	medias, err := c.db.GetAllMedias()
+	if err != nil {
+		return nil, err
+	}
+	movies, shows := indexMediasByTitle(medias)
+
+	var unreadable []string
+	for _, root := range c.roots {
+		readable := true
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				c.logger.WithError(err).WithField("path", path).Warn("Failed to read library path")
+				readable = false
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if d.IsDir() || !utils.IsVideoFile(path) {
+				return nil
+			}
+
+			stats.Files++
+			item, ok := utils.ParseLibraryFile(path)
+			if !ok {
+				stats.Unmatched++
+				return nil
+			}
+
+			var media *models.Media
+			if item.IsEpisode() {
+				media = matchTitle(shows, item)
+			} else {
+				media = matchTitle(movies, item)
+			}
+			if media == nil {
+				c.logger.WithFields(logrus.Fields{
+					"path":  path,
+					"title": item.Title,
+					"year":  item.Year,
+				}).Debug("No media matches library file")
+				stats.Unmatched++
+				return nil
+			}
+
+			var size int64
+			if info, err := d.Info(); err == nil {
+				size = info.Size()
+			}
+			file := &models.LibraryFile{
+				IMDBId:    media.IMDBId,
+				MediaID:   media.ID,
+				MediaType: media.MediaType,
+				Season:    item.Season,
+				Episode:   item.Episode,
+				Path:      path,
+				Size:      size,
+				ScannedAt: time.Now(),
+			}
+			if err := c.db.SaveLibraryFile(file); err != nil {
+				c.logger.WithError(err).WithField("path", path).Warn("Failed to save library file")
+				return nil
+			}
+
+			if item.IsEpisode() {
+				stats.Episodes++
+				return nil
+			}
+			stats.Movies++
+			c.markMovieOnDisk(media, path)
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+		if !readable {
+			unreadable = append(unreadable, root)
+		}
+	}
+
+	if len(unreadable) > 0 {
+		c.logger.WithField("roots", unreadable).Warn("Library not fully readable, keeping the files missing from the scan")
+	} else {
+		removed, err := c.db.DeleteLibraryFilesScannedBefore(scanStart)
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to forget library files gone from disk")
+		}
+		stats.Removed = removed
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"files":     stats.Files,
+		"movies":    stats.Movies,
+		"episodes":  stats.Episodes,
+		"unmatched": stats.Unmatched,
+		"removed":   stats.Removed,
+	}).Info("Library scan completed")
+
+	return stats, nil
+}
+
+// markMovieOnDisk completes a movie found in the library, unless it is being downloaded
+func (c *LibraryController) markMovieOnDisk(media *models.Media, path string) {
+	if media.Path == path && media.Status == models.StatusCompleted {
+		return
+	}
+	switch media.Status {
+	case models.StatusSearching, models.StatusDownloading:
+		return
+	}
+
+	media.Path = path
+	if media.Status != models.StatusCompleted {
+		now := time.Now()
+		media.Status = models.StatusCompleted
+		media.CompletedAt = &now
+	}
+	if err := c.db.UpdateMedia(media); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to mark movie on disk")
+		return
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"path":     path,
+	}).Info("Found movie in library")
+}
+
+// indexMediasByTitle indexes the movies and shows by normalized title, the Trakt one and
+// the one in the metadata language. Episode-level media follow their parent show and are left out.
+func indexMediasByTitle(medias []*models.Media) (movies, shows map[string][]*models.Media) {
+	movies = make(map[string][]*models.Media)
+	shows = make(map[string][]*models.Media)
+	for _, media := range medias {
+		titles := []string{utils.NormalizeTitle(media.Title)}
+		if search := utils.NormalizeTitle(media.QueryTitle()); search != titles[0] {
+			titles = append(titles, search)
+		}
+		for _, title := range titles {
+			switch {
+			case media.MediaType == models.MediaTypeMovie:
+				movies[title] = append(movies[title], media)
+			case media.ParentID == 0:
+				shows[title] = append(shows[title], media)
+			}
+		}
+	}
+	return movies, shows
+}
+
+// matchTitle finds the media item of a parsed library file
+// The year must match when both sides know it, a year off by one is tolerated for
+// movies released around new year.
+func matchTitle(index map[string][]*models.Media, item utils.LibraryItem) *models.Media {
+	candidates := index[utils.NormalizeTitle(item.Title)]
+	if item.Year == 0 {
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		return nil
+	}
+
+	var fallback *models.Media
+	for _, media := range candidates {
+		diff := media.Year - item.Year
+		switch {
+		case diff == 0:
+			return media
+		case media.Year == 0 || diff == 1 || diff == -1:
+			fallback = media
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestScanKeepsFilesOfUnreadableRoots(t *testing.T) {
+	db := newTestDatabase(t)
+
+	missing := filepath.Join(t.TempDir(), "unmounted")
+	file := &models.LibraryFile{
+		IMDBId:    "tt0133093",
+		MediaType: models.MediaTypeMovie,
+		Path:      filepath.Join(missing, "The Matrix (1999).mkv"),
+		ScannedAt: time.Now().Add(-24 * time.Hour),
+	}
+	if err := db.SaveLibraryFile(file); err != nil {
+		t.Fatalf("Failed to save library file: %v", err)
+	}
+
+	ctrl := NewLibraryController(db, []string{t.TempDir(), missing}, logrus.New())
+	stats, err := ctrl.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if stats.Removed != 0 {
+		t.Errorf("Expected no file removed, got %d", stats.Removed)
+	}
+	if onDisk, _ := db.IsOnDisk("tt0133093", 0, 0); !onDisk {
+		t.Error("Expected the file of the unreadable root to be kept")
+	}
+
+	// Once every root is readable, files missing from the scan are forgotten
+	ctrl = NewLibraryController(db, []string{t.TempDir()}, logrus.New())
+	if stats, err = ctrl.Scan(context.Background()); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if stats.Removed != 1 {
+		t.Errorf("Expected the missing file to be removed, got %d", stats.Removed)
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrMediaExists is returned when adding a media item that is already tracked
+	ErrMediaExists = errors.New("media already exists")
+	// ErrMediaNotFound is returned when a media item or its Trakt metadata cannot be found
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrInvalidMedia is returned when an add request is incomplete or inconsistent
+	ErrInvalidMedia = errors.New("invalid media")
+	// ErrMediaBusy is returned when a media item is being searched or downloaded
+	ErrMediaBusy = errors.New("media is busy")
+	// ErrReleaseUnavailable is returned when picking a release that is downloading, completed or replaced
+	ErrReleaseUnavailable = errors.New("release cannot be downloaded")
+)
+
+// AddMediaRequest describes a media item added outside Trakt lists
+// One of IMDBId, TMDBID or TraktID is required; title and year are looked up on Trakt.
+type AddMediaRequest struct {
+	IMDBId    string                `json:"imdb_id"`
+	TMDBID    int                   `json:"tmdb_id"`
+	TraktID   int                   `json:"trakt_id"`
+	MediaType models.MediaType      `json:"media_type"` // "movie" or "tv", optional with IMDB IDs
+	Overrides models.MediaOverrides `json:"overrides"`
+}
+
+// MediaMetadata looks up movies and shows and the watch progress of shows on Trakt
+type MediaMetadata interface {
+	LookupMedia(ctx context.Context, idType string, id string) ([]trakt.TraktMedia, error)
+	GetShowProgress(ctx context.Context, imdbID string) (*trakt.ShowProgress, error)
+}
+
+// MediaController handles manual media management
+type MediaController struct {
+	db                *models.Database
+	traktClient       MediaMetadata
+	cleanupCtrl       *CleanupController
+	redownloadWatched bool // Allow movies from the watched ledger to be imported again
+	notifier          *notify.Dispatcher
+	logger            *logrus.Logger
+
+	// Batches added in the background, kept for polling
+	batchMu sync.Mutex
+	batches map[string]*BatchJob
+}
+
+// NewMediaController creates a new media controller
+func NewMediaController(db *models.Database, traktClient MediaMetadata, cleanupCtrl *CleanupController, redownloadWatched bool, notifier *notify.Dispatcher, logger *logrus.Logger) *MediaController {
+	return &MediaController{
+		db:                db,
+		traktClient:       traktClient,
+		cleanupCtrl:       cleanupCtrl,
+		redownloadWatched: redownloadWatched,
+		notifier:          notifier,
+		logger:            logger,
+		batches:           make(map[string]*BatchJob),
+	}
+}
+
+// AddMedia looks up a movie or show on Trakt and adds it as a pending manual media
+func (c *MediaController) AddMedia(ctx context.Context, req AddMediaRequest) (*models.Media, error) {
+	media, err := c.lookupMedia(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := c.db.GetMediaByIMDBID(media.IMDBId, media.MediaType, nil, nil); err == nil {
+		return existing, ErrMediaExists
+	}
+
+	if err := c.createMedia(media); err != nil {
+		return nil, err
+	}
+
+	c.notifier.Notify(mediaEvent(notify.EventMediaAdded, media, fmt.Sprintf("Added %s manually", describeMedia(media))))
+
+	return media, nil
+}
+
+// lookupMedia finds a movie or show on Trakt, returning the manual media to add
+func (c *MediaController) lookupMedia(ctx context.Context, req AddMediaRequest) (*models.Media, error) {
+	if req.MediaType != "" && req.MediaType != models.MediaTypeMovie && req.MediaType != models.MediaTypeTV {
+		return nil, fmt.Errorf("%w: media_type must be movie or tv", ErrInvalidMedia)
+	}
+
+	idType, id := "imdb", strings.TrimSpace(req.IMDBId)
+	if id == "" {
+		switch {
+		case req.TMDBID > 0:
+			idType, id = "tmdb", fmt.Sprintf("%d", req.TMDBID)
+		case req.TraktID > 0:
+			idType, id = "trakt", fmt.Sprintf("%d", req.TraktID)
+		default:
+			return nil, fmt.Errorf("%w: imdb_id, tmdb_id or trakt_id is required", ErrInvalidMedia)
+		}
+		if req.MediaType == "" {
+			// TMDB and Trakt IDs are only unique per type
+			return nil, fmt.Errorf("%w: media_type is required with %s_id", ErrInvalidMedia, idType)
+		}
+	}
+
+	items, err := c.traktClient.LookupMedia(ctx, idType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var imdbID, title string
+	var year int
+	var mediaType models.MediaType
+	for _, item := range items {
+		if item.Movie != nil && (req.MediaType == "" || req.MediaType == models.MediaTypeMovie) {
+			imdbID, title, year, mediaType = item.Movie.IDs.IMDB, item.Movie.Title, item.Movie.Year, models.MediaTypeMovie
+			break
+		}
+		if item.Show != nil && (req.MediaType == "" || req.MediaType == models.MediaTypeTV) {
+			imdbID, title, year, mediaType = item.Show.IDs.IMDB, item.Show.Title, item.Show.Year, models.MediaTypeTV
+			break
+		}
+	}
+
+	if mediaType == "" {
+		return nil, fmt.Errorf("%w: no match on Trakt for %s %s", ErrMediaNotFound, idType, id)
+	}
+	if imdbID == "" {
+		return nil, fmt.Errorf("%w: %s has no IMDB ID on Trakt", ErrInvalidMedia, title)
+	}
+
+	return &models.Media{
+		IMDBId:          imdbID,
+		MediaType:       mediaType,
+		Title:           title,
+		Year:            year,
+		Source:          models.SourceManual,
+		Overrides:       req.Overrides,
+		Status:          models.StatusPending,
+		LastSeenInTrakt: time.Now(),
+	}, nil
+}
+
+// createMedia saves a manual media
+func (c *MediaController) createMedia(media *models.Media) error {
+	if err := c.db.CreateMedia(media); err != nil {
+		return fmt.Errorf("failed to create media: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"type":     media.MediaType,
+	}).Info("Added manual media")
+
+	return nil
+}
+
+// RemoveMedia removes a media item with its downloads and library files
+func (c *MediaController) RemoveMedia(id uint64) error {
+	media, err := c.db.GetMediaByID(id)
+	if err != nil {
+		return ErrMediaNotFound
+	}
+
+	return c.cleanupCtrl.RemoveMedia(media)
+}
+
+// PrepareSearch resets a media item to pending so it can be searched immediately
+// Media currently searching or downloading are left alone.
+func (c *MediaController) PrepareSearch(id uint64) (*models.Media, error) {
+	media, err := c.db.GetMediaByID(id)
+	if err != nil {
+		return nil, ErrMediaNotFound
+	}
+
+	switch media.Status {
+	case models.StatusSearching, models.StatusDownloading:
+		return media, fmt.Errorf("%w: status is %s", ErrMediaBusy, media.Status)
+	}
+
+	media.Status = models.StatusPending
+	if err := c.db.UpdateMedia(media); err != nil {
+		return nil, fmt.Errorf("failed to update media: %w", err)
+	}
+
+	return media, nil
+}
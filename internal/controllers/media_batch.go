@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBatchNotFound is returned when polling an unknown or expired batch
+var ErrBatchNotFound = errors.New("batch not found")
+
+const (
+	// maxBatchItems bounds the IDs of a batch, each one is looked up on Trakt
+	maxBatchItems = 500
+	// batchRetention is how long the report of a finished batch can be polled
+	batchRetention = time.Hour
+)
+
+// Outcomes of the items of a batch
+const (
+	BatchAdded     = "added"
+	BatchExists    = "exists"    // Already tracked
+	BatchDuplicate = "duplicate" // Listed earlier in the batch
+	BatchExcluded  = "excluded"  // Movie watched and cleaned up
+	BatchInvalid   = "invalid"
+	BatchNotFound  = "not_found"
+	BatchFailed    = "failed"
+)
+
+var (
+	imdbIDRegex = regexp.MustCompile(`(?i)\btt\d{5,}\b`)
+	tmdbIDRegex = regexp.MustCompile(`(?i)^(?:tmdb[:-])?(\d+)$`)
+)
+
+// BatchAddRequest lists movies and shows to add by ID, e.g. pasted from a spreadsheet
+// Each entry may hold several IDs separated by spaces, commas or new lines: IMDB IDs
+// (tt0133093, IMDB links) or TMDB IDs (603, tmdb:603), which need media_type.
+type BatchAddRequest struct {
+	IDs       []string              `json:"ids"`
+	MediaType models.MediaType      `json:"media_type"` // "movie" or "tv", optional with IMDB IDs
+	Overrides models.MediaOverrides `json:"overrides"`
+}
+
+// BatchAddResult is the outcome of one ID of a batch
+type BatchAddResult struct {
+	Input   string `json:"input"`
+	Status  string `json:"status"`
+	MediaID uint64 `json:"media_id,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchAddReport is the outcome of a batch, item by item
+type BatchAddReport struct {
+	Added   int              `json:"added"`
+	Skipped int              `json:"skipped"` // Existing, duplicate and excluded items
+	Failed  int              `json:"failed"`  // Invalid, not found and failed items
+	RampUp  bool             `json:"ramp_up"` // Grabs of the added media are spread over the next search cycles
+	Items   []BatchAddResult `json:"items"`
+}
+
+// BatchJob is a batch added in the background, polled for its report until done
+type BatchJob struct {
+	ID         string         `json:"id"`
+	Total      int            `json:"total"` // IDs in the batch
+	Done       bool           `json:"done"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Report     BatchAddReport `json:"report"` // Outcome of the IDs processed so far
+}
+
+// StartMediaBatch validates a batch and adds its movies and shows in the background
+// Each ID is looked up on Trakt, so a large batch outlasts an HTTP request: the returned
+// job is polled with MediaBatch. schedule is called once the batch is done with the number
+// of added media, to search them, and reports whether their grabs are ramped up.
+func (c *MediaController) StartMediaBatch(req BatchAddRequest, schedule func(added int) bool) (*BatchJob, error) {
+	inputs, err := batchInputs(req)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &BatchJob{ID: rand.Text(), Total: len(inputs), StartedAt: time.Now()}
+
+	c.batchMu.Lock()
+	for id, old := range c.batches {
+		if old.Done && time.Since(*old.FinishedAt) > batchRetention {
+			delete(c.batches, id)
+		}
+	}
+	c.batches[job.ID] = job
+	snapshot := job.snapshot()
+	c.batchMu.Unlock()
+
+	go c.runBatch(context.Background(), job, inputs, req, schedule)
+
+	return snapshot, nil
+}
+
+// MediaBatch returns the progress of a batch, its report once done
+func (c *MediaController) MediaBatch(id string) (*BatchJob, error) {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+
+	job, ok := c.batches[id]
+	if !ok {
+		return nil, ErrBatchNotFound
+	}
+	return job.snapshot(), nil
+}
+
+// batchInputs splits the entries of a batch into IDs
+func batchInputs(req BatchAddRequest) ([]string, error) {
+	if req.MediaType != "" && req.MediaType != models.MediaTypeMovie && req.MediaType != models.MediaTypeTV {
+		return nil, fmt.Errorf("%w: media_type must be movie or tv", ErrInvalidMedia)
+	}
+
+	var inputs []string
+	for _, entry := range req.IDs {
+		inputs = append(inputs, strings.FieldsFunc(entry, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ',' || r == ';'
+		})...)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: ids is required", ErrInvalidMedia)
+	}
+	if len(inputs) > maxBatchItems {
+		return nil, fmt.Errorf("%w: at most %d ids per batch, got %d", ErrInvalidMedia, maxBatchItems, len(inputs))
+	}
+	return inputs, nil
+}
+
+// runBatch looks up and adds the movies and shows of a batch as pending manual media
+// IDs already tracked, listed twice or of movies in the watched ledger are skipped, and
+// a failed item doesn't stop the batch.
+func (c *MediaController) runBatch(ctx context.Context, job *BatchJob, inputs []string, req BatchAddRequest, schedule func(added int) bool) {
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		result := c.addBatchItem(ctx, input, req, seen)
+		c.batchMu.Lock()
+		job.Report.add(result)
+		c.batchMu.Unlock()
+	}
+
+	c.batchMu.Lock()
+	added := job.Report.Added
+	c.logger.WithFields(logrus.Fields{
+		"batch":   job.ID,
+		"added":   added,
+		"skipped": job.Report.Skipped,
+		"failed":  job.Report.Failed,
+	}).Info("Batch import finished")
+	c.batchMu.Unlock()
+
+	rampUp := false
+	if added > 0 {
+		c.notifier.Notify(notify.Event{
+			Type:    notify.EventMediaAdded,
+			Message: fmt.Sprintf("Added %d media from a batch import", added),
+		})
+		if schedule != nil {
+			rampUp = schedule(added)
+		}
+	}
+
+	finished := time.Now()
+	c.batchMu.Lock()
+	job.Report.RampUp = rampUp
+	job.Done, job.FinishedAt = true, &finished
+	c.batchMu.Unlock()
+}
+
+// snapshot copies a job so it can be read while the batch goes on
+func (j *BatchJob) snapshot() *BatchJob {
+	copied := *j
+	copied.Report.Items = append([]BatchAddResult(nil), j.Report.Items...)
+	return &copied
+}
+
+// addBatchItem adds the media of one ID, seen holding the IDs and media already in the batch
+func (c *MediaController) addBatchItem(ctx context.Context, input string, batch BatchAddRequest, seen map[string]bool) BatchAddResult {
+	result := BatchAddResult{Input: input}
+
+	req := AddMediaRequest{MediaType: batch.MediaType, Overrides: batch.Overrides}
+	key := ""
+	if imdbID := imdbIDRegex.FindString(input); imdbID != "" {
+		req.IMDBId = strings.ToLower(imdbID)
+		key = req.IMDBId
+	} else if m := tmdbIDRegex.FindStringSubmatch(input); m != nil {
+		req.TMDBID, _ = strconv.Atoi(m[1])
+		key = fmt.Sprintf("tmdb:%s:%d", batch.MediaType, req.TMDBID)
+	} else {
+		result.Status, result.Error = BatchInvalid, "not an IMDB or TMDB ID"
+		return result
+	}
+	if seen[key] {
+		result.Status = BatchDuplicate
+		return result
+	}
+	seen[key] = true
+
+	media, err := c.lookupMedia(ctx, req)
+	switch {
+	case errors.Is(err, ErrInvalidMedia):
+		result.Status, result.Error = BatchInvalid, err.Error()
+		return result
+	case errors.Is(err, ErrMediaNotFound):
+		result.Status, result.Error = BatchNotFound, err.Error()
+		return result
+	case err != nil:
+		result.Status, result.Error = BatchFailed, err.Error()
+		return result
+	}
+	result.Title = media.Title
+
+	// TMDB and IMDB IDs of the same media
+	mediaKey := string(media.MediaType) + ":" + media.IMDBId
+	if seen[mediaKey] {
+		result.Status = BatchDuplicate
+		return result
+	}
+	seen[mediaKey] = true
+
+	if existing, err := c.db.GetMediaByIMDBID(media.IMDBId, media.MediaType, nil, nil); err == nil {
+		result.Status, result.MediaID = BatchExists, existing.ID
+		return result
+	}
+	if media.MediaType == models.MediaTypeMovie && !c.redownloadWatched {
+		watched, err := c.db.IsWatched(media.IMDBId, 0, 0)
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to check watched ledger")
+		}
+		if watched {
+			result.Status, result.Error = BatchExcluded, "watched and cleaned up, remove it from the watched ledger to add it again"
+			return result
+		}
+	}
+
+	if err := c.createMedia(media); err != nil {
+		result.Status, result.Error = BatchFailed, err.Error()
+		return result
+	}
+	result.Status, result.MediaID = BatchAdded, media.ID
+	return result
+}
+
+// add records the outcome of an item
+func (r *BatchAddReport) add(result BatchAddResult) {
+	switch result.Status {
+	case BatchAdded:
+		r.Added++
+	case BatchExists, BatchDuplicate, BatchExcluded:
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+	r.Items = append(r.Items, result)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeMetadata answers Trakt lookups from a fixed set of movies
+type fakeMetadata struct {
+	movies map[string]string // IMDB ID to title
+}
+
+func (f *fakeMetadata) LookupMedia(ctx context.Context, idType string, id string) ([]trakt.TraktMedia, error) {
+	title, ok := f.movies[id]
+	if idType != "imdb" || !ok {
+		return nil, nil
+	}
+
+	var items []trakt.TraktMedia
+	body := fmt.Sprintf(`[{"type":"movie","movie":{"title":%q,"year":1999,"ids":{"imdb":%q}}}]`, title, id)
+	if err := json.Unmarshal([]byte(body), &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (f *fakeMetadata) GetShowProgress(ctx context.Context, imdbID string) (*trakt.ShowProgress, error) {
+	return nil, nil
+}
+
+func TestStartMediaBatch(t *testing.T) {
+	db := newTestDatabase(t)
+
+	if err := db.CreateMedia(&models.Media{IMDBId: "tt0000002", MediaType: models.MediaTypeMovie, Title: "Tracked"}); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+
+	metadata := &fakeMetadata{movies: map[string]string{"tt0000001": "New", "tt0000002": "Tracked"}}
+	ctrl := NewMediaController(db, metadata, nil, false, nil, logrus.New())
+
+	if _, err := ctrl.StartMediaBatch(BatchAddRequest{}, nil); err == nil {
+		t.Error("Expected an error for an empty batch")
+	}
+
+	scheduled := 0
+	job, err := ctrl.StartMediaBatch(BatchAddRequest{
+		IDs: []string{"tt0000001, tt0000001", "https://www.imdb.com/title/tt0000002/", "bogus", "tt0000009"},
+	}, func(added int) bool {
+		scheduled = added
+		return false
+	})
+	if err != nil {
+		t.Fatalf("StartMediaBatch failed: %v", err)
+	}
+	if job.Total != 5 {
+		t.Errorf("Expected 5 IDs, got %d", job.Total)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.Done {
+		if time.Now().After(deadline) {
+			t.Fatal("Batch did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if job, err = ctrl.MediaBatch(job.ID); err != nil {
+			t.Fatalf("MediaBatch failed: %v", err)
+		}
+	}
+
+	want := []string{BatchAdded, BatchDuplicate, BatchExists, BatchInvalid, BatchNotFound}
+	if len(job.Report.Items) != len(want) {
+		t.Fatalf("Expected %d items, got %+v", len(want), job.Report.Items)
+	}
+	for i, status := range want {
+		if job.Report.Items[i].Status != status {
+			t.Errorf("Item %q: expected %s, got %s", job.Report.Items[i].Input, status, job.Report.Items[i].Status)
+		}
+	}
+	if job.Report.Added != 1 || job.Report.Skipped != 2 || job.Report.Failed != 2 {
+		t.Errorf("Unexpected counts: %+v", job.Report)
+	}
+	if scheduled != 1 {
+		t.Errorf("Expected a search scheduled for 1 added media, got %d", scheduled)
+	}
+
+	if _, err := ctrl.MediaBatch("unknown"); !errors.Is(err, ErrBatchNotFound) {
+		t.Errorf("Expected ErrBatchNotFound, got %v", err)
+	}
+}
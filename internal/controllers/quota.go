@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrQuotaExceeded is returned when a release doesn't fit in the storage quota of its library
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// QuotaPolicy is what happens to a grab that would exceed a storage quota
+type QuotaPolicy string
+
+const (
+	QuotaSkip    QuotaPolicy = "skip"    // Don't download the release
+	QuotaSmaller QuotaPolicy = "smaller" // Download the best candidate that fits instead
+	QuotaEvict   QuotaPolicy = "evict"   // Delete the oldest watched media of the library to make room
+)
+
+// StorageQuota is the storage budget of a library
+type StorageQuota struct {
+	Name    string
+	Library string // Custom Trakt list name, source (watchlist, favorites, manual) or media type (movie, tv)
+	Limit   int64  // Bytes
+	Policy  QuotaPolicy
+}
+
+// Matches reports whether a media item belongs to the library of the quota
+func (q StorageQuota) Matches(media *models.Media) bool {
+	return q.Library == media.List || q.Library == string(media.Source) || q.Library == string(media.MediaType)
+}
+
+// quotaEviction is the watched media to delete to make room for a release in a quota library
+// The media is only deleted once the release is sent to TorBox.
+type quotaEviction struct {
+	quota  StorageQuota
+	action models.QuotaAction
+	medias []*models.Media
+}
+
+// QuotaUsage is the storage used by the library of a quota
+type QuotaUsage struct {
+	Name    string      `json:"name"`
+	Library string      `json:"library"`
+	Policy  QuotaPolicy `json:"policy"`
+	Limit   int64       `json:"limit"`
+	Used    int64       `json:"used"`
+}
+
+// QuotaUsage returns the storage used by the library of each quota
+func (c *DownloadController) QuotaUsage() ([]QuotaUsage, error) {
+	usage := []QuotaUsage{}
+	for _, quota := range c.quotas {
+		used, err := c.quotaUsed(quota, nil)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, QuotaUsage{
+			Name:    quota.Name,
+			Library: quota.Library,
+			Policy:  quota.Policy,
+			Limit:   quota.Limit,
+			Used:    used,
+		})
+	}
+	return usage, nil
+}
+
+// enforceQuotas checks a release against the quotas of its library before it is downloaded
+// Returns the release to download: the same one, or a smaller candidate under the smaller
+// policy, and the evictions to run once it is grabbed under the evict policy. Returns an
+// error wrapping ErrQuotaExceeded when the release is skipped.
+func (c *DownloadController) enforceQuotas(media *models.Media, nzb *models.NZB) (*models.NZB, []quotaEviction, error) {
+	if media == nil {
+		return nzb, nil, nil
+	}
+
+	var evictions []quotaEviction
+	for _, quota := range c.quotas {
+		if !quota.Matches(media) {
+			continue
+		}
+
+		used, err := c.quotaUsed(quota, nzb)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get %s quota usage: %w", quota.Name, err)
+		}
+		if used+nzb.Size <= quota.Limit {
+			continue
+		}
+
+		action := &models.QuotaAction{
+			Quota:   quota.Name,
+			MediaID: media.ID,
+			Title:   describeMedia(media),
+			Release: nzb.Title,
+			Size:    nzb.Size,
+			Used:    used,
+			Limit:   quota.Limit,
+			At:      time.Now(),
+		}
+
+		switch quota.Policy {
+		case QuotaSmaller:
+			if smaller := c.smallerRelease(nzb, quota.Limit-used); smaller != nil {
+				if err := c.swapRelease(nzb, smaller); err != nil {
+					return nil, nil, err
+				}
+				action.Action = models.QuotaActionSmaller
+				action.Detail = smaller.Title
+				c.recordQuotaAction(action)
+				nzb = smaller
+				continue
+			}
+		case QuotaEvict:
+			if medias := c.planEviction(quota, media, used+nzb.Size-quota.Limit); len(medias) > 0 {
+				evictions = append(evictions, quotaEviction{quota: quota, action: *action, medias: medias})
+				continue
+			}
+		}
+
+		action.Action = models.QuotaActionSkipped
+		c.recordQuotaAction(action)
+
+		nzb.Status = models.NZBStatusCandidate
+		if err := c.db.UpdateNZB(nzb); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Failed to update NZB status")
+		}
+		return nil, nil, fmt.Errorf("%w: %s uses %s of %s, %s needs %s", ErrQuotaExceeded,
+			quota.Name, formatGB(used), formatGB(quota.Limit), nzb.Title, formatGB(nzb.Size))
+	}
+
+	return nzb, evictions, nil
+}
+
+// quotaUsed sums the size of the downloading and completed releases of a quota library
+// The releases an upgrade replaces are left out, they are deleted once it completes.
+func (c *DownloadController) quotaUsed(quota StorageQuota, upgrade *models.NZB) (int64, error) {
+	excluded := make(map[uint64]bool)
+	if upgrade != nil {
+		excluded[upgrade.ID] = true
+		if upgrade.Replaces != 0 {
+			excluded[upgrade.Replaces] = true
+		}
+		for _, id := range upgrade.ReplacesEpisodes {
+			excluded[id] = true
+		}
+	}
+
+	medias := make(map[uint64]*models.Media)
+	var used int64
+	for _, status := range []models.NZBStatus{models.NZBStatusDownloading, models.NZBStatusCompleted} {
+		nzbs, err := c.db.GetNZBsByStatus(status)
+		if err != nil {
+			return 0, err
+		}
+		for _, nzb := range nzbs {
+			if excluded[nzb.ID] {
+				continue
+			}
+			media, ok := medias[nzb.MediaID]
+			if !ok {
+				media, _ = c.db.GetMediaByID(nzb.MediaID)
+				medias[nzb.MediaID] = media
+			}
+			if media != nil && quota.Matches(media) {
+				used += nzb.Size
+			}
+		}
+	}
+	return used, nil
+}
+
+// smallerRelease picks the best scoring candidate covering the same episodes as a release
+// that fits in the available space, nil if none does. Upgrades are never swapped.
+func (c *DownloadController) smallerRelease(nzb *models.NZB, available int64) *models.NZB {
+	if nzb.IsUpgrade() || available <= 0 {
+		return nil
+	}
+
+	candidates, err := c.db.GetNZBsByMediaIDAndStatus(nzb.MediaID, models.NZBStatusCandidate)
+	if err != nil {
+		c.logger.WithError(err).WithField("media_id", nzb.MediaID).Warn("Failed to get candidates")
+		return nil
+	}
+
+	var best *models.NZB
+	for _, candidate := range candidates {
+		if candidate.ID == nzb.ID || candidate.Size <= 0 || candidate.Size > available {
+			continue
+		}
+		if candidate.IsSeasonPack != nzb.IsSeasonPack || !equalNumber(candidate.Season, nzb.Season) || !equalNumber(candidate.Episode, nzb.Episode) {
+			continue
+		}
+		if best == nil || candidate.QualityScore > best.QualityScore ||
+			(candidate.QualityScore == best.QualityScore && candidate.Score > best.Score) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// swapRelease puts a selected release back with the candidates and selects a smaller one
+func (c *DownloadController) swapRelease(nzb *models.NZB, smaller *models.NZB) error {
+	nzb.Status = models.NZBStatusCandidate
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		return fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	smaller.Status = models.NZBStatusSelected
+	if err := c.db.UpdateNZB(smaller); err != nil {
+		return fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": nzb.MediaID,
+		"release":  nzb.Title,
+		"smaller":  smaller.Title,
+	}).Info("Release exceeds storage quota, downloading a smaller one")
+	return nil
+}
+
+// planEviction picks the oldest watched media of a quota library freeing needed bytes
+// Returns nil when the watched media don't free enough.
+func (c *DownloadController) planEviction(quota StorageQuota, media *models.Media, needed int64) []*models.Media {
+	medias, err := c.db.GetMediasByStatus(models.StatusCompleted)
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to get completed medias")
+		return nil
+	}
+
+	var watched []*models.Media
+	for _, candidate := range medias {
+		if candidate.Watched && candidate.ID != media.ID && quota.Matches(candidate) {
+			watched = append(watched, candidate)
+		}
+	}
+	sort.SliceStable(watched, func(i, j int) bool {
+		return completedBefore(watched[i], watched[j])
+	})
+
+	var evict []*models.Media
+	var freed int64
+	for _, candidate := range watched {
+		if freed >= needed {
+			break
+		}
+		size, err := c.mediaSize(candidate)
+		if err != nil || size == 0 {
+			continue
+		}
+		evict = append(evict, candidate)
+		freed += size
+	}
+	if freed < needed {
+		return nil
+	}
+	return evict
+}
+
+// evict deletes the watched media planned by enforceQuotas, once their room is taken
+func (c *DownloadController) evict(evictions []quotaEviction) {
+	deleted := make(map[uint64]bool)
+	for _, eviction := range evictions {
+		for _, candidate := range eviction.medias {
+			// Media in several quota libraries may be planned twice
+			if deleted[candidate.ID] {
+				continue
+			}
+			c.logger.WithFields(logrus.Fields{
+				"media_id": candidate.ID,
+				"title":    candidate.Title,
+				"quota":    eviction.quota.Name,
+			}).Info("Deleting watched media to make room in storage quota")
+
+			if err := c.cleanupCtrl.deleteMedia(candidate); err != nil {
+				c.logger.WithError(err).WithField("media_id", candidate.ID).Warn("Failed to delete watched media")
+				break
+			}
+			deleted[candidate.ID] = true
+			if candidate.MediaType == models.MediaTypeMovie {
+				c.cleanupCtrl.recordWatched(candidate, 0, 0, candidate.UpdatedAt)
+			}
+
+			action := eviction.action
+			action.Action = models.QuotaActionEvicted
+			action.Detail = describeMedia(candidate)
+			c.recordQuotaAction(&action)
+		}
+	}
+}
+
+// mediaSize sums the size of the downloading and completed releases of a media item
+func (c *DownloadController) mediaSize(media *models.Media) (int64, error) {
+	nzbs, err := c.db.GetNZBsByMediaID(media.ID)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, nzb := range nzbs {
+		if nzb.Status == models.NZBStatusDownloading || nzb.Status == models.NZBStatusCompleted {
+			size += nzb.Size
+		}
+	}
+	return size, nil
+}
+
+// recordQuotaAction stores a storage quota action in the history
+func (c *DownloadController) recordQuotaAction(action *models.QuotaAction) {
+	if err := c.db.RecordQuotaAction(action); err != nil {
+		c.logger.WithError(err).WithField("quota", action.Quota).Warn("Failed to record storage quota action")
+	}
+}
+
+// completedBefore orders media by completion, oldest first
+func completedBefore(a, b *models.Media) bool {
+	if a.CompletedAt == nil || b.CompletedAt == nil {
+		return a.CompletedAt != nil && b.CompletedAt == nil
+	}
+	return a.CompletedAt.Before(*b.CompletedAt)
+}
+
+// formatGB formats a size in bytes as gigabytes
+func formatGB(size int64) string {
+	return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// quotaFixture is a movie library holding a watched movie, and a release that doesn't fit
+type quotaFixture struct {
+	db      *models.Database
+	watched *models.Media
+	media   *models.Media
+	nzb     *models.NZB // Selected, exceeds the quota
+	smaller *models.NZB // Candidate that fits
+}
+
+func newQuotaFixture(t *testing.T) *quotaFixture {
+	db := newTestDatabase(t)
+	completedAt := time.Now().Add(-time.Hour)
+	f := &quotaFixture{
+		db:      db,
+		watched: &models.Media{IMDBId: "tt0000001", MediaType: models.MediaTypeMovie, Title: "Watched", Status: models.StatusCompleted, Watched: true, CompletedAt: &completedAt},
+		media:   &models.Media{IMDBId: "tt0000002", MediaType: models.MediaTypeMovie, Title: "New", Status: models.StatusPending},
+	}
+	for _, media := range []*models.Media{f.watched, f.media} {
+		if err := db.CreateMedia(media); err != nil {
+			t.Fatalf("Failed to create media: %v", err)
+		}
+	}
+
+	f.nzb = &models.NZB{MediaID: f.media.ID, GUID: "large", Title: "New.2160p", Size: 50, QualityScore: 2, Status: models.NZBStatusSelected}
+	f.smaller = &models.NZB{MediaID: f.media.ID, GUID: "small", Title: "New.1080p", Size: 30, QualityScore: 1, Status: models.NZBStatusCandidate}
+	for _, nzb := range []*models.NZB{
+		{MediaID: f.watched.ID, GUID: "watched", Title: "Watched.1080p", Size: 60, Status: models.NZBStatusCompleted},
+		f.nzb,
+		f.smaller,
+	} {
+		if err := db.CreateNZB(nzb); err != nil {
+			t.Fatalf("Failed to create NZB: %v", err)
+		}
+	}
+	return f
+}
+
+func (f *quotaFixture) controller(policy QuotaPolicy, diskGuard DiskGuard) *DownloadController {
+	quotas := []StorageQuota{{Name: "movies", Library: "movie", Limit: 100, Policy: policy}}
+	return NewDownloadController(f.db, nil, nil, DownloadParamTemplates{}, nil, &CleanupController{}, quotas, diskGuard, 0, RetryPolicy{}, nil, nil, false, logrus.New())
+}
+
+func TestEnforceQuotas(t *testing.T) {
+	t.Run("skip", func(t *testing.T) {
+		f := newQuotaFixture(t)
+		if _, _, err := f.controller(QuotaSkip, DiskGuard{}).enforceQuotas(f.media, f.nzb); !errors.Is(err, ErrQuotaExceeded) {
+			t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+		}
+		if stored, _ := f.db.GetNZBByID(f.nzb.ID); stored.Status != models.NZBStatusCandidate {
+			t.Errorf("Expected the release back with the candidates, got %s", stored.Status)
+		}
+	})
+
+	t.Run("smaller", func(t *testing.T) {
+		f := newQuotaFixture(t)
+		nzb, evictions, err := f.controller(QuotaSmaller, DiskGuard{}).enforceQuotas(f.media, f.nzb)
+		if err != nil {
+			t.Fatalf("enforceQuotas failed: %v", err)
+		}
+		if nzb.ID != f.smaller.ID || len(evictions) != 0 {
+			t.Errorf("Expected the smaller release, got %s with %d evictions", nzb.Title, len(evictions))
+		}
+		if stored, _ := f.db.GetNZBByID(f.smaller.ID); stored.Status != models.NZBStatusSelected {
+			t.Errorf("Expected the smaller release to be selected, got %s", stored.Status)
+		}
+	})
+
+	t.Run("evict", func(t *testing.T) {
+		f := newQuotaFixture(t)
+		nzb, evictions, err := f.controller(QuotaEvict, DiskGuard{}).enforceQuotas(f.media, f.nzb)
+		if err != nil {
+			t.Fatalf("enforceQuotas failed: %v", err)
+		}
+		if nzb.ID != f.nzb.ID || len(evictions) != 1 || len(evictions[0].medias) != 1 || evictions[0].medias[0].ID != f.watched.ID {
+			t.Fatalf("Expected the watched movie to be planned for eviction, got %+v", evictions)
+		}
+		if _, err := f.db.GetMediaByID(f.watched.ID); err != nil {
+			t.Errorf("Expected the watched movie to be kept until the grab, got %v", err)
+		}
+	})
+
+	t.Run("evict held back by disk space", func(t *testing.T) {
+		f := newQuotaFixture(t)
+		ctrl := f.controller(QuotaEvict, DiskGuard{Path: t.TempDir(), MinFree: 1 << 62})
+		if err := ctrl.DownloadNZB(f.nzb); !errors.Is(err, ErrDiskSpaceLow) {
+			t.Fatalf("Expected ErrDiskSpaceLow, got %v", err)
+		}
+		if _, err := f.db.GetMediaByID(f.watched.ID); err != nil {
+			t.Errorf("Expected the watched movie to be kept, got %v", err)
+		}
+	})
+}
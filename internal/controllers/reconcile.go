@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/torbox"
+	"github.com/sirupsen/logrus"
+)
+
+// missingJobGrace is how long a download may be absent from the TorBox lists before
+// it is considered gone (TorBox lists new jobs with a small delay)
+var missingJobGrace = 10 * time.Minute
+
+// torboxJobLookup reads the TorBox jobs the reconciler checks, implemented by *torbox.Client
+type torboxJobLookup interface {
+	ListUsenetDownloads() ([]torbox.UsenetDownload, error)
+	ListTorrents() ([]torbox.TorrentDownload, error)
+	FindDownloadByID(downloadID int) (*torbox.UsenetDownload, error)
+	FindTorrentByID(torrentID int) (*torbox.TorrentDownload, error)
+}
+
+// torboxJob is the state of a TorBox usenet download or torrent
+type torboxJob struct {
+	state    string
+	finished bool
+}
+
+// ReconcileStats counts what a reconciliation found
+type ReconcileStats struct {
+	Checked   int
+	Completed int
+	Failed    int
+}
+
+// ReconcileDownloads polls TorBox for the downloads still in progress and applies
+// their outcome, so completions are picked up even when webhooks never arrive
+// (NAT, wrong webhook URL). Downloads TorBox confirms are gone are treated as failed.
+func (c *DownloadController) ReconcileDownloads() (*ReconcileStats, error) {
+	stats := &ReconcileStats{}
+
+	nzbs, err := c.db.GetNZBsByStatus(models.NZBStatusDownloading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get downloading NZBs: %w", err)
+	}
+	if len(nzbs) == 0 {
+		return stats, nil
+	}
+
+	usenetJobs, torrentJobs, err := c.listJobs(nzbs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, nzb := range nzbs {
+		if nzb.TorBoxJobID == "" {
+			continue
+		}
+		stats.Checked++
+
+		jobs := usenetJobs
+		if nzb.IsTorrent() {
+			jobs = torrentJobs
+		}
+
+		status, reason := "", ""
+		job, found := jobs[nzb.TorBoxJobID]
+		if !found {
+			if time.Since(nzb.UpdatedAt) < missingJobGrace {
+				continue
+			}
+			// The lists are not paginated, only a lookup by ID tells the job is gone
+			job, err = c.lookupJob(nzb)
+			if errors.Is(err, torbox.ErrJobNotFound) {
+				status, reason = "failed", "download no longer in TorBox"
+			} else if err != nil {
+				c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to look up download missing from TorBox list")
+				continue
+			}
+		}
+		switch {
+		case status != "":
+		case job.finished:
+			status = "completed"
+		case isFailedState(job.state):
+			status, reason = "failed", "TorBox download "+job.state
+		default:
+			continue
+		}
+
+		c.logger.WithFields(logrus.Fields{
+			"nzb_id": nzb.ID,
+			"job_id": nzb.TorBoxJobID,
+			"status": status,
+		}).Info("Reconciled download status from TorBox")
+
+		if err := c.applyJobStatus(nzb, status, reason); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Failed to apply reconciled status")
+			continue
+		}
+		if status == "completed" {
+			stats.Completed++
+		} else {
+			stats.Failed++
+		}
+	}
+
+	return stats, nil
+}
+
+// listJobs reads the TorBox usenet downloads and torrents by job ID
+// Each list is only fetched when an NZB of its protocol is downloading.
+func (c *DownloadController) listJobs(nzbs []*models.NZB) (usenet, torrents map[string]torboxJob, err error) {
+	var needUsenet, needTorrents bool
+	for _, nzb := range nzbs {
+		if nzb.IsTorrent() {
+			needTorrents = true
+		} else {
+			needUsenet = true
+		}
+	}
+
+	usenet = make(map[string]torboxJob)
+	if needUsenet {
+		downloads, err := c.jobLookup.ListUsenetDownloads()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list usenet downloads: %w", err)
+		}
+		for _, download := range downloads {
+			usenet[strconv.Itoa(download.ID)] = torboxJob{
+				state:    download.DownloadState,
+				finished: download.DownloadFinished || download.Cached,
+			}
+		}
+	}
+
+	torrents = make(map[string]torboxJob)
+	if needTorrents {
+		list, err := c.jobLookup.ListTorrents()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list torrents: %w", err)
+		}
+		for _, torrent := range list {
+			torrents[strconv.Itoa(torrent.ID)] = torboxJob{
+				state:    torrent.DownloadState,
+				finished: torrent.DownloadFinished || torrent.Cached,
+			}
+		}
+	}
+
+	return usenet, torrents, nil
+}
+
+// lookupJob reads the TorBox job of an NZB by its ID
+func (c *DownloadController) lookupJob(nzb *models.NZB) (torboxJob, error) {
+	id, err := strconv.Atoi(nzb.TorBoxJobID)
+	if err != nil {
+		return torboxJob{}, fmt.Errorf("invalid job ID: %w", err)
+	}
+
+	if nzb.IsTorrent() {
+		torrent, err := c.jobLookup.FindTorrentByID(id)
+		if err != nil {
+			return torboxJob{}, err
+		}
+		return torboxJob{state: torrent.DownloadState, finished: torrent.DownloadFinished || torrent.Cached}, nil
+	}
+
+	download, err := c.jobLookup.FindDownloadByID(id)
+	if err != nil {
+		return torboxJob{}, err
+	}
+	return torboxJob{state: download.DownloadState, finished: download.DownloadFinished || download.Cached}, nil
+}
+
+// isFailedState checks if a TorBox download state is a failure (e.g. "failed", "error")
+func isFailedState(state string) bool {
+	state = strings.ToLower(state)
+	return strings.Contains(state, "fail") || strings.Contains(state, "error")
+}
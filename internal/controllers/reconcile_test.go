@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/torbox"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeJobLookup answers TorBox job reads from a fixed set of usenet downloads
+type fakeJobLookup struct {
+	listed []torbox.UsenetDownload        // Returned by the (first page of the) list
+	byID   map[int]*torbox.UsenetDownload // Returned by lookups, nil entries are gone
+}
+
+func (f *fakeJobLookup) ListUsenetDownloads() ([]torbox.UsenetDownload, error) {
+	return f.listed, nil
+}
+
+func (f *fakeJobLookup) ListTorrents() ([]torbox.TorrentDownload, error) {
+	return nil, nil
+}
+
+func (f *fakeJobLookup) FindDownloadByID(downloadID int) (*torbox.UsenetDownload, error) {
+	download, ok := f.byID[downloadID]
+	if !ok {
+		return nil, fmt.Errorf("lookup of %d failed", downloadID)
+	}
+	if download == nil {
+		return nil, torbox.ErrJobNotFound
+	}
+	return download, nil
+}
+
+func (f *fakeJobLookup) FindTorrentByID(torrentID int) (*torbox.TorrentDownload, error) {
+	return nil, torbox.ErrJobNotFound
+}
+
+// createDownloading stores a media item with a release downloading as TorBox job jobID
+func createDownloading(t *testing.T, db *models.Database, jobID string) *models.NZB {
+	media := &models.Media{IMDBId: "tt" + jobID, MediaType: models.MediaTypeMovie, Title: "Movie " + jobID, Status: models.StatusDownloading}
+	if err := db.CreateMedia(media); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+	nzb := &models.NZB{MediaID: media.ID, GUID: "guid-" + jobID, Title: "Release " + jobID, Status: models.NZBStatusDownloading, TorBoxJobID: jobID}
+	if err := db.CreateNZB(nzb); err != nil {
+		t.Fatalf("Failed to create NZB: %v", err)
+	}
+	return nzb
+}
+
+func TestReconcileDownloads(t *testing.T) {
+	grace := missingJobGrace
+	missingJobGrace = 0
+	defer func() { missingJobGrace = grace }()
+
+	db := newTestDatabase(t)
+	pastFirstPage := createDownloading(t, db, "1")
+	lookupFailed := createDownloading(t, db, "2")
+	listed := createDownloading(t, db, "3")
+
+	ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, &CleanupController{}, nil, DiskGuard{}, 0, RetryPolicy{}, nil, nil, false, logrus.New())
+	ctrl.jobLookup = &fakeJobLookup{
+		listed: []torbox.UsenetDownload{{ID: 3, DownloadState: "downloading"}},
+		byID:   map[int]*torbox.UsenetDownload{1: {ID: 1, DownloadFinished: true}},
+	}
+
+	stats, err := ctrl.ReconcileDownloads()
+	if err != nil {
+		t.Fatalf("ReconcileDownloads failed: %v", err)
+	}
+	if stats.Checked != 3 || stats.Completed != 1 || stats.Failed != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	for nzb, want := range map[*models.NZB]models.NZBStatus{
+		pastFirstPage: models.NZBStatusCompleted,   // Missing from the list but finished
+		lookupFailed:  models.NZBStatusDownloading, // Not confirmed gone
+		listed:        models.NZBStatusDownloading,
+	} {
+		stored, err := db.GetNZBByID(nzb.ID)
+		if err != nil {
+			t.Fatalf("Failed to get NZB: %v", err)
+		}
+		if stored.Status != want {
+			t.Errorf("NZB %s: expected %s, got %s", nzb.TorBoxJobID, want, stored.Status)
+		}
+	}
+}
+
+func TestApplyJobStatusSkipsHandledJobs(t *testing.T) {
+	db := newTestDatabase(t)
+	nzb := createDownloading(t, db, "1")
+	stale := *nzb
+
+	// A webhook completes the download while the reconciler still holds the old record
+	ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, &CleanupController{}, nil, DiskGuard{}, 0, RetryPolicy{}, nil, nil, false, logrus.New())
+	if err := ctrl.applyJobStatus(nzb, "completed", ""); err != nil {
+		t.Fatalf("applyJobStatus failed: %v", err)
+	}
+	for _, status := range []string{"failed", "completed"} {
+		if err := ctrl.applyJobStatus(&stale, status, "late"); err != nil {
+			t.Fatalf("applyJobStatus failed: %v", err)
+		}
+	}
+
+	stored, err := db.GetNZBByID(nzb.ID)
+	if err != nil {
+		t.Fatalf("Failed to get NZB: %v", err)
+	}
+	if stored.Status != models.NZBStatusCompleted || stored.RetryCount != 0 || stored.FailureReason != "" {
+		t.Errorf("Expected the completed NZB to be left alone, got %s retries=%d", stored.Status, stored.RetryCount)
+	}
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// RecoveryStats counts what a recovery scan found
+type RecoveryStats struct {
+	Scanned   int // Finished TorBox downloads checked
+	Recovered int // Downloads completed by the scan
+}
+
+// jobKey identifies a TorBox job, usenet and torrent IDs are separate sequences
+type jobKey struct {
+	id      string
+	torrent bool
+}
+
+// finishedJob is a finished TorBox usenet download or torrent
+type finishedJob struct {
+	id      string
+	name    string
+	hash    string
+	torrent bool
+}
+
+// RecoverDownloads scans the TorBox download history for finished downloads whose
+// completion was never handled (e.g. gomenarr was down when the webhook was sent) and
+// runs the normal completion flow for them. Downloads are matched by job ID, hash, then
+// by title for releases that were sent to TorBox without their job ID being saved.
+// maxAge limits the scan to recent downloads, 0 scans the whole history.
+func (c *DownloadController) RecoverDownloads(maxAge time.Duration) (*RecoveryStats, error) {
+	stats := &RecoveryStats{}
+
+	// Releases that may have a finished download: in progress, selected (crash before the
+	// job ID was saved) or failed (stuck timeout while TorBox kept downloading)
+	var pending []*models.NZB
+	for _, status := range []models.NZBStatus{models.NZBStatusDownloading, models.NZBStatusSelected, models.NZBStatusFailed} {
+		nzbs, err := c.db.GetNZBsByStatus(status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s NZBs: %w", status, err)
+		}
+		pending = append(pending, nzbs...)
+	}
+	if len(pending) == 0 {
+		return stats, nil
+	}
+
+	byJob := make(map[jobKey]*models.NZB)
+	byHash := make(map[string]*models.NZB)
+	byTitle := make(map[string]*models.NZB)
+	for _, nzb := range pending {
+		if nzb.TorBoxJobID != "" {
+			byJob[jobKey{id: nzb.TorBoxJobID, torrent: nzb.IsTorrent()}] = nzb
+		}
+		if nzb.TorBoxHash != "" {
+			byHash[nzb.TorBoxHash] = nzb
+		}
+		if nzb.Status == models.NZBStatusSelected && nzb.TorBoxJobID == "" {
+			byTitle[utils.NormalizeTitle(nzb.Title)] = nzb
+		}
+	}
+
+	jobs, err := c.finishedJobs(maxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		stats.Scanned++
+
+		nzb := byJob[jobKey{id: job.id, torrent: job.torrent}]
+		if nzb == nil && job.hash != "" {
+			nzb = byHash[job.hash]
+		}
+		if nzb == nil {
+			nzb = byTitle[utils.NormalizeTitle(job.name)]
+		}
+		if nzb == nil || nzb.IsTorrent() != job.torrent {
+			continue
+		}
+
+		// A webhook may have handled the job since the lists were read
+		current, err := c.db.GetNZBByID(nzb.ID)
+		if err != nil || current.Status == models.NZBStatusCompleted || current.Status == models.NZBStatusReplaced || current.Status == models.NZBStatusRejected {
+			continue
+		}
+
+		if err := c.recoverJob(current, job); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", current.ID).Warn("Failed to recover finished download")
+			continue
+		}
+		stats.Recovered++
+	}
+
+	return stats, nil
+}
+
+// recoverJob completes an NZB from a finished TorBox download
+func (c *DownloadController) recoverJob(nzb *models.NZB, job finishedJob) error {
+	c.logger.WithFields(logrus.Fields{
+		"nzb_id": nzb.ID,
+		"job_id": job.id,
+		"title":  nzb.Title,
+		"status": nzb.Status,
+	}).Info("Recovering finished download without webhook")
+
+	if nzb.TorBoxJobID == "" {
+		nzb.TorBoxJobID = job.id
+	}
+	if nzb.TorBoxHash == "" {
+		nzb.TorBoxHash = job.hash
+	}
+	if nzb.Status == models.NZBStatusSelected {
+		nzb.Status = models.NZBStatusDownloading
+	}
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		return fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	return c.applyJobStatus(nzb, "completed", "")
+}
+
+// finishedJobs lists the finished TorBox usenet downloads and torrents created within maxAge
+func (c *DownloadController) finishedJobs(maxAge time.Duration) ([]finishedJob, error) {
+	recent := func(createdAt string) bool {
+		if maxAge <= 0 {
+			return true
+		}
+		created, err := time.Parse(time.RFC3339, createdAt)
+		return err != nil || time.Since(created) <= maxAge
+	}
+
+	var jobs []finishedJob
+
+	downloads, err := c.torboxClient.ListUsenetDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usenet downloads: %w", err)
+	}
+	for _, download := range downloads {
+		if (download.DownloadFinished || download.Cached) && recent(download.CreatedAt) {
+			jobs = append(jobs, finishedJob{id: strconv.Itoa(download.ID), name: download.Name, hash: download.Hash})
+		}
+	}
+
+	torrents, err := c.torboxClient.ListTorrents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	for _, torrent := range torrents {
+		if (torrent.DownloadFinished || torrent.Cached) && recent(torrent.CreatedAt) {
+			jobs = append(jobs, finishedJob{id: strconv.Itoa(torrent.ID), name: torrent.Name, hash: torrent.Hash, torrent: true})
+		}
+	}
+
+	return jobs, nil
+}
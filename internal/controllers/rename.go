@@ -0,0 +1,573 @@
+package controllers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// Rename modes
+const (
+	RenameModeMove     = "move"
+	RenameModeHardlink = "hardlink"
+	RenameModeCopy     = "copy"
+)
+
+// Import rejections, the download is quarantined and the next candidate downloaded
+var (
+	// ErrChecksumMismatch is returned when a copied file doesn't match the checksum of its download
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrSampleOnly is returned when a download holds sample videos only
+	ErrSampleOnly = errors.New("sample only")
+	// ErrWrongContent is returned when a download holds no video or not the expected episode
+	ErrWrongContent = errors.New("wrong content")
+)
+
+// copyAttempts is the number of tries of an interrupted copy, each resuming where the last one stopped
+const copyAttempts = 3
+
+// subtitleExtensions are the sidecar files renamed along with a video file
+var subtitleExtensions = map[string]bool{".srt": true, ".ass": true, ".ssa": true, ".sub": true, ".idx": true}
+
+// RenameOptions holds the configured library layout
+type RenameOptions struct {
+	Mode            string // move, hardlink or copy, empty to leave downloads in place
+	DownloadDir     string // Where completed downloads appear, one file or folder per release
+	MoviesDir       string
+	ShowsDir        string
+	MovieTemplate   string
+	EpisodeTemplate string
+	QuarantineDir   string // Where rejected downloads are moved, empty to leave them in DownloadDir
+}
+
+// Renamer places completed downloads into the library using the naming templates
+type Renamer struct {
+	options RenameOptions
+	link    func(oldname, newname string) error // os.Link, replaced in tests to cross devices
+	logger  *logrus.Logger
+}
+
+// NewRenamer creates a new renamer, checking the naming templates when renaming is enabled
+func NewRenamer(options RenameOptions, logger *logrus.Logger) (*Renamer, error) {
+	if options.Mode != "" {
+		if err := utils.ValidateNamingTemplate(options.MovieTemplate); err != nil {
+			return nil, fmt.Errorf("invalid movie naming template: %w", err)
+		}
+		if err := utils.ValidateNamingTemplate(options.EpisodeTemplate); err != nil {
+			return nil, fmt.Errorf("invalid episode naming template: %w", err)
+		}
+	}
+
+	return &Renamer{
+		options: options,
+		link:    os.Link,
+		logger:  logger,
+	}, nil
+}
+
+// RenamedFile is a video file placed in the library
+type RenamedFile struct {
+	Season  int // 0 for movies
+	Episode int // 0 for movies
+	Path    string
+	Size    int64
+}
+
+// Enabled checks if completed downloads are renamed
+func (r *Renamer) Enabled() bool {
+	return r != nil && r.options.Mode != ""
+}
+
+// Copies checks if renaming may copy the downloaded files, moves and hardlinks across
+// file systems do
+func (r *Renamer) Copies() bool {
+	return r.Enabled()
+}
+
+// Rename places the video files of a completed release into the library
+// Movies keep their largest video file. Episodes are matched by their SxxEyy marker,
+// season packs import every episode found. Subtitles next to a video file follow it.
+// checksums holds the MD5 of the download files by lowercase file name, copies are
+// verified against it and fail with ErrChecksumMismatch. nil skips the verification.
+// Downloads of samples only fail with ErrSampleOnly, downloads without the expected
+// video with ErrWrongContent.
+func (r *Renamer) Rename(media *models.Media, nzb *models.NZB, checksums map[string]string) ([]RenamedFile, error) {
+	source, err := findDownload(r.options.DownloadDir, nzb.Title)
+	if err != nil {
+		return nil, err
+	}
+	return r.rename(source, media, nzb, checksums, false)
+}
+
+// ForceRename places a rejected download into the library anyway
+// Samples are imported when there is nothing else, a single episode release without
+// the expected episode marker imports its largest video, checksums are not verified.
+func (r *Renamer) ForceRename(source string, media *models.Media, nzb *models.NZB) ([]RenamedFile, error) {
+	return r.rename(source, media, nzb, nil, true)
+}
+
+// rename places the video files of a download found at source into the library
+func (r *Renamer) rename(source string, media *models.Media, nzb *models.NZB, checksums map[string]string, force bool) ([]RenamedFile, error) {
+	videos, err := videoFiles(source, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(videos) == 0 {
+		samples, err := videoFiles(source, true)
+		switch {
+		case err != nil:
+			return nil, err
+		case len(samples) == 0:
+			return nil, fmt.Errorf("%w: no video file in %s", ErrWrongContent, source)
+		case !force:
+			return nil, fmt.Errorf("%w: %s holds %d sample video(s)", ErrSampleOnly, source, len(samples))
+		}
+		videos = samples
+	}
+
+	fields := utils.NamingFields{
+		Title:   media.Title,
+		Year:    media.Year,
+		IMDBId:  media.IMDBId,
+		Release: nzb.Title,
+	}
+
+	var renamed []RenamedFile
+	if media.MediaType == models.MediaTypeMovie {
+		// Videos are sorted by size, the largest is the feature
+		file, err := r.place(videos[0], r.options.MoviesDir, r.options.MovieTemplate, fields, checksums)
+		if err != nil {
+			return nil, err
+		}
+		return append(renamed, file), nil
+	}
+
+	seen := make(map[[2]int]bool)
+	for _, video := range videos {
+		season, episode, ok := utils.ParseFileEpisode(filepath.Base(video))
+		last := episode
+		if _, first, end, isRange := utils.ParseEpisodeRange(filepath.Base(video)); isRange && first == episode {
+			last = end
+		}
+		if !ok {
+			if nzb.IsSeasonPack || nzb.Season == nil || nzb.Episode == nil {
+				r.logger.WithField("file", video).Debug("Skipping file without episode number")
+				continue
+			}
+			season, episode, last = *nzb.Season, *nzb.Episode, *nzb.Episode
+			if nzb.LastEpisode != nil {
+				last = *nzb.LastEpisode
+			}
+		}
+		if !nzb.IsSeasonPack && nzb.Season != nil && nzb.Episode != nil && !nzb.Covers(season, episode) {
+			continue
+		}
+		if seen[[2]int{season, episode}] {
+			continue
+		}
+		seen[[2]int{season, episode}] = true
+
+		fields.Season, fields.Episode = season, episode
+		file, err := r.place(video, r.options.ShowsDir, r.options.EpisodeTemplate, fields, checksums)
+		if err != nil {
+			return renamed, err
+		}
+		file.Season, file.Episode = season, episode
+		renamed = append(renamed, file)
+
+		// The other episodes of a multi-episode file are recorded on the same path, sized once
+		for other := episode + 1; other <= last; other++ {
+			seen[[2]int{season, other}] = true
+			renamed = append(renamed, RenamedFile{Season: season, Episode: other, Path: file.Path})
+		}
+	}
+
+	if len(renamed) == 0 && force && !nzb.IsSeasonPack && nzb.Season != nil && nzb.Episode != nil {
+		fields.Season, fields.Episode = *nzb.Season, *nzb.Episode
+		file, err := r.place(videos[0], r.options.ShowsDir, r.options.EpisodeTemplate, fields, nil)
+		if err != nil {
+			return nil, err
+		}
+		file.Season, file.Episode = *nzb.Season, *nzb.Episode
+		renamed = append(renamed, file)
+	}
+	if len(renamed) == 0 {
+		return nil, fmt.Errorf("%w: no matching episode in %s", ErrWrongContent, source)
+	}
+	return renamed, nil
+}
+
+// Quarantine moves the download of a rejected release to the quarantine directory
+// Returns where the download is and its size. The download stays in place when no
+// quarantine directory is set, and is copied then deleted when the quarantine directory
+// is on another file system.
+func (r *Renamer) Quarantine(nzb *models.NZB) (string, int64, error) {
+	source, err := findDownload(r.options.DownloadDir, nzb.Title)
+	if err != nil {
+		return "", 0, err
+	}
+	size := pathSize(source)
+	if r.options.QuarantineDir == "" {
+		return source, size, nil
+	}
+
+	if err := os.MkdirAll(r.options.QuarantineDir, 0755); err != nil {
+		return "", 0, err
+	}
+	target := filepath.Join(r.options.QuarantineDir, fmt.Sprintf("%d-%s", nzb.ID, filepath.Base(source)))
+	if err := os.RemoveAll(target); err != nil {
+		return "", 0, err
+	}
+	err = os.Rename(source, target)
+	if errors.Is(err, syscall.EXDEV) {
+		if err = copyTree(source, target); err == nil {
+			err = os.RemoveAll(source)
+		} else {
+			os.RemoveAll(target)
+		}
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to move download to quarantine: %w", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"source": source,
+		"dest":   target,
+	}).Warn("Quarantined rejected download")
+	return target, size, nil
+}
+
+// copyTree copies a file or a directory with its files
+func copyTree(source, target string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		return copyFile(path, dest, "")
+	})
+}
+
+// Purge deletes a quarantined download
+// Downloads outside the quarantine directory (no directory set) are deleted from DownloadDir.
+func (r *Renamer) Purge(path string) error {
+	for _, root := range []string{r.options.QuarantineDir, r.options.DownloadDir} {
+		if root == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return os.RemoveAll(path)
+		}
+	}
+	return fmt.Errorf("refusing to delete %s outside the quarantine and download directories", path)
+}
+
+// place transfers a video file and its subtitles to the rendered library path
+func (r *Renamer) place(video string, root string, template string, fields utils.NamingFields, checksums map[string]string) (RenamedFile, error) {
+	name, err := utils.RenderNamingTemplate(template, fields)
+	if err != nil {
+		return RenamedFile{}, err
+	}
+	dest := filepath.Join(root, filepath.FromSlash(name)) + strings.ToLower(filepath.Ext(video))
+
+	info, err := os.Stat(video)
+	if err != nil {
+		return RenamedFile{}, err
+	}
+	if err := r.transfer(video, dest, fileChecksum(checksums, video)); err != nil {
+		return RenamedFile{}, fmt.Errorf("failed to %s %s: %w", r.options.Mode, video, err)
+	}
+
+	// Subtitles named after the video keep their language suffix, e.g. ".en.srt"
+	videoBase := strings.TrimSuffix(video, filepath.Ext(video))
+	destBase := strings.TrimSuffix(dest, filepath.Ext(dest))
+	siblings, _ := filepath.Glob(globEscape(videoBase) + ".*")
+	for _, sibling := range siblings {
+		if !subtitleExtensions[strings.ToLower(filepath.Ext(sibling))] {
+			continue
+		}
+		if err := r.transfer(sibling, destBase+strings.TrimPrefix(sibling, videoBase), fileChecksum(checksums, sibling)); err != nil {
+			r.logger.WithError(err).WithField("file", sibling).Warn("Failed to rename subtitle")
+		}
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"source": video,
+		"dest":   dest,
+		"mode":   r.options.Mode,
+	}).Info("Renamed download into library")
+
+	return RenamedFile{Path: dest, Size: info.Size()}, nil
+}
+
+// transfer moves, hardlinks or copies a file, replacing an existing destination
+// The file is written next to dest then renamed over it, so the existing destination
+// is kept when the transfer fails. Moves and hardlinks across file systems fall back to
+// copying. Copies are checked against checksum (MD5) when it is known.
+func (r *Renamer) transfer(source, dest, checksum string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	switch r.options.Mode {
+	case RenameModeHardlink:
+		tmp := dest + ".link"
+		if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		err := r.link(source, tmp)
+		if errors.Is(err, syscall.EXDEV) {
+			return r.copy(source, dest, checksum)
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, dest); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	case RenameModeCopy:
+		return r.copy(source, dest, checksum)
+	default:
+		err := os.Rename(source, dest)
+		if errors.Is(err, syscall.EXDEV) {
+			if err := r.copy(source, dest, checksum); err != nil {
+				return err
+			}
+			return os.Remove(source)
+		}
+		return err
+	}
+}
+
+// copy copies a file, deleting copies that don't match their checksum
+// The download itself is left for the import to quarantine.
+func (r *Renamer) copy(source, dest, checksum string) error {
+	err := copyFile(source, dest, checksum)
+	if errors.Is(err, ErrChecksumMismatch) {
+		for _, path := range []string{dest + ".partial", dest + ".partial" + partialSourceSuffix} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				r.logger.WithError(err).WithField("file", path).Warn("Failed to delete copy failing checksum verification")
+			}
+		}
+	}
+	return err
+}
+
+// partialSourceSuffix names the file recording which source a partial copy belongs to
+const partialSourceSuffix = ".source"
+
+// copyFile copies a file through a temporary file so a partial copy is never visible
+// Interrupted copies resume from the temporary file, seeking the source (range requests
+// on network mounts), including those left by a previous import of the same source file.
+// The copy is checked against checksum (MD5) when it is known, a mismatch returns
+// ErrChecksumMismatch.
+func copyFile(source, dest, checksum string) error {
+	tmp := dest + ".partial"
+
+	var err error
+	for attempt := 0; attempt < copyAttempts; attempt++ {
+		if err = resumeCopy(source, tmp); err == nil || os.IsNotExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if checksum != "" {
+		sum, err := fileMD5(tmp)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, checksum) {
+			return fmt.Errorf("%w: %s has MD5 %s, expected %s", ErrChecksumMismatch, filepath.Base(source), sum, checksum)
+		}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return err
+	}
+	os.Remove(tmp + partialSourceSuffix)
+	return nil
+}
+
+// resumeCopy appends the part of a file missing from its partial copy
+// A partial copy is only resumed when its sidecar records the same source path, size
+// and modification time, anything else left at tmp is overwritten.
+func resumeCopy(source, tmp string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	identity := fmt.Sprintf("%s\n%d\n%d\n", source, info.Size(), info.ModTime().UnixNano())
+	recorded, err := os.ReadFile(tmp + partialSourceSuffix)
+	resume := err == nil && string(recorded) == identity
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if !resume {
+		// Left over by another file or by an older version of this one, start over
+		flags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(tmp, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if !resume {
+		if err := os.WriteFile(tmp+partialSourceSuffix, []byte(identity), 0644); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err == nil && offset > info.Size() {
+		// Larger than the source: the partial copy is corrupt, start over
+		offset = 0
+		if err = out.Truncate(0); err == nil {
+			_, err = out.Seek(0, io.SeekStart)
+		}
+	}
+	if err == nil && offset > 0 {
+		_, err = in.Seek(offset, io.SeekStart)
+	}
+	if err == nil {
+		_, err = io.Copy(out, in)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// fileMD5 returns the MD5 of a file as lowercase hex
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fileChecksum returns the expected MD5 of a downloaded file, empty if unknown
+func fileChecksum(checksums map[string]string, path string) string {
+	return checksums[strings.ToLower(filepath.Base(path))]
+}
+
+// findDownload locates a completed release in the download directory
+// Releases are looked up by name, with or without a video extension, then by
+// normalized title to tolerate the renaming done by the download client. Titles
+// holding a path separator or ".." are only matched by normalized title, so an indexer
+// title can't point outside the download directory.
+func findDownload(downloadDir, title string) (string, error) {
+	if filepath.IsLocal(title) && !strings.ContainsAny(title, `/\`) {
+		for _, ext := range []string{"", ".mkv", ".mp4", ".avi"} {
+			candidate := filepath.Join(downloadDir, title+ext)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(downloadDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read download directory: %w", err)
+	}
+	wanted := utils.NormalizeTitle(title)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		if utils.NormalizeTitle(name) == wanted {
+			return filepath.Join(downloadDir, entry.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("release %s not found in %s", title, downloadDir)
+}
+
+// videoFiles lists the video files of a release, largest first
+// Samples are left out, or listed alone when samples is set.
+func videoFiles(source string, samples bool) ([]string, error) {
+	sizes := make(map[string]int64)
+	err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !utils.IsVideoFile(path) || strings.Contains(strings.ToLower(filepath.Base(path)), "sample") != samples {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sizes[path] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release files: %w", err)
+	}
+
+	videos := make([]string, 0, len(sizes))
+	for path := range sizes {
+		videos = append(videos, path)
+	}
+	sort.Slice(videos, func(i, j int) bool {
+		if sizes[videos[i]] != sizes[videos[j]] {
+			return sizes[videos[i]] > sizes[videos[j]]
+		}
+		return videos[i] < videos[j]
+	})
+	return videos, nil
+}
+
+// pathSize sums the size of a file or of the files of a folder
+func pathSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// globEscape escapes the glob metacharacters of a path
+func globEscape(path string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(path)
+}
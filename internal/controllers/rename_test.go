@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRenamerTransfer(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "download.mkv")
+	if err := os.WriteFile(source, []byte("new release"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	sum := md5.Sum([]byte("new release"))
+	checksum := hex.EncodeToString(sum[:])
+
+	// existing returns a library path already holding the release it replaces
+	existing := func(name string) string {
+		dest := filepath.Join(dir, "library", name)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			t.Fatalf("Failed to create library: %v", err)
+		}
+		if err := os.WriteFile(dest, []byte("old release"), 0644); err != nil {
+			t.Fatalf("Failed to write destination: %v", err)
+		}
+		return dest
+	}
+	content := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return string(data)
+	}
+
+	t.Run("same device link", func(t *testing.T) {
+		r := &Renamer{options: RenameOptions{Mode: RenameModeHardlink}, link: os.Link, logger: logrus.New()}
+		dest := existing("linked.mkv")
+		if err := r.transfer(source, dest, ""); err != nil {
+			t.Fatalf("transfer failed: %v", err)
+		}
+		sourceInfo, _ := os.Stat(source)
+		destInfo, _ := os.Stat(dest)
+		if !os.SameFile(sourceInfo, destInfo) {
+			t.Error("Expected the destination to be a hardlink of the source")
+		}
+	})
+
+	t.Run("cross device copy", func(t *testing.T) {
+		crossDevice := func(oldname, newname string) error {
+			return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EXDEV}
+		}
+		r := &Renamer{options: RenameOptions{Mode: RenameModeHardlink}, link: crossDevice, logger: logrus.New()}
+		dest := existing("copied.mkv")
+		if err := r.transfer(source, dest, checksum); err != nil {
+			t.Fatalf("transfer failed: %v", err)
+		}
+		if got := content(dest); got != "new release" {
+			t.Errorf("Expected the copied release, got %q", got)
+		}
+		if _, err := os.Stat(source); err != nil {
+			t.Errorf("Expected the source to be kept: %v", err)
+		}
+	})
+
+	t.Run("checksum mismatch keeps the old file", func(t *testing.T) {
+		r := &Renamer{options: RenameOptions{Mode: RenameModeCopy}, link: os.Link, logger: logrus.New()}
+		dest := existing("corrupt.mkv")
+		err := r.transfer(source, dest, "00000000000000000000000000000000")
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+		}
+		if got := content(dest); got != "old release" {
+			t.Errorf("Expected the old release to be kept, got %q", got)
+		}
+		if _, err := os.Stat(dest + ".partial"); !os.IsNotExist(err) {
+			t.Error("Expected the corrupt copy to be deleted")
+		}
+	})
+}
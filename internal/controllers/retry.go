@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy bounds and spaces the download retries with the next candidate
+type RetryPolicy struct {
+	MaxRetries int             // Failures of a release before its media item is given up
+	Backoff    []time.Duration // Wait before the nth retry of a media item, the last one repeats
+}
+
+// delay returns the wait before the nth retry of a media item, counting from 1
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	if len(p.Backoff) == 0 || attempt < 1 {
+		return 0
+	}
+	if attempt > len(p.Backoff) {
+		attempt = len(p.Backoff)
+	}
+	return p.Backoff[attempt-1]
+}
+
+// deferRetry schedules the retry of a failed release once the backoff of its media item
+// is over, the releases that failed so far counting as retries. Returns false if the
+// retry is due now.
+func (c *DownloadController) deferRetry(nzb *models.NZB) bool {
+	failed, err := c.db.GetNZBsByMediaIDAndStatus(nzb.MediaID, models.NZBStatusFailed)
+	if err != nil {
+		c.logger.WithError(err).WithField("media_id", nzb.MediaID).Warn("Failed to get failed NZBs, retrying now")
+		return false
+	}
+	delay := c.retry.delay(len(failed))
+	if delay <= 0 {
+		return false
+	}
+
+	retryAt := time.Now().Add(delay)
+	nzb.NextRetryAt = &retryAt
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		c.logger.WithError(err).WithField("nzb_id", nzb.ID).Error("Failed to schedule retry, retrying now")
+		nzb.NextRetryAt = nil
+		return false
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": nzb.MediaID,
+		"nzb_id":   nzb.ID,
+		"failures": len(failed),
+		"retry_at": retryAt.Local(),
+	}).Info("Retrying with next candidate after backoff")
+	return true
+}
+
+// RetryDue downloads the next candidate of the failed releases whose retry backoff is over
+// Returns the number of retries started.
+func (c *DownloadController) RetryDue() (int, error) {
+	failed, err := c.db.GetNZBsByStatus(models.NZBStatusFailed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get failed NZBs: %w", err)
+	}
+
+	now := time.Now()
+	retried := 0
+	for _, nzb := range failed {
+		if nzb.NextRetryAt == nil || now.Before(*nzb.NextRetryAt) {
+			continue
+		}
+
+		nzb.NextRetryAt = nil
+		if err := c.db.UpdateNZB(nzb); err != nil {
+			c.logger.WithError(err).WithField("nzb_id", nzb.ID).Error("Failed to update NZB")
+			continue
+		}
+
+		// Media removed, searched again or given a release by hand in the meantime
+		media, err := c.db.GetMediaByID(nzb.MediaID)
+		if err != nil || media.Status != models.StatusDownloading {
+			continue
+		}
+
+		retried++
+		err = c.RetryWithNextCandidate(media.ID)
+		if err == nil || errors.Is(err, ErrCandidatesExpired) {
+			continue
+		}
+		c.logger.WithError(err).WithField("media_id", media.ID).Error("Failed to retry with next candidate")
+		media.Status = models.StatusFailed
+		if err := c.db.UpdateMedia(media); err != nil {
+			c.logger.WithError(err).Error("Failed to update media status")
+		}
+		c.notifyMediaFailed(media, "no candidates left")
+	}
+	return retried, nil
+}
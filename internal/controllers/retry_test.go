@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: []time.Duration{time.Minute, 5 * time.Minute, time.Hour}}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, 0},
+		{1, time.Minute},
+		{2, 5 * time.Minute},
+		{3, time.Hour},
+		{4, time.Hour},
+		{10, time.Hour},
+	}
+	for _, tt := range tests {
+		if delay := policy.delay(tt.attempt); delay != tt.expected {
+			t.Errorf("delay(%d) = %s, expected %s", tt.attempt, delay, tt.expected)
+		}
+	}
+
+	if delay := (RetryPolicy{}).delay(3); delay != 0 {
+		t.Errorf("delay(3) without backoff = %s, expected 0", delay)
+	}
+}
+
+// createRetryMedia stores a media item in a status with a failed release and a candidate
+func createRetryMedia(t *testing.T, db *models.Database, imdbID string, status models.Status) (*models.NZB, *models.NZB) {
+	media := &models.Media{IMDBId: imdbID, MediaType: models.MediaTypeMovie, Title: "Movie " + imdbID, Status: status}
+	if err := db.CreateMedia(media); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+	failed := &models.NZB{MediaID: media.ID, GUID: imdbID + "-failed", Title: "Movie.2024.1080p.WEB-DL-FLUX", Status: models.NZBStatusFailed}
+	candidate := &models.NZB{MediaID: media.ID, GUID: imdbID + "-candidate", Title: "Movie.2024.1080p.WEB-DL-NTb", Status: models.NZBStatusCandidate}
+	for _, nzb := range []*models.NZB{failed, candidate} {
+		if err := db.CreateNZB(nzb); err != nil {
+			t.Fatalf("Failed to create NZB: %v", err)
+		}
+	}
+	return failed, candidate
+}
+
+func TestDeferRetry(t *testing.T) {
+	db := newTestDatabase(t)
+	failed, _ := createRetryMedia(t, db, "tt0000001", models.StatusDownloading)
+
+	ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, nil, nil, DiskGuard{}, 0, RetryPolicy{}, nil, nil, false, logrus.New())
+	if ctrl.deferRetry(failed) {
+		t.Error("Expected the retry to be due now without backoff")
+	}
+
+	// One failed release so far: the first backoff applies
+	ctrl.retry = RetryPolicy{MaxRetries: 3, Backoff: []time.Duration{time.Hour, 2 * time.Hour}}
+	before := time.Now()
+	if !ctrl.deferRetry(failed) {
+		t.Fatal("Expected the retry to be deferred")
+	}
+
+	stored, err := db.GetNZBByID(failed.ID)
+	if err != nil {
+		t.Fatalf("Failed to get NZB: %v", err)
+	}
+	if stored.NextRetryAt == nil || stored.NextRetryAt.Before(before.Add(time.Hour)) || stored.NextRetryAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("Expected the retry to be scheduled in an hour, got %v", stored.NextRetryAt)
+	}
+}
+
+func TestRetryDue(t *testing.T) {
+	db := newTestDatabase(t)
+	due, dueCandidate := createRetryMedia(t, db, "tt0000001", models.StatusDownloading)
+	pending, pendingCandidate := createRetryMedia(t, db, "tt0000002", models.StatusPending)
+	later, laterCandidate := createRetryMedia(t, db, "tt0000003", models.StatusDownloading)
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	due.NextRetryAt = &past
+	pending.NextRetryAt = &past
+	later.NextRetryAt = &future
+	for _, nzb := range []*models.NZB{due, pending, later} {
+		if err := db.UpdateNZB(nzb); err != nil {
+			t.Fatalf("Failed to update NZB: %v", err)
+		}
+	}
+
+	// Dry run: the next candidate is selected without reaching TorBox
+	ctrl := NewDownloadController(db, nil, nil, DownloadParamTemplates{}, nil, nil, nil, DiskGuard{}, 0, RetryPolicy{MaxRetries: 3}, nil, nil, true, logrus.New())
+	retried, err := ctrl.RetryDue()
+	if err != nil {
+		t.Fatalf("RetryDue failed: %v", err)
+	}
+	if retried != 1 {
+		t.Errorf("Expected 1 retry, got %d", retried)
+	}
+
+	tests := []struct {
+		name      string
+		failed    *models.NZB
+		candidate *models.NZB
+		scheduled bool
+		status    models.NZBStatus
+	}{
+		{"due", due, dueCandidate, false, models.NZBStatusSelected},
+		{"no longer downloading", pending, pendingCandidate, false, models.NZBStatusCandidate},
+		{"not due yet", later, laterCandidate, true, models.NZBStatusCandidate},
+	}
+	for _, tt := range tests {
+		failed, err := db.GetNZBByID(tt.failed.ID)
+		if err != nil {
+			t.Fatalf("Failed to get NZB: %v", err)
+		}
+		if (failed.NextRetryAt != nil) != tt.scheduled {
+			t.Errorf("%s: expected retry scheduled %v, got %v", tt.name, tt.scheduled, failed.NextRetryAt)
+		}
+		candidate, err := db.GetNZBByID(tt.candidate.ID)
+		if err != nil {
+			t.Fatalf("Failed to get NZB: %v", err)
+		}
+		if candidate.Status != tt.status {
+			t.Errorf("%s: expected candidate status %s, got %s", tt.name, tt.status, candidate.Status)
+		}
+	}
+}
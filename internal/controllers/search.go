@@ -3,10 +3,14 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/models"
 	"github.com/amaumene/gomenarr/internal/services/newznab"
 	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/tracing"
 	"github.com/amaumene/gomenarr/internal/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -17,22 +21,55 @@ type SearchController struct {
 	newznabClient *newznab.Client
 	traktClient   *trakt.Client
 	blacklist     *utils.Blacklist
+	profiles      *utils.QualityProfiles
+	backfillLimit int // Parallel season searches of backfilled shows
+	widenAfter    int // Empty searches after which show searches are widened, 0 never widens
 	logger        *logrus.Logger
+
+	seasonsMu sync.Mutex
+	seasons   map[string]seasonLengths // Season lengths of the anime shows by IMDB ID
 }
 
 // NewSearchController creates a new search controller
-func NewSearchController(db *models.Database, newznabClient *newznab.Client, traktClient *trakt.Client, blacklist *utils.Blacklist, logger *logrus.Logger) *SearchController {
+func NewSearchController(db *models.Database, newznabClient *newznab.Client, traktClient *trakt.Client, blacklist *utils.Blacklist, profiles *utils.QualityProfiles, backfillLimit int, widenAfter int, logger *logrus.Logger) *SearchController {
 	return &SearchController{
 		db:            db,
 		newznabClient: newznabClient,
 		traktClient:   traktClient,
 		blacklist:     blacklist,
+		profiles:      profiles,
+		backfillLimit: backfillLimit,
+		widenAfter:    widenAfter,
 		logger:        logger,
 	}
 }
 
-// SearchMedia searches for media based on strategy
+// SearchMedia searches for media based on strategy and selects the releases to download
 func (c *SearchController) SearchMedia(ctx context.Context, media *models.Media, strategy *DownloadStrategy) ([]*models.NZB, error) {
+	return c.search(ctx, media, strategy, true)
+}
+
+// FindCandidates searches for media based on strategy and returns the releases found,
+// best first and blacklisted ones last, without selecting any (interactive search)
+func (c *SearchController) FindCandidates(ctx context.Context, media *models.Media, strategy *DownloadStrategy) ([]*models.NZB, error) {
+	nzbs, err := c.search(ctx, media, strategy, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(nzbs, func(i, j int) bool {
+		return nzbs[i].Status != models.NZBStatusBlacklisted && nzbs[j].Status == models.NZBStatusBlacklisted
+	})
+	return nzbs, nil
+}
+
+// search runs the indexer searches of a strategy and saves the releases found as candidates
+// selectBest marks the best releases as selected for download.
+func (c *SearchController) search(ctx context.Context, media *models.Media, strategy *DownloadStrategy, selectBest bool) ([]*models.NZB, error) {
+	ctx, span := tracing.Start(ctx, "search")
+	span.SetAttribute("strategy", string(strategy.Type))
+	defer span.End()
+
 	c.logger.WithFields(logrus.Fields{
 		"media_id": media.ID,
 		"title":    media.Title,
@@ -41,39 +78,189 @@ func (c *SearchController) SearchMedia(ctx context.Context, media *models.Media,
 
 	var allResults []newznab.SearchResult
 	var err error
+	widened := c.widens(media, strategy)
+	attempt := &models.SearchAttempt{At: time.Now(), Widened: widened}
 
 	switch strategy.Type {
 	case StrategySingleMovie:
-		allResults, err = c.newznabClient.SearchByIMDBID(media.IMDBId, "movie")
+		allResults, err = c.newznabClient.SearchByIMDBID(ctx, media.IMDBId, "movie")
 	case StrategySingleEpisode:
 		if len(strategy.Episodes) == 0 {
 			return nil, fmt.Errorf("no episodes in strategy")
 		}
 		ep := strategy.Episodes[0]
-		allResults, err = c.newznabClient.SearchEpisode(media.IMDBId, ep.Season, ep.Episode)
+		allResults, err = c.searchEpisode(ctx, media, ep)
 	case StrategySeasonPack, StrategyNext3Episodes:
 		// For favorites: search both season pack and individual episodes
 		allResults, err = c.searchFavorites(ctx, media, strategy)
+	case StrategyBackfill:
+		allResults, err = c.searchBackfill(ctx, media, strategy)
+	case StrategyGapFill:
+		allResults = c.searchGaps(ctx, media, strategy)
 	}
 
 	if err != nil {
+		span.RecordError(err)
+		attempt.Error = err.Error()
+		c.recordSearch(media, attempt, nil)
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
+	if widened {
+		allResults = appendNewResults(allResults, c.searchWidened(ctx, media, strategy))
+	}
+
 	c.logger.WithField("count", len(allResults)).Debug("Search results received")
 
-	// Convert and process results
-	nzbs := c.processResults(ctx, media, allResults)
+	nzbs, rejections := c.saveCandidates(ctx, media, allResults, selectBest, widened)
+
+	attempt.Results = len(allResults)
+	attempt.Candidates = len(nzbs)
+	attempt.Rejected = len(rejections)
+	c.recordSearch(media, attempt, rejections)
+
+	c.logger.WithField("candidates", len(nzbs)).Info("Search completed")
+	return nzbs, nil
+}
+
+// saveCandidates ranks search results and saves them as candidates of a media item
+// selectBest marks the best releases as selected for download, widened drops the resolution
+// limits of the quality profile.
+func (c *SearchController) saveCandidates(ctx context.Context, media *models.Media, results []newznab.SearchResult, selectBest bool, widened bool) ([]*models.NZB, []*models.Rejection) {
+	_, scoreSpan := tracing.Start(ctx, "score")
+	nzbs, rejections := c.processResults(ctx, media, results, widened)
+	scoreSpan.SetAttribute("results", len(results))
+	scoreSpan.SetAttribute("candidates", len(nzbs))
+	scoreSpan.End()
+	if selectBest {
+		c.selectReleases(nzbs)
+	}
 
 	// Save all candidates to database
 	for _, nzb := range nzbs {
+		status := nzb.Status
 		if err := c.db.CreateNZB(nzb); err != nil {
 			c.logger.WithError(err).Error("Failed to save NZB to database")
+			continue
+		}
+		// Selections of an earlier search are kept by the merge, the new selection replaces them
+		if selectBest && status == models.NZBStatusCandidate && nzb.Status == models.NZBStatusSelected {
+			nzb.Status = models.NZBStatusCandidate
+			if err := c.db.UpdateNZB(nzb); err != nil {
+				c.logger.WithError(err).WithField("nzb_id", nzb.ID).Error("Failed to update NZB")
+			}
 		}
 	}
+	return nzbs, rejections
+}
 
-	c.logger.WithField("candidates", len(nzbs)).Info("Search completed")
-	return nzbs, nil
+// recordSearch adds a search attempt and the releases it dropped to the history of a media item
+func (c *SearchController) recordSearch(media *models.Media, attempt *models.SearchAttempt, rejections []*models.Rejection) {
+	media.RecordSearchAttempt(attempt)
+	if err := c.db.RecordSearch(media, attempt); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to record search attempt")
+		return
+	}
+	if err := c.db.RecordRejections(attempt, rejections); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to record rejected releases")
+	}
+}
+
+// FindUpgrade searches a completed movie again and returns the best release if it
+// scores higher than the current one under the media quality profile, nil otherwise
+func (c *SearchController) FindUpgrade(ctx context.Context, media *models.Media, current *models.NZB) (*models.NZB, error) {
+	strategy := &DownloadStrategy{Type: StrategySingleMovie, Episodes: []trakt.Episode{}}
+	nzbs, err := c.SearchMedia(ctx, media, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current release keeps its completed state, so anything selected is a different one
+	var best *models.NZB
+	for _, nzb := range nzbs {
+		if nzb.Status == models.NZBStatusSelected {
+			best = nzb
+			break
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	profile := c.profiles.For(media)
+	if !utils.IsUpgrade(profile, current, best) {
+		best.Status = models.NZBStatusCandidate
+		if err := c.db.UpdateNZB(best); err != nil {
+			c.logger.WithError(err).Error("Failed to update NZB")
+		}
+		return nil, nil
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"current":  current.Title,
+		"upgrade":  best.Title,
+		"profile":  profile.Name,
+	}).Info("Found quality upgrade")
+
+	return best, nil
+}
+
+// FindSeasonPack searches a pack of a season that finished airing and returns the best one
+// if it doesn't score lower than the episodes it replaces under the media quality profile, nil otherwise
+func (c *SearchController) FindSeasonPack(ctx context.Context, media *models.Media, season int, episodes []*models.NZB) (*models.NZB, error) {
+	strategy := &DownloadStrategy{Type: StrategySeasonPack, Episodes: []trakt.Episode{}, SeasonNumber: &season}
+	nzbs, err := c.search(ctx, media, strategy, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pack := c.pickSeasonPack(media, nzbs, season, episodes)
+	if pack == nil {
+		return nil, nil
+	}
+
+	pack.Status = models.NZBStatusSelected
+	if err := c.db.UpdateNZB(pack); err != nil {
+		return nil, fmt.Errorf("failed to update NZB: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"season":   season,
+		"pack":     pack.Title,
+		"episodes": len(episodes),
+	}).Info("Found season pack upgrade")
+
+	return pack, nil
+}
+
+// pickSeasonPack returns the best candidate pack of a season among ranked search results.
+// It returns nil when there is none or the pack scores lower than an episode it would replace;
+// releases already tried keep their state and are passed over.
+func (c *SearchController) pickSeasonPack(media *models.Media, nzbs []*models.NZB, season int, episodes []*models.NZB) *models.NZB {
+	var pack *models.NZB
+	for _, nzb := range nzbs {
+		if nzb.IsSeasonPack && nzb.Status == models.NZBStatusCandidate && nzb.Season != nil && *nzb.Season == season {
+			pack = nzb
+			break
+		}
+	}
+	if pack == nil {
+		return nil
+	}
+
+	for _, episode := range episodes {
+		if episode.QualityScore > pack.QualityScore {
+			c.logger.WithFields(logrus.Fields{
+				"media_id": media.ID,
+				"pack":     pack.Title,
+				"episode":  episode.Title,
+			}).Debug("Season pack scores lower than an episode it would replace")
+			return nil
+		}
+	}
+	return pack
 }
 
 // searchFavorites searches for both season packs and individual episodes for favorites
@@ -82,7 +269,7 @@ func (c *SearchController) searchFavorites(ctx context.Context, media *models.Me
 
 	// Search for season pack
 	if strategy.SeasonNumber != nil {
-		seasonResults, err := c.newznabClient.SearchSeason(media.IMDBId, *strategy.SeasonNumber)
+		seasonResults, err := c.newznabClient.SearchSeason(ctx, media.IMDBId, *strategy.SeasonNumber)
 		if err != nil {
 			c.logger.WithError(err).Warn("Season pack search failed")
 		} else {
@@ -90,11 +277,14 @@ func (c *SearchController) searchFavorites(ctx context.Context, media *models.Me
 		}
 	}
 
-	// Search for next 3 individual episodes
+	// Search for next 3 individual episodes, unless only season packs are allowed
 	episodeCount := len(strategy.Episodes)
 	if episodeCount > 3 {
 		episodeCount = 3
 	}
+	if media.Overrides.Pack == models.PackPolicyOnly {
+		episodeCount = 0
+	}
 
 	c.logger.WithFields(logrus.Fields{
 		"total_episodes":  len(strategy.Episodes),
@@ -109,7 +299,7 @@ func (c *SearchController) searchFavorites(ctx context.Context, media *models.Me
 			"episode": ep.Episode,
 		}).Info("Searching for episode")
 
-		epResults, err := c.newznabClient.SearchEpisode(media.IMDBId, ep.Season, ep.Episode)
+		epResults, err := c.searchEpisode(ctx, media, ep)
 		if err != nil {
 			c.logger.WithError(err).WithFields(logrus.Fields{
 				"season":  ep.Season,
@@ -123,13 +313,120 @@ func (c *SearchController) searchFavorites(ctx context.Context, media *models.Me
 	return allResults, nil
 }
 
-// processResults processes search results into NZB models
-func (c *SearchController) processResults(ctx context.Context, media *models.Media, results []newznab.SearchResult) []*models.NZB {
+// searchBackfill searches every season of a backfilled show, up to backfillLimit at a time
+// Each season is searched as a pack first, its episodes are searched individually
+// when no pack is found (or packs are disabled in the item notes).
+func (c *SearchController) searchBackfill(ctx context.Context, media *models.Media, strategy *DownloadStrategy) ([]newznab.SearchResult, error) {
+	episodesBySeason := make(map[int][]trakt.Episode)
+	for _, ep := range strategy.Episodes {
+		episodesBySeason[ep.Season] = append(episodesBySeason[ep.Season], ep)
+	}
+
+	limit := c.backfillLimit
+	if limit < 1 {
+		limit = 1
+	}
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		allResults []newznab.SearchResult
+	)
+	sem := make(chan struct{}, limit)
+
+	for _, season := range strategy.Seasons {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(season int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results := c.searchBackfillSeason(ctx, media, season, episodesBySeason[season])
+
+			mu.Lock()
+			allResults = append(allResults, results...)
+			mu.Unlock()
+		}(season)
+	}
+	wg.Wait()
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"seasons":  len(strategy.Seasons),
+		"results":  len(allResults),
+	}).Info("Backfill search completed")
+
+	return allResults, nil
+}
+
+// searchBackfillSeason searches one season of a backfilled show
+func (c *SearchController) searchBackfillSeason(ctx context.Context, media *models.Media, season int, episodes []trakt.Episode) []newznab.SearchResult {
+	if media.Overrides.Pack != models.PackPolicyNever {
+		packs, err := c.newznabClient.SearchSeason(ctx, media.IMDBId, season)
+		if err != nil {
+			c.logger.WithError(err).WithField("season", season).Warn("Season pack search failed")
+		}
+		if len(packs) > 0 || media.Overrides.Pack == models.PackPolicyOnly {
+			return packs
+		}
+	}
+
+	return c.searchEpisodes(ctx, media, episodes)
+}
+
+// searchGaps searches the missing episodes of a season, as a pack when the whole season is missing
+func (c *SearchController) searchGaps(ctx context.Context, media *models.Media, strategy *DownloadStrategy) []newznab.SearchResult {
+	if strategy.SeasonNumber != nil {
+		return c.searchBackfillSeason(ctx, media, *strategy.SeasonNumber, strategy.Episodes)
+	}
+	return c.searchEpisodes(ctx, media, strategy.Episodes)
+}
+
+// searchEpisodes searches episodes one by one, failed searches are skipped
+func (c *SearchController) searchEpisodes(ctx context.Context, media *models.Media, episodes []trakt.Episode) []newznab.SearchResult {
+	var results []newznab.SearchResult
+
+	for _, ep := range episodes {
+		epResults, err := c.searchEpisode(ctx, media, ep)
+		if err != nil {
+			c.logger.WithError(err).WithFields(logrus.Fields{
+				"season":  ep.Season,
+				"episode": ep.Episode,
+			}).Warn("Episode search failed")
+			continue
+		}
+		results = append(results, epResults...)
+	}
+
+	return results
+}
+
+// processResults processes search results into NZB models, ranked by quality
+// Also returns the releases dropped by the blacklist, the overrides and the quality profile.
+// Widened searches accept every resolution, the profile still ranks them.
+func (c *SearchController) processResults(ctx context.Context, media *models.Media, results []newznab.SearchResult, widened bool) ([]*models.NZB, []*models.Rejection) {
 	var nzbs []*models.NZB
+	var rejections []*models.Rejection
+
+	profile := c.profiles.For(media)
+	if widened {
+		profile.MinResolution, profile.MaxResolution = 0, 0
+	}
+	now := time.Now()
+	reject := func(result newznab.SearchResult, reason, detail string, qualityScore int) {
+		rejections = append(rejections, &models.Rejection{
+			Title:        result.Title,
+			Indexer:      result.Indexer,
+			Size:         result.Size,
+			Reason:       reason,
+			Detail:       detail,
+			QualityScore: qualityScore,
+		})
+	}
 
 	for _, result := range results {
 		// Check blacklist
-		if isBlacklisted, term := c.blacklist.IsBlacklisted(result.Title); isBlacklisted {
+		if isBlacklisted, term := c.blacklist.IsBlacklisted(result.Title, media); isBlacklisted {
 			c.logger.WithFields(logrus.Fields{
 				"title": result.Title,
 				"term":  term,
@@ -142,10 +439,45 @@ func (c *SearchController) processResults(ctx context.Context, media *models.Med
 				GUID:           result.GUID,
 				Size:           result.Size,
 				Quality:        utils.DetermineQuality(result.Title),
+				Indexer:        result.Indexer,
+				Protocol:       result.Protocol,
 				Status:         models.NZBStatusBlacklisted,
 				BlacklistMatch: term,
 			}
 			nzbs = append(nzbs, nzb)
+			reject(result, models.RejectBlacklist, term, 0)
+			continue
+		}
+
+		// Drop posts outside the retention and age limits of their indexer
+		if reason := c.newznabClient.CheckAge(result, now); reason != "" {
+			c.logger.WithFields(logrus.Fields{
+				"title":  result.Title,
+				"reason": reason,
+			}).Debug("Skipping NZB due to post age")
+			reject(result, models.RejectAge, reason, 0)
+			continue
+		}
+
+		// Apply per-item overrides from Trakt notes
+		if reason := c.checkOverrides(media, result); reason != "" {
+			c.logger.WithFields(logrus.Fields{
+				"title":  result.Title,
+				"reason": reason,
+			}).Debug("Skipping NZB due to item overrides")
+			reject(result, models.RejectOverride, reason, 0)
+			continue
+		}
+
+		// Weight the release against the quality profile, its size is checked once the episodes are known
+		qualityScore, reason := utils.ScoreRelease(profile, result.Title, 0)
+		if reason != "" {
+			c.logger.WithFields(logrus.Fields{
+				"title":   result.Title,
+				"profile": profile.Name,
+				"reason":  reason,
+			}).Debug("Skipping NZB rejected by quality profile")
+			reject(result, models.RejectProfile, reason, 0)
 			continue
 		}
 
@@ -163,6 +495,7 @@ func (c *SearchController) processResults(ctx context.Context, media *models.Med
 					"nzb_year":   year,
 					"media_year": media.Year,
 				}).Debug("Skipping movie NZB due to year mismatch")
+				reject(result, models.RejectYear, fmt.Sprintf("year %d, expected %d", year, media.Year), qualityScore)
 				continue
 			}
 		}
@@ -185,7 +518,18 @@ func (c *SearchController) processResults(ctx context.Context, media *models.Med
 			Status:       models.NZBStatusCandidate,
 			Season:       result.Season,
 			Episode:      result.Episode,
+			LastEpisode:  result.LastEpisode,
 			IsSeasonPack: result.IsSeasonPack,
+			Indexer:      result.Indexer,
+			Score:        result.Score,
+			QualityScore: qualityScore,
+			Protocol:     result.Protocol,
+			Alternates:   result.Alternates,
+			Widened:      widened,
+		}
+		if !result.PublishedAt.IsZero() {
+			published := result.PublishedAt
+			nzb.PublishedAt = &published
 		}
 
 		// If season pack, populate episode list from Trakt
@@ -198,61 +542,141 @@ func (c *SearchController) processResults(ctx context.Context, media *models.Med
 			}
 		}
 
+		// Score the release with its size, rejected by the size limits of the profile
+		nzb.QualityScore, reason = utils.ScoreRelease(profile, result.Title, itemSize(nzb))
+		if reason != "" {
+			c.logger.WithFields(logrus.Fields{
+				"title":   result.Title,
+				"profile": profile.Name,
+				"reason":  reason,
+			}).Debug("Skipping NZB rejected by size limits")
+			reject(result, models.RejectSize, reason, qualityScore)
+			continue
+		}
+
 		nzbs = append(nzbs, nzb)
 	}
 
 	// Rank by quality
-	ranked := utils.RankByQuality(nzbs)
+	return utils.RankByQuality(nzbs), rejections
+}
 
+// selectReleases marks the releases to download among ranked candidates
+func (c *SearchController) selectReleases(ranked []*models.NZB) {
 	// Selection logic:
-	// 1. Season pack → select best season pack
-	// 2. Individual episodes → select best for each episode
+	// 1. Season packs → select the best season pack of each season
+	// 2. Individual episodes → select best for each episode not covered by a pack,
+	//    multi-episode releases only when none of their episodes is selected yet
 	// 3. Movies → select best movie
 
-	hasSeasonPack := false
+	packSeasons := make(map[int]bool) // Seasons with a selected pack
+	packAnySeason := false            // A selected pack of unknown season covers every episode
+
+	for _, nzb := range ranked {
+		if !nzb.IsSeasonPack || nzb.Status != models.NZBStatusCandidate {
+			continue
+		}
+		if nzb.Season == nil {
+			if packAnySeason || len(packSeasons) > 0 {
+				continue
+			}
+			packAnySeason = true
+		} else {
+			if packAnySeason || packSeasons[*nzb.Season] {
+				continue
+			}
+			packSeasons[*nzb.Season] = true
+		}
+		nzb.Status = models.NZBStatusSelected
+		c.logger.WithField("title", nzb.Title).Info("Selected season pack")
+	}
+	hasSeasonPack := packAnySeason || len(packSeasons) > 0
+
 	hasEpisodes := false
+	selectedEpisodes := make(map[[2]int]bool) // Track which season/episode pairs we've selected
 
-	// Check if we have season packs
 	for _, nzb := range ranked {
-		if nzb.IsSeasonPack && nzb.Status == models.NZBStatusCandidate {
-			hasSeasonPack = true
+		if nzb.Status != models.NZBStatusCandidate || nzb.IsSeasonPack {
+			continue
+		}
+
+		// Handle episodes
+		if nzb.Episode != nil {
+			hasEpisodes = true
+			if hasSeasonPack && (packAnySeason || nzb.Season == nil || packSeasons[*nzb.Season]) {
+				continue // Covered by a season pack
+			}
+
+			season := 0
+			if nzb.Season != nil {
+				season = *nzb.Season
+			}
+			episodes := nzb.EpisodeNumbers()
+			overlaps := false
+			for _, episode := range episodes {
+				overlaps = overlaps || selectedEpisodes[[2]int{season, episode}]
+			}
+			if overlaps {
+				continue // Already selected this episode
+			}
 			nzb.Status = models.NZBStatusSelected
-			c.logger.WithField("title", nzb.Title).Info("Selected season pack")
+			for _, episode := range episodes {
+				selectedEpisodes[[2]int{season, episode}] = true
+			}
+			c.logger.WithFields(logrus.Fields{
+				"episode":  *nzb.Episode,
+				"episodes": len(episodes),
+				"title":    nzb.Title,
+			}).Info("Selected individual episode")
+		} else if !hasEpisodes && !hasSeasonPack {
+			// This is a movie (no episode number) - select the first (best) one
+			nzb.Status = models.NZBStatusSelected
+			c.logger.WithField("title", nzb.Title).Info("Selected movie")
 			break
 		}
 	}
+}
 
-	// If no season pack, select best quality for each episode OR best movie
-	if !hasSeasonPack {
-		selectedEpisodes := make(map[int]bool) // Track which episodes we've selected
+// checkOverrides validates a search result against the media overrides
+// Returns the reason the result was rejected, or an empty string if it is acceptable
+func (c *SearchController) checkOverrides(media *models.Media, result newznab.SearchResult) string {
+	overrides := media.Overrides
 
-		for _, nzb := range ranked {
-			if nzb.Status != models.NZBStatusCandidate {
-				continue
-			}
+	if !utils.MatchesQuality(result.Title, overrides.Quality) {
+		return fmt.Sprintf("quality %s required", overrides.Quality)
+	}
 
-			// Handle episodes
-			if nzb.Episode != nil {
-				hasEpisodes = true
-				if selectedEpisodes[*nzb.Episode] {
-					continue // Already selected this episode
-				}
-				nzb.Status = models.NZBStatusSelected
-				selectedEpisodes[*nzb.Episode] = true
-				c.logger.WithFields(logrus.Fields{
-					"episode": *nzb.Episode,
-					"title":   nzb.Title,
-				}).Info("Selected individual episode")
-			} else if !hasEpisodes {
-				// This is a movie (no episode number) - select the first (best) one
-				nzb.Status = models.NZBStatusSelected
-				c.logger.WithField("title", nzb.Title).Info("Selected movie")
-				break
+	if overrides.Language != "" && !utils.MatchesLanguage(result.Title, overrides.Language) {
+		return fmt.Sprintf("language %s required", overrides.Language)
+	}
+
+	if media.MediaType == models.MediaTypeTV {
+		switch overrides.Pack {
+		case models.PackPolicyNever:
+			if result.IsSeasonPack {
+				return "season packs disabled"
+			}
+		case models.PackPolicyOnly:
+			if !result.IsSeasonPack {
+				return "season packs only"
 			}
 		}
 	}
 
-	return ranked
+	return ""
+}
+
+// itemSize returns the size of a release per movie or episode, 0 if unknown
+// Season packs and multi-episode releases are split over their episodes.
+func itemSize(nzb *models.NZB) int64 {
+	episodes := len(nzb.EpisodeNumbers())
+	if !nzb.IsSeasonPack && episodes <= 1 {
+		return nzb.Size
+	}
+	if episodes == 0 {
+		return 0
+	}
+	return nzb.Size / int64(episodes)
 }
 
 // populateSeasonPackEpisodes gets episode list from Trakt for a season pack
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPickSeasonPack(t *testing.T) {
+	one, two := 1, 2
+	tried := &models.NZB{Title: "Tried.S01", IsSeasonPack: true, Season: &one, Status: models.NZBStatusFailed, QualityScore: 90}
+	otherSeason := &models.NZB{Title: "Other.S02", IsSeasonPack: true, Season: &two, Status: models.NZBStatusCandidate, QualityScore: 80}
+	episode := &models.NZB{Title: "Show.S01E01", Season: &one, Episode: &one, Status: models.NZBStatusCandidate, QualityScore: 70}
+	pack := &models.NZB{Title: "Show.S01", IsSeasonPack: true, Season: &one, Status: models.NZBStatusCandidate, QualityScore: 60}
+	nzbs := []*models.NZB{tried, otherSeason, episode, pack}
+
+	c := &SearchController{logger: logrus.New()}
+	media := &models.Media{Title: "Show"}
+
+	if got := c.pickSeasonPack(media, nzbs, 1, []*models.NZB{{Title: "Current.S01E01", QualityScore: 50}}); got != pack {
+		t.Errorf("Expected %s, got %v", pack.Title, got)
+	}
+	if got := c.pickSeasonPack(media, nzbs, 1, []*models.NZB{{Title: "Current.S01E01", QualityScore: 65}}); got != nil {
+		t.Errorf("Expected no pack scoring lower than an episode, got %s", got.Title)
+	}
+	if got := c.pickSeasonPack(media, nzbs, 3, nil); got != nil {
+		t.Errorf("Expected no pack for a season without one, got %s", got.Title)
+	}
+}
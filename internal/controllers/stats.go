@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+)
+
+// EpisodeRef identifies an episode of a show
+type EpisodeRef struct {
+	Season  int `json:"season"`
+	Episode int `json:"episode"`
+}
+
+// ShowStats compares the aired episodes of a show with the ones watched and on disk
+type ShowStats struct {
+	MediaID  uint64       `json:"media_id"`
+	Title    string       `json:"title"`
+	Aired    int          `json:"aired"`
+	Watched  int          `json:"watched"`
+	OnDisk   int          `json:"on_disk"`
+	Missing  []EpisodeRef `json:"missing"` // Aired episodes neither watched nor on disk
+	Complete bool         `json:"complete"`
+}
+
+// SeasonGaps lists the missing episodes of a season
+type SeasonGaps struct {
+	Season   int   `json:"season"`
+	Episodes []int `json:"episodes"`
+}
+
+// ShowGaps is the gap report of a show: its missing episodes by season, earliest first
+type ShowGaps struct {
+	MediaID   uint64       `json:"media_id"`
+	Title     string       `json:"title"`
+	Monitored bool         `json:"monitored"` // Whole show monitored (backfill strategy), gaps are searched by gap_fill
+	Missing   int          `json:"missing"`
+	Seasons   []SeasonGaps `json:"seasons"`
+}
+
+// ShowGaps computes the gap report of a show
+func (c *MediaController) ShowGaps(ctx context.Context, id uint64) (*ShowGaps, error) {
+	stats, err := c.ShowStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	media, err := c.db.GetMediaByID(id)
+	if err != nil {
+		return nil, ErrMediaNotFound
+	}
+
+	gaps := &ShowGaps{
+		MediaID:   stats.MediaID,
+		Title:     stats.Title,
+		Monitored: media.ShowStrategy() == models.EpisodeStrategyBackfill,
+		Missing:   len(stats.Missing),
+		Seasons:   []SeasonGaps{},
+	}
+	// Trakt returns the episodes in order
+	for _, ep := range stats.Missing {
+		if n := len(gaps.Seasons); n == 0 || gaps.Seasons[n-1].Season != ep.Season {
+			gaps.Seasons = append(gaps.Seasons, SeasonGaps{Season: ep.Season})
+		}
+		last := &gaps.Seasons[len(gaps.Seasons)-1]
+		last.Episodes = append(last.Episodes, ep.Episode)
+	}
+
+	return gaps, nil
+}
+
+// GapReport computes the gap report of every show with missing episodes
+// Shows whose Trakt progress can't be read are skipped.
+func (c *MediaController) GapReport(ctx context.Context) ([]*ShowGaps, error) {
+	medias, err := c.db.GetAllMedias()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get medias: %w", err)
+	}
+
+	report := []*ShowGaps{}
+	for _, media := range medias {
+		if media.MediaType != models.MediaTypeTV || media.ParentID != 0 || media.SeasonNumber != nil {
+			continue
+		}
+
+		gaps, err := c.ShowGaps(ctx, media.ID)
+		if errors.Is(err, trakt.ErrUnavailable) {
+			return nil, err
+		}
+		if err != nil {
+			c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to compute show gaps")
+			continue
+		}
+		if gaps.Missing > 0 {
+			report = append(report, gaps)
+		}
+	}
+
+	return report, nil
+}
+
+// ShowStats computes the completeness of a show
+// Episodes count as on disk when found by the library scan or downloaded (single
+// episodes and season packs) and not cleaned up yet.
+func (c *MediaController) ShowStats(ctx context.Context, id uint64) (*ShowStats, error) {
+	media, err := c.db.GetMediaByID(id)
+	if err != nil {
+		return nil, ErrMediaNotFound
+	}
+	if media.MediaType != models.MediaTypeTV || media.ParentID != 0 {
+		return nil, fmt.Errorf("%w: not a show", ErrInvalidMedia)
+	}
+
+	progress, err := c.traktClient.GetShowProgress(ctx, media.IMDBId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show progress: %w", err)
+	}
+
+	onDisk, err := episodesOnDisk(c.db, media)
+	if err != nil {
+		return nil, err
+	}
+
+	unwatched := make(map[trakt.Episode]bool)
+	for _, ep := range progress.UnwatchedEpisodes {
+		unwatched[ep] = true
+	}
+
+	stats := &ShowStats{
+		MediaID: media.ID,
+		Title:   media.Title,
+		Aired:   len(progress.AiredEpisodes),
+		Missing: []EpisodeRef{},
+	}
+	for _, ep := range progress.AiredEpisodes {
+		watched := !unwatched[ep]
+		if watched {
+			stats.Watched++
+		}
+		if onDisk[ep] {
+			stats.OnDisk++
+		}
+		if !watched && !onDisk[ep] {
+			stats.Missing = append(stats.Missing, EpisodeRef{Season: ep.Season, Episode: ep.Episode})
+		}
+	}
+	stats.Complete = len(stats.Missing) == 0
+
+	return stats, nil
+}
+
+// episodesOnDisk collects the episodes of a show found in the library or downloaded
+func episodesOnDisk(db *models.Database, media *models.Media) (map[trakt.Episode]bool, error) {
+	onDisk := make(map[trakt.Episode]bool)
+
+	files, err := db.GetLibraryFiles(media.IMDBId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library files: %w", err)
+	}
+	for _, file := range files {
+		onDisk[trakt.Episode{Season: file.Season, Episode: file.Episode}] = true
+	}
+
+	medias := []*models.Media{media}
+	children, err := db.GetChildMedias(media.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get episode-level media: %w", err)
+	}
+	medias = append(medias, children...)
+
+	for _, m := range medias {
+		nzbs, err := db.GetNZBsByMediaIDAndStatus(m.ID, models.NZBStatusCompleted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get NZBs: %w", err)
+		}
+		for _, nzb := range nzbs {
+			if nzb.Season == nil {
+				continue
+			}
+			for _, episode := range nzb.EpisodeNumbers() {
+				onDisk[trakt.Episode{Season: *nzb.Season, Episode: episode}] = true
+			}
+		}
+	}
+
+	return onDisk, nil
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/models"
 	"github.com/amaumene/gomenarr/internal/services/trakt"
@@ -17,28 +19,40 @@ const (
 	StrategySeasonPack    StrategyType = "season_pack"
 	StrategyNext3Episodes StrategyType = "next_3_episodes"
 	StrategySingleMovie   StrategyType = "single_movie"
+	StrategyBackfill      StrategyType = "backfill"
+	StrategyGapFill       StrategyType = "gap_fill"
 )
 
+// ErrNotAired is returned when the episodes to search aired less than the air offset ago
+var ErrNotAired = errors.New("episodes not aired long enough")
+
 // DownloadStrategy represents a download strategy decision
 type DownloadStrategy struct {
 	Type         StrategyType
 	Episodes     []trakt.Episode
-	SeasonNumber *int
+	SeasonNumber *int  // Season of a season pack (gap fill: set when the whole season is missing)
+	Seasons      []int // Seasons with unwatched episodes (backfill)
 }
 
 // StrategyController determines download strategies
 type StrategyController struct {
-	db          *models.Database
-	traktClient *trakt.Client
-	logger      *logrus.Logger
+	db                *models.Database
+	traktClient       *trakt.Client
+	redownloadWatched bool          // Allow episodes from the watched ledger to be downloaded again
+	airOffset         time.Duration // Wait after an episode aired before searching it
+	collectionSources CollectionSources
+	logger            *logrus.Logger
 }
 
 // NewStrategyController creates a new strategy controller
-func NewStrategyController(db *models.Database, traktClient *trakt.Client, logger *logrus.Logger) *StrategyController {
+func NewStrategyController(db *models.Database, traktClient *trakt.Client, redownloadWatched bool, airOffset time.Duration, collectionSources CollectionSources, logger *logrus.Logger) *StrategyController {
 	return &StrategyController{
-		db:          db,
-		traktClient: traktClient,
-		logger:      logger,
+		db:                db,
+		traktClient:       traktClient,
+		redownloadWatched: redownloadWatched,
+		airOffset:         airOffset,
+		collectionSources: collectionSources,
+		logger:            logger,
 	}
 }
 
@@ -57,8 +71,31 @@ func (c *StrategyController) DetermineStrategy(ctx context.Context, media *model
 		}, nil
 	}
 
-	// TV Shows: Strategy depends on source
-	if media.Source == models.SourceWatchlist {
+	// Episode-level media (e.g. replacement for a damaged season pack file): that episode only
+	if media.SeasonNumber != nil && media.EpisodeNumber != nil {
+		c.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+			"season":   *media.SeasonNumber,
+			"episode":  *media.EpisodeNumber,
+		}).Debug("Strategy: Single episode for episode-level media")
+
+		return &DownloadStrategy{
+			Type:     StrategySingleEpisode,
+			Episodes: []trakt.Episode{{Season: *media.SeasonNumber, Episode: *media.EpisodeNumber}},
+		}, nil
+	}
+
+	// TV Shows: Strategy depends on source, custom lists and notes set their own
+	switch strategy := media.ShowStrategy(); {
+	case strategy == models.EpisodeStrategyBackfill:
+		// Backfill: every unwatched season
+		return c.backfillStrategy(ctx, media)
+	case strategy == models.EpisodeStrategyNext || (strategy == "" && media.Source == models.SourceWatchlist):
+		// Season packs only in the item notes: the season pack of the next episode
+		if media.Overrides.Pack == models.PackPolicyOnly {
+			return c.favoritesStrategy(ctx, media)
+		}
 		// Watchlist: Next single episode
 		return c.nextEpisodeStrategy(ctx, media)
 	}
@@ -79,16 +116,30 @@ func (c *StrategyController) nextEpisodeStrategy(ctx context.Context, media *mod
 		return nil, fmt.Errorf("no unwatched episodes found")
 	}
 
+	// Already watched and cleaned up: Trakt progress was reset, move on to the
+	// first unwatched episode missing from the ledger
+	next := *progress.NextEpisode
+	if remaining := c.filterAvailable(media, []trakt.Episode{next}); len(remaining) == 0 {
+		remaining = c.filterAvailable(media, progress.UnwatchedEpisodes)
+		if len(remaining) == 0 {
+			return nil, fmt.Errorf("no unwatched episodes found")
+		}
+		next = remaining[0]
+	}
+	if len(c.filterAired(ctx, media, progress, []trakt.Episode{next})) == 0 {
+		return nil, ErrNotAired
+	}
+
 	c.logger.WithFields(logrus.Fields{
 		"media_id": media.ID,
 		"title":    media.Title,
-		"season":   progress.NextEpisode.Season,
-		"episode":  progress.NextEpisode.Episode,
+		"season":   next.Season,
+		"episode":  next.Episode,
 	}).Debug("Strategy: Single episode from watchlist")
 
 	return &DownloadStrategy{
 		Type:     StrategySingleEpisode,
-		Episodes: []trakt.Episode{*progress.NextEpisode},
+		Episodes: []trakt.Episode{next},
 	}, nil
 }
 
@@ -99,9 +150,14 @@ func (c *StrategyController) favoritesStrategy(ctx context.Context, media *model
 		return nil, fmt.Errorf("failed to get show progress: %w", err)
 	}
 
+	progress.UnwatchedEpisodes = c.filterAvailable(media, progress.UnwatchedEpisodes)
 	if len(progress.UnwatchedEpisodes) == 0 {
 		return nil, fmt.Errorf("no unwatched episodes found")
 	}
+	progress.UnwatchedEpisodes = c.filterAired(ctx, media, progress, progress.UnwatchedEpisodes)
+	if len(progress.UnwatchedEpisodes) == 0 {
+		return nil, ErrNotAired
+	}
 
 	// DEBUG: Log ALL unwatched episodes from Trakt
 	c.logger.WithFields(logrus.Fields{
@@ -137,6 +193,19 @@ func (c *StrategyController) favoritesStrategy(ctx context.Context, media *model
 		"total_unwatched":        len(progress.UnwatchedEpisodes),
 	}).Debug("Strategy: Season pack for favorites")
 
+	// Season packs disabled in the item notes: next 3 episodes only
+	if media.Overrides.Pack == models.PackPolicyNever {
+		c.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+		}).Debug("Strategy: Next 3 episodes (season packs disabled by notes)")
+
+		return &DownloadStrategy{
+			Type:     StrategyNext3Episodes,
+			Episodes: unwatchedInSeason,
+		}, nil
+	}
+
 	// Return strategy to search for season pack
 	// Search controller will also search for next 3 episodes and compare
 	return &DownloadStrategy{
@@ -145,3 +214,215 @@ func (c *StrategyController) favoritesStrategy(ctx context.Context, media *model
 		SeasonNumber: &season,
 	}, nil
 }
+
+// backfillStrategy determines strategy for a whole back catalog: all unwatched episodes
+// The search controller looks for a season pack of each season, and for individual
+// episodes of the seasons without one.
+func (c *StrategyController) backfillStrategy(ctx context.Context, media *models.Media) (*DownloadStrategy, error) {
+	progress, err := c.traktClient.GetShowProgress(ctx, media.IMDBId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show progress: %w", err)
+	}
+
+	episodes := c.filterAvailable(media, progress.UnwatchedEpisodes)
+	if len(episodes) == 0 {
+		return nil, fmt.Errorf("no unwatched episodes found")
+	}
+	episodes = c.filterAired(ctx, media, progress, episodes)
+	if len(episodes) == 0 {
+		return nil, ErrNotAired
+	}
+
+	var seasons []int
+	for _, ep := range episodes {
+		if len(seasons) == 0 || seasons[len(seasons)-1] != ep.Season {
+			seasons = append(seasons, ep.Season)
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"seasons":  len(seasons),
+		"episodes": len(episodes),
+	}).Debug("Strategy: Backfill all unwatched episodes")
+
+	return &DownloadStrategy{
+		Type:     StrategyBackfill,
+		Episodes: episodes,
+		Seasons:  seasons,
+	}, nil
+}
+
+// GapStrategy determines the missing episodes of a show to search: aired, unwatched and
+// neither downloaded nor in the library. Only the earliest season with gaps is returned so
+// gap searches stay small. Returns nil when the show has no gaps.
+func (c *StrategyController) GapStrategy(ctx context.Context, media *models.Media) (*DownloadStrategy, error) {
+	progress, err := c.traktClient.GetShowProgress(ctx, media.IMDBId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get show progress: %w", err)
+	}
+
+	onDisk, err := episodesOnDisk(c.db, media)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []trakt.Episode
+	available := c.filterAired(ctx, media, progress, c.filterAvailable(media, progress.UnwatchedEpisodes))
+	for _, ep := range available {
+		if ep.Season == 0 || onDisk[ep] {
+			continue
+		}
+		if len(missing) > 0 && missing[0].Season != ep.Season {
+			break
+		}
+		missing = append(missing, ep)
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	season := missing[0].Season
+	strategy := &DownloadStrategy{
+		Type:     StrategyGapFill,
+		Episodes: missing,
+		Seasons:  []int{season},
+	}
+
+	// A season pack only makes sense when nothing of the season is there
+	aired := 0
+	for _, ep := range progress.AiredEpisodes {
+		if ep.Season == season {
+			aired++
+		}
+	}
+	if len(missing) == aired {
+		strategy.SeasonNumber = &season
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"season":   season,
+		"episodes": len(missing),
+	}).Debug("Strategy: Fill missing episodes")
+
+	return strategy, nil
+}
+
+// filterAvailable drops the episodes already watched and cleaned up, found in the library
+// or owned through the Trakt collection
+func (c *StrategyController) filterAvailable(media *models.Media, episodes []trakt.Episode) []trakt.Episode {
+	return c.filterCollected(media, c.filterOnDisk(media, c.filterWatched(media, episodes)))
+}
+
+// filterAired drops the episodes that aired less than the air offset ago, indexers rarely
+// have them yet. The air time of the first one dropped is recorded on the media.
+// Only the season of the last aired episode can hold such recent episodes.
+func (c *StrategyController) filterAired(ctx context.Context, media *models.Media, progress *trakt.ShowProgress, episodes []trakt.Episode) []trakt.Episode {
+	media.AirsAt = nil
+	if c.airOffset <= 0 || progress.LastEpisode == nil || progress.LastAiredAt == nil {
+		return episodes
+	}
+	cutoff := time.Now().Add(-c.airOffset)
+	if progress.LastAiredAt.Before(cutoff) {
+		return episodes
+	}
+
+	airTimes, err := c.traktClient.GetEpisodeAirTimes(ctx, media.IMDBId, progress.LastEpisode.Season)
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to get episode air times, holding back the last aired episode only")
+		airTimes = map[trakt.Episode]time.Time{*progress.LastEpisode: *progress.LastAiredAt}
+	}
+
+	var remaining []trakt.Episode
+	for _, ep := range episodes {
+		if airedAt, ok := airTimes[ep]; ok && airedAt.After(cutoff) {
+			if media.AirsAt == nil || airedAt.Before(*media.AirsAt) {
+				media.AirsAt = &airedAt
+			}
+			continue
+		}
+		remaining = append(remaining, ep)
+	}
+
+	if media.AirsAt != nil {
+		c.logger.WithFields(logrus.Fields{
+			"media_id":  media.ID,
+			"title":     media.Title,
+			"held_back": len(episodes) - len(remaining),
+			"aired_at":  media.AirsAt.Local(),
+			"search_at": media.AirsAt.Add(c.airOffset).Local(),
+		}).Debug("Holding back episodes that just aired")
+	}
+
+	return remaining
+}
+
+// SearchableAt returns when the episode a show is held back for can be searched, zero if none is
+func (c *StrategyController) SearchableAt(media *models.Media) time.Time {
+	if media.AirsAt == nil {
+		return time.Time{}
+	}
+	return media.AirsAt.Add(c.airOffset)
+}
+
+// filterOnDisk drops the episodes found in the media library by the library scan
+func (c *StrategyController) filterOnDisk(media *models.Media, episodes []trakt.Episode) []trakt.Episode {
+	var remaining []trakt.Episode
+	skipped := 0
+	for _, ep := range episodes {
+		onDisk, err := c.db.IsOnDisk(media.IMDBId, ep.Season, ep.Episode)
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to check library files")
+		}
+		if onDisk {
+			skipped++
+			continue
+		}
+		remaining = append(remaining, ep)
+	}
+
+	if skipped > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+			"skipped":  skipped,
+		}).Debug("Skipping episodes already in the library")
+	}
+
+	return remaining
+}
+
+// filterWatched drops the episodes found in the watched ledger unless re-downloads are allowed
+// Trakt reporting them as unwatched means the show progress was reset.
+func (c *StrategyController) filterWatched(media *models.Media, episodes []trakt.Episode) []trakt.Episode {
+	if c.redownloadWatched {
+		return episodes
+	}
+
+	var remaining []trakt.Episode
+	skipped := 0
+	for _, ep := range episodes {
+		watched, err := c.db.IsWatched(media.IMDBId, ep.Season, ep.Episode)
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to check watched ledger")
+		}
+		if watched {
+			skipped++
+			continue
+		}
+		remaining = append(remaining, ep)
+	}
+
+	if skipped > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+			"skipped":  skipped,
+		}).Warn("Skipping episodes already watched and cleaned up (Trakt progress reset?)")
+	}
+
+	return remaining
+}
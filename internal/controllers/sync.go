@@ -3,35 +3,68 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
 	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/utils"
 	"github.com/sirupsen/logrus"
 )
 
 // SyncController handles synchronization with Trakt
 type SyncController struct {
-	db          *models.Database
-	traktClient *trakt.Client
-	cleanupCtrl *CleanupController
-	logger      *logrus.Logger
+	db                *models.Database
+	traktClient       *trakt.Client
+	cleanupCtrl       *CleanupController
+	redownloadWatched bool // Allow movies from the watched ledger to be added again
+	lists             []TraktList
+	metadataLanguage  string // Language of the search titles, empty to search with the synced titles
+	collectionSources CollectionSources
+	notifier          *notify.Dispatcher
+	logger            *logrus.Logger
 }
 
 // NewSyncController creates a new sync controller
-func NewSyncController(db *models.Database, traktClient *trakt.Client, cleanupCtrl *CleanupController, logger *logrus.Logger) *SyncController {
+func NewSyncController(db *models.Database, traktClient *trakt.Client, cleanupCtrl *CleanupController, redownloadWatched bool, lists []TraktList, metadataLanguage string, collectionSources CollectionSources, notifier *notify.Dispatcher, logger *logrus.Logger) *SyncController {
 	return &SyncController{
-		db:          db,
-		traktClient: traktClient,
-		cleanupCtrl: cleanupCtrl,
-		logger:      logger,
+		db:                db,
+		traktClient:       traktClient,
+		cleanupCtrl:       cleanupCtrl,
+		redownloadWatched: redownloadWatched,
+		lists:             lists,
+		metadataLanguage:  metadataLanguage,
+		collectionSources: collectionSources,
+		notifier:          notifier,
+		logger:            logger,
 	}
 }
 
+// TraktList is a custom Trakt list synced like the watchlist and favorites
+type TraktList struct {
+	Name     string
+	Path     string                 // API path, e.g. "users/justin/lists/imdb-top-250"
+	Types    []string               // "movies" and/or "shows"
+	Strategy models.EpisodeStrategy // Episodes downloaded for shows of the list
+}
+
+// SyncStats counts what a Trakt sync processed
+type SyncStats struct {
+	Movies   int // Movies found in Trakt lists
+	Shows    int // Shows found in Trakt lists
+	Added    int // New media created
+	Episodes int // Season pack episodes marked as watched
+	Removed  int // Media cleaned up after being removed from Trakt
+	Failures int // Sync steps that failed
+}
+
 // SyncAll synchronizes all data from Trakt
-func (c *SyncController) SyncAll(ctx context.Context) error {
+func (c *SyncController) SyncAll(ctx context.Context) (*SyncStats, error) {
 	c.logger.Info("Starting Trakt sync")
 
+	stats := &SyncStats{}
+
 	// Step 1: Mark ALL existing medias as NOT in Trakt
 	if err := c.db.MarkAllMediasNotInTrakt(); err != nil {
 		c.logger.WithError(err).Error("Failed to mark medias as not in Trakt, skipping cleanup")
@@ -40,56 +73,97 @@ func (c *SyncController) SyncAll(ctx context.Context) error {
 
 	syncFailed := false
 
-	// Step 2: Sync favorites (TV shows)
-	if err := c.syncFavorites(ctx, "shows"); err != nil {
+	// Step 1b: Sync the collection, its items count as owned for the configured sources
+	if len(c.collectionSources) > 0 {
+		if err := c.syncCollection(ctx); err != nil {
+			c.logger.WithError(err).Error("Failed to sync Trakt collection")
+			stats.Failures++
+		}
+	}
+
+	// Step 2: Sync custom lists (favorites and watchlist run after and take precedence)
+	for _, list := range c.lists {
+		for _, mediaType := range list.Types {
+			if err := c.syncList(ctx, list, mediaType, stats); err != nil {
+				c.logger.WithError(err).WithField("list", list.Name).Error("Failed to sync Trakt list")
+				syncFailed = true
+				stats.Failures++
+			}
+		}
+	}
+
+	// Step 3: Sync favorites (TV shows)
+	if err := c.syncFavorites(ctx, "shows", stats); err != nil {
 		c.logger.WithError(err).Error("Failed to sync TV favorites")
 		syncFailed = true
+		stats.Failures++
 	}
 
-	// Step 3: Sync favorites (movies)
-	if err := c.syncFavorites(ctx, "movies"); err != nil {
+	// Step 4: Sync favorites (movies)
+	if err := c.syncFavorites(ctx, "movies", stats); err != nil {
 		c.logger.WithError(err).Error("Failed to sync movie favorites")
 		syncFailed = true
+		stats.Failures++
 	}
 
-	// Step 4: Sync watchlist (TV shows)
-	if err := c.syncWatchlist(ctx, "shows"); err != nil {
+	// Step 5: Sync watchlist (TV shows)
+	if err := c.syncWatchlist(ctx, "shows", stats); err != nil {
 		c.logger.WithError(err).Error("Failed to sync TV watchlist")
 		syncFailed = true
+		stats.Failures++
 	}
 
-	// Step 5: Sync watchlist (movies)
-	if err := c.syncWatchlist(ctx, "movies"); err != nil {
+	// Step 6: Sync watchlist (movies)
+	if err := c.syncWatchlist(ctx, "movies", stats); err != nil {
 		c.logger.WithError(err).Error("Failed to sync movie watchlist")
 		syncFailed = true
+		stats.Failures++
 	}
 
-	// Step 6: Sync watched status
+	// Step 6b: Look up the titles of new media in the metadata language
+	c.resolveSearchTitles(ctx)
+
+	// Step 7: Sync watched status
 	if err := c.syncWatched(ctx); err != nil {
 		c.logger.WithError(err).Error("Failed to sync watched status")
 		syncFailed = true
+		stats.Failures++
 	}
 
-	// Step 7: Update episode watched status in season packs
-	if err := c.updateEpisodeWatchedStatus(ctx); err != nil {
+	// Step 8: Update episode watched status in season packs
+	episodes, err := c.updateEpisodeWatchedStatus(ctx)
+	if err != nil {
 		c.logger.WithError(err).Error("Failed to update episode watched status")
+		stats.Failures++
 	}
+	stats.Episodes = episodes
 
-	// Step 8: IMMEDIATELY trigger cleanup of removed items (only if sync succeeded)
+	// Step 9: IMMEDIATELY trigger cleanup of removed items (only if sync succeeded)
 	if !syncFailed {
-		if err := c.cleanupCtrl.CleanupRemovedFromTrakt(ctx); err != nil {
+		removed, err := c.cleanupCtrl.CleanupRemovedFromTrakt(ctx)
+		if err != nil {
 			c.logger.WithError(err).Error("Failed to cleanup removed items")
+			stats.Failures++
 		}
+		stats.Removed = removed
 	} else {
 		c.logger.Warn("Skipping cleanup due to sync failures")
 	}
 
 	c.logger.Info("Trakt sync completed")
-	return nil
+	return stats, nil
+}
+
+// syncOrigin describes the Trakt list synced items come from
+type syncOrigin struct {
+	source   models.Source
+	list     string                 // Custom list name, empty for favorites and watchlist
+	strategy models.EpisodeStrategy // Episode strategy of shows from custom lists
+	label    string                 // Used in logs and notifications, e.g. "favorites"
 }
 
 // syncFavorites syncs favorites from Trakt
-func (c *SyncController) syncFavorites(ctx context.Context, mediaType string) error {
+func (c *SyncController) syncFavorites(ctx context.Context, mediaType string, stats *SyncStats) error {
 	c.logger.WithField("type", mediaType).Info("Syncing favorites")
 
 	items, err := c.traktClient.GetFavorites(ctx, mediaType)
@@ -99,6 +173,54 @@ func (c *SyncController) syncFavorites(ctx context.Context, mediaType string) er
 
 	c.logger.WithField("count", len(items)).Debug("Retrieved favorites")
 
+	c.syncItems(items, mediaType, syncOrigin{source: models.SourceFavorites, label: "favorites"}, stats)
+	return nil
+}
+
+// syncWatchlist syncs watchlist from Trakt
+func (c *SyncController) syncWatchlist(ctx context.Context, mediaType string, stats *SyncStats) error {
+	c.logger.WithField("type", mediaType).Info("Syncing watchlist")
+
+	items, err := c.traktClient.GetWatchlist(ctx, mediaType)
+	if err != nil {
+		return fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	c.logger.WithField("count", len(items)).Debug("Retrieved watchlist")
+
+	c.syncItems(items, mediaType, syncOrigin{source: models.SourceWatchlist, label: "watchlist"}, stats)
+	return nil
+}
+
+// syncList syncs a custom Trakt list
+func (c *SyncController) syncList(ctx context.Context, list TraktList, mediaType string, stats *SyncStats) error {
+	c.logger.WithFields(logrus.Fields{
+		"list": list.Name,
+		"type": mediaType,
+	}).Info("Syncing Trakt list")
+
+	items, err := c.traktClient.GetListItems(ctx, list.Path, mediaType)
+	if err != nil {
+		return fmt.Errorf("failed to get list %s: %w", list.Name, err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"list":  list.Name,
+		"count": len(items),
+	}).Debug("Retrieved Trakt list")
+
+	origin := syncOrigin{
+		source:   models.SourceList,
+		list:     list.Name,
+		strategy: list.Strategy,
+		label:    "list " + list.Name,
+	}
+	c.syncItems(items, mediaType, origin, stats)
+	return nil
+}
+
+// syncItems creates or refreshes the media of Trakt list items
+func (c *SyncController) syncItems(items []trakt.TraktMedia, mediaType string, origin syncOrigin, stats *SyncStats) {
 	for _, item := range items {
 		var imdbID string
 		var title string
@@ -124,6 +246,12 @@ func (c *SyncController) syncFavorites(ctx context.Context, mediaType string) er
 			continue
 		}
 
+		if mType == models.MediaTypeMovie {
+			stats.Movies++
+		} else {
+			stats.Shows++
+		}
+
 		// Check if media already exists
 		existingMedia, err := c.db.GetMediaByIMDBID(imdbID, mType, nil, nil)
 		if err == nil {
@@ -131,7 +259,20 @@ func (c *SyncController) syncFavorites(ctx context.Context, mediaType string) er
 			existingMedia.IMDBId = imdbID
 			existingMedia.InTrakt = true
 			existingMedia.LastSeenInTrakt = time.Now()
-			existingMedia.Source = models.SourceFavorites
+			existingMedia.RemovedFromWatchlist = false
+			existingMedia.Source = origin.source
+			existingMedia.List = origin.list
+			existingMedia.EpisodeStrategy = origin.strategy
+			existingMedia.Overrides = c.parseOverrides(title, item.Notes)
+
+			// Movies collected since they were added are owned, nothing to download
+			if (existingMedia.Status == models.StatusPending || existingMedia.Status == models.StatusFailed) &&
+				c.collectedMovie(imdbID, mType, origin) {
+				now := time.Now()
+				existingMedia.Status = models.StatusCompleted
+				existingMedia.CompletedAt = &now
+				c.logger.WithField("title", title).Info("Movie in Trakt collection, not downloading it")
+			}
 
 			// Do NOT reset completed downloads - we don't want to re-download them!
 			// Only reset failed downloads to give them another chance
@@ -147,14 +288,30 @@ func (c *SyncController) syncFavorites(ctx context.Context, mediaType string) er
 				c.logger.WithError(err).Error("Failed to update media")
 			}
 		} else {
-			// Create new media
+			if c.skipWatched(imdbID, mType, title) {
+				continue
+			}
+
+			// Create new media, collected movies are owned and never searched
+			status := models.StatusPending
+			var completedAt *time.Time
+			if c.collectedMovie(imdbID, mType, origin) {
+				now := time.Now()
+				status = models.StatusCompleted
+				completedAt = &now
+				c.logger.WithField("title", title).Info("Movie in Trakt collection, not downloading it")
+			}
 			media := &models.Media{
 				IMDBId:          imdbID,
 				MediaType:       mType,
 				Title:           title,
 				Year:            year,
-				Source:          models.SourceFavorites,
-				Status:          models.StatusPending,
+				Source:          origin.source,
+				List:            origin.list,
+				EpisodeStrategy: origin.strategy,
+				Overrides:       c.parseOverrides(title, item.Notes),
+				Status:          status,
+				CompletedAt:     completedAt,
 				Watched:         false,
 				InTrakt:         true,
 				LastSeenInTrakt: time.Now(),
@@ -163,101 +320,103 @@ func (c *SyncController) syncFavorites(ctx context.Context, mediaType string) er
 			if err := c.db.CreateMedia(media); err != nil {
 				c.logger.WithError(err).Error("Failed to create media")
 			} else {
+				stats.Added++
 				c.logger.WithFields(logrus.Fields{
 					"title": title,
 					"type":  mType,
-				}).Info("Added new media from favorites")
+				}).Info("Added new media from " + origin.label)
+
+				c.notifier.Notify(mediaEvent(notify.EventMediaAdded, media, fmt.Sprintf("Added %s from %s", describeMedia(media), origin.label)))
 			}
 		}
 	}
-
-	return nil
 }
 
-// syncWatchlist syncs watchlist from Trakt
-func (c *SyncController) syncWatchlist(ctx context.Context, mediaType string) error {
-	c.logger.WithField("type", mediaType).Info("Syncing watchlist")
+// skipWatched checks if a movie was already watched and cleaned up
+// Shows are kept, their episodes are filtered against the ledger by the strategy.
+func (c *SyncController) skipWatched(imdbID string, mType models.MediaType, title string) bool {
+	if c.redownloadWatched || mType != models.MediaTypeMovie {
+		return false
+	}
 
-	items, err := c.traktClient.GetWatchlist(ctx, mediaType)
+	watched, err := c.db.IsWatched(imdbID, 0, 0)
 	if err != nil {
-		return fmt.Errorf("failed to get watchlist: %w", err)
+		c.logger.WithError(err).Warn("Failed to check watched ledger")
+		return false
 	}
+	if watched {
+		c.logger.WithFields(logrus.Fields{
+			"imdb_id": imdbID,
+			"title":   title,
+		}).Info("Movie already watched and cleaned up, not adding it again")
+	}
+	return watched
+}
 
-	c.logger.WithField("count", len(items)).Debug("Retrieved watchlist")
-
-	for _, item := range items {
-		var imdbID string
-		var title string
-		var year int
-		var mType models.MediaType
-
-		if mediaType == "movies" && item.Movie != nil {
-			imdbID = item.Movie.IDs.IMDB
-			title = item.Movie.Title
-			year = item.Movie.Year
-			mType = models.MediaTypeMovie
-		} else if mediaType == "shows" && item.Show != nil {
-			imdbID = item.Show.IDs.IMDB
-			title = item.Show.Title
-			year = item.Show.Year
-			mType = models.MediaTypeTV
-		} else {
-			continue
-		}
-
-		if imdbID == "" {
-			c.logger.WithField("title", title).Warn("Missing IMDB ID, skipping")
-			continue
-		}
-
-		// Check if media already exists
-		existingMedia, err := c.db.GetMediaByIMDBID(imdbID, mType, nil, nil)
-		if err == nil {
-			// Update existing media
-			existingMedia.IMDBId = imdbID
-			existingMedia.InTrakt = true
-			existingMedia.LastSeenInTrakt = time.Now()
-			existingMedia.Source = models.SourceWatchlist
-
-			// Do NOT reset completed downloads - we don't want to re-download them!
-			// Only reset failed downloads to give them another chance
-			if existingMedia.Status == models.StatusFailed {
-				existingMedia.Status = models.StatusPending
+// parseOverrides converts directives found in Trakt list item notes into media overrides
+func (c *SyncController) parseOverrides(title string, notes string) models.MediaOverrides {
+	var overrides models.MediaOverrides
+
+	for key, value := range utils.ParseNoteDirectives(notes) {
+		switch key {
+		case "quality":
+			overrides.Quality = value
+		case "profile":
+			overrides.Profile = strings.ToLower(value)
+		case "lang", "language":
+			overrides.Language = strings.ToLower(value)
+		case "strategy":
+			switch models.EpisodeStrategy(strings.ToLower(value)) {
+			case models.EpisodeStrategyNext, models.EpisodeStrategySeason, models.EpisodeStrategyBackfill:
+				overrides.Strategy = models.EpisodeStrategy(strings.ToLower(value))
+			default:
 				c.logger.WithFields(logrus.Fields{
-					"title":      title,
-					"old_status": "failed",
-				}).Debug("Resetting failed media status to pending for retry")
+					"title": title,
+					"value": value,
+				}).Warn("Unknown strategy directive in Trakt notes, ignoring")
 			}
-
-			if err := c.db.UpdateMedia(existingMedia); err != nil {
-				c.logger.WithError(err).Error("Failed to update media")
+		case "pack":
+			switch models.PackPolicy(strings.ToLower(value)) {
+			case models.PackPolicyNever, models.PackPolicyOnly:
+				overrides.Pack = models.PackPolicy(strings.ToLower(value))
+			default:
+				c.logger.WithFields(logrus.Fields{
+					"title": title,
+					"value": value,
+				}).Warn("Unknown pack directive in Trakt notes, ignoring")
 			}
-		} else {
-			// Create new media
-			media := &models.Media{
-				IMDBId:          imdbID,
-				MediaType:       mType,
-				Title:           title,
-				Year:            year,
-				Source:          models.SourceWatchlist,
-				Status:          models.StatusPending,
-				Watched:         false,
-				InTrakt:         true,
-				LastSeenInTrakt: time.Now(),
+		case "packupgrade":
+			switch strings.ToLower(value) {
+			case "on", "yes", "true":
+				overrides.PackUpgrade = models.PackUpgradeOn
+			case "off", "no", "false":
+				overrides.PackUpgrade = models.PackUpgradeOff
+			default:
+				c.logger.WithFields(logrus.Fields{
+					"title": title,
+					"value": value,
+				}).Warn("Unknown packupgrade directive in Trakt notes, ignoring")
 			}
-
-			if err := c.db.CreateMedia(media); err != nil {
-				c.logger.WithError(err).Error("Failed to create media")
-			} else {
+		case "anime":
+			switch strings.ToLower(value) {
+			case "on", "yes", "true":
+				overrides.Anime = true
+			case "off", "no", "false":
+			default:
 				c.logger.WithFields(logrus.Fields{
 					"title": title,
-					"type":  mType,
-				}).Info("Added new media from watchlist")
+					"value": value,
+				}).Warn("Unknown anime directive in Trakt notes, ignoring")
 			}
+		default:
+			c.logger.WithFields(logrus.Fields{
+				"title":     title,
+				"directive": key,
+			}).Debug("Unknown directive in Trakt notes, ignoring")
 		}
 	}
 
-	return nil
+	return overrides
 }
 
 // syncWatched syncs watched status from Trakt
@@ -287,21 +446,24 @@ func (c *SyncController) syncWatched(ctx context.Context) error {
 }
 
 // updateEpisodeWatchedStatus updates watched status for episodes in season packs
-func (c *SyncController) updateEpisodeWatchedStatus(ctx context.Context) error {
+// Returns the number of episodes newly marked as watched
+func (c *SyncController) updateEpisodeWatchedStatus(ctx context.Context) (int, error) {
 	c.logger.Info("Updating episode watched status")
 
 	// Get recently watched episodes
 	watchedItems, err := c.traktClient.GetRecentlyWatched(ctx, 3)
 	if err != nil {
-		return fmt.Errorf("failed to get watched items: %w", err)
+		return 0, fmt.Errorf("failed to get watched items: %w", err)
 	}
 
 	// Get all medias
 	allMedias, err := c.db.GetAllMedias()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	updatedEpisodes := 0
+
 	// Update episode status in season packs
 	for _, media := range allMedias {
 		if media.MediaType != models.MediaTypeTV {
@@ -327,6 +489,9 @@ func (c *SyncController) updateEpisodeWatchedStatus(ctx context.Context) error {
 				// Update episode watched status
 				for i := range nzb.Episodes {
 					if nzb.Episodes[i].EpisodeNumber == watchedItem.Episode {
+						if !nzb.Episodes[i].Watched {
+							updatedEpisodes++
+						}
 						nzb.Episodes[i].Watched = true
 						watchedAt := watchedItem.WatchedAt
 						nzb.Episodes[i].WatchedAt = &watchedAt
@@ -343,5 +508,5 @@ func (c *SyncController) updateEpisodeWatchedStatus(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	return updatedEpisodes, nil
 }
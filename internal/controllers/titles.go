@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/sirupsen/logrus"
+)
+
+// resolveSearchTitles looks up the title in the metadata language of the media whose
+// search title was never resolved for it. Lookups failing are retried at the next sync.
+func (c *SyncController) resolveSearchTitles(ctx context.Context) {
+	medias, err := c.db.GetAllMedias()
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to get medias for title lookup")
+		return
+	}
+
+	titles := make(map[string]string) // Shows tracked by season share their title
+	for _, media := range medias {
+		if media.ParentID != 0 || media.SearchTitleLanguage == c.metadataLanguage {
+			continue
+		}
+
+		title := ""
+		if c.metadataLanguage != "" {
+			var ok bool
+			if title, ok = titles[media.IMDBId]; !ok {
+				mediaType := "shows"
+				if media.MediaType == models.MediaTypeMovie {
+					mediaType = "movies"
+				}
+				title, err = c.traktClient.GetTitle(ctx, mediaType, media.IMDBId, c.metadataLanguage)
+				if errors.Is(err, trakt.ErrUnavailable) || ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					c.logger.WithError(err).WithField("media_id", media.ID).Debug("Failed to look up search title")
+					continue
+				}
+				titles[media.IMDBId] = title
+			}
+		}
+
+		if title == media.Title {
+			title = ""
+		}
+		media.SearchTitle = title
+		media.SearchTitleLanguage = c.metadataLanguage
+		if err := c.db.UpdateMedia(media); err != nil {
+			c.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to update media")
+			continue
+		}
+		if title != "" {
+			c.logger.WithFields(logrus.Fields{
+				"media_id":     media.ID,
+				"title":        media.Title,
+				"search_title": title,
+				"language":     c.metadataLanguage,
+			}).Info("Searching media under its title in the metadata language")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/services/torbox"
+	"github.com/sirupsen/logrus"
+)
+
+// fileChecksums returns the MD5 TorBox reports for the files of a release, by lowercase
+// file name. nil when the download can't be found or has no checksums.
+func (c *DownloadController) fileChecksums(nzb *models.NZB) map[string]string {
+	id, err := strconv.Atoi(nzb.TorBoxJobID)
+	if err != nil {
+		return nil
+	}
+
+	var files []torbox.UsenetDownloadFile
+	if nzb.IsTorrent() {
+		var torrent *torbox.TorrentDownload
+		if torrent, err = c.torboxClient.FindTorrentByID(id); err == nil {
+			files = torrent.Files
+		}
+	} else {
+		var download *torbox.UsenetDownload
+		if download, err = c.torboxClient.FindDownloadByID(id); err == nil {
+			files = download.Files
+		}
+	}
+	if err != nil {
+		c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to get download checksums, copies are not verified")
+	}
+
+	var checksums map[string]string
+	for _, file := range files {
+		if file.MD5 == "" {
+			continue
+		}
+		name := file.ShortName
+		if name == "" {
+			name = filepath.Base(file.Name)
+		}
+		if checksums == nil {
+			checksums = make(map[string]string)
+		}
+		checksums[strings.ToLower(name)] = file.MD5
+	}
+	return checksums
+}
+
+// rejectRelease drops a completed release rejected on import (corrupt copy, samples
+// only, wrong content). The release is deleted from TorBox when deleteJob is set (the
+// download was quarantined) and kept as rejected, so it is never grabbed again, and the
+// next candidate is downloaded. The media goes back to pending when no candidate is left,
+// so the next search finds another release.
+func (c *DownloadController) rejectRelease(media *models.Media, nzb *models.NZB, err error, deleteJob bool) {
+	c.logger.WithError(err).WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"nzb_id":   nzb.ID,
+		"release":  nzb.Title,
+	}).Warn("Download rejected on import, retrying with next candidate")
+
+	if deleteJob && nzb.TorBoxJobID != "" {
+		if err := deleteTorBoxJob(c.torboxClient, nzb); err != nil {
+			c.logger.WithError(err).WithField("job_id", nzb.TorBoxJobID).Warn("Failed to delete job from TorBox")
+		}
+	}
+
+	nzb.Status = models.NZBStatusRejected
+	nzb.FailureReason = err.Error()
+	nzb.RetryCount++
+	if err := c.db.UpdateNZB(nzb); err != nil {
+		c.logger.WithError(err).WithField("nzb_id", nzb.ID).Error("Failed to update NZB")
+	}
+
+	event := releaseEvent(notify.EventDownloadFailed, media, nzb, fmt.Sprintf("Download of %s was rejected on import", describeMedia(media)))
+	event.Error = err.Error()
+	c.notifier.Notify(event)
+
+	if err := c.RetryWithNextCandidate(media.ID); err != nil {
+		c.logger.WithError(err).WithField("media_id", media.ID).Warn("No candidate left, searching again")
+
+		current, err := c.db.GetMediaByID(media.ID)
+		if err != nil {
+			return
+		}
+		current.Status = models.StatusPending
+		if err := c.db.UpdateMedia(current); err != nil {
+			c.logger.WithError(err).Error("Failed to update media status")
+		}
+	}
+}
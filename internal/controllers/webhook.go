@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// webhookDedupTTL is how long the keys of processed webhook events are kept
+// TorBox redelivers within minutes, a day leaves room for retries after an outage.
+const webhookDedupTTL = 24 * time.Hour
+
+// WebhookDelivery identifies a webhook event, so an event delivered twice is applied once
+type WebhookDelivery struct {
+	Type string    // Provider event type
+	At   time.Time // Event time set by the provider, zero if unknown
+}
+
+// key derives the idempotency key of an event from its type, download job and time
+// Returns "" when the event can't be told apart from a new one (no time or no job).
+func (d WebhookDelivery) key(nzb *models.NZB) string {
+	if d.At.IsZero() || nzb.TorBoxJobID == "" {
+		return ""
+	}
+	protocol := models.ProtocolUsenet
+	if nzb.IsTorrent() {
+		protocol = models.ProtocolTorrent
+	}
+	return strings.Join([]string{d.Type, string(protocol), nzb.TorBoxJobID, d.At.UTC().Format(time.RFC3339Nano)}, "|")
+}
+
+// handleWebhookOnce applies a webhook event to a download unless it was already processed
+// The key of a failed event is released, so the provider retrying it is processed again.
+func (c *DownloadController) handleWebhookOnce(delivery WebhookDelivery, nzb *models.NZB, status string) error {
+	jobID := nzb.TorBoxJobID
+	key := delivery.key(nzb)
+	if key == "" {
+		return c.HandleWebhook(nzb.Protocol, jobID, status, "")
+	}
+
+	claimed, err := c.db.ClaimWebhook(key, webhookDedupTTL)
+	if err != nil {
+		// Processing twice beats dropping the event
+		c.logger.WithError(err).WithField("key", key).Warn("Failed to record webhook event")
+		return c.HandleWebhook(nzb.Protocol, jobID, status, "")
+	}
+	if !claimed {
+		c.logger.WithFields(logrus.Fields{
+			"job_id": jobID,
+			"status": status,
+			"key":    key,
+		}).Info("Skipping duplicate webhook event")
+		return nil
+	}
+
+	if err := c.HandleWebhook(nzb.Protocol, jobID, status, ""); err != nil {
+		if releaseErr := c.db.ReleaseWebhook(key); releaseErr != nil {
+			c.logger.WithError(releaseErr).WithField("key", key).Warn("Failed to release webhook event")
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWidenedEpisodes bounds the episodes searched one by one by title in a widened search,
+// larger strategies (backfills, gap fills) are only widened with season packs
+const maxWidenedEpisodes = 10
+
+// widens checks if a show search is widened after repeated empty searches
+func (c *SearchController) widens(media *models.Media, strategy *DownloadStrategy) bool {
+	return c.widenAfter > 0 && strategy.Type != StrategySingleMovie && media.EmptySearches >= c.widenAfter
+}
+
+// searchWidened searches the seasons and episodes of a strategy by show title, finding the
+// releases indexers didn't tag with an IMDB ID, often older ones (DVDRip season packs).
+// Failed searches are skipped.
+func (c *SearchController) searchWidened(ctx context.Context, media *models.Media, strategy *DownloadStrategy) []newznab.SearchResult {
+	title := media.QueryTitle()
+	c.logger.WithFields(logrus.Fields{
+		"media_id":       media.ID,
+		"title":          media.Title,
+		"empty_searches": media.EmptySearches,
+	}).Info("Widening search after repeated empty searches")
+
+	var seasons []int
+	if strategy.SeasonNumber != nil {
+		seasons = append(seasons, *strategy.SeasonNumber)
+	}
+	seasons = append(seasons, strategy.Seasons...)
+	for _, ep := range strategy.Episodes {
+		seasons = append(seasons, ep.Season)
+	}
+	slices.Sort(seasons)
+	seasons = slices.Compact(seasons)
+
+	var results []newznab.SearchResult
+	if media.Overrides.Pack != models.PackPolicyNever {
+		for _, season := range seasons {
+			packs, err := c.newznabClient.SearchSeasonByTitle(ctx, title, season)
+			if err != nil {
+				c.logger.WithError(err).WithField("season", season).Warn("Widened season pack search failed")
+				continue
+			}
+			results = appendNewResults(results, packs)
+		}
+	}
+
+	if media.Overrides.Pack != models.PackPolicyOnly && len(strategy.Episodes) <= maxWidenedEpisodes {
+		for _, ep := range strategy.Episodes {
+			episodes, err := c.newznabClient.SearchEpisodeByTitle(ctx, title, ep.Season, ep.Episode)
+			if err != nil {
+				c.logger.WithError(err).WithFields(logrus.Fields{
+					"season":  ep.Season,
+					"episode": ep.Episode,
+				}).Warn("Widened episode search failed")
+				continue
+			}
+			results = appendNewResults(results, episodes)
+		}
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"results":  len(results),
+	}).Debug("Widened search completed")
+	return results
+}
+
+// appendNewResults appends the results not already in a list, compared by GUID
+func appendNewResults(results []newznab.SearchResult, more []newznab.SearchResult) []newznab.SearchResult {
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.GUID] = true
+	}
+	for _, result := range more {
+		if result.GUID == "" || !seen[result.GUID] {
+			seen[result.GUID] = true
+			results = append(results, result)
+		}
+	}
+	return results
+}
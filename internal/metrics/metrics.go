@@ -0,0 +1,42 @@
+package metrics
+
+import "github.com/amaumene/gomenarr/internal/version"
+
+// BuildInfo exposes the running build as labels, value is always 1
+var BuildInfo = NewGauge("gomenarr_build_info", "Build information of the running gomenarr instance.",
+	"version", "commit", "build_date", "go_version")
+
+// RecordBuildInfo sets the build_info metric for the running build
+func RecordBuildInfo() {
+	info := version.Info()
+	BuildInfo.Set(1, info.Version, info.Commit, info.BuildDate, info.GoVersion)
+}
+
+// Scheduler cycle summary, one set of series per task
+var (
+	CycleItems = NewGauge("gomenarr_cycle_items", "Items processed during the last cycle of a scheduler task.",
+		"task", "item")
+	CycleFailures = NewGauge("gomenarr_cycle_failures", "Failures during the last cycle of a scheduler task.",
+		"task")
+	CycleDuration = NewGauge("gomenarr_cycle_duration_seconds", "Duration of the last cycle of a scheduler task.",
+		"task")
+	CycleLastRun = NewGauge("gomenarr_cycle_last_run_timestamp_seconds", "Unix time the last cycle of a scheduler task finished.",
+		"task")
+)
+
+// External API requests, recorded by the shared HTTP transport of the Trakt, Newznab and TorBox clients
+var (
+	ExternalRequests = NewCounter("gomenarr_external_requests_total", "Requests to external APIs by result (2xx, 4xx, 5xx, error, circuit_open).",
+		"service", "result")
+	ExternalRequestSeconds = NewCounter("gomenarr_external_request_duration_seconds_total", "Time spent in requests to external APIs, retries included.",
+		"service")
+	ExternalRetries = NewCounter("gomenarr_external_retries_total", "Retries of rate limited or unavailable external API responses.",
+		"service")
+)
+
+// Database activity, read from the bbolt statistics on each scrape
+var (
+	DBReadTransactions = NewCounter("gomenarr_db_read_transactions_total", "Read transactions started on the database.")
+	DBPageWrites       = NewCounter("gomenarr_db_page_writes_total", "Pages written to the database file.")
+	DBOpenTransactions = NewGauge("gomenarr_db_open_read_transactions", "Read transactions currently open on the database.")
+)
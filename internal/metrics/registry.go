@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metricType is the Prometheus metric type of a family
+type metricType string
+
+const (
+	typeCounter metricType = "counter"
+	typeGauge   metricType = "gauge"
+)
+
+// Registry holds metric families and renders them in the Prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	families   []*family
+	collectors []func() // Run before each rendering to refresh values read from elsewhere
+}
+
+// family is a named metric with a set of labelled series
+type family struct {
+	name       string
+	help       string
+	metricType metricType
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*Series
+}
+
+// Series is a single labelled value of a metric
+type Series struct {
+	labelValues []string
+
+	mu    sync.Mutex
+	value float64
+}
+
+// Default is the registry exposed on /metrics
+var Default = &Registry{}
+
+// register adds a new family to the registry
+func (r *Registry) register(name, help string, metricType metricType, labelNames []string) *family {
+	f := &family{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		series:     make(map[string]*Series),
+	}
+
+	r.mu.Lock()
+	r.families = append(r.families, f)
+	r.mu.Unlock()
+
+	return f
+}
+
+// with returns the series for the given label values, creating it if needed
+func (f *family) with(labelValues ...string) *Series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &Series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	return s
+}
+
+// CounterVec is a monotonically increasing metric with labels
+type CounterVec struct {
+	family *family
+}
+
+// NewCounter registers a new counter on the default registry
+func NewCounter(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: Default.register(name, help, typeCounter, labelNames)}
+}
+
+// Inc increments the counter for the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a positive value to the counter for the given label values
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	s := c.family.with(labelValues...)
+	s.mu.Lock()
+	s.value += value
+	s.mu.Unlock()
+}
+
+// GaugeVec is a metric that can go up and down, with labels
+type GaugeVec struct {
+	family *family
+}
+
+// NewGauge registers a new gauge on the default registry
+func NewGauge(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{family: Default.register(name, help, typeGauge, labelNames)}
+}
+
+// Set sets the gauge for the given label values
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	s := g.family.with(labelValues...)
+	s.mu.Lock()
+	s.value = value
+	s.mu.Unlock()
+}
+
+// Add adds a value (possibly negative) to the gauge for the given label values
+func (g *GaugeVec) Add(value float64, labelValues ...string) {
+	s := g.family.with(labelValues...)
+	s.mu.Lock()
+	s.value += value
+	s.mu.Unlock()
+}
+
+// OnCollect registers a function updating metrics before each rendering of the default registry
+func OnCollect(collect func()) {
+	Default.mu.Lock()
+	defer Default.mu.Unlock()
+	Default.collectors = append(Default.collectors, collect)
+}
+
+// WriteTo renders all metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	r.mu.Lock()
+	collectors := append([]func(){}, r.collectors...)
+	r.mu.Unlock()
+	for _, collect := range collectors {
+		collect()
+	}
+
+	r.mu.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mu.Unlock()
+
+	for _, f := range families {
+		f.mu.Lock()
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.metricType)
+		for _, key := range keys {
+			s := f.series[key]
+			s.mu.Lock()
+			value := s.value
+			s.mu.Unlock()
+
+			b.WriteString(f.name)
+			writeLabels(&b, f.labelNames, s.labelValues)
+			b.WriteByte(' ')
+			b.WriteString(formatValue(value))
+			b.WriteByte('\n')
+		}
+		f.mu.Unlock()
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeLabels renders a label set like {name="value",other="value"}
+func writeLabels(b *strings.Builder, names, values []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+}
+
+// escapeLabelValue escapes backslashes, quotes and new lines in label values
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatValue formats a sample value
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// Handler returns an HTTP handler serving the default registry
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Default.WriteTo(w)
+	})
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryTextFormat(t *testing.T) {
+	registry := &Registry{}
+	counter := &CounterVec{family: registry.register("test_requests_total", "Test requests.", typeCounter, []string{"service"})}
+	gauge := &GaugeVec{family: registry.register("test_queue", "Test queue.", typeGauge, nil)}
+
+	counter.Inc("trakt")
+	counter.Add(2, "trakt")
+	counter.Inc(`quo"te`)
+	gauge.Set(7)
+
+	var b strings.Builder
+	if _, err := registry.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{service="trakt"} 3` + "\n",
+		`test_requests_total{service="quo\"te"} 1` + "\n",
+		"# TYPE test_queue gauge\n",
+		"test_queue 7\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistryCollectors(t *testing.T) {
+	registry := &Registry{}
+	gauge := &GaugeVec{family: registry.register("test_db_size", "Test database size.", typeGauge, nil)}
+
+	// Collectors refresh the values on each rendering
+	size := 0.0
+	registry.collectors = append(registry.collectors, func() {
+		size += 512
+		gauge.Set(size)
+	})
+
+	for _, want := range []string{"test_db_size 512\n", "test_db_size 1024\n"} {
+		var b strings.Builder
+		if _, err := registry.WriteTo(&b); err != nil {
+			t.Fatal(err)
+		}
+		if out := b.String(); !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// AvailabilityRecord measures how long after its air or release date an imported release
+// was posted on its indexer and imported
+type AvailabilityRecord struct {
+	ID         uint64 `boltholdKey:"ID"`
+	MediaID    uint64 `boltholdIndex:"MediaID"`
+	NZBID      uint64
+	MediaType  MediaType
+	Title      string // Release title
+	Season     int
+	Episode    int      // First episode of the release, 0 for movies and season packs
+	Indexer    string   // Indexer the release was grabbed from
+	Alternates []string // Other indexers offering the same release
+	Resolution int      // 0 when unknown
+	Source     string   // e.g. web-dl, bluray, empty when unknown
+	Upgrade    bool     // Replaced a completed release
+
+	ReleasedAt  time.Time // Air time of the last episode of the release, home release date of movies
+	AvailableAt time.Time // Post date on the indexer, or when a search first found it when unknown
+	ImportedAt  time.Time
+}
+
+// AvailableAfter returns how long after its release the release was available
+func (r *AvailabilityRecord) AvailableAfter() time.Duration {
+	return r.AvailableAt.Sub(r.ReleasedAt)
+}
+
+// ImportedAfter returns how long after its release the release was imported
+func (r *AvailabilityRecord) ImportedAfter() time.Duration {
+	return r.ImportedAt.Sub(r.ReleasedAt)
+}
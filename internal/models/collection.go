@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CollectedItem is a movie or episode found in the Trakt collection, e.g. ripped from a disc
+// Collected items of the configured sources count as owned and are not downloaded.
+type CollectedItem struct {
+	Key         string `boltholdKey:"Key"`
+	IMDBId      string `boltholdIndex:"IMDBId"`
+	MediaType   MediaType
+	Season      int // 0 for movies
+	Episode     int // 0 for movies
+	CollectedAt time.Time
+}
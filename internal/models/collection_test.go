@@ -0,0 +1,47 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestReplaceCollection(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	collected := func(imdb string, season, episode int) bool {
+		ok, err := db.IsCollected(imdb, season, episode)
+		if err != nil {
+			t.Fatalf("Failed to check collection: %v", err)
+		}
+		return ok
+	}
+
+	if err := db.ReplaceCollection([]*CollectedItem{
+		{IMDBId: "tt1", MediaType: MediaTypeMovie},
+		{IMDBId: "tt2", MediaType: MediaTypeTV, Season: 1, Episode: 2},
+	}); err != nil {
+		t.Fatalf("Failed to replace collection: %v", err)
+	}
+	if !collected("tt1", 0, 0) || !collected("tt2", 1, 2) {
+		t.Error("Expected the collected items to be found")
+	}
+	if collected("tt2", 1, 3) {
+		t.Error("Expected an episode outside the collection not to be found")
+	}
+
+	if err := db.ReplaceCollection([]*CollectedItem{{IMDBId: "tt2", MediaType: MediaTypeTV, Season: 1, Episode: 3}}); err != nil {
+		t.Fatalf("Failed to replace collection: %v", err)
+	}
+	if collected("tt1", 0, 0) || collected("tt2", 1, 2) {
+		t.Error("Expected the previous collection to be replaced")
+	}
+	if !collected("tt2", 1, 3) {
+		t.Error("Expected the new item to be found")
+	}
+}
@@ -2,19 +2,26 @@ package models
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/amaumene/gomenarr/internal/metrics"
+	"github.com/sirupsen/logrus"
 	"github.com/timshannon/bolthold"
 	"go.etcd.io/bbolt"
 )
 
 // Database wraps the bolthold store
 type Database struct {
-	store *bolthold.Store
+	store  *bolthold.Store
+	logger *logrus.Logger
+
+	statsMu   sync.Mutex
+	lastStats bbolt.Stats // Statistics at the previous metrics collection
 }
 
 // NewDatabase creates a new database connection
-func NewDatabase(path string) (*Database, error) {
+func NewDatabase(path string, logger *logrus.Logger) (*Database, error) {
 	store, err := bolthold.Open(path, 0600, &bolthold.Options{
 		Options: &bbolt.Options{
 			Timeout: 1 * time.Second,
@@ -24,7 +31,13 @@ func NewDatabase(path string) (*Database, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return &Database{store: store}, nil
+	db := &Database{store: store, logger: logger}
+	if err := db.migrate(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return db, nil
 }
 
 // Close closes the database connection
@@ -32,6 +45,20 @@ func (db *Database) Close() error {
 	return db.store.Close()
 }
 
+// CollectMetrics adds the database activity since the previous call to the metrics
+func (db *Database) CollectMetrics() {
+	db.statsMu.Lock()
+	defer db.statsMu.Unlock()
+
+	stats := db.store.Bolt().Stats()
+	diff := stats.Sub(&db.lastStats)
+	db.lastStats = stats
+
+	metrics.DBReadTransactions.Add(float64(diff.TxN))
+	metrics.DBPageWrites.Add(float64(diff.TxStats.GetWrite()))
+	metrics.DBOpenTransactions.Set(float64(stats.OpenTxN))
+}
+
 // Media operations
 
 // CreateMedia creates a new media item in the database
@@ -42,7 +69,11 @@ func (db *Database) CreateMedia(media *Media) error {
 }
 
 // UpdateMedia updates an existing media item
+// Status changes not allowed by the state machine are rejected with ErrInvalidTransition.
 func (db *Database) UpdateMedia(media *Media) error {
+	if err := db.checkMediaTransition(media); err != nil {
+		return err
+	}
 	media.UpdatedAt = time.Now()
 	return db.store.Update(media.ID, media)
 }
@@ -64,6 +95,13 @@ func (db *Database) GetPendingMedias() ([]*Media, error) {
 	return medias, err
 }
 
+// GetMediasByStatus retrieves all media items with a specific status
+func (db *Database) GetMediasByStatus(status Status) ([]*Media, error) {
+	var medias []*Media
+	err := db.store.Find(&medias, bolthold.Where("Status").Eq(status))
+	return medias, err
+}
+
 // GetMediaByIMDBID retrieves a media item by IMDB ID and type
 func (db *Database) GetMediaByIMDBID(imdbID string, mediaType MediaType, season *int, episode *int) (*Media, error) {
 	var medias []*Media
@@ -108,8 +146,21 @@ func (db *Database) GetMediasNotInTrakt() ([]*Media, error) {
 	return medias, err
 }
 
-// DeleteMedia deletes a media item by ID
+// GetChildMedias retrieves all episode-level media items attached to a parent show
+func (db *Database) GetChildMedias(parentID uint64) ([]*Media, error) {
+	var medias []*Media
+	err := db.store.Find(&medias, bolthold.Where("ParentID").Eq(parentID))
+	return medias, err
+}
+
+// DeleteMedia deletes a media item by ID, along with its search history
 func (db *Database) DeleteMedia(id uint64) error {
+	if err := db.store.DeleteMatching(&SearchAttempt{}, bolthold.Where("MediaID").Eq(id).Index("MediaID")); err != nil {
+		return err
+	}
+	if err := db.store.DeleteMatching(&Rejection{}, bolthold.Where("MediaID").Eq(id).Index("MediaID")); err != nil {
+		return err
+	}
 	return db.store.Delete(id, &Media{})
 }
 
@@ -134,15 +185,68 @@ func (db *Database) MarkAllMediasNotInTrakt() error {
 
 // NZB operations
 
-// CreateNZB creates a new NZB record
+// CreateNZB creates a new NZB record, or refreshes the stored record when the same
+// release was already found for this media. nzb is updated with the stored state.
 func (db *Database) CreateNZB(nzb *NZB) error {
+	nzb.DedupeKey = NZBDedupeKey(nzb.MediaID, nzb.GUID, nzb.Link, nzb.Title)
+
+	var existing NZB
+	err := db.store.FindOne(&existing, bolthold.Where("DedupeKey").Eq(nzb.DedupeKey))
+	if err == nil {
+		mergeNZB(&existing, nzb)
+		existing.UpdatedAt = time.Now()
+		if err := db.store.Update(existing.ID, &existing); err != nil {
+			return err
+		}
+		*nzb = existing
+		return nil
+	}
+	if err != bolthold.ErrNotFound {
+		return err
+	}
+
 	nzb.CreatedAt = time.Now()
 	nzb.UpdatedAt = time.Now()
 	return db.store.Insert(bolthold.NextSequence(), nzb)
 }
 
+// mergeNZB refreshes an existing NZB with a new search result for the same release
+// Records that already went through a download attempt keep their state, selected and
+// blacklisted ones keep their status when found again as a plain candidate.
+func mergeNZB(existing *NZB, found *NZB) {
+	switch existing.Status {
+	case NZBStatusDownloading, NZBStatusCompleted, NZBStatusFailed, NZBStatusReplaced, NZBStatusRejected:
+		return
+	}
+
+	existing.Title = found.Title
+	existing.Link = found.Link
+	existing.Size = found.Size
+	existing.Quality = found.Quality
+	existing.Year = found.Year
+	existing.Indexer = found.Indexer
+	existing.Score = found.Score
+	existing.QualityScore = found.QualityScore
+	existing.Protocol = found.Protocol
+	existing.Alternates = found.Alternates
+	if found.Status != NZBStatusCandidate || (existing.Status != NZBStatusSelected && existing.Status != NZBStatusBlacklisted) {
+		existing.Status = found.Status
+		existing.BlacklistMatch = found.BlacklistMatch
+	}
+	existing.Season = found.Season
+	existing.Episode = found.Episode
+	existing.IsSeasonPack = found.IsSeasonPack
+	if len(existing.Episodes) == 0 {
+		existing.Episodes = found.Episodes
+	}
+}
+
 // UpdateNZB updates an existing NZB record
+// Status changes not allowed by the state machine are rejected with ErrInvalidTransition.
 func (db *Database) UpdateNZB(nzb *NZB) error {
+	if err := db.checkNZBTransition(nzb); err != nil {
+		return err
+	}
 	nzb.UpdatedAt = time.Now()
 	return db.store.Update(nzb.ID, nzb)
 }
@@ -165,16 +269,31 @@ func (db *Database) GetNZBsByMediaID(mediaID uint64) ([]*NZB, error) {
 }
 
 // GetNZBByTorBoxJobID retrieves an NZB by TorBox job ID
-func (db *Database) GetNZBByTorBoxJobID(jobID string) (*NZB, error) {
+// Usenet downloads and torrents are numbered separately, the protocol tells them apart.
+func (db *Database) GetNZBByTorBoxJobID(protocol Protocol, jobID string) (*NZB, error) {
 	var nzbs []*NZB
 	err := db.store.Find(&nzbs, bolthold.Where("TorBoxJobID").Eq(jobID))
 	if err != nil {
 		return nil, err
 	}
-	if len(nzbs) == 0 {
-		return nil, bolthold.ErrNotFound
+	for _, nzb := range nzbs {
+		if nzb.IsTorrent() == (protocol == ProtocolTorrent) {
+			return nzb, nil
+		}
 	}
-	return nzbs[0], nil
+	return nil, bolthold.ErrNotFound
+}
+
+// GetRecentNZBs retrieves the most recently created NZBs, newest first
+func (db *Database) GetRecentNZBs(limit int) ([]*NZB, error) {
+	query := (&bolthold.Query{}).SortBy("ID").Reverse()
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var nzbs []*NZB
+	err := db.store.Find(&nzbs, query)
+	return nzbs, err
 }
 
 // GetNZBByTitle retrieves an NZB by its title (download name)
@@ -206,6 +325,15 @@ func (db *Database) GetBestCandidateNZB(mediaID uint64) (*NZB, error) {
 	return nzbs[0], nil
 }
 
+// GetNZBsByMediaIDAndStatus retrieves the NZBs of a media item with a specific status
+func (db *Database) GetNZBsByMediaIDAndStatus(mediaID uint64, status NZBStatus) ([]*NZB, error) {
+	var nzbs []*NZB
+	err := db.store.Find(&nzbs,
+		bolthold.Where("MediaID").Eq(mediaID).
+		And("Status").Eq(status))
+	return nzbs, err
+}
+
 // GetNZBsByStatus retrieves all NZBs with a specific status
 func (db *Database) GetNZBsByStatus(status NZBStatus) ([]*NZB, error) {
 	var nzbs []*NZB
@@ -223,6 +351,11 @@ func (db *Database) GetNZBByHash(hash string) (*NZB, error) {
 	return &nzb, nil
 }
 
+// DeleteNZB deletes an NZB
+func (db *Database) DeleteNZB(id uint64) error {
+	return db.store.Delete(id, &NZB{})
+}
+
 // DeleteNZBsByMediaID deletes all NZBs for a media item
 func (db *Database) DeleteNZBsByMediaID(mediaID uint64) error {
 	var nzbs []*NZB
@@ -239,3 +372,413 @@ func (db *Database) DeleteNZBsByMediaID(mediaID uint64) error {
 
 	return nil
 }
+
+// Watched ledger operations
+
+// RecordWatched adds a watched and cleaned item to the ledger
+func (db *Database) RecordWatched(entry *WatchedEntry) error {
+	entry.Key = WatchedKey(entry.IMDBId, entry.Season, entry.Episode)
+	entry.CleanedAt = time.Now()
+	return db.store.Upsert(entry.Key, entry)
+}
+
+// IsWatched checks if a movie (season and episode 0) or an episode is in the ledger
+func (db *Database) IsWatched(imdbID string, season, episode int) (bool, error) {
+	var entry WatchedEntry
+	err := db.store.Get(WatchedKey(imdbID, season, episode), &entry)
+	if err == bolthold.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetWatchedEntries retrieves the ledger entries, all of them if imdbID is empty
+func (db *Database) GetWatchedEntries(imdbID string) ([]*WatchedEntry, error) {
+	var entries []*WatchedEntry
+	var query *bolthold.Query
+	if imdbID != "" {
+		query = bolthold.Where("IMDBId").Eq(imdbID)
+	}
+	err := db.store.Find(&entries, query)
+	return entries, err
+}
+
+// DeleteWatchedEntries removes the ledger entries of a movie or show
+// Returns the number of entries removed.
+func (db *Database) DeleteWatchedEntries(imdbID string) (int, error) {
+	entries, err := db.GetWatchedEntries(imdbID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		if err := db.store.Delete(entry.Key, &WatchedEntry{}); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// Library operations
+
+// SaveLibraryFile adds or refreshes a file found in the media library
+func (db *Database) SaveLibraryFile(file *LibraryFile) error {
+	file.Key = WatchedKey(file.IMDBId, file.Season, file.Episode)
+	return db.store.Upsert(file.Key, file)
+}
+
+// IsOnDisk checks if a movie (season and episode 0) or an episode was found in the library
+func (db *Database) IsOnDisk(imdbID string, season, episode int) (bool, error) {
+	var file LibraryFile
+	err := db.store.Get(WatchedKey(imdbID, season, episode), &file)
+	if err == bolthold.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetLibraryFile retrieves the library file of a movie (season and episode 0) or an episode
+func (db *Database) GetLibraryFile(imdbID string, season, episode int) (*LibraryFile, error) {
+	var file LibraryFile
+	if err := db.store.Get(WatchedKey(imdbID, season, episode), &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// DeleteLibraryFile removes a library file record, the file itself is left to the caller
+func (db *Database) DeleteLibraryFile(key string) error {
+	err := db.store.Delete(key, &LibraryFile{})
+	if err == bolthold.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// GetLibraryFiles retrieves the library files, all of them if imdbID is empty
+func (db *Database) GetLibraryFiles(imdbID string) ([]*LibraryFile, error) {
+	var files []*LibraryFile
+	var query *bolthold.Query
+	if imdbID != "" {
+		query = bolthold.Where("IMDBId").Eq(imdbID)
+	}
+	err := db.store.Find(&files, query)
+	return files, err
+}
+
+// DeleteLibraryFilesScannedBefore removes the files not found again since a scan started
+// Returns the number of files removed.
+func (db *Database) DeleteLibraryFilesScannedBefore(scanStart time.Time) (int, error) {
+	var files []*LibraryFile
+	if err := db.store.Find(&files, bolthold.Where("ScannedAt").Lt(scanStart)); err != nil {
+		return 0, err
+	}
+
+	for _, file := range files {
+		if err := db.store.Delete(file.Key, &LibraryFile{}); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(files), nil
+}
+
+// Search history operations
+
+// RecordSearch stores a search attempt and the search counters of its media item
+// Only the counters are written, the media status is left to the caller.
+// The oldest attempts beyond maxSearchAttempts are removed.
+func (db *Database) RecordSearch(media *Media, attempt *SearchAttempt) error {
+	attempt.MediaID = media.ID
+	if err := db.store.Insert(bolthold.NextSequence(), attempt); err != nil {
+		return err
+	}
+
+	var stored Media
+	if err := db.store.Get(media.ID, &stored); err != nil {
+		return err
+	}
+	stored.SearchCount = media.SearchCount
+	stored.EmptySearches = media.EmptySearches
+	stored.NoResultsSince = media.NoResultsSince
+	stored.LastSearchedAt = media.LastSearchedAt
+	if err := db.store.Update(media.ID, &stored); err != nil {
+		return err
+	}
+
+	attempts, err := db.GetSearchAttempts(media.ID)
+	if err != nil || len(attempts) <= maxSearchAttempts {
+		return err
+	}
+	for _, old := range attempts[maxSearchAttempts:] {
+		if err := db.store.Delete(old.ID, &SearchAttempt{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSearchAttempts retrieves the search history of a media item, newest first
+func (db *Database) GetSearchAttempts(mediaID uint64) ([]*SearchAttempt, error) {
+	var attempts []*SearchAttempt
+	err := db.store.Find(&attempts, bolthold.Where("MediaID").Eq(mediaID).Index("MediaID").SortBy("ID").Reverse())
+	return attempts, err
+}
+
+// RecordRejections stores the releases a search attempt dropped
+// Only the rejections of the last maxRejectedSearches attempts of the media item are kept.
+func (db *Database) RecordRejections(attempt *SearchAttempt, rejections []*Rejection) error {
+	for _, rejection := range rejections {
+		rejection.MediaID = attempt.MediaID
+		rejection.AttemptID = attempt.ID
+		rejection.At = attempt.At
+		if err := db.store.Insert(bolthold.NextSequence(), rejection); err != nil {
+			return err
+		}
+	}
+
+	attempts, err := db.GetSearchAttempts(attempt.MediaID)
+	if err != nil || len(attempts) <= maxRejectedSearches {
+		return err
+	}
+	oldest := attempts[maxRejectedSearches-1].ID
+	return db.store.DeleteMatching(&Rejection{},
+		bolthold.Where("MediaID").Eq(attempt.MediaID).Index("MediaID").
+			And("AttemptID").Lt(oldest))
+}
+
+// GetRejections retrieves the releases dropped by the last searches of a media item, newest first
+func (db *Database) GetRejections(mediaID uint64) ([]*Rejection, error) {
+	var rejections []*Rejection
+	err := db.store.Find(&rejections, bolthold.Where("MediaID").Eq(mediaID).Index("MediaID").SortBy("ID").Reverse())
+	return rejections, err
+}
+
+// GetAllRejections retrieves the releases dropped by the last searches of every media item, newest first
+func (db *Database) GetAllRejections() ([]*Rejection, error) {
+	var rejections []*Rejection
+	err := db.store.Find(&rejections, (&bolthold.Query{}).SortBy("ID").Reverse())
+	return rejections, err
+}
+
+// Hook run operations
+
+// RecordHookRun stores a script hook run, the oldest runs beyond maxHookRuns are removed
+func (db *Database) RecordHookRun(run *HookRun) error {
+	if err := db.store.Insert(bolthold.NextSequence(), run); err != nil {
+		return err
+	}
+
+	runs, err := db.GetHookRuns(0)
+	if err != nil || len(runs) <= maxHookRuns {
+		return err
+	}
+	for _, old := range runs[maxHookRuns:] {
+		if err := db.store.Delete(old.ID, &HookRun{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHookRuns retrieves the script hook history, newest first, limit 0 returns every run
+func (db *Database) GetHookRuns(limit int) ([]*HookRun, error) {
+	query := (&bolthold.Query{}).SortBy("ID").Reverse()
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var runs []*HookRun
+	err := db.store.Find(&runs, query)
+	return runs, err
+}
+
+// Storage quota operations
+
+// RecordQuotaAction stores a storage quota action, the oldest actions beyond maxQuotaActions are removed
+func (db *Database) RecordQuotaAction(action *QuotaAction) error {
+	if err := db.store.Insert(bolthold.NextSequence(), action); err != nil {
+		return err
+	}
+
+	actions, err := db.GetQuotaActions(0)
+	if err != nil || len(actions) <= maxQuotaActions {
+		return err
+	}
+	for _, old := range actions[maxQuotaActions:] {
+		if err := db.store.Delete(old.ID, &QuotaAction{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetQuotaActions retrieves the storage quota history, newest first, limit 0 returns every action
+func (db *Database) GetQuotaActions(limit int) ([]*QuotaAction, error) {
+	query := (&bolthold.Query{}).SortBy("ID").Reverse()
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var actions []*QuotaAction
+	err := db.store.Find(&actions, query)
+	return actions, err
+}
+
+// Quarantine operations
+
+// RecordQuarantineItem stores a download moved to the quarantine directory
+func (db *Database) RecordQuarantineItem(item *QuarantineItem) error {
+	return db.store.Insert(bolthold.NextSequence(), item)
+}
+
+// GetQuarantineItems retrieves the quarantined downloads, newest first
+func (db *Database) GetQuarantineItems() ([]*QuarantineItem, error) {
+	var items []*QuarantineItem
+	err := db.store.Find(&items, (&bolthold.Query{}).SortBy("ID").Reverse())
+	return items, err
+}
+
+// GetQuarantineItem retrieves a quarantined download by ID
+func (db *Database) GetQuarantineItem(id uint64) (*QuarantineItem, error) {
+	var item QuarantineItem
+	if err := db.store.Get(id, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// DeleteQuarantineItem removes a quarantined download record
+func (db *Database) DeleteQuarantineItem(id uint64) error {
+	return db.store.Delete(id, &QuarantineItem{})
+}
+
+// Grab ramp operations
+
+// GetGrabRamp retrieves the cold-start state, nil if it was never recorded
+func (db *Database) GetGrabRamp() (*GrabRamp, error) {
+	var ramp GrabRamp
+	err := db.store.Get(grabRampKey, &ramp)
+	if err == bolthold.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ramp, nil
+}
+
+// SaveGrabRamp records the cold-start state
+func (db *Database) SaveGrabRamp(ramp *GrabRamp) error {
+	ramp.Key = grabRampKey
+	return db.store.Upsert(grabRampKey, ramp)
+}
+
+// HasNZBs checks if any release was ever recorded, i.e. the install is not fresh
+func (db *Database) HasNZBs() (bool, error) {
+	count, err := db.store.Count(&NZB{}, nil)
+	return count > 0, err
+}
+
+// Scheduler pause operations
+
+// GetSchedulerPause retrieves the pause state, nil if the scheduler was never paused
+func (db *Database) GetSchedulerPause() (*SchedulerPause, error) {
+	var pause SchedulerPause
+	err := db.store.Get(schedulerPauseKey, &pause)
+	if err == bolthold.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pause, nil
+}
+
+// SaveSchedulerPause records the pause state
+func (db *Database) SaveSchedulerPause(pause *SchedulerPause) error {
+	pause.Key = schedulerPauseKey
+	return db.store.Upsert(schedulerPauseKey, pause)
+}
+
+// Webhook dedup operations
+
+// ClaimWebhook records the key of a webhook event about to be processed
+// Returns false if the key was already recorded less than ttl ago. Older keys are removed.
+func (db *Database) ClaimWebhook(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	if err := db.store.DeleteMatching(&ProcessedWebhook{}, bolthold.Where("ProcessedAt").Lt(now.Add(-ttl))); err != nil {
+		return false, err
+	}
+
+	err := db.store.Insert(key, &ProcessedWebhook{Key: key, ProcessedAt: now})
+	if err == bolthold.ErrKeyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseWebhook removes the key of a webhook event, so a new delivery is processed
+func (db *Database) ReleaseWebhook(key string) error {
+	err := db.store.Delete(key, &ProcessedWebhook{})
+	if err == bolthold.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Trakt collection operations
+
+// ReplaceCollection replaces the stored Trakt collection with the items of the last sync
+// in one transaction, so strategies never see a partial collection
+func (db *Database) ReplaceCollection(items []*CollectedItem) error {
+	return db.store.Bolt().Update(func(tx *bbolt.Tx) error {
+		if err := db.store.TxDeleteMatching(tx, &CollectedItem{}, nil); err != nil {
+			return err
+		}
+		for _, item := range items {
+			item.Key = WatchedKey(item.IMDBId, item.Season, item.Episode)
+			if err := db.store.TxUpsert(tx, item.Key, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// IsCollected checks if a movie (season and episode 0) or an episode is in the Trakt collection
+func (db *Database) IsCollected(imdbID string, season, episode int) (bool, error) {
+	var item CollectedItem
+	err := db.store.Get(WatchedKey(imdbID, season, episode), &item)
+	if err == bolthold.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Availability operations
+
+// RecordAvailability stores the availability of an imported release
+func (db *Database) RecordAvailability(record *AvailabilityRecord) error {
+	return db.store.Insert(bolthold.NextSequence(), record)
+}
+
+// GetAvailabilityRecords retrieves the availability of the releases imported since a time
+func (db *Database) GetAvailabilityRecords(since time.Time) ([]*AvailabilityRecord, error) {
+	var records []*AvailabilityRecord
+	err := db.store.Find(&records, bolthold.Where("ImportedAt").Ge(since).SortBy("ID"))
+	return records, err
+}
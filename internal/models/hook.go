@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// maxHookRuns bounds the script hook history
+const maxHookRuns = 200
+
+// HookRun records a run of a script hook
+type HookRun struct {
+	ID        uint64 `boltholdKey:"ID"`
+	Hook      string
+	Stage     string
+	MediaID   uint64 // 0 for runs not tied to a media item (cycle-complete)
+	StartedAt time.Time
+	Duration  time.Duration
+	ExitCode  int    // -1 when the command didn't exit on its own (timeout, not found)
+	Output    string // Combined stdout and stderr, truncated
+	Error     string // Set when the hook failed
+	Aborted   bool   // The failure cancelled the stage under the abort policy
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// WatchedEntry records a movie or episode that was watched and cleaned up
+// A Trakt progress reset makes them look unwatched again, the ledger keeps them
+// from being downloaded a second time.
+type WatchedEntry struct {
+	Key       string `boltholdKey:"Key"`
+	IMDBId    string `boltholdIndex:"IMDBId"`
+	MediaType MediaType
+	Title     string
+	Season    int // 0 for movies
+	Episode   int // 0 for movies
+	WatchedAt time.Time
+	CleanedAt time.Time
+}
+
+// WatchedKey builds the ledger key of a movie (season and episode 0) or an episode
+func WatchedKey(imdbID string, season, episode int) string {
+	if season == 0 && episode == 0 {
+		return imdbID
+	}
+	return fmt.Sprintf("%s:S%02dE%02d", imdbID, season, episode)
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LibraryFile records a movie or episode file found in the media library
+// Files imported from an existing library (e.g. after migrating from Sonarr/Radarr)
+// are not downloaded again.
+type LibraryFile struct {
+	Key       string `boltholdKey:"Key"` // Same format as the watched ledger, see WatchedKey
+	IMDBId    string `boltholdIndex:"IMDBId"`
+	MediaID   uint64
+	MediaType MediaType
+	Season    int // 0 for movies
+	Episode   int // 0 for movies
+	Path      string
+	Size      int64
+	ScannedAt time.Time
+}
@@ -11,22 +11,94 @@ type Media struct {
 	Title     string
 	Year      int
 
+	// Title in the metadata language, used by title searches and matches (empty to use Title)
+	SearchTitle         string
+	SearchTitleLanguage string // Language SearchTitle was resolved for, empty if never resolved
+
 	// TV Show specific fields
 	SeasonNumber  *int // nil for movies
 	EpisodeNumber *int // nil for movies/seasons
 
+	// Parent show for episode-level media (e.g. re-search of a damaged season pack file)
+	ParentID uint64 `boltholdIndex:"ParentID"`
+
 	// Tracking
-	Source  Source // "favorites" or "watchlist"
+	Source  Source // "favorites", "watchlist", "list" or "manual"
 	Status  Status // "pending", "searching", "downloading", "completed", "failed"
 	Watched bool
 
+	// Custom Trakt list the media comes from and the episode strategy it uses (Source "list")
+	List            string
+	EpisodeStrategy EpisodeStrategy
+
+	// Location in the media library (file or folder), empty if not on disk
+	Path string
+
+	// Per-item overrides parsed from Trakt list item notes
+	Overrides MediaOverrides
+
 	// Trakt presence tracking (for cleanup of removed items)
 	InTrakt         bool      `boltholdIndex:"InTrakt"` // Currently in Trakt lists?
 	LastSeenInTrakt time.Time // Last seen during Trakt sync
 
+	// Removed from the Trakt watchlist by gomenarr once downloaded: kept until watched
+	// instead of being cleaned up as removed from Trakt
+	RemovedFromWatchlist bool
+
+	// Search history summary, the attempts themselves are SearchAttempt records
+	SearchCount    int
+	EmptySearches  int        // Consecutive searches without candidates
+	NoResultsSince *time.Time // First of the consecutive empty searches
+	AirsAt         *time.Time // Air time of the next episode of a show held back by the air offset
+
 	// Metadata
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	LastSearchedAt *time.Time
 	CompletedAt    *time.Time
 }
+
+// MediaOverrides holds per-item settings parsed from Trakt list item notes
+// e.g. "quality=720p lang=fr pack=never packupgrade=on anime=on"
+type MediaOverrides struct {
+	Quality     string          // Required quality tier or title tag (e.g. "720p", "remux")
+	Language    string          // Required language code (e.g. "fr")
+	Pack        PackPolicy      // Season pack usage for TV shows
+	PackUpgrade PackUpgrade     // Season pack upgrade of finished seasons, overriding SEASON_PACK_UPGRADE
+	Profile     string          // Named quality profile (e.g. "casual"), empty for the media type default
+	Strategy    EpisodeStrategy // Episode strategy of the show, overriding the one of its list
+	Anime       bool            // Episodes are released with absolute numbers (e.g. "One Piece - 1071")
+}
+
+// ShowStrategy returns the episode strategy of a show, the notes override taking precedence
+// Empty means the default strategy of the media source.
+func (m *Media) ShowStrategy() EpisodeStrategy {
+	if m.Overrides.Strategy != "" {
+		return m.Overrides.Strategy
+	}
+	return m.EpisodeStrategy
+}
+
+// WantsPackUpgrade checks if the episodes of a finished season of a show are replaced by
+// a season pack, the notes override taking precedence over the global setting
+func (m *Media) WantsPackUpgrade(enabled bool) bool {
+	if m.Overrides.Pack == PackPolicyNever {
+		return false
+	}
+	switch m.Overrides.PackUpgrade {
+	case PackUpgradeOn:
+		return true
+	case PackUpgradeOff:
+		return false
+	}
+	return enabled
+}
+
+// QueryTitle returns the title used in title searches: the one in the metadata language,
+// the Trakt title if it has none
+func (m *Media) QueryTitle() string {
+	if m.SearchTitle != "" {
+		return m.SearchTitle
+	}
+	return m.Title
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/timshannon/bolthold"
+)
+
+// Migration records a one-time data migration applied to the database
+type Migration struct {
+	Name      string `boltholdKey:"Name"`
+	AppliedAt time.Time
+}
+
+// migration is a named one-time data migration
+type migration struct {
+	name string
+	run  func(db *Database) error
+}
+
+// migrations lists all data migrations in the order they must be applied
+var migrations = []migration{
+	{name: "nzb_dedupe_v1", run: dedupeNZBs},
+}
+
+// migrate applies all pending migrations
+func (db *Database) migrate() error {
+	for _, m := range migrations {
+		var applied Migration
+		err := db.store.Get(m.name, &applied)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, bolthold.ErrNotFound) {
+			return fmt.Errorf("failed to check migration %s: %w", m.name, err)
+		}
+
+		if err := m.run(db); err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+
+		if err := db.store.Insert(m.name, &Migration{Name: m.name, AppliedAt: time.Now()}); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// NZBDedupeKey builds the unique key of a release for a media item
+// The GUID identifies the release, falling back to the link then the title
+func NZBDedupeKey(mediaID uint64, guid, link, title string) string {
+	identity := guid
+	if identity == "" {
+		identity = link
+	}
+	if identity == "" {
+		identity = title
+	}
+
+	sum := sha1.Sum([]byte(identity))
+	return fmt.Sprintf("%d:%s", mediaID, hex.EncodeToString(sum[:]))
+}
+
+// nzbStatusRank orders NZB statuses by how much state they carry
+// Used to decide which duplicate to keep
+func nzbStatusRank(status NZBStatus) int {
+	switch status {
+	case NZBStatusCompleted:
+		return 5
+	case NZBStatusDownloading:
+		return 4
+	case NZBStatusSelected:
+		return 3
+	case NZBStatusFailed:
+		return 2
+	case NZBStatusBlacklisted:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// dedupeNZBs removes duplicate NZB records and assigns dedupe keys to the remaining ones
+func dedupeNZBs(db *Database) error {
+	var nzbs []*NZB
+	if err := db.store.Find(&nzbs, nil); err != nil {
+		return err
+	}
+
+	groups := make(map[string][]*NZB)
+	for _, nzb := range nzbs {
+		key := NZBDedupeKey(nzb.MediaID, nzb.GUID, nzb.Link, nzb.Title)
+		groups[key] = append(groups[key], nzb)
+	}
+
+	for key, group := range groups {
+		// Keep the record with the most state, oldest first on ties
+		sort.Slice(group, func(i, j int) bool {
+			rankI, rankJ := nzbStatusRank(group[i].Status), nzbStatusRank(group[j].Status)
+			if rankI != rankJ {
+				return rankI > rankJ
+			}
+			return group[i].ID < group[j].ID
+		})
+
+		for _, duplicate := range group[1:] {
+			if err := db.store.Delete(duplicate.ID, &NZB{}); err != nil {
+				return err
+			}
+		}
+
+		keep := group[0]
+		keep.DedupeKey = key
+		if err := db.store.Update(keep.ID, keep); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/timshannon/bolthold"
+	"go.etcd.io/bbolt"
+)
+
+// insertLegacyNZB writes an NZB the way releases were stored before dedupe keys,
+// without going through the unique index
+func insertLegacyNZB(t *testing.T, db *Database, nzb *NZB) {
+	err := db.store.Bolt().Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("NZB"))
+		if err != nil {
+			return err
+		}
+		if nzb.ID, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+		key, err := bolthold.DefaultEncode(nzb.ID)
+		if err != nil {
+			return err
+		}
+		value, err := bolthold.DefaultEncode(nzb)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, value)
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert NZB: %v", err)
+	}
+}
+
+func TestDedupeNZBs(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Records written before dedupe keys existed, the same release found several times
+	for _, nzb := range []*NZB{
+		{MediaID: 1, GUID: "guid-a", Title: "Release.A", Status: NZBStatusCandidate},
+		{MediaID: 1, GUID: "guid-a", Title: "Release.A", Status: NZBStatusCompleted},
+		{MediaID: 1, GUID: "guid-a", Title: "Release.A", Status: NZBStatusFailed},
+		{MediaID: 1, Link: "https://indexer/b", Title: "Release.B", Status: NZBStatusCandidate},
+		{MediaID: 1, Link: "https://indexer/b", Title: "Release.B", Status: NZBStatusCandidate},
+		{MediaID: 2, GUID: "guid-a", Title: "Release.A", Status: NZBStatusCandidate},
+	} {
+		insertLegacyNZB(t, db, nzb)
+	}
+	if err := db.store.Delete("nzb_dedupe_v1", &Migration{}); err != nil {
+		t.Fatalf("Failed to reset migration: %v", err)
+	}
+
+	if err := db.migrate(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	var nzbs []*NZB
+	if err := db.store.Find(&nzbs, nil); err != nil {
+		t.Fatalf("Failed to list NZBs: %v", err)
+	}
+	keys := make(map[string]*NZB)
+	for _, nzb := range nzbs {
+		if nzb.DedupeKey == "" {
+			t.Errorf("NZB %d has no dedupe key", nzb.ID)
+		}
+		if keys[nzb.DedupeKey] != nil {
+			t.Errorf("Duplicate NZB for key %s", nzb.DedupeKey)
+		}
+		keys[nzb.DedupeKey] = nzb
+	}
+	if len(keys) != 3 {
+		t.Errorf("Expected 3 NZBs after dedupe, got %d", len(keys))
+	}
+	if kept := keys[NZBDedupeKey(1, "guid-a", "", "")]; kept == nil || kept.Status != NZBStatusCompleted {
+		t.Errorf("Expected the completed duplicate to be kept, got %+v", kept)
+	}
+
+	// The same releases found again are merged into the stored records
+	completed := &NZB{MediaID: 1, GUID: "guid-a", Title: "Release.A.REPACK", Status: NZBStatusCandidate}
+	if err := db.CreateNZB(completed); err != nil {
+		t.Fatalf("CreateNZB failed: %v", err)
+	}
+	if completed.Status != NZBStatusCompleted || completed.Title != "Release.A" {
+		t.Errorf("Expected the completed record to keep its state, got %s %s", completed.Status, completed.Title)
+	}
+	candidate := &NZB{MediaID: 1, Link: "https://indexer/b", Title: "Release.B.PROPER", Status: NZBStatusCandidate}
+	if err := db.CreateNZB(candidate); err != nil {
+		t.Fatalf("CreateNZB failed: %v", err)
+	}
+	if candidate.Title != "Release.B.PROPER" || candidate.ID != keys[candidate.DedupeKey].ID {
+		t.Errorf("Expected the candidate record to be refreshed, got %d %s", candidate.ID, candidate.Title)
+	}
+
+	count, err := db.store.Count(&NZB{}, nil)
+	if err != nil {
+		t.Fatalf("Failed to count NZBs: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 NZBs after re-inserting, got %d", count)
+	}
+}
+
+func TestCreateNZBKeepsSelectedAndBlacklisted(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for _, status := range []NZBStatus{NZBStatusSelected, NZBStatusBlacklisted} {
+		guid := "guid-" + string(status)
+		stored := &NZB{MediaID: 1, GUID: guid, Title: "Release", Status: status, BlacklistMatch: "cam"}
+		if err := db.CreateNZB(stored); err != nil {
+			t.Fatalf("CreateNZB failed: %v", err)
+		}
+
+		// Found again by an interactive search
+		found := &NZB{MediaID: 1, GUID: guid, Title: "Release.PROPER", Status: NZBStatusCandidate}
+		if err := db.CreateNZB(found); err != nil {
+			t.Fatalf("CreateNZB failed: %v", err)
+		}
+		if found.Status != status || found.BlacklistMatch != "cam" {
+			t.Errorf("Expected the %s release to keep its status, got %s", status, found.Status)
+		}
+		if found.Title != "Release.PROPER" {
+			t.Errorf("Expected the %s release to be refreshed, got %s", status, found.Title)
+		}
+	}
+}
@@ -14,6 +14,23 @@ type NZB struct {
 	Size    int64   // bytes
 	Quality Quality
 	Year    int // Extracted from NZB title (for movies)
+	Indexer string // Indexer that returned this release
+	Score   int    // Indexer-weighted health score (torrent seeders/freeleech)
+
+	// Resolution/source/codec score from the media quality profile
+	QualityScore int
+
+	// Found by a search widened after repeated empty searches, the profile resolutions not applied
+	Widened bool
+
+	// Protocol of the release (empty for records created before torrent support, treated as usenet)
+	Protocol Protocol
+
+	// Same release found on less preferred indexers, tried when the link can't be fetched
+	Alternates []NZBAlternate
+
+	// Unique per media and release (GUID/link hash), prevents duplicate candidates across searches
+	DedupeKey string `boltholdUnique:"DedupeKey"`
 
 	// Download tracking
 	TorBoxJobID   string    `boltholdIndex:"TorBoxJobID"`
@@ -21,6 +38,13 @@ type NZB struct {
 	Status        NZBStatus `boltholdIndex:"Status"`
 	RetryCount    int
 	FailureReason string
+	NextRetryAt   *time.Time // Set on a failed release while the retry with the next candidate waits out its backoff
+
+	// Upgrade of a completed release: ID of the NZB it replaces once downloaded, 0 otherwise
+	Replaces uint64
+
+	// Season pack upgrade: IDs of the completed episode NZBs it replaces once downloaded
+	ReplacesEpisodes []uint64
 
 	// Blacklist check
 	BlacklistMatch string // Which blacklist term matched (if any)
@@ -28,20 +52,78 @@ type NZB struct {
 	// Episode/Season tracking (parsed from NZB title)
 	Season       *int // Season number (for individual episodes AND season packs)
 	Episode      *int // Episode number (nil for season packs)
+	LastEpisode  *int // Last episode of a multi-episode release (S01E01E02, S01E01-E03), nil otherwise
 	IsSeasonPack bool
 
 	// Season pack episode list (populated from Trakt API when IsSeasonPack=true)
 	Episodes []EpisodeInfo // Episodes in this pack (from Trakt)
 
 	// Metadata
+	PublishedAt  *time.Time // Post date on the indexer, nil if unknown
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	DownloadedAt *time.Time
 }
 
+// NZBAlternate is another indexer offering the same release
+type NZBAlternate struct {
+	Indexer  string
+	Link     string
+	GUID     string
+	Protocol Protocol
+}
+
 // EpisodeInfo tracks individual episodes in a season pack
 type EpisodeInfo struct {
 	EpisodeNumber int
 	Watched       bool
 	WatchedAt     *time.Time
+	Failed        bool // File was infected/corrupt in the TorBox download
+}
+
+// IsUpgrade reports whether the release replaces completed releases once downloaded
+func (n *NZB) IsUpgrade() bool {
+	return n.Replaces != 0 || len(n.ReplacesEpisodes) > 0
+}
+
+// IsTorrent reports whether the release is downloaded through TorBox's torrent API
+func (n *NZB) IsTorrent() bool {
+	return n.Protocol == ProtocolTorrent
+}
+
+// EpisodeNumbers returns the episodes of its season a release covers, nil if unknown
+func (n *NZB) EpisodeNumbers() []int {
+	if n.IsSeasonPack {
+		numbers := make([]int, 0, len(n.Episodes))
+		for _, ep := range n.Episodes {
+			numbers = append(numbers, ep.EpisodeNumber)
+		}
+		return numbers
+	}
+	if n.Episode == nil {
+		return nil
+	}
+
+	last := *n.Episode
+	if n.LastEpisode != nil && *n.LastEpisode > last {
+		last = *n.LastEpisode
+	}
+	numbers := make([]int, 0, last-*n.Episode+1)
+	for episode := *n.Episode; episode <= last; episode++ {
+		numbers = append(numbers, episode)
+	}
+	return numbers
+}
+
+// Covers reports whether a release covers an episode
+func (n *NZB) Covers(season, episode int) bool {
+	if n.Season == nil || *n.Season != season {
+		return false
+	}
+	for _, number := range n.EpisodeNumbers() {
+		if number == episode {
+			return true
+		}
+	}
+	return false
 }
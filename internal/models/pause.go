@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// schedulerPauseKey is the key of the single SchedulerPause record
+const schedulerPauseKey = "scheduler_pause"
+
+// SchedulerPause records that the automatic tasks were paused through the API
+// Kept in the database so a restart during maintenance doesn't resume them.
+type SchedulerPause struct {
+	Key       string `boltholdKey:"Key"`
+	Paused    bool
+	Reason    string
+	PausedAt  *time.Time
+	ResumedAt *time.Time
+}
@@ -0,0 +1,46 @@
+package models
+
+// QualityProfile describes which releases are acceptable for a media item and how they rank
+// Resolutions are vertical line counts (2160, 1080, 720, ...), 0 means unset.
+type QualityProfile struct {
+	Name                string
+	PreferredResolution int               // Ranked above every other resolution
+	MinResolution       int               // Releases below are rejected
+	MaxResolution       int               // Releases above are rejected
+	Sources             []string          // Preferred release sources, best first (remux, bluray, web-dl, webrip, hdtv, dvd)
+	Codecs              []string          // Preferred video codecs, best first (x265, x264, av1)
+	PreferredGroups     []string          // Preferred release groups, best first, uppercase (e.g. FLUX, NTB)
+	AvoidedGroups       []string          // Release groups ranked below every other group, uppercase (e.g. YIFY)
+	Sizes               map[int]SizeLimit // Size limits by resolution
+	Weights             ScoringWeights
+
+	// Languages are ISO 639-1 codes, untagged releases count as English (en)
+	Languages         []string // Preferred audio languages, best first
+	RequiredLanguages []string // Releases in none of these are rejected (multi-language releases match all)
+	RejectedLanguages []string // Releases dubbed in one of these are rejected
+	Subtitles         []string // Preferred subtitle languages of original audio releases (e.g. VOSTFR), best first
+}
+
+// SizeLimit bounds the size of the releases of one resolution, in bytes, 0 means unset
+// Sizes are per movie or per episode, season packs are split over their episodes.
+type SizeLimit struct {
+	Min       int64 // Smaller releases are rejected (fakes, samples)
+	Max       int64 // Larger releases are rejected
+	Preferred int64 // Releases closest to this size rank first
+}
+
+// ScoringWeights multiply the resolution, source, release group, codec, size, language and
+// subtitle parts of a release score. The zero value stands for DefaultScoringWeights.
+type ScoringWeights struct {
+	Resolution int
+	Source     int
+	Group      int
+	Codec      int
+	Size       int
+	Language   int
+	Subtitle   int
+}
+
+// DefaultScoringWeights rank resolution over source over release group over codec over size,
+// a preferred language weighs a resolution step and a preferred subtitle a tenth of one
+var DefaultScoringWeights = ScoringWeights{Resolution: 10000, Source: 100, Group: 10, Codec: 3, Size: 1, Language: 100000, Subtitle: 1000}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Quarantine reasons, why an import rejected a completed download
+const (
+	QuarantineSampleOnly   = "sample_only"   // Only sample videos in the download
+	QuarantineWrongContent = "wrong_content" // No video or no file of the expected episode
+	QuarantineCorrupt      = "corrupt"       // A copy failed checksum verification
+)
+
+// QuarantineItem records a download moved to the quarantine directory by an import
+type QuarantineItem struct {
+	ID      uint64 `boltholdKey:"ID"`
+	MediaID uint64
+	NZBID   uint64
+	Title   string // Media the release was downloaded for
+	Release string
+	Reason  string
+	Detail  string // Error of the import
+	Path    string // File or folder of the download in the quarantine directory
+	Size    int64
+	At      time.Time
+}
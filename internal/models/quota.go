@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// maxQuotaActions bounds the storage quota history
+const maxQuotaActions = 200
+
+// Storage quota actions
+const (
+	QuotaActionSkipped = "skipped" // The release was not downloaded
+	QuotaActionSmaller = "smaller" // A smaller release of the same media was downloaded instead
+	QuotaActionEvicted = "evicted" // A watched media item was deleted to make room
+)
+
+// QuotaAction records a grab that would have exceeded a storage quota
+type QuotaAction struct {
+	ID      uint64 `boltholdKey:"ID"`
+	Quota   string
+	Action  string
+	MediaID uint64
+	Title   string // Media the release was grabbed for
+	Release string // Release exceeding the quota
+	Size    int64
+	Used    int64 // Library size before the grab
+	Limit   int64
+	Detail  string // Release picked instead or media deleted
+	At      time.Time
+}
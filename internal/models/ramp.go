@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// grabRampKey is the key of the single GrabRamp record
+const grabRampKey = "grab_ramp"
+
+// GrabRamp tracks the cold-start protection of a fresh install
+// The first search cycles grab a limited number of releases, the limit doubling
+// every cycle, so a large watchlist doesn't flood the indexers and TorBox at once.
+type GrabRamp struct {
+	Key         string `boltholdKey:"Key"`
+	Active      bool   // False once the backlog is caught up, confirmed, or for existing installs
+	Cycles      int    // Search cycles run during the ramp-up
+	Grabs       int    // Releases grabbed during the ramp-up
+	StartedAt   time.Time
+	EndedAt     *time.Time
+	ConfirmedAt *time.Time // Set when the limit was lifted on request
+}
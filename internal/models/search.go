@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// maxSearchAttempts bounds the search history kept per media item
+const maxSearchAttempts = 50
+
+// maxRejectedSearches bounds the searches whose rejected releases are kept per media item
+const maxRejectedSearches = 5
+
+// Reasons a release found by a search was not kept as a candidate
+const (
+	RejectBlacklist = "blacklist" // Title matches a blacklist term (kept as a blacklisted NZB)
+	RejectOverride  = "override"  // Fails a Trakt note override (quality, language, packs)
+	RejectProfile   = "profile"   // Outside the resolutions of the quality profile
+	RejectYear      = "year"      // Movie release of another year
+	RejectSize      = "size"      // Outside the size limits of the quality profile
+	RejectAge       = "age"       // Posted too recently, or beyond the retention or age limit of the indexer
+)
+
+// SearchAttempt records a search of a media item
+type SearchAttempt struct {
+	ID         uint64 `boltholdKey:"ID"`
+	MediaID    uint64 `boltholdIndex:"MediaID"`
+	At         time.Time
+	Results    int    // Releases returned by the indexers
+	Candidates int    // Releases kept after filtering
+	Rejected   int    // Releases dropped by filtering, see Rejection
+	Error      string // Set when the search failed, failed searches don't count as empty
+	Widened    bool   // Searched by title too and outside the profile resolutions, after repeated empty searches
+}
+
+// Rejection records a release dropped by a search and why
+type Rejection struct {
+	ID           uint64 `boltholdKey:"ID"`
+	MediaID      uint64 `boltholdIndex:"MediaID"`
+	AttemptID    uint64 // Search attempt that found the release
+	At           time.Time
+	Title        string
+	Indexer      string
+	Size         int64
+	Reason       string // One of the Reject* reasons
+	Detail       string // e.g. the blacklist term or the failed profile rule
+	QualityScore int    // Score under the quality profile, 0 when rejected before scoring
+}
+
+// RecordSearchAttempt updates the search counters of a media item with an attempt
+func (m *Media) RecordSearchAttempt(attempt *SearchAttempt) {
+	at := attempt.At
+	m.LastSearchedAt = &at
+	m.SearchCount++
+	if attempt.Error != "" {
+		return
+	}
+
+	if attempt.Candidates > 0 {
+		m.EmptySearches = 0
+		m.NoResultsSince = nil
+		return
+	}
+	if m.EmptySearches == 0 {
+		m.NoResultsSince = &at
+	}
+	m.EmptySearches++
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRecordSearch(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	media := &Media{Title: "Test", Status: StatusPending}
+	if err := db.CreateMedia(media); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+
+	start := time.Now()
+	record := func(attempt *SearchAttempt) {
+		media.RecordSearchAttempt(attempt)
+		if err := db.RecordSearch(media, attempt); err != nil {
+			t.Fatalf("Failed to record search: %v", err)
+		}
+	}
+
+	record(&SearchAttempt{At: start, Candidates: 0})
+	record(&SearchAttempt{At: start.Add(time.Hour), Error: "indexers down"})
+	record(&SearchAttempt{At: start.Add(2 * time.Hour), Candidates: 0})
+
+	stored, err := db.GetMediaByID(media.ID)
+	if err != nil {
+		t.Fatalf("Failed to get media: %v", err)
+	}
+	if stored.SearchCount != 3 || stored.EmptySearches != 2 {
+		t.Errorf("Expected 3 searches and 2 empty ones, got %d and %d", stored.SearchCount, stored.EmptySearches)
+	}
+	if stored.NoResultsSince == nil || !stored.NoResultsSince.Equal(start) {
+		t.Errorf("Expected no results since %v, got %v", start, stored.NoResultsSince)
+	}
+
+	for i := 0; i < maxSearchAttempts; i++ {
+		record(&SearchAttempt{At: start.Add(3 * time.Hour), Candidates: 4})
+	}
+	attempts, err := db.GetSearchAttempts(media.ID)
+	if err != nil {
+		t.Fatalf("Failed to get attempts: %v", err)
+	}
+	if len(attempts) != maxSearchAttempts || attempts[0].ID < attempts[len(attempts)-1].ID {
+		t.Errorf("Expected %d attempts newest first, got %d", maxSearchAttempts, len(attempts))
+	}
+	if media.EmptySearches != 0 || media.NoResultsSince != nil {
+		t.Error("Search with candidates should reset the empty search counters")
+	}
+}
+
+func TestRecordRejections(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	media := &Media{Title: "Test", Status: StatusPending}
+	if err := db.CreateMedia(media); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+
+	for i := 0; i < maxRejectedSearches+2; i++ {
+		attempt := &SearchAttempt{At: time.Now(), Rejected: 2}
+		media.RecordSearchAttempt(attempt)
+		if err := db.RecordSearch(media, attempt); err != nil {
+			t.Fatalf("Failed to record search: %v", err)
+		}
+		rejections := []*Rejection{
+			{Title: "Test.2019.1080p", Reason: RejectYear},
+			{Title: "Test.CAM", Reason: RejectBlacklist, Detail: "CAM"},
+		}
+		if err := db.RecordRejections(attempt, rejections); err != nil {
+			t.Fatalf("Failed to record rejections: %v", err)
+		}
+	}
+
+	rejections, err := db.GetRejections(media.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rejections: %v", err)
+	}
+	if len(rejections) != 2*maxRejectedSearches {
+		t.Fatalf("Expected the rejections of %d searches, got %d records", maxRejectedSearches, len(rejections))
+	}
+	attempts, err := db.GetSearchAttempts(media.ID)
+	if err != nil {
+		t.Fatalf("Failed to get attempts: %v", err)
+	}
+	if rejections[0].AttemptID != attempts[0].ID || rejections[0].MediaID != media.ID {
+		t.Errorf("Expected the newest rejection to belong to attempt %d, got %d", attempts[0].ID, rejections[0].AttemptID)
+	}
+
+	if err := db.DeleteMedia(media.ID); err != nil {
+		t.Fatalf("Failed to delete media: %v", err)
+	}
+	if rejections, _ := db.GetRejections(media.ID); len(rejections) != 0 {
+		t.Errorf("Expected rejections to be deleted with the media, got %d", len(rejections))
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/amaumene/gomenarr/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidTransition is returned when a status update is not allowed by the state machine
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// InvalidTransitions counts the status updates rejected by the state machine
+var InvalidTransitions = metrics.NewCounter("gomenarr_invalid_status_transitions_total",
+	"Status updates rejected by the media/NZB state machine.", "kind", "from", "to")
+
+// mediaTransitions lists the statuses a media item can move to from each status
+// Completed media never go back to searching or failed: a late failure webhook or a
+// stuck check must not undo a download that already succeeded.
+var mediaTransitions = map[Status][]Status{
+	StatusPending:     {StatusSearching, StatusDownloading, StatusCompleted, StatusFailed},
+	StatusSearching:   {StatusPending, StatusDownloading, StatusCompleted, StatusFailed},
+	StatusDownloading: {StatusPending, StatusCompleted, StatusFailed},
+	StatusCompleted:   {StatusPending, StatusDownloading},
+	StatusFailed:      {StatusPending, StatusSearching, StatusDownloading, StatusCompleted},
+}
+
+// nzbTransitions lists the statuses an NZB can move to from each status
+// A failed NZB can still complete (TorBox finishing after the stuck timeout), a completed
+// one can only be replaced by an upgrade or rejected when its files fail verification. A
+// rejected one completes when its quarantined download is imported anyway.
+var nzbTransitions = map[NZBStatus][]NZBStatus{
+	NZBStatusCandidate:   {NZBStatusSelected, NZBStatusFailed},
+	NZBStatusSelected:    {NZBStatusCandidate, NZBStatusDownloading, NZBStatusFailed},
+	NZBStatusDownloading: {NZBStatusCompleted, NZBStatusFailed},
+	NZBStatusCompleted:   {NZBStatusReplaced, NZBStatusRejected},
+	NZBStatusFailed:      {NZBStatusSelected, NZBStatusDownloading, NZBStatusCompleted},
+	NZBStatusBlacklisted: {NZBStatusCandidate, NZBStatusSelected},
+	NZBStatusReplaced:    {},
+	NZBStatusRejected:    {NZBStatusCompleted},
+}
+
+// CanTransitionMedia checks if a media item can move from one status to another
+// Staying in the same status is always allowed.
+func CanTransitionMedia(from, to Status) bool {
+	return from == to || contains(mediaTransitions[from], to)
+}
+
+// CanTransitionNZB checks if an NZB can move from one status to another
+// Staying in the same status is always allowed.
+func CanTransitionNZB(from, to NZBStatus) bool {
+	return from == to || contains(nzbTransitions[from], to)
+}
+
+// checkMediaTransition validates a media status update against the stored record
+func (db *Database) checkMediaTransition(media *Media) error {
+	var stored Media
+	if err := db.store.Get(media.ID, &stored); err != nil {
+		return nil // Missing records are reported by the update itself
+	}
+	if CanTransitionMedia(stored.Status, media.Status) {
+		return nil
+	}
+	return db.rejectTransition("media", media.ID, string(stored.Status), string(media.Status))
+}
+
+// checkNZBTransition validates an NZB status update against the stored record
+func (db *Database) checkNZBTransition(nzb *NZB) error {
+	var stored NZB
+	if err := db.store.Get(nzb.ID, &stored); err != nil {
+		return nil // Missing records are reported by the update itself
+	}
+	if CanTransitionNZB(stored.Status, nzb.Status) {
+		return nil
+	}
+	return db.rejectTransition("nzb", nzb.ID, string(stored.Status), string(nzb.Status))
+}
+
+// rejectTransition records an illegal transition and builds its error
+func (db *Database) rejectTransition(kind string, id uint64, from, to string) error {
+	InvalidTransitions.Inc(kind, from, to)
+	if db.logger != nil {
+		db.logger.WithFields(logrus.Fields{
+			"kind": kind,
+			"id":   id,
+			"from": from,
+			"to":   to,
+		}).Warn("Rejected invalid status transition")
+	}
+	return fmt.Errorf("%w: %s %d %s -> %s", ErrInvalidTransition, kind, id, from, to)
+}
+
+// contains reports whether a status list holds a status
+func contains[T comparable](list []T, value T) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
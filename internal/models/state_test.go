@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestStatusTransitions(t *testing.T) {
+	if !CanTransitionMedia(StatusDownloading, StatusCompleted) {
+		t.Error("downloading -> completed should be allowed")
+	}
+	if CanTransitionMedia(StatusCompleted, StatusFailed) {
+		t.Error("completed -> failed should be rejected")
+	}
+	if !CanTransitionMedia(StatusCompleted, StatusCompleted) {
+		t.Error("staying completed should be allowed")
+	}
+	if CanTransitionNZB(NZBStatusCompleted, NZBStatusFailed) {
+		t.Error("completed NZB -> failed should be rejected")
+	}
+	if !CanTransitionNZB(NZBStatusFailed, NZBStatusCompleted) {
+		t.Error("failed NZB -> completed should be allowed")
+	}
+	if CanTransitionNZB(NZBStatusReplaced, NZBStatusDownloading) {
+		t.Error("replaced NZB should be final")
+	}
+	if !CanTransitionNZB(NZBStatusCompleted, NZBStatusRejected) {
+		t.Error("completed NZB -> rejected should be allowed")
+	}
+	if CanTransitionNZB(NZBStatusRejected, NZBStatusSelected) {
+		t.Error("rejected NZB should only complete")
+	}
+}
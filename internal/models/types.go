@@ -8,12 +8,23 @@ const (
 	MediaTypeTV    MediaType = "tv"
 )
 
-// Source represents where the media came from (favorites or watchlist)
+// Source represents where the media came from (favorites, watchlist or added through the API)
 type Source string
 
 const (
 	SourceFavorites Source = "favorites"
 	SourceWatchlist Source = "watchlist"
+	SourceManual    Source = "manual" // Added through the API, not managed by Trakt sync
+	SourceList      Source = "list"   // Custom Trakt list (see Media.List)
+)
+
+// EpisodeStrategy controls which episodes of a show are downloaded
+type EpisodeStrategy string
+
+const (
+	EpisodeStrategyNext     EpisodeStrategy = "next"     // Next unwatched episode (watchlist behaviour)
+	EpisodeStrategySeason   EpisodeStrategy = "season"   // Season pack or next episodes of the season (favorites behaviour)
+	EpisodeStrategyBackfill EpisodeStrategy = "backfill" // Every unwatched season and episode (whole back catalog)
 )
 
 // Status represents the current processing status of a media item
@@ -27,6 +38,24 @@ const (
 	StatusFailed      Status = "failed"
 )
 
+// PackPolicy controls whether season packs are used for a TV show
+type PackPolicy string
+
+const (
+	PackPolicyAuto  PackPolicy = ""      // Default behaviour for the source
+	PackPolicyNever PackPolicy = "never" // Individual episodes only
+	PackPolicyOnly  PackPolicy = "only"  // Season packs only
+)
+
+// PackUpgrade controls whether the episodes of a season that finished airing are replaced by a season pack
+type PackUpgrade string
+
+const (
+	PackUpgradeDefault PackUpgrade = ""    // SEASON_PACK_UPGRADE setting
+	PackUpgradeOn      PackUpgrade = "on"  // Replace the episodes with a season pack
+	PackUpgradeOff     PackUpgrade = "off" // Keep the episodes
+)
+
 // Quality represents the quality tier of an NZB
 type Quality string
 
@@ -36,6 +65,14 @@ const (
 	QualityOther Quality = "OTHER"
 )
 
+// Protocol represents how a release is downloaded
+type Protocol string
+
+const (
+	ProtocolUsenet  Protocol = "usenet"
+	ProtocolTorrent Protocol = "torrent"
+)
+
 // NZBStatus represents the status of an NZB download
 type NZBStatus string
 
@@ -46,4 +83,6 @@ const (
 	NZBStatusCompleted   NZBStatus = "completed"   // Successfully downloaded
 	NZBStatusFailed      NZBStatus = "failed"      // Download failed
 	NZBStatusBlacklisted NZBStatus = "blacklisted" // Matched blacklist
+	NZBStatusReplaced    NZBStatus = "replaced"    // Completed, then superseded by an upgrade
+	NZBStatusRejected    NZBStatus = "rejected"    // Completed, then rejected on import (corrupt copy, samples only)
 )
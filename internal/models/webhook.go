@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// ProcessedWebhook records the idempotency key of a webhook event already applied
+type ProcessedWebhook struct {
+	Key         string `boltholdKey:"Key"`
+	ProcessedAt time.Time
+}
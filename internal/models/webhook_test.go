@@ -0,0 +1,79 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestClaimWebhook(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	claim := func(key string, ttl time.Duration) bool {
+		claimed, err := db.ClaimWebhook(key, ttl)
+		if err != nil {
+			t.Fatalf("Failed to claim webhook: %v", err)
+		}
+		return claimed
+	}
+
+	if !claim("a", time.Hour) {
+		t.Error("Expected the first delivery to be claimed")
+	}
+	if claim("a", time.Hour) {
+		t.Error("Expected the second delivery to be skipped")
+	}
+	if !claim("b", time.Hour) {
+		t.Error("Expected another event to be claimed")
+	}
+
+	if err := db.ReleaseWebhook("a"); err != nil {
+		t.Fatalf("Failed to release webhook: %v", err)
+	}
+	if !claim("a", time.Hour) {
+		t.Error("Expected a released event to be claimed again")
+	}
+
+	// Keys older than the TTL are forgotten
+	time.Sleep(10 * time.Millisecond)
+	if !claim("b", time.Millisecond) {
+		t.Error("Expected an expired key to be claimed again")
+	}
+}
+
+func TestGetNZBByTorBoxJobID(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// Usenet downloads and torrents with the same TorBox ID, the usenet one stored before
+	// torrent support (no protocol)
+	usenet := &NZB{MediaID: 1, GUID: "usenet", Title: "Usenet", TorBoxJobID: "7"}
+	torrent := &NZB{MediaID: 2, GUID: "torrent", Title: "Torrent", TorBoxJobID: "7", Protocol: ProtocolTorrent}
+	for _, nzb := range []*NZB{torrent, usenet} {
+		if err := db.CreateNZB(nzb); err != nil {
+			t.Fatalf("CreateNZB failed: %v", err)
+		}
+	}
+
+	for protocol, want := range map[Protocol]*NZB{ProtocolUsenet: usenet, ProtocolTorrent: torrent} {
+		got, err := db.GetNZBByTorBoxJobID(protocol, "7")
+		if err != nil {
+			t.Fatalf("GetNZBByTorBoxJobID(%s) failed: %v", protocol, err)
+		}
+		if got.ID != want.ID {
+			t.Errorf("GetNZBByTorBoxJobID(%s) returned %s", protocol, got.Title)
+		}
+	}
+	if _, err := db.GetNZBByTorBoxJobID(ProtocolTorrent, "8"); err == nil {
+		t.Error("Expected an error for an unknown job")
+	}
+}
@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+)
+
+// publishSearch streams the outcome of a media search
+func (s *Scheduler) publishSearch(media *models.Media, strategy *controllers.DownloadStrategy, nzbs []*models.NZB) {
+	selected := 0
+	for _, nzb := range nzbs {
+		if nzb.Status == models.NZBStatusSelected {
+			selected++
+		}
+	}
+
+	s.notifier.Publish(notify.Event{
+		Type:      notify.EventSearchCompleted,
+		Message:   "Search completed for " + media.Title,
+		MediaID:   media.ID,
+		IMDBId:    media.IMDBId,
+		Title:     media.Title,
+		Year:      media.Year,
+		MediaType: string(media.MediaType),
+		Source:    string(media.Source),
+		Season:    media.SeasonNumber,
+		Episode:   media.EpisodeNumber,
+		Strategy:  string(strategy.Type),
+		Counts: map[string]int{
+			"candidates": len(nzbs),
+			"selected":   selected,
+		},
+	})
+}
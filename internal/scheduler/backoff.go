@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// maxBackoffShift bounds the doubling of the search backoff
+const maxBackoffShift = 20
+
+// SearchBackoff lengthens the interval between scheduled searches of media that keep
+// coming back empty, so unavailable content isn't searched every cycle forever.
+// Manual searches ignore it.
+type SearchBackoff struct {
+	Base time.Duration // Wait after the first empty search, doubled after each one (0 disables the backoff)
+	Max  time.Duration // Longest wait between two searches, 0 for no limit
+}
+
+// interval returns the wait before searching again after consecutive empty searches
+func (b SearchBackoff) interval(emptySearches int) time.Duration {
+	if b.Base <= 0 || emptySearches <= 0 {
+		return 0
+	}
+	shift := emptySearches - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	wait := b.Base << shift
+	if b.Max > 0 && wait > b.Max {
+		wait = b.Max
+	}
+	return wait
+}
+
+// NextSearch returns when a scheduled search picks up a media item again, zero if it is due
+func (s *Scheduler) NextSearch(media *models.Media) time.Time {
+	if media.LastSearchedAt == nil {
+		return time.Time{}
+	}
+	wait := s.searchBackoff.interval(media.EmptySearches)
+	if wait == 0 {
+		return time.Time{}
+	}
+	return media.LastSearchedAt.Add(wait)
+}
@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+func TestSearchBackoff(t *testing.T) {
+	backoff := SearchBackoff{Base: time.Hour, Max: 24 * time.Hour}
+	cases := map[int]time.Duration{0: 0, 1: time.Hour, 2: 2 * time.Hour, 4: 8 * time.Hour, 6: 24 * time.Hour, 100: 24 * time.Hour}
+	for empty, want := range cases {
+		if got := backoff.interval(empty); got != want {
+			t.Errorf("interval(%d) = %v, want %v", empty, got, want)
+		}
+	}
+
+	s := &Scheduler{searchBackoff: backoff}
+	last := time.Now().Add(-90 * time.Minute)
+	media := &models.Media{LastSearchedAt: &last, EmptySearches: 1}
+	if next := s.NextSearch(media); !next.IsZero() && next.After(time.Now()) {
+		t.Errorf("Media searched 90 minutes ago after one empty search should be due, next at %v", next)
+	}
+	media.EmptySearches = 2
+	if next := s.NextSearch(media); !next.After(time.Now()) {
+		t.Errorf("Media should wait 2 hours after two empty searches, next at %v", next)
+	}
+
+	if interval := (SearchBackoff{}).interval(5); interval != 0 {
+		t.Errorf("Disabled backoff should not wait, got %v", interval)
+	}
+}
@@ -2,11 +2,18 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/amaumene/gomenarr/internal/controllers"
 	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/services/trakt"
+	"github.com/amaumene/gomenarr/internal/tracing"
+	"github.com/amaumene/gomenarr/internal/utils"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
@@ -19,9 +26,33 @@ type Scheduler struct {
 	searchCtrl             *controllers.SearchController
 	downloadCtrl           *controllers.DownloadController
 	cleanupCtrl            *controllers.CleanupController
+	libraryCtrl            *controllers.LibraryController
+	traktClient            *trakt.Client
 	db                     *models.Database
+	blacklist              *utils.Blacklist
 	logger                 *logrus.Logger
 	downloadTimeoutMinutes int
+	upgradeEnabled         bool // Search completed movies for better releases
+	seasonPackUpgrade      bool // Replace the episodes of finished seasons with packs, unless the show notes say otherwise
+	taskOptions            TaskOptions
+	tasks                  []*task
+	grabLimits             GrabLimits
+	searchBackoff          SearchBackoff
+	maintenance            []maintenanceWindow
+	maintenanceTasks       []string
+	notifier               *notify.Dispatcher
+	hooks                  *hooks.Runner
+	rampMu                 sync.Mutex // Serializes cold-start state updates
+	pauseMu                sync.Mutex
+	pause                  *models.SchedulerPause // Pause requested through the API, nil if never paused
+	stop                   chan struct{}          // Closed when the scheduler stops
+	cyclesMu               sync.Mutex
+	cycles                 map[string]CycleStatus // Outcome of the last runs by cycle name
+	idleMu                 sync.Mutex
+	wokeAt                 time.Time // Last media or download activity, ends the idle stretching
+
+	// Media currently being searched, shared by scheduled and manual searches
+	processing sync.Map
 }
 
 // NewScheduler creates a new scheduler
@@ -31,68 +62,103 @@ func NewScheduler(
 	searchCtrl *controllers.SearchController,
 	downloadCtrl *controllers.DownloadController,
 	cleanupCtrl *controllers.CleanupController,
+	libraryCtrl *controllers.LibraryController,
+	traktClient *trakt.Client,
 	db *models.Database,
+	blacklist *utils.Blacklist,
 	downloadTimeoutMinutes int,
+	upgradeEnabled bool,
+	seasonPackUpgrade bool,
+	taskOptions TaskOptions,
+	grabLimits GrabLimits,
+	searchBackoff SearchBackoff,
+	notifier *notify.Dispatcher,
+	hookRunner *hooks.Runner,
 	logger *logrus.Logger,
 ) *Scheduler {
-	return &Scheduler{
+	s := &Scheduler{
 		cron:                   cron.New(),
 		syncCtrl:               syncCtrl,
 		strategyCtrl:           strategyCtrl,
 		searchCtrl:             searchCtrl,
 		downloadCtrl:           downloadCtrl,
 		cleanupCtrl:            cleanupCtrl,
+		libraryCtrl:            libraryCtrl,
+		traktClient:            traktClient,
 		db:                     db,
+		blacklist:              blacklist,
 		downloadTimeoutMinutes: downloadTimeoutMinutes,
+		upgradeEnabled:         upgradeEnabled,
+		seasonPackUpgrade:      seasonPackUpgrade,
+		taskOptions:            taskOptions,
+		grabLimits:             grabLimits,
+		searchBackoff:          searchBackoff,
+		notifier:               notifier,
+		hooks:                  hookRunner,
+		stop:                   make(chan struct{}),
 		logger:                 logger,
 	}
+	s.registerTasks()
+	return s
 }
 
 // Start starts the scheduler
 func (s *Scheduler) Start() error {
 	s.logger.Info("Starting scheduler")
 
-	// Every 6 hours: Sync from Trakt (also triggers immediate cleanup of removed items)
-	_, err := s.cron.AddFunc("0 */6 * * *", func() {
-		s.runSync()
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add sync job: %w", err)
+	if err := s.configureTasks(); err != nil {
+		return err
 	}
-
-	// Every 30 minutes: Process pending medias (search + download)
-	_, err = s.cron.AddFunc("*/30 * * * *", func() {
-		s.runSearch()
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add search job: %w", err)
+	if err := s.configureMaintenance(); err != nil {
+		return err
+	}
+	if err := s.configureIdle(); err != nil {
+		return err
+	}
+	if err := s.loadPause(); err != nil {
+		return err
 	}
 
-	// Every hour: Cleanup watched medias
-	_, err = s.cron.AddFunc("0 * * * *", func() {
-		s.runCleanupWatched()
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add cleanup job: %w", err)
+	for _, t := range s.tasks {
+		if !t.enabled {
+			s.logger.WithField("task", t.name).Debug("Task disabled, not scheduling")
+			continue
+		}
+		if t.schedule == "" {
+			continue // Startup and on-demand runs only
+		}
+		t := t
+		entry, err := s.cron.AddFunc(t.schedule, func() { s.executeScheduled(t) })
+		if err != nil {
+			return fmt.Errorf("failed to add %s job: %w", t.name, err)
+		}
+		t.entry = entry
 	}
 
-	// Every 10 minutes: Check for stuck downloads
-	_, err = s.cron.AddFunc("*/10 * * * *", func() {
-		s.runStuckDownloadCheck()
-	})
+	startup := s.taskOptions.Startup
+	if len(startup) == 0 {
+		startup = defaultStartupTasks
+	}
+	plan, err := s.plan(startup, true)
 	if err != nil {
-		return fmt.Errorf("failed to add stuck download check job: %w", err)
+		return fmt.Errorf("failed to plan startup tasks: %w", err)
 	}
 
 	s.cron.Start()
+	if s.taskOptions.StallAfter > 0 {
+		go s.watchdog(s.stop)
+	}
+	if s.taskOptions.IdleInterval > 0 && s.notifier != nil {
+		go s.watchActivity(s.stop)
+	}
 	s.logger.Info("Scheduler started")
 
-	// Run initial sync and search immediately
+	// Run the startup tasks immediately (default: sync, then search), one after the other
 	go func() {
-		s.runSync()
-		// Wait a bit for sync to complete, then run search
-		s.logger.Info("Running initial search after sync")
-		s.runSearch()
+		for _, t := range plan {
+			s.logger.WithField("task", t.name).Info("Running startup task")
+			s.executeScheduled(t)
+		}
 	}()
 
 	return nil
@@ -101,30 +167,56 @@ func (s *Scheduler) Start() error {
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
 	s.logger.Info("Stopping scheduler")
+	close(s.stop)
 	s.cron.Stop()
 }
 
 // runSync executes the sync job
-func (s *Scheduler) runSync() {
+func (s *Scheduler) runSync(ctx context.Context) {
 	s.logger.Info("Running scheduled sync")
-	ctx := context.Background()
 
-	if err := s.syncCtrl.SyncAll(ctx); err != nil {
+	if !s.traktAvailable("sync") {
+		return
+	}
+
+	cycle := startCycle("sync", "movies", "shows", "added", "episodes", "removed")
+	defer s.finishCycle(cycle)
+
+	stats, err := s.syncCtrl.SyncAll(ctx)
+	if err != nil {
 		s.logger.WithError(err).Error("Sync job failed")
-	} else {
-		s.logger.Info("Sync job completed successfully")
+		cycle.fail()
+		return
+	}
+
+	cycle.add("movies", stats.Movies)
+	cycle.add("shows", stats.Shows)
+	cycle.add("added", stats.Added)
+	cycle.add("episodes", stats.Episodes)
+	cycle.add("removed", stats.Removed)
+	cycle.failures += stats.Failures
+	s.logger.Info("Sync job completed successfully")
+
+	// New media may already be in the library: scan it before they are searched
+	if stats.Added > 0 {
+		if t := s.task(TaskLibraryScan); t != nil && t.enabled {
+			s.execute(t)
+		}
 	}
 }
 
 // runSearch executes the search and download job
-func (s *Scheduler) runSearch() {
+func (s *Scheduler) runSearch(ctx context.Context) {
 	s.logger.Info("Running scheduled search")
-	ctx := context.Background()
+
+	cycle := startCycle("search", "searches", "candidates", "grabs", "deferred", "backed_off", "not_aired", "over_quota", "disk_low")
+	defer s.finishCycle(cycle)
 
 	// Get pending medias
 	medias, err := s.db.GetPendingMedias()
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to get pending medias")
+		cycle.fail()
 		return
 	}
 
@@ -133,116 +225,487 @@ func (s *Scheduler) runSearch() {
 		return
 	}
 
+	// Grabs would be held back: wait for space to be freed instead of searching
+	if s.downloadCtrl.DiskSpaceLow() {
+		s.logger.WithField("count", len(medias)).Warn("Disk space low, skipping search")
+		cycle.add("disk_low", len(medias))
+		return
+	}
+
 	s.logger.WithField("count", len(medias)).Info("Processing pending medias")
 
 	for _, media := range medias {
-		s.logger.WithFields(logrus.Fields{
-			"media_id": media.ID,
-			"title":    media.Title,
-		}).Info("Processing media")
-
-		// Update status to searching
-		media.Status = models.StatusSearching
-		if err := s.db.UpdateMedia(media); err != nil {
-			s.logger.WithError(err).Error("Failed to update media status")
+		if media.MediaType == models.MediaTypeTV {
+			s.refreshWatchedShows(ctx)
+			break
+		}
+	}
+
+	// Media left once the grab limit is reached stay pending for the next cycle
+	limit := s.cycleGrabLimit()
+
+	now := time.Now()
+	for _, media := range medias {
+		if s.canceled(ctx, "search") {
+			break
+		}
+
+		// Media that keep coming back empty are searched less and less often
+		if next := s.NextSearch(media); now.Before(next) {
+			cycle.add("backed_off", 1)
 			continue
 		}
 
-		// Determine strategy
-		strategy, err := s.strategyCtrl.DetermineStrategy(ctx, media)
-		if err != nil {
-			s.logger.WithError(err).Error("Failed to determine strategy")
-			media.Status = models.StatusFailed
-			s.db.UpdateMedia(media)
+		// Shows waiting for an episode that just aired are searched once the air offset passed
+		if now.Before(s.strategyCtrl.SearchableAt(media)) {
+			cycle.add("not_aired", 1)
 			continue
 		}
 
-		// Search for media
-		nzbs, err := s.searchCtrl.SearchMedia(ctx, media, strategy)
-		if err != nil {
-			s.logger.WithError(err).Error("Search failed")
-			media.Status = models.StatusFailed
-			s.db.UpdateMedia(media)
+		if limit > 0 && cycle.items["grabs"] >= limit {
+			cycle.add("deferred", 1)
 			continue
 		}
 
-		if len(nzbs) == 0 {
-			s.logger.Warn("No results found")
-			media.Status = models.StatusPending // Keep as pending to retry later
-			s.db.UpdateMedia(media)
+		// TV strategies depend on Trakt progress: keep them pending while Trakt is paused
+		if media.MediaType == models.MediaTypeTV && !s.traktClient.Available() {
+			s.logger.WithField("media_id", media.ID).Debug("Trakt unavailable, postponing TV media")
 			continue
 		}
 
-		// Find all selected NZBs and download them
-		var selectedNZBs []*models.NZB
-		for _, nzb := range nzbs {
-			if nzb.Status == models.NZBStatusSelected {
-				selectedNZBs = append(selectedNZBs, nzb)
-			}
+		s.processMedia(ctx, media, cycle, s.searchCtrl.SearchMedia)
+	}
+
+	if deferred := cycle.items["deferred"]; deferred > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"limit":    limit,
+			"deferred": deferred,
+		}).Info("Grab limit reached, remaining medias wait for the next cycle")
+	}
+	if backedOff := cycle.items["backed_off"]; backedOff > 0 {
+		s.logger.WithField("backed_off", backedOff).Debug("Skipped medias without results until their next search")
+	}
+	s.advanceGrabRamp(cycle.items["grabs"], cycle.items["deferred"])
+
+	s.logger.Info("Search job completed")
+}
+
+// refreshWatchedShows fetches the watch state of every show in one Trakt call, so the
+// progress of shows watched since the last cycle only is fetched again
+func (s *Scheduler) refreshWatchedShows(ctx context.Context) {
+	if !s.traktClient.Available() {
+		return
+	}
+	count, err := s.traktClient.RefreshWatchedShows(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get watched shows, fetching the progress of each show")
+		return
+	}
+	s.logger.WithField("shows", count).Debug("Refreshed watched shows")
+}
+
+// ProcessMedia searches and downloads a single media item immediately
+// Returns an error if the media is already being processed.
+func (s *Scheduler) ProcessMedia(ctx context.Context, media *models.Media) error {
+	cycle := startCycle("manual_search", "searches", "candidates", "grabs")
+	defer s.finishCycle(cycle)
+
+	if !s.processMedia(ctx, media, cycle, s.searchCtrl.SearchMedia) {
+		return fmt.Errorf("media %d is already being processed", media.ID)
+	}
+	return nil
+}
+
+// searchFunc finds the releases of a media item wanted by a strategy and selects the ones to download
+type searchFunc func(ctx context.Context, media *models.Media, strategy *controllers.DownloadStrategy) ([]*models.NZB, error)
+
+// processMedia runs a media item through search and download unless another task
+// is already processing it. Returns false if the media was skipped.
+func (s *Scheduler) processMedia(ctx context.Context, media *models.Media, cycle *cycleSummary, search searchFunc) bool {
+	if _, busy := s.processing.LoadOrStore(media.ID, true); busy {
+		s.logger.WithField("media_id", media.ID).Debug("Media already being processed, skipping")
+		return false
+	}
+	defer s.processing.Delete(media.ID)
+
+	ctx, cancel := s.mediaContext(ctx)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "media")
+	span.SetAttribute("media.id", media.ID)
+	span.SetAttribute("media.title", media.Title)
+	span.SetAttribute("media.type", string(media.MediaType))
+	defer span.End()
+
+	s.searchAndDownload(ctx, media, cycle, search)
+	span.SetAttribute("media.status", string(media.Status))
+	return true
+}
+
+// searchAndDownload determines the strategy, searches and downloads a media item
+func (s *Scheduler) searchAndDownload(ctx context.Context, media *models.Media, cycle *cycleSummary, search searchFunc) {
+	s.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+	}).Info("Processing media")
+
+	// Update status to searching
+	media.Status = models.StatusSearching
+	if err := s.db.UpdateMedia(media); err != nil {
+		s.logger.WithError(err).Error("Failed to update media status")
+		return
+	}
+
+	// Determine strategy
+	strategy, err := s.strategyCtrl.DetermineStrategy(ctx, media)
+	if err != nil && s.interrupted(ctx, media, err, cycle) {
+		return
+	}
+	if errors.Is(err, trakt.ErrUnavailable) {
+		s.logger.WithError(err).Warn("Trakt unavailable, keeping media pending")
+		media.Status = models.StatusPending
+		s.db.UpdateMedia(media)
+		return
+	}
+	if errors.Is(err, controllers.ErrNotAired) {
+		s.logger.WithFields(logrus.Fields{
+			"media_id":  media.ID,
+			"search_at": s.strategyCtrl.SearchableAt(media).Local(),
+		}).Info("Episodes aired too recently, keeping media pending")
+		media.Status = models.StatusPending
+		s.db.UpdateMedia(media)
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to determine strategy")
+		cycle.fail()
+		media.Status = models.StatusFailed
+		s.db.UpdateMedia(media)
+		return
+	}
+
+	// Search for media
+	cycle.add("searches", 1)
+	nzbs, err := search(ctx, media, strategy)
+	if err != nil && s.interrupted(ctx, media, err, cycle) {
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Search failed")
+		cycle.fail()
+		media.Status = models.StatusFailed
+		s.db.UpdateMedia(media)
+		return
+	}
+
+	cycle.add("candidates", len(nzbs))
+	s.publishSearch(media, strategy, nzbs)
+
+	if len(nzbs) == 0 {
+		s.logger.Warn("No results found")
+		media.Status = models.StatusPending // Keep as pending to retry later
+		s.db.UpdateMedia(media)
+		return
+	}
+
+	// Find all selected NZBs and download them
+	var selectedNZBs []*models.NZB
+	for _, nzb := range nzbs {
+		if nzb.Status == models.NZBStatusSelected {
+			selectedNZBs = append(selectedNZBs, nzb)
+		}
+	}
+
+	if len(selectedNZBs) == 0 {
+		s.logger.Warn("No suitable NZB found (all blacklisted?)")
+		media.Status = models.StatusFailed
+		s.db.UpdateMedia(media)
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"count":    len(selectedNZBs),
+	}).Info("Found selected NZBs to download")
+
+	// Download all selected NZBs
+	downloadFailed := false
+	heldBack := 0
+	for _, nzb := range selectedNZBs {
+		s.logger.WithFields(logrus.Fields{
+			"nzb_id":  nzb.ID,
+			"title":   nzb.Title,
+			"episode": nzb.Episode,
+		}).Info("Downloading NZB")
+
+		_, span := tracing.Start(ctx, "queue")
+		span.SetAttribute("nzb.id", nzb.ID)
+		span.SetAttribute("nzb.title", nzb.Title)
+		err := s.downloadCtrl.DownloadNZB(nzb)
+		span.RecordError(err)
+		span.End()
+		if errors.Is(err, controllers.ErrQuotaExceeded) {
+			s.logger.WithError(err).Warn("Release exceeds storage quota, not downloading")
+			cycle.add("over_quota", 1)
+			heldBack++
+			continue
+		}
+		if errors.Is(err, controllers.ErrDiskSpaceLow) {
+			s.logger.WithError(err).Warn("Release doesn't fit on the download volume, not downloading")
+			cycle.add("disk_low", 1)
+			heldBack++
+			continue
+		}
+		if errors.Is(err, controllers.ErrAlreadyCovered) {
+			continue
 		}
+		if err != nil {
+			s.logger.WithError(err).Error("Download failed")
+			cycle.fail()
+			downloadFailed = true
+			// Continue with other downloads instead of stopping
+			continue
+		}
+		cycle.add("grabs", 1)
+	}
+
+	// Media without room in their library or on disk are searched again once space is freed
+	if heldBack == len(selectedNZBs) {
+		media.Status = models.StatusPending
+		s.db.UpdateMedia(media)
+		return
+	}
+
+	// Only mark as failed if ALL downloads failed
+	if downloadFailed && len(selectedNZBs) == 1 {
+		media.Status = models.StatusFailed
+		s.db.UpdateMedia(media)
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"count":    len(selectedNZBs),
+	}).Info("Media downloads started")
+}
+
+// runUpgradeSearch searches completed movies again and downloads releases that score
+// higher under their quality profile. The current release is replaced once the upgrade completes.
+func (s *Scheduler) runUpgradeSearch(ctx context.Context) {
+	s.logger.Info("Running scheduled upgrade search")
+
+	cycle := startCycle("upgrade", "checked", "upgrades")
+	defer s.finishCycle(cycle)
+
+	medias, err := s.db.GetMediasByStatus(models.StatusCompleted)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get completed medias")
+		cycle.fail()
+		return
+	}
 
-		if len(selectedNZBs) == 0 {
-			s.logger.Warn("No suitable NZB found (all blacklisted?)")
-			media.Status = models.StatusFailed
-			s.db.UpdateMedia(media)
+	for _, media := range medias {
+		if s.canceled(ctx, "upgrade") {
+			break
+		}
+		if media.MediaType != models.MediaTypeMovie {
 			continue
 		}
 
-		s.logger.WithFields(logrus.Fields{
-			"media_id": media.ID,
-			"count":    len(selectedNZBs),
-		}).Info("Found selected NZBs to download")
-
-		// Download all selected NZBs
-		downloadFailed := false
-		for _, nzb := range selectedNZBs {
-			s.logger.WithFields(logrus.Fields{
-				"nzb_id":  nzb.ID,
-				"title":   nzb.Title,
-				"episode": nzb.Episode,
-			}).Info("Downloading NZB")
-
-			if err := s.downloadCtrl.DownloadNZB(nzb); err != nil {
-				s.logger.WithError(err).Error("Download failed")
-				downloadFailed = true
-				// Continue with other downloads instead of stopping
-			}
+		if _, busy := s.processing.LoadOrStore(media.ID, true); busy {
+			continue
 		}
+		mediaCtx, cancel := s.mediaContext(ctx)
+		upgraded, err := s.upgradeMedia(mediaCtx, media)
+		cancel()
+		s.processing.Delete(media.ID)
 
-		// Only mark as failed if ALL downloads failed
-		if downloadFailed && len(selectedNZBs) == 1 {
-			media.Status = models.StatusFailed
-			s.db.UpdateMedia(media)
+		cycle.add("checked", 1)
+		if err != nil {
+			s.logger.WithError(err).WithField("media_id", media.ID).Error("Upgrade search failed")
+			cycle.fail()
 			continue
 		}
+		if upgraded {
+			cycle.add("upgrades", 1)
+		}
+	}
 
-		s.logger.WithFields(logrus.Fields{
-			"media_id": media.ID,
-			"count":    len(selectedNZBs),
-		}).Info("Media downloads started")
+	s.logger.Info("Upgrade search completed")
+}
+
+// upgradeMedia looks for a better release of a completed movie and starts its download
+// Returns true if an upgrade was started.
+func (s *Scheduler) upgradeMedia(ctx context.Context, media *models.Media) (bool, error) {
+	nzbs, err := s.db.GetNZBsByMediaID(media.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get NZBs: %w", err)
 	}
 
-	s.logger.Info("Search job completed")
+	// Current release: the last completed one. Skip media with an upgrade in progress.
+	var current *models.NZB
+	for _, nzb := range nzbs {
+		switch nzb.Status {
+		case models.NZBStatusDownloading:
+			return false, nil
+		case models.NZBStatusCompleted:
+			if current == nil || (nzb.DownloadedAt != nil && current.DownloadedAt != nil && nzb.DownloadedAt.After(*current.DownloadedAt)) {
+				current = nzb
+			}
+		}
+	}
+	if current == nil {
+		return false, nil
+	}
+
+	upgrade, err := s.searchCtrl.FindUpgrade(ctx, media, current)
+	if err != nil || upgrade == nil {
+		return false, err
+	}
+
+	if err := s.downloadCtrl.DownloadUpgrade(upgrade, current); err != nil {
+		return false, fmt.Errorf("failed to download upgrade: %w", err)
+	}
+	return true, nil
 }
 
 // runCleanupWatched executes the watched cleanup job
-func (s *Scheduler) runCleanupWatched() {
+func (s *Scheduler) runCleanupWatched(ctx context.Context) {
 	s.logger.Info("Running scheduled cleanup of watched content")
-	ctx := context.Background()
 
-	if err := s.cleanupCtrl.CleanupWatched(ctx); err != nil {
+	if !s.traktAvailable("cleanup") {
+		return
+	}
+
+	cycle := startCycle("cleanup", "cleanups")
+	defer s.finishCycle(cycle)
+
+	cleaned, err := s.cleanupCtrl.CleanupWatched(ctx)
+	if err != nil {
 		s.logger.WithError(err).Error("Cleanup job failed")
-	} else {
-		s.logger.Info("Cleanup job completed successfully")
+		cycle.fail()
+		return
+	}
+
+	cycle.add("cleanups", cleaned)
+	s.logger.Info("Cleanup job completed successfully")
+}
+
+// traktAvailable checks if Trakt-dependent tasks can run and logs why they are skipped
+func (s *Scheduler) traktAvailable(task string) bool {
+	availability := s.traktClient.Availability()
+	if availability.Available {
+		return true
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"task":         task,
+		"reason":       availability.Reason,
+		"paused_until": availability.PausedUntil,
+	}).Warn("Skipping task while Trakt is unavailable")
+	return false
+}
+
+// runLibraryScan matches the files of the media library to media items
+func (s *Scheduler) runLibraryScan(ctx context.Context) {
+	s.logger.Info("Running library scan")
+
+	cycle := startCycle("library_scan", "files", "movies", "episodes", "unmatched")
+	defer s.finishCycle(cycle)
+
+	stats, err := s.libraryCtrl.Scan(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Library scan failed")
+		cycle.fail()
+	}
+	if stats != nil {
+		cycle.add("files", stats.Files)
+		cycle.add("movies", stats.Movies)
+		cycle.add("episodes", stats.Episodes)
+		cycle.add("unmatched", stats.Unmatched)
+	}
+}
+
+// runBlacklistRefresh downloads the subscribed blacklists
+func (s *Scheduler) runBlacklistRefresh(ctx context.Context) {
+	s.logger.Debug("Refreshing subscribed blacklists")
+
+	cycle := startCycle("blacklist_refresh", "terms")
+	defer s.finishCycle(cycle)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	terms, err := s.blacklist.RefreshRemote(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh a subscribed blacklist, keeping its previous terms")
+		cycle.fail()
+	}
+
+	cycle.add("terms", terms)
+}
+
+// runReconcile applies the TorBox status of downloads still in progress
+func (s *Scheduler) runReconcile(ctx context.Context) {
+	s.logger.Debug("Reconciling downloads with TorBox")
+
+	cycle := startCycle("reconcile_downloads", "checked", "completed", "failed")
+	defer s.finishCycle(cycle)
+
+	stats, err := s.downloadCtrl.ReconcileDownloads()
+	if err != nil {
+		s.logger.WithError(err).Warn("Download reconciliation failed")
+		cycle.fail()
+		return
+	}
+
+	cycle.add("checked", stats.Checked)
+	cycle.add("completed", stats.Completed)
+	cycle.add("failed", stats.Failed)
+}
+
+// runRecover completes the downloads TorBox finished without gomenarr handling their webhook
+func (s *Scheduler) runRecover(ctx context.Context) {
+	s.logger.Info("Scanning TorBox history for unhandled downloads")
+
+	cycle := startCycle("recover_downloads", "scanned", "recovered")
+	defer s.finishCycle(cycle)
+
+	stats, err := s.downloadCtrl.RecoverDownloads(s.taskOptions.RecoveryMaxAge)
+	if err != nil {
+		s.logger.WithError(err).Warn("Download recovery failed")
+		cycle.fail()
+		return
+	}
+
+	cycle.add("scanned", stats.Scanned)
+	cycle.add("recovered", stats.Recovered)
+	if stats.Recovered > 0 {
+		s.logger.WithField("recovered", stats.Recovered).Info("Recovered finished downloads")
 	}
 }
 
 // runStuckDownloadCheck executes the stuck download check job
-func (s *Scheduler) runStuckDownloadCheck() {
+func (s *Scheduler) runStuckDownloadCheck(ctx context.Context) {
 	s.logger.Debug("Running stuck download check")
 
+	cycle := startCycle("stuck_check", "stuck", "retries")
+	defer s.finishCycle(cycle)
+
+	// Failed downloads waiting out their retry backoff
+	retries, err := s.downloadCtrl.RetryDue()
+	if err != nil {
+		s.logger.WithError(err).Error("Delayed download retries failed")
+		cycle.fail()
+	}
+	cycle.add("retries", retries)
+
 	timeout := time.Duration(s.downloadTimeoutMinutes) * time.Minute
-	if err := s.downloadCtrl.CheckStuckDownloads(timeout); err != nil {
+	stuck, err := s.downloadCtrl.CheckStuckDownloads(timeout)
+	if err != nil {
 		s.logger.WithError(err).Error("Stuck download check failed")
+		cycle.fail()
+		return
 	}
+
+	cycle.add("stuck", stuck)
 }
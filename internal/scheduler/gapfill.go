@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// runGapFill searches the missing episodes of completed shows monitored as a whole
+// (backfill strategy). Each run searches up to GapFillShows shows, the ones searched the
+// longest ago first, and only the earliest season with gaps of each show.
+func (s *Scheduler) runGapFill(ctx context.Context) {
+	s.logger.Info("Running scheduled gap fill")
+
+	cycle := startCycle("gap_fill", "searches", "gaps", "grabs", "backed_off", "over_quota", "disk_low")
+	defer s.finishCycle(cycle)
+
+	// Gaps are computed from Trakt progress: wait for the next run while it is paused
+	if !s.traktClient.Available() {
+		s.logger.Info("Trakt unavailable, skipping gap fill")
+		return
+	}
+
+	medias, err := s.db.GetAllMedias()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get medias")
+		cycle.fail()
+		return
+	}
+
+	var shows []*models.Media
+	for _, media := range medias {
+		if media.MediaType != models.MediaTypeTV || media.ParentID != 0 || media.SeasonNumber != nil {
+			continue
+		}
+		if media.Status == models.StatusCompleted && media.ShowStrategy() == models.EpisodeStrategyBackfill {
+			shows = append(shows, media)
+		}
+	}
+	sort.SliceStable(shows, func(i, j int) bool {
+		return searchedBefore(shows[i].LastSearchedAt, shows[j].LastSearchedAt)
+	})
+
+	if len(shows) == 0 {
+		return
+	}
+	if s.downloadCtrl.DiskSpaceLow() {
+		s.logger.Warn("Disk space low, skipping gap fill")
+		return
+	}
+	s.refreshWatchedShows(ctx)
+
+	limit := s.cycleGrabLimit()
+	now := time.Now()
+	for _, media := range shows {
+		if s.canceled(ctx, "gap_fill") {
+			break
+		}
+		if cycle.items["searches"] >= s.taskOptions.GapFillShows {
+			break
+		}
+		if limit > 0 && cycle.items["grabs"] >= limit {
+			break
+		}
+		// Gaps that keep coming back empty are searched less and less often
+		if next := s.NextSearch(media); now.Before(next) {
+			cycle.add("backed_off", 1)
+			continue
+		}
+
+		if _, busy := s.processing.LoadOrStore(media.ID, true); busy {
+			continue
+		}
+		mediaCtx, cancel := s.mediaContext(ctx)
+		s.fillGaps(mediaCtx, media, cycle)
+		cancel()
+		s.processing.Delete(media.ID)
+	}
+
+	s.logger.Info("Gap fill completed")
+}
+
+// fillGaps searches and downloads the missing episodes of the earliest season with gaps of a show
+func (s *Scheduler) fillGaps(ctx context.Context, media *models.Media, cycle *cycleSummary) {
+	strategy, err := s.strategyCtrl.GapStrategy(ctx, media)
+	if err != nil {
+		s.logger.WithError(err).WithField("media_id", media.ID).Warn("Failed to determine show gaps")
+		cycle.fail()
+		return
+	}
+	if strategy == nil {
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"title":    media.Title,
+		"season":   strategy.Seasons[0],
+		"episodes": len(strategy.Episodes),
+	}).Info("Searching missing episodes")
+
+	cycle.add("searches", 1)
+	cycle.add("gaps", len(strategy.Episodes))
+
+	nzbs, err := s.searchCtrl.SearchMedia(ctx, media, strategy)
+	if err != nil {
+		s.logger.WithError(err).WithField("media_id", media.ID).Error("Gap search failed")
+		cycle.fail()
+		return
+	}
+	s.publishSearch(media, strategy, nzbs)
+
+	for _, nzb := range nzbs {
+		if nzb.Status != models.NZBStatusSelected {
+			continue
+		}
+		err := s.downloadCtrl.DownloadNZB(nzb)
+		if errors.Is(err, controllers.ErrQuotaExceeded) {
+			s.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Release exceeds storage quota, not downloading")
+			cycle.add("over_quota", 1)
+			continue
+		}
+		if errors.Is(err, controllers.ErrDiskSpaceLow) {
+			s.logger.WithError(err).WithField("nzb_id", nzb.ID).Warn("Release doesn't fit on the download volume, not downloading")
+			cycle.add("disk_low", 1)
+			continue
+		}
+		if errors.Is(err, controllers.ErrAlreadyCovered) {
+			continue
+		}
+		if err != nil {
+			s.logger.WithError(err).WithField("nzb_id", nzb.ID).Error("Download failed")
+			cycle.fail()
+			continue
+		}
+		cycle.add("grabs", 1)
+	}
+}
+
+// searchedBefore orders media never searched first, then by last search
+func searchedBefore(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return a.Before(*b)
+}
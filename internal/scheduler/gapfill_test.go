@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+func TestSearchedBefore(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	shows := []*models.Media{
+		{ID: 1, LastSearchedAt: &recent},
+		{ID: 2},
+		{ID: 3, LastSearchedAt: &old},
+	}
+	sort.SliceStable(shows, func(i, j int) bool {
+		return searchedBefore(shows[i].LastSearchedAt, shows[j].LastSearchedAt)
+	})
+
+	for i, expected := range []uint64{2, 3, 1} {
+		if shows[i].ID != expected {
+			t.Errorf("shows[%d] = %d, expected %d (never searched first, then oldest search)", i, shows[i].ID, expected)
+		}
+	}
+}
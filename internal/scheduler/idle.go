@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+)
+
+// defaultIdleTasks run at most every IdleInterval while idle unless configured otherwise
+var defaultIdleTasks = []string{TaskSync, TaskSearch, TaskReconcile, TaskStuckCheck}
+
+// busyStatuses are the media statuses with a search or a download left to do
+var busyStatuses = []models.Status{models.StatusPending, models.StatusSearching, models.StatusDownloading}
+
+// configureIdle checks the tasks stretched while idle exist
+func (s *Scheduler) configureIdle() error {
+	names := s.taskOptions.IdleTasks
+	if names == nil {
+		names = defaultIdleTasks
+	}
+	for _, name := range names {
+		t := s.task(name)
+		if t == nil {
+			return fmt.Errorf("cannot stretch task %s while idle: %w", name, ErrUnknownTask)
+		}
+		t.stretched = true
+	}
+	return nil
+}
+
+// idle checks if every media item is on disk or given up on, with no download in flight
+func (s *Scheduler) idle() bool {
+	if s.taskOptions.IdleInterval <= 0 {
+		return false
+	}
+	for _, status := range busyStatuses {
+		medias, err := s.db.GetMediasByStatus(status)
+		if err != nil || len(medias) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// idleSkip checks if a scheduled run of a task is skipped while idle
+// A stretched task runs when its last run is older than the idle interval or happened
+// before the last wake-up, so activity brings back the normal schedule right away.
+func (s *Scheduler) idleSkip(t *task, now time.Time) bool {
+	if !t.stretched || s.taskOptions.IdleInterval <= 0 {
+		return false
+	}
+
+	t.stateMu.Lock()
+	lastRun := t.lastRun
+	t.stateMu.Unlock()
+	if lastRun == nil || now.Sub(*lastRun) >= s.taskOptions.IdleInterval {
+		return false
+	}
+
+	s.idleMu.Lock()
+	wokeAt := s.wokeAt
+	s.idleMu.Unlock()
+	if lastRun.Before(wokeAt) {
+		return false
+	}
+	return s.idle()
+}
+
+// Wake ends the idle stretching until the tasks find nothing to do again
+func (s *Scheduler) Wake(reason string) {
+	s.idleMu.Lock()
+	s.wokeAt = time.Now()
+	s.idleMu.Unlock()
+	s.logger.WithField("reason", reason).Debug("Activity detected, back to the normal schedule")
+}
+
+// watchActivity wakes the scheduler on media and download events until stop is closed
+// Webhooks completing or failing downloads come through as download events.
+func (s *Scheduler) watchActivity(stop <-chan struct{}) {
+	events, unsubscribe := s.notifier.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case notify.EventMediaAdded, notify.EventDownloadStarted, notify.EventDownloadCompleted, notify.EventDownloadFailed:
+				s.Wake(string(event.Type))
+			}
+		}
+	}
+}
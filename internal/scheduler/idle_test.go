@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestIdleSkip(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := models.NewDatabase(filepath.Join(t.TempDir(), "test.db"), logger)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	lastRun := now.Add(-time.Hour)
+	syncTask := &task{name: TaskSync, stretched: true, lastRun: &lastRun}
+	s := &Scheduler{db: db, taskOptions: TaskOptions{IdleInterval: 12 * time.Hour}, logger: logger}
+
+	if !s.idleSkip(syncTask, now) {
+		t.Error("task not skipped while idle")
+	}
+	if s.idleSkip(&task{name: TaskUpgrade, lastRun: &lastRun}, now) {
+		t.Error("task not stretched while idle was skipped")
+	}
+	if later := now.Add(12 * time.Hour); s.idleSkip(syncTask, later) {
+		t.Error("task skipped after the idle interval")
+	}
+
+	s.Wake("test")
+	if s.idleSkip(syncTask, now) {
+		t.Error("task skipped after a wake-up")
+	}
+
+	lastRun = time.Now()
+	if !s.idleSkip(syncTask, lastRun.Add(time.Minute)) {
+		t.Error("task not skipped after running since the wake-up")
+	}
+
+	if err := db.CreateMedia(&models.Media{Title: "Test", Status: models.StatusPending}); err != nil {
+		t.Fatalf("Failed to create media: %v", err)
+	}
+	if s.idleSkip(syncTask, lastRun.Add(time.Minute)) {
+		t.Error("task skipped with a pending media item")
+	}
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// Candidates runs a live search for a media item and returns the releases found,
+// best first, without downloading any of them
+func (s *Scheduler) Candidates(ctx context.Context, media *models.Media) ([]*models.NZB, error) {
+	if _, busy := s.processing.LoadOrStore(media.ID, true); busy {
+		return nil, fmt.Errorf("%w: media %d is being processed", controllers.ErrMediaBusy, media.ID)
+	}
+	defer s.processing.Delete(media.ID)
+
+	strategy, err := s.strategyCtrl.DetermineStrategy(ctx, media)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine strategy: %w", err)
+	}
+	return s.searchCtrl.FindCandidates(ctx, media, strategy)
+}
+
+// DownloadRelease downloads a release picked by hand unless its media is being processed
+func (s *Scheduler) DownloadRelease(nzb *models.NZB) error {
+	if _, busy := s.processing.LoadOrStore(nzb.MediaID, true); busy {
+		return fmt.Errorf("%w: media %d is being processed", controllers.ErrMediaBusy, nzb.MediaID)
+	}
+	defer s.processing.Delete(nzb.MediaID)
+
+	return s.downloadCtrl.DownloadRelease(nzb)
+}
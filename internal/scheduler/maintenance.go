@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultMaintenanceTasks are skipped during maintenance windows unless configured otherwise
+var defaultMaintenanceTasks = []string{TaskSearch, TaskRSS, TaskUpgrade, TaskSeasonPack, TaskGapFill}
+
+// MaintenanceWindow is a recurring period during which some tasks are skipped
+type MaintenanceWindow struct {
+	Start    string // Cron spec of the window start, CRON_TZ= prefix supported
+	Duration time.Duration
+}
+
+// maintenanceWindow is a parsed maintenance window
+type maintenanceWindow struct {
+	MaintenanceWindow
+	schedule cron.Schedule
+}
+
+// MaintenanceInfo describes a maintenance window
+type MaintenanceInfo struct {
+	Start     string    `json:"start"`
+	Duration  string    `json:"duration"`
+	Active    bool      `json:"active"`
+	NextStart time.Time `json:"next_start"`
+}
+
+// SchedulerInfo describes the scheduled tasks and maintenance windows
+type SchedulerInfo struct {
+	Tasks            []TaskInfo        `json:"tasks"`
+	Maintenance      []MaintenanceInfo `json:"maintenance"`
+	MaintenanceTasks []string          `json:"maintenance_tasks"`
+	InMaintenance    bool              `json:"in_maintenance"`
+}
+
+// configureMaintenance parses the maintenance windows and checks their tasks exist
+func (s *Scheduler) configureMaintenance() error {
+	for _, window := range s.taskOptions.Maintenance {
+		schedule, err := cron.ParseStandard(window.Start)
+		if err != nil {
+			return fmt.Errorf("invalid maintenance window %q: %w", window.Start, err)
+		}
+		s.maintenance = append(s.maintenance, maintenanceWindow{MaintenanceWindow: window, schedule: schedule})
+	}
+
+	s.maintenanceTasks = s.taskOptions.MaintenanceTasks
+	if s.maintenanceTasks == nil {
+		s.maintenanceTasks = defaultMaintenanceTasks
+	}
+	for _, name := range s.maintenanceTasks {
+		t := s.task(name)
+		if t == nil {
+			return fmt.Errorf("cannot pause task %s during maintenance: %w", name, ErrUnknownTask)
+		}
+		t.maintenance = true
+	}
+	return nil
+}
+
+// active checks if the window covers a point in time
+// The window is active when it started less than Duration ago.
+func (w maintenanceWindow) active(at time.Time) bool {
+	return !w.schedule.Next(at.Add(-w.Duration)).After(at)
+}
+
+// inMaintenance checks if a maintenance window is active
+func (s *Scheduler) inMaintenance(at time.Time) bool {
+	for _, window := range s.maintenance {
+		if window.active(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// Status describes the scheduled tasks and maintenance windows
+func (s *Scheduler) Status() SchedulerInfo {
+	now := time.Now()
+	info := SchedulerInfo{
+		Tasks:            s.Tasks(),
+		Maintenance:      make([]MaintenanceInfo, 0, len(s.maintenance)),
+		MaintenanceTasks: s.maintenanceTasks,
+		InMaintenance:    s.inMaintenance(now),
+	}
+	for _, window := range s.maintenance {
+		info.Maintenance = append(info.Maintenance, MaintenanceInfo{
+			Start:     window.Start,
+			Duration:  window.Duration.String(),
+			Active:    window.active(now),
+			NextStart: window.schedule.Next(now),
+		})
+	}
+	return info
+}
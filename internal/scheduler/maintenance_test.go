@@ -0,0 +1,33 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	schedule, err := cron.ParseStandard("CRON_TZ=UTC 45 23 * * *")
+	if err != nil {
+		t.Fatalf("ParseStandard failed: %v", err)
+	}
+	window := maintenanceWindow{MaintenanceWindow{Duration: 30 * time.Minute}, schedule}
+
+	tests := []struct {
+		at       string
+		expected bool
+	}{
+		{"2024-01-01T23:40:00Z", false},
+		{"2024-01-01T23:45:00Z", true},
+		{"2024-01-02T00:10:00Z", true},
+		{"2024-01-02T00:15:00Z", false},
+		{"2024-01-02T12:00:00Z", false},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if active := window.active(at); active != tt.expected {
+			t.Errorf("active(%s) = %v, expected %v", tt.at, active, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrAlreadyPaused = errors.New("scheduler is already paused")
+	ErrNotPaused     = errors.New("scheduler is not paused")
+)
+
+// OrchestratorInfo describes whether the automatic tasks run
+type OrchestratorInfo struct {
+	Paused        bool       `json:"paused"`
+	Reason        string     `json:"reason,omitempty"`
+	PausedAt      *time.Time `json:"paused_at,omitempty"`
+	ResumedAt     *time.Time `json:"resumed_at,omitempty"`
+	InMaintenance bool       `json:"in_maintenance"`
+	Idle          bool       `json:"idle"`    // Nothing wanted, IdleTasks run at most every IdleInterval
+	Running       []string   `json:"running"` // Tasks in flight, left to finish when pausing
+}
+
+// loadPause restores the pause state recorded before a restart
+func (s *Scheduler) loadPause() error {
+	pause, err := s.db.GetSchedulerPause()
+	if err != nil {
+		return fmt.Errorf("failed to load pause state: %w", err)
+	}
+
+	s.pauseMu.Lock()
+	s.pause = pause
+	s.pauseMu.Unlock()
+
+	if pause != nil && pause.Paused {
+		s.logger.WithFields(logrus.Fields{
+			"reason":    pause.Reason,
+			"paused_at": pause.PausedAt,
+		}).Warn("Scheduler paused, automatic tasks are skipped until resumed")
+	}
+	return nil
+}
+
+// paused reports whether the automatic tasks are paused
+func (s *Scheduler) paused() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return s.pause != nil && s.pause.Paused
+}
+
+// Pause stops the scheduled and startup task runs until Resume
+// Running tasks finish, webhooks are still handled and tasks can still be run on demand.
+func (s *Scheduler) Pause(reason string) error {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	if s.pause != nil && s.pause.Paused {
+		return ErrAlreadyPaused
+	}
+
+	now := time.Now()
+	pause := &models.SchedulerPause{Paused: true, Reason: reason, PausedAt: &now}
+	if err := s.db.SaveSchedulerPause(pause); err != nil {
+		return fmt.Errorf("failed to save pause state: %w", err)
+	}
+	s.pause = pause
+
+	s.logger.WithField("reason", reason).Warn("Scheduler paused")
+	return nil
+}
+
+// Resume restarts the scheduled task runs, each task running again at its next schedule
+func (s *Scheduler) Resume() error {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	if s.pause == nil || !s.pause.Paused {
+		return ErrNotPaused
+	}
+
+	now := time.Now()
+	pause := *s.pause
+	pause.Paused = false
+	pause.ResumedAt = &now
+	if err := s.db.SaveSchedulerPause(&pause); err != nil {
+		return fmt.Errorf("failed to save pause state: %w", err)
+	}
+	s.pause = &pause
+
+	s.logger.WithField("paused_for", now.Sub(*pause.PausedAt).Round(time.Second).String()).Info("Scheduler resumed")
+	return nil
+}
+
+// Orchestrator describes the pause state and the tasks in flight
+func (s *Scheduler) Orchestrator() OrchestratorInfo {
+	info := OrchestratorInfo{
+		InMaintenance: s.inMaintenance(time.Now()),
+		Idle:          s.idle(),
+		Running:       []string{},
+	}
+
+	s.pauseMu.Lock()
+	if s.pause != nil {
+		info.Paused = s.pause.Paused
+		info.Reason = s.pause.Reason
+		info.PausedAt = s.pause.PausedAt
+		info.ResumedAt = s.pause.ResumedAt
+	}
+	s.pauseMu.Unlock()
+
+	for _, t := range s.tasks {
+		t.stateMu.Lock()
+		if t.running {
+			info.Running = append(info.Running, t.name)
+		}
+		t.stateMu.Unlock()
+	}
+	return info
+}
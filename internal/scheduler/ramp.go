@@ -0,0 +1,215 @@
+package scheduler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRampShift bounds the doubling of the ramp-up limit
+const maxRampShift = 16
+
+// ErrRampInactive is returned when confirming a ramp-up that is not running
+var ErrRampInactive = errors.New("cold-start protection is not active")
+
+// GrabLimits caps the releases grabbed by scheduled searches
+// Manual searches are never limited.
+type GrabLimits struct {
+	ColdStart   int // Grabs of the first search cycle of a fresh install, doubled every cycle (0 disables the ramp-up)
+	MaxPerCycle int // Grabs per search cycle once ramped up, 0 for unlimited
+}
+
+// GrabRampInfo describes the cold-start protection
+type GrabRampInfo struct {
+	Active      bool       `json:"active"`
+	Cycles      int        `json:"cycles"`
+	Grabs       int        `json:"grabs"`
+	CycleLimit  int        `json:"cycle_limit"` // Grabs allowed in the next search cycle, 0 for unlimited
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+}
+
+// loadGrabRamp returns the cold-start state, recording it on first use
+// An install is fresh when no release was ever grabbed.
+// Must be called with rampMu held.
+func (s *Scheduler) loadGrabRamp() (*models.GrabRamp, error) {
+	ramp, err := s.db.GetGrabRamp()
+	if err != nil || ramp != nil {
+		return ramp, err
+	}
+
+	fresh := false
+	if s.grabLimits.ColdStart > 0 {
+		hasNZBs, err := s.db.HasNZBs()
+		if err != nil {
+			return nil, err
+		}
+		fresh = !hasNZBs
+	}
+
+	now := time.Now()
+	ramp = &models.GrabRamp{Active: fresh, StartedAt: now}
+	if !fresh {
+		ramp.EndedAt = &now
+	}
+	if err := s.db.SaveGrabRamp(ramp); err != nil {
+		return nil, err
+	}
+
+	if fresh {
+		s.logger.WithField("first_cycle_limit", s.rampLimit(ramp)).Info("Fresh install, limiting grabs while ramping up")
+	}
+	return ramp, nil
+}
+
+// rampLimit returns the grab limit of the next ramp-up cycle
+func (s *Scheduler) rampLimit(ramp *models.GrabRamp) int {
+	shift := ramp.Cycles
+	if shift > maxRampShift {
+		shift = maxRampShift
+	}
+	limit := s.grabLimits.ColdStart << shift
+	if s.grabLimits.MaxPerCycle > 0 && limit > s.grabLimits.MaxPerCycle {
+		limit = s.grabLimits.MaxPerCycle
+	}
+	return limit
+}
+
+// cycleGrabLimit returns the grab limit of the next search cycle, 0 for unlimited
+func (s *Scheduler) cycleGrabLimit() int {
+	s.rampMu.Lock()
+	defer s.rampMu.Unlock()
+
+	ramp, err := s.loadGrabRamp()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to load cold-start state")
+		return s.grabLimits.MaxPerCycle
+	}
+	if ramp.Active {
+		return s.rampLimit(ramp)
+	}
+	return s.grabLimits.MaxPerCycle
+}
+
+// advanceGrabRamp records a search cycle of the ramp-up
+// The ramp-up ends once a cycle leaves no media waiting (the backlog is caught up)
+// or its limit reaches MaxPerCycle.
+func (s *Scheduler) advanceGrabRamp(grabs int, deferred int) {
+	s.rampMu.Lock()
+	defer s.rampMu.Unlock()
+
+	ramp, err := s.loadGrabRamp()
+	if err != nil || !ramp.Active {
+		return
+	}
+
+	ramp.Cycles++
+	ramp.Grabs += grabs
+	next := s.rampLimit(ramp)
+	caughtUp := deferred == 0
+	if caughtUp || (s.grabLimits.MaxPerCycle > 0 && next >= s.grabLimits.MaxPerCycle) {
+		now := time.Now()
+		ramp.Active = false
+		ramp.EndedAt = &now
+	}
+
+	if err := s.db.SaveGrabRamp(ramp); err != nil {
+		s.logger.WithError(err).Warn("Failed to save cold-start state")
+		return
+	}
+
+	fields := logrus.Fields{
+		"cycles": ramp.Cycles,
+		"grabs":  ramp.Grabs,
+	}
+	if ramp.Active {
+		fields["next_cycle_limit"] = next
+		s.logger.WithFields(fields).Info("Ramping up grabs")
+	} else {
+		fields["caught_up"] = caughtUp
+		s.logger.WithFields(fields).Info("Cold-start ramp-up finished")
+	}
+}
+
+// GrabRamp describes the cold-start protection
+func (s *Scheduler) GrabRamp() (GrabRampInfo, error) {
+	s.rampMu.Lock()
+	defer s.rampMu.Unlock()
+
+	ramp, err := s.loadGrabRamp()
+	if err != nil {
+		return GrabRampInfo{}, err
+	}
+
+	info := GrabRampInfo{
+		Active:      ramp.Active,
+		Cycles:      ramp.Cycles,
+		Grabs:       ramp.Grabs,
+		CycleLimit:  s.grabLimits.MaxPerCycle,
+		StartedAt:   ramp.StartedAt,
+		EndedAt:     ramp.EndedAt,
+		ConfirmedAt: ramp.ConfirmedAt,
+	}
+	if ramp.Active {
+		info.CycleLimit = s.rampLimit(ramp)
+	}
+	return info, nil
+}
+
+// RampUpBacklog restarts the ramp-up for a backlog of new media, e.g. a batch import, when it
+// is larger than the first cycle limit. Reports whether the grabs are ramped up.
+func (s *Scheduler) RampUpBacklog(count int) (bool, error) {
+	if s.grabLimits.ColdStart <= 0 || count <= s.grabLimits.ColdStart {
+		return false, nil
+	}
+
+	s.rampMu.Lock()
+	defer s.rampMu.Unlock()
+
+	ramp, err := s.loadGrabRamp()
+	if err != nil {
+		return false, err
+	}
+	if ramp.Active {
+		return true, nil
+	}
+
+	*ramp = models.GrabRamp{Active: true, StartedAt: time.Now()}
+	if err := s.db.SaveGrabRamp(ramp); err != nil {
+		return false, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"backlog":           count,
+		"first_cycle_limit": s.rampLimit(ramp),
+	}).Info("Large backlog added, limiting grabs while ramping up")
+	return true, nil
+}
+
+// ConfirmGrabRamp ends the ramp-up, the next search cycles only apply MaxPerCycle
+func (s *Scheduler) ConfirmGrabRamp() error {
+	s.rampMu.Lock()
+	defer s.rampMu.Unlock()
+
+	ramp, err := s.loadGrabRamp()
+	if err != nil {
+		return err
+	}
+	if !ramp.Active {
+		return ErrRampInactive
+	}
+
+	now := time.Now()
+	ramp.Active = false
+	ramp.EndedAt = &now
+	ramp.ConfirmedAt = &now
+	if err := s.db.SaveGrabRamp(ramp); err != nil {
+		return err
+	}
+
+	s.logger.WithField("cycles", ramp.Cycles).Info("Cold-start limit lifted on request")
+	return nil
+}
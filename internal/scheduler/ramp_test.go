@@ -0,0 +1,22 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+func TestRampLimit(t *testing.T) {
+	s := &Scheduler{grabLimits: GrabLimits{ColdStart: 10, MaxPerCycle: 50}}
+
+	for cycles, expected := range []int{10, 20, 40, 50, 50} {
+		if limit := s.rampLimit(&models.GrabRamp{Cycles: cycles}); limit != expected {
+			t.Errorf("rampLimit after %d cycles = %d, expected %d", cycles, limit, expected)
+		}
+	}
+
+	s.grabLimits.MaxPerCycle = 0
+	if limit := s.rampLimit(&models.GrabRamp{Cycles: 100}); limit != 10<<maxRampShift {
+		t.Errorf("rampLimit after 100 cycles = %d, expected %d", limit, 10<<maxRampShift)
+	}
+}
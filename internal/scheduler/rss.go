@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/amaumene/gomenarr/internal/controllers"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/newznab"
+	"github.com/sirupsen/logrus"
+)
+
+// runRSS fetches the latest releases of the indexers once and grabs the ones wanted by
+// pending media, so new releases are downloaded within minutes instead of at the next
+// search. Media are only searched through the feed: their search backoff is left as is.
+func (s *Scheduler) runRSS(ctx context.Context) {
+	cycle := startCycle("rss_sync", "releases", "matches", "grabs", "over_quota", "disk_low")
+	defer s.finishCycle(cycle)
+
+	medias, err := s.db.GetPendingMedias()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get pending medias")
+		cycle.fail()
+		return
+	}
+	// Nothing wanted, spare the indexer API calls
+	if len(medias) == 0 {
+		return
+	}
+	if s.downloadCtrl.DiskSpaceLow() {
+		s.logger.Debug("Disk space low, skipping RSS sync")
+		return
+	}
+
+	feed, err := s.searchCtrl.LatestReleases(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to fetch latest releases")
+		cycle.fail()
+		return
+	}
+	cycle.add("releases", len(feed))
+
+	limit := s.cycleGrabLimit()
+	for _, media := range medias {
+		if s.canceled(ctx, TaskRSS) {
+			break
+		}
+		if limit > 0 && cycle.items["grabs"] >= limit {
+			break
+		}
+
+		matches := s.searchCtrl.FeedMatches(media, feed)
+		if len(matches) == 0 {
+			continue
+		}
+		// TV strategies depend on Trakt progress: leave the releases to the next search
+		if media.MediaType == models.MediaTypeTV && !s.traktClient.Available() {
+			continue
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+			"releases": len(matches),
+		}).Debug("Pending media found in latest releases")
+		cycle.add("matches", len(matches))
+
+		s.processMedia(ctx, media, cycle, s.feedSearch(matches))
+	}
+}
+
+// feedSearch returns a search that picks the releases of a media item among feed matches
+func (s *Scheduler) feedSearch(matches []newznab.SearchResult) searchFunc {
+	return func(ctx context.Context, media *models.Media, strategy *controllers.DownloadStrategy) ([]*models.NZB, error) {
+		return s.searchCtrl.SearchFeed(ctx, media, strategy, matches)
+	}
+}
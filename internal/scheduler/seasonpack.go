@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// runSeasonPackUpgrade replaces the episodes of seasons that finished airing with a season
+// pack, for the shows that want it. The episodes are replaced once the pack completes.
+func (s *Scheduler) runSeasonPackUpgrade(ctx context.Context) {
+	s.logger.Info("Running scheduled season pack upgrade")
+
+	cycle := startCycle("season_pack_upgrade", "checked", "upgrades")
+	defer s.finishCycle(cycle)
+
+	// Finished seasons are known from Trakt: wait for the next run while it is paused
+	if !s.traktClient.Available() {
+		s.logger.Info("Trakt unavailable, skipping season pack upgrade")
+		return
+	}
+
+	medias, err := s.db.GetAllMedias()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get medias")
+		cycle.fail()
+		return
+	}
+
+	for _, media := range medias {
+		if s.canceled(ctx, "season_pack_upgrade") {
+			break
+		}
+		if media.MediaType != models.MediaTypeTV || media.ParentID != 0 || media.SeasonNumber != nil {
+			continue
+		}
+		if !media.WantsPackUpgrade(s.seasonPackUpgrade) {
+			continue
+		}
+
+		if _, busy := s.processing.LoadOrStore(media.ID, true); busy {
+			continue
+		}
+		mediaCtx, cancel := s.mediaContext(ctx)
+		upgrades, err := s.upgradeSeasons(mediaCtx, media)
+		cancel()
+		s.processing.Delete(media.ID)
+
+		cycle.add("checked", 1)
+		cycle.add("upgrades", upgrades)
+		if err != nil {
+			s.logger.WithError(err).WithField("media_id", media.ID).Error("Season pack upgrade failed")
+			cycle.fail()
+		}
+	}
+
+	s.logger.Info("Season pack upgrade completed")
+}
+
+// upgradeSeasons starts a season pack download for each finished season of a show whose
+// episodes were downloaded one by one. Returns the number of downloads started.
+func (s *Scheduler) upgradeSeasons(ctx context.Context, media *models.Media) (int, error) {
+	nzbs, err := s.db.GetNZBsByMediaID(media.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get NZBs: %w", err)
+	}
+
+	// Completed episode releases by season. Seasons with a pack or a download in progress are left alone.
+	episodes := make(map[int][]*models.NZB)
+	busy := make(map[int]bool)
+	for _, nzb := range nzbs {
+		if nzb.Season == nil {
+			continue
+		}
+		season := *nzb.Season
+		switch {
+		case nzb.Status == models.NZBStatusDownloading:
+			busy[season] = true
+		case nzb.IsSeasonPack && nzb.Status == models.NZBStatusCompleted:
+			busy[season] = true
+		case nzb.Episode != nil && nzb.Status == models.NZBStatusCompleted:
+			episodes[season] = append(episodes[season], nzb)
+		}
+	}
+	if len(episodes) == 0 {
+		return 0, nil
+	}
+
+	// A pack would bring back the episodes already watched and cleaned up
+	watched, err := s.db.GetWatchedEntries(media.IMDBId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get watched ledger: %w", err)
+	}
+	for _, entry := range watched {
+		busy[entry.Season] = true
+	}
+
+	seasons, err := s.traktClient.GetSeasons(ctx, media.IMDBId)
+	if err != nil {
+		return 0, err
+	}
+
+	started := 0
+	for _, season := range seasons {
+		current := episodes[season.Number]
+		if season.Number == 0 || len(current) < 2 || busy[season.Number] || !season.Finished() {
+			continue
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"media_id": media.ID,
+			"title":    media.Title,
+			"season":   season.Number,
+			"episodes": len(current),
+		}).Debug("Searching season pack for finished season")
+
+		pack, err := s.searchCtrl.FindSeasonPack(ctx, media, season.Number, current)
+		if err != nil {
+			return started, fmt.Errorf("season %d: %w", season.Number, err)
+		}
+		if pack == nil {
+			continue
+		}
+
+		if err := s.downloadCtrl.DownloadSeasonUpgrade(pack, current); err != nil {
+			return started, fmt.Errorf("failed to download season pack: %w", err)
+		}
+		started++
+	}
+
+	return started, nil
+}
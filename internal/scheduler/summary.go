@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/metrics"
+	"github.com/amaumene/gomenarr/internal/services/hooks"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// cycleSummary accumulates what a single run of a scheduler task did
+type cycleSummary struct {
+	task     string
+	started  time.Time
+	items    map[string]int
+	failures int
+}
+
+// CycleStatus is the outcome of the last runs of a task cycle
+type CycleStatus struct {
+	LastRun     time.Time  `json:"last_run"`
+	Failures    int        `json:"failures"`               // Failures of the last run
+	LastSuccess *time.Time `json:"last_success,omitempty"` // Last run without failures
+}
+
+// startCycle begins the summary of a scheduler task run
+// items are the item kinds the task reports, reset to zero so gauges don't keep
+// values from a previous cycle that stopped early
+func startCycle(task string, items ...string) *cycleSummary {
+	c := &cycleSummary{
+		task:    task,
+		started: time.Now(),
+		items:   make(map[string]int),
+	}
+	for _, item := range items {
+		c.items[item] = 0
+	}
+	return c
+}
+
+// add counts processed items of a kind (e.g. "searches", "grabs")
+func (c *cycleSummary) add(item string, count int) {
+	c.items[item] += count
+}
+
+// fail counts a failure during the cycle
+func (c *cycleSummary) fail() {
+	c.failures++
+}
+
+// finishCycle emits the cycle summary as a single log line and as gauge metrics
+func (s *Scheduler) finishCycle(c *cycleSummary) {
+	duration := time.Since(c.started)
+
+	fields := logrus.Fields{
+		"task":        c.task,
+		"duration_ms": duration.Milliseconds(),
+		"failures":    c.failures,
+	}
+	env := map[string]string{
+		"GOMENARR_TASK":        c.task,
+		"GOMENARR_DURATION_MS": strconv.FormatInt(duration.Milliseconds(), 10),
+		"GOMENARR_FAILURES":    strconv.Itoa(c.failures),
+	}
+
+	items := make([]string, 0, len(c.items))
+	for item := range c.items {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+
+	for _, item := range items {
+		fields[item] = c.items[item]
+		env["GOMENARR_ITEM_"+strings.ToUpper(item)] = strconv.Itoa(c.items[item])
+		metrics.CycleItems.Set(float64(c.items[item]), c.task, item)
+	}
+
+	metrics.CycleFailures.Set(float64(c.failures), c.task)
+	metrics.CycleDuration.Set(duration.Seconds(), c.task)
+	metrics.CycleLastRun.Set(float64(time.Now().Unix()), c.task)
+	s.recordCycle(c)
+
+	s.logger.WithFields(fields).Info("Cycle summary")
+
+	counts := make(map[string]int, len(c.items))
+	for item, count := range c.items {
+		counts[item] = count
+	}
+	s.notifier.Publish(notify.Event{
+		Type:       notify.EventTaskCompleted,
+		Message:    "Task " + c.task + " completed",
+		Task:       c.task,
+		Counts:     counts,
+		Failures:   c.failures,
+		DurationMS: duration.Milliseconds(),
+	})
+
+	// Cycle hooks don't hold up the task, there is nothing to abort
+	go s.hooks.Run(context.Background(), hooks.StageCycleComplete, env)
+}
+
+// recordCycle keeps the outcome of a finished cycle for Cycles
+func (s *Scheduler) recordCycle(c *cycleSummary) {
+	s.cyclesMu.Lock()
+	defer s.cyclesMu.Unlock()
+
+	if s.cycles == nil {
+		s.cycles = make(map[string]CycleStatus)
+	}
+	now := time.Now()
+	status := s.cycles[c.task]
+	status.LastRun = now
+	status.Failures = c.failures
+	if c.failures == 0 {
+		status.LastSuccess = &now
+	}
+	s.cycles[c.task] = status
+}
+
+// Cycles returns the outcome of the last runs of each cycle since startup (e.g. "sync", "search")
+func (s *Scheduler) Cycles() map[string]CycleStatus {
+	s.cyclesMu.Lock()
+	defer s.cyclesMu.Unlock()
+
+	cycles := make(map[string]CycleStatus, len(s.cycles))
+	for name, status := range s.cycles {
+		cycles[name] = status
+	}
+	return cycles
+}
@@ -0,0 +1,355 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/tracing"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Task names
+const (
+	TaskBlacklistRefresh = "blacklist_refresh"
+	TaskSync             = "sync"
+	TaskSearch           = "search"
+	TaskCleanupWatched   = "cleanup_watched"
+	TaskStuckCheck       = "stuck_check"
+	TaskUpgrade          = "upgrade"
+	TaskSeasonPack       = "season_pack_upgrade"
+	TaskLibraryScan      = "library_scan"
+	TaskReconcile        = "reconcile_downloads"
+	TaskRecover          = "recover_downloads"
+	TaskGapFill          = "gap_fill"
+	TaskRSS              = "rss_sync"
+)
+
+// defaultStartupTasks run once when the scheduler starts
+var defaultStartupTasks = []string{TaskRecover, TaskBlacklistRefresh, TaskSync, TaskSearch}
+
+var (
+	ErrUnknownTask  = errors.New("unknown task")
+	ErrTaskDisabled = errors.New("task is disabled")
+	ErrTaskRunning  = errors.New("task is already running")
+	ErrTaskIdle     = errors.New("task is not running")
+)
+
+// TaskOptions customizes the scheduled tasks
+type TaskOptions struct {
+	Disabled  []string          // Tasks neither scheduled, run at startup nor run as a dependency
+	Schedules map[string]string // Cron schedule overrides by task name
+	Startup   []string          // Tasks run at startup, in order (dependencies are run first)
+
+	RecoveryMaxAge time.Duration // Age of the TorBox downloads checked by recover_downloads, 0 for all
+	GapFillShows   int           // Shows searched for missing episodes per gap_fill run, 0 disables the task
+	RSS            bool          // Grab the pending media found in the latest releases of the indexers
+
+	MediaTimeout time.Duration // Deadline of the search of a single media item, 0 for none
+	StallAfter   time.Duration // Run time after which the watchdog reports a task, 0 disables it
+
+	Maintenance      []MaintenanceWindow // Windows during which MaintenanceTasks are skipped
+	MaintenanceTasks []string            // Default: search, rss_sync, upgrade, season_pack_upgrade, gap_fill
+
+	IdleInterval time.Duration // Least time between two runs of IdleTasks while nothing is wanted, 0 disables idle mode
+	IdleTasks    []string      // Default: sync, search, reconcile_downloads, stuck_check
+}
+
+// TaskInfo describes a scheduled task
+type TaskInfo struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	DependsOn []string   `json:"depends_on"`
+	Enabled   bool       `json:"enabled"`
+	Running   bool       `json:"running"`
+	Paused    bool       `json:"paused"`  // Skipped by a scheduler pause or the current maintenance window
+	Stalled   bool       `json:"stalled"` // Running for longer than the watchdog threshold
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+}
+
+// task is a node of the task graph
+type task struct {
+	name      string
+	schedule  string
+	dependsOn []string // Tasks run before this one at startup and on demand
+	enabled   bool
+	run       func(ctx context.Context)
+
+	maintenance bool // Skipped during maintenance windows
+	stretched   bool // Run at most every IdleInterval while idle
+
+	entry cron.EntryID // Cron entry of a scheduled task, 0 when not scheduled
+
+	mu        sync.Mutex // Held while running, runs never overlap
+	stateMu   sync.Mutex
+	running   bool
+	startedAt *time.Time
+	cancel    context.CancelFunc // Cancels the current run
+	stalled   bool               // Current run reported by the watchdog
+	lastRun   *time.Time
+}
+
+// registerTasks builds the task graph
+func (s *Scheduler) registerTasks() {
+	s.tasks = []*task{
+		// Every day at 3am: Refresh the subscribed blacklists
+		{name: TaskBlacklistRefresh, schedule: "0 3 * * *", run: s.runBlacklistRefresh, enabled: s.blacklist.HasSubscriptions()},
+		// Every 6 hours: Sync from Trakt (also triggers immediate cleanup of removed items)
+		{name: TaskSync, schedule: "0 */6 * * *", run: s.runSync, enabled: true},
+		// Every 30 minutes: Process pending medias (search + download)
+		{name: TaskSearch, schedule: "*/30 * * * *", run: s.runSearch, dependsOn: []string{TaskSync}, enabled: true},
+		// Every 5 minutes: Grab the pending media found in the latest releases of the indexers
+		{name: TaskRSS, schedule: "*/5 * * * *", run: s.runRSS, enabled: s.taskOptions.RSS},
+		// Every hour: Cleanup watched medias
+		{name: TaskCleanupWatched, schedule: "0 * * * *", run: s.runCleanupWatched, dependsOn: []string{TaskSync}, enabled: true},
+		// Every 5 minutes: Poll TorBox for downloads whose webhook never arrived
+		{name: TaskReconcile, schedule: "*/5 * * * *", run: s.runReconcile, enabled: true},
+		// At startup only: Complete the downloads that finished while gomenarr was down
+		{name: TaskRecover, run: s.runRecover, enabled: true},
+		// Every 10 minutes: Check for stuck downloads
+		{name: TaskStuckCheck, schedule: "*/10 * * * *", run: s.runStuckDownloadCheck, enabled: true},
+		// Every day at 4am: Search completed movies for quality upgrades
+		{name: TaskUpgrade, schedule: "0 4 * * *", run: s.runUpgradeSearch, enabled: s.upgradeEnabled},
+		// Every day at 5am: Replace the episodes of finished seasons with season packs (shows opt in with notes)
+		{name: TaskSeasonPack, schedule: "0 5 * * *", run: s.runSeasonPackUpgrade, dependsOn: []string{TaskSync}, enabled: true},
+		// Every 6 hours: Search the missing episodes of shows monitored as a whole, a few shows at a time
+		{name: TaskGapFill, schedule: "15 */6 * * *", run: s.runGapFill, dependsOn: []string{TaskSync}, enabled: s.taskOptions.GapFillShows > 0},
+		// Every day at 2am: Match library files to media (also after a sync adds media)
+		{name: TaskLibraryScan, schedule: "0 2 * * *", run: s.runLibraryScan, enabled: s.libraryCtrl.Enabled()},
+	}
+}
+
+// configureTasks applies the task options
+func (s *Scheduler) configureTasks() error {
+	for _, name := range s.taskOptions.Disabled {
+		t := s.task(name)
+		if t == nil {
+			return fmt.Errorf("cannot disable task %s: %w", name, ErrUnknownTask)
+		}
+		t.enabled = false
+	}
+
+	for name, schedule := range s.taskOptions.Schedules {
+		t := s.task(name)
+		if t == nil {
+			return fmt.Errorf("cannot schedule task %s: %w", name, ErrUnknownTask)
+		}
+		if schedule != "" {
+			if _, err := cron.ParseStandard(schedule); err != nil {
+				return fmt.Errorf("invalid schedule %q for task %s: %w", schedule, name, err)
+			}
+		}
+		t.schedule = schedule
+	}
+
+	for _, name := range s.taskOptions.Startup {
+		if s.task(name) == nil {
+			return fmt.Errorf("cannot run task %s at startup: %w", name, ErrUnknownTask)
+		}
+	}
+
+	return nil
+}
+
+// task returns a task by name, nil if unknown
+func (s *Scheduler) task(name string) *task {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// plan orders tasks so each one runs after its dependencies
+// Each task appears once, disabled tasks are left out.
+func (s *Scheduler) plan(names []string, withDependencies bool) ([]*task, error) {
+	var ordered []*task
+	done := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if done[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("task dependency cycle at %s", name)
+		}
+		t := s.task(name)
+		if t == nil {
+			return fmt.Errorf("%w: %s", ErrUnknownTask, name)
+		}
+
+		visiting[name] = true
+		if withDependencies {
+			for _, dep := range t.dependsOn {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		visiting[name] = false
+		done[name] = true
+
+		if t.enabled {
+			ordered = append(ordered, t)
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// executeScheduled runs a scheduled or startup task unless the scheduler is paused or
+// a maintenance window pauses it. On-demand runs are not paused.
+func (s *Scheduler) executeScheduled(t *task) {
+	if s.paused() {
+		s.logger.WithField("task", t.name).Info("Scheduler paused, skipping task")
+		return
+	}
+	if t.maintenance && s.inMaintenance(time.Now()) {
+		s.logger.WithField("task", t.name).Info("Maintenance window active, skipping task")
+		return
+	}
+	if s.idleSkip(t, time.Now()) {
+		s.logger.WithField("task", t.name).Debug("Nothing wanted, skipping task until the idle interval elapses")
+		return
+	}
+	s.execute(t)
+}
+
+// execute runs a task unless it is already running
+func (s *Scheduler) execute(t *task) {
+	if !t.mu.TryLock() {
+		s.logger.WithField("task", t.name).Info("Task already running, skipping")
+		return
+	}
+	defer t.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "task "+t.name)
+	span.SetAttribute("task", t.name)
+	defer span.End()
+
+	started := time.Now()
+	t.stateMu.Lock()
+	t.running = true
+	t.startedAt = &started
+	t.cancel = cancel
+	t.stalled = false
+	t.stateMu.Unlock()
+
+	s.notifier.Publish(notify.Event{
+		Type:    notify.EventTaskStarted,
+		Message: "Task " + t.name + " started",
+		Task:    t.name,
+	})
+	t.run(ctx)
+
+	now := time.Now()
+	t.stateMu.Lock()
+	t.running = false
+	t.startedAt = nil
+	t.cancel = nil
+	t.lastRun = &now
+	t.stateMu.Unlock()
+}
+
+// Tasks describes the scheduled tasks
+func (s *Scheduler) Tasks() []TaskInfo {
+	inMaintenance := s.inMaintenance(time.Now())
+	paused := s.paused()
+	infos := make([]TaskInfo, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		t.stateMu.Lock()
+		infos = append(infos, TaskInfo{
+			Name:      t.name,
+			Schedule:  t.schedule,
+			DependsOn: append([]string{}, t.dependsOn...),
+			Enabled:   t.enabled,
+			Running:   t.running,
+			Paused:    paused || (t.maintenance && inMaintenance),
+			Stalled:   t.stalled,
+			StartedAt: t.startedAt,
+			LastRun:   t.lastRun,
+			NextRun:   s.nextRun(t),
+		})
+		t.stateMu.Unlock()
+	}
+	return infos
+}
+
+// nextRun returns when a scheduled task runs next, nil if it isn't scheduled
+func (s *Scheduler) nextRun(t *task) *time.Time {
+	if t.entry == 0 {
+		return nil
+	}
+	next := s.cron.Entry(t.entry).Next
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// Schedule returns the enabled scheduled tasks, soonest first
+func (s *Scheduler) Schedule() []TaskInfo {
+	var schedule []TaskInfo
+	for _, info := range s.Tasks() {
+		if info.Enabled && info.NextRun != nil {
+			schedule = append(schedule, info)
+		}
+	}
+	sort.SliceStable(schedule, func(i, j int) bool {
+		return schedule[i].NextRun.Before(*schedule[j].NextRun)
+	})
+	return schedule
+}
+
+// RunTask starts a task in the background, after its enabled dependencies if withDependencies is set
+func (s *Scheduler) RunTask(name string, withDependencies bool) error {
+	t := s.task(name)
+	if t == nil {
+		return ErrUnknownTask
+	}
+	if !t.enabled {
+		return ErrTaskDisabled
+	}
+
+	t.stateMu.Lock()
+	running := t.running
+	t.stateMu.Unlock()
+	if running {
+		return ErrTaskRunning
+	}
+
+	plan, err := s.plan([]string{name}, withDependencies)
+	if err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"task":  name,
+		"tasks": len(plan),
+	}).Info("Running task on demand")
+
+	go func() {
+		for _, t := range plan {
+			s.execute(t)
+		}
+	}()
+	return nil
+}
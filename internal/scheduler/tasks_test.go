@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	s := &Scheduler{
+		tasks: []*task{
+			{name: TaskSync, enabled: true},
+			{name: TaskSearch, dependsOn: []string{TaskSync}, enabled: true},
+			{name: TaskCleanupWatched, dependsOn: []string{TaskSync}, enabled: true},
+			{name: TaskUpgrade, enabled: false},
+		},
+	}
+
+	plan, err := s.plan([]string{TaskCleanupWatched, TaskSearch, TaskUpgrade}, true)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+
+	var names []string
+	for _, task := range plan {
+		names = append(names, task.name)
+	}
+	expected := []string{TaskSync, TaskCleanupWatched, TaskSearch}
+	if len(names) != len(expected) {
+		t.Fatalf("plan = %v, expected %v", names, expected)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("plan = %v, expected %v", names, expected)
+		}
+	}
+
+	plan, err = s.plan([]string{TaskSearch}, false)
+	if err != nil || len(plan) != 1 || plan[0].name != TaskSearch {
+		t.Errorf("plan without dependencies = %v, %v, expected search only", plan, err)
+	}
+
+	if _, err := s.plan([]string{"unknown"}, true); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("Expected ErrUnknownTask, got %v", err)
+	}
+}
+
+func TestConfigureTasksSchedules(t *testing.T) {
+	newScheduler := func(schedules map[string]string) *Scheduler {
+		return &Scheduler{
+			tasks:       []*task{{name: TaskSync, schedule: "0 */6 * * *", enabled: true}},
+			taskOptions: TaskOptions{Schedules: schedules},
+		}
+	}
+
+	s := newScheduler(map[string]string{TaskSync: "0 */4 * * *"})
+	if err := s.configureTasks(); err != nil {
+		t.Fatalf("configureTasks failed: %v", err)
+	}
+	if got := s.task(TaskSync).schedule; got != "0 */4 * * *" {
+		t.Errorf("schedule = %q, expected the override", got)
+	}
+
+	if err := newScheduler(map[string]string{TaskSync: "every 4 hours"}).configureTasks(); err == nil {
+		t.Error("Expected an error for an invalid schedule")
+	}
+	if err := newScheduler(map[string]string{"unknown": "* * * * *"}).configureTasks(); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("Expected ErrUnknownTask, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// watchdogInterval is how often the watchdog checks the running tasks
+const watchdogInterval = time.Minute
+
+// CancelTask cancels the current run of a task
+// The task stops before its next media item, the one being searched is interrupted.
+func (s *Scheduler) CancelTask(name string) error {
+	t := s.task(name)
+	if t == nil {
+		return ErrUnknownTask
+	}
+
+	t.stateMu.Lock()
+	cancel := t.cancel
+	t.stateMu.Unlock()
+	if cancel == nil {
+		return ErrTaskIdle
+	}
+
+	s.logger.WithField("task", name).Warn("Canceling task")
+	cancel()
+	return nil
+}
+
+// watchdog reports the tasks running for longer than the stall threshold until stop is closed
+func (s *Scheduler) watchdog(stop <-chan struct{}) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.checkStalled(now)
+		}
+	}
+}
+
+// checkStalled reports each run exceeding the stall threshold once
+func (s *Scheduler) checkStalled(now time.Time) {
+	for _, t := range s.tasks {
+		t.stateMu.Lock()
+		stalled := t.running && !t.stalled && t.startedAt != nil && now.Sub(*t.startedAt) > s.taskOptions.StallAfter
+		if stalled {
+			t.stalled = true
+		}
+		var running time.Duration
+		if t.startedAt != nil {
+			running = now.Sub(*t.startedAt)
+		}
+		t.stateMu.Unlock()
+
+		if !stalled {
+			continue
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"task":    t.name,
+			"running": running.Round(time.Second).String(),
+		}).Warn("Task running for longer than expected, cancel it with POST /api/tasks/{name}/cancel")
+		s.notifier.Notify(notify.Event{
+			Type:       notify.EventTaskStalled,
+			Message:    fmt.Sprintf("Task %s has been running for %s", t.name, running.Round(time.Minute)),
+			Task:       t.name,
+			DurationMS: running.Milliseconds(),
+		})
+	}
+}
+
+// mediaContext bounds the search of a single media item by the media timeout
+func (s *Scheduler) mediaContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.taskOptions.MediaTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.taskOptions.MediaTimeout)
+}
+
+// interrupted keeps a media item pending when its search hit its deadline or the task was canceled
+// Returns false when the error has another cause.
+func (s *Scheduler) interrupted(ctx context.Context, media *models.Media, err error, cycle *cycleSummary) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+
+	s.logger.WithError(err).WithFields(logrus.Fields{
+		"media_id": media.ID,
+		"cause":    ctx.Err(),
+	}).Warn("Search interrupted, keeping media pending")
+	cycle.fail()
+	media.Status = models.StatusPending
+	s.db.UpdateMedia(media)
+	return true
+}
+
+// canceled reports whether a task was canceled, remaining items wait for its next run
+func (s *Scheduler) canceled(ctx context.Context, task string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	s.logger.WithField("task", task).Warn("Task canceled, remaining items wait for the next run")
+	return true
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCheckStalled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	started := time.Now().Add(-2 * time.Hour)
+	long := &task{name: TaskSearch, running: true, startedAt: &started}
+	recent := time.Now().Add(-time.Minute)
+	short := &task{name: TaskSync, running: true, startedAt: &recent}
+	idle := &task{name: TaskUpgrade}
+
+	s := &Scheduler{tasks: []*task{long, short, idle}, taskOptions: TaskOptions{StallAfter: time.Hour}, logger: logger}
+	s.checkStalled(time.Now())
+
+	if !long.stalled {
+		t.Error("task running for 2h not reported with a 1h threshold")
+	}
+	if short.stalled || idle.stalled {
+		t.Error("task reported before the threshold")
+	}
+
+	if err := s.CancelTask(TaskUpgrade); err != ErrTaskIdle {
+		t.Errorf("CancelTask of an idle task = %v, expected %v", err, ErrTaskIdle)
+	}
+}
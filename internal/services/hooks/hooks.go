@@ -0,0 +1,217 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// maxOutput bounds the command output kept in the hook history
+const maxOutput = 4096
+
+// Stage is a point of the pipeline where hooks run
+type Stage string
+
+const (
+	StagePreGrab       Stage = config.HookStagePreGrab       // Before a release is sent to TorBox
+	StagePostImport    Stage = config.HookStagePostImport    // After a completed download is imported
+	StagePreDelete     Stage = config.HookStagePreDelete     // Before a media item and its files are deleted
+	StageCycleComplete Stage = config.HookStageCycleComplete // After a scheduled task cycle
+)
+
+// inheritedEnv lists the variables of the gomenarr environment hooks get, API keys and
+// other secrets of the configuration stay out of hook commands
+var inheritedEnv = []string{"PATH", "HOME", "USER", "SHELL", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// ErrAborted is returned when a hook with the abort policy fails
+var ErrAborted = errors.New("aborted by hook")
+
+// Hook is an external command run at a pipeline stage
+type Hook struct {
+	Name    string
+	Stage   Stage
+	Command string
+	Timeout time.Duration
+	Abort   bool // A failure cancels the stage instead of being ignored
+}
+
+// Runner runs the hooks of a stage and records their runs
+// A nil Runner is valid and runs nothing.
+type Runner struct {
+	hooks  []Hook
+	db     *models.Database
+	logger *logrus.Logger
+}
+
+// NewRunner creates a runner with the hooks from configuration
+func NewRunner(cfg *config.Config, db *models.Database, logger *logrus.Logger) *Runner {
+	runner := &Runner{
+		db:     db,
+		logger: logger,
+	}
+	for _, hookCfg := range cfg.Hooks {
+		runner.hooks = append(runner.hooks, Hook{
+			Name:    hookCfg.Name,
+			Stage:   Stage(hookCfg.Stage),
+			Command: hookCfg.Command,
+			Timeout: hookCfg.Timeout,
+			Abort:   hookCfg.Policy == config.HookPolicyAbort,
+		})
+	}
+	return runner
+}
+
+// Hooks returns the configured hooks
+func (r *Runner) Hooks() []Hook {
+	if r == nil {
+		return nil
+	}
+	return r.hooks
+}
+
+// Run runs the hooks of a stage one after the other with env added to their environment
+// Hooks only inherit the basic variables of the gomenarr environment (PATH, HOME, ...).
+// Returns an error wrapping ErrAborted when a hook with the abort policy fails, the
+// remaining hooks of the stage are skipped. Other failures are logged and recorded only.
+func (r *Runner) Run(ctx context.Context, stage Stage, env map[string]string) error {
+	if r == nil {
+		return nil
+	}
+
+	for _, hook := range r.hooks {
+		if hook.Stage != stage {
+			continue
+		}
+
+		run := r.execute(ctx, hook, env)
+		run.Aborted = run.Error != "" && hook.Abort
+		if mediaID, err := strconv.ParseUint(env["GOMENARR_MEDIA_ID"], 10, 64); err == nil {
+			run.MediaID = mediaID
+		}
+		if err := r.db.RecordHookRun(run); err != nil {
+			r.logger.WithError(err).WithField("hook", hook.Name).Warn("Failed to record hook run")
+		}
+
+		fields := logrus.Fields{
+			"hook":        hook.Name,
+			"stage":       stage,
+			"exit_code":   run.ExitCode,
+			"duration_ms": run.Duration.Milliseconds(),
+		}
+		if run.Error == "" {
+			r.logger.WithFields(fields).Debug("Hook completed")
+			continue
+		}
+		if run.Aborted {
+			r.logger.WithFields(fields).WithField("error", run.Error).Warn("Hook failed, aborting")
+			return fmt.Errorf("%w %s: %s", ErrAborted, hook.Name, run.Error)
+		}
+		r.logger.WithFields(fields).WithField("error", run.Error).Warn("Hook failed")
+	}
+
+	return nil
+}
+
+// execute runs a hook command with its timeout and captures its output
+func (r *Runner) execute(ctx context.Context, hook Hook, env map[string]string) *models.HookRun {
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	for _, key := range inheritedEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	cmd.Env = append(cmd.Env, "GOMENARR_HOOK="+hook.Name, "GOMENARR_STAGE="+string(hook.Stage))
+	for _, key := range sortedKeys(env) {
+		cmd.Env = append(cmd.Env, key+"="+env[key])
+	}
+	// Children keeping the output pipes open must not outlive the timeout
+	cmd.WaitDelay = time.Second
+
+	run := &models.HookRun{
+		Hook:      hook.Name,
+		Stage:     string(hook.Stage),
+		StartedAt: time.Now(),
+	}
+	output, err := cmd.CombinedOutput()
+	run.Duration = time.Since(run.StartedAt)
+	run.Output = truncate(string(output), maxOutput)
+	run.ExitCode = cmd.ProcessState.ExitCode() // -1 when the command didn't start or was killed
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		run.Error = fmt.Sprintf("timed out after %s", hook.Timeout)
+	case err != nil:
+		run.Error = err.Error()
+	}
+	return run
+}
+
+// EventEnv converts a notification event into hook environment variables
+func EventEnv(event notify.Event) map[string]string {
+	env := map[string]string{
+		"GOMENARR_EVENT": string(event.Type),
+	}
+	set := func(key, value string) {
+		if value != "" {
+			env[key] = value
+		}
+	}
+
+	if event.MediaID != 0 {
+		env["GOMENARR_MEDIA_ID"] = strconv.FormatUint(event.MediaID, 10)
+	}
+	set("GOMENARR_IMDB_ID", event.IMDBId)
+	set("GOMENARR_TITLE", event.Title)
+	if event.Year != 0 {
+		env["GOMENARR_YEAR"] = strconv.Itoa(event.Year)
+	}
+	set("GOMENARR_MEDIA_TYPE", event.MediaType)
+	if event.Season != nil {
+		env["GOMENARR_SEASON"] = strconv.Itoa(*event.Season)
+	}
+	if event.Episode != nil {
+		env["GOMENARR_EPISODE"] = strconv.Itoa(*event.Episode)
+	}
+
+	set("GOMENARR_RELEASE", event.Release)
+	set("GOMENARR_QUALITY", event.Quality)
+	if event.Size != 0 {
+		env["GOMENARR_SIZE"] = strconv.FormatInt(event.Size, 10)
+	}
+	set("GOMENARR_INDEXER", event.Indexer)
+	set("GOMENARR_PROTOCOL", event.Protocol)
+	set("GOMENARR_JOB_ID", event.JobID)
+	return env
+}
+
+// sortedKeys returns the keys of an environment map in order
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// truncate keeps the end of an output, where errors usually are
+func truncate(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	return "..." + strings.TrimLeft(output[len(output)-limit:], "\n")
+}
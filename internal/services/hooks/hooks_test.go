@@ -0,0 +1,96 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRunnerRun(t *testing.T) {
+	db, err := models.NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	runner := &Runner{
+		hooks: []Hook{
+			{Name: "echo", Stage: StagePreGrab, Command: `echo "$GOMENARR_STAGE $GOMENARR_TITLE"`, Timeout: 5 * time.Second},
+			{Name: "ignored", Stage: StagePreGrab, Command: "exit 3", Timeout: 5 * time.Second},
+			{Name: "other", Stage: StagePreDelete, Command: "exit 1", Timeout: 5 * time.Second, Abort: true},
+		},
+		db:     db,
+		logger: logrus.New(),
+	}
+
+	if err := runner.Run(context.Background(), StagePreGrab, map[string]string{"GOMENARR_TITLE": "Movie", "GOMENARR_MEDIA_ID": "7"}); err != nil {
+		t.Fatalf("Run returned %v, expected ignored failures", err)
+	}
+
+	runs, err := db.GetHookRuns(0)
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("GetHookRuns = %d runs (%v), expected 2", len(runs), err)
+	}
+	failed, echoed := runs[0], runs[1]
+	if strings.TrimSpace(echoed.Output) != "pre-grab Movie" || echoed.Error != "" || echoed.MediaID != 7 {
+		t.Errorf("Unexpected echo run %+v", echoed)
+	}
+	if failed.ExitCode != 3 || failed.Error == "" || failed.Aborted {
+		t.Errorf("Unexpected ignored run %+v", failed)
+	}
+
+	// Abort policy
+	if err := runner.Run(context.Background(), StagePreDelete, nil); !errors.Is(err, ErrAborted) {
+		t.Errorf("Run returned %v, expected ErrAborted", err)
+	}
+
+	// Timeout
+	runner.hooks = []Hook{{Name: "slow", Stage: StagePostImport, Command: "sleep 5", Timeout: 100 * time.Millisecond}}
+	start := time.Now()
+	runner.Run(context.Background(), StagePostImport, nil)
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("Hook ran for %s, expected the timeout to stop it", time.Since(start))
+	}
+	if runs, _ := db.GetHookRuns(1); len(runs) != 1 || !strings.Contains(runs[0].Error, "timed out") {
+		t.Errorf("Expected a timed out run, got %+v", runs)
+	}
+
+	// A nil runner runs nothing
+	var none *Runner
+	if err := none.Run(context.Background(), StagePreGrab, nil); err != nil {
+		t.Errorf("nil Runner returned %v", err)
+	}
+}
+
+func TestRunnerEnvironment(t *testing.T) {
+	db, err := models.NewDatabase(filepath.Join(t.TempDir(), "test.db"), logrus.New())
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	t.Setenv("TORBOX_API_KEY", "secret")
+	runner := &Runner{
+		hooks:  []Hook{{Name: "env", Stage: StagePreGrab, Command: `echo "${TORBOX_API_KEY:-unset} $GOMENARR_TITLE"; command -v sh >/dev/null`, Timeout: 5 * time.Second}},
+		db:     db,
+		logger: logrus.New(),
+	}
+	if err := runner.Run(context.Background(), StagePreGrab, map[string]string{"GOMENARR_TITLE": "Movie"}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	runs, err := db.GetHookRuns(1)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("GetHookRuns = %d runs (%v), expected 1", len(runs), err)
+	}
+	// PATH is passed on (sh is found), secrets of the configuration are not
+	if strings.TrimSpace(runs[0].Output) != "unset Movie" || runs[0].Error != "" {
+		t.Errorf("Unexpected run %+v", runs[0])
+	}
+}
@@ -0,0 +1,192 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned without sending the request while a host is failing
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerHalfOpen = "half_open"
+	BreakerOpen     = "open"
+)
+
+// breakerStateValues are the metric values of the breaker states
+var breakerStateValues = map[string]float64{BreakerClosed: 0, BreakerHalfOpen: 1, BreakerOpen: 2}
+
+// BreakerState exposes the circuit breaker state of each external host (0 closed, 1 half-open, 2 open)
+var BreakerState = metrics.NewGauge("gomenarr_circuit_breaker_state",
+	"Circuit breaker state of an external API host (0 closed, 1 half-open, 2 open).", "service", "host")
+
+// BreakerInfo describes the circuit breaker of a host
+type BreakerInfo struct {
+	Service  string     `json:"service"`
+	Host     string     `json:"host"`
+	State    string     `json:"state"`
+	Failures int        `json:"consecutive_failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"` // When the next request is let through to probe the host
+}
+
+// breaker counts the consecutive failures of a host
+// Once open, requests fail fast until the cooldown ends, then a single probe request
+// decides whether the breaker closes or stays open for another cooldown.
+type breaker struct {
+	state    string
+	failures int
+	openedAt time.Time
+}
+
+// transports are all the transports created, listed by Breakers
+var transports struct {
+	mu   sync.Mutex
+	list []*Transport
+}
+
+// register records a transport for Breakers
+func register(t *Transport) {
+	transports.mu.Lock()
+	defer transports.mu.Unlock()
+	transports.list = append(transports.list, t)
+}
+
+// Breakers describes the circuit breakers of every host contacted so far
+func Breakers() []BreakerInfo {
+	transports.mu.Lock()
+	list := transports.list
+	transports.mu.Unlock()
+
+	infos := []BreakerInfo{}
+	for _, t := range list {
+		infos = append(infos, t.breakerInfos()...)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Service != infos[j].Service {
+			return infos[i].Service < infos[j].Service
+		}
+		return infos[i].Host < infos[j].Host
+	})
+	return infos
+}
+
+// breakerInfos describes the circuit breakers of a transport
+func (t *Transport) breakerInfos() []BreakerInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	infos := make([]BreakerInfo, 0, len(t.breakers))
+	for host, b := range t.breakers {
+		info := BreakerInfo{
+			Service:  t.options.Name,
+			Host:     host,
+			State:    b.state,
+			Failures: b.failures,
+		}
+		if b.state != BreakerClosed {
+			openedAt := b.openedAt
+			retryAt := openedAt.Add(t.options.BreakerCooldown)
+			info.OpenedAt = &openedAt
+			info.RetryAt = &retryAt
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// allow checks if a request to a host can be sent
+func (t *Transport) allow(host string) error {
+	if t.options.BreakerFailures <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.breaker(host)
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < t.options.BreakerCooldown {
+			return fmt.Errorf("%w for %s %s", ErrCircuitOpen, t.options.Name, host)
+		}
+		t.setState(host, b, BreakerHalfOpen)
+		return nil
+	case BreakerHalfOpen:
+		// A probe is already in flight
+		return fmt.Errorf("%w for %s %s", ErrCircuitOpen, t.options.Name, host)
+	default:
+		return nil
+	}
+}
+
+// record updates the breaker of a host with the outcome of a request
+// Requests cancelled by the caller don't say anything about the host.
+func (t *Transport) record(ctx context.Context, host string, failed bool) {
+	if t.options.BreakerFailures <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.breaker(host)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		if b.state == BreakerHalfOpen {
+			t.setState(host, b, BreakerOpen)
+		}
+		return
+	}
+
+	if !failed {
+		if b.state != BreakerClosed {
+			t.logger.WithFields(logrus.Fields{
+				"service": t.options.Name,
+				"host":    host,
+			}).Info("Circuit breaker closed, host responding again")
+		}
+		b.failures = 0
+		t.setState(host, b, BreakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= t.options.BreakerFailures {
+		b.openedAt = time.Now()
+		if b.state != BreakerOpen {
+			t.logger.WithFields(logrus.Fields{
+				"service":  t.options.Name,
+				"host":     host,
+				"failures": b.failures,
+				"cooldown": t.options.BreakerCooldown.String(),
+			}).Warn("Circuit breaker opened, failing requests fast")
+		}
+		t.setState(host, b, BreakerOpen)
+	}
+}
+
+// breaker returns the breaker of a host, must be called with mu held
+func (t *Transport) breaker(host string) *breaker {
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &breaker{state: BreakerClosed}
+		t.breakers[host] = b
+		BreakerState.Set(breakerStateValues[BreakerClosed], t.options.Name, host)
+	}
+	return b
+}
+
+// setState changes the state of a breaker, must be called with mu held
+func (t *Transport) setState(host string, b *breaker, state string) {
+	b.state = state
+	BreakerState.Set(breakerStateValues[state], t.options.Name, host)
+}
@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/config"
+)
+
+// HostTLS is the TLS setup of the connections to a host
+type HostTLS struct {
+	SkipVerify bool           // Accept self-signed and invalid certificates, pins still apply
+	RootCAs    *x509.CertPool // Trusted CAs, nil for the system roots
+}
+
+// LoadCAFile adds the certificates of a PEM bundle to pool, the system roots when nil
+func LoadCAFile(pool *x509.CertPool, path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	if pool == nil {
+		if pool, err = x509.SystemCertPool(); err != nil {
+			pool = x509.NewCertPool()
+		}
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificate in %s", path)
+	}
+	return pool, nil
+}
+
+// dialingTransport returns a copy of base dialing over one IP family and resolving
+// host names with a DNS server, as set in the options
+func dialingTransport(base *http.Transport, options Options) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if options.DNSServer != "" {
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, options.DNSServer)
+			},
+		}
+	}
+
+	transport := base.Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		switch options.IPFamily {
+		case config.IPFamilyV4:
+			network = strings.Replace(network, "tcp", "tcp4", 1)
+		case config.IPFamilyV6:
+			network = strings.Replace(network, "tcp", "tcp6", 1)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+// hostTransport sends the requests to the hosts with their own TLS setup through
+// dedicated transports, the others through the default one
+type hostTransport struct {
+	fallback http.RoundTripper
+	hosts    map[string]http.RoundTripper
+}
+
+// newHostTransport builds a transport per host of the TLS setups, copies of base
+func newHostTransport(base *http.Transport, hosts map[string]HostTLS) *hostTransport {
+	t := &hostTransport{
+		fallback: base,
+		hosts:    make(map[string]http.RoundTripper),
+	}
+	for host, setup := range hosts {
+		transport := base.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = setup.SkipVerify
+		if setup.RootCAs != nil {
+			transport.TLSClientConfig.RootCAs = setup.RootCAs
+		}
+		t.hosts[strings.ToLower(host)] = transport
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := t.hosts[strings.ToLower(req.URL.Hostname())]; ok {
+		return transport.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
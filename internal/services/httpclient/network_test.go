@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestHostTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	get := func(hosts map[string]HostTLS) error {
+		client := &http.Client{Transport: NewTransport(Options{Name: "test", TLS: hosts}, logrus.New())}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(nil); err == nil {
+		t.Error("Expected the self-signed certificate to be rejected by default")
+	}
+	if err := get(map[string]HostTLS{"127.0.0.1": {SkipVerify: true}}); err != nil {
+		t.Errorf("Expected skip verify to accept the certificate: %v", err)
+	}
+	if err := get(map[string]HostTLS{"other.example": {SkipVerify: true}}); err == nil {
+		t.Error("Expected skip verify to only apply to its host")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := LoadCAFile(x509.NewCertPool(), caFile)
+	if err != nil {
+		t.Fatalf("LoadCAFile failed: %v", err)
+	}
+	if err := get(map[string]HostTLS{"127.0.0.1": {RootCAs: pool}}); err != nil {
+		t.Errorf("Expected the CA bundle to be trusted: %v", err)
+	}
+}
+
+func TestIPFamily(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	get := func(family string) error {
+		client := &http.Client{Transport: NewTransport(Options{Name: "test", IPFamily: family}, logrus.New())}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get("ipv4"); err != nil {
+		t.Errorf("Expected an IPv4 connection to the IPv4 server: %v", err)
+	}
+	if err := get("ipv6"); err == nil {
+		t.Error("Expected IPv6 only connections to fail on an IPv4 address")
+	}
+}
@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrPinMismatch is returned when a host presents a certificate matching none of its pins
+var ErrPinMismatch = errors.New("certificate does not match the pinned fingerprints")
+
+// spkiPinPrefix marks a pin of the certificate public key (SPKI) rather than of the whole certificate
+const spkiPinPrefix = "sha256/"
+
+// pin is the SHA-256 fingerprint of a certificate or of its public key
+type pin struct {
+	spki bool
+	sum  []byte
+}
+
+// PinSet holds the certificate pins of each host name
+type PinSet map[string][]pin
+
+// NewPinSet parses the pins of each host name
+// A pin is either "sha256/<base64>", the SHA-256 of the certificate public key (SPKI, as
+// printed by curl --pinnedpubkey), or the hex SHA-256 fingerprint of the certificate,
+// colons allowed (as printed by openssl x509 -fingerprint -sha256).
+func NewPinSet(hosts map[string][]string) (PinSet, error) {
+	pins := make(PinSet)
+	for host, values := range hosts {
+		for _, value := range values {
+			p, err := parsePin(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pin %q for %s: %w", value, host, err)
+			}
+			host = strings.ToLower(host)
+			pins[host] = append(pins[host], p)
+		}
+	}
+	return pins, nil
+}
+
+// parsePin decodes a SPKI or certificate pin
+func parsePin(value string) (pin, error) {
+	value = strings.TrimSpace(value)
+	if encoded, ok := strings.CutPrefix(value, spkiPinPrefix); ok {
+		sum, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(sum) != sha256.Size {
+			return pin{}, fmt.Errorf("expected the base64 SHA-256 of the public key")
+		}
+		return pin{spki: true, sum: sum}, nil
+	}
+
+	sum, err := hex.DecodeString(strings.ReplaceAll(value, ":", ""))
+	if err != nil || len(sum) != sha256.Size {
+		return pin{}, fmt.Errorf("expected sha256/<base64> or a hex SHA-256 certificate fingerprint")
+	}
+	return pin{sum: sum}, nil
+}
+
+// matches reports whether a certificate matches the pin
+func (p pin) matches(cert *x509.Certificate) bool {
+	if p.spki {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		return bytes.Equal(p.sum, sum[:])
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return bytes.Equal(p.sum, sum[:])
+}
+
+// verify checks that a certificate of the verified chains (leaf, intermediate or root) matches a pin of the host
+// Hosts without pins are accepted, the usual certificate verification still applies to all.
+func (s PinSet) verify(state tls.ConnectionState) error {
+	pins := s[strings.ToLower(state.ServerName)]
+	if len(pins) == 0 {
+		return nil
+	}
+
+	for _, cert := range pinCandidates(state) {
+		for _, p := range pins {
+			if p.matches(cert) {
+				return nil
+			}
+		}
+	}
+
+	presented := "none"
+	if len(state.PeerCertificates) > 0 {
+		presented = SPKIPin(state.PeerCertificates[0])
+	}
+	return fmt.Errorf("%w for %s (presented %s)", ErrPinMismatch, state.ServerName, presented)
+}
+
+// pinCandidates returns the certificates a pin may match
+// Extra certificates sent by the server outside the verified chains are ignored. The chains are only
+// missing when InsecureSkipVerify skipped the usual verification: the leaf alone is then trusted.
+func pinCandidates(state tls.ConnectionState) []*x509.Certificate {
+	if len(state.VerifiedChains) == 0 {
+		if len(state.PeerCertificates) == 0 {
+			return nil
+		}
+		return state.PeerCertificates[:1]
+	}
+
+	var certs []*x509.Certificate
+	for _, chain := range state.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	return certs
+}
+
+// SPKIPin returns the "sha256/<base64>" pin of a certificate public key
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// pinnedTransport returns a copy of base checking the certificate pins after the usual verification
+func pinnedTransport(base *http.Transport, pins PinSet) *http.Transport {
+	transport := base.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.VerifyConnection = pins.verify
+	return transport
+}
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPinnedTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cert := server.Certificate()
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	// Pins are looked up by host name: send example.com (in the test certificate) to the server
+	base := server.Client().Transport.(*http.Transport).Clone()
+	base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	get := func(pins map[string][]string) error {
+		set, err := NewPinSet(pins)
+		if err != nil {
+			t.Fatalf("NewPinSet failed: %v", err)
+		}
+		client := &http.Client{Transport: pinnedTransport(base, set)}
+		resp, err := client.Get("https://example.com/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(map[string][]string{"example.com": {SPKIPin(cert)}}); err != nil {
+		t.Errorf("SPKI pin rejected: %v", err)
+	}
+	if err := get(map[string][]string{"example.com": {hex.EncodeToString(fingerprint[:])}}); err != nil {
+		t.Errorf("Certificate fingerprint rejected: %v", err)
+	}
+	if err := get(map[string][]string{"other.example": {"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}); err != nil {
+		t.Errorf("Host without pins rejected: %v", err)
+	}
+	if err := get(map[string][]string{"example.com": {"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}); !errors.Is(err, ErrPinMismatch) {
+		t.Errorf("Expected ErrPinMismatch, got %v", err)
+	}
+
+	if _, err := NewPinSet(map[string][]string{"host": {"sha256/short"}}); err == nil {
+		t.Error("Expected an error for an invalid pin")
+	}
+}
+
+func TestPinIgnoresUnverifiedCertificates(t *testing.T) {
+	// A certificate outside the verified chain, sent along with the server certificate
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "unrelated"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	extra, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate failed: %v", err)
+	}
+
+	trusted := httptest.NewTLSServer(http.NotFoundHandler())
+	defer trusted.Close()
+	certificate := trusted.TLS.Certificates[0]
+	certificate.Certificate = append(certificate.Certificate[:1:1], der)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	defer server.Close()
+
+	base := server.Client().Transport.(*http.Transport).Clone()
+	base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	get := func(insecure bool) error {
+		set, err := NewPinSet(map[string][]string{"example.com": {SPKIPin(extra)}})
+		if err != nil {
+			t.Fatalf("NewPinSet failed: %v", err)
+		}
+		transport := pinnedTransport(base, set)
+		transport.TLSClientConfig.InsecureSkipVerify = insecure
+		resp, err := (&http.Client{Transport: transport}).Get("https://example.com/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(false); !errors.Is(err, ErrPinMismatch) {
+		t.Errorf("Expected ErrPinMismatch for a pin outside the verified chain, got %v", err)
+	}
+	if err := get(true); !errors.Is(err, ErrPinMismatch) {
+		t.Errorf("Expected ErrPinMismatch for a pin other than the leaf, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/amaumene/gomenarr/internal/config"
+)
+
+// proxiedTransport returns a copy of base sending its requests through a proxy
+// HTTP(S) proxies get the credentials of the URL in Proxy-Authorization, SOCKS5
+// proxies with username/password authentication. socks5 and socks5h are handled alike:
+// host names are sent to the proxy, which resolves them.
+func proxiedTransport(base *http.Transport, proxy string) (*http.Transport, error) {
+	transport := base.Clone()
+	if proxy == config.ProxyDirect {
+		transport.Proxy = nil
+		return transport, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL %s has no host", proxyURL.Redacted())
+	}
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport, nil
+}
+
+// redactProxy hides the password of a proxy URL for logs
+func redactProxy(proxy string) string {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return proxy
+	}
+	return proxyURL.Redacted()
+}
@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTransportProxy(t *testing.T) {
+	// The proxy answers itself, an HTTP proxy gets the absolute URL of the request
+	var requestURI, authorization string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+		authorization = r.Header.Get("Proxy-Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+	client := &http.Client{Transport: NewTransport(Options{Name: "test", Proxy: proxyURL}, logrus.New())}
+
+	resp, err := client.Get("http://api.example.invalid/search?t=movie")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if requestURI != "http://api.example.invalid/search?t=movie" {
+		t.Errorf("Expected the request to go through the proxy, got %q", requestURI)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret")); authorization != want {
+		t.Errorf("Expected Proxy-Authorization %q, got %q", want, authorization)
+	}
+}
+
+func TestProxiedTransportDirect(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://proxy.invalid:3128")
+
+	transport, err := proxiedTransport(http.DefaultTransport.(*http.Transport), "direct")
+	if err != nil {
+		t.Fatalf("proxiedTransport failed: %v", err)
+	}
+	if transport.Proxy != nil {
+		t.Error("Expected direct connections to ignore HTTP_PROXY")
+	}
+
+	if _, err := proxiedTransport(http.DefaultTransport.(*http.Transport), "ftp://proxy.invalid"); err == nil {
+		t.Error("Expected an unsupported proxy scheme to fail")
+	}
+}
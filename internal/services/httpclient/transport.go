@@ -0,0 +1,309 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/amaumene/gomenarr/internal/metrics"
+	"github.com/amaumene/gomenarr/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// initialRetryBackoff is the wait before the first retry, doubled on each attempt
+const initialRetryBackoff = time.Second
+
+// Options configures the rate limiting and retries of an external API client
+type Options struct {
+	Name       string        // Service name used in logs
+	RateLimit  float64       // Requests per second to each host, 0 for unlimited
+	MaxRetries int           // Retries of rate limited (429) and unavailable (502, 503, 504) responses
+	MaxWait    time.Duration // Longest wait before a retry, responses asking for more are returned as is
+
+	// Consecutive failures (network errors, timeouts, 5xx) opening the circuit breaker of a host,
+	// 0 disables it, and how long requests then fail fast before the host is probed again
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	// Certificate pins by host name, connections to a pinned host fail with ErrPinMismatch
+	// when none of its pins matches
+	Pins PinSet
+
+	// Proxy URL (http, https, socks5 or socks5h, credentials as user:password@), "direct"
+	// to ignore the HTTP_PROXY/HTTPS_PROXY environment, empty to follow it
+	Proxy string
+
+	// TLS setup by host name (self-signed or internal CA certificates), other hosts use the defaults
+	TLS map[string]HostTLS
+
+	IPFamily  string // "ipv4" or "ipv6" to only connect over one, empty for both
+	DNSServer string // host:port of the DNS server resolving host names, empty for the system resolver
+}
+
+// Transport rate limits requests per host with a token bucket, retries rate limited
+// or unavailable responses with exponential backoff, honoring Retry-After, and stops
+// calling hosts that keep failing with a circuit breaker
+type Transport struct {
+	base    http.RoundTripper
+	options Options
+	backoff time.Duration
+	logger  *logrus.Logger
+
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	breakers map[string]*breaker
+}
+
+// bucket is the token bucket of a host
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTransport creates a transport on top of http.DefaultTransport
+// An invalid proxy is logged and ignored, the config validates them beforehand.
+func NewTransport(options Options, logger *logrus.Logger) *Transport {
+	var base http.RoundTripper = http.DefaultTransport
+	if options.Proxy != "" || len(options.Pins) > 0 || len(options.TLS) > 0 || options.IPFamily != "" || options.DNSServer != "" {
+		transport := http.DefaultTransport.(*http.Transport)
+		if options.IPFamily != "" || options.DNSServer != "" {
+			transport = dialingTransport(transport, options)
+		}
+		if options.Proxy != "" {
+			proxied, err := proxiedTransport(transport, options.Proxy)
+			if err != nil {
+				logger.WithError(err).WithField("service", options.Name).Warn("Ignoring invalid proxy")
+			} else {
+				transport = proxied
+				logger.WithFields(logrus.Fields{
+					"service": options.Name,
+					"proxy":   redactProxy(options.Proxy),
+				}).Debug("Using proxy")
+			}
+		}
+		if len(options.Pins) > 0 {
+			transport = pinnedTransport(transport, options.Pins)
+		}
+		base = transport
+		if len(options.TLS) > 0 {
+			base = newHostTransport(transport, options.TLS)
+		}
+	}
+
+	t := &Transport{
+		base:     base,
+		options:  options,
+		backoff:  initialRetryBackoff,
+		logger:   logger,
+		buckets:  make(map[string]*bucket),
+		breakers: make(map[string]*breaker),
+	}
+	register(t)
+	return t
+}
+
+// NewOptions reads the retry, circuit breaker and network settings of a service from the config
+func NewOptions(name string, rateLimit float64, cfg *config.Config) Options {
+	return Options{
+		Name:            name,
+		RateLimit:       rateLimit,
+		MaxRetries:      cfg.Retry.MaxRetries,
+		MaxWait:         time.Duration(cfg.Retry.MaxWait) * time.Second,
+		BreakerFailures: cfg.CircuitBreaker.Failures,
+		BreakerCooldown: time.Duration(cfg.CircuitBreaker.Cooldown) * time.Second,
+		IPFamily:        cfg.Network.IPFamily,
+		DNSServer:       cfg.Network.DNSServer,
+	}
+}
+
+// NewClient creates an HTTP client using a new Transport
+// The timeout covers the whole request, retries included.
+func NewClient(timeout time.Duration, options Options, logger *logrus.Logger) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewTransport(options, logger),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	// Client span, the query string is left out as it may hold API keys
+	ctx, span := tracing.StartKind(req.Context(), req.Method+" "+t.options.Name, tracing.KindClient)
+	defer span.End()
+	span.SetAttribute("service", t.options.Name)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("server.address", host)
+	span.SetAttribute("url.path", req.URL.Path)
+	if span != nil {
+		req = req.Clone(ctx)
+		tracing.Inject(ctx, req.Header)
+	}
+
+	if err := t.allow(host); err != nil {
+		metrics.ExternalRequests.Inc(t.options.Name, requestResult(nil, err))
+		span.RecordError(err)
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.roundTrip(req)
+	metrics.ExternalRequestSeconds.Add(time.Since(start).Seconds(), t.options.Name)
+	metrics.ExternalRequests.Inc(t.options.Name, requestResult(resp, err))
+	span.RecordError(err)
+	if resp != nil {
+		span.SetAttribute("http.status_code", resp.StatusCode)
+	}
+
+	t.record(req.Context(), host, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
+
+// requestResult is the result label of a request metric: the status class or the failure
+func requestResult(resp *http.Response, err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case err != nil:
+		return "error"
+	}
+	return strconv.Itoa(resp.StatusCode/100) + "xx"
+}
+
+// roundTrip sends a request, retrying it while the host is rate limited or unavailable
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		if !retryable(resp.StatusCode) || attempt >= t.options.MaxRetries || !replayable(req) {
+			return resp, nil
+		}
+
+		delay := t.backoff << attempt
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			delay = retryAfter
+		}
+		if delay > t.options.MaxWait {
+			return resp, nil
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		t.logger.WithFields(logrus.Fields{
+			"service": t.options.Name,
+			"host":    req.URL.Host,
+			"status":  resp.StatusCode,
+			"attempt": attempt + 1,
+			"wait":    delay.String(),
+		}).Warn("Retrying request")
+		metrics.ExternalRetries.Inc(t.options.Name)
+
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until the rate limit of the request host allows it
+// Up to one second of requests can be sent in a burst.
+func (t *Transport) wait(req *http.Request) error {
+	rate := t.options.RateLimit
+	if rate <= 0 {
+		return nil
+	}
+	capacity := math.Max(1, rate)
+
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		b, ok := t.buckets[req.URL.Host]
+		if !ok {
+			b = &bucket{tokens: capacity, last: now}
+			t.buckets[req.URL.Host] = b
+		}
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			t.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		t.mu.Unlock()
+
+		if err := sleep(req, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for a delay unless the request is cancelled first
+func sleep(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// retryable checks if a status code is worth retrying after a wait
+func retryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// replayable checks if the request body can be sent again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
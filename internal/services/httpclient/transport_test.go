@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTransportRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("Expected body to be replayed, got %q", body)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := NewTransport(Options{Name: "test", MaxRetries: 3, MaxWait: time.Second}, logrus.New())
+	transport.backoff = time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls.Load())
+	}
+
+	// Retry-After beyond MaxWait returns the response instead of waiting
+	calls.Store(0)
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+
+	resp, err = client.Get(limited.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("Expected a single 429 response, got status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("30"); !ok || delay != 30*time.Second {
+		t.Errorf("Expected 30s, got %v", delay)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date); !ok || delay <= 0 || delay > time.Minute {
+		t.Errorf("Expected about a minute, got %v", delay)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("Invalid Retry-After should be ignored")
+	}
+}
+
+func TestTransportBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := NewTransport(Options{Name: "test", BreakerFailures: 2, BreakerCooldown: 50 * time.Millisecond}, logrus.New())
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Open: requests fail without reaching the host
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls.Load())
+	}
+	if infos := transport.breakerInfos(); len(infos) != 1 || infos[0].State != BreakerOpen {
+		t.Errorf("Expected an open breaker, got %+v", infos)
+	}
+
+	// After the cooldown a probe closes the breaker
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Probe failed: %v", err)
+	}
+	resp.Body.Close()
+	if infos := transport.breakerInfos(); infos[0].State != BreakerClosed || infos[0].Failures != 0 {
+		t.Errorf("Expected a closed breaker, got %+v", infos[0])
+	}
+}
@@ -0,0 +1,98 @@
+package mediaserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Supported media server types
+const (
+	TypePlex     = "plex"
+	TypeJellyfin = "jellyfin"
+	TypeEmby     = "emby"
+)
+
+// Client triggers library scans on a media server
+type Client struct {
+	serverType string
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// NewClient creates a media server client
+// Returns nil if no media server is configured.
+func NewClient(cfg *config.Config, logger *logrus.Logger) (*Client, error) {
+	if cfg.MediaServerURL == "" {
+		return nil, nil
+	}
+
+	serverType := strings.ToLower(cfg.MediaServerType)
+	switch serverType {
+	case TypePlex, TypeJellyfin, TypeEmby:
+	default:
+		return nil, fmt.Errorf("unsupported media server type %q (plex, jellyfin or emby)", cfg.MediaServerType)
+	}
+
+	if cfg.MediaServerToken == "" {
+		return nil, fmt.Errorf("media server token is required")
+	}
+
+	return &Client{
+		serverType: serverType,
+		baseURL:    strings.TrimRight(cfg.MediaServerURL, "/"),
+		token:      cfg.MediaServerToken,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}, nil
+}
+
+// Type returns the media server type
+func (c *Client) Type() string {
+	return c.serverType
+}
+
+// RefreshLibrary asks the media server to scan all libraries
+func (c *Client) RefreshLibrary(ctx context.Context) error {
+	var req *http.Request
+	var err error
+
+	switch c.serverType {
+	case TypePlex:
+		req, err = http.NewRequestWithContext(ctx, "GET", c.baseURL+"/library/sections/all/refresh", nil)
+		if err == nil {
+			req.Header.Set("X-Plex-Token", c.token)
+		}
+	default:
+		// Jellyfin and Emby share the same API
+		req, err = http.NewRequestWithContext(ctx, "POST", c.baseURL+"/Library/Refresh", nil)
+		if err == nil {
+			req.Header.Set("X-Emby-Token", c.token)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s refresh returned status %d: %s", c.serverType, resp.StatusCode, string(body))
+	}
+
+	c.logger.WithField("server", c.serverType).Info("Triggered media server library refresh")
+	return nil
+}
@@ -0,0 +1,60 @@
+package newznab
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// pubDateLayouts are the date formats of the pubDate element and the usenetdate attribute
+var pubDateLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339}
+
+// parsePublished returns the post date of an item, zero if the indexer gives none
+// The usenetdate attribute (posting date) is preferred over pubDate (indexing date).
+func parsePublished(item Item) time.Time {
+	for _, value := range []string{GetAttributeValue(item, "usenetdate"), item.PubDate} {
+		if value == "" {
+			continue
+		}
+		for _, layout := range pubDateLayouts {
+			if published, err := time.Parse(layout, value); err == nil {
+				return published
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// checkAge validates the post date of a result against the indexer retention and age limits
+// Returns the reason the result was rejected, or an empty string if it is acceptable.
+// Results without a date are always accepted.
+func (i Indexer) checkAge(result SearchResult, now time.Time) string {
+	if result.PublishedAt.IsZero() {
+		return ""
+	}
+	age := now.Sub(result.PublishedAt)
+
+	if i.MinAge > 0 && age < i.MinAge {
+		return fmt.Sprintf("posted %s ago, %s required", age.Round(time.Minute), i.MinAge)
+	}
+	if i.MaxAge > 0 && age > i.MaxAge {
+		return fmt.Sprintf("posted %d days ago, at most %d days allowed", int(age.Hours()/24), int(i.MaxAge.Hours()/24))
+	}
+	// Torrents are seeded, only usenet posts expire
+	if i.Retention > 0 && result.Protocol == models.ProtocolUsenet && age > i.Retention {
+		return fmt.Sprintf("posted %d days ago, beyond the %d days retention", int(age.Hours()/24), int(i.Retention.Hours()/24))
+	}
+	return ""
+}
+
+// CheckAge validates the post date of a result against the limits of the indexer that returned it
+// Returns the reason the result was rejected, or an empty string if it is acceptable.
+func (c *Client) CheckAge(result SearchResult, now time.Time) string {
+	for _, indexer := range c.indexers {
+		if indexer.Name == result.Indexer {
+			return indexer.checkAge(result, now)
+		}
+	}
+	return ""
+}
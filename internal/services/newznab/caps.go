@@ -0,0 +1,133 @@
+package newznab
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// capsRetryAfter is how long a failed capabilities detection is cached before it is tried again
+const capsRetryAfter = 10 * time.Minute
+
+// searchKind tells which limit applies to a search
+type searchKind int
+
+const (
+	searchMovie searchKind = iota
+	searchEpisode
+	searchSeason
+	searchLatest // Latest releases, left to the indexer default
+)
+
+// sortParams maps the configured result orders to the Newznab sort parameter
+var sortParams = map[string]string{
+	"date": "posted_desc",
+	"size": "size_desc",
+}
+
+// capsResponse is the part of the t=caps response used to bound requests
+type capsResponse struct {
+	XMLName xml.Name `xml:"caps"`
+	Limits  struct {
+		Max     int `xml:"max,attr"`
+		Default int `xml:"default,attr"`
+	} `xml:"limits"`
+}
+
+// capabilities are the detected limits of an indexer
+type capabilities struct {
+	maxLimit int       // 0 if unknown
+	expires  time.Time // Set when detection failed, zero once detected
+}
+
+// limit returns the number of results to request for a search kind, 0 for the indexer default
+func (i Indexer) limit(kind searchKind) int {
+	switch kind {
+	case searchMovie:
+		return i.MovieLimit
+	case searchSeason:
+		return i.SeasonLimit
+	case searchLatest:
+		return 0
+	default:
+		return i.EpisodeLimit
+	}
+}
+
+// capabilities returns the limits of an indexer, detected once with a t=caps request
+// Indexers that don't answer caps are assumed to accept any limit until the next try.
+// The lock isn't held during the request, so a slow indexer doesn't hold up the others.
+func (c *Client) capabilities(indexer Indexer) capabilities {
+	c.capsMu.Lock()
+	caps, ok := c.caps[indexer.Name]
+	c.capsMu.Unlock()
+	if ok && (caps.expires.IsZero() || time.Now().Before(caps.expires)) {
+		return caps
+	}
+
+	caps, err := c.fetchCapabilities(indexer)
+	if err != nil {
+		caps.expires = time.Now().Add(capsRetryAfter)
+		c.logger.WithError(err).WithField("indexer", indexer.Name).Debug("Failed to detect indexer capabilities")
+	} else {
+		c.logger.WithFields(logrus.Fields{
+			"indexer":   indexer.Name,
+			"max_limit": caps.maxLimit,
+		}).Debug("Detected indexer capabilities")
+	}
+
+	c.capsMu.Lock()
+	c.caps[indexer.Name] = caps
+	c.capsMu.Unlock()
+	return caps
+}
+
+// fetchCapabilities requests the capabilities of an indexer
+func (c *Client) fetchCapabilities(indexer Indexer) (capabilities, error) {
+	apiURL, err := url.Parse(indexer.URL)
+	if err != nil {
+		return capabilities{}, fmt.Errorf("invalid newznab URL: %w", err)
+	}
+	if apiURL.Path == "" || apiURL.Path == "/" {
+		apiURL.Path = "/api"
+	}
+	apiURL.RawQuery = url.Values{"t": {"caps"}, "apikey": {indexer.APIKey}}.Encode()
+
+	req, err := http.NewRequest("GET", apiURL.String(), nil)
+	if err != nil {
+		return capabilities{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "gomenarr/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return capabilities{}, fmt.Errorf("caps request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return capabilities{}, fmt.Errorf("caps request returned status %d", resp.StatusCode)
+	}
+
+	var response capsResponse
+	if err := xml.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return capabilities{}, fmt.Errorf("failed to parse caps response: %w", err)
+	}
+	return capabilities{maxLimit: response.Limits.Max}, nil
+}
+
+// requestLimit returns the limit parameter of a search, 0 to leave it out
+func (c *Client) requestLimit(indexer Indexer, kind searchKind) int {
+	limit := indexer.limit(kind)
+	if limit == 0 {
+		return 0
+	}
+	if caps := c.capabilities(indexer); caps.maxLimit > 0 && limit > caps.maxLimit {
+		return caps.maxLimit
+	}
+	return limit
+}
@@ -1,18 +1,32 @@
 package newznab
 
 import (
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/services/httpclient"
+	"github.com/amaumene/gomenarr/internal/services/notify"
+	"github.com/amaumene/gomenarr/internal/tracing"
 	"github.com/sirupsen/logrus"
 )
 
+// pinAlertInterval limits the certificate pin mismatch notifications of an indexer
+const pinAlertInterval = time.Hour
+
 // NewznabResponse represents the XML RSS response from Newznab API
 type NewznabResponse struct {
 	XMLName xml.Name `xml:"rss"`
@@ -28,7 +42,7 @@ type Channel struct {
 // Item represents a single search result
 type Item struct {
 	Title      string      `xml:"title"`
-	Link       string      `xml:"link"`      // Details page (not for download)
+	Link       string      `xml:"link"` // Details page (not for download)
 	GUID       string      `xml:"guid"`
 	PubDate    string      `xml:"pubDate"`
 	Enclosure  Enclosure   `xml:"enclosure"` // The actual NZB download URL
@@ -48,41 +62,317 @@ type Attribute struct {
 	Value string `xml:"value,attr"`
 }
 
-// Client wraps direct Newznab API HTTP calls
+// Indexer is a single Newznab or Torznab indexer
+type Indexer struct {
+	Name       string
+	Protocol   models.Protocol // usenet for Newznab, torrent for Torznab
+	URL        string
+	APIKey     string
+	Categories []string
+	Priority   int // Lower is preferred
+
+	AnimeCategories []string // Categories of the anime text searches, empty for all
+
+	// Torrent health filters and scoring
+	MinSeeders      int
+	MinLeechers     int
+	FreeleechOnly   bool
+	SeedersWeight   int
+	FreeleechWeight int
+
+	// Post age filters, 0 for none
+	Retention time.Duration // Usenet provider retention, older posts are incomplete
+	MinAge    time.Duration // Newer posts may not be propagated to the provider yet
+	MaxAge    time.Duration
+
+	// Results requested per search type (0 for the indexer default) and their order
+	MovieLimit   int
+	EpisodeLimit int
+	SeasonLimit  int
+	Sort         string
+}
+
+// Client wraps direct Newznab API HTTP calls to one or more indexers
 type Client struct {
-	baseURL    string
-	apiKey     string
+	indexers   []Indexer
 	httpClient *http.Client
+	notifier   *notify.Dispatcher
 	logger     *logrus.Logger
+
+	pinAlertsMu sync.Mutex
+	pinAlerts   map[string]time.Time // Last pin mismatch notification by indexer name
+
+	capsMu sync.Mutex
+	caps   map[string]capabilities // Detected capabilities by indexer name
 }
 
 // NewClient creates a new Newznab client with direct HTTP calls
-func NewClient(cfg *config.Config, logger *logrus.Logger) (*Client, error) {
-	if cfg.NewznabURL == "" {
-		return nil, fmt.Errorf("newznab URL is required")
+func NewClient(cfg *config.Config, notifier *notify.Dispatcher, logger *logrus.Logger) (*Client, error) {
+	if len(cfg.Indexers) == 0 {
+		return nil, fmt.Errorf("at least one newznab indexer is required")
 	}
-	if cfg.NewznabKey == "" {
-		return nil, fmt.Errorf("newznab API key is required")
+
+	indexers := make([]Indexer, 0, len(cfg.Indexers))
+	hostPins := make(map[string][]string)
+	hostTLS := make(map[string]httpclient.HostTLS)
+	for _, indexerCfg := range cfg.Indexers {
+		if indexerCfg.URL == "" {
+			return nil, fmt.Errorf("newznab URL is required for indexer %s", indexerCfg.Name)
+		}
+		if indexerCfg.APIKey == "" {
+			return nil, fmt.Errorf("newznab API key is required for indexer %s", indexerCfg.Name)
+		}
+		if len(indexerCfg.TLSPins) > 0 {
+			parsed, err := url.Parse(indexerCfg.URL)
+			if err != nil || parsed.Scheme != "https" {
+				return nil, fmt.Errorf("TLS pins of indexer %s require an https URL", indexerCfg.Name)
+			}
+			// Connections to IP addresses carry no server name to look the pins up with
+			if net.ParseIP(parsed.Hostname()) != nil {
+				return nil, fmt.Errorf("TLS pins of indexer %s require a host name, not an IP address", indexerCfg.Name)
+			}
+			host := strings.ToLower(parsed.Hostname())
+			hostPins[host] = append(hostPins[host], indexerCfg.TLSPins...)
+		}
+		if indexerCfg.TLSSkipVerify || indexerCfg.TLSCAFile != "" {
+			parsed, err := url.Parse(indexerCfg.URL)
+			if err != nil || parsed.Scheme != "https" {
+				return nil, fmt.Errorf("TLS settings of indexer %s require an https URL", indexerCfg.Name)
+			}
+			// Indexers sharing a host share its TLS setup
+			host := strings.ToLower(parsed.Hostname())
+			setup := hostTLS[host]
+			setup.SkipVerify = setup.SkipVerify || indexerCfg.TLSSkipVerify
+			if indexerCfg.TLSCAFile != "" {
+				if setup.RootCAs, err = httpclient.LoadCAFile(setup.RootCAs, indexerCfg.TLSCAFile); err != nil {
+					return nil, fmt.Errorf("invalid TLS CA file of indexer %s: %w", indexerCfg.Name, err)
+				}
+			}
+			if indexerCfg.TLSSkipVerify {
+				logger.WithField("indexer", indexerCfg.Name).Warn("TLS certificate verification disabled")
+			}
+			hostTLS[host] = setup
+		}
+		protocol := models.ProtocolUsenet
+		if indexerCfg.Type == config.IndexerTypeTorznab {
+			protocol = models.ProtocolTorrent
+		}
+
+		indexers = append(indexers, Indexer{
+			Name:            indexerCfg.Name,
+			Protocol:        protocol,
+			URL:             indexerCfg.URL,
+			APIKey:          indexerCfg.APIKey,
+			Categories:      indexerCfg.Categories,
+			AnimeCategories: indexerCfg.AnimeCategories,
+			Priority:        indexerCfg.Priority,
+			MinSeeders:      indexerCfg.MinSeeders,
+			MinLeechers:     indexerCfg.MinLeechers,
+			FreeleechOnly:   indexerCfg.FreeleechOnly,
+			SeedersWeight:   indexerCfg.SeedersWeight,
+			FreeleechWeight: indexerCfg.FreeleechWeight,
+			Retention:       time.Duration(indexerCfg.RetentionDays) * 24 * time.Hour,
+			MinAge:          time.Duration(indexerCfg.MinAgeMinutes) * time.Minute,
+			MaxAge:          time.Duration(indexerCfg.MaxAgeDays) * 24 * time.Hour,
+			MovieLimit:      indexerCfg.MovieLimit,
+			EpisodeLimit:    indexerCfg.EpisodeLimit,
+			SeasonLimit:     indexerCfg.SeasonLimit,
+			Sort:            indexerCfg.Sort,
+		})
 	}
 
+	// Preferred indexers first, config order on ties
+	sort.SliceStable(indexers, func(i, j int) bool {
+		return indexers[i].Priority < indexers[j].Priority
+	})
+
+	pins, err := httpclient.NewPinSet(hostPins)
+	if err != nil {
+		return nil, err
+	}
+	options := httpclient.NewOptions("newznab", cfg.RateLimits.Newznab, cfg)
+	options.Pins = pins
+	options.Proxy = cfg.Proxies.Newznab
+	options.TLS = hostTLS
+
 	return &Client{
-		baseURL: cfg.NewznabURL,
-		apiKey:  cfg.NewznabKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		logger: logger,
+		indexers:   indexers,
+		httpClient: httpclient.NewClient(30*time.Second, options, logger),
+		notifier:   notifier,
+		logger:     logger,
+		pinAlerts:  make(map[string]time.Time),
+		caps:       make(map[string]capabilities),
 	}, nil
 }
 
-// search performs Newznab API search
+// Indexers returns the configured indexers, preferred first
+func (c *Client) Indexers() []Indexer {
+	return c.indexers
+}
+
+// dedupeSizeTolerance is the relative size difference under which two results
+// with the same title are the same release (indexers round sizes differently)
+const dedupeSizeTolerance = 0.01
+
+// indexerResults holds the items returned by one indexer
+type indexerResults struct {
+	indexer Indexer
+	items   []Item
+	err     error
+}
+
+// searchAll runs the same search on every indexer in parallel and merges the results
+// The same release found on several indexers (same GUID, or same normalized title
+// and a size within dedupeSizeTolerance) is kept once, from the preferred indexer,
+// the other indexers being recorded as alternates for failover.
+// An error is only returned if every indexer failed.
+func (c *Client) searchAll(ctx context.Context, searchType string, kind searchKind, imdbID string, query string, season *int, episode *int) ([]SearchResult, error) {
+	responses := make([]indexerResults, len(c.indexers))
+
+	var wg sync.WaitGroup
+	for i, indexer := range c.indexers {
+		wg.Add(1)
+		go func(i int, indexer Indexer) {
+			defer wg.Done()
+			items, err := c.search(ctx, indexer, searchType, kind, imdbID, query, season, episode)
+			responses[i] = indexerResults{indexer: indexer, items: items, err: err}
+		}(i, indexer)
+	}
+	wg.Wait()
+
+	var results []SearchResult
+	var errs []string
+	seenGUIDs := make(map[string]bool)
+	byTitle := make(map[string][]int) // Normalized title -> indexes in results
+
+	// responses follow the indexer order, so preferred indexers win on duplicates
+	for _, response := range responses {
+		if response.err != nil {
+			c.logger.WithError(response.err).WithField("indexer", response.indexer.Name).Warn("Indexer search failed")
+			errs = append(errs, fmt.Sprintf("%s: %v", response.indexer.Name, response.err))
+			continue
+		}
+
+		for _, result := range c.convertResults(response.items) {
+			if result.GUID != "" && seenGUIDs[result.GUID] {
+				continue
+			}
+			if reason := response.indexer.checkHealth(result); reason != "" {
+				c.logger.WithFields(logrus.Fields{
+					"indexer": response.indexer.Name,
+					"title":   result.Title,
+					"reason":  reason,
+				}).Debug("Skipping unhealthy torrent")
+				continue
+			}
+			seenGUIDs[result.GUID] = true
+
+			titleKey := NormalizeReleaseTitle(result.Title)
+			if i := findDuplicate(results, byTitle[titleKey], result.Size); i >= 0 {
+				if results[i].Indexer != response.indexer.Name {
+					results[i].Alternates = append(results[i].Alternates, models.NZBAlternate{
+						Indexer:  response.indexer.Name,
+						Link:     result.Link,
+						GUID:     result.GUID,
+						Protocol: response.indexer.Protocol,
+					})
+				}
+				continue
+			}
+
+			result.Indexer = response.indexer.Name
+			result.Protocol = response.indexer.Protocol
+			result.Score = response.indexer.score(result)
+			byTitle[titleKey] = append(byTitle[titleKey], len(results))
+			results = append(results, result)
+		}
+	}
+
+	if len(errs) == len(c.indexers) {
+		return nil, fmt.Errorf("all indexers failed: %s", strings.Join(errs, "; "))
+	}
+
+	return results, nil
+}
+
+// NormalizeReleaseTitle lowercases a release title and drops its separators
+// e.g. "Show.S01E01.1080p.WEB-DL" and "Show S01E01 1080p WEB DL" match.
+func NormalizeReleaseTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// findDuplicate returns the index of the result with a matching size among candidates, -1 if none
+// Unknown sizes (0) match any size.
+func findDuplicate(results []SearchResult, candidates []int, size int64) int {
+	for _, i := range candidates {
+		other := results[i].Size
+		if size == 0 || other == 0 {
+			return i
+		}
+		diff := size - other
+		if diff < 0 {
+			diff = -diff
+		}
+		if float64(diff) <= float64(other)*dedupeSizeTolerance {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkHealth validates torrent attributes against the indexer minimums
+// Returns the reason the result was rejected, or an empty string if it is acceptable.
+// Results without seeders (usenet) are always accepted.
+func (i Indexer) checkHealth(result SearchResult) string {
+	if result.Seeders == nil {
+		return ""
+	}
+
+	if *result.Seeders < i.MinSeeders {
+		return fmt.Sprintf("%d seeders, %d required", *result.Seeders, i.MinSeeders)
+	}
+	if result.Leechers != nil && *result.Leechers < i.MinLeechers {
+		return fmt.Sprintf("%d leechers, %d required", *result.Leechers, i.MinLeechers)
+	}
+	if i.FreeleechOnly && !result.Freeleech {
+		return "not freeleech"
+	}
+
+	return ""
+}
+
+// score computes the indexer-weighted health score of a result
+// Seeders count up to 100, so a single huge swarm doesn't dominate quality.
+func (i Indexer) score(result SearchResult) int {
+	if result.Seeders == nil {
+		return 0
+	}
+
+	const maxScoredSeeders = 100
+	seeders := *result.Seeders
+	if seeders > maxScoredSeeders {
+		seeders = maxScoredSeeders
+	}
+
+	score := i.SeedersWeight * seeders / maxScoredSeeders
+	if result.Freeleech {
+		score += i.FreeleechWeight
+	}
+	return score
+}
+
+// search performs Newznab API search on a single indexer
 // searchType: always "tvsearch" (works for both movies and TV shows)
 // imdbID: IMDB ID of the media (e.g., "tt0133093")
 // season: required for TV (always provided), nil for movies
 // episode: nil for movies and season packs, set for specific episodes
-func (c *Client) search(searchType string, imdbID string, season *int, episode *int) ([]Item, error) {
+// kind selects the configured result limit (movie, episode or season pack search)
+// Text searches (query set) use the anime categories of the indexer.
+func (c *Client) search(ctx context.Context, indexer Indexer, searchType string, kind searchKind, imdbID string, query string, season *int, episode *int) ([]Item, error) {
 	// Build base URL
-	apiURL, err := url.Parse(c.baseURL)
+	apiURL, err := url.Parse(indexer.URL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid newznab URL: %w", err)
 	}
@@ -95,8 +385,22 @@ func (c *Client) search(searchType string, imdbID string, season *int, episode *
 	// Build query parameters
 	params := url.Values{}
 	params.Add("t", searchType)
-	params.Add("apikey", c.apiKey)
-	params.Add("imdbid", imdbID)
+	params.Add("apikey", indexer.APIKey)
+
+	// Restrict to configured categories
+	categories := indexer.Categories
+	if query != "" {
+		params.Add("q", query)
+		// Text searches without a type are the anime episode searches
+		if searchType == "search" {
+			categories = indexer.AnimeCategories
+		}
+	} else if imdbID != "" {
+		params.Add("imdbid", imdbID)
+	}
+	if len(categories) > 0 {
+		params.Add("cat", strings.Join(categories, ","))
+	}
 
 	// Add season parameter for TV searches
 	if season != nil {
@@ -108,20 +412,35 @@ func (c *Client) search(searchType string, imdbID string, season *int, episode *
 		params.Add("ep", strconv.Itoa(*episode))
 	}
 
+	// Result count and order, indexers ignore the sort parameter if they don't support it
+	if limit := c.requestLimit(indexer, kind); limit > 0 {
+		params.Add("limit", strconv.Itoa(limit))
+	}
+	if sort := sortParams[indexer.Sort]; sort != "" {
+		params.Add("sort", sort)
+	}
+
 	apiURL.RawQuery = params.Encode()
 	finalURL := apiURL.String()
 
 	// Log the request
 	c.logger.WithFields(logrus.Fields{
+		"indexer":     indexer.Name,
 		"url":         finalURL,
 		"search_type": searchType,
 		"imdb_id":     imdbID,
+		"query":       query,
 		"season":      season,
 		"episode":     episode,
 	}).Debug("Performing Newznab search")
 
+	ctx, span := tracing.Start(ctx, "indexer search")
+	span.SetAttribute("indexer", indexer.Name)
+	span.SetAttribute("search_type", searchType)
+	defer span.End()
+
 	// Make HTTP request
-	req, err := http.NewRequest("GET", finalURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", finalURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -130,6 +449,7 @@ func (c *Client) search(searchType string, imdbID string, season *int, episode *
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.checkPin(indexer.Name, err)
 		return nil, fmt.Errorf("newznab API request failed: %w", err)
 	}
 	defer resp.Body.Close()
@@ -151,7 +471,10 @@ func (c *Client) search(searchType string, imdbID string, season *int, episode *
 		return nil, fmt.Errorf("failed to parse XML response: %w", err)
 	}
 
-	c.logger.WithField("count", len(nzResponse.Channel.Items)).Debug("Newznab search completed")
+	c.logger.WithFields(logrus.Fields{
+		"indexer": indexer.Name,
+		"count":   len(nzResponse.Channel.Items),
+	}).Debug("Newznab search completed")
 
 	return nzResponse.Channel.Items, nil
 }
@@ -201,38 +524,121 @@ func GetAttributeInt64(item Item, attrName string) int64 {
 func (c *Client) DownloadNZB(enclosureURL string) ([]byte, error) {
 	c.logger.WithField("url", enclosureURL).Debug("Downloading NZB file")
 
-	// Create HTTP request
-	req, err := http.NewRequest("GET", enclosureURL, nil)
+	// Limit to 15MB to be safe
+	const maxNZBSize = 15 * 1024 * 1024
+	nzbData, _, err := c.fetch(enclosureURL, maxNZBSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download NZB: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"size_bytes": len(nzbData),
+		"size_kb":    len(nzbData) / 1024,
+	}).Debug("NZB file downloaded successfully")
+
+	return nzbData, nil
+}
+
+// DownloadTorrent downloads a .torrent file from a Torznab enclosure URL
+// Torznab proxies (Jackett, Prowlarr) may redirect to a magnet link instead,
+// in which case the magnet is returned and the data is empty.
+func (c *Client) DownloadTorrent(enclosureURL string) ([]byte, string, error) {
+	if strings.HasPrefix(enclosureURL, "magnet:") {
+		return nil, enclosureURL, nil
+	}
+
+	c.logger.WithField("url", enclosureURL).Debug("Downloading torrent file")
+
+	const maxTorrentSize = 10 * 1024 * 1024
+	torrentData, magnet, err := c.fetch(enclosureURL, maxTorrentSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create NZB download request: %w", err)
+		return nil, "", fmt.Errorf("failed to download torrent: %w", err)
+	}
+
+	return torrentData, magnet, nil
+}
+
+// fetch downloads a release file from an indexer
+// Redirects to magnet links are not followed, the magnet is returned instead.
+func (c *Client) fetch(fileURL string, maxSize int64) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", fileURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", "gomenarr/1.0")
 
+	client := &http.Client{
+		Timeout:   c.httpClient.Timeout,
+		Transport: c.httpClient.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme == "magnet" {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+
 	// Execute request
-	resp, err := c.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download NZB: %w", err)
+		c.checkPin(c.indexerName(req.URL.Hostname()), err)
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
+	if location := resp.Header.Get("Location"); strings.HasPrefix(location, "magnet:") {
+		return nil, location, nil
+	}
+
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("NZB download failed with status %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("download failed with status %d", resp.StatusCode)
 	}
 
-	// Read NZB file content (limit to 15MB to be safe)
-	const maxNZBSize = 15 * 1024 * 1024 // 15MB
-	limitReader := io.LimitReader(resp.Body, maxNZBSize)
-	nzbData, err := io.ReadAll(limitReader)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read NZB content: %w", err)
+		return nil, "", fmt.Errorf("failed to read content: %w", err)
 	}
 
-	c.logger.WithFields(logrus.Fields{
-		"size_bytes": len(nzbData),
-		"size_kb":    len(nzbData) / 1024,
-	}).Debug("NZB file downloaded successfully")
+	return data, "", nil
+}
 
-	return nzbData, nil
+// checkPin reports an indexer whose certificate matches none of its pins
+// The request already failed closed, the notification is sent at most once per pinAlertInterval.
+func (c *Client) checkPin(indexer string, err error) {
+	if !errors.Is(err, httpclient.ErrPinMismatch) {
+		return
+	}
+
+	c.logger.WithError(err).WithField("indexer", indexer).Error("Indexer certificate doesn't match its pins, possible DNS hijack or certificate change")
+
+	c.pinAlertsMu.Lock()
+	last, alerted := c.pinAlerts[indexer]
+	if alerted && time.Since(last) < pinAlertInterval {
+		c.pinAlertsMu.Unlock()
+		return
+	}
+	c.pinAlerts[indexer] = time.Now()
+	c.pinAlertsMu.Unlock()
+
+	c.notifier.Notify(notify.Event{
+		Type:    notify.EventIndexerPinFailed,
+		Message: fmt.Sprintf("Indexer %s presented a certificate matching none of its pins, requests to it are refused", indexer),
+		Indexer: indexer,
+		Error:   err.Error(),
+	})
+}
+
+// indexerName returns the name of the indexer at a host, the host itself if none is configured
+func (c *Client) indexerName(host string) string {
+	for _, indexer := range c.indexers {
+		if parsed, err := url.Parse(indexer.URL); err == nil && strings.EqualFold(parsed.Hostname(), host) {
+			return indexer.Name
+		}
+	}
+	return host
 }
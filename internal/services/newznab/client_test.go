@@ -2,7 +2,13 @@ package newznab
 
 import (
 	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/sirupsen/logrus"
 )
 
 func TestXMLParsing(t *testing.T) {
@@ -100,7 +106,7 @@ func TestXMLParsing(t *testing.T) {
 
 func TestConvertResults(t *testing.T) {
 	// Create mock client (minimal setup for testing)
-	client := &Client{}
+	client := &Client{logger: logrus.New()}
 
 	// Test items
 	items := []Item{
@@ -169,3 +175,176 @@ func TestConvertResults(t *testing.T) {
 		t.Error("Season pack should not have episode number")
 	}
 }
+
+func TestIndexerHealth(t *testing.T) {
+	indexer := Indexer{MinSeeders: 5, SeedersWeight: 10, FreeleechWeight: 3}
+	intPtr := func(v int) *int { return &v }
+
+	// Usenet results carry no seeders and are always accepted
+	if reason := indexer.checkHealth(SearchResult{}); reason != "" {
+		t.Errorf("Usenet result rejected: %s", reason)
+	}
+	if score := indexer.score(SearchResult{}); score != 0 {
+		t.Errorf("Expected usenet score 0, got %d", score)
+	}
+
+	if reason := indexer.checkHealth(SearchResult{Seeders: intPtr(2)}); reason == "" {
+		t.Error("Torrent below minimum seeders should be rejected")
+	}
+	if reason := indexer.checkHealth(SearchResult{Seeders: intPtr(5)}); reason != "" {
+		t.Errorf("Healthy torrent rejected: %s", reason)
+	}
+
+	indexer.FreeleechOnly = true
+	if reason := indexer.checkHealth(SearchResult{Seeders: intPtr(50)}); reason == "" {
+		t.Error("Non-freeleech torrent should be rejected")
+	}
+
+	if score := indexer.score(SearchResult{Seeders: intPtr(50), Freeleech: true}); score != 8 {
+		t.Errorf("Expected score 8, got %d", score)
+	}
+	if score := indexer.score(SearchResult{Seeders: intPtr(5000)}); score != 10 {
+		t.Errorf("Expected score capped at 10, got %d", score)
+	}
+}
+
+func TestIndexerAge(t *testing.T) {
+	day := 24 * time.Hour
+	indexer := Indexer{Retention: 100 * day, MinAge: 10 * time.Minute}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	posted := func(age time.Duration, protocol models.Protocol) SearchResult {
+		return SearchResult{PublishedAt: now.Add(-age), Protocol: protocol}
+	}
+
+	if reason := indexer.checkAge(SearchResult{}, now); reason != "" {
+		t.Errorf("Result without date rejected: %s", reason)
+	}
+	if reason := indexer.checkAge(posted(5*time.Minute, models.ProtocolUsenet), now); reason == "" {
+		t.Error("Post younger than the minimum age should be rejected")
+	}
+	if reason := indexer.checkAge(posted(50*day, models.ProtocolUsenet), now); reason != "" {
+		t.Errorf("Post within retention rejected: %s", reason)
+	}
+	if reason := indexer.checkAge(posted(150*day, models.ProtocolUsenet), now); reason == "" {
+		t.Error("Post beyond retention should be rejected")
+	}
+	if reason := indexer.checkAge(posted(150*day, models.ProtocolTorrent), now); reason != "" {
+		t.Errorf("Torrent rejected by usenet retention: %s", reason)
+	}
+
+	indexer.MaxAge = 30 * day
+	if reason := indexer.checkAge(posted(50*day, models.ProtocolTorrent), now); reason == "" {
+		t.Error("Post older than the maximum age should be rejected")
+	}
+
+	item := Item{PubDate: "Mon, 01 Jan 2024 12:00:00 +0000"}
+	if published := parsePublished(item); !published.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected pubDate to be parsed, got %v", published)
+	}
+	item.Attributes = []Attribute{{Name: "usenetdate", Value: "Sun, 31 Dec 2023 08:00:00 +0000"}}
+	if published := parsePublished(item); !published.Equal(time.Date(2023, 12, 31, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected usenetdate to win over pubDate, got %v", published)
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	results := []SearchResult{
+		{Title: "Show.S01E01.1080p.WEB-DL-GRP", Size: 1000000},
+		{Title: "Show S01E01 1080p WEB DL GRP", Size: 2000000},
+	}
+	candidates := []int{0, 1}
+
+	if key := NormalizeReleaseTitle(results[0].Title); key != NormalizeReleaseTitle(results[1].Title) {
+		t.Fatalf("titles normalized differently: %q", key)
+	}
+
+	tests := []struct {
+		size     int64
+		expected int
+	}{
+		{1005000, 0},  // Within tolerance of the first
+		{1980000, 1},  // Within tolerance of the second
+		{1500000, -1}, // Different release
+		{0, 0},        // Unknown size
+	}
+	for _, tt := range tests {
+		if i := findDuplicate(results, candidates, tt.size); i != tt.expected {
+			t.Errorf("findDuplicate(size %d) = %d, expected %d", tt.size, i, tt.expected)
+		}
+	}
+}
+
+func TestFilterShow(t *testing.T) {
+	results := []SearchResult{
+		{Title: "Grey's.Anatomy.S01E01.DVDRip.XviD-GRP"},
+		{Title: "Greys Anatomy S01 DVDRip"},
+		{Title: "Anatomy.S01E01.720p"},
+		{Title: "Grey.Gardens.S01E01.720p"},
+	}
+
+	matches := filterShow(results, "Grey's Anatomy")
+	if len(matches) != 2 || matches[0].Title != results[0].Title || matches[1].Title != results[1].Title {
+		t.Errorf("filterShow kept %v, expected the first two releases", matches)
+	}
+}
+
+func TestRequestLimit(t *testing.T) {
+	var caps capsResponse
+	data := `<?xml version="1.0" encoding="UTF-8"?><caps><limits max="100" default="50"/></caps>`
+	if err := xml.Unmarshal([]byte(data), &caps); err != nil {
+		t.Fatalf("Failed to parse caps: %v", err)
+	}
+	if caps.Limits.Max != 100 || caps.Limits.Default != 50 {
+		t.Fatalf("Unexpected limits: %+v", caps.Limits)
+	}
+
+	indexer := Indexer{Name: "test", MovieLimit: 50, SeasonLimit: 300}
+	client := &Client{logger: logrus.New(), caps: map[string]capabilities{"test": {maxLimit: caps.Limits.Max}}}
+
+	if limit := client.requestLimit(indexer, searchMovie); limit != 50 {
+		t.Errorf("Expected movie limit 50, got %d", limit)
+	}
+	if limit := client.requestLimit(indexer, searchSeason); limit != 100 {
+		t.Errorf("Expected season limit capped at 100, got %d", limit)
+	}
+	if limit := client.requestLimit(indexer, searchEpisode); limit != 0 {
+		t.Errorf("Expected no episode limit, got %d", limit)
+	}
+}
+
+func TestCapabilitiesRetry(t *testing.T) {
+	requests := 0
+	up := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !up {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><caps><limits max="100" default="50"/></caps>`))
+	}))
+	defer server.Close()
+
+	indexer := Indexer{Name: "test", URL: server.URL, SeasonLimit: 300}
+	client := &Client{httpClient: server.Client(), logger: logrus.New(), caps: make(map[string]capabilities)}
+
+	// The failure is cached for a while
+	for i := 0; i < 2; i++ {
+		if limit := client.requestLimit(indexer, searchSeason); limit != 300 {
+			t.Errorf("Expected the configured limit while caps are unknown, got %d", limit)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 caps request, got %d", requests)
+	}
+
+	// Then detection is tried again
+	up = true
+	client.caps["test"] = capabilities{expires: time.Now().Add(-time.Second)}
+	if limit := client.requestLimit(indexer, searchSeason); limit != 100 {
+		t.Errorf("Expected season limit capped at 100, got %d", limit)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 caps requests, got %d", requests)
+	}
+}
@@ -1,9 +1,15 @@
 package newznab
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/utils"
 )
 
 // SearchResult represents a search result from Newznab
@@ -11,60 +17,113 @@ type SearchResult struct {
 	Title        string
 	Link         string
 	GUID         string
+	IMDBID       string // e.g. "tt0133093", set when the indexer reports it
 	Size         int64
+	PublishedAt  time.Time // Post date, zero if unknown
 	Season       *int
 	Episode      *int
+	LastEpisode  *int // Last episode of a multi-episode release, nil otherwise
 	IsSeasonPack bool
+	Indexer      string          // Name of the indexer that returned this result
+	Protocol     models.Protocol // usenet (Newznab) or torrent (Torznab)
+
+	// Same release on less preferred indexers
+	Alternates []models.NZBAlternate
+
+	// Torrent health (nil/false for usenet results)
+	Seeders   *int
+	Leechers  *int
+	Freeleech bool
+	Score     int // Indexer-weighted health score
 }
 
 // SearchByIMDBID searches for content by IMDB ID (movies only)
-func (c *Client) SearchByIMDBID(imdbID string, mediaType string) ([]SearchResult, error) {
+func (c *Client) SearchByIMDBID(ctx context.Context, imdbID string, mediaType string) ([]SearchResult, error) {
 	if mediaType != "movie" {
 		return nil, fmt.Errorf("SearchByIMDBID only supports movies, got: %s", mediaType)
 	}
 
 	c.logger.WithField("imdb_id", imdbID).Debug("Searching for movie by IMDB ID")
 
-	items, err := c.search("tvsearch", imdbID, nil, nil)
+	results, err := c.searchAll(ctx, "tvsearch", searchMovie, imdbID, "", nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("movie search failed: %w", err)
 	}
 
-	return c.convertResults(items), nil
+	return results, nil
 }
 
 // SearchEpisode searches for a specific episode by IMDB ID
-func (c *Client) SearchEpisode(imdbID string, season, episode int) ([]SearchResult, error) {
+func (c *Client) SearchEpisode(ctx context.Context, imdbID string, season, episode int) ([]SearchResult, error) {
 	c.logger.WithFields(map[string]interface{}{
 		"imdb_id": imdbID,
 		"season":  season,
 		"episode": episode,
 	}).Debug("Searching for TV episode by IMDB ID")
 
-	items, err := c.search("tvsearch", imdbID, &season, &episode)
+	results, err := c.searchAll(ctx, "tvsearch", searchEpisode, imdbID, "", &season, &episode)
 	if err != nil {
 		return nil, fmt.Errorf("episode search failed: %w", err)
 	}
 
-	return c.convertResults(items), nil
+	return results, nil
+}
+
+// SearchAnimeEpisode searches for an anime episode by title and absolute episode number
+// Anime releases are numbered from the first episode of the show (e.g. "One Piece - 1071")
+// and seldom carry an IMDB ID, so the indexers are searched by text in their anime categories.
+// The releases of other shows or episodes are dropped, the others get the Trakt season and
+// episode numbers.
+func (c *Client) SearchAnimeEpisode(ctx context.Context, title string, season, episode, absolute int) ([]SearchResult, error) {
+	c.logger.WithFields(map[string]interface{}{
+		"title":    title,
+		"season":   season,
+		"episode":  episode,
+		"absolute": absolute,
+	}).Debug("Searching for anime episode by absolute number")
+
+	query := fmt.Sprintf("%s %02d", title, absolute)
+	results, err := c.searchAll(ctx, "search", searchEpisode, "", query, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("anime episode search failed: %w", err)
+	}
+
+	show := NormalizeReleaseTitle(title)
+	var matches []SearchResult
+	for _, result := range results {
+		if number, ok := utils.ParseAbsoluteEpisode(result.Title); !ok || number != absolute {
+			continue
+		}
+		if !strings.Contains(NormalizeReleaseTitle(result.Title), show) {
+			continue
+		}
+		result.Season = &season
+		result.Episode = &episode
+		result.IsSeasonPack = false
+		matches = append(matches, result)
+	}
+
+	c.logger.WithFields(map[string]interface{}{
+		"results": len(results),
+		"matches": len(matches),
+	}).Debug("Filtered anime results by absolute episode number")
+
+	return matches, nil
 }
 
 // SearchSeason searches for a season pack by IMDB ID
-func (c *Client) SearchSeason(imdbID string, season int) ([]SearchResult, error) {
+func (c *Client) SearchSeason(ctx context.Context, imdbID string, season int) ([]SearchResult, error) {
 	c.logger.WithFields(map[string]interface{}{
 		"imdb_id": imdbID,
 		"season":  season,
 	}).Debug("Searching for TV season pack by IMDB ID")
 
 	// Search with season but no episode to get season packs
-	items, err := c.search("tvsearch", imdbID, &season, nil)
+	results, err := c.searchAll(ctx, "tvsearch", searchSeason, imdbID, "", &season, nil)
 	if err != nil {
 		return nil, fmt.Errorf("season search failed: %w", err)
 	}
 
-	// Convert all results
-	results := c.convertResults(items)
-
 	// Filter to only season packs
 	var seasonPacks []SearchResult
 	for _, result := range results {
@@ -78,9 +137,99 @@ func (c *Client) SearchSeason(imdbID string, season int) ([]SearchResult, error)
 	return seasonPacks, nil
 }
 
-// parseSeasonEpisode extracts season and episode numbers from title
+// SearchEpisodeByTitle searches for an episode by show title, finding the releases the
+// indexers didn't tag with an IMDB ID. The releases of other shows or episodes are dropped.
+func (c *Client) SearchEpisodeByTitle(ctx context.Context, title string, season, episode int) ([]SearchResult, error) {
+	c.logger.WithFields(map[string]interface{}{
+		"title":   title,
+		"season":  season,
+		"episode": episode,
+	}).Debug("Searching for TV episode by title")
+
+	results, err := c.searchAll(ctx, "tvsearch", searchEpisode, "", title, &season, &episode)
+	if err != nil {
+		return nil, fmt.Errorf("episode title search failed: %w", err)
+	}
+
+	var matches []SearchResult
+	for _, result := range filterShow(results, title) {
+		if result.IsSeasonPack || result.Season == nil || *result.Season != season || result.Episode == nil {
+			continue
+		}
+		last := *result.Episode
+		if result.LastEpisode != nil {
+			last = *result.LastEpisode
+		}
+		if episode >= *result.Episode && episode <= last {
+			matches = append(matches, result)
+		}
+	}
+	return matches, nil
+}
+
+// SearchSeasonByTitle searches for a season pack by show title, finding the releases the
+// indexers didn't tag with an IMDB ID. The releases of other shows or seasons are dropped.
+func (c *Client) SearchSeasonByTitle(ctx context.Context, title string, season int) ([]SearchResult, error) {
+	c.logger.WithFields(map[string]interface{}{
+		"title":  title,
+		"season": season,
+	}).Debug("Searching for TV season pack by title")
+
+	results, err := c.searchAll(ctx, "tvsearch", searchSeason, "", title, &season, nil)
+	if err != nil {
+		return nil, fmt.Errorf("season title search failed: %w", err)
+	}
+
+	var packs []SearchResult
+	for _, result := range filterShow(results, title) {
+		if result.IsSeasonPack && result.Season != nil && *result.Season == season {
+			packs = append(packs, result)
+		}
+	}
+	return packs, nil
+}
+
+// filterShow keeps the releases whose name starts with a show title, apostrophes ignored
+func filterShow(results []SearchResult, title string) []SearchResult {
+	show := NormalizeReleaseTitle(strings.ReplaceAll(title, "'", ""))
+	if show == "" {
+		return nil
+	}
+
+	var matches []SearchResult
+	for _, result := range results {
+		if strings.HasPrefix(NormalizeReleaseTitle(strings.ReplaceAll(result.Title, "'", ""))+" ", show+" ") {
+			matches = append(matches, result)
+		}
+	}
+	return matches
+}
+
+// LatestReleases returns the newest releases of every indexer in its configured categories
+// Indexers answer a search without a query with their latest items, like their RSS feed.
+func (c *Client) LatestReleases(ctx context.Context) ([]SearchResult, error) {
+	c.logger.Debug("Fetching latest releases")
+
+	results, err := c.searchAll(ctx, "search", searchLatest, "", "", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("latest releases fetch failed: %w", err)
+	}
+
+	return results, nil
+}
+
+// parseIMDBID normalizes the imdb attribute of an item, "0133093" or "tt0133093", to "tt0133093"
+func parseIMDBID(value string) string {
+	digits := strings.TrimPrefix(strings.TrimSpace(value), "tt")
+	if _, err := strconv.Atoi(digits); err != nil || strings.Trim(digits, "0") == "" {
+		return ""
+	}
+	return "tt" + digits
+}
+
+// ParseSeasonEpisode extracts season and episode numbers from title
 // Returns (season, episode, isSeasonPack)
-func parseSeasonEpisode(title string) (*int, *int, bool) {
+func ParseSeasonEpisode(title string) (*int, *int, bool) {
 	// Try to match single episode pattern first: S01E01, S02E05, etc.
 	episodeRegex := regexp.MustCompile(`(?i)[\._ ]S(\d{1,2})E(\d{1,2})`)
 	if matches := episodeRegex.FindStringSubmatch(title); matches != nil {
@@ -111,6 +260,11 @@ func (c *Client) convertResults(items []Item) []SearchResult {
 			GUID:  item.GUID,
 		}
 
+		// Torznab results may only carry a magnet link
+		if result.Link == "" {
+			result.Link = GetAttributeValue(item, "magneturl")
+		}
+
 		// DEBUG: Log the URL extraction
 		c.logger.WithFields(map[string]interface{}{
 			"title":         item.Title,
@@ -120,12 +274,35 @@ func (c *Client) convertResults(items []Item) []SearchResult {
 
 		// Extract size from attributes
 		result.Size = GetAttributeInt64(item, "size")
+		result.PublishedAt = parsePublished(item)
+		result.IMDBID = parseIMDBID(GetAttributeValue(item, "imdb"))
+		if result.IMDBID == "" {
+			result.IMDBID = parseIMDBID(GetAttributeValue(item, "imdbid"))
+		}
 
 		// Parse season/episode from title (attributes are not provided by indexer)
-		parsedSeason, parsedEpisode, isSeasonPack := parseSeasonEpisode(item.Title)
+		parsedSeason, parsedEpisode, isSeasonPack := ParseSeasonEpisode(item.Title)
 		result.Season = parsedSeason
 		result.Episode = parsedEpisode
 		result.IsSeasonPack = isSeasonPack
+		if _, _, last, ok := utils.ParseEpisodeRange(item.Title); ok && parsedEpisode != nil {
+			result.LastEpisode = &last
+		}
+
+		// Torznab health attributes
+		result.Seeders = GetAttributeInt(item, "seeders")
+		result.Leechers = GetAttributeInt(item, "leechers")
+		if result.Leechers == nil {
+			// Torznab reports peers as seeders + leechers
+			if peers := GetAttributeInt(item, "peers"); peers != nil && result.Seeders != nil {
+				leechers := *peers - *result.Seeders
+				if leechers < 0 {
+					leechers = 0
+				}
+				result.Leechers = &leechers
+			}
+		}
+		result.Freeleech = GetAttributeValue(item, "downloadvolumefactor") == "0"
 
 		results = append(results, result)
 	}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// discordColors are the embed colors of each event type
+var discordColors = map[EventType]int{
+	EventMediaAdded:        0x3498db,
+	EventMediaRemoved:      0x95a5a6,
+	EventMediaFailed:       0xe74c3c,
+	EventDownloadStarted:   0xf1c40f,
+	EventDownloadCompleted: 0x2ecc71,
+	EventDownloadFailed:    0xe67e22,
+}
+
+// DiscordNotifier posts events to a Discord channel webhook
+type DiscordNotifier struct {
+	webhookURL string
+	events     eventFilter
+	httpClient *http.Client
+}
+
+// NewDiscordNotifier creates a Discord notifier
+func NewDiscordNotifier(webhookURL string, events []string) *DiscordNotifier {
+	return &DiscordNotifier{
+		webhookURL: webhookURL,
+		events:     newEventFilter(events),
+		httpClient: &http.Client{Timeout: chatTimeout},
+	}
+}
+
+// Name returns the notifier name
+func (d *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// Wants reports whether Discord is subscribed to an event type
+func (d *DiscordNotifier) Wants(eventType EventType) bool {
+	return d.events.wants(eventType)
+}
+
+// Send posts an event as a Discord embed
+func (d *DiscordNotifier) Send(ctx context.Context, event Event) error {
+	type embed struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Color       int    `json:"color"`
+		Timestamp   string `json:"timestamp"`
+	}
+	payload := struct {
+		Username string  `json:"username"`
+		Embeds   []embed `json:"embeds"`
+	}{
+		Username: "Gomenarr",
+		Embeds: []embed{{
+			Title:       eventTitle(event),
+			Description: eventText(event),
+			Color:       discordColors[event.Type],
+			Timestamp:   event.Timestamp.UTC().Format("2006-01-02T15:04:05Z"),
+		}},
+	}
+
+	return postJSON(ctx, d.httpClient, d.webhookURL, payload)
+}
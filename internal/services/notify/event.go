@@ -0,0 +1,58 @@
+package notify
+
+import "time"
+
+// EventType identifies what happened
+type EventType string
+
+const (
+	EventMediaAdded        EventType = "media.added"        // New media synced from Trakt
+	EventMediaRemoved      EventType = "media.removed"      // Media and its files cleaned up
+	EventMediaFailed       EventType = "media.failed"       // Download failed after max retries or without candidates left
+	EventDownloadStarted   EventType = "download.started"   // Release sent to TorBox
+	EventDownloadCompleted EventType = "download.completed" // TorBox finished the download
+	EventDownloadFailed    EventType = "download.failed"    // TorBox reported a failure
+	EventIndexerPinFailed  EventType = "indexer.pin_failed" // Indexer certificate doesn't match its pins
+	EventTaskStalled       EventType = "task.stalled"       // Scheduler task running for longer than expected
+	EventDiskSpaceLow      EventType = "disk.low"           // Download volume too full, grabs held back
+
+	// Activity events, only sent to the live event stream
+	EventTaskStarted     EventType = "task.started"     // Scheduler task started
+	EventTaskCompleted   EventType = "task.completed"   // Scheduler task finished, with its cycle summary
+	EventSearchCompleted EventType = "search.completed" // Indexer search finished, with its candidate count
+)
+
+// Event is a notification payload
+// Fields that don't apply to an event type are left empty.
+type Event struct {
+	Type      EventType `json:"type"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Media
+	MediaID   uint64 `json:"media_id,omitempty"`
+	IMDBId    string `json:"imdb_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Year      int    `json:"year,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Source    string `json:"source,omitempty"`
+	Season    *int   `json:"season,omitempty"`
+	Episode   *int   `json:"episode,omitempty"`
+
+	// Release
+	Release  string `json:"release,omitempty"`
+	Quality  string `json:"quality,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Indexer  string `json:"indexer,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	JobID    string `json:"job_id,omitempty"`
+
+	// Activity
+	Task       string         `json:"task,omitempty"`
+	Strategy   string         `json:"strategy,omitempty"`
+	Counts     map[string]int `json:"counts,omitempty"`
+	Failures   int            `json:"failures,omitempty"`
+	DurationMS int64          `json:"duration_ms,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// chatTimeout bounds the requests of the chat notifiers (Discord, Telegram, Pushover)
+const chatTimeout = 10 * time.Second
+
+// eventTitles are the headlines of chat notifications
+var eventTitles = map[EventType]string{
+	EventMediaAdded:        "Media added",
+	EventMediaRemoved:      "Media removed",
+	EventMediaFailed:       "Media failed",
+	EventDownloadStarted:   "Download started",
+	EventDownloadCompleted: "Download completed",
+	EventDownloadFailed:    "Download failed",
+	EventIndexerPinFailed:  "Indexer certificate mismatch",
+	EventTaskStalled:       "Task stalled",
+	EventDiskSpaceLow:      "Disk space low",
+}
+
+// eventFilter holds the event types a notifier is subscribed to, nil for all
+type eventFilter map[EventType]bool
+
+// newEventFilter builds a filter from configured event names
+func newEventFilter(events []string) eventFilter {
+	if len(events) == 0 {
+		return nil
+	}
+
+	filter := make(eventFilter)
+	for _, event := range events {
+		filter[EventType(event)] = true
+	}
+	return filter
+}
+
+// wants reports whether an event type passes the filter
+func (f eventFilter) wants(eventType EventType) bool {
+	return f == nil || f[eventType]
+}
+
+// eventTitle returns the headline of an event
+func eventTitle(event Event) string {
+	if title, ok := eventTitles[event.Type]; ok {
+		return title
+	}
+	return string(event.Type)
+}
+
+// eventText renders an event as plain text lines for chat notifications
+func eventText(event Event) string {
+	lines := []string{event.Message}
+	if event.Release != "" {
+		lines = append(lines, "Release: "+event.Release)
+	}
+	if event.Indexer != "" {
+		lines = append(lines, "Indexer: "+event.Indexer)
+	}
+	if event.Error != "" {
+		lines = append(lines, "Error: "+event.Error)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// postJSON sends a JSON payload and checks for a 2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gomenarr/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("request returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// sendTimeout bounds how long a single notifier may take for one event
+const sendTimeout = 15 * time.Second
+
+// Notifier delivers events to an external service
+type Notifier interface {
+	Name() string
+	Wants(eventType EventType) bool
+	Send(ctx context.Context, event Event) error
+}
+
+// Dispatcher fans events out to the configured notifiers and the live event stream
+// A nil Dispatcher is valid and drops every event.
+type Dispatcher struct {
+	notifiers []Notifier
+	stream    *Broker
+	logger    *logrus.Logger
+}
+
+// NewDispatcher creates a dispatcher with the notifiers from configuration
+func NewDispatcher(cfg *config.Config, logger *logrus.Logger) (*Dispatcher, error) {
+	var notifiers []Notifier
+
+	for _, webhookCfg := range cfg.Webhooks {
+		webhook, err := NewWebhookNotifier(webhookCfg)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, webhook)
+	}
+
+	if cfg.DiscordWebhookURL != "" {
+		notifiers = append(notifiers, NewDiscordNotifier(cfg.DiscordWebhookURL, cfg.DiscordEvents))
+	}
+
+	if cfg.TelegramBotToken != "" {
+		if cfg.TelegramChatID == "" {
+			return nil, fmt.Errorf("TELEGRAM_CHAT_ID is required with TELEGRAM_BOT_TOKEN")
+		}
+		notifiers = append(notifiers, NewTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, cfg.TelegramEvents))
+	}
+
+	if cfg.PushoverToken != "" {
+		if cfg.PushoverUser == "" {
+			return nil, fmt.Errorf("PUSHOVER_USER is required with PUSHOVER_TOKEN")
+		}
+		notifiers = append(notifiers, NewPushoverNotifier(cfg.PushoverToken, cfg.PushoverUser, cfg.PushoverPriority, cfg.PushoverEvents))
+	}
+
+	return &Dispatcher{
+		notifiers: notifiers,
+		stream:    NewBroker(),
+		logger:    logger,
+	}, nil
+}
+
+// Notifiers returns the names of the configured notifiers
+func (d *Dispatcher) Notifiers() []string {
+	if d == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(d.notifiers))
+	for _, notifier := range d.notifiers {
+		names = append(names, notifier.Name())
+	}
+	return names
+}
+
+// Subscribe returns a channel receiving the events sent from now on and a function
+// ending the subscription
+// Without a dispatcher the channel is already closed.
+func (d *Dispatcher) Subscribe() (<-chan Event, func()) {
+	if d == nil {
+		events := make(chan Event)
+		close(events)
+		return events, func() {}
+	}
+
+	return d.stream.Subscribe()
+}
+
+// Publish sends an activity event to the live event stream only
+func (d *Dispatcher) Publish(event Event) {
+	if d == nil {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	d.stream.Publish(event)
+}
+
+// Notify sends an event to the live event stream and every notifier subscribed to its type
+// Delivery is asynchronous: failures are logged and never block the caller.
+func (d *Dispatcher) Notify(event Event) {
+	if d == nil {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	d.stream.Publish(event)
+
+	for _, notifier := range d.notifiers {
+		if !notifier.Wants(event.Type) {
+			continue
+		}
+
+		go func(notifier Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+
+			if err := notifier.Send(ctx, event); err != nil {
+				d.logger.WithError(err).WithFields(logrus.Fields{
+					"notifier": notifier.Name(),
+					"event":    event.Type,
+				}).Warn("Failed to send notification")
+				return
+			}
+
+			d.logger.WithFields(logrus.Fields{
+				"notifier": notifier.Name(),
+				"event":    event.Type,
+			}).Debug("Notification sent")
+		}(notifier)
+	}
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// pushoverAPIURL is the Pushover message endpoint
+const pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+// PushoverNotifier sends events as Pushover push notifications
+type PushoverNotifier struct {
+	apiURL     string
+	token      string
+	user       string
+	priority   int
+	events     eventFilter
+	httpClient *http.Client
+}
+
+// NewPushoverNotifier creates a Pushover notifier
+// priority follows Pushover's scale, -2 (silent) to 1 (high).
+func NewPushoverNotifier(token, user string, priority int, events []string) *PushoverNotifier {
+	return &PushoverNotifier{
+		apiURL:     pushoverAPIURL,
+		token:      token,
+		user:       user,
+		priority:   priority,
+		events:     newEventFilter(events),
+		httpClient: &http.Client{Timeout: chatTimeout},
+	}
+}
+
+// Name returns the notifier name
+func (p *PushoverNotifier) Name() string {
+	return "pushover"
+}
+
+// Wants reports whether Pushover is subscribed to an event type
+func (p *PushoverNotifier) Wants(eventType EventType) bool {
+	return p.events.wants(eventType)
+}
+
+// Send pushes an event to Pushover
+func (p *PushoverNotifier) Send(ctx context.Context, event Event) error {
+	payload := map[string]interface{}{
+		"token":     p.token,
+		"user":      p.user,
+		"title":     "Gomenarr: " + eventTitle(event),
+		"message":   eventText(event),
+		"priority":  p.priority,
+		"timestamp": event.Timestamp.Unix(),
+	}
+
+	return postJSON(ctx, p.httpClient, p.apiURL, payload)
+}
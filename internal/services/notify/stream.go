@@ -0,0 +1,59 @@
+package notify
+
+import "sync"
+
+// streamBuffer is how many events a stream subscriber may lag behind before
+// events are dropped for it
+const streamBuffer = 64
+
+// Broker broadcasts events to live subscribers (e.g. the activity stream)
+// Slow subscribers miss events instead of holding up the publisher.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewBroker creates a broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published from now on and a
+// function ending the subscription, which closes the channel
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, streamBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			close(ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends an event to every subscriber with room in its buffer
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the number of live subscribers
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
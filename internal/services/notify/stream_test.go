@@ -0,0 +1,39 @@
+package notify
+
+import "testing"
+
+func TestBroker(t *testing.T) {
+	broker := NewBroker()
+
+	events, unsubscribe := broker.Subscribe()
+	broker.Publish(Event{Type: EventDownloadStarted})
+
+	if event := <-events; event.Type != EventDownloadStarted {
+		t.Errorf("got event %q, want %q", event.Type, EventDownloadStarted)
+	}
+
+	// A subscriber that doesn't read must not block the publisher
+	for i := 0; i < streamBuffer*2; i++ {
+		broker.Publish(Event{Type: EventTaskCompleted})
+	}
+	if got := len(events); got != streamBuffer {
+		t.Errorf("got %d buffered events, want %d", got, streamBuffer)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if got := broker.Subscribers(); got != 0 {
+		t.Errorf("got %d subscribers after unsubscribe, want 0", got)
+	}
+	broker.Publish(Event{Type: EventTaskStarted})
+}
+
+func TestDispatcherSubscribeDisabled(t *testing.T) {
+	var d *Dispatcher
+
+	events, unsubscribe := d.Subscribe()
+	if _, ok := <-events; ok {
+		t.Error("got an event from a disabled dispatcher, want a closed channel")
+	}
+	unsubscribe()
+}
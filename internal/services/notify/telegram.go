@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// telegramAPIBase is the Telegram Bot API endpoint
+const telegramAPIBase = "https://api.telegram.org"
+
+// TelegramNotifier sends events to a Telegram chat through a bot
+type TelegramNotifier struct {
+	apiBase    string
+	botToken   string
+	chatID     string
+	events     eventFilter
+	httpClient *http.Client
+}
+
+// NewTelegramNotifier creates a Telegram notifier
+func NewTelegramNotifier(botToken, chatID string, events []string) *TelegramNotifier {
+	return &TelegramNotifier{
+		apiBase:    telegramAPIBase,
+		botToken:   botToken,
+		chatID:     chatID,
+		events:     newEventFilter(events),
+		httpClient: &http.Client{Timeout: chatTimeout},
+	}
+}
+
+// Name returns the notifier name
+func (t *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Wants reports whether Telegram is subscribed to an event type
+func (t *TelegramNotifier) Wants(eventType EventType) bool {
+	return t.events.wants(eventType)
+}
+
+// Send posts an event as a Telegram message
+func (t *TelegramNotifier) Send(ctx context.Context, event Event) error {
+	payload := map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     eventTitle(event) + "\n" + eventText(event),
+		"disable_web_page_preview": true,
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", t.apiBase, t.botToken)
+	if err := postJSON(ctx, t.httpClient, url, payload); err != nil {
+		// Transport errors quote the URL, which holds the bot token
+		if t.botToken != "" {
+			return errors.New(strings.ReplaceAll(err.Error(), t.botToken, "<redacted>"))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	var gotPath string
+	var gotPayload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotPayload)
+	}))
+	defer server.Close()
+
+	notifier := NewTelegramNotifier("123:abc", "42", []string{string(EventMediaFailed)})
+	notifier.apiBase = server.URL
+
+	if notifier.Wants(EventDownloadStarted) {
+		t.Error("Telegram should not want unsubscribed events")
+	}
+
+	err := notifier.Send(context.Background(), Event{
+		Type:    EventMediaFailed,
+		Message: "Gave up on The Matrix (1999)",
+		Error:   "max retries reached",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if gotPath != "/bot123:abc/sendMessage" {
+		t.Errorf("Expected sendMessage path, got %s", gotPath)
+	}
+	if gotPayload["chat_id"] != "42" {
+		t.Errorf("Expected chat_id 42, got %v", gotPayload["chat_id"])
+	}
+	text, _ := gotPayload["text"].(string)
+	if !strings.HasPrefix(text, "Media failed\n") || !strings.Contains(text, "Error: max retries reached") {
+		t.Errorf("Unexpected message text %q", text)
+	}
+}
+
+func TestTelegramNotifierRedactsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	notifier := NewTelegramNotifier("123:abc", "42", nil)
+	notifier.apiBase = server.URL
+
+	err := notifier.Send(context.Background(), Event{Type: EventMediaFailed})
+	if err == nil {
+		t.Fatal("Expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "123:abc") {
+		t.Errorf("Error leaks the bot token: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/config"
+)
+
+// WebhookNotifier sends events to a generic HTTP endpoint
+// The body is rendered from a Go template with the event as data, or is the
+// JSON-encoded event when no template is configured.
+type WebhookNotifier struct {
+	name       string
+	url        string
+	method     string
+	headers    map[string]string
+	body       *template.Template
+	events     eventFilter
+	httpClient *http.Client
+}
+
+// templateFuncs are available in webhook body templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value, e.g. {"title": {{json .Title}}}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// NewWebhookNotifier creates a webhook notifier from configuration
+func NewWebhookNotifier(cfg config.WebhookConfig) (*WebhookNotifier, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook %s: URL is required", cfg.Name)
+	}
+
+	notifier := &WebhookNotifier{
+		name:       cfg.Name,
+		url:        cfg.URL,
+		method:     cfg.Method,
+		headers:    cfg.Headers,
+		events:     newEventFilter(cfg.Events),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if notifier.method == "" {
+		notifier.method = http.MethodPost
+	}
+
+	if cfg.Body != "" {
+		body, err := template.New(cfg.Name).Funcs(templateFuncs).Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid body template: %w", cfg.Name, err)
+		}
+		notifier.body = body
+	}
+
+	return notifier, nil
+}
+
+// Name returns the webhook name
+func (w *WebhookNotifier) Name() string {
+	return w.name
+}
+
+// Wants reports whether the webhook is subscribed to an event type
+func (w *WebhookNotifier) Wants(eventType EventType) bool {
+	return w.events.wants(eventType)
+}
+
+// Send delivers an event to the webhook
+func (w *WebhookNotifier) Send(ctx context.Context, event Event) error {
+	body, err := w.render(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gomenarr/1.0")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// render builds the request body for an event
+func (w *WebhookNotifier) render(event Event) ([]byte, error) {
+	if w.body == nil {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event: %w", err)
+		}
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := w.body.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("failed to render body template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/config"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var gotMethod, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(config.WebhookConfig{
+		Name:    "test",
+		URL:     server.URL,
+		Method:  http.MethodPut,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Body:    `{"message": {{json .Message}}, "type": "{{.Type}}"}`,
+		Events:  []string{string(EventDownloadCompleted)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	if notifier.Wants(EventMediaAdded) {
+		t.Error("Webhook should not want unsubscribed events")
+	}
+	if !notifier.Wants(EventDownloadCompleted) {
+		t.Error("Webhook should want subscribed events")
+	}
+
+	event := Event{Type: EventDownloadCompleted, Message: `Downloaded "Dune"`}
+	if err := notifier.Send(context.Background(), event); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("Expected PUT, got %s", gotMethod)
+	}
+	if gotAuth != "Bearer token" {
+		t.Errorf("Expected Authorization header, got %q", gotAuth)
+	}
+	expected := `{"message": "Downloaded \"Dune\"", "type": "download.completed"}`
+	if gotBody != expected {
+		t.Errorf("Expected body %s, got %s", expected, gotBody)
+	}
+}
+
+func TestWebhookInvalidTemplate(t *testing.T) {
+	_, err := NewWebhookNotifier(config.WebhookConfig{Name: "bad", URL: "http://localhost", Body: "{{.Title"})
+	if err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
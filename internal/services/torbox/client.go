@@ -2,15 +2,23 @@ package torbox
 
 import (
 	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/amaumene/gomenarr/internal/services/httpclient"
 	"github.com/sirupsen/logrus"
 )
 
 // Client wraps the TorBox SDK
 type Client struct {
-	apiKey string
-	logger *logrus.Logger
+	apiKey        string
+	httpClient    *http.Client // API calls
+	uploadClient  *http.Client // NZB and torrent uploads, large season packs take longer
+	uploadRetries int
+	uploadGzip    atomic.Bool // Cleared when TorBox rejects compressed uploads
+	logger        *logrus.Logger
 }
 
 // NewClient creates a new TorBox client
@@ -19,8 +27,21 @@ func NewClient(cfg *config.Config, logger *logrus.Logger) (*Client, error) {
 		return nil, fmt.Errorf("TorBox API key is required")
 	}
 
-	return &Client{
-		apiKey: cfg.TorBoxAPIKey,
-		logger: logger,
-	}, nil
+	options := httpclient.NewOptions("torbox", cfg.RateLimits.TorBox, cfg)
+	options.Proxy = cfg.Proxies.TorBox
+	// Uploads have their own retries (TORBOX_UPLOAD_RETRIES)
+	uploadOptions := httpclient.NewOptions("torbox_upload", cfg.RateLimits.TorBox, cfg)
+	uploadOptions.MaxRetries = 0
+	uploadOptions.Proxy = cfg.Proxies.TorBox
+
+	client := &Client{
+		apiKey:        cfg.TorBoxAPIKey,
+		httpClient:    httpclient.NewClient(time.Duration(cfg.TorBoxAPITimeout)*time.Second, options, logger),
+		uploadClient:  httpclient.NewClient(time.Duration(cfg.TorBoxUploadTimeout)*time.Second, uploadOptions, logger),
+		uploadRetries: cfg.TorBoxUploadRetries,
+		logger:        logger,
+	}
+	client.uploadGzip.Store(cfg.TorBoxUploadGzip)
+
+	return client, nil
 }
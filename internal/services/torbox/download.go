@@ -3,6 +3,7 @@ package torbox
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -13,6 +14,9 @@ import (
 
 const torboxAPIBase = "https://api.torbox.app/v1/api"
 
+// ErrJobNotFound is returned when TorBox no longer has a download job
+var ErrJobNotFound = errors.New("job not found")
+
 // CreateDownloadJobRequest represents a download job creation request
 type CreateDownloadJobRequest struct {
 	Link string `json:"link"` // NZB download link
@@ -69,6 +73,28 @@ type UsenetDownload struct {
 	ExpiresAt        *string              `json:"expires_at"`
 }
 
+// InfectedFiles returns the files TorBox flagged as infected or corrupt
+func (d *UsenetDownload) InfectedFiles() []UsenetDownloadFile {
+	var files []UsenetDownloadFile
+	for _, file := range d.Files {
+		if file.Infected {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// HealthyFiles returns the files that downloaded without issues
+func (d *UsenetDownload) HealthyFiles() []UsenetDownloadFile {
+	var files []UsenetDownloadFile
+	for _, file := range d.Files {
+		if !file.Infected {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
 // UsenetListResponse represents the response from listing usenet downloads
 type UsenetListResponse struct {
 	Success bool             `json:"success"`
@@ -77,9 +103,16 @@ type UsenetListResponse struct {
 	Data    []UsenetDownload `json:"data"`
 }
 
+// reservedJobFields are form fields managed by CreateDownloadJob itself
+var reservedJobFields = map[string]bool{
+	"file": true,
+	"name": true,
+}
+
 // CreateDownloadJob creates a new download job in TorBox by uploading NZB file
+// params are passed through as extra form fields (e.g. post_processing, as_queued, password)
 // Returns the job ID and the full response (for checking cached status)
-func (c *Client) CreateDownloadJob(nzbData []byte, filename string, name string) (string, *CreateDownloadJobResponse, error) {
+func (c *Client) CreateDownloadJob(nzbData []byte, filename string, name string, params map[string]string) (string, *CreateDownloadJobResponse, error) {
 	// Create multipart form data
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
@@ -101,6 +134,17 @@ func (c *Client) CreateDownloadJob(nzbData []byte, filename string, name string)
 		}
 	}
 
+	// Add extra parameters
+	for key, value := range params {
+		if reservedJobFields[key] {
+			c.logger.WithField("param", key).Warn("Ignoring reserved TorBox download parameter")
+			continue
+		}
+		if err := writer.WriteField(key, value); err != nil {
+			return "", nil, fmt.Errorf("failed to add %s field: %w", key, err)
+		}
+	}
+
 	// Close the writer to finalize the multipart form
 	if err := writer.Close(); err != nil {
 		return "", nil, fmt.Errorf("failed to close multipart writer: %w", err)
@@ -112,37 +156,17 @@ func (c *Client) CreateDownloadJob(nzbData []byte, filename string, name string)
 		"filename":  filename,
 		"size_kb":   len(nzbData) / 1024,
 		"size_bytes": len(nzbData),
+		"params":    params,
 	}).Debug("Uploading NZB file to TorBox API")
 
-	req, err := http.NewRequest("POST", torboxAPIBase+"/usenet/createusenetdownload", &buf)
+	bodyBytes, err := c.upload("/usenet/createusenetdownload", writer.FormDataContentType(), buf.Bytes(), c.createdDownload(name))
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body for debugging
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return "", nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return "", nil, err
 	}
 
 	// DEBUG: Log the raw response
 	c.logger.WithFields(map[string]interface{}{
-		"status_code": resp.StatusCode,
-		"body":        string(bodyBytes),
+		"body": string(bodyBytes),
 	}).Debug("TorBox API response")
 
 	var result CreateDownloadJobResponse
@@ -163,6 +187,33 @@ func (c *Client) CreateDownloadJob(nzbData []byte, filename string, name string)
 	return jobID, &result, nil
 }
 
+// createdDownload finds the usenet download an upload named name created, as the
+// response TorBox would have sent for it, nil if there is none. The check itself is nil
+// when the upload is unnamed.
+func (c *Client) createdDownload(name string) func() ([]byte, error) {
+	if name == "" {
+		return nil
+	}
+	return func() ([]byte, error) {
+		downloads, err := c.ListUsenetDownloads()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check whether TorBox created the download: %w", err)
+		}
+		for _, download := range downloads {
+			if download.Name != name {
+				continue
+			}
+			var result CreateDownloadJobResponse
+			result.Success = true
+			result.Data.Hash = download.Hash
+			result.Data.UsenetDownloadID = download.ID
+			result.Data.AuthID = download.AuthID
+			return json.Marshal(result)
+		}
+		return nil, nil
+	}
+}
+
 // JobStatusResponse represents the response from job status query
 type JobStatusResponse struct {
 	Success bool   `json:"success"`
@@ -179,8 +230,7 @@ func (c *Client) GetJobStatus(jobID string) (string, error) {
 
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -225,8 +275,7 @@ func (c *Client) ControlUsenetDownload(usenetID int, operation string) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -264,8 +313,7 @@ func (c *Client) ListUsenetDownloads() ([]UsenetDownload, error) {
 
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -293,18 +341,67 @@ func (c *Client) ListUsenetDownloads() ([]UsenetDownload, error) {
 	return result.Data, nil
 }
 
-// FindDownloadByID finds a specific usenet download by its ID
+// FindDownloadByID looks up a specific usenet download by its ID
+// Returns ErrJobNotFound when TorBox no longer has it.
 func (c *Client) FindDownloadByID(downloadID int) (*UsenetDownload, error) {
-	downloads, err := c.ListUsenetDownloads()
+	var download *UsenetDownload
+	if err := c.getJob("/usenet/mylist", downloadID, &download); err != nil {
+		return nil, err
+	}
+	if download == nil {
+		return nil, fmt.Errorf("download with ID %d: %w", downloadID, ErrJobNotFound)
+	}
+	return download, nil
+}
+
+// getJob reads a single download of a list endpoint into data
+// Only that job is fetched, the full lists are not paginated and may not include it.
+func (c *Client) getJob(endpoint string, id int, data interface{}) error {
+	query := url.Values{}
+	query.Set("id", strconv.Itoa(id))
+	query.Set("bypass_cache", "true")
+
+	req, err := http.NewRequest("GET", torboxAPIBase+endpoint+"?"+query.Encode(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list downloads: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	for _, download := range downloads {
-		if download.ID == downloadID {
-			return &download, nil
-		}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("download with ID %d: %w", id, ErrJobNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	return nil, fmt.Errorf("download with ID %d not found", downloadID)
+	result := struct {
+		Success bool            `json:"success"`
+		Detail  string          `json:"detail"`
+		Data    json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("failed to get download %d: %s", id, result.Detail)
+	}
+	if len(result.Data) == 0 || string(result.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, data); err != nil {
+		return fmt.Errorf("failed to decode download: %w", err)
+	}
+	return nil
 }
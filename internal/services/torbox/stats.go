@@ -0,0 +1,48 @@
+package torbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stats summarizes the usenet and torrent queues of the TorBox account
+type Stats struct {
+	Queued        int   `json:"queued"`          // Unfinished downloads
+	Paused        int   `json:"paused"`          // Unfinished downloads TorBox paused
+	DownloadSpeed int64 `json:"download_speed"`  // Bytes per second, all downloads
+	Remaining     int64 `json:"remaining_bytes"` // Bytes left to download
+}
+
+// Stats reads the usenet downloads and torrents of the account and sums their progress
+func (c *Client) Stats() (*Stats, error) {
+	downloads, err := c.ListUsenetDownloads()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usenet downloads: %w", err)
+	}
+	torrents, err := c.ListTorrents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+
+	stats := &Stats{}
+	for _, download := range downloads {
+		stats.add(download.DownloadState, download.DownloadFinished || download.Cached, download.DownloadSpeed, download.Size, download.Progress)
+	}
+	for _, torrent := range torrents {
+		stats.add(torrent.DownloadState, torrent.DownloadFinished || torrent.Cached, torrent.DownloadSpeed, torrent.Size, torrent.Progress)
+	}
+	return stats, nil
+}
+
+// add counts a download in the stats, progress is the downloaded fraction (0 to 1)
+func (s *Stats) add(state string, finished bool, speed int, size int64, progress float64) {
+	if finished {
+		return
+	}
+	s.Queued++
+	if strings.Contains(strings.ToLower(state), "paused") {
+		s.Paused++
+	}
+	s.DownloadSpeed += int64(speed)
+	s.Remaining += int64(float64(size) * (1 - min(max(progress, 0), 1)))
+}
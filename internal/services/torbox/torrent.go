@@ -0,0 +1,255 @@
+package torbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// CreateTorrentJobResponse represents the response from creating a torrent job
+type CreateTorrentJobResponse struct {
+	Success bool    `json:"success"`
+	Error   *string `json:"error"`
+	Detail  string  `json:"detail"` // e.g., "Found cached torrent. Using cached torrent."
+	Data    struct {
+		Hash      string `json:"hash"`
+		TorrentID int    `json:"torrent_id"`
+		AuthID    string `json:"auth_id"`
+	} `json:"data"`
+}
+
+// TorrentDownload represents a torrent download from TorBox
+type TorrentDownload struct {
+	ID               int                  `json:"id"`
+	CreatedAt        string               `json:"created_at"`
+	UpdatedAt        string               `json:"updated_at"`
+	Name             string               `json:"name"`
+	Hash             string               `json:"hash"`
+	DownloadState    string               `json:"download_state"`
+	DownloadSpeed    int                  `json:"download_speed"`
+	Progress         float64              `json:"progress"`
+	Size             int64                `json:"size"`
+	Seeds            int                  `json:"seeds"`
+	Peers            int                  `json:"peers"`
+	Files            []UsenetDownloadFile `json:"files"`
+	Active           bool                 `json:"active"`
+	Cached           bool                 `json:"cached"`
+	DownloadPresent  bool                 `json:"download_present"`
+	DownloadFinished bool                 `json:"download_finished"`
+	ExpiresAt        *string              `json:"expires_at"`
+}
+
+// TorrentListResponse represents the response from listing torrent downloads
+type TorrentListResponse struct {
+	Success bool              `json:"success"`
+	Error   *string           `json:"error"`
+	Detail  string            `json:"detail"`
+	Data    []TorrentDownload `json:"data"`
+}
+
+// reservedTorrentFields are form fields managed by CreateTorrentJob itself
+var reservedTorrentFields = map[string]bool{
+	"file":   true,
+	"magnet": true,
+	"name":   true,
+}
+
+// CreateTorrentJob creates a new torrent download in TorBox
+// Either torrentData (a .torrent file) or magnet must be set.
+// Returns the job ID and the full response (for checking cached status)
+func (c *Client) CreateTorrentJob(torrentData []byte, magnet string, name string, params map[string]string) (string, *CreateTorrentJobResponse, error) {
+	if len(torrentData) == 0 && magnet == "" {
+		return "", nil, fmt.Errorf("torrent file or magnet link is required")
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	if magnet != "" {
+		if err := writer.WriteField("magnet", magnet); err != nil {
+			return "", nil, fmt.Errorf("failed to add magnet field: %w", err)
+		}
+	} else {
+		part, err := writer.CreateFormFile("file", name+".torrent")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to create form file: %w", err)
+		}
+		if _, err := part.Write(torrentData); err != nil {
+			return "", nil, fmt.Errorf("failed to write torrent data: %w", err)
+		}
+	}
+
+	if name != "" {
+		if err := writer.WriteField("name", name); err != nil {
+			return "", nil, fmt.Errorf("failed to add name field: %w", err)
+		}
+	}
+
+	for key, value := range params {
+		if reservedTorrentFields[key] {
+			c.logger.WithField("param", key).Warn("Ignoring reserved TorBox download parameter")
+			continue
+		}
+		if err := writer.WriteField(key, value); err != nil {
+			return "", nil, fmt.Errorf("failed to add %s field: %w", key, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	c.logger.WithFields(map[string]interface{}{
+		"name":   name,
+		"magnet": magnet != "",
+		"params": params,
+	}).Debug("Creating torrent in TorBox API")
+
+	bodyBytes, err := c.upload("/torrents/createtorrent", writer.FormDataContentType(), buf.Bytes(), c.createdTorrent(name))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var result CreateTorrentJobResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return "", nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Success {
+		return "", nil, fmt.Errorf("torrent creation failed: %s", result.Detail)
+	}
+
+	jobID := fmt.Sprintf("%d", result.Data.TorrentID)
+	c.logger.WithFields(map[string]interface{}{
+		"job_id": jobID,
+		"detail": result.Detail,
+	}).Info("Created TorBox torrent job")
+	return jobID, &result, nil
+}
+
+// createdTorrent finds the torrent an upload named name created, as the response
+// TorBox would have sent for it, nil if there is none. The check itself is nil when the
+// upload is unnamed.
+func (c *Client) createdTorrent(name string) func() ([]byte, error) {
+	if name == "" {
+		return nil
+	}
+	return func() ([]byte, error) {
+		torrents, err := c.ListTorrents()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check whether TorBox created the torrent: %w", err)
+		}
+		for _, torrent := range torrents {
+			if torrent.Name != name {
+				continue
+			}
+			var result CreateTorrentJobResponse
+			result.Success = true
+			result.Data.Hash = torrent.Hash
+			result.Data.TorrentID = torrent.ID
+			return json.Marshal(result)
+		}
+		return nil, nil
+	}
+}
+
+// ControlTorrent controls a torrent download (delete, pause, etc.)
+func (c *Client) ControlTorrent(torrentID int, operation string) error {
+	data := map[string]interface{}{
+		"torrent_id": torrentID,
+		"operation":  operation,
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", torboxAPIBase+"/torrents/controltorrent", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	c.logger.WithFields(map[string]interface{}{
+		"torrent_id": torrentID,
+		"operation":  operation,
+	}).Info("Controlled TorBox torrent")
+	return nil
+}
+
+// DeleteTorrentJob deletes a torrent job by ID
+func (c *Client) DeleteTorrentJob(jobID string) error {
+	torrentID, err := strconv.Atoi(jobID)
+	if err != nil {
+		return fmt.Errorf("invalid job ID: %w", err)
+	}
+
+	return c.ControlTorrent(torrentID, "delete")
+}
+
+// ListTorrents retrieves all torrent downloads from TorBox
+func (c *Client) ListTorrents() ([]TorrentDownload, error) {
+	req, err := http.NewRequest("GET", torboxAPIBase+"/torrents/mylist", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result TorrentListResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Success {
+		return nil, fmt.Errorf("failed to list torrents: %s", result.Detail)
+	}
+
+	return result.Data, nil
+}
+
+// FindTorrentByID looks up a specific torrent download by its ID
+// Returns ErrJobNotFound when TorBox no longer has it.
+func (c *Client) FindTorrentByID(torrentID int) (*TorrentDownload, error) {
+	var torrent *TorrentDownload
+	if err := c.getJob("/torrents/mylist", torrentID, &torrent); err != nil {
+		return nil, err
+	}
+	if torrent == nil {
+		return nil, fmt.Errorf("torrent with ID %d: %w", torrentID, ErrJobNotFound)
+	}
+	return torrent, nil
+}
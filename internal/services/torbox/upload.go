@@ -0,0 +1,146 @@
+package torbox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// uploadBackoff is the delay before the first upload retry, doubled for each following one
+const uploadBackoff = 2 * time.Second
+
+// gzipMinSize is the smallest payload worth compressing
+const gzipMinSize = 64 * 1024
+
+// uploadOutcome tells whether a failed upload may be sent again
+type uploadOutcome int
+
+const (
+	uploadFinal   uploadOutcome = iota // Not worth sending again
+	uploadRefused                      // TorBox never accepted it (connection failed, rate limited)
+	uploadUnknown                      // TorBox may have created the job (timeout, server error)
+)
+
+// upload posts a multipart form to a TorBox endpoint and returns the response body
+// Uploads TorBox never accepted are retried with exponential backoff. Uploads that may
+// have gone through (timeouts, server errors) are only retried when created confirms
+// TorBox has no job for them, created returns the response for the job it finds. Large payloads are
+// gzip-compressed when enabled; if TorBox rejects the encoding, compression is disabled
+// and the upload is sent again uncompressed.
+func (c *Client) upload(endpoint string, contentType string, body []byte, created func() ([]byte, error)) ([]byte, error) {
+	backoff := uploadBackoff
+
+	for attempt := 0; ; attempt++ {
+		respBody, outcome, err := c.uploadOnce(endpoint, contentType, body)
+		if err == nil {
+			return respBody, nil
+		}
+		if outcome == uploadFinal || attempt >= c.uploadRetries {
+			return nil, err
+		}
+		if outcome == uploadUnknown {
+			if created == nil {
+				return nil, err
+			}
+			respBody, checkErr := created()
+			if checkErr != nil {
+				c.logger.WithError(checkErr).WithField("endpoint", endpoint).Warn("Not retrying upload TorBox may have accepted")
+				return nil, err
+			}
+			if respBody != nil {
+				c.logger.WithError(err).WithField("endpoint", endpoint).Info("TorBox created the job despite the failed upload")
+				return respBody, nil
+			}
+		}
+
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"endpoint": endpoint,
+			"attempt":  attempt + 1,
+			"backoff":  backoff,
+			"size_kb":  len(body) / 1024,
+		}).Warn("TorBox upload failed, retrying")
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// uploadOnce sends a single upload attempt
+// Returns the response body, or whether the upload may be sent again.
+func (c *Client) uploadOnce(endpoint string, contentType string, body []byte) ([]byte, uploadOutcome, error) {
+	compressed := c.uploadGzip.Load() && len(body) >= gzipMinSize
+
+	payload := body
+	if compressed {
+		var err error
+		if payload, err = gzipPayload(body); err != nil {
+			return nil, uploadFinal, fmt.Errorf("failed to compress upload: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest("POST", torboxAPIBase+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, uploadFinal, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := c.uploadClient.Do(req)
+	if err != nil {
+		// A failed dial never reached TorBox
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, uploadRefused, fmt.Errorf("failed to execute request: %w", err)
+		}
+		return nil, uploadUnknown, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, uploadUnknown, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if compressed && resp.StatusCode == http.StatusUnsupportedMediaType {
+		c.uploadGzip.Store(false)
+		c.logger.Warn("TorBox rejected compressed upload, disabling gzip")
+		return c.uploadOnce(endpoint, contentType, body)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		outcome := uploadFinal
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			outcome = uploadRefused
+		case resp.StatusCode >= 500:
+			outcome = uploadUnknown
+		}
+		return nil, outcome, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, uploadFinal, nil
+}
+
+// gzipPayload compresses an upload body
+func gzipPayload(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -3,9 +3,11 @@ package trakt
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"time"
+
+	"github.com/amaumene/gomenarr/internal/storage"
 )
 
 // TokenStore defines the interface for storing and retrieving tokens
@@ -21,22 +23,25 @@ type Token struct {
 	ExpiresAt    time.Time `json:"expires_at"`
 }
 
-// FileTokenStore implements TokenStore using a JSON file
-type FileTokenStore struct {
-	filepath string
+// tokenKey is the storage key of the token
+const tokenKey = "token.json"
+
+// StorageTokenStore implements TokenStore on a storage backend (token.json in the config directory by default)
+type StorageTokenStore struct {
+	store storage.Store
 }
 
-// NewFileTokenStore creates a new file-based token store
-func NewFileTokenStore(filepath string) (*FileTokenStore, error) {
-	return &FileTokenStore{filepath: filepath}, nil
+// NewStorageTokenStore creates a token store on a storage backend
+func NewStorageTokenStore(store storage.Store) *StorageTokenStore {
+	return &StorageTokenStore{store: store}
 }
 
-// GetToken retrieves the token from the file
-func (s *FileTokenStore) GetToken() (*Token, error) {
-	data, err := os.ReadFile(s.filepath)
+// GetToken retrieves the stored token
+func (s *StorageTokenStore) GetToken() (*Token, error) {
+	data, err := s.store.Get(context.Background(), tokenKey)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("token file not found")
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("token not found")
 		}
 		return nil, err
 	}
@@ -49,14 +54,14 @@ func (s *FileTokenStore) GetToken() (*Token, error) {
 	return &token, nil
 }
 
-// SaveToken saves the token to the file
-func (s *FileTokenStore) SaveToken(token *Token) error {
+// SaveToken stores the token
+func (s *StorageTokenStore) SaveToken(token *Token) error {
 	data, err := json.MarshalIndent(token, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(s.filepath, data, 0600)
+	return s.store.Put(context.Background(), tokenKey, data)
 }
 
 // DeviceCodeResponse represents the response from device code request
@@ -74,6 +79,7 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int    `json:"expires_in"`
 	TokenType    string `json:"token_type"`
+	CreatedAt    int64  `json:"created_at"` // Unix time on the Trakt clock
 }
 
 // GetToken retrieves the current token from the token store
@@ -129,11 +135,7 @@ func (c *Client) Authenticate(ctx context.Context) error {
 			}
 
 			// Success! Save token
-			token := &Token{
-				AccessToken:  tokenResp.AccessToken,
-				RefreshToken: tokenResp.RefreshToken,
-				ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
-			}
+			token := c.tokenFromResponse(&tokenResp)
 
 			if err := c.tokenStore.SaveToken(token); err != nil {
 				return fmt.Errorf("failed to save token: %w", err)
@@ -164,11 +166,7 @@ func (c *Client) RefreshToken(ctx context.Context) error {
 		return fmt.Errorf("failed to refresh token: %w", err)
 	}
 
-	newToken := &Token{
-		AccessToken:  tokenResp.AccessToken,
-		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
-	}
+	newToken := c.tokenFromResponse(&tokenResp)
 
 	if err := c.tokenStore.SaveToken(newToken); err != nil {
 		return fmt.Errorf("failed to save refreshed token: %w", err)
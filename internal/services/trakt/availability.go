@@ -0,0 +1,125 @@
+package trakt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	initialPauseBackoff = 5 * time.Minute
+	maxPauseBackoff     = 6 * time.Hour
+)
+
+// ErrUnavailable is returned while Trakt requests are paused
+var ErrUnavailable = errors.New("trakt API temporarily unavailable")
+
+// APIError represents a non-2xx response from the Trakt API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Availability describes whether Trakt-dependent tasks can currently run
+type Availability struct {
+	Available           bool       `json:"available"`
+	Reason              string     `json:"reason,omitempty"`
+	PausedUntil         *time.Time `json:"paused_until,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// Availability returns the current Trakt availability state
+func (c *Client) Availability() Availability {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pausedUntil.IsZero() || time.Now().After(c.pausedUntil) {
+		return Availability{
+			Available:           true,
+			ConsecutiveFailures: c.pauseFailures,
+		}
+	}
+
+	until := c.pausedUntil
+	return Availability{
+		Available:           false,
+		Reason:              c.pauseReason,
+		PausedUntil:         &until,
+		ConsecutiveFailures: c.pauseFailures,
+	}
+}
+
+// Available returns true if Trakt requests are not paused
+func (c *Client) Available() bool {
+	return c.Availability().Available
+}
+
+// checkAvailable returns ErrUnavailable while requests are paused
+func (c *Client) checkAvailable() error {
+	availability := c.Availability()
+	if availability.Available {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (until %s)", ErrUnavailable, availability.Reason, availability.PausedUntil.Format(time.RFC3339))
+}
+
+// pauseReasonForStatus returns a reason if the status code means Trakt should be left alone for a while
+func pauseReasonForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusLocked:
+		return "account locked or over its limits (423)"
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout,
+		520, 521, 522:
+		return fmt.Sprintf("Trakt is under maintenance or unreachable (%d)", statusCode)
+	default:
+		return ""
+	}
+}
+
+// pause stops Trakt requests with exponential backoff, honoring Retry-After when provided
+func (c *Client) pause(reason string, retryAfter string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pauseFailures++
+	backoff := initialPauseBackoff << (c.pauseFailures - 1)
+	if backoff > maxPauseBackoff || backoff <= 0 {
+		backoff = maxPauseBackoff
+	}
+
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		backoff = time.Duration(seconds) * time.Second
+	}
+
+	c.pausedUntil = time.Now().Add(backoff)
+	c.pauseReason = reason
+
+	c.logger.WithFields(logrus.Fields{
+		"reason":       reason,
+		"paused_until": c.pausedUntil.Format(time.RFC3339),
+		"failures":     c.pauseFailures,
+	}).Warn("Pausing Trakt requests")
+}
+
+// resume clears the pause state after a successful request
+func (c *Client) resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pauseFailures == 0 {
+		return
+	}
+
+	c.logger.WithField("failures", c.pauseFailures).Info("Trakt API available again, resuming")
+	c.pauseFailures = 0
+	c.pausedUntil = time.Time{}
+	c.pauseReason = ""
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/amaumene/gomenarr/internal/services/httpclient"
+	"github.com/amaumene/gomenarr/internal/storage"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,26 +28,44 @@ type Client struct {
 	tokenStore   TokenStore
 	httpClient   *http.Client
 	logger       *logrus.Logger
+
+	// Pause state after account-limit or maintenance responses
+	mu            sync.Mutex
+	pausedUntil   time.Time
+	pauseReason   string
+	pauseFailures int
+
+	// Show progress reused between searches while the watch state is unchanged
+	progress progressCache
+
+	// Token expiry is checked on the Trakt clock, refreshing refreshMargin before it
+	clock         serverClock
+	refreshMargin time.Duration
 }
 
-// NewClient creates a new Trakt API client
-func NewClient(cfg *config.Config, logger *logrus.Logger) (*Client, error) {
-	tokenStore, err := NewFileTokenStore(cfg.TokenFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create token store: %w", err)
-	}
+// NewClient creates a new Trakt API client, keeping its token in a storage backend
+func NewClient(cfg *config.Config, store storage.Store, logger *logrus.Logger) (*Client, error) {
+	options := httpclient.NewOptions("trakt", cfg.RateLimits.Trakt, cfg)
+	options.Proxy = cfg.Proxies.Trakt
 
 	return &Client{
 		clientID:     cfg.TraktClientID,
 		clientSecret: cfg.TraktClientSecret,
-		tokenStore:   tokenStore,
-		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		tokenStore:   NewStorageTokenStore(store),
+		httpClient:   httpclient.NewClient(30*time.Second, options, logger),
 		logger:       logger,
+
+		refreshMargin: time.Duration(cfg.TraktTokenRefreshHours) * time.Hour,
 	}, nil
 }
 
 // doRequest performs an authenticated HTTP request to Trakt API
 func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	// Don't hit Trakt while it is locked or under maintenance
+	if err := c.checkAvailable(); err != nil {
+		return err
+	}
+
 	// Check and refresh token if needed
 	if err := c.ensureValidToken(ctx); err != nil {
 		return fmt.Errorf("failed to ensure valid token: %w", err)
@@ -87,12 +108,18 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	c.observeDate(resp.Header)
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		if reason := pauseReasonForStatus(resp.StatusCode); reason != "" {
+			c.pause(reason, resp.Header.Get("Retry-After"))
+			return fmt.Errorf("%w: %s", ErrUnavailable, reason)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
 	}
+	c.resume()
 
 	// Parse response
 	if result != nil {
@@ -112,8 +139,8 @@ func (c *Client) ensureValidToken(ctx context.Context) error {
 		return nil
 	}
 
-	// Check if token expires within 24 hours
-	if time.Until(token.ExpiresAt) < 24*time.Hour {
+	// Check if token expires within the refresh margin, on the Trakt clock
+	if token.ExpiresAt.Sub(c.serverNow()) < c.refreshMargin {
 		c.logger.Info("Token expires soon, refreshing...")
 		return c.RefreshToken(ctx)
 	}
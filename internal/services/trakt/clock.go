@@ -0,0 +1,70 @@
+package trakt
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxClockSkew is the clock difference with Trakt tolerated without a warning
+	maxClockSkew = time.Minute
+	// skewWarningInterval throttles the clock skew warnings
+	skewWarningInterval = time.Hour
+)
+
+// serverClock tracks the offset between the local clock and the Trakt clock
+type serverClock struct {
+	skew     time.Duration // Trakt time minus local time, from the last response Date header
+	warnedAt time.Time
+}
+
+// observeDate records the clock skew given by the Date header of a Trakt response
+// Warns when the local clock is off by more than maxClockSkew.
+func (c *Client) observeDate(header http.Header) {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// Date has a second resolution, don't count the truncation as skew
+	skew := date.Sub(time.Now().Truncate(time.Second))
+
+	c.mu.Lock()
+	c.clock.skew = skew
+	warn := (skew > maxClockSkew || skew < -maxClockSkew) && time.Since(c.clock.warnedAt) > skewWarningInterval
+	if warn {
+		c.clock.warnedAt = time.Now()
+	}
+	c.mu.Unlock()
+
+	if warn {
+		c.logger.WithFields(logrus.Fields{
+			"skew":       skew.Round(time.Second).String(),
+			"trakt_time": date.UTC().Format(time.RFC3339),
+			"local_time": time.Now().UTC().Format(time.RFC3339),
+		}).Warn("Local clock differs from the Trakt clock, token expiry is checked on the Trakt clock")
+	}
+}
+
+// serverNow returns the current time on the Trakt clock, the local time until a response was seen
+func (c *Client) serverNow() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Add(c.clock.skew)
+}
+
+// tokenFromResponse builds a token from a token response
+// The expiry is created_at + expires_in, both on the Trakt clock. Responses without
+// created_at are dated with the Trakt time estimated from the last Date header.
+func (c *Client) tokenFromResponse(resp *TokenResponse) *Token {
+	createdAt := c.serverNow()
+	if resp.CreatedAt > 0 {
+		createdAt = time.Unix(resp.CreatedAt, 0)
+	}
+	return &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    createdAt.Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+}
@@ -0,0 +1,194 @@
+package trakt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CollectionItem is a movie, or episodes of a show, added to or removed from the collection
+type CollectionItem struct {
+	IMDBId      string
+	Show        bool
+	Season      *int  // Season of the episodes of a show, nil for movies and whole shows
+	Episodes    []int // Episodes of the season, empty for the whole season
+	CollectedAt time.Time
+
+	// Metadata shown by Trakt, empty when unknown
+	MediaType  string // digital, bluray or dvd
+	Resolution string // uhd_4k, hd_1080p, hd_720p, sd_576p or sd_480p
+	HDR        string // dolby_vision, hdr10, hdr10_plus or hlg
+}
+
+// collectionMetadata is the metadata of a collected movie or episode
+type collectionMetadata struct {
+	CollectedAt string `json:"collected_at,omitempty"`
+	MediaType   string `json:"media_type,omitempty"`
+	Resolution  string `json:"resolution,omitempty"`
+	HDR         string `json:"hdr,omitempty"`
+}
+
+type collectionEpisode struct {
+	Number int `json:"number"`
+	collectionMetadata
+}
+
+type collectionSeason struct {
+	Number   int                 `json:"number"`
+	Episodes []collectionEpisode `json:"episodes,omitempty"`
+	collectionMetadata
+}
+
+type collectionEntry struct {
+	IDs struct {
+		IMDB string `json:"imdb"`
+	} `json:"ids"`
+	Seasons []collectionSeason `json:"seasons,omitempty"`
+	collectionMetadata
+}
+
+// collectionBody is the body of the collection add and remove endpoints
+type collectionBody struct {
+	Movies []collectionEntry `json:"movies,omitempty"`
+	Shows  []collectionEntry `json:"shows,omitempty"`
+}
+
+// collectionCounts are the movies and episodes an endpoint changed
+type collectionCounts struct {
+	Movies   int `json:"movies"`
+	Episodes int `json:"episodes"`
+}
+
+// AddToCollection adds movies and episodes to the collection, with their metadata
+// Items already collected get their metadata updated.
+func (c *Client) AddToCollection(ctx context.Context, items []CollectionItem) error {
+	var response struct {
+		Added   collectionCounts `json:"added"`
+		Updated collectionCounts `json:"updated"`
+	}
+	if err := c.doRequest(ctx, "POST", "/sync/collection", newCollectionBody(items, true), &response); err != nil {
+		return fmt.Errorf("failed to add to collection: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"movies":   response.Added.Movies + response.Updated.Movies,
+		"episodes": response.Added.Episodes + response.Updated.Episodes,
+	}).Debug("Added to Trakt collection")
+
+	return nil
+}
+
+// RemoveFromCollection removes movies and episodes from the collection
+func (c *Client) RemoveFromCollection(ctx context.Context, items []CollectionItem) error {
+	var response struct {
+		Deleted collectionCounts `json:"deleted"`
+	}
+	if err := c.doRequest(ctx, "POST", "/sync/collection/remove", newCollectionBody(items, false), &response); err != nil {
+		return fmt.Errorf("failed to remove from collection: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"movies":   response.Deleted.Movies,
+		"episodes": response.Deleted.Episodes,
+	}).Debug("Removed from Trakt collection")
+
+	return nil
+}
+
+// newCollectionBody builds the request body of collection items, with their metadata or not
+func newCollectionBody(items []CollectionItem, withMetadata bool) collectionBody {
+	var body collectionBody
+	for _, item := range items {
+		var metadata collectionMetadata
+		if withMetadata {
+			metadata = collectionMetadata{
+				MediaType:  item.MediaType,
+				Resolution: item.Resolution,
+				HDR:        item.HDR,
+			}
+			if !item.CollectedAt.IsZero() {
+				metadata.CollectedAt = item.CollectedAt.UTC().Format(time.RFC3339)
+			}
+		}
+
+		var entry collectionEntry
+		entry.IDs.IMDB = item.IMDBId
+		if !item.Show {
+			entry.collectionMetadata = metadata
+			body.Movies = append(body.Movies, entry)
+			continue
+		}
+
+		switch {
+		case item.Season == nil:
+			entry.collectionMetadata = metadata
+		case len(item.Episodes) == 0:
+			entry.Seasons = []collectionSeason{{Number: *item.Season, collectionMetadata: metadata}}
+		default:
+			season := collectionSeason{Number: *item.Season}
+			for _, episode := range item.Episodes {
+				season.Episodes = append(season.Episodes, collectionEpisode{Number: episode, collectionMetadata: metadata})
+			}
+			entry.Seasons = []collectionSeason{season}
+		}
+		body.Shows = append(body.Shows, entry)
+	}
+	return body
+}
+
+// CollectedItem is a movie (season and episode 0) or an episode of the collection
+type CollectedItem struct {
+	IMDBId      string
+	Season      int
+	Episode     int
+	CollectedAt time.Time
+}
+
+// GetCollection retrieves the collected movies ("movies") or episodes ("shows")
+func (c *Client) GetCollection(ctx context.Context, mediaType string) ([]CollectedItem, error) {
+	var entries []struct {
+		CollectedAt time.Time `json:"collected_at"`
+		Movie       *struct {
+			IDs struct {
+				IMDB string `json:"imdb"`
+			} `json:"ids"`
+		} `json:"movie,omitempty"`
+		Show *struct {
+			IDs struct {
+				IMDB string `json:"imdb"`
+			} `json:"ids"`
+		} `json:"show,omitempty"`
+		Seasons []struct {
+			Number   int `json:"number"`
+			Episodes []struct {
+				Number      int       `json:"number"`
+				CollectedAt time.Time `json:"collected_at"`
+			} `json:"episodes"`
+		} `json:"seasons,omitempty"`
+	}
+	if err := c.doRequest(ctx, "GET", "/sync/collection/"+mediaType, nil, &entries); err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	var items []CollectedItem
+	for _, entry := range entries {
+		switch {
+		case entry.Movie != nil && entry.Movie.IDs.IMDB != "":
+			items = append(items, CollectedItem{IMDBId: entry.Movie.IDs.IMDB, CollectedAt: entry.CollectedAt})
+		case entry.Show != nil && entry.Show.IDs.IMDB != "":
+			for _, season := range entry.Seasons {
+				for _, episode := range season.Episodes {
+					items = append(items, CollectedItem{
+						IMDBId:      entry.Show.IDs.IMDB,
+						Season:      season.Number,
+						Episode:     episode.Number,
+						CollectedAt: episode.CollectedAt,
+					})
+				}
+			}
+		}
+	}
+	return items, nil
+}
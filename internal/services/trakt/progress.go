@@ -0,0 +1,124 @@
+package trakt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// watchedShowsMaxAge bounds how long a watched shows batch vouches for cached progress
+const watchedShowsMaxAge = 15 * time.Minute
+
+// watchState is the watch state of a show in the watched shows batch
+// Cached progress stays valid while the state it was fetched under doesn't change.
+type watchState struct {
+	lastWatchedAt time.Time
+	resetAt       time.Time
+	airedEpisodes int // Episodes aired so far, changes when a new one airs
+}
+
+// cachedProgress is the progress of a show with the watch state it was fetched under
+type cachedProgress struct {
+	progress *ShowProgress
+	state    watchState
+}
+
+// progressCache keeps show progress between searches, validated by the watched shows batch
+type progressCache struct {
+	mu        sync.Mutex
+	states    map[string]watchState // By IMDB ID, from the last batch
+	fetchedAt time.Time
+	shows     map[string]cachedProgress
+}
+
+// RefreshWatchedShows fetches the watch state of every watched show in a single call
+// Cached progress of shows whose state is unchanged is then reused by GetShowProgress
+// instead of one progress call per show. Returns the number of watched shows.
+func (c *Client) RefreshWatchedShows(ctx context.Context) (int, error) {
+	var shows []struct {
+		LastWatchedAt *time.Time `json:"last_watched_at"`
+		ResetAt       *time.Time `json:"reset_at"`
+		Show          struct {
+			AiredEpisodes int `json:"aired_episodes"`
+			IDs           struct {
+				IMDB string `json:"imdb"`
+			} `json:"ids"`
+		} `json:"show"`
+	}
+	if err := c.doRequest(ctx, "GET", "/sync/watched/shows?extended=noseasons,full", nil, &shows); err != nil {
+		return 0, fmt.Errorf("failed to get watched shows: %w", err)
+	}
+
+	states := make(map[string]watchState, len(shows))
+	for _, show := range shows {
+		if show.Show.IDs.IMDB == "" {
+			continue
+		}
+		state := watchState{airedEpisodes: show.Show.AiredEpisodes}
+		if show.LastWatchedAt != nil {
+			state.lastWatchedAt = *show.LastWatchedAt
+		}
+		if show.ResetAt != nil {
+			state.resetAt = *show.ResetAt
+		}
+		states[show.Show.IDs.IMDB] = state
+	}
+
+	c.progress.mu.Lock()
+	defer c.progress.mu.Unlock()
+	c.progress.states = states
+	c.progress.fetchedAt = time.Now()
+
+	// Drop the progress of shows no longer watched or whose state changed
+	for imdbID, cached := range c.progress.shows {
+		if state, ok := states[imdbID]; !ok || state != cached.state {
+			delete(c.progress.shows, imdbID)
+		}
+	}
+
+	return len(states), nil
+}
+
+// cachedShowProgress returns the cached progress of a show if the latest watched shows
+// batch shows its watch state unchanged, nil when it has to be fetched
+func (c *Client) cachedShowProgress(imdbID string) *ShowProgress {
+	c.progress.mu.Lock()
+	defer c.progress.mu.Unlock()
+
+	if time.Since(c.progress.fetchedAt) > watchedShowsMaxAge {
+		return nil
+	}
+	cached, ok := c.progress.shows[imdbID]
+	if !ok || c.progress.states[imdbID] != cached.state {
+		return nil
+	}
+	return cached.progress.clone()
+}
+
+// storeShowProgress caches the progress of a show present in the latest watched shows batch
+// Shows never watched aren't part of the batch and are always fetched.
+func (c *Client) storeShowProgress(imdbID string, progress *ShowProgress) {
+	c.progress.mu.Lock()
+	defer c.progress.mu.Unlock()
+
+	if time.Since(c.progress.fetchedAt) > watchedShowsMaxAge {
+		return
+	}
+	state, ok := c.progress.states[imdbID]
+	if !ok {
+		return
+	}
+	if c.progress.shows == nil {
+		c.progress.shows = make(map[string]cachedProgress)
+	}
+	c.progress.shows[imdbID] = cachedProgress{progress: progress.clone(), state: state}
+}
+
+// clone copies a progress so callers filtering its episodes don't alter the cache
+func (p *ShowProgress) clone() *ShowProgress {
+	clone := *p
+	clone.UnwatchedEpisodes = append([]Episode{}, p.UnwatchedEpisodes...)
+	clone.AiredEpisodes = append([]Episode(nil), p.AiredEpisodes...)
+	return &clone
+}
@@ -3,12 +3,17 @@ package trakt
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // TraktMedia represents a media item from Trakt API
 type TraktMedia struct {
 	Type  string // "movie" or "show"
+	Notes string `json:"notes"` // Free-form list item notes (may contain directives)
 	Movie *struct {
 		Title string `json:"title"`
 		Year  int    `json:"year"`
@@ -49,6 +54,63 @@ func (c *Client) GetWatchlist(ctx context.Context, mediaType string) ([]TraktMed
 	return items, nil
 }
 
+// GetListItems retrieves the movies or shows of a custom Trakt list
+// listPath is the list API path, e.g. "users/justin/lists/imdb-top-250" or "lists/2142753"
+func (c *Client) GetListItems(ctx context.Context, listPath string, mediaType string) ([]TraktMedia, error) {
+	path := fmt.Sprintf("/%s/items/%s", strings.Trim(listPath, "/"), mediaType)
+
+	var items []TraktMedia
+	if err := c.doRequest(ctx, "GET", path, nil, &items); err != nil {
+		return nil, fmt.Errorf("failed to get list items: %w", err)
+	}
+
+	return items, nil
+}
+
+// RemoveFromWatchlist removes a movie from the Trakt watchlist
+func (c *Client) RemoveFromWatchlist(ctx context.Context, imdbID string) error {
+	type ids struct {
+		IMDB string `json:"imdb"`
+	}
+	type movie struct {
+		IDs ids `json:"ids"`
+	}
+	body := struct {
+		Movies []movie `json:"movies"`
+	}{
+		Movies: []movie{{IDs: ids{IMDB: imdbID}}},
+	}
+
+	var response struct {
+		Deleted struct {
+			Movies int `json:"movies"`
+		} `json:"deleted"`
+	}
+	if err := c.doRequest(ctx, "POST", "/sync/watchlist/remove", body, &response); err != nil {
+		return fmt.Errorf("failed to remove from watchlist: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"imdb_id": imdbID,
+		"deleted": response.Deleted.Movies,
+	}).Debug("Removed movie from Trakt watchlist")
+
+	return nil
+}
+
+// LookupMedia finds movies and shows by external ID
+// idType is "imdb" (e.g. "tt0133093") or "trakt" (numeric Trakt ID)
+func (c *Client) LookupMedia(ctx context.Context, idType string, id string) ([]TraktMedia, error) {
+	path := fmt.Sprintf("/search/%s/%s?type=movie,show", idType, url.PathEscape(id))
+
+	var items []TraktMedia
+	if err := c.doRequest(ctx, "GET", path, nil, &items); err != nil {
+		return nil, fmt.Errorf("failed to look up %s %s: %w", idType, id, err)
+	}
+
+	return items, nil
+}
+
 // WatchedItem represents a watched item from Trakt history
 type WatchedItem struct {
 	IMDBId    string
@@ -143,6 +205,95 @@ func (c *Client) GetSeasonInfo(ctx context.Context, imdbID string, season int) (
 	}, nil
 }
 
+// SeasonSummary represents the airing state of a season
+type SeasonSummary struct {
+	Number        int `json:"number"`
+	EpisodeCount  int `json:"episode_count"`
+	AiredEpisodes int `json:"aired_episodes"`
+}
+
+// Finished checks if every episode of the season has aired
+func (s SeasonSummary) Finished() bool {
+	return s.EpisodeCount > 0 && s.AiredEpisodes >= s.EpisodeCount
+}
+
+// GetSeasons retrieves the airing state of every season of a show, specials included
+func (c *Client) GetSeasons(ctx context.Context, imdbID string) ([]SeasonSummary, error) {
+	traktID, err := c.lookupTraktIDFromIMDB(ctx, imdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/shows/%d/seasons?extended=full", traktID)
+
+	var seasons []SeasonSummary
+	if err := c.doRequest(ctx, "GET", path, nil, &seasons); err != nil {
+		return nil, fmt.Errorf("failed to get seasons: %w", err)
+	}
+
+	return seasons, nil
+}
+
+// GetEpisodeAirTimes retrieves the air time of every episode of a season
+// Trakt air times are UTC timestamps, episodes without one yet are left out.
+func (c *Client) GetEpisodeAirTimes(ctx context.Context, imdbID string, season int) (map[Episode]time.Time, error) {
+	traktID, err := c.lookupTraktIDFromIMDB(ctx, imdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/shows/%d/seasons/%d?extended=full", traktID, season)
+
+	var episodes []struct {
+		Season     int        `json:"season"`
+		Number     int        `json:"number"`
+		FirstAired *time.Time `json:"first_aired"`
+	}
+	if err := c.doRequest(ctx, "GET", path, nil, &episodes); err != nil {
+		return nil, fmt.Errorf("failed to get episode air times: %w", err)
+	}
+
+	airTimes := make(map[Episode]time.Time, len(episodes))
+	for _, ep := range episodes {
+		if ep.FirstAired != nil {
+			airTimes[Episode{Season: ep.Season, Episode: ep.Number}] = *ep.FirstAired
+		}
+	}
+	return airTimes, nil
+}
+
+// GetMovieReleaseDate retrieves the date a movie became available at home: its earliest
+// digital release, else its earliest physical release, else its earliest release of any kind
+// Returns nil when Trakt has no release date for the movie.
+func (c *Client) GetMovieReleaseDate(ctx context.Context, imdbID string) (*time.Time, error) {
+	var releases []struct {
+		ReleaseDate string `json:"release_date"`
+		ReleaseType string `json:"release_type"`
+	}
+	if err := c.doRequest(ctx, "GET", fmt.Sprintf("/movies/%s/releases", url.PathEscape(imdbID)), nil, &releases); err != nil {
+		return nil, fmt.Errorf("failed to get movie releases: %w", err)
+	}
+
+	earliest := make(map[string]time.Time)
+	for _, release := range releases {
+		date, err := time.Parse("2006-01-02", release.ReleaseDate)
+		if err != nil {
+			continue
+		}
+		for _, kind := range []string{release.ReleaseType, ""} {
+			if current, ok := earliest[kind]; !ok || date.Before(current) {
+				earliest[kind] = date
+			}
+		}
+	}
+	for _, kind := range []string{"digital", "physical", ""} {
+		if date, ok := earliest[kind]; ok {
+			return &date, nil
+		}
+	}
+	return nil, nil
+}
+
 // Episode represents an episode reference
 type Episode struct {
 	Season  int
@@ -153,6 +304,9 @@ type Episode struct {
 type ShowProgress struct {
 	NextEpisode       *Episode
 	UnwatchedEpisodes []Episode
+	AiredEpisodes     []Episode  // Every aired episode, specials excluded
+	LastEpisode       *Episode   // Most recently aired episode
+	LastAiredAt       *time.Time // Air time of LastEpisode
 }
 
 // lookupTraktIDFromIMDB looks up the Trakt ID for a show using its IMDB ID
@@ -180,20 +334,30 @@ func (c *Client) lookupTraktIDFromIMDB(ctx context.Context, imdbID string) (int,
 }
 
 // GetShowProgress retrieves the watch progress for a TV show
+// The progress cached since the last RefreshWatchedShows is returned when still valid.
 func (c *Client) GetShowProgress(ctx context.Context, imdbID string) (*ShowProgress, error) {
+	if progress := c.cachedShowProgress(imdbID); progress != nil {
+		return progress, nil
+	}
+
 	// First, look up the Trakt ID from the IMDB ID
 	traktID, err := c.lookupTraktIDFromIMDB(ctx, imdbID)
 	if err != nil {
 		return nil, err
 	}
 
-	path := fmt.Sprintf("/shows/%d/progress/watched", traktID)
+	path := fmt.Sprintf("/shows/%d/progress/watched?extended=full", traktID)
 
 	var progress struct {
 		NextEpisode *struct {
 			Season int `json:"season"`
 			Number int `json:"number"`
 		} `json:"next_episode"`
+		LastEpisode *struct {
+			Season     int        `json:"season"`
+			Number     int        `json:"number"`
+			FirstAired *time.Time `json:"first_aired"`
+		} `json:"last_episode"`
 		Seasons []struct {
 			Number   int `json:"number"`
 			Episodes []struct {
@@ -219,9 +383,21 @@ func (c *Client) GetShowProgress(ctx context.Context, imdbID string) (*ShowProgr
 		}
 	}
 
-	// Collect unwatched episodes
+	if progress.LastEpisode != nil {
+		result.LastEpisode = &Episode{
+			Season:  progress.LastEpisode.Season,
+			Episode: progress.LastEpisode.Number,
+		}
+		result.LastAiredAt = progress.LastEpisode.FirstAired
+	}
+
+	// Collect aired and unwatched episodes
 	for _, season := range progress.Seasons {
 		for _, ep := range season.Episodes {
+			result.AiredEpisodes = append(result.AiredEpisodes, Episode{
+				Season:  season.Number,
+				Episode: ep.Number,
+			})
 			if !ep.Completed {
 				result.UnwatchedEpisodes = append(result.UnwatchedEpisodes, Episode{
 					Season:  season.Number,
@@ -231,5 +407,6 @@ func (c *Client) GetShowProgress(ctx context.Context, imdbID string) (*ShowProgr
 		}
 	}
 
+	c.storeShowProgress(imdbID, result)
 	return result, nil
 }
@@ -0,0 +1,60 @@
+package trakt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// languageCountries maps a metadata language to the country of its aliases, when the
+// title has no translation in that language
+var languageCountries = map[string]string{
+	"en": "us",
+	"fr": "fr",
+	"de": "de",
+	"es": "es",
+	"it": "it",
+	"nl": "nl",
+	"pt": "br",
+	"ja": "jp",
+}
+
+// titleEntry is an item of the translations and aliases endpoints
+type titleEntry struct {
+	Title    string `json:"title"`
+	Language string `json:"language"`
+	Country  string `json:"country"`
+}
+
+// GetTitle retrieves the title of a movie or show in a language, from its Trakt translations
+// then its aliases in the main country of the language. Returns "" if Trakt has none.
+// mediaType is "movies" or "shows".
+func (c *Client) GetTitle(ctx context.Context, mediaType string, imdbID string, language string) (string, error) {
+	id := url.PathEscape(imdbID)
+
+	var translations []titleEntry
+	path := fmt.Sprintf("/%s/%s/translations/%s", mediaType, id, url.PathEscape(language))
+	if err := c.doRequest(ctx, "GET", path, nil, &translations); err != nil {
+		return "", fmt.Errorf("failed to get translations: %w", err)
+	}
+	for _, translation := range translations {
+		if translation.Title != "" {
+			return translation.Title, nil
+		}
+	}
+
+	country, ok := languageCountries[language]
+	if !ok {
+		return "", nil
+	}
+	var aliases []titleEntry
+	if err := c.doRequest(ctx, "GET", fmt.Sprintf("/%s/%s/aliases", mediaType, id), nil, &aliases); err != nil {
+		return "", fmt.Errorf("failed to get aliases: %w", err)
+	}
+	for _, alias := range aliases {
+		if alias.Country == country && alias.Title != "" {
+			return alias.Title, nil
+		}
+	}
+	return "", nil
+}
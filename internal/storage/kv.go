@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EtcdStore keeps each key in etcd through its v3 JSON gateway
+type EtcdStore struct {
+	client  *http.Client
+	options Options
+}
+
+// NewEtcdStore creates a store writing to etcd
+func NewEtcdStore(client *http.Client, options Options) *EtcdStore {
+	options.URL = strings.TrimSuffix(options.URL, "/")
+	return &EtcdStore{client: client, options: options}
+}
+
+// Get reads a key
+func (s *EtcdStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var response struct {
+		KVs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := s.call(ctx, "/v3/kv/range", map[string]string{"key": s.encodedKey(key)}, &response); err != nil {
+		return nil, err
+	}
+	if len(response.KVs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	data, err := base64.StdEncoding.DecodeString(response.KVs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid etcd value: %w", err)
+	}
+	return data, nil
+}
+
+// Put writes a key
+func (s *EtcdStore) Put(ctx context.Context, key string, data []byte) error {
+	request := map[string]string{
+		"key":   s.encodedKey(key),
+		"value": base64.StdEncoding.EncodeToString(data),
+	}
+	return s.call(ctx, "/v3/kv/put", request, nil)
+}
+
+// encodedKey returns the prefixed key, base64-encoded as the gateway expects
+func (s *EtcdStore) encodedKey(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s.options.Prefix + key))
+}
+
+// call posts a request to the gateway, authenticating first when credentials are set
+func (s *EtcdStore) call(ctx context.Context, path string, request interface{}, response interface{}) error {
+	token := ""
+	if s.options.Username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		credentials := map[string]string{"name": s.options.Username, "password": s.options.Password}
+		if err := s.post(ctx, "/v3/auth/authenticate", "", credentials, &auth); err != nil {
+			return fmt.Errorf("etcd authentication failed: %w", err)
+		}
+		token = auth.Token
+	}
+	return s.post(ctx, path, token, request, response)
+}
+
+// post sends a JSON request to the gateway
+func (s *EtcdStore) post(ctx context.Context, path, token string, request interface{}, response interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.options.URL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("etcd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("etcd %s returned status %d: %s", path, resp.StatusCode, body)
+	}
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+	return nil
+}
+
+// ConsulStore keeps each key in the Consul KV store
+type ConsulStore struct {
+	client  *http.Client
+	options Options
+}
+
+// NewConsulStore creates a store writing to Consul
+func NewConsulStore(client *http.Client, options Options) *ConsulStore {
+	options.URL = strings.TrimSuffix(options.URL, "/")
+	return &ConsulStore{client: client, options: options}
+}
+
+// Get reads a key
+func (s *ConsulStore) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consul value: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("consul get %s returned status %d: %s", key, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// Put writes a key
+func (s *ConsulStore) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("consul put %s returned status %d: %s", key, resp.StatusCode, body)
+	}
+	return nil
+}
+
+// do sends a request for a key, raw values are read and written as is
+func (s *ConsulStore) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	keyURL := s.options.URL + "/v1/kv/" + escapeKey(s.options.Prefix+key)
+	if method == http.MethodGet {
+		keyURL += "?raw"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, keyURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.options.Token != "" {
+		req.Header.Set("X-Consul-Token", s.options.Token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consul request failed: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Backends
+const (
+	BackendFile   = "file"
+	BackendS3     = "s3"
+	BackendEtcd   = "etcd"
+	BackendConsul = "consul"
+)
+
+// requestTimeout bounds the requests to remote backends
+const requestTimeout = 30 * time.Second
+
+// ErrNotFound is returned when a key was never stored
+var ErrNotFound = errors.New("key not found")
+
+// Store keeps the small files gomenarr persists outside its database (the Trakt
+// token, the blacklist), so deployments without a persistent volume can keep them
+// in a bucket or a key-value store
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// Options selects and configures a backend
+type Options struct {
+	Backend string // file (default), s3, etcd or consul
+	Dir     string // Directory of the file backend
+	URL     string // Endpoint of the remote backend
+	Prefix  string // Prepended to the keys of remote backends
+
+	// S3-compatible bucket
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+
+	// etcd authentication
+	Username string
+	Password string
+
+	// Consul ACL token
+	Token string
+}
+
+// New creates the store of the configured backend
+func New(options Options) (Store, error) {
+	client := &http.Client{Timeout: requestTimeout}
+
+	switch options.Backend {
+	case "", BackendFile:
+		return NewFileStore(options.Dir), nil
+	case BackendS3:
+		if options.URL == "" || options.Bucket == "" {
+			return nil, fmt.Errorf("the s3 storage backend requires a URL and a bucket")
+		}
+		return NewS3Store(client, options), nil
+	case BackendEtcd:
+		if options.URL == "" {
+			return nil, fmt.Errorf("the etcd storage backend requires a URL")
+		}
+		return NewEtcdStore(client, options), nil
+	case BackendConsul:
+		if options.URL == "" {
+			return nil, fmt.Errorf("the consul storage backend requires a URL")
+		}
+		return NewConsulStore(client, options), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (file, s3, etcd or consul)", options.Backend)
+	}
+}
+
+// escapeKey percent-encodes a key for a URL path, keeping the slashes between its segments
+// Only unreserved characters are left as is, as AWS Signature V4 requires.
+func escapeKey(key string) string {
+	var b strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryServer is a fake S3/Consul endpoint keeping objects by path
+func memoryServer(check func(r *http.Request)) *httptest.Server {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+}
+
+func roundTrip(t *testing.T, store Store) {
+	ctx := context.Background()
+	if _, err := store.Get(ctx, "token.json"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if err := store.Put(ctx, "token.json", []byte(`{"access_token":"abc"}`)); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	data, err := store.Get(ctx, "token.json")
+	if err != nil || string(data) != `{"access_token":"abc"}` {
+		t.Fatalf("Expected stored token, got %q (%v)", data, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	roundTrip(t, NewFileStore(t.TempDir()))
+}
+
+func TestS3Store(t *testing.T) {
+	server := memoryServer(func(r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/bucket/gomenarr/") {
+			t.Errorf("Unexpected object path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") {
+			t.Errorf("Unexpected authorization %q", auth)
+		}
+	})
+	defer server.Close()
+
+	store, err := New(Options{Backend: BackendS3, URL: server.URL, Bucket: "bucket", Prefix: "gomenarr/", AccessKey: "key", SecretKey: "secret"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	roundTrip(t, store)
+}
+
+func TestConsulStore(t *testing.T) {
+	server := memoryServer(func(r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "token" {
+			t.Error("Missing Consul token")
+		}
+	})
+	defer server.Close()
+
+	store, err := New(Options{Backend: BackendConsul, URL: server.URL, Prefix: "gomenarr/", Token: "token"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	roundTrip(t, store)
+}
+
+func TestEscapeKey(t *testing.T) {
+	if escaped := escapeKey("gomenarr/my list+1.txt"); escaped != "gomenarr/my%20list%2B1.txt" {
+		t.Errorf("Unexpected escaped key %s", escaped)
+	}
+}
@@ -0,0 +1,273 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize      = 2048            // Spans waiting for export, newer spans are dropped when full
+	batchSize      = 512             // Spans sent per export request
+	exportInterval = 5 * time.Second // Longest wait before queued spans are sent
+	exportTimeout  = 10 * time.Second
+)
+
+// Options configures the span export
+type Options struct {
+	Endpoint    string  // OTLP/HTTP base URL (e.g. http://tempo:4318), spans are posted to /v1/traces
+	ServiceName string  // service.name resource attribute
+	SampleRatio float64 // Share of the traces exported, from 0 to 1
+}
+
+// Tracer batches finished spans and posts them to the collector
+type Tracer struct {
+	options Options
+	url     string
+	client  *http.Client
+	logger  *logrus.Logger
+
+	queue   chan *Span
+	done    chan struct{}
+	stopped sync.WaitGroup
+	dropped atomic.Int64
+}
+
+// active is the tracer used by Start, nil while tracing is off
+var active atomic.Pointer[Tracer]
+
+// current returns the active tracer, nil while tracing is off
+func current() *Tracer {
+	return active.Load()
+}
+
+// Init starts exporting the spans to the collector and makes the tracer active
+func Init(options Options, logger *logrus.Logger) *Tracer {
+	t := &Tracer{
+		options: options,
+		url:     strings.TrimRight(options.Endpoint, "/") + "/v1/traces",
+		client:  &http.Client{Timeout: exportTimeout}, // Not traced, exports would trace themselves
+		logger:  logger,
+		queue:   make(chan *Span, queueSize),
+		done:    make(chan struct{}),
+	}
+	t.stopped.Add(1)
+	go t.run()
+	active.Store(t)
+
+	logger.WithFields(logrus.Fields{
+		"endpoint":     t.url,
+		"sample_ratio": options.SampleRatio,
+	}).Info("Tracing enabled")
+	return t
+}
+
+// Shutdown stops tracing and sends the queued spans
+// A nil Tracer is valid and does nothing.
+func (t *Tracer) Shutdown(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	active.CompareAndSwap(t, nil)
+	close(t.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		t.stopped.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.logger.Warn("Timed out sending the last spans")
+	}
+}
+
+// export queues a finished span without blocking the traced code
+func (t *Tracer) export(span *Span) {
+	select {
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// run sends the queued spans in batches until Shutdown
+func (t *Tracer) run() {
+	defer t.stopped.Done()
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.send(batch); err != nil {
+			t.logger.WithError(err).WithField("spans", len(batch)).Warn("Failed to export spans")
+		}
+		if dropped := t.dropped.Swap(0); dropped > 0 {
+			t.logger.WithField("spans", dropped).Warn("Span queue full, spans dropped")
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.done:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts a batch of spans in the OTLP/HTTP JSON encoding
+func (t *Tracer) send(batch []*Span) error {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, span.otlp())
+	}
+
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{attribute("service.name", t.options.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "gomenarr"},
+			Spans: spans,
+		}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/HTTP JSON payload, see opentelemetry-proto trace/v1
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              Kind            `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 values are strings in the JSON encoding
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 2 is error
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// otlp converts a finished span to its OTLP JSON form
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.ctx.traceID[:]),
+		SpanID:            hex.EncodeToString(s.ctx.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for key, value := range s.attrs {
+		span.Attributes = append(span.Attributes, attribute(key, value))
+	}
+	if s.err != nil {
+		span.Status = &otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+	return span
+}
+
+// attribute converts a value to an OTLP attribute, unknown types are formatted as strings
+func attribute(key string, value interface{}) otlpAttribute {
+	var v otlpValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		s := strconv.Itoa(value)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case uint64:
+		s := strconv.FormatUint(value, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
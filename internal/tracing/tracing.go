@@ -0,0 +1,175 @@
+// Package tracing records spans of the pipeline and exports them to an OTLP/HTTP collector
+// (Jaeger, Tempo, OpenTelemetry Collector), propagating the W3C traceparent header.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the OTLP kind of a span
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// traceparentHeader carries the trace context between services
+const traceparentHeader = "traceparent"
+
+// spanContext identifies a span within a trace
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+}
+
+// Span is a timed operation of a trace
+// A nil Span is valid and records nothing, it is what Start returns while tracing is off.
+type Span struct {
+	tracer   *Tracer
+	ctx      spanContext
+	parentID [8]byte
+	name     string
+	kind     Kind
+	start    time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+// spanKey is the context key of the current span context
+type spanKey struct{}
+
+// Start begins an internal span, child of the span in ctx
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+// StartKind begins a span of a kind, child of the span in ctx
+// Returns ctx unchanged and a nil span while tracing is off.
+func StartKind(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	tracer := current()
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: tracer,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(spanContext); ok {
+		span.ctx.traceID = parent.traceID
+		span.ctx.sampled = parent.sampled
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.ctx.traceID[:])
+		span.ctx.sampled = tracer.sample(span.ctx.traceID)
+	}
+	rand.Read(span.ctx.spanID[:])
+
+	return context.WithValue(ctx, spanKey{}, span.ctx), span
+}
+
+// SetAttribute adds a string, bool, int or float attribute to the span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]interface{})
+	}
+	s.attrs[key] = value
+}
+
+// RecordError marks the span as failed, nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End finishes the span and queues it for export if its trace is sampled
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.ctx.sampled {
+		s.tracer.export(s)
+	}
+}
+
+// Inject adds the traceparent header of the span in ctx to outgoing request headers
+func Inject(ctx context.Context, header http.Header) {
+	sc, ok := ctx.Value(spanKey{}).(spanContext)
+	if !ok {
+		return
+	}
+	flags := "00"
+	if sc.sampled {
+		flags = "01"
+	}
+	header.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.traceID[:]), hex.EncodeToString(sc.spanID[:]), flags))
+}
+
+// Extract returns ctx with the remote parent span of an incoming traceparent header, if valid
+func Extract(ctx context.Context, header http.Header) context.Context {
+	parts := strings.Split(header.Get(traceparentHeader), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+
+	var sc spanContext
+	if _, err := hex.Decode(sc.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(sc.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || sc.traceID == [16]byte{} || sc.spanID == [8]byte{} {
+		return ctx
+	}
+	sc.sampled = flags[0]&1 == 1
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// sample decides whether a new trace is exported, from its random ID so the decision is stable
+func (t *Tracer) sample(traceID [16]byte) bool {
+	if t.options.SampleRatio >= 1 {
+		return true
+	}
+	var n uint64
+	for _, b := range traceID[8:] {
+		n = n<<8 | uint64(b)
+	}
+	return float64(n) < t.options.SampleRatio*math.MaxUint64
+}
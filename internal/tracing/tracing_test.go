@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	incoming := http.Header{}
+	incoming.Set(traceparentHeader, traceparent)
+	ctx := Extract(context.Background(), incoming)
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	if got := outgoing.Get(traceparentHeader); got != traceparent {
+		t.Errorf("traceparent = %q, want %q", got, traceparent)
+	}
+}
+
+func TestExtractIgnoresInvalidHeaders(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		header := http.Header{}
+		header.Set(traceparentHeader, value)
+
+		outgoing := http.Header{}
+		Inject(Extract(context.Background(), header), outgoing)
+		if got := outgoing.Get(traceparentHeader); got != "" {
+			t.Errorf("Extract(%q) propagated %q", value, got)
+		}
+	}
+}
+
+func TestDisabledTracingIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "test")
+	if span != nil {
+		t.Fatal("span created without a tracer")
+	}
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("failed"))
+	span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	if got := header.Get(traceparentHeader); got != "" {
+		t.Errorf("traceparent = %q without a span", got)
+	}
+
+	var tracer *Tracer
+	tracer.Shutdown(context.Background())
+}
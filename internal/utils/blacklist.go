@@ -2,55 +2,504 @@ package utils
 
 import (
 	"bufio"
-	"os"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/amaumene/gomenarr/internal/models"
+	"github.com/amaumene/gomenarr/internal/storage"
+	"github.com/sirupsen/logrus"
 )
 
-// Blacklist holds blacklist terms for filtering NZB results
-type Blacklist struct {
-	terms []string
+// blacklistFetchTimeout bounds the download of a subscribed blacklist
+const blacklistFetchTimeout = 30 * time.Second
+
+// BlacklistKey is the storage key of the local entries
+const BlacklistKey = "blacklist.txt"
+
+// Blacklist scopes, any other scope is the IMDB ID of a show
+const (
+	BlacklistScopeGlobal = ""       // Every release
+	BlacklistScopeMovies = "movies" // Movie releases only
+	BlacklistScopeShows  = "shows"  // TV releases only
+)
+
+// BlacklistEntry is a blacklisted term or regular expression, matched case-insensitively
+// In the line format a regular expression is written re:pattern and a scope other than
+// global is a bracketed prefix, e.g. "[movies] re:\bcam(rip)?\b" or "[tt0903747] fakegroup".
+// Show entries can be required instead, written with a + prefix: "[tt0903747] +AMZN"
+// blacklists the releases of the show without AMZN in their title.
+type BlacklistEntry struct {
+	Term     string `json:"term"`
+	Regex    bool   `json:"regex"`
+	Scope    string `json:"scope"`    // "", "movies", "shows" or the IMDB ID of a show
+	Required bool   `json:"required"` // Releases not matching the entry are blacklisted
+
+	re *regexp.Regexp
 }
 
-// LoadBlacklist loads blacklist terms from a file
-func LoadBlacklist(path string) (*Blacklist, error) {
-	// If file doesn't exist, return empty blacklist
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return &Blacklist{terms: []string{}}, nil
+// NewBlacklistEntry validates a term or pattern and its scope
+func NewBlacklistEntry(term string, regex bool, scope string) (BlacklistEntry, error) {
+	entry := BlacklistEntry{Term: strings.TrimSpace(term), Regex: regex, Scope: strings.ToLower(strings.TrimSpace(scope))}
+	if entry.Term == "" {
+		return entry, fmt.Errorf("empty blacklist term")
+	}
+
+	if !isBlacklistScope(entry.Scope) {
+		return entry, fmt.Errorf("invalid blacklist scope %q: expected movies, shows or a show IMDB ID", entry.Scope)
 	}
 
-	file, err := os.Open(path)
+	if entry.Regex {
+		re, err := regexp.Compile("(?i)" + entry.Term)
+		if err != nil {
+			return entry, fmt.Errorf("invalid blacklist pattern %q: %w", entry.Term, err)
+		}
+		entry.re = re
+	}
+	return entry, nil
+}
+
+// NewRequiredEntry validates a term or pattern required in the release titles of a show
+func NewRequiredEntry(term string, regex bool, scope string) (BlacklistEntry, error) {
+	entry, err := NewBlacklistEntry(term, regex, scope)
 	if err != nil {
+		return entry, err
+	}
+	if !isIMDBID(entry.Scope) {
+		return entry, fmt.Errorf("required term %q needs a show IMDB ID scope", entry.Term)
+	}
+	entry.Required = true
+	return entry, nil
+}
+
+// ParseBlacklistEntry reads an entry in the line format
+func ParseBlacklistEntry(line string) (BlacklistEntry, error) {
+	line = strings.TrimSpace(line)
+
+	// Bracketed terms that aren't a scope (e.g. "[rartv]") stay terms
+	scope := ""
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 0 && isBlacklistScope(line[1:end]) {
+			scope = line[1:end]
+			line = strings.TrimSpace(line[end+1:])
+		}
+	}
+
+	// A leading + only marks a required term in a show scope, elsewhere it is part of the term
+	newEntry := NewBlacklistEntry
+	if term, ok := strings.CutPrefix(line, "+"); ok && isIMDBID(scope) {
+		newEntry = NewRequiredEntry
+		line = strings.TrimSpace(term)
+	}
+
+	if pattern, ok := strings.CutPrefix(line, "re:"); ok {
+		return newEntry(pattern, true, scope)
+	}
+	return newEntry(line, false, scope)
+}
+
+// String renders the entry in the line format
+func (e BlacklistEntry) String() string {
+	line := e.Term
+	if e.Regex {
+		line = "re:" + line
+	}
+	if e.Required {
+		line = "+" + line
+	}
+	if e.Scope != BlacklistScopeGlobal {
+		line = "[" + e.Scope + "] " + line
+	}
+	return line
+}
+
+// Matches checks if a release title of a media item is blacklisted by the entry
+// A nil media only matches global entries. Required entries blacklist the titles they
+// don't match.
+func (e BlacklistEntry) Matches(title string, media *models.Media) bool {
+	switch e.Scope {
+	case BlacklistScopeGlobal:
+	case BlacklistScopeMovies:
+		if media == nil || media.MediaType != models.MediaTypeMovie {
+			return false
+		}
+	case BlacklistScopeShows:
+		if media == nil || media.MediaType != models.MediaTypeTV {
+			return false
+		}
+	default:
+		if media == nil || media.MediaType != models.MediaTypeTV || !strings.EqualFold(media.IMDBId, e.Scope) {
+			return false
+		}
+	}
+
+	var matches bool
+	if e.re != nil {
+		matches = e.re.MatchString(title)
+	} else {
+		matches = strings.Contains(strings.ToLower(title), strings.ToLower(e.Term))
+	}
+	return matches != e.Required
+}
+
+// isBlacklistScope checks if a scope is global, movies, shows or a show IMDB ID
+func isBlacklistScope(scope string) bool {
+	scope = strings.ToLower(scope)
+	switch scope {
+	case BlacklistScopeGlobal, BlacklistScopeMovies, BlacklistScopeShows:
+		return true
+	}
+	return isIMDBID(scope)
+}
+
+// isIMDBID checks the tt1234567 format
+func isIMDBID(id string) bool {
+	if len(id) < 3 || !strings.HasPrefix(id, "tt") {
+		return false
+	}
+	for _, r := range id[2:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Blacklist holds blacklist entries for filtering NZB results
+// Local entries are persisted to the storage backend, entries from subscribed lists are
+// kept in memory and refreshed periodically.
+type Blacklist struct {
+	mu            sync.RWMutex
+	store         storage.Store // Backend persisting the local entries, nil for in-memory only
+	key           string
+	entries       []BlacklistEntry            // Local entries
+	remote        map[string][]BlacklistEntry // Subscription URL -> entries
+	subscriptions []string
+	logger        *logrus.Logger // Reports the invalid lines skipped, nil to skip them silently
+}
+
+// LoadBlacklist loads blacklist entries from a file
+func LoadBlacklist(path string, logger *logrus.Logger) (*Blacklist, error) {
+	return LoadBlacklistFrom(storage.NewFileStore(filepath.Dir(path)), filepath.Base(path), logger)
+}
+
+// LoadBlacklistFrom loads blacklist entries from a storage backend
+// A missing key is an empty blacklist.
+func LoadBlacklistFrom(store storage.Store, key string, logger *logrus.Logger) (*Blacklist, error) {
+	b := &Blacklist{store: store, key: key, logger: logger}
+	if err := b.Reload(context.Background()); err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return b, nil
+}
+
+// Reload reads the local entries from the storage backend again, e.g. after another
+// instance sharing the backend changed them
+func (b *Blacklist) Reload(ctx context.Context) error {
+	if b.store == nil {
+		return nil
+	}
+
+	data, err := b.store.Get(ctx, b.key)
+	if errors.Is(err, storage.ErrNotFound) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := ParseBlacklist(bytes.NewReader(data), b.logger)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.entries = entries
+	b.mu.Unlock()
+	return nil
+}
 
-	var terms []string
-	scanner := bufio.NewScanner(file)
+// ParseBlacklist reads entries in the blacklist line format
+// One entry per line, blank lines and lines starting with # are ignored. Invalid lines
+// (e.g. a broken pattern) are logged and skipped, logger may be nil.
+func ParseBlacklist(r io.Reader, logger *logrus.Logger) ([]BlacklistEntry, error) {
+	entries := []BlacklistEntry{}
+	scanner := bufio.NewScanner(r)
+	line := 0
 	for scanner.Scan() {
-		term := strings.TrimSpace(scanner.Text())
-		if term != "" && !strings.HasPrefix(term, "#") {
-			terms = append(terms, term)
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		entry, err := ParseBlacklistEntry(text)
+		if err != nil {
+			if logger != nil {
+				logger.WithError(err).WithField("line", line).Warn("Skipping invalid blacklist line")
+			}
+			continue
 		}
+		entries = append(entries, entry)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
 
-	return &Blacklist{terms: terms}, nil
+	return entries, nil
 }
 
-// IsBlacklisted checks if a title matches any blacklist term
-// Returns (isBlacklisted, matchedTerm)
-func (b *Blacklist) IsBlacklisted(title string) (bool, string) {
-	titleLower := strings.ToLower(title)
+// IsBlacklisted checks if a release title of a media item matches any blacklist entry
+// Returns (isBlacklisted, matchedEntry) with the entry in the line format.
+func (b *Blacklist) IsBlacklisted(title string, media *models.Media) (bool, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	for _, term := range b.terms {
-		termLower := strings.ToLower(term)
-		if strings.Contains(titleLower, termLower) {
-			return true, term
+	for _, entry := range b.entries {
+		if entry.Matches(title, media) {
+			return true, entry.String()
+		}
+	}
+	for _, entries := range b.remote {
+		for _, entry := range entries {
+			if entry.Matches(title, media) {
+				return true, entry.String()
+			}
 		}
 	}
 
 	return false, ""
 }
+
+// Entries returns the local entries
+func (b *Blacklist) Entries() []BlacklistEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return append([]BlacklistEntry{}, b.entries...)
+}
+
+// Terms returns the local entries in the line format
+func (b *Blacklist) Terms() []string {
+	entries := b.Entries()
+	terms := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		terms = append(terms, entry.String())
+	}
+	return terms
+}
+
+// RemoteEntries returns the entries of the subscribed lists, deduplicated and sorted
+func (b *Blacklist) RemoteEntries() []BlacklistEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	entries := []BlacklistEntry{}
+	for _, list := range b.remote {
+		for _, entry := range list {
+			if key := strings.ToLower(entry.String()); !seen[key] {
+				seen[key] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].String() < entries[j].String() })
+	return entries
+}
+
+// Add adds local terms in the line format, skipping the ones already present (case-insensitive)
+// Returns the number of terms added.
+func (b *Blacklist) Add(terms ...string) (int, error) {
+	entries := make([]BlacklistEntry, 0, len(terms))
+	for _, term := range terms {
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		entry, err := ParseBlacklistEntry(term)
+		if err != nil {
+			return 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return b.AddEntries(entries...)
+}
+
+// AddEntries adds local entries, skipping the ones already present (case-insensitive)
+// Returns the number of entries added.
+func (b *Blacklist) AddEntries(entries ...BlacklistEntry) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	added := b.add(entries)
+	if added == 0 {
+		return 0, nil
+	}
+	return added, b.save()
+}
+
+// Replace replaces all local entries
+// Returns the number of distinct entries kept.
+func (b *Blacklist) Replace(entries []BlacklistEntry) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = []BlacklistEntry{}
+	return b.add(entries), b.save()
+}
+
+// Remove removes a local entry given in the line format (case-insensitive)
+// Returns false if the entry is not in the local list.
+func (b *Blacklist) Remove(term string) (bool, error) {
+	entry, err := ParseBlacklistEntry(term)
+	if err != nil {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.indexOf(entry)
+	if i < 0 {
+		return false, nil
+	}
+	b.entries = append(b.entries[:i], b.entries[i+1:]...)
+	return true, b.save()
+}
+
+// Export writes the local entries in the line format
+func (b *Blacklist) Export(w io.Writer) error {
+	for _, term := range b.Terms() {
+		if _, err := fmt.Fprintln(w, term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe sets the URLs of the remote lists merged with the local terms
+func (b *Blacklist) Subscribe(urls []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions = urls
+}
+
+// HasSubscriptions checks if remote lists are configured
+func (b *Blacklist) HasSubscriptions() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscriptions) > 0
+}
+
+// RefreshRemote downloads the subscribed lists
+// A list that fails to download or parse keeps its previous entries. Returns the number of
+// remote entries and the first error encountered.
+func (b *Blacklist) RefreshRemote(ctx context.Context) (int, error) {
+	b.mu.RLock()
+	urls := append([]string{}, b.subscriptions...)
+	b.mu.RUnlock()
+
+	client := &http.Client{Timeout: blacklistFetchTimeout}
+
+	var firstErr error
+	for _, url := range urls {
+		entries, err := fetchBlacklist(ctx, client, url, b.logger)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		if b.remote == nil {
+			b.remote = make(map[string][]BlacklistEntry)
+		}
+		b.remote[url] = entries
+		b.mu.Unlock()
+	}
+
+	return len(b.RemoteEntries()), firstErr
+}
+
+// fetchBlacklist downloads a list in the line format
+func fetchBlacklist(ctx context.Context, client *http.Client, url string, logger *logrus.Logger) ([]BlacklistEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	req.Header.Set("User-Agent", "gomenarr/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+
+	entries, err := ParseBlacklist(io.LimitReader(resp.Body, 10<<20), logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return entries, nil
+}
+
+// add appends the new entries, returning how many were added
+// Must be called with the lock held.
+func (b *Blacklist) add(entries []BlacklistEntry) int {
+	added := 0
+	for _, entry := range entries {
+		if entry.Term == "" || b.indexOf(entry) >= 0 {
+			continue
+		}
+		b.entries = append(b.entries, entry)
+		added++
+	}
+	return added
+}
+
+// indexOf returns the position of a local entry, -1 if absent
+// Must be called with the lock held.
+func (b *Blacklist) indexOf(entry BlacklistEntry) int {
+	for i, existing := range b.entries {
+		if strings.EqualFold(existing.String(), entry.String()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the local entries to the storage backend
+// Must be called with the lock held.
+func (b *Blacklist) save() error {
+	if b.store == nil {
+		return nil
+	}
+
+	lines := make([]string, 0, len(b.entries))
+	for _, entry := range b.entries {
+		lines = append(lines, entry.String())
+	}
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := b.store.Put(context.Background(), b.key, []byte(content)); err != nil {
+		return fmt.Errorf("failed to save blacklist: %w", err)
+	}
+	return nil
+}
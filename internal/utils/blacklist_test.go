@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+func TestBlacklistEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blacklist.txt")
+	blacklist, err := LoadBlacklist(path, nil)
+	if err != nil {
+		t.Fatalf("LoadBlacklist failed: %v", err)
+	}
+
+	added, err := blacklist.Add("FAKEGROUP", "cam", "fakegroup")
+	if err != nil || added != 2 {
+		t.Fatalf("Add() = %d, %v, expected 2 terms added", added, err)
+	}
+	if blocked, term := blacklist.IsBlacklisted("Movie.2024.1080p.WEB-DL-FakeGroup", nil); !blocked || term != "FAKEGROUP" {
+		t.Errorf("Expected title to match FAKEGROUP, got %v %q", blocked, term)
+	}
+
+	if removed, err := blacklist.Remove("Cam"); err != nil || !removed {
+		t.Errorf("Remove() = %v, %v, expected removal", removed, err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Blacklist file not saved: %v", err)
+	}
+	if strings.TrimSpace(string(content)) != "FAKEGROUP" {
+		t.Errorf("Unexpected blacklist file content %q", content)
+	}
+
+	reloaded, err := LoadBlacklist(path, nil)
+	if err != nil || len(reloaded.Terms()) != 1 {
+		t.Errorf("Expected 1 term after reload, got %v (%v)", reloaded.Terms(), err)
+	}
+}
+
+func TestBlacklistPatternsAndScopes(t *testing.T) {
+	entries, err := ParseBlacklist(strings.NewReader("[movies] re:\\bcam(rip)?\\b\n[tt0903747] fakegroup\n[rartv]\nre:cam(\n/x264/\n"), nil)
+	if err != nil {
+		t.Fatalf("ParseBlacklist failed: %v", err)
+	}
+	blacklist := &Blacklist{}
+	if _, err := blacklist.Replace(entries); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	movie := &models.Media{MediaType: models.MediaTypeMovie, IMDBId: "tt0111161"}
+	show := &models.Media{MediaType: models.MediaTypeTV, IMDBId: "tt0903747"}
+	otherShow := &models.Media{MediaType: models.MediaTypeTV, IMDBId: "tt0944947"}
+
+	tests := []struct {
+		title   string
+		media   *models.Media
+		blocked bool
+	}{
+		{"Movie.2024.CAMRip.x264", movie, true},
+		{"Movie.2024.Camera.1080p", movie, false},
+		{"Show.S01E01.CAMRip.x264", show, false},
+		{"Show.S01E01.1080p-FakeGroup", show, true},
+		{"Show.S01E01.1080p-FakeGroup", otherShow, false},
+		{"Show.S01E01.1080p-FakeGroup", nil, false},
+		{"Movie.2024.1080p.[rartv]", nil, true},
+	}
+	for _, tt := range tests {
+		if blocked, _ := blacklist.IsBlacklisted(tt.title, tt.media); blocked != tt.blocked {
+			t.Errorf("IsBlacklisted(%q) = %v, expected %v", tt.title, blocked, tt.blocked)
+		}
+	}
+
+	// The invalid pattern line is skipped, /x264/ stays a literal term
+	if len(entries) != 4 || entries[3].Regex || entries[3].String() != "/x264/" {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+	if _, err := ParseBlacklistEntry("re:cam("); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+	if _, err := NewBlacklistEntry("cam", false, "anime"); err == nil {
+		t.Error("Expected an invalid scope to be rejected")
+	}
+}
+
+func TestBlacklistRequiredTerms(t *testing.T) {
+	entries, err := ParseBlacklist(strings.NewReader("[tt0903747] +AMZN\n[tt0903747] REPACKED.INTERNAL\n+plus\n"), nil)
+	if err != nil {
+		t.Fatalf("ParseBlacklist failed: %v", err)
+	}
+	if !entries[0].Required || entries[0].String() != "[tt0903747] +AMZN" || entries[2].Required {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	blacklist := &Blacklist{}
+	if _, err := blacklist.Replace(entries); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	show := &models.Media{MediaType: models.MediaTypeTV, IMDBId: "tt0903747"}
+	otherShow := &models.Media{MediaType: models.MediaTypeTV, IMDBId: "tt0944947"}
+
+	tests := []struct {
+		title   string
+		media   *models.Media
+		blocked bool
+	}{
+		{"Show.S01E01.1080p.AMZN.WEB-DL", show, false},
+		{"Show.S01E01.1080p.NF.WEB-DL", show, true},
+		{"Show.S01E01.1080p.AMZN.WEB-DL.REPACKED.INTERNAL", show, true},
+		{"Show.S01E01.1080p.NF.WEB-DL", otherShow, false},
+		{"Show.S01E01.1080p.NF.WEB-DL", nil, false},
+	}
+	for _, tt := range tests {
+		if blocked, _ := blacklist.IsBlacklisted(tt.title, tt.media); blocked != tt.blocked {
+			t.Errorf("IsBlacklisted(%q) = %v, expected %v", tt.title, blocked, tt.blocked)
+		}
+	}
+
+	if _, err := NewRequiredEntry("AMZN", false, "shows"); err == nil {
+		t.Error("Expected a required term without a show scope to be rejected")
+	}
+}
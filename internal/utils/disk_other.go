@@ -0,0 +1,10 @@
+//go:build !unix
+
+package utils
+
+import "errors"
+
+// DiskUsage returns the free and total bytes of the filesystem holding a path
+func DiskUsage(path string) (free, total uint64, err error) {
+	return 0, 0, errors.New("disk usage not supported on this platform")
+}
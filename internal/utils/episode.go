@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var fileEpisodeRegex = regexp.MustCompile(`(?i)S(\d{1,2})[\._ -]?E(\d{1,3})`)
+
+// ParseFileEpisode extracts season and episode numbers from a file name
+// Matches names like: Show.S01E05.mkv, Show - S01 E05.mkv
+// Returns ok=false if no episode marker is found
+func ParseFileEpisode(name string) (season int, episode int, ok bool) {
+	matches := fileEpisodeRegex.FindStringSubmatch(name)
+	if len(matches) < 3 {
+		return 0, 0, false
+	}
+
+	season, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	episode, err = strconv.Atoi(matches[2])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return season, episode, true
+}
+
+var absoluteEpisodeRegex = regexp.MustCompile(`(?i)(?:\s-\s|[\._ ]E)(\d{1,4})(?:v\d)?(?:[\s\._\[\(]|$)`)
+
+// ParseAbsoluteEpisode extracts the absolute episode number of an anime release or file name
+// Matches names like: One Piece - 1071, [Group] Show - 05v2 (1080p).mkv, Show.E1071.1080p
+// Returns ok=false for names with a season marker (S01E05) or without an episode number
+func ParseAbsoluteEpisode(name string) (episode int, ok bool) {
+	if fileEpisodeRegex.MatchString(name) {
+		return 0, false
+	}
+
+	matches := absoluteEpisodeRegex.FindStringSubmatch(name)
+	if len(matches) < 2 {
+		return 0, false
+	}
+
+	episode, err := strconv.Atoi(matches[1])
+	if err != nil || episode == 0 {
+		return 0, false
+	}
+	return episode, true
+}
+
+var episodeRangeRegex = regexp.MustCompile(`(?i)S(\d{1,2})[\._ -]?E(\d{1,3})(?:(?:-?E\d{1,3})*-?E|-)(\d{1,3})(?:[^0-9a-z]|$)`)
+
+// ParseEpisodeRange extracts the episodes of a multi-episode release or file name
+// Matches names like: Show.S01E01E02.mkv, Show.S01E01-E03.1080p, Show - S01E01-03
+// Returns ok=false for single episodes
+func ParseEpisodeRange(name string) (season int, first int, last int, ok bool) {
+	matches := episodeRangeRegex.FindStringSubmatch(name)
+	if len(matches) < 4 {
+		return 0, 0, 0, false
+	}
+
+	season, _ = strconv.Atoi(matches[1])
+	first, _ = strconv.Atoi(matches[2])
+	last, _ = strconv.Atoi(matches[3])
+	if last <= first {
+		return 0, 0, 0, false
+	}
+	return season, first, last, true
+}
@@ -0,0 +1,53 @@
+package utils
+
+import "testing"
+
+func TestParseAbsoluteEpisode(t *testing.T) {
+	tests := []struct {
+		name    string
+		episode int
+		ok      bool
+	}{
+		{"One Piece - 1071", 1071, true},
+		{"[SubsPlease] Frieren - 05 (1080p) [A1B2C3D4].mkv", 5, true},
+		{"[Group] Show - 12v2 [720p]", 12, true},
+		{"One.Piece.E1071.1080p.WEB.H264", 1071, true},
+		{"Show.S01E05.1080p.WEB", 0, false},
+		{"[Group] Show - 01-12 (Batch) [1080p]", 0, false},
+		{"Show - 1080p", 0, false},
+		{"Movie.2023.1080p.BluRay", 0, false},
+	}
+
+	for _, tt := range tests {
+		episode, ok := ParseAbsoluteEpisode(tt.name)
+		if episode != tt.episode || ok != tt.ok {
+			t.Errorf("ParseAbsoluteEpisode(%q) = %d, %v, want %d, %v", tt.name, episode, ok, tt.episode, tt.ok)
+		}
+	}
+}
+
+func TestParseEpisodeRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		season      int
+		first, last int
+		ok          bool
+	}{
+		{"Show.S01E01E02.1080p.WEB", 1, 1, 2, true},
+		{"Show.S02E01-E03.720p.HDTV", 2, 1, 3, true},
+		{"Show - S01E09-10.mkv", 1, 9, 10, true},
+		{"Show.S01E01E02E03.mkv", 1, 1, 3, true},
+		{"Show.S01E01.1080p.WEB", 0, 0, 0, false},
+		{"Show.S01E01-720p.HDTV", 0, 0, 0, false},
+		{"Show.S01E05-10bit.x265", 0, 0, 0, false},
+		{"Show.S01E03-E01.mkv", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		season, first, last, ok := ParseEpisodeRange(tt.name)
+		if season != tt.season || first != tt.first || last != tt.last || ok != tt.ok {
+			t.Errorf("ParseEpisodeRange(%q) = %d, %d, %d, %v, want %d, %d, %d, %v",
+				tt.name, season, first, last, ok, tt.season, tt.first, tt.last, tt.ok)
+		}
+	}
+}
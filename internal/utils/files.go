@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// artifactExtensions are sidecar files media servers keep next to a video file
+var artifactExtensions = []string{".nfo", ".srt", ".sub", ".idx", ".ass", ".ssa", ".jpg", ".jpeg", ".png", ".tbn"}
+
+// LibraryRoot returns the library root containing path, or an empty string if
+// the path is outside every root (or is a root itself)
+func LibraryRoot(path string, roots []string) string {
+	cleanPath := filepath.Clean(path)
+	for _, root := range roots {
+		cleanRoot := filepath.Clean(root)
+		rel, err := filepath.Rel(cleanRoot, cleanPath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return cleanRoot
+	}
+	return ""
+}
+
+// DeleteLibraryPath deletes a media file or folder inside a library root, optionally
+// with its sidecar artifacts (nfo, subtitles, artwork), then prunes the parent
+// directories left empty up to (but excluding) the library root
+func DeleteLibraryPath(path string, roots []string, removeArtifacts bool) error {
+	root := LibraryRoot(path, roots)
+	if root == "" {
+		return fmt.Errorf("refusing to delete %s: not inside a library root", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil {
+		if info.IsDir() {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		} else {
+			if removeArtifacts {
+				if err := removeSidecarFiles(path); err != nil {
+					return err
+				}
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return PruneEmptyDirs(filepath.Dir(path), root)
+}
+
+// removeSidecarFiles deletes artifacts sharing the video file base name
+// e.g. "Show - S01E01.nfo", "Show - S01E01.en.srt", "Show - S01E01-thumb.jpg"
+func removeSidecarFiles(videoPath string) error {
+	dir := filepath.Dir(videoPath)
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == filepath.Base(videoPath) || !strings.HasPrefix(name, base) {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		for _, artifactExt := range artifactExtensions {
+			if ext == artifactExt {
+				if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// PruneEmptyDirs removes dir and its parents while they are empty, stopping at root
+func PruneEmptyDirs(dir string, root string) error {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && LibraryRoot(dir, []string{root}) != ""; dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return nil
+		}
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
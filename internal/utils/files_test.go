@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteLibraryPathPrunesSeasonFolder(t *testing.T) {
+	root := t.TempDir()
+	seasonDir := filepath.Join(root, "Show (2020)", "Season 01")
+	if err := os.MkdirAll(seasonDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	video := filepath.Join(seasonDir, "Show - S01E01.mkv")
+	for _, name := range []string{"Show - S01E01.mkv", "Show - S01E01.nfo", "Show - S01E01.en.srt"} {
+		if err := os.WriteFile(filepath.Join(seasonDir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DeleteLibraryPath(video, []string{root}, true); err != nil {
+		t.Fatalf("DeleteLibraryPath failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "Show (2020)")); !os.IsNotExist(err) {
+		t.Errorf("Expected empty show folder to be pruned, got err=%v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Errorf("Library root must be kept: %v", err)
+	}
+}
+
+func TestDeleteLibraryPathOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "movie.mkv")
+	if err := os.WriteFile(outside, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteLibraryPath(outside, []string{root}, false); err == nil {
+		t.Error("Expected deletion outside the library root to be refused")
+	}
+	if err := DeleteLibraryPath(root, []string{root}, false); err == nil {
+		t.Error("Expected deletion of the library root itself to be refused")
+	}
+}
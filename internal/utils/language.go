@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MultiLanguage is the language ReleaseLanguages reports for multi-language releases
+const MultiLanguage = "multi"
+
+// languageTags maps ISO 639-1 codes to the tags release groups use in titles
+var languageTags = map[string][]string{
+	"fr": {"FRENCH", "TRUEFRENCH", "VFF", "VFQ", "VF2"},
+	"de": {"GERMAN"},
+	"es": {"SPANISH", "CASTELLANO", "LATINO"},
+	"it": {"ITALIAN", "ITA"},
+	"nl": {"DUTCH"},
+	"pt": {"PORTUGUESE"},
+	"ja": {"JAPANESE"},
+	"ko": {"KOREAN"},
+	"ru": {"RUSSIAN"},
+}
+
+// subtitleTags maps ISO 639-1 codes to the tags of releases keeping the original audio
+// with subtitles in that language
+var subtitleTags = map[string][]string{
+	"fr": {"VOSTFR", "SUBFRENCH"},
+	"de": {"GERSUB"},
+	"it": {"SUBITA"},
+	"nl": {"NLSUBS"},
+}
+
+// multiLanguageTags mark releases carrying several audio tracks
+var multiLanguageTags = []string{"MULTI", "DUAL"}
+
+// tagRegexes caches the compiled pattern of each tag, hasTag runs for every search result
+var tagRegexes sync.Map
+
+// hasTag checks if a title contains a tag as a separate word
+func hasTag(title, tag string) bool {
+	re, ok := tagRegexes.Load(tag)
+	if !ok {
+		re, _ = tagRegexes.LoadOrStore(tag, regexp.MustCompile(`(?i)(^|[\._ \-\[\(])`+regexp.QuoteMeta(tag)+`($|[\._ \-\]\)])`))
+	}
+	return re.(*regexp.Regexp).MatchString(title)
+}
+
+// hasAnyTag checks if a title contains one of the tags
+func hasAnyTag(title string, tags []string) bool {
+	for _, tag := range tags {
+		if hasTag(title, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// taggedLanguages returns the codes of a tag map found in a title, sorted
+func taggedLanguages(title string, tags map[string][]string) []string {
+	var codes []string
+	for code, codeTags := range tags {
+		if hasAnyTag(title, codeTags) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ReleaseLanguages returns the audio languages tagged in a release title
+// Titles without an audio language tag are taken as English. Multi-language releases
+// also return MultiLanguage.
+func ReleaseLanguages(title string) []string {
+	languages := taggedLanguages(title, languageTags)
+	if hasAnyTag(title, multiLanguageTags) {
+		languages = append(languages, MultiLanguage)
+	}
+	if len(languages) == 0 {
+		return []string{"en"}
+	}
+	return languages
+}
+
+// ReleaseSubtitles returns the subtitle languages tagged in a release title (e.g. fr for VOSTFR)
+func ReleaseSubtitles(title string) []string {
+	return taggedLanguages(title, subtitleTags)
+}
+
+// MatchesLanguage checks if a release title is suitable for the requested language
+// Multi-language releases always match, subtitled ones match their subtitle language.
+// English (or an empty language) matches titles without any known foreign language tag.
+func MatchesLanguage(title, lang string) bool {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+
+	if hasAnyTag(title, multiLanguageTags) {
+		return true
+	}
+
+	if lang == "" || lang == "en" {
+		return len(taggedLanguages(title, languageTags)) == 0 && len(taggedLanguages(title, subtitleTags)) == 0
+	}
+
+	tags, ok := languageTags[lang]
+	if !ok {
+		// Unknown code: use it as a literal tag (e.g. "hindi")
+		tags = []string{lang}
+	}
+
+	return hasAnyTag(title, tags) || hasAnyTag(title, subtitleTags[lang])
+}
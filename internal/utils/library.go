@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// videoExtensions are the files considered by the library scanner
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".m4v": true, ".avi": true, ".ts": true, ".wmv": true, ".mov": true,
+}
+
+var (
+	separatorRegex    = regexp.MustCompile(`[\._]+`)
+	bracketYearRegex  = regexp.MustCompile(`[\(\[]((?:19|20)\d{2})[\)\]]`)
+	seasonDirRegex    = regexp.MustCompile(`(?i)^(season|series|s)[ ._-]*\d+$|^specials$`)
+	nonWordRegex      = regexp.MustCompile(`[^a-z0-9]+`)
+	folderSeasonRegex = regexp.MustCompile(`\d+$`)
+)
+
+// folderEpisodeRegexes find the episode number of a file in a season folder without an
+// SxxEyy marker, e.g. "1x05 - Title", "Episode 5", "E05" or "05 - Title"
+var folderEpisodeRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:^|[ ._-])\d{1,2}x(\d{1,3})(?:[ ._-]|$)`),
+	regexp.MustCompile(`(?i)(?:^|[ ._-])(?:episode|ep|e)[ ._-]?(\d{1,3})(?:[ ._-]|$)`),
+	regexp.MustCompile(`^(\d{1,3})(?:[ ._-]|$)`),
+}
+
+// LibraryItem is a video file name parsed by ParseLibraryFile
+type LibraryItem struct {
+	Title   string
+	Year    int // 0 if unknown
+	Season  int // 0 for movies
+	Episode int // 0 for movies
+}
+
+// IsEpisode checks if the file is a TV episode
+func (i LibraryItem) IsEpisode() bool {
+	return i.Episode > 0
+}
+
+// IsVideoFile checks if a path has a video file extension
+func IsVideoFile(path string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ParseLibraryFile extracts the title, year and episode from a library file path
+// Handles the usual layouts, e.g. "Show (2011)/Season 01/Show - S01E05.mkv",
+// "Movie (1999)/Movie (1999) 1080p.mkv" or "Movie.1999.1080p.BluRay.mkv". The title
+// falls back to the folder names when the file name doesn't hold one. Files of season
+// folders without an SxxEyy marker take their season from the folder and their title
+// from the show folder, e.g. "Show (2011)/Season 02/05 - Title.mkv".
+// Returns ok=false if no title is found.
+func ParseLibraryFile(path string) (LibraryItem, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dir := filepath.Dir(path)
+
+	var item LibraryItem
+	season, episode, marked := ParseFileEpisode(name)
+	ok := marked
+	if !ok {
+		season, episode, ok = seasonFolderEpisode(name, dir)
+	}
+	if ok {
+		item.Season, item.Episode = season, episode
+
+		// Show folder: parent, or grandparent for season folders
+		showDir := filepath.Base(dir)
+		if seasonDirRegex.MatchString(showDir) {
+			showDir = filepath.Base(filepath.Dir(dir))
+		}
+
+		if marked {
+			item.Title, item.Year = parseTitleYear(name[:fileEpisodeRegex.FindStringIndex(name)[0]])
+		}
+		if item.Title == "" {
+			item.Title, item.Year = parseTitleYear(showDir)
+		} else if item.Year == 0 {
+			if folderTitle, folderYear := parseTitleYear(showDir); NormalizeTitle(folderTitle) == NormalizeTitle(item.Title) {
+				item.Year = folderYear
+			}
+		}
+	} else {
+		item.Title, item.Year = parseTitleYear(name)
+		if item.Title == "" || item.Year == 0 {
+			if folderTitle, folderYear := parseTitleYear(filepath.Base(dir)); folderTitle != "" && folderYear != 0 {
+				item.Title, item.Year = folderTitle, folderYear
+			}
+		}
+	}
+
+	return item, item.Title != ""
+}
+
+// seasonFolderEpisode reads the season of a file from its season folder ("Specials" is
+// season 0) and the episode from its name. Returns ok=false outside season folders.
+func seasonFolderEpisode(name string, dir string) (season int, episode int, ok bool) {
+	folder := filepath.Base(dir)
+	if !seasonDirRegex.MatchString(folder) {
+		return 0, 0, false
+	}
+	if digits := folderSeasonRegex.FindString(folder); digits != "" {
+		season, _ = strconv.Atoi(digits)
+	}
+
+	for _, re := range folderEpisodeRegexes {
+		if matches := re.FindStringSubmatch(name); matches != nil {
+			if episode, _ = strconv.Atoi(matches[1]); episode > 0 {
+				return season, episode, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// parseTitleYear splits a release or folder name into its title and year
+// Everything after the year (resolution, source, group) is dropped.
+func parseTitleYear(name string) (string, int) {
+	name = separatorRegex.ReplaceAllString(name, " ")
+
+	year := 0
+	if loc := bracketYearRegex.FindStringSubmatchIndex(name); loc != nil {
+		year = ExtractYear(name[loc[2]:loc[3]])
+		name = name[:loc[0]]
+	} else if loc := yearRegex.FindStringIndex(name); loc != nil && loc[0] > 0 {
+		year = ExtractYear(name[loc[0]:loc[1]])
+		name = name[:loc[0]]
+	}
+
+	title := strings.Trim(strings.TrimSpace(name), "-([ ")
+	return title, year
+}
+
+// NormalizeTitle lowercases a title and drops punctuation for matching
+// e.g. "Marvel's Agents of S.H.I.E.L.D." and "Marvels Agents of SHIELD" match.
+func NormalizeTitle(title string) string {
+	title = strings.ToLower(title)
+	title = strings.ReplaceAll(title, "&", "and")
+	title = strings.ReplaceAll(title, "'", "")
+	title = strings.ReplaceAll(title, ".", "")
+	return strings.TrimSpace(nonWordRegex.ReplaceAllString(title, " "))
+}
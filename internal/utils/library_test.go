@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestParseLibraryFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected LibraryItem
+	}{
+		{"/tv/Game of Thrones (2011)/Season 01/Game of Thrones - S01E05 - The Wolf and the Lion.mkv", LibraryItem{"Game of Thrones", 2011, 1, 5}},
+		{"/tv/Severance/Season 2/S02E03.mkv", LibraryItem{"Severance", 0, 2, 3}},
+		{"/tv/downloads/The.Bear.S03E01.1080p.WEB-DL.mkv", LibraryItem{"The Bear", 0, 3, 1}},
+		{"/tv/Severance (2022)/Season 02/05 - Trojan's Horse.mkv", LibraryItem{"Severance", 2022, 2, 5}},
+		{"/tv/The Bear/Season 3/The Bear - 3x07 - Legacy.mkv", LibraryItem{"The Bear", 0, 3, 7}},
+		{"/tv/Doctor Who (2005)/Specials/Episode 2.mkv", LibraryItem{"Doctor Who", 2005, 0, 2}},
+		{"/movies/The Matrix (1999)/The Matrix (1999) Bluray-1080p.mkv", LibraryItem{"The Matrix", 1999, 0, 0}},
+		{"/movies/Dune.Part.Two.2024.2160p.WEB-DL.DDP5.1.mkv", LibraryItem{"Dune Part Two", 2024, 0, 0}},
+		{"/movies/Blade Runner 2049 (2017)/movie.mkv", LibraryItem{"Blade Runner 2049", 2017, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		item, ok := ParseLibraryFile(tt.path)
+		if !ok || item != tt.expected {
+			t.Errorf("ParseLibraryFile(%q) = %+v, %v, expected %+v", tt.path, item, ok, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps rotated log files, sorting them by rotation time
+const backupTimeFormat = "20060102T150405.000"
+
+// LogFileOptions configures a rotating log file
+type LogFileOptions struct {
+	Path       string
+	MaxSize    int64         // Bytes at which the file is rotated, 0 never rotates
+	MaxAge     time.Duration // Rotated files older than this are deleted, 0 keeps them
+	MaxBackups int           // Rotated files kept, 0 keeps them all
+	Compress   bool          // Gzip rotated files
+}
+
+// LogFile is a log file rotated once it reaches its maximum size
+// Rotated files are renamed with their rotation time, e.g. gomenarr-20240102T150405.000.log,
+// then compressed and pruned in the background so writes don't wait for them.
+type LogFile struct {
+	options LogFileOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	cleanupMu sync.Mutex // Serializes the compression and pruning of rotated files
+	cleanups  sync.WaitGroup
+}
+
+// OpenLogFile opens a log file for appending, creating it and its directory if needed
+func OpenLogFile(options LogFileOptions) (*LogFile, error) {
+	f := &LogFile{options: options}
+	if err := os.MkdirAll(filepath.Dir(options.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends to the log file, rotating it first when the write would exceed its maximum size
+func (f *LogFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.options.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.options.MaxSize {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file once the pending compressions are done
+func (f *LogFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cleanups.Wait()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the log file for appending
+func (f *LogFile) open() error {
+	file, err := os.OpenFile(f.options.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file with its rotation time and starts a new one
+func (f *LogFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	// Rotations within the same millisecond get the next free name
+	at := time.Now()
+	rotated := f.backupPath(at)
+	for fileExists(rotated) || fileExists(rotated+".gz") {
+		at = at.Add(time.Millisecond)
+		rotated = f.backupPath(at)
+	}
+	if err := os.Rename(f.options.Path, rotated); err != nil {
+		// Keep writing to the current file, rotation is tried again on the next write
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.cleanups.Add(1)
+	go func() {
+		defer f.cleanups.Done()
+		f.cleanup(rotated)
+	}()
+	return nil
+}
+
+// backupPath returns the name of the file rotated at a time
+func (f *LogFile) backupPath(at time.Time) string {
+	ext := filepath.Ext(f.options.Path)
+	base := strings.TrimSuffix(f.options.Path, ext)
+	return base + "-" + at.Format(backupTimeFormat) + ext
+}
+
+// cleanup compresses a rotated file and deletes the backups past the count and age limits
+// Failures are reported on stderr, the logger writing to this file.
+func (f *LogFile) cleanup(rotated string) {
+	f.cleanupMu.Lock()
+	defer f.cleanupMu.Unlock()
+
+	if f.options.Compress {
+		if err := compressFile(rotated); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compress rotated log file %s: %v\n", rotated, err)
+		}
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list rotated log files: %v\n", err)
+		return
+	}
+	cutoff := time.Now().Add(-f.options.MaxAge)
+	for i, backup := range backups {
+		expired := f.options.MaxAge > 0 && backup.rotatedAt.Before(cutoff)
+		if (f.options.MaxBackups > 0 && i >= f.options.MaxBackups) || expired {
+			if err := os.Remove(backup.path); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to delete rotated log file %s: %v\n", backup.path, err)
+			}
+		}
+	}
+}
+
+// logBackup is a rotated log file
+type logBackup struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// backups lists the rotated files of the log file, newest first
+func (f *LogFile) backups() ([]logBackup, error) {
+	ext := filepath.Ext(f.options.Path)
+	prefix := strings.TrimSuffix(filepath.Base(f.options.Path), ext) + "-"
+
+	dir := filepath.Dir(f.options.Path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []logBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		rotatedAt, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{path: filepath.Join(dir, name), rotatedAt: rotatedAt})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// fileExists reports whether a path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile gzips a file next to it and deletes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLogFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gomenarr.log")
+
+	file, err := OpenLogFile(LogFileOptions{Path: path, MaxSize: 10, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("OpenLogFile failed: %v", err)
+	}
+	for _, line := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != "line four\n" {
+		t.Errorf("Expected the last line in the current file, got %q", current)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backups []string
+	for _, entry := range entries {
+		if entry.Name() != "gomenarr.log" {
+			backups = append(backups, entry.Name())
+		}
+	}
+	sort.Strings(backups)
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups kept, got %v", backups)
+	}
+	for _, name := range backups {
+		if !strings.HasPrefix(name, "gomenarr-") || !strings.HasSuffix(name, ".log.gz") {
+			t.Errorf("Expected a compressed backup, got %s", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	namingTokenRegex  = regexp.MustCompile(`\{(\w+)(?::(\d+))?\}`)
+	emptyBracketRegex = regexp.MustCompile(`\(\s*\)|\[\s*\]`)
+	spaceRunRegex     = regexp.MustCompile(`\s{2,}`)
+	danglingDashRegex = regexp.MustCompile(`(\s-)+\s*$|^\s*(-\s)+`)
+)
+
+// invalidPathChars are replaced in rendered path segments (Windows/SMB safe)
+var invalidPathChars = strings.NewReplacer(
+	"/", "-", "\\", "-", ":", " -", "*", "", "?", "", "\"", "'", "<", "", ">", "", "|", "-",
+)
+
+// sourceNames are the display names of the release sources
+var sourceNames = map[string]string{
+	"remux":  "Remux",
+	"bluray": "BluRay",
+	"webrip": "WEBRip",
+	"web-dl": "WEB-DL",
+	"hdtv":   "HDTV",
+	"dvd":    "DVD",
+}
+
+// NamingFields holds the values available in naming templates
+type NamingFields struct {
+	Title   string
+	Year    int
+	Season  int
+	Episode int
+	IMDBId  string
+	Release string // Release title, used for the quality tokens and {Group}
+}
+
+// RenderNamingTemplate renders a library path from a naming template
+// Tokens are {Title}, {Year}, {Season}, {Episode}, {IMDBId}, {Quality} (e.g. "1080p WEB-DL"),
+// {Resolution}, {Source}, {Codec}, {Group} and {Release}. Numbers take an optional
+// zero-padding width, e.g. {Season:02}. The template is split on "/" into folders,
+// each one cleaned of characters not allowed in file names; empty brackets and
+// dangling dashes left by unknown values are dropped.
+func RenderNamingTemplate(template string, fields NamingFields) (string, error) {
+	values := namingValues(fields)
+
+	var segments []string
+	for _, segment := range strings.Split(template, "/") {
+		var renderErr error
+		rendered := namingTokenRegex.ReplaceAllStringFunc(segment, func(token string) string {
+			match := namingTokenRegex.FindStringSubmatch(token)
+			value, ok := values[match[1]]
+			if !ok {
+				renderErr = fmt.Errorf("unknown naming token %s", token)
+				return ""
+			}
+			if number, isNumber := value.(int); isNumber {
+				if number == 0 && match[1] == "Year" {
+					return ""
+				}
+				width, _ := strconv.Atoi(match[2])
+				return fmt.Sprintf("%0*d", width, number)
+			}
+			return invalidPathChars.Replace(value.(string))
+		})
+		if renderErr != nil {
+			return "", renderErr
+		}
+
+		rendered = cleanSegment(rendered)
+		if rendered == "" {
+			return "", fmt.Errorf("naming template %q renders an empty folder or file name", template)
+		}
+		segments = append(segments, rendered)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// ValidateNamingTemplate checks a naming template only uses known tokens
+func ValidateNamingTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("naming template is empty")
+	}
+	if strings.HasPrefix(template, "/") || strings.Contains(template, "..") {
+		return fmt.Errorf("naming template %q must be a relative path", template)
+	}
+	_, err := RenderNamingTemplate(template, NamingFields{
+		Title:   "Title",
+		Year:    2000,
+		Season:  1,
+		Episode: 1,
+		IMDBId:  "tt0000000",
+		Release: "Title.2000.1080p.WEB-DL.x264-GROUP",
+	})
+	return err
+}
+
+// ReleaseQuality describes the resolution and source of a release, e.g. "1080p WEB-DL"
+func ReleaseQuality(title string) string {
+	var parts []string
+	if resolution := ReleaseResolution(title); resolution > 0 {
+		parts = append(parts, fmt.Sprintf("%dp", resolution))
+	}
+	if source := ReleaseSource(title); source != "" {
+		parts = append(parts, sourceNames[source])
+	}
+	return strings.Join(parts, " ")
+}
+
+// namingValues maps the naming tokens to their value, ints for numeric tokens
+func namingValues(fields NamingFields) map[string]interface{} {
+	resolution := ""
+	if lines := ReleaseResolution(fields.Release); lines > 0 {
+		resolution = fmt.Sprintf("%dp", lines)
+	}
+
+	return map[string]interface{}{
+		"Title":      fields.Title,
+		"Year":       fields.Year,
+		"Season":     fields.Season,
+		"Episode":    fields.Episode,
+		"IMDBId":     fields.IMDBId,
+		"Quality":    ReleaseQuality(fields.Release),
+		"Resolution": resolution,
+		"Source":     sourceNames[ReleaseSource(fields.Release)],
+		"Codec":      ReleaseCodec(fields.Release),
+		"Group":      ReleaseGroup(fields.Release),
+		"Release":    fields.Release,
+	}
+}
+
+// cleanSegment tidies a rendered folder or file name
+func cleanSegment(segment string) string {
+	segment = emptyBracketRegex.ReplaceAllString(segment, "")
+	segment = spaceRunRegex.ReplaceAllString(segment, " ")
+	segment = danglingDashRegex.ReplaceAllString(segment, "")
+	return strings.Trim(strings.TrimSpace(segment), ". ")
+}
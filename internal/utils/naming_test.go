@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+func TestRenderNamingTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		fields   NamingFields
+		expected string
+	}{
+		{
+			"{Title} ({Year})/Season {Season:02}/{Title} - S{Season:02}E{Episode:02} - {Quality}",
+			NamingFields{Title: "The Bear", Year: 2022, Season: 3, Episode: 1, Release: "The.Bear.S03E01.1080p.WEB-DL.x264-GRP"},
+			"The Bear (2022)/Season 03/The Bear - S03E01 - 1080p WEB-DL",
+		},
+		{
+			"{Title} ({Year})/{Title} ({Year}) - {Quality}",
+			NamingFields{Title: "Mission: Impossible", Release: "Mission.Impossible.DVDRip"},
+			"Mission - Impossible/Mission - Impossible - DVD",
+		},
+		{
+			"{Title} ({Year})/{Title} ({Year}) - {Quality}",
+			NamingFields{Title: "Heat", Year: 1995, Release: "Heat"},
+			"Heat (1995)/Heat (1995)",
+		},
+	}
+
+	for _, tt := range tests {
+		path, err := RenderNamingTemplate(tt.template, tt.fields)
+		if err != nil || path != tt.expected {
+			t.Errorf("RenderNamingTemplate(%q) = %q, %v, expected %q", tt.template, path, err, tt.expected)
+		}
+	}
+}
+
+func TestValidateNamingTemplate(t *testing.T) {
+	for _, template := range []string{"{Title}/{Nope}", "/{Title}", "{Title}/../{Title}", ""} {
+		if err := ValidateNamingTemplate(template); err == nil {
+			t.Errorf("ValidateNamingTemplate(%q) = nil, expected an error", template)
+		}
+	}
+}
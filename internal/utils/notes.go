@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"strings"
+)
+
+// ParseNoteDirectives extracts key=value directives from a Trakt list item note
+// Directives can be separated by spaces, commas, semicolons or new lines,
+// e.g. "quality=720p lang=fr pack=never". Keys are lowercased, anything that
+// is not a directive is ignored so notes can still hold free text.
+func ParseNoteDirectives(notes string) map[string]string {
+	directives := make(map[string]string)
+
+	fields := strings.FieldsFunc(notes, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '\n' || r == '\r' || r == '\t'
+	})
+
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if key == "" || value == "" {
+			continue
+		}
+		directives[key] = value
+	}
+
+	return directives
+}
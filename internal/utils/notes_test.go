@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestParseNoteDirectives(t *testing.T) {
+	directives := ParseNoteDirectives("Great show! quality=720p, LANG=fr;pack=never\nbroken= =x")
+
+	if directives["quality"] != "720p" {
+		t.Errorf("Expected quality 720p, got %q", directives["quality"])
+	}
+	if directives["lang"] != "fr" {
+		t.Errorf("Expected lang fr, got %q", directives["lang"])
+	}
+	if directives["pack"] != "never" {
+		t.Errorf("Expected pack never, got %q", directives["pack"])
+	}
+	if len(directives) != 3 {
+		t.Errorf("Expected 3 directives, got %d: %v", len(directives), directives)
+	}
+}
+
+func TestMatchesLanguage(t *testing.T) {
+	tests := []struct {
+		title string
+		lang  string
+		want  bool
+	}{
+		{"Show.S01E01.FRENCH.1080p.WEB-DL", "fr", true},
+		{"Show.S01E01.MULTi.1080p.WEB-DL", "fr", true},
+		{"Show.S01E01.1080p.WEB-DL", "fr", false},
+		{"Show.S01E01.1080p.WEB-DL", "en", true},
+		{"Show.S01E01.GERMAN.1080p.WEB-DL", "en", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesLanguage(tt.title, tt.lang); got != tt.want {
+			t.Errorf("MatchesLanguage(%q, %q) = %v, want %v", tt.title, tt.lang, got, tt.want)
+		}
+	}
+}
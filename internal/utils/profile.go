@@ -0,0 +1,511 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/config"
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// defaultProfile keeps the historical ranking (REMUX > WEB-DL > others) when no profile is configured
+var defaultProfile = models.QualityProfile{
+	Name:    "default",
+	Sources: []string{"remux", "web-dl"},
+}
+
+// bytesPerGB converts the configured size limits, given in GB
+const bytesPerGB = 1 << 30
+
+// resolutionLadder lists the known resolutions, lowest first
+var resolutionLadder = []int{480, 576, 720, 1080, 2160}
+
+var (
+	resolutionRegex = regexp.MustCompile(`(?i)\b(2160|1080|720|576|480)[pi]\b`)
+	uhdRegex        = regexp.MustCompile(`(?i)\b(4k|uhd)\b`)
+)
+
+// sourcePatterns detects release sources, checked in order (a REMUX is also a BluRay)
+var sourcePatterns = []struct {
+	source  string
+	pattern *regexp.Regexp
+}{
+	{"remux", regexp.MustCompile(`(?i)\bremux\b`)},
+	{"bluray", regexp.MustCompile(`(?i)\b(blu-?ray|bdrip|brrip)\b`)},
+	{"webrip", regexp.MustCompile(`(?i)\bweb-?rip\b`)},
+	{"web-dl", regexp.MustCompile(`(?i)\b(web-?dl|web dl|web)\b`)},
+	{"hdtv", regexp.MustCompile(`(?i)\b(hdtv|pdtv)\b`)},
+	{"dvd", regexp.MustCompile(`(?i)\b(dvdrip|dvd)\b`)},
+}
+
+// codecPatterns detects video codecs
+var codecPatterns = []struct {
+	codec   string
+	pattern *regexp.Regexp
+}{
+	{"x265", regexp.MustCompile(`(?i)\b(x265|h\.?265|hevc)\b`)},
+	{"x264", regexp.MustCompile(`(?i)\b(x264|h\.?264|avc)\b`)},
+	{"av1", regexp.MustCompile(`(?i)\bav1\b`)},
+}
+
+// ParseResolution converts a resolution tag (2160p, 4k, 1080p, 720, sd) to its line count
+// An empty value returns 0.
+func ParseResolution(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "":
+		return 0, nil
+	case "4k", "uhd":
+		return 2160, nil
+	case "sd":
+		return 480, nil
+	}
+
+	lines, err := strconv.Atoi(strings.TrimRight(value, "pi"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid resolution %q", value)
+	}
+	for _, known := range resolutionLadder {
+		if lines == known {
+			return lines, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported resolution %q", value)
+}
+
+// ReleaseResolution extracts the resolution from a release title, 0 if unknown
+func ReleaseResolution(title string) int {
+	if matches := resolutionRegex.FindStringSubmatch(title); len(matches) > 1 {
+		lines, _ := strconv.Atoi(matches[1])
+		return lines
+	}
+	if uhdRegex.MatchString(title) {
+		return 2160
+	}
+	return 0
+}
+
+// ReleaseSource extracts the source (remux, bluray, web-dl, ...) from a release title
+func ReleaseSource(title string) string {
+	for _, p := range sourcePatterns {
+		if p.pattern.MatchString(title) {
+			return p.source
+		}
+	}
+	return ""
+}
+
+// ReleaseCodec extracts the video codec (x265, x264, av1) from a release title
+func ReleaseCodec(title string) string {
+	for _, p := range codecPatterns {
+		if p.pattern.MatchString(title) {
+			return p.codec
+		}
+	}
+	return ""
+}
+
+// ScoreRelease weights a release title and size against a quality profile
+// With the default weights, resolution outweighs source, then release group, then codec, then size. The size is
+// that of a single item (an episode for season packs), 0 if unknown. Returns the reason the release is rejected by
+// the profile, or an empty string if it is acceptable.
+func ScoreRelease(profile models.QualityProfile, title string, size int64) (int, string) {
+	resolution := ReleaseResolution(title)
+
+	if profile.MinResolution > 0 && resolution < profile.MinResolution {
+		if resolution == 0 {
+			return 0, fmt.Sprintf("unknown resolution, %dp minimum", profile.MinResolution)
+		}
+		return 0, fmt.Sprintf("%dp below %dp minimum", resolution, profile.MinResolution)
+	}
+	if profile.MaxResolution > 0 && resolution > profile.MaxResolution {
+		return 0, fmt.Sprintf("%dp above %dp maximum", resolution, profile.MaxResolution)
+	}
+
+	languages := ReleaseLanguages(title)
+	if reason := languageReason(profile, title, languages); reason != "" {
+		return 0, reason
+	}
+
+	// Undersized releases are usually fakes
+	limit := profile.Sizes[resolution]
+	if reason := sizeReason(limit, resolution, size); reason != "" {
+		return 0, reason
+	}
+
+	weights := profile.Weights
+	if weights == (models.ScoringWeights{}) {
+		weights = models.DefaultScoringWeights
+	}
+
+	score := resolutionScore(profile.PreferredResolution, resolution)*weights.Resolution +
+		preferenceScore(profile.Sources, ReleaseSource(title))*weights.Source +
+		groupBonus(profile, ReleaseGroup(title), weights) +
+		preferenceScore(profile.Codecs, ReleaseCodec(title))*weights.Codec +
+		sizeBonus(limit.Preferred, size, weights) +
+		languageScore(profile.Languages, languages)*weights.Language +
+		bestPreference(profile.Subtitles, ReleaseSubtitles(title))*weights.Subtitle
+
+	return score, ""
+}
+
+// languageReason returns the reason the audio languages of a release are rejected by a
+// profile, or an empty string. Multi-language releases keep the original audio and are
+// never rejected for one of their languages.
+func languageReason(profile models.QualityProfile, title string, languages []string) string {
+	if len(profile.RequiredLanguages) > 0 {
+		required := false
+		for _, lang := range profile.RequiredLanguages {
+			required = required || MatchesLanguage(title, lang)
+		}
+		if !required {
+			return fmt.Sprintf("language %s required", strings.Join(profile.RequiredLanguages, " or "))
+		}
+	}
+
+	if slices.Contains(languages, MultiLanguage) {
+		return ""
+	}
+	for _, lang := range languages {
+		if slices.Contains(profile.RejectedLanguages, lang) {
+			return fmt.Sprintf("language %s rejected", lang)
+		}
+	}
+	return ""
+}
+
+// languageScore ranks the audio languages of a release by the preferred languages, best first
+// Multi-language releases rank as the best preferred language.
+func languageScore(preferences []string, languages []string) int {
+	if slices.Contains(languages, MultiLanguage) {
+		return len(preferences)
+	}
+	return bestPreference(preferences, languages)
+}
+
+// bestPreference scores the best of several values by a preference list, best first
+func bestPreference(preferences []string, values []string) int {
+	best := 0
+	for _, value := range values {
+		best = max(best, preferenceScore(preferences, value))
+	}
+	return best
+}
+
+// groupScore ranks the preferred release groups first and the avoided ones last
+// Avoided groups score below releases without a known group.
+func groupScore(profile models.QualityProfile, group string) int {
+	if group == "" {
+		return 0
+	}
+	for _, avoided := range profile.AvoidedGroups {
+		if avoided == group {
+			return -(len(profile.PreferredGroups) + 1)
+		}
+	}
+	return preferenceScore(profile.PreferredGroups, group)
+}
+
+// groupBonus weights the release group of a release, scaled to stay below one source step
+// however many groups are preferred
+func groupBonus(profile models.QualityProfile, group string, weights models.ScoringWeights) int {
+	score := groupScore(profile, group) * weights.Group
+	limit := weights.Source - 1
+	top := (len(profile.PreferredGroups) + 1) * weights.Group
+	if limit <= 0 || top <= limit {
+		return score
+	}
+	return score * limit / top
+}
+
+// sizeReason returns the reason a release size is rejected by the size limits of its
+// resolution, or an empty string. A size of 0 (unknown) is always accepted.
+func sizeReason(limit models.SizeLimit, resolution int, size int64) string {
+	if size <= 0 {
+		return ""
+	}
+	if limit.Min > 0 && size < limit.Min {
+		return fmt.Sprintf("%s below %s minimum for %dp", formatGB(size), formatGB(limit.Min), resolution)
+	}
+	if limit.Max > 0 && size > limit.Max {
+		return fmt.Sprintf("%s above %s maximum for %dp", formatGB(size), formatGB(limit.Max), resolution)
+	}
+	return ""
+}
+
+// maxSizeScore is the score of a release of the preferred size
+const maxSizeScore = 9
+
+// sizeBonus weights the size of a release by its distance to the preferred size, scaled to
+// stay below one codec step
+func sizeBonus(preferred, size int64, weights models.ScoringWeights) int {
+	score := sizeScore(preferred, size) * weights.Size
+	limit := weights.Codec - 1
+	top := maxSizeScore * weights.Size
+	if limit <= 0 || top <= limit {
+		return score
+	}
+	return score * limit / top
+}
+
+// sizeScore ranks a size by its distance to the preferred size, one point lost per 10%
+func sizeScore(preferred, size int64) int {
+	if preferred <= 0 || size <= 0 {
+		return 0
+	}
+	deviation := math.Abs(float64(size-preferred)) / float64(preferred)
+	return max(maxSizeScore-int(deviation*10), 0)
+}
+
+// formatGB formats a size in bytes as gigabytes
+func formatGB(size int64) string {
+	return fmt.Sprintf("%.1fGB", float64(size)/bytesPerGB)
+}
+
+// IsUpgrade checks if a movie release scores higher than the current one under a profile
+// A current release the profile rejects (e.g. below its minimum resolution) is
+// upgraded by any acceptable release.
+func IsUpgrade(profile models.QualityProfile, current, candidate *models.NZB) bool {
+	candidateScore, reason := ScoreRelease(profile, candidate.Title, candidate.Size)
+	if reason != "" {
+		return false
+	}
+
+	currentScore, reason := ScoreRelease(profile, current.Title, current.Size)
+	if reason != "" {
+		return true
+	}
+	return candidateScore > currentScore
+}
+
+// resolutionScore ranks the preferred resolution first, then the closest ones
+func resolutionScore(preferred, resolution int) int {
+	if preferred == 0 || resolution == 0 {
+		return 0
+	}
+	if resolution == preferred {
+		return 99
+	}
+
+	distance := ladderIndex(resolution) - ladderIndex(preferred)
+	if distance < 0 {
+		distance = -distance
+	}
+	// Lower resolutions win ties over higher ones at the same distance
+	if resolution > preferred {
+		return 90 - distance*10 - 1
+	}
+	return 90 - distance*10
+}
+
+// ladderIndex returns the position of a resolution in the ladder
+func ladderIndex(resolution int) int {
+	for i, known := range resolutionLadder {
+		if known == resolution {
+			return i
+		}
+	}
+	return 0
+}
+
+// preferenceScore scores a value by its position in a preference list, best first
+func preferenceScore(preferences []string, value string) int {
+	if value == "" {
+		return 0
+	}
+	for i, preferred := range preferences {
+		if preferred == value {
+			return len(preferences) - i
+		}
+	}
+	return 0
+}
+
+// QualityProfiles resolves the quality profile of media items
+type QualityProfiles struct {
+	movie models.QualityProfile
+	tv    models.QualityProfile
+	named map[string]models.QualityProfile
+	shows map[string]string // IMDB ID -> profile name
+}
+
+// NewQualityProfiles builds the profiles from configuration
+// Media types without a configured profile keep the default ranking with the global weights.
+func NewQualityProfiles(profiles []config.QualityProfileConfig, scoring config.ScoringConfig) (*QualityProfiles, error) {
+	fallback := defaultProfile
+	fallback.Weights = scoringWeights(scoring)
+
+	qp := &QualityProfiles{
+		movie: fallback,
+		tv:    fallback,
+		named: make(map[string]models.QualityProfile),
+		shows: make(map[string]string),
+	}
+
+	for _, cfg := range profiles {
+		profile := models.QualityProfile{
+			Name:    cfg.Name,
+			Sources: cfg.Sources,
+			Codecs:  cfg.Codecs,
+			Weights: scoringWeights(cfg.Scoring),
+
+			Languages:         cfg.Languages,
+			RequiredLanguages: cfg.RequiredLanguages,
+			RejectedLanguages: cfg.RejectedLanguages,
+			Subtitles:         cfg.Subtitles,
+
+			PreferredGroups: upperAll(cfg.PreferredGroups),
+			AvoidedGroups:   upperAll(cfg.AvoidedGroups),
+		}
+
+		var err error
+		if profile.Sizes, err = parseSizeLimits(cfg.Sizes); err != nil {
+			return nil, fmt.Errorf("quality profile %s: %w", cfg.Name, err)
+		}
+		if profile.PreferredResolution, err = ParseResolution(cfg.Preferred); err != nil {
+			return nil, fmt.Errorf("quality profile %s: %w", cfg.Name, err)
+		}
+		if profile.MinResolution, err = ParseResolution(cfg.Min); err != nil {
+			return nil, fmt.Errorf("quality profile %s: %w", cfg.Name, err)
+		}
+		if profile.MaxResolution, err = ParseResolution(cfg.Max); err != nil {
+			return nil, fmt.Errorf("quality profile %s: %w", cfg.Name, err)
+		}
+		if profile.MaxResolution > 0 && profile.MinResolution > profile.MaxResolution {
+			return nil, fmt.Errorf("quality profile %s: minimum resolution above maximum", cfg.Name)
+		}
+
+		switch cfg.Name {
+		case config.QualityProfileMovie:
+			qp.movie = profile
+		case config.QualityProfileTV:
+			qp.tv = profile
+		default:
+			qp.named[cfg.Name] = profile
+		}
+		for _, imdbID := range cfg.Shows {
+			qp.shows[imdbID] = cfg.Name
+		}
+	}
+
+	return qp, nil
+}
+
+// scoringWeights converts configured weights
+func scoringWeights(cfg config.ScoringConfig) models.ScoringWeights {
+	return models.ScoringWeights{
+		Resolution: cfg.Resolution,
+		Source:     cfg.Source,
+		Group:      cfg.Group,
+		Codec:      cfg.Codec,
+		Size:       cfg.Size,
+		Language:   cfg.Language,
+		Subtitle:   cfg.Subtitle,
+	}
+}
+
+// parseSizeLimits parses size limits in GB by resolution
+// Format: "1080p:2-40~8", the minimum, maximum (after -) and preferred size (after ~) are each optional.
+func parseSizeLimits(values []string) (map[int]models.SizeLimit, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	limits := make(map[int]models.SizeLimit, len(values))
+	for _, value := range values {
+		tag, sizes, found := strings.Cut(value, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid size limit %q, expected resolution:min-max~preferred", value)
+		}
+		resolution, err := ParseResolution(tag)
+		if err != nil {
+			return nil, err
+		}
+		if resolution == 0 {
+			return nil, fmt.Errorf("invalid size limit %q, missing resolution", value)
+		}
+
+		sizes, preferred, _ := strings.Cut(sizes, "~")
+		minSize, maxSize, _ := strings.Cut(sizes, "-")
+
+		var limit models.SizeLimit
+		for _, part := range []struct {
+			value  string
+			target *int64
+		}{{minSize, &limit.Min}, {maxSize, &limit.Max}, {preferred, &limit.Preferred}} {
+			if *part.target, err = parseGB(part.value); err != nil {
+				return nil, fmt.Errorf("invalid size limit %q: %w", value, err)
+			}
+		}
+		if limit.Max > 0 && limit.Min > limit.Max {
+			return nil, fmt.Errorf("invalid size limit %q, minimum above maximum", value)
+		}
+		limits[resolution] = limit
+	}
+
+	return limits, nil
+}
+
+// parseGB converts a size in GB (e.g. 2 or 1.5) to bytes, an empty value returns 0
+func parseGB(value string) (int64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "GB"))
+	if value == "" {
+		return 0, nil
+	}
+	gb, err := strconv.ParseFloat(value, 64)
+	if err != nil || gb < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(gb * bytesPerGB), nil
+}
+
+// upperAll uppercases release group names, ReleaseGroup returns them uppercase
+func upperAll(values []string) []string {
+	upper := make([]string, 0, len(values))
+	for _, value := range values {
+		upper = append(upper, strings.ToUpper(value))
+	}
+	return upper
+}
+
+// Lookup returns a named profile
+func (qp *QualityProfiles) Lookup(name string) (models.QualityProfile, bool) {
+	switch strings.ToLower(name) {
+	case config.QualityProfileMovie:
+		return qp.movie, true
+	case config.QualityProfileTV:
+		return qp.tv, true
+	}
+	profile, ok := qp.named[strings.ToLower(name)]
+	return profile, ok
+}
+
+// For returns the profile of a media item
+// Priority: the profile override from Trakt notes, the profile listing the show, then the
+// media type default. Unknown profile names fall back to the media type default.
+func (qp *QualityProfiles) For(media *models.Media) models.QualityProfile {
+	if qp == nil {
+		return defaultProfile
+	}
+
+	if media.Overrides.Profile != "" {
+		if profile, ok := qp.Lookup(media.Overrides.Profile); ok {
+			return profile
+		}
+	}
+
+	if name, ok := qp.shows[media.IMDBId]; ok {
+		if profile, ok := qp.Lookup(name); ok {
+			return profile
+		}
+	}
+
+	if media.MediaType == models.MediaTypeMovie {
+		return qp.movie
+	}
+	return qp.tv
+}
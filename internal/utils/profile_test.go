@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+func TestScoreRelease(t *testing.T) {
+	profile := models.QualityProfile{
+		Name:                "movie",
+		PreferredResolution: 2160,
+		MinResolution:       1080,
+		Sources:             []string{"remux", "bluray", "web-dl"},
+		Codecs:              []string{"x265", "x264"},
+	}
+
+	// Best first
+	ranked := []string{
+		"Movie.2024.2160p.UHD.BluRay.REMUX.HEVC-FraMeSToR",
+		"Movie.2024.2160p.WEB-DL.DDP5.1.H.265-FLUX",
+		"Movie.2024.1080p.BluRay.REMUX.AVC-FraMeSToR",
+		"Movie.2024.1080p.WEB-DL.DDP5.1.H.264-FLUX",
+	}
+
+	previous := -1
+	for i, title := range ranked {
+		score, reason := ScoreRelease(profile, title, 0)
+		if reason != "" {
+			t.Fatalf("ScoreRelease(%q) rejected: %s", title, reason)
+		}
+		if i > 0 && score >= previous {
+			t.Errorf("ScoreRelease(%q) = %d, expected less than %d", title, score, previous)
+		}
+		previous = score
+	}
+
+	for _, title := range []string{"Movie.2024.720p.WEB-DL-FLUX", "Movie.2024.WEB-DL-FLUX"} {
+		if _, reason := ScoreRelease(profile, title, 0); reason == "" {
+			t.Errorf("ScoreRelease(%q) accepted, expected below minimum", title)
+		}
+	}
+}
+
+func TestScoreReleaseWeights(t *testing.T) {
+	profile := models.QualityProfile{
+		PreferredResolution: 2160,
+		Sources:             []string{"remux", "web-dl"},
+		Weights:             models.ScoringWeights{Resolution: 1, Source: 1000},
+	}
+
+	remux, _ := ScoreRelease(profile, "Movie.2024.1080p.BluRay.REMUX.AVC-FraMeSToR", 0)
+	web, _ := ScoreRelease(profile, "Movie.2024.2160p.WEB-DL.DDP5.1.H.265-FLUX", 0)
+	if remux <= web {
+		t.Errorf("Expected a source-weighted profile to prefer the 1080p REMUX (%d) over the 2160p WEB-DL (%d)", remux, web)
+	}
+}
+
+func TestScoreReleaseGroups(t *testing.T) {
+	profile := models.QualityProfile{
+		PreferredResolution: 1080,
+		Sources:             []string{"bluray", "web-dl"},
+		PreferredGroups:     []string{"FLUX", "NTB"},
+		AvoidedGroups:       []string{"YIFY"},
+	}
+
+	// Best first: group preferences rank releases of the same resolution and source
+	ranked := []string{
+		"Movie.2024.1080p.WEB-DL.H.264-FLUX",
+		"Movie.2024.1080p.WEB-DL.H.264-NTb",
+		"Movie.2024.1080p.WEB-DL.H.264-OTHER",
+		"Movie.2024.1080p.WEB-DL.H.264-YIFY",
+	}
+
+	previous := 0
+	for i, title := range ranked {
+		score, _ := ScoreRelease(profile, title, 0)
+		if i > 0 && score >= previous {
+			t.Errorf("ScoreRelease(%q) = %d, expected less than %d", title, score, previous)
+		}
+		previous = score
+	}
+
+	// Source still outweighs the release group
+	bluray, _ := ScoreRelease(profile, "Movie.2024.1080p.BluRay.x264-YIFY", 0)
+	web, _ := ScoreRelease(profile, "Movie.2024.1080p.WEB-DL.H.264-FLUX", 0)
+	if bluray <= web {
+		t.Errorf("Expected the BluRay (%d) to outrank the preferred group WEB-DL (%d)", bluray, web)
+	}
+
+	// However many groups are preferred
+	profile.Sources = []string{"web-dl", "hdtv"}
+	profile.PreferredGroups = []string{"FLUX", "NTB", "CMRG", "SMURF", "HONE", "PECULATE", "KINGS", "GLHF", "TEPES", "SIGMA", "EDITH", "ETHEL"}
+	hdtv, _ := ScoreRelease(profile, "Show.S01E01.1080p.HDTV.H.264-FLUX", 0)
+	web, _ = ScoreRelease(profile, "Show.S01E01.1080p.WEB-DL.H.264-OTHER", 0)
+	if hdtv >= web {
+		t.Errorf("Expected the WEB-DL (%d) to outrank the preferred group HDTV (%d)", web, hdtv)
+	}
+	second, _ := ScoreRelease(profile, "Show.S01E01.1080p.HDTV.H.264-NTb", 0)
+	if second >= hdtv {
+		t.Errorf("Expected the second preferred group (%d) to rank below the first (%d)", second, hdtv)
+	}
+}
+
+func TestScoreReleaseLanguages(t *testing.T) {
+	profile := models.QualityProfile{
+		Name:                "french",
+		PreferredResolution: 1080,
+		Languages:           []string{"fr", "en"},
+		RejectedLanguages:   []string{"de"},
+		Subtitles:           []string{"fr"},
+	}
+
+	// Best first
+	ranked := []string{
+		"Show.S01E01.MULTi.1080p.WEB-DL-GROUP",
+		"Show.S01E01.FRENCH.1080p.WEB-DL-GROUP",
+		"Show.S01E01.VOSTFR.1080p.WEB-DL-GROUP",
+		"Show.S01E01.1080p.WEB-DL-GROUP",
+		"Show.S01E01.ITALIAN.1080p.WEB-DL-GROUP",
+	}
+
+	previous := 0
+	for i, title := range ranked {
+		score, reason := ScoreRelease(profile, title, 0)
+		if reason != "" {
+			t.Fatalf("ScoreRelease(%q) rejected: %s", title, reason)
+		}
+		if i > 0 && score > previous {
+			t.Errorf("ScoreRelease(%q) = %d, expected at most %d", title, score, previous)
+		}
+		previous = score
+	}
+
+	if _, reason := ScoreRelease(profile, "Show.S01E01.GERMAN.1080p.WEB-DL-GROUP", 0); reason == "" {
+		t.Error("German dub accepted, expected rejected")
+	}
+	if _, reason := ScoreRelease(profile, "Show.S01E01.GERMAN.DUAL.1080p.WEB-DL-GROUP", 0); reason != "" {
+		t.Errorf("Dual language release rejected: %s", reason)
+	}
+
+	profile.RequiredLanguages = []string{"fr"}
+	if _, reason := ScoreRelease(profile, "Show.S01E01.1080p.WEB-DL-GROUP", 0); reason == "" {
+		t.Error("English release accepted, expected French required")
+	}
+	if _, reason := ScoreRelease(profile, "Show.S01E01.VOSTFR.1080p.WEB-DL-GROUP", 0); reason != "" {
+		t.Errorf("French subtitled release rejected: %s", reason)
+	}
+}
+
+func TestScoreSize(t *testing.T) {
+	limits, err := parseSizeLimits([]string{"1080p:2-40~8", "2160p:10-"})
+	if err != nil {
+		t.Fatalf("parseSizeLimits failed: %v", err)
+	}
+	profile := models.QualityProfile{Sizes: limits}
+
+	tests := []struct {
+		title    string
+		gb       float64
+		rejected bool
+	}{
+		{"Movie.2024.1080p.WEB-DL-FLUX", 1.5, true},
+		{"Movie.2024.1080p.WEB-DL-FLUX", 8, false},
+		{"Movie.2024.1080p.WEB-DL-FLUX", 45, true},
+		{"Movie.2024.2160p.WEB-DL-FLUX", 9, true},
+		{"Movie.2024.2160p.WEB-DL-FLUX", 90, false},
+		{"Movie.2024.720p.WEB-DL-FLUX", 0.5, false},
+	}
+	for _, tt := range tests {
+		if _, reason := ScoreRelease(profile, tt.title, int64(tt.gb*bytesPerGB)); (reason != "") != tt.rejected {
+			t.Errorf("ScoreRelease(%q, %.1fGB) reason = %q, expected rejected %v", tt.title, tt.gb, reason, tt.rejected)
+		}
+	}
+
+	// Closest to the preferred size first, given room below a codec step
+	profile.Weights = models.ScoringWeights{Resolution: 10000, Codec: 10, Size: 1}
+	previous := -1
+	for i, gb := range []float64{8, 9, 5, 20} {
+		score, _ := ScoreRelease(profile, "Movie.2024.1080p.WEB-DL-FLUX", int64(gb*bytesPerGB))
+		if i > 0 && score >= previous {
+			t.Errorf("ScoreRelease(%.1fGB) = %d, expected less than %d", gb, score, previous)
+		}
+		previous = score
+	}
+
+	// The size never outweighs the codec
+	profile.Weights = models.ScoringWeights{}
+	profile.Codecs = []string{"x265", "x264"}
+	x265, _ := ScoreRelease(profile, "Movie.2024.1080p.WEB-DL.x265-FLUX", int64(20*bytesPerGB))
+	x264, _ := ScoreRelease(profile, "Movie.2024.1080p.WEB-DL.x264-FLUX", int64(8*bytesPerGB))
+	if x265 <= x264 {
+		t.Errorf("Expected the x265 release (%d) to outrank the x264 one of the preferred size (%d)", x265, x264)
+	}
+
+	for _, value := range []string{"1080p", "1080p:40-2", "1080p:x-2", "999p:2-4"} {
+		if _, err := parseSizeLimits([]string{value}); err == nil {
+			t.Errorf("parseSizeLimits(%q) succeeded, expected an error", value)
+		}
+	}
+}
+
+func TestQualityProfilesFor(t *testing.T) {
+	profiles := &QualityProfiles{
+		movie: models.QualityProfile{Name: "movie"},
+		tv:    models.QualityProfile{Name: "tv"},
+		named: map[string]models.QualityProfile{"casual": {Name: "casual"}},
+		shows: map[string]string{"tt0944947": "casual"},
+	}
+
+	tests := []struct {
+		media    models.Media
+		expected string
+	}{
+		{models.Media{MediaType: models.MediaTypeMovie}, "movie"},
+		{models.Media{MediaType: models.MediaTypeTV}, "tv"},
+		{models.Media{MediaType: models.MediaTypeTV, IMDBId: "tt0944947"}, "casual"},
+		{models.Media{MediaType: models.MediaTypeMovie, Overrides: models.MediaOverrides{Profile: "casual"}}, "casual"},
+		{models.Media{MediaType: models.MediaTypeTV, Overrides: models.MediaOverrides{Profile: "unknown"}}, "tv"},
+	}
+
+	for _, tt := range tests {
+		if profile := profiles.For(&tt.media); profile.Name != tt.expected {
+			t.Errorf("For(%+v) = %q, expected %q", tt.media.Overrides, profile.Name, tt.expected)
+		}
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	profile := models.QualityProfile{PreferredResolution: 2160, MinResolution: 1080, Sources: []string{"remux", "web-dl"}}
+
+	tests := []struct {
+		current, candidate string
+		expected           bool
+	}{
+		{"Movie.2024.1080p.WEB-DL-FLUX", "Movie.2024.2160p.WEB-DL-FLUX", true},
+		{"Movie.2024.2160p.WEB-DL-FLUX", "Movie.2024.1080p.BluRay.REMUX-FGT", false},
+		{"Movie.2024.2160p.WEB-DL-FLUX", "Movie.2024.2160p.WEB-DL-NTb", false},
+		{"Movie.2024.720p.HDTV-LOL", "Movie.2024.1080p.WEB-DL-FLUX", true},
+		{"Movie.2024.1080p.WEB-DL-FLUX", "Movie.2024.720p.WEB-DL-FLUX", false},
+	}
+
+	for _, tt := range tests {
+		current := &models.NZB{Title: tt.current}
+		candidate := &models.NZB{Title: tt.candidate}
+		if upgrade := IsUpgrade(profile, current, candidate); upgrade != tt.expected {
+			t.Errorf("IsUpgrade(%q, %q) = %v, expected %v", tt.current, tt.candidate, upgrade, tt.expected)
+		}
+	}
+}
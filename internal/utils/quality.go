@@ -28,8 +28,10 @@ func DetermineQuality(title string) models.Quality {
 
 // RankByQuality sorts NZBs by:
 // 1. Season packs (preferred over individual episodes for favorites)
-// 2. Quality (REMUX > WEB-DL > OTHER)
-// 3. Size (larger is better)
+// 2. Quality profile score (resolution, source, codec)
+// 3. Quality tier (REMUX > WEB-DL > OTHER)
+// 4. Score (indexer-weighted torrent health)
+// 5. Size (larger is better)
 func RankByQuality(nzbs []*models.NZB) []*models.NZB {
 	sorted := make([]*models.NZB, len(nzbs))
 	copy(sorted, nzbs)
@@ -40,7 +42,12 @@ func RankByQuality(nzbs []*models.NZB) []*models.NZB {
 			return sorted[i].IsSeasonPack // Season pack wins
 		}
 
-		// PRIORITY 2: Compare by quality
+		// PRIORITY 2: Compare by quality profile score
+		if sorted[i].QualityScore != sorted[j].QualityScore {
+			return sorted[i].QualityScore > sorted[j].QualityScore
+		}
+
+		// PRIORITY 3: Compare by quality tier
 		qualityI := qualityValue(sorted[i].Quality)
 		qualityJ := qualityValue(sorted[j].Quality)
 
@@ -48,7 +55,12 @@ func RankByQuality(nzbs []*models.NZB) []*models.NZB {
 			return qualityI > qualityJ // Higher quality first
 		}
 
-		// PRIORITY 3: If quality is the same, larger size wins
+		// PRIORITY 4: Healthier torrents win
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score > sorted[j].Score
+		}
+
+		// PRIORITY 5: If quality and score are the same, larger size wins
 		return sorted[i].Size > sorted[j].Size
 	})
 
@@ -84,3 +96,21 @@ func ExtractYear(title string) int {
 	}
 	return 0
 }
+
+// MatchesQuality checks if a release title satisfies a requested quality
+// The quality can be a tier name (remux, web-dl) or a title tag such as 720p
+func MatchesQuality(title, quality string) bool {
+	quality = strings.TrimSpace(quality)
+	if quality == "" {
+		return true
+	}
+
+	switch strings.ToLower(quality) {
+	case "remux":
+		return DetermineQuality(title) == models.QualityREMUX
+	case "web-dl", "webdl":
+		return DetermineQuality(title) == models.QualityWEBDL
+	}
+
+	return strings.Contains(strings.ToLower(title), strings.ToLower(quality))
+}
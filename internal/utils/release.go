@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+// releaseGroupRegex matches the group suffix of a scene release name: Title.2024.1080p.WEB-DL-GROUP
+var releaseGroupRegex = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\[[^\]]*\])?(?:\.(?:nzb|mkv|mp4|avi))?$`)
+
+// ReleaseGroup extracts the release group from a release title
+// Returns an empty string if the title has no group suffix
+func ReleaseGroup(title string) string {
+	matches := releaseGroupRegex.FindStringSubmatch(strings.TrimSpace(title))
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.ToUpper(matches[1])
+}
+
+// OrderForRetry reorders ranked candidates so that a retry moves away from what already failed:
+// 1. Different release group and different indexer
+// 2. Different release group, same indexer
+// 3. Same release group, different indexer
+// 4. Same release group and indexer
+// The quality ranking is kept within each tier. Unknown groups/indexers never count as a match.
+func OrderForRetry(candidates []*models.NZB, failed []*models.NZB) []*models.NZB {
+	failedGroups := make(map[string]bool)
+	failedIndexers := make(map[string]bool)
+	for _, nzb := range failed {
+		if group := ReleaseGroup(nzb.Title); group != "" {
+			failedGroups[group] = true
+		}
+		if nzb.Indexer != "" {
+			failedIndexers[nzb.Indexer] = true
+		}
+	}
+
+	tiers := make([][]*models.NZB, 4)
+	for _, nzb := range candidates {
+		sameGroup := failedGroups[ReleaseGroup(nzb.Title)]
+		sameIndexer := nzb.Indexer != "" && failedIndexers[nzb.Indexer]
+
+		tier := 0
+		switch {
+		case sameGroup && sameIndexer:
+			tier = 3
+		case sameGroup:
+			tier = 2
+		case sameIndexer:
+			tier = 1
+		}
+		tiers[tier] = append(tiers[tier], nzb)
+	}
+
+	ordered := make([]*models.NZB, 0, len(candidates))
+	for _, tier := range tiers {
+		ordered = append(ordered, tier...)
+	}
+	return ordered
+}
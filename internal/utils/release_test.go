@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/amaumene/gomenarr/internal/models"
+)
+
+func TestReleaseGroup(t *testing.T) {
+	tests := map[string]string{
+		"Movie.2024.1080p.WEB-DL.DDP5.1.H.264-FLUX":       "FLUX",
+		"Show.S01E01.1080p.WEB.h264-ethel":                "ETHEL",
+		"Show.S01E01.1080p.WEB.h264-ethel[rarbg]":         "ETHEL",
+		"Movie.2024.2160p.UHD.BluRay.REMUX-FraMeSToR.nzb": "FRAMESTOR",
+		"Movie 2024 1080p BluRay x264":                    "",
+	}
+
+	for title, expected := range tests {
+		if group := ReleaseGroup(title); group != expected {
+			t.Errorf("ReleaseGroup(%q) = %q, expected %q", title, group, expected)
+		}
+	}
+}
+
+func TestOrderForRetry(t *testing.T) {
+	failed := []*models.NZB{{Title: "Movie.2024.1080p.WEB-DL-FLUX", Indexer: "alpha"}}
+	candidates := []*models.NZB{
+		{ID: 1, Title: "Movie.2024.1080p.WEB-DL.PROPER-FLUX", Indexer: "alpha"},
+		{ID: 2, Title: "Movie.2024.1080p.WEB-DL-FLUX", Indexer: "beta"},
+		{ID: 3, Title: "Movie.2024.1080p.WEB-DL-NTb", Indexer: "alpha"},
+		{ID: 4, Title: "Movie.2024.1080p.WEB-DL-CMRG", Indexer: "beta"},
+	}
+
+	ordered := OrderForRetry(candidates, failed)
+
+	expected := []uint64{4, 3, 2, 1}
+	for i, nzb := range ordered {
+		if nzb.ID != expected[i] {
+			t.Fatalf("Position %d: expected NZB %d, got %d", i, expected[i], nzb.ID)
+		}
+	}
+}
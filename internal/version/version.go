@@ -0,0 +1,69 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, injected at build time:
+//
+//	go build -ldflags "-X github.com/amaumene/gomenarr/internal/version.Version=v1.2.3 \
+//	  -X github.com/amaumene/gomenarr/internal/version.Commit=abc1234 \
+//	  -X github.com/amaumene/gomenarr/internal/version.BuildDate=2024-01-01T00:00:00Z"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Info returns the build information, falling back to the VCS data embedded
+// by the Go toolchain when ldflags were not provided
+func Info() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	return info
+}
+
+// String returns a one-line description of the build
+func (b BuildInfo) String() string {
+	commit := b.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return b.Version + " (commit " + commit + ", built " + b.BuildDate + ", " + b.GoVersion + ")"
+}